@@ -0,0 +1,161 @@
+// control-plane/cmd/network-controller/main.go
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers"
+	"gon-cloud-platform/control-plane/internal/api/metadata"
+	"gon-cloud-platform/control-plane/internal/database"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	// Load configuration
+	config, err := utils.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize logger
+	logger := utils.NewLogger(config.LogLevel)
+
+	// Initialize database connection. Migrations are left to the API server.
+	db, err := database.NewConnection(config.Database)
+	if err != nil {
+		logger.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Initialize repositories
+	vpcRepo := repositories.NewVPCRepository(db.DB)
+	subnetRepo := repositories.NewSubnetRepository(db.DB)
+	ipAllocationRepo := repositories.NewIPAllocationRepository(db.DB)
+	securityGroupRepo := repositories.NewSecurityGroupRepository(db.DB)
+	networkACLRepo := repositories.NewNetworkACLRepository(db.DB)
+	routeTableRepo := repositories.NewRouteTableRepository(db.DB)
+	igwRepo := repositories.NewInternetGatewayRepository(db.DB)
+	natRepo := repositories.NewNATGatewayRepository(db.DB)
+	peeringRepo := repositories.NewVPCPeeringRepository(db.DB)
+	workerNodeRepo := repositories.NewWorkerNodeRepository(db.DB)
+	instanceRepo := repositories.NewInstanceRepository(db.DB)
+	flowLogRepo := repositories.NewFlowLogRepository(db.DB)
+	trafficMirrorRepo := repositories.NewTrafficMirrorRepository(db.DB)
+
+	// Initialize managers
+	ovsManager := network.NewOVSManager()
+	if config.Network.OVSDBAddress != "" {
+		ovsdbManager, err := network.NewOVSDBManager(config.Network.OVSDBAddress)
+		if err != nil {
+			logger.Warn("Failed to connect to OVSDB, falling back to ovs-vsctl", "error", err, "address", config.Network.OVSDBAddress)
+		} else {
+			ovsManager = ovsdbManager
+		}
+	}
+
+	dhcpManager := network.NewDNSMasqManager(config.Network.DHCPDir)
+
+	// Initialize services
+	overlayService := services.NewOverlayService(workerNodeRepo, ovsManager, config.Network.NodeName, config.Network.TunnelType, logger)
+	securityGroupService := services.NewSecurityGroupService(securityGroupRepo, vpcRepo, ipAllocationRepo, ovsManager, logger)
+	networkACLService := services.NewNetworkACLService(networkACLRepo, vpcRepo, subnetRepo, ovsManager, logger)
+	routeTableService := services.NewRouteTableService(routeTableRepo, vpcRepo, subnetRepo, ipAllocationRepo, igwRepo, natRepo, peeringRepo, ovsManager, logger)
+	igwService := services.NewInternetGatewayService(igwRepo, vpcRepo, routeTableRepo, natRepo, ovsManager, config.Network.UplinkBridge, logger)
+	dhcpService := services.NewDHCPService(vpcRepo, subnetRepo, ipAllocationRepo, config.Network.DNSUpstream, logger)
+	metadataService := services.NewMetadataService(instanceRepo, vpcRepo, subnetRepo, ipAllocationRepo, routeTableRepo, ovsManager, logger)
+	vpcPeeringService := services.NewVPCPeeringService(peeringRepo, vpcRepo, routeTableService, ovsManager, logger)
+	networkLimitsService := services.NewNetworkLimitsService(instanceRepo, vpcRepo, ovsManager, logger)
+	flowLogService := services.NewFlowLogService(flowLogRepo, vpcRepo, subnetRepo, instanceRepo, ovsManager, config.Network.FlowLogCollector, logger)
+	trafficMirrorService := services.NewTrafficMirrorService(trafficMirrorRepo, vpcRepo, subnetRepo, instanceRepo, ovsManager, logger)
+
+	// The metadata service is served from this process, inside the
+	// namespace of each VPC bridge's DHCP and DNS responder
+	if config.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	metadataServer := metadata.NewServer(handlers.NewMetadataHandler(metadataService, logger), logger)
+
+	reconcileService := services.NewReconcileService(vpcRepo, igwRepo, securityGroupService, networkACLService, routeTableService, igwService, overlayService, dhcpService, metadataService, vpcPeeringService, networkLimitsService, flowLogService, trafficMirrorService, ovsManager, dhcpManager, metadataServer, config.Network.UplinkBridge, logger)
+
+	// The bridges of this node export flow log samples to this process
+	var collector *network.IPFIXCollector
+	if config.Network.FlowLogCollector != "" {
+		collector, err = network.ListenIPFIX(config.Network.FlowLogCollector)
+		if err != nil {
+			logger.Fatalf("Failed to start flow log collector: %v", err)
+		}
+		go func() {
+			err := collector.Serve(func(records []network.IPFIXRecord, err error) {
+				if err != nil {
+					logger.Warn("Failed to decode flow log records", "error", err)
+					return
+				}
+				if err := flowLogService.IngestRecords(config.Network.NodeName, records); err != nil {
+					logger.Error("Failed to ingest flow log records", "error", err)
+				}
+			})
+			if err != nil {
+				logger.Error("Flow log collector stopped", "error", err)
+			}
+		}()
+	}
+
+	interval := time.Duration(config.Network.ReconcileInterval) * time.Second
+	if interval <= 0 {
+		logger.Fatalf("Invalid reconcile interval: %d", config.Network.ReconcileInterval)
+	}
+
+	reconcile := func() {
+		if _, err := reconcileService.Reconcile(); err != nil {
+			logger.Error("Failed to reconcile network", "error", err)
+		}
+	}
+
+	logger.Infof("Starting network controller on node %s, reconciling every %s", config.Network.NodeName, interval)
+	reconcile()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Records past their retention are purged hourly
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	// Reconcile until an interrupt signal arrives. A pass in progress is
+	// finished first, as it may be halfway through replacing flows.
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	for {
+		select {
+		case <-ticker.C:
+			reconcile()
+		case <-purge.C:
+			if err := flowLogService.PurgeExpiredRecords(); err != nil {
+				logger.Error("Failed to purge flow log records", "error", err)
+			}
+		case <-quit:
+			if collector != nil {
+				if err := collector.Close(); err != nil {
+					logger.Error("Failed to close flow log collector", "error", err)
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := metadataServer.Shutdown(ctx); err != nil {
+				logger.Error("Failed to shut down metadata server", "error", err)
+			}
+			cancel()
+			logger.Info("Network controller exited")
+			return
+		}
+	}
+}
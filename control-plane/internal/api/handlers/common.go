@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+// getUserID returns the authenticated user ID set by the auth middleware.
+// It writes the error response itself and returns false when unavailable.
+func getUserID(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, errors.ErrUnauthorized, "User not authenticated")
+		return "", false
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInvalidID, "Invalid user ID format")
+		return "", false
+	}
+
+	return userIDStr, true
+}
+
+// getPagination parses page and page_size query parameters with defaults
+func getPagination(c *gin.Context) (int, int) {
+	page := 1
+	pageSize := 20
+
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	if pageSizeStr := c.Query("page_size"); pageSizeStr != "" {
+		if ps, err := strconv.Atoi(pageSizeStr); err == nil && ps > 0 && ps <= 100 {
+			pageSize = ps
+		}
+	}
+
+	return page, pageSize
+}
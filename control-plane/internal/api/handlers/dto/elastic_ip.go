@@ -0,0 +1,81 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type CreatePublicIPPoolRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	CIDRBlock   string `json:"cidr_block" binding:"required"`
+	Description string `json:"description"`
+}
+
+// AllocateElasticIPRequest takes an address from the given pool, or from the
+// first pool with a free address when PoolID is omitted
+type AllocateElasticIPRequest struct {
+	PoolID *string `json:"pool_id,omitempty" binding:"omitempty,uuid"`
+}
+
+type AssociateElasticIPRequest struct {
+	InstanceID string `json:"instance_id" binding:"required,uuid"`
+}
+
+type PublicIPPoolResponse struct {
+	ID                 string    `json:"id"`
+	Name               string    `json:"name"`
+	CIDRBlock          string    `json:"cidr_block"`
+	Description        string    `json:"description"`
+	Capacity           int       `json:"capacity"`
+	AllocatedAddresses int       `json:"allocated_addresses"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type ElasticIPResponse struct {
+	ID           string     `json:"id"`
+	PoolID       string     `json:"pool_id"`
+	PublicIP     string     `json:"public_ip"`
+	UserID       string     `json:"user_id"`
+	InstanceID   *string    `json:"instance_id"`
+	NATGatewayID *string    `json:"nat_gateway_id"`
+	AllocatedAt  time.Time  `json:"allocated_at"`
+	AssociatedAt *time.Time `json:"associated_at"`
+}
+
+type ElasticIPListResponse struct {
+	ElasticIPs []ElasticIPResponse `json:"elastic_ips"`
+	Total      int                 `json:"total"`
+	Page       int                 `json:"page"`
+	PageSize   int                 `json:"page_size"`
+	TotalPages int                 `json:"total_pages"`
+}
+
+// Convert PublicIPPool model to response
+func ToPublicIPPoolResponse(pool *models.PublicIPPool, capacity, allocated int) PublicIPPoolResponse {
+	return PublicIPPoolResponse{
+		ID:                 pool.ID,
+		Name:               pool.Name,
+		CIDRBlock:          pool.CIDRBlock,
+		Description:        pool.Description,
+		Capacity:           capacity,
+		AllocatedAddresses: allocated,
+		CreatedAt:          pool.CreatedAt,
+		UpdatedAt:          pool.UpdatedAt,
+	}
+}
+
+// Convert ElasticIP model to response
+func ToElasticIPResponse(eip *models.ElasticIP) ElasticIPResponse {
+	return ElasticIPResponse{
+		ID:           eip.ID,
+		PoolID:       eip.PoolID,
+		PublicIP:     eip.PublicIP,
+		UserID:       eip.UserID,
+		InstanceID:   eip.InstanceID,
+		NATGatewayID: eip.NATGatewayID,
+		AllocatedAt:  eip.AllocatedAt,
+		AssociatedAt: eip.AssociatedAt,
+	}
+}
@@ -0,0 +1,50 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type CreateInternetGatewayRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=255"`
+}
+
+type UpdateInternetGatewayRequest struct {
+	Name *string `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+}
+
+type InternetGatewayAttachmentRequest struct {
+	VPCID string `json:"vpc_id" binding:"required,uuid"`
+}
+
+type InternetGatewayResponse struct {
+	ID        string    `json:"id"`
+	VPCID     *string   `json:"vpc_id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type InternetGatewayListResponse struct {
+	InternetGateways []InternetGatewayResponse `json:"internet_gateways"`
+	Total            int                       `json:"total"`
+	Page             int                       `json:"page"`
+	PageSize         int                       `json:"page_size"`
+	TotalPages       int                       `json:"total_pages"`
+}
+
+// Convert InternetGateway model to response
+func ToInternetGatewayResponse(igw *models.InternetGateway) InternetGatewayResponse {
+	return InternetGatewayResponse{
+		ID:        igw.ID,
+		VPCID:     igw.VPCID,
+		UserID:    igw.UserID,
+		Name:      igw.Name,
+		State:     igw.State,
+		CreatedAt: igw.CreatedAt,
+		UpdatedAt: igw.UpdatedAt,
+	}
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type AllocateIPRequest struct {
+	IPAddress   *string `json:"ip_address,omitempty" binding:"omitempty,ipv4"`
+	InstanceID  *string `json:"instance_id,omitempty" binding:"omitempty,uuid"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=255"`
+}
+
+type IPAllocationResponse struct {
+	ID          string    `json:"id"`
+	SubnetID    string    `json:"subnet_id"`
+	IPAddress   string    `json:"ip_address"`
+	IPv6Address *string   `json:"ipv6_address"`
+	InstanceID  *string   `json:"instance_id"`
+	Description *string   `json:"description"`
+	AllocatedAt time.Time `json:"allocated_at"`
+}
+
+type SubnetUtilizationResponse struct {
+	SubnetID           string            `json:"subnet_id"`
+	CIDRBlock          string            `json:"cidr_block"`
+	TotalAddresses     int               `json:"total_addresses"`
+	ReservedAddresses  map[string]string `json:"reserved_addresses"`
+	AllocatedAddresses int               `json:"allocated_addresses"`
+	AvailableAddresses int               `json:"available_addresses"`
+	UtilizationPercent float64           `json:"utilization_percent"`
+}
+
+// Convert IPAllocation model to response
+func ToIPAllocationResponse(a *models.IPAllocation) IPAllocationResponse {
+	return IPAllocationResponse{
+		ID:          a.ID,
+		SubnetID:    a.SubnetID,
+		IPAddress:   a.IPAddress,
+		IPv6Address: a.IPv6Address,
+		InstanceID:  a.InstanceID,
+		Description: a.Description,
+		AllocatedAt: a.AllocatedAt,
+	}
+}
@@ -0,0 +1,20 @@
+package dto
+
+// InstanceMetadata is what the metadata service tells an instance about
+// itself. PublicIPv4, KeyName, SSHPublicKey and UserData are empty when the
+// instance has none.
+type InstanceMetadata struct {
+	InstanceID       string   `json:"instance_id"`
+	InstanceType     string   `json:"instance_type"`
+	ImageID          string   `json:"image_id"`
+	Hostname         string   `json:"hostname"`
+	LocalIPv4        string   `json:"local_ipv4"`
+	PublicIPv4       string   `json:"public_ipv4,omitempty"`
+	MAC              string   `json:"mac"`
+	AvailabilityZone string   `json:"availability_zone"`
+	KeyName          string   `json:"key_name,omitempty"`
+	SSHPublicKey     string   `json:"ssh_public_key,omitempty"`
+	SecurityGroups   []string `json:"security_groups"`
+	UserData         string   `json:"-"`
+	TokensRequired   bool     `json:"tokens_required"`
+}
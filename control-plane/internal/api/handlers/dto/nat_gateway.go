@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type CreateNATGatewayRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	SubnetID    string `json:"subnet_id" binding:"required,uuid"`
+	ElasticIPID string `json:"elastic_ip_id" binding:"required,uuid"`
+}
+
+type NATGatewayResponse struct {
+	ID          string    `json:"id"`
+	VPCID       string    `json:"vpc_id"`
+	SubnetID    string    `json:"subnet_id"`
+	ElasticIPID string    `json:"elastic_ip_id"`
+	PublicIP    string    `json:"public_ip"`
+	UserID      string    `json:"user_id"`
+	Name        string    `json:"name"`
+	State       string    `json:"state"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type NATGatewayListResponse struct {
+	NATGateways []NATGatewayResponse `json:"nat_gateways"`
+	Total       int                  `json:"total"`
+	Page        int                  `json:"page"`
+	PageSize    int                  `json:"page_size"`
+	TotalPages  int                  `json:"total_pages"`
+}
+
+// NATGatewayConnectionsResponse reports the translations a NAT gateway
+// currently tracks
+type NATGatewayConnectionsResponse struct {
+	NATGatewayID      string `json:"nat_gateway_id"`
+	PublicIP          string `json:"public_ip"`
+	ConntrackZone     int    `json:"conntrack_zone"`
+	ActiveConnections int    `json:"active_connections"`
+}
+
+// Convert NATGateway model to response
+func ToNATGatewayResponse(natGateway *models.NATGateway) NATGatewayResponse {
+	return NATGatewayResponse{
+		ID:          natGateway.ID,
+		VPCID:       natGateway.VPCID,
+		SubnetID:    natGateway.SubnetID,
+		ElasticIPID: natGateway.ElasticIPID,
+		PublicIP:    natGateway.PublicIP,
+		UserID:      natGateway.UserID,
+		Name:        natGateway.Name,
+		State:       natGateway.State,
+		CreatedAt:   natGateway.CreatedAt,
+		UpdatedAt:   natGateway.UpdatedAt,
+	}
+}
@@ -0,0 +1,138 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+)
+
+type CreateNetworkACLRequest struct {
+	VPCID       string `json:"vpc_id" binding:"required"`
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	Description string `json:"description"`
+}
+
+type UpdateNetworkACLRequest struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description,omitempty"`
+}
+
+// NetworkACLEntryRequest adds a numbered entry. CIDRBlock is the source for
+// inbound entries and the destination for outbound ones, and entries only
+// apply to subnet blocks of its family. For icmp and icmpv6 entries FromPort
+// and ToPort carry the ICMP type and code (-1 for any).
+type NetworkACLEntryRequest struct {
+	RuleNumber  int    `json:"rule_number" binding:"required,min=1,max=32766"`
+	Direction   string `json:"direction" binding:"required,oneof=inbound outbound"`
+	Protocol    string `json:"protocol" binding:"required,oneof=tcp udp icmp icmpv6 all"`
+	FromPort    int    `json:"from_port" binding:"min=-1,max=65535"`
+	ToPort      int    `json:"to_port" binding:"min=-1,max=65535"`
+	CIDRBlock   string `json:"cidr_block" binding:"required"`
+	Action      string `json:"action" binding:"required,oneof=allow deny"`
+	Description string `json:"description"`
+}
+
+type NetworkACLAssociationRequest struct {
+	SubnetID string `json:"subnet_id" binding:"required,uuid"`
+}
+
+// NetworkACLEntryResponse is one step of the evaluation order. The implicit
+// deny that ends every direction is reported with is_default set.
+type NetworkACLEntryResponse struct {
+	ID              string `json:"id,omitempty"`
+	EvaluationOrder int    `json:"evaluation_order"`
+	RuleNumber      int    `json:"rule_number"`
+	Direction       string `json:"direction"`
+	Protocol        string `json:"protocol"`
+	FromPort        int    `json:"from_port"`
+	ToPort          int    `json:"to_port"`
+	CIDRBlock       string `json:"cidr_block"`
+	Action          string `json:"action"`
+	Description     string `json:"description"`
+	FlowPriority    int    `json:"flow_priority"`
+	IsDefault       bool   `json:"is_default"`
+}
+
+type NetworkACLResponse struct {
+	ID              string                    `json:"id"`
+	Name            string                    `json:"name"`
+	Description     string                    `json:"description"`
+	VPCID           string                    `json:"vpc_id"`
+	UserID          string                    `json:"user_id"`
+	SubnetIDs       []string                  `json:"subnet_ids"`
+	InboundEntries  []NetworkACLEntryResponse `json:"inbound_entries"`
+	OutboundEntries []NetworkACLEntryResponse `json:"outbound_entries"`
+	CreatedAt       time.Time                 `json:"created_at"`
+	UpdatedAt       time.Time                 `json:"updated_at"`
+}
+
+type NetworkACLListResponse struct {
+	NetworkACLs []NetworkACLResponse `json:"network_acls"`
+	Total       int                  `json:"total"`
+	Page        int                  `json:"page"`
+	PageSize    int                  `json:"page_size"`
+	TotalPages  int                  `json:"total_pages"`
+}
+
+// Convert NetworkACLEntry model to response
+func ToNetworkACLEntryResponse(e *models.NetworkACLEntry) NetworkACLEntryResponse {
+	return NetworkACLEntryResponse{
+		ID:           e.ID,
+		RuleNumber:   e.RuleNumber,
+		Direction:    e.Direction,
+		Protocol:     e.Protocol,
+		FromPort:     e.FromPort,
+		ToPort:       e.ToPort,
+		CIDRBlock:    e.CIDRBlock,
+		Action:       e.Action,
+		Description:  e.Description,
+		FlowPriority: network.NetworkACLRulePriority(e.RuleNumber),
+	}
+}
+
+// Convert NetworkACL model to response
+func ToNetworkACLResponse(acl *models.NetworkACL) NetworkACLResponse {
+	subnetIDs := acl.SubnetIDs
+	if subnetIDs == nil {
+		subnetIDs = []string{}
+	}
+
+	return NetworkACLResponse{
+		ID:              acl.ID,
+		Name:            acl.Name,
+		Description:     acl.Description,
+		VPCID:           acl.VPCID,
+		UserID:          acl.UserID,
+		SubnetIDs:       subnetIDs,
+		InboundEntries:  toEvaluationOrder(acl.InboundEntries, "inbound"),
+		OutboundEntries: toEvaluationOrder(acl.OutboundEntries, "outbound"),
+		CreatedAt:       acl.CreatedAt,
+		UpdatedAt:       acl.UpdatedAt,
+	}
+}
+
+// toEvaluationOrder numbers entries sorted by rule number and appends the
+// implicit deny
+func toEvaluationOrder(entries []models.NetworkACLEntry, direction string) []NetworkACLEntryResponse {
+	responses := make([]NetworkACLEntryResponse, 0, len(entries)+1)
+	for i, entry := range entries {
+		resp := ToNetworkACLEntryResponse(&entry)
+		resp.EvaluationOrder = i + 1
+		responses = append(responses, resp)
+	}
+
+	return append(responses, NetworkACLEntryResponse{
+		EvaluationOrder: len(entries) + 1,
+		RuleNumber:      network.MaxNetworkACLRuleNumber + 1,
+		Direction:       direction,
+		Protocol:        "all",
+		FromPort:        0,
+		ToPort:          65535,
+		CIDRBlock:       "0.0.0.0/0",
+		Action:          "deny",
+		Description:     "Default deny",
+		FlowPriority:    network.PriorityNetworkACLDefaultDeny,
+		IsDefault:       true,
+	})
+}
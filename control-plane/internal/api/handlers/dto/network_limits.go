@@ -0,0 +1,36 @@
+package dto
+
+// UpdateNetworkLimitsRequest replaces the overrides of an instance's network
+// limits. A limit left out is decided by the instance type again.
+type UpdateNetworkLimitsRequest struct {
+	InboundMbps  *int `json:"inbound_mbps,omitempty" binding:"omitempty,min=1,max=4000000"`
+	OutboundMbps *int `json:"outbound_mbps,omitempty" binding:"omitempty,min=1,max=4000000"`
+	BurstKb      *int `json:"burst_kb,omitempty" binding:"omitempty,min=1,max=400000000"`
+}
+
+// NetworkLimits limits what an instance receives and sends. Zero rates are
+// unlimited.
+type NetworkLimits struct {
+	InboundMbps  int `json:"inbound_mbps"`
+	OutboundMbps int `json:"outbound_mbps"`
+	BurstKb      int `json:"burst_kb"`
+}
+
+// NetworkLimitOverrides are the limits set on an instance instead of the
+// ones its type decides
+type NetworkLimitOverrides struct {
+	InboundMbps  *int `json:"inbound_mbps,omitempty"`
+	OutboundMbps *int `json:"outbound_mbps,omitempty"`
+	BurstKb      *int `json:"burst_kb,omitempty"`
+}
+
+// NetworkLimitsResponse shows how the limits enforced on an instance's port
+// come about. TypeBandwidthMbps is 0 when the instance type sets no limit.
+type NetworkLimitsResponse struct {
+	InstanceID        string                `json:"instance_id"`
+	InstanceType      string                `json:"instance_type"`
+	TypeNetwork       string                `json:"type_network"`
+	TypeBandwidthMbps int                   `json:"type_bandwidth_mbps"`
+	Overrides         NetworkLimitOverrides `json:"overrides"`
+	Effective         NetworkLimits         `json:"effective"`
+}
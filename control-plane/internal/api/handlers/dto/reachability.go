@@ -0,0 +1,33 @@
+package dto
+
+// ReachabilityRequest describes the first packet of a connection to analyze.
+// Source and Destination are instance IDs or IPv4 addresses; the source
+// must live in the VPC while the destination may lie outside it.
+type ReachabilityRequest struct {
+	VPCID       string `json:"vpc_id" binding:"required,uuid"`
+	Source      string `json:"source" binding:"required"`
+	Destination string `json:"destination" binding:"required"`
+	Protocol    string `json:"protocol" binding:"required,oneof=tcp udp icmp"`
+	Port        int    `json:"port" binding:"min=0,max=65535"` // ICMP type for icmp
+	Trace       bool   `json:"trace"`                          // include the datapath trace of the VPC bridge
+}
+
+// ReachabilityHop is the verdict of one stage of the path
+type ReachabilityHop struct {
+	Stage        string `json:"stage"`   // network_acl_egress, network_acl_ingress, security_group_egress, security_group_ingress, route_table, gateway, destination
+	Verdict      string `json:"verdict"` // allow, deny, forward
+	ResourceType string `json:"resource_type,omitempty"`
+	ResourceID   string `json:"resource_id,omitempty"`
+	RuleID       string `json:"rule_id,omitempty"`
+	Explanation  string `json:"explanation"`
+}
+
+type ReachabilityResponse struct {
+	Reachable     bool              `json:"reachable"`
+	SourceIP      string            `json:"source_ip"`
+	DestinationIP string            `json:"destination_ip"`
+	Protocol      string            `json:"protocol"`
+	Port          int               `json:"port"`
+	Hops          []ReachabilityHop `json:"hops"`
+	Trace         string            `json:"trace,omitempty"`
+}
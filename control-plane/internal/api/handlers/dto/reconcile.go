@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// ReconcileChange is one correction the network controller made to OVS
+type ReconcileChange struct {
+	Resource string `json:"resource"` // bridge, port, flows, qos, mirror, ipfix
+	Name     string `json:"name"`
+	Action   string `json:"action"` // created, deleted, replaced
+	VPCID    string `json:"vpc_id,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ReconcileReport describes one pass of the network controller. A VPC that
+// fails to reconcile is reported in Errors and does not stop the others.
+type ReconcileReport struct {
+	StartedAt time.Time         `json:"started_at"`
+	Duration  time.Duration     `json:"duration"`
+	VPCs      int               `json:"vpcs"`
+	Changes   []ReconcileChange `json:"changes"`
+	Errors    []string          `json:"errors"`
+}
@@ -0,0 +1,90 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type CreateRouteTableRequest struct {
+	VPCID string `json:"vpc_id" binding:"required"`
+	Name  string `json:"name" binding:"required,min=1,max=255"`
+}
+
+type UpdateRouteTableRequest struct {
+	Name *string `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+}
+
+type CreateRouteRequest struct {
+	DestinationCIDR string `json:"destination_cidr" binding:"required"`
+	TargetType      string `json:"target_type" binding:"required,oneof=igw nat instance peering"`
+	TargetID        string `json:"target_id" binding:"required"`
+}
+
+type RouteTableAssociationRequest struct {
+	SubnetID string `json:"subnet_id" binding:"required,uuid"`
+}
+
+type RouteResponse struct {
+	ID              string `json:"id,omitempty"`
+	DestinationCIDR string `json:"destination_cidr"`
+	TargetType      string `json:"target_type"`
+	TargetID        string `json:"target_id"`
+	Priority        int    `json:"priority"`
+	State           string `json:"state"`
+}
+
+type RouteTableResponse struct {
+	ID        string          `json:"id"`
+	VPCID     string          `json:"vpc_id"`
+	Name      string          `json:"name"`
+	IsMain    bool            `json:"is_main"`
+	Routes    []RouteResponse `json:"routes"`
+	SubnetIDs []string        `json:"subnet_ids"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type RouteTableListResponse struct {
+	RouteTables []RouteTableResponse `json:"route_tables"`
+	Total       int                  `json:"total"`
+	Page        int                  `json:"page"`
+	PageSize    int                  `json:"page_size"`
+	TotalPages  int                  `json:"total_pages"`
+}
+
+// Convert Route model to response
+func ToRouteResponse(r *models.Route) RouteResponse {
+	return RouteResponse{
+		ID:              r.ID,
+		DestinationCIDR: r.DestinationCIDR,
+		TargetType:      r.TargetType,
+		TargetID:        r.TargetID,
+		Priority:        r.Priority,
+		State:           r.State,
+	}
+}
+
+// Convert RouteTable model to response
+func ToRouteTableResponse(rt *models.RouteTable) RouteTableResponse {
+	routes := make([]RouteResponse, len(rt.Routes))
+	for i, route := range rt.Routes {
+		routes[i] = ToRouteResponse(&route)
+	}
+
+	subnetIDs := rt.SubnetIDs
+	if subnetIDs == nil {
+		subnetIDs = []string{}
+	}
+
+	return RouteTableResponse{
+		ID:        rt.ID,
+		VPCID:     rt.VPCID,
+		Name:      rt.Name,
+		IsMain:    rt.IsMain,
+		Routes:    routes,
+		SubnetIDs: subnetIDs,
+		CreatedAt: rt.CreatedAt,
+		UpdatedAt: rt.UpdatedAt,
+	}
+}
@@ -0,0 +1,103 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type CreateSecurityGroupRequest struct {
+	VPCID       string `json:"vpc_id" binding:"required"`
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	Description string `json:"description"`
+}
+
+type UpdateSecurityGroupRequest struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description,omitempty"`
+}
+
+// SecurityGroupRuleRequest adds a rule. Source is a CIDR block or the ID of a
+// security group in the same VPC; for outbound rules it is the destination.
+// For icmp and icmpv6 rules FromPort and ToPort carry the ICMP type and code
+// (-1 for any); icmp applies to IPv4 sources and icmpv6 to IPv6 ones.
+type SecurityGroupRuleRequest struct {
+	Direction   string `json:"direction" binding:"required,oneof=inbound outbound"`
+	Protocol    string `json:"protocol" binding:"required,oneof=tcp udp icmp icmpv6 all"`
+	FromPort    int    `json:"from_port" binding:"min=-1,max=65535"`
+	ToPort      int    `json:"to_port" binding:"min=-1,max=65535"`
+	Source      string `json:"source" binding:"required"`
+	Description string `json:"description"`
+}
+
+type SecurityGroupMemberRequest struct {
+	InstanceID string `json:"instance_id" binding:"required,uuid"`
+}
+
+type SecurityGroupRuleResponse struct {
+	ID          string `json:"id"`
+	Direction   string `json:"direction"`
+	Protocol    string `json:"protocol"`
+	FromPort    int    `json:"from_port"`
+	ToPort      int    `json:"to_port"`
+	Source      string `json:"source"`
+	Description string `json:"description"`
+}
+
+type SecurityGroupResponse struct {
+	ID            string                      `json:"id"`
+	Name          string                      `json:"name"`
+	Description   string                      `json:"description"`
+	VPCID         string                      `json:"vpc_id"`
+	UserID        string                      `json:"user_id"`
+	InboundRules  []SecurityGroupRuleResponse `json:"inbound_rules"`
+	OutboundRules []SecurityGroupRuleResponse `json:"outbound_rules"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	UpdatedAt     time.Time                   `json:"updated_at"`
+}
+
+type SecurityGroupListResponse struct {
+	SecurityGroups []SecurityGroupResponse `json:"security_groups"`
+	Total          int                     `json:"total"`
+	Page           int                     `json:"page"`
+	PageSize       int                     `json:"page_size"`
+	TotalPages     int                     `json:"total_pages"`
+}
+
+// Convert SecurityGroupRule model to response
+func ToSecurityGroupRuleResponse(r *models.SecurityGroupRule) SecurityGroupRuleResponse {
+	return SecurityGroupRuleResponse{
+		ID:          r.ID,
+		Direction:   r.Direction,
+		Protocol:    r.Protocol,
+		FromPort:    r.FromPort,
+		ToPort:      r.ToPort,
+		Source:      r.Source,
+		Description: r.Description,
+	}
+}
+
+// Convert SecurityGroup model to response
+func ToSecurityGroupResponse(sg *models.SecurityGroup) SecurityGroupResponse {
+	inbound := make([]SecurityGroupRuleResponse, len(sg.InboundRules))
+	for i, rule := range sg.InboundRules {
+		inbound[i] = ToSecurityGroupRuleResponse(&rule)
+	}
+
+	outbound := make([]SecurityGroupRuleResponse, len(sg.OutboundRules))
+	for i, rule := range sg.OutboundRules {
+		outbound[i] = ToSecurityGroupRuleResponse(&rule)
+	}
+
+	return SecurityGroupResponse{
+		ID:            sg.ID,
+		Name:          sg.Name,
+		Description:   sg.Description,
+		VPCID:         sg.VPCID,
+		UserID:        sg.UserID,
+		InboundRules:  inbound,
+		OutboundRules: outbound,
+		CreatedAt:     sg.CreatedAt,
+		UpdatedAt:     sg.UpdatedAt,
+	}
+}
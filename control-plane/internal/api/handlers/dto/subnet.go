@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// CreateSubnetRequest creates a subnet. IPv6CIDRBlock is an optional /64 of
+// the VPC's IPv6 block.
+type CreateSubnetRequest struct {
+	VPCID            string  `json:"vpc_id"`
+	Name             string  `json:"name" binding:"required,min=1,max=255"`
+	CIDRBlock        string  `json:"cidr_block" binding:"required"`
+	IPv6CIDRBlock    *string `json:"ipv6_cidr_block,omitempty" binding:"omitempty,cidrv6"`
+	AvailabilityZone string  `json:"availability_zone" binding:"required,max=50"`
+	IsPublic         bool    `json:"is_public"`
+}
+
+type UpdateSubnetRequest struct {
+	Name     *string `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+	IsPublic *bool   `json:"is_public,omitempty"`
+}
+
+type SubnetResponse struct {
+	ID               string    `json:"id"`
+	VPCID            string    `json:"vpc_id"`
+	Name             string    `json:"name"`
+	CIDRBlock        string    `json:"cidr_block"`
+	IPv6CIDRBlock    *string   `json:"ipv6_cidr_block"`
+	AvailabilityZone string    `json:"availability_zone"`
+	IsPublic         bool      `json:"is_public"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type SubnetListResponse struct {
+	Subnets    []SubnetResponse `json:"subnets"`
+	Total      int              `json:"total"`
+	Page       int              `json:"page"`
+	PageSize   int              `json:"page_size"`
+	TotalPages int              `json:"total_pages"`
+}
+
+// Convert Subnet model to response
+func ToSubnetResponse(s *models.Subnet) SubnetResponse {
+	return SubnetResponse{
+		ID:               s.ID,
+		VPCID:            s.VPCID,
+		Name:             s.Name,
+		CIDRBlock:        s.CIDRBlock,
+		IPv6CIDRBlock:    s.IPv6CIDRBlock,
+		AvailabilityZone: s.AvailabilityZone,
+		IsPublic:         s.IsPublic,
+		CreatedAt:        s.CreatedAt,
+		UpdatedAt:        s.UpdatedAt,
+	}
+}
@@ -0,0 +1,98 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// CreateTrafficMirrorSessionRequest mirrors the traffic of an instance to
+// another instance of its VPC, given by TargetInstanceID, or to a GRE or
+// ERSPAN endpoint at TargetIP. TargetKey is the GRE key or ERSPAN session ID
+// of the endpoint. All traffic is mirrored unless FilterRules narrow it down.
+type CreateTrafficMirrorSessionRequest struct {
+	Name             string                           `json:"name" binding:"required,min=1,max=255"`
+	SourceInstanceID string                           `json:"source_instance_id" binding:"required,uuid"`
+	TargetType       string                           `json:"target_type" binding:"required,oneof=instance gre erspan"`
+	TargetInstanceID string                           `json:"target_instance_id,omitempty" binding:"omitempty,uuid"`
+	TargetIP         string                           `json:"target_ip,omitempty" binding:"omitempty,ipv4"`
+	TargetKey        int                              `json:"target_key,omitempty" binding:"omitempty,min=0,max=4294967295"`
+	FilterRules      []TrafficMirrorFilterRuleRequest `json:"filter_rules,omitempty" binding:"omitempty,dive"`
+}
+
+// TrafficMirrorFilterRuleRequest adds a filter rule. Directions are seen from
+// the source instance, and CIDRBlock is the remote side of its traffic,
+// every address if left out. For icmp rules FromPort and ToPort carry the
+// ICMP type and code (-1 for any).
+type TrafficMirrorFilterRuleRequest struct {
+	Direction string `json:"direction" binding:"required,oneof=inbound outbound"`
+	Protocol  string `json:"protocol" binding:"required,oneof=tcp udp icmp all"`
+	FromPort  int    `json:"from_port" binding:"min=-1,max=65535"`
+	ToPort    int    `json:"to_port" binding:"min=-1,max=65535"`
+	CIDRBlock string `json:"cidr_block,omitempty" binding:"omitempty,cidrv4"`
+}
+
+type TrafficMirrorFilterRuleResponse struct {
+	ID        string `json:"id"`
+	Direction string `json:"direction"`
+	Protocol  string `json:"protocol"`
+	FromPort  int    `json:"from_port"`
+	ToPort    int    `json:"to_port"`
+	CIDRBlock string `json:"cidr_block"`
+}
+
+type TrafficMirrorSessionResponse struct {
+	ID               string                            `json:"id"`
+	Name             string                            `json:"name"`
+	VPCID            string                            `json:"vpc_id"`
+	SourceInstanceID string                            `json:"source_instance_id"`
+	TargetType       string                            `json:"target_type"`
+	TargetInstanceID string                            `json:"target_instance_id,omitempty"`
+	TargetIP         string                            `json:"target_ip,omitempty"`
+	TargetKey        int                               `json:"target_key"`
+	FilterRules      []TrafficMirrorFilterRuleResponse `json:"filter_rules"`
+	CreatedAt        time.Time                         `json:"created_at"`
+	UpdatedAt        time.Time                         `json:"updated_at"`
+}
+
+type TrafficMirrorSessionListResponse struct {
+	Sessions   []TrafficMirrorSessionResponse `json:"sessions"`
+	Total      int                            `json:"total"`
+	Page       int                            `json:"page"`
+	PageSize   int                            `json:"page_size"`
+	TotalPages int                            `json:"total_pages"`
+}
+
+// Convert TrafficMirrorFilterRule model to response
+func ToTrafficMirrorFilterRuleResponse(r *models.TrafficMirrorFilterRule) TrafficMirrorFilterRuleResponse {
+	return TrafficMirrorFilterRuleResponse{
+		ID:        r.ID,
+		Direction: r.Direction,
+		Protocol:  r.Protocol,
+		FromPort:  r.FromPort,
+		ToPort:    r.ToPort,
+		CIDRBlock: r.CIDRBlock,
+	}
+}
+
+// Convert TrafficMirrorSession model to response
+func ToTrafficMirrorSessionResponse(session *models.TrafficMirrorSession) TrafficMirrorSessionResponse {
+	rules := make([]TrafficMirrorFilterRuleResponse, len(session.FilterRules))
+	for i := range session.FilterRules {
+		rules[i] = ToTrafficMirrorFilterRuleResponse(&session.FilterRules[i])
+	}
+
+	return TrafficMirrorSessionResponse{
+		ID:               session.ID,
+		Name:             session.Name,
+		VPCID:            session.VPCID,
+		SourceInstanceID: session.SourceInstanceID,
+		TargetType:       session.TargetType,
+		TargetInstanceID: session.TargetInstanceID,
+		TargetIP:         session.TargetIP,
+		TargetKey:        session.TargetKey,
+		FilterRules:      rules,
+		CreatedAt:        session.CreatedAt,
+		UpdatedAt:        session.UpdatedAt,
+	}
+}
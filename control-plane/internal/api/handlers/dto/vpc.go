@@ -6,10 +6,16 @@ import (
 	"gon-cloud-platform/control-plane/internal/models"
 )
 
+// CreateVPCRequest creates a VPC. An IPv6 /56 is either given as
+// IPv6CIDRBlock, taken from the unique local range or a prefix delegated to
+// the platform, or generated in the unique local range when
+// AssignIPv6CIDRBlock is set.
 type CreateVPCRequest struct {
-	Name        string  `json:"name" binding:"required,min=1,max=255"`
-	CIDRBlock   string  `json:"cidr_block" binding:"required"`
-	Description *string `json:"description,omitempty"`
+	Name                string  `json:"name" binding:"required,min=1,max=255"`
+	CIDRBlock           string  `json:"cidr_block" binding:"required"`
+	IPv6CIDRBlock       *string `json:"ipv6_cidr_block,omitempty" binding:"omitempty,cidrv6"`
+	AssignIPv6CIDRBlock bool    `json:"assign_ipv6_cidr_block"`
+	Description         *string `json:"description,omitempty"`
 }
 
 type UpdateVPCRequest struct {
@@ -18,13 +24,23 @@ type UpdateVPCRequest struct {
 }
 
 type VPCResponse struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	CIDRBlock   string    `json:"cidr_block"`
-	Description *string   `json:"description"`
-	UserID      string    `json:"user_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            string                `json:"id"`
+	Name          string                `json:"name"`
+	CIDRBlock     string                `json:"cidr_block"`
+	IPv6CIDRBlock *string               `json:"ipv6_cidr_block"`
+	Description   *string               `json:"description"`
+	UserID        string                `json:"user_id"`
+	Dataplane     *VPCDataplaneResponse `json:"dataplane,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+// VPCDataplaneResponse is part of VPC detail responses only
+type VPCDataplaneResponse struct {
+	BridgeName    string `json:"bridge_name"`
+	DatapathID    string `json:"datapath_id"`
+	VNI           int    `json:"vni"`
+	ConntrackZone int    `json:"conntrack_zone"`
 }
 
 type VPCListResponse struct {
@@ -37,13 +53,38 @@ type VPCListResponse struct {
 
 // Convert VPC model to response
 func ToVPCResponse(v *models.VPC) VPCResponse {
-	return VPCResponse{
-		ID:          v.ID,
-		Name:        v.Name,
-		CIDRBlock:   v.CIDRBlock,
-		Description: v.Description,
-		UserID:      v.UserID,
-		CreatedAt:   v.CreatedAt,
-		UpdatedAt:   v.UpdatedAt,
+	resp := VPCResponse{
+		ID:            v.ID,
+		Name:          v.Name,
+		CIDRBlock:     v.CIDRBlock,
+		IPv6CIDRBlock: v.IPv6CIDRBlock,
+		Description:   v.Description,
+		UserID:        v.UserID,
+		CreatedAt:     v.CreatedAt,
+		UpdatedAt:     v.UpdatedAt,
+	}
+
+	if v.Dataplane != nil {
+		resp.Dataplane = &VPCDataplaneResponse{
+			BridgeName:    v.Dataplane.BridgeName,
+			DatapathID:    v.Dataplane.DatapathID,
+			VNI:           v.Dataplane.VNI,
+			ConntrackZone: v.Dataplane.ConntrackZone,
+		}
 	}
+
+	return resp
+}
+
+type VPCCIDRDryRunRequest struct {
+	CIDRBlock    *string `json:"cidr_block,omitempty"`
+	PrefixLength *int    `json:"prefix_length,omitempty" binding:"omitempty,min=16,max=28"`
+}
+
+type VPCCIDRDryRunResponse struct {
+	CIDRBlock          *string  `json:"cidr_block,omitempty"`
+	Available          bool     `json:"available"`
+	Reason             string   `json:"reason,omitempty"`
+	ConflictingCIDRs   []string `json:"conflicting_cidr_blocks"`
+	SuggestedCIDRBlock *string  `json:"suggested_cidr_block"`
 }
@@ -0,0 +1,52 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// CreateVPCPeeringRequest asks to peer one of the caller's VPCs with another
+// VPC. The peer VPC belongs to the caller unless PeerUserID names its owner,
+// who then has to accept the peering.
+type CreateVPCPeeringRequest struct {
+	Name       string `json:"name" binding:"required,min=1,max=255"`
+	VPCID      string `json:"vpc_id" binding:"required,uuid"`
+	PeerVPCID  string `json:"peer_vpc_id" binding:"required,uuid"`
+	PeerUserID string `json:"peer_user_id,omitempty" binding:"omitempty,uuid"`
+}
+
+type VPCPeeringResponse struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	RequesterVPCID  string    `json:"requester_vpc_id"`
+	RequesterUserID string    `json:"requester_user_id"`
+	AccepterVPCID   string    `json:"accepter_vpc_id"`
+	AccepterUserID  string    `json:"accepter_user_id"`
+	State           string    `json:"state"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type VPCPeeringListResponse struct {
+	VPCPeerings []VPCPeeringResponse `json:"vpc_peerings"`
+	Total       int                  `json:"total"`
+	Page        int                  `json:"page"`
+	PageSize    int                  `json:"page_size"`
+	TotalPages  int                  `json:"total_pages"`
+}
+
+// Convert VPCPeering model to response
+func ToVPCPeeringResponse(peering *models.VPCPeering) VPCPeeringResponse {
+	return VPCPeeringResponse{
+		ID:              peering.ID,
+		Name:            peering.Name,
+		RequesterVPCID:  peering.RequesterVPCID,
+		RequesterUserID: peering.RequesterUserID,
+		AccepterVPCID:   peering.AccepterVPCID,
+		AccepterUserID:  peering.AccepterUserID,
+		State:           peering.State,
+		CreatedAt:       peering.CreatedAt,
+		UpdatedAt:       peering.UpdatedAt,
+	}
+}
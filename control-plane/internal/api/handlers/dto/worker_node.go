@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type RegisterWorkerNodeRequest struct {
+	Name     string `json:"name" binding:"required,min=1,max=255"`
+	TunnelIP string `json:"tunnel_ip" binding:"required"`
+}
+
+type WorkerNodeResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	TunnelIP  string    `json:"tunnel_ip"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Convert WorkerNode model to response
+func ToWorkerNodeResponse(node *models.WorkerNode) WorkerNodeResponse {
+	return WorkerNodeResponse{
+		ID:        node.ID,
+		Name:      node.Name,
+		TunnelIP:  node.TunnelIP,
+		State:     node.State,
+		CreatedAt: node.CreatedAt,
+		UpdatedAt: node.UpdatedAt,
+	}
+}
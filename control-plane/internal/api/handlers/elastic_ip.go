@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type ElasticIPHandler struct {
+	eipService services.ElasticIPService
+	logger     *utils.Logger
+}
+
+func NewElasticIPHandler(eipService services.ElasticIPService, logger *utils.Logger) *ElasticIPHandler {
+	return &ElasticIPHandler{
+		eipService: eipService,
+		logger:     logger,
+	}
+}
+
+// CreatePublicIPPool godoc
+// @Summary Create a public IP pool
+// @Description Register a block of public addresses that elastic IPs are allocated from. Administrators only.
+// @Tags PublicIPPool
+// @Accept json
+// @Produce json
+// @Param pool body dto.CreatePublicIPPoolRequest true "Public IP pool creation request"
+// @Success 201 {object} response.Response{data=dto.PublicIPPoolResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/admin/public-ip-pools [post]
+func (h *ElasticIPHandler) CreatePublicIPPool(c *gin.Context) {
+	var req dto.CreatePublicIPPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	pool, err := h.eipService.CreatePool(&req)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidCIDR:
+			response.Error(c, http.StatusBadRequest, err, "Invalid CIDR block")
+		case errors.ErrPublicIPPoolExists:
+			response.Error(c, http.StatusConflict, err, "Public IP pool already exists")
+		case errors.ErrPublicIPPoolConflict:
+			response.Error(c, http.StatusConflict, err, "CIDR block overlaps an existing public IP pool")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Public IP pool created successfully", pool)
+}
+
+// ListPublicIPPools godoc
+// @Summary List public IP pools
+// @Description List public IP pools with their capacity and allocated address count. Administrators only.
+// @Tags PublicIPPool
+// @Produce json
+// @Success 200 {object} response.Response{data=[]dto.PublicIPPoolResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/admin/public-ip-pools [get]
+func (h *ElasticIPHandler) ListPublicIPPools(c *gin.Context) {
+	pools, err := h.eipService.ListPools()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Public IP pools retrieved successfully", pools)
+}
+
+// DeletePublicIPPool godoc
+// @Summary Delete a public IP pool
+// @Description Delete a public IP pool that has no allocated addresses. Administrators only.
+// @Tags PublicIPPool
+// @Produce json
+// @Param id path string true "Public IP pool ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/admin/public-ip-pools/{id} [delete]
+func (h *ElasticIPHandler) DeletePublicIPPool(c *gin.Context) {
+	idStr := c.Param("id")
+
+	if err := h.eipService.DeletePool(idStr); err != nil {
+		switch err {
+		case errors.ErrPublicIPPoolNotFound:
+			response.Error(c, http.StatusNotFound, err, "Public IP pool not found")
+		case errors.ErrResourceInUse:
+			response.Error(c, http.StatusConflict, err, "Public IP pool still has allocated addresses")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Public IP pool deleted successfully", nil)
+}
+
+// AllocateElasticIP godoc
+// @Summary Allocate an elastic IP
+// @Description Allocate a public address to the user. The address is kept until released, independent of any instance.
+// @Tags ElasticIP
+// @Accept json
+// @Produce json
+// @Param elastic_ip body dto.AllocateElasticIPRequest false "Elastic IP allocation request"
+// @Success 201 {object} response.Response{data=dto.ElasticIPResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/elastic-ips [post]
+func (h *ElasticIPHandler) AllocateElasticIP(c *gin.Context) {
+	var req dto.AllocateElasticIPRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	eip, err := h.eipService.AllocateElasticIP(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrPublicIPPoolNotFound:
+			response.Error(c, http.StatusNotFound, err, "Public IP pool not found")
+		case errors.ErrPublicIPPoolExhausted:
+			response.Error(c, http.StatusConflict, err, "No public IP addresses available")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Elastic IP allocated successfully", dto.ToElasticIPResponse(eip))
+}
+
+// GetElasticIP godoc
+// @Summary Get elastic IP by ID
+// @Description Get an elastic IP and the instance it is associated with
+// @Tags ElasticIP
+// @Produce json
+// @Param id path string true "Elastic IP ID"
+// @Success 200 {object} response.Response{data=dto.ElasticIPResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/elastic-ips/{id} [get]
+func (h *ElasticIPHandler) GetElasticIP(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	eip, err := h.eipService.GetElasticIP(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrElasticIPNotFound:
+			response.Error(c, http.StatusNotFound, err, "Elastic IP not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Elastic IP retrieved successfully", dto.ToElasticIPResponse(eip))
+}
+
+// ListElasticIPs godoc
+// @Summary List elastic IPs
+// @Description Get a paginated list of the user's elastic IPs
+// @Tags ElasticIP
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.ElasticIPListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/elastic-ips [get]
+func (h *ElasticIPHandler) ListElasticIPs(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := getPagination(c)
+
+	result, err := h.eipService.ListElasticIPs(userID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Elastic IPs retrieved successfully", result)
+}
+
+// ReleaseElasticIP godoc
+// @Summary Release elastic IP
+// @Description Return an elastic IP to its pool. Refused while the address is associated with an instance.
+// @Tags ElasticIP
+// @Produce json
+// @Param id path string true "Elastic IP ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/elastic-ips/{id} [delete]
+func (h *ElasticIPHandler) ReleaseElasticIP(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.eipService.ReleaseElasticIP(idStr, userID); err != nil {
+		switch err {
+		case errors.ErrElasticIPNotFound:
+			response.Error(c, http.StatusNotFound, err, "Elastic IP not found")
+		case errors.ErrElasticIPAssociated:
+			response.Error(c, http.StatusConflict, err, "Elastic IP must be disassociated before release")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Elastic IP released successfully", nil)
+}
+
+// AssociateElasticIP godoc
+// @Summary Associate elastic IP with an instance
+// @Description Map the elastic IP 1:1 onto an instance's private address. An associated address moves to the new instance without interruption.
+// @Tags ElasticIP
+// @Accept json
+// @Produce json
+// @Param id path string true "Elastic IP ID"
+// @Param association body dto.AssociateElasticIPRequest true "Instance to associate with"
+// @Success 200 {object} response.Response{data=dto.ElasticIPResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/elastic-ips/{id}/associate [post]
+func (h *ElasticIPHandler) AssociateElasticIP(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.AssociateElasticIPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	eip, err := h.eipService.AssociateElasticIP(idStr, userID, req.InstanceID)
+	if err != nil {
+		switch err {
+		case errors.ErrElasticIPNotFound:
+			response.Error(c, http.StatusNotFound, err, "Elastic IP not found")
+		case errors.ErrInstanceNotFound:
+			response.Error(c, http.StatusNotFound, err, "Instance not found")
+		case errors.ErrInstanceHasPublicIP:
+			response.Error(c, http.StatusConflict, err, "Instance already has a public IP")
+		case errors.ErrElasticIPAssociated:
+			response.Error(c, http.StatusConflict, err, "Elastic IP is in use by a NAT gateway")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Elastic IP associated successfully", dto.ToElasticIPResponse(eip))
+}
+
+// DisassociateElasticIP godoc
+// @Summary Disassociate elastic IP from its instance
+// @Description Remove the 1:1 NAT mapping. The address stays allocated to the user.
+// @Tags ElasticIP
+// @Produce json
+// @Param id path string true "Elastic IP ID"
+// @Success 200 {object} response.Response{data=dto.ElasticIPResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/elastic-ips/{id}/disassociate [post]
+func (h *ElasticIPHandler) DisassociateElasticIP(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	eip, err := h.eipService.DisassociateElasticIP(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrElasticIPNotFound:
+			response.Error(c, http.StatusNotFound, err, "Elastic IP not found")
+		case errors.ErrElasticIPNotAssociated:
+			response.Error(c, http.StatusBadRequest, err, "Elastic IP is not associated")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Elastic IP disassociated successfully", dto.ToElasticIPResponse(eip))
+}
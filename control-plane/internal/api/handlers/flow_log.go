@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type FlowLogHandler struct {
+	flowLogService services.FlowLogService
+	logger         *utils.Logger
+}
+
+func NewFlowLogHandler(flowLogService services.FlowLogService, logger *utils.Logger) *FlowLogHandler {
+	return &FlowLogHandler{
+		flowLogService: flowLogService,
+		logger:         logger,
+	}
+}
+
+// ListFlowLogRecords godoc
+// @Summary Query flow log records
+// @Description Get a paginated list of the traffic your flow logs recorded, latest first. Each record is one connection in one direction with the action the network ACLs and security groups took on it. Records overlapping the range from start_time to end_time (RFC 3339) are returned; src_ip and dst_ip take an address or a CIDR block, and port matches either end.
+// @Tags FlowLog
+// @Produce json
+// @Param flow_log_id query string false "Flow log ID"
+// @Param vpc_id query string false "VPC ID"
+// @Param action query string false "accept or reject"
+// @Param protocol query int false "IP protocol number"
+// @Param src_ip query string false "Source address or CIDR block"
+// @Param dst_ip query string false "Destination address or CIDR block"
+// @Param port query int false "Source or destination port"
+// @Param start_time query string false "Start of the time range"
+// @Param end_time query string false "End of the time range"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.FlowLogRecordListResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/flow-logs [get]
+func (h *FlowLogHandler) ListFlowLogRecords(c *gin.Context) {
+	var query dto.FlowLogRecordQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := getPagination(c)
+
+	result, err := h.flowLogService.ListRecords(userID, &query, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Flow log records retrieved successfully", result)
+}
+
+// CreateFlowLog godoc
+// @Summary Create a flow log
+// @Description Start recording the traffic of one of your VPCs, subnets or instances. traffic_type limits the records to accepted or rejected traffic, and records are kept for retention_days, 14 by default.
+// @Tags FlowLog
+// @Accept json
+// @Produce json
+// @Param flow_log body dto.CreateFlowLogRequest true "Flow log creation request"
+// @Success 201 {object} response.Response{data=dto.FlowLogResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/flow-logs/configs [post]
+func (h *FlowLogHandler) CreateFlowLog(c *gin.Context) {
+	var req dto.CreateFlowLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	flowLog, err := h.flowLogService.CreateFlowLog(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrVPCNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC not found")
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found")
+		case errors.ErrInstanceNotFound:
+			response.Error(c, http.StatusNotFound, err, "Instance not found")
+		case errors.ErrFlowLogNoInterface:
+			response.Error(c, http.StatusBadRequest, err, "Instance has no network interface to log")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Flow log created successfully", dto.ToFlowLogResponse(flowLog))
+}
+
+// GetFlowLog godoc
+// @Summary Get flow log by ID
+// @Description Get a flow log you own
+// @Tags FlowLog
+// @Produce json
+// @Param id path string true "Flow log ID"
+// @Success 200 {object} response.Response{data=dto.FlowLogResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/flow-logs/configs/{id} [get]
+func (h *FlowLogHandler) GetFlowLog(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	flowLog, err := h.flowLogService.GetFlowLog(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrFlowLogNotFound:
+			response.Error(c, http.StatusNotFound, err, "Flow log not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Flow log retrieved successfully", dto.ToFlowLogResponse(flowLog))
+}
+
+// ListFlowLogs godoc
+// @Summary List flow logs
+// @Description Get a paginated list of your flow logs, optionally filtered by VPC
+// @Tags FlowLog
+// @Produce json
+// @Param vpc_id query string false "VPC ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.FlowLogListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/flow-logs/configs [get]
+func (h *FlowLogHandler) ListFlowLogs(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var vpcID *string
+	if v := c.Query("vpc_id"); v != "" {
+		vpcID = &v
+	}
+	page, pageSize := getPagination(c)
+
+	result, err := h.flowLogService.ListFlowLogs(userID, vpcID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Flow logs retrieved successfully", result)
+}
+
+// DeleteFlowLog godoc
+// @Summary Delete a flow log
+// @Description Stop recording the traffic of a flow log and delete its records
+// @Tags FlowLog
+// @Produce json
+// @Param id path string true "Flow log ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/flow-logs/configs/{id} [delete]
+func (h *FlowLogHandler) DeleteFlowLog(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.flowLogService.DeleteFlowLog(idStr, userID); err != nil {
+		switch err {
+		case errors.ErrFlowLogNotFound:
+			response.Error(c, http.StatusNotFound, err, "Flow log not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Flow log deleted successfully", nil)
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type InternetGatewayHandler struct {
+	igwService services.InternetGatewayService
+	logger     *utils.Logger
+}
+
+func NewInternetGatewayHandler(igwService services.InternetGatewayService, logger *utils.Logger) *InternetGatewayHandler {
+	return &InternetGatewayHandler{
+		igwService: igwService,
+		logger:     logger,
+	}
+}
+
+// CreateInternetGateway godoc
+// @Summary Create a new internet gateway
+// @Description Create an internet gateway in the available state. It carries no traffic until attached to a VPC.
+// @Tags InternetGateway
+// @Accept json
+// @Produce json
+// @Param internet_gateway body dto.CreateInternetGatewayRequest true "Internet gateway creation request"
+// @Success 201 {object} response.Response{data=dto.InternetGatewayResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/internet-gateways [post]
+func (h *InternetGatewayHandler) CreateInternetGateway(c *gin.Context) {
+	var req dto.CreateInternetGatewayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	igw, err := h.igwService.CreateInternetGateway(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrInternetGatewayExists:
+			response.Error(c, http.StatusConflict, err, "Internet gateway already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Internet gateway created successfully", dto.ToInternetGatewayResponse(igw))
+}
+
+// GetInternetGateway godoc
+// @Summary Get internet gateway by ID
+// @Description Get an internet gateway and the VPC it is attached to
+// @Tags InternetGateway
+// @Produce json
+// @Param id path string true "Internet gateway ID"
+// @Success 200 {object} response.Response{data=dto.InternetGatewayResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/internet-gateways/{id} [get]
+func (h *InternetGatewayHandler) GetInternetGateway(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	igw, err := h.igwService.GetInternetGateway(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrInternetGatewayNotFound:
+			response.Error(c, http.StatusNotFound, err, "Internet gateway not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Internet gateway retrieved successfully", dto.ToInternetGatewayResponse(igw))
+}
+
+// ListInternetGateways godoc
+// @Summary List internet gateways
+// @Description Get a paginated list of the user's internet gateways
+// @Tags InternetGateway
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.InternetGatewayListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/internet-gateways [get]
+func (h *InternetGatewayHandler) ListInternetGateways(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := getPagination(c)
+
+	result, err := h.igwService.ListInternetGateways(userID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Internet gateways retrieved successfully", result)
+}
+
+// UpdateInternetGateway godoc
+// @Summary Update internet gateway
+// @Description Update the name of an internet gateway
+// @Tags InternetGateway
+// @Accept json
+// @Produce json
+// @Param id path string true "Internet gateway ID"
+// @Param internet_gateway body dto.UpdateInternetGatewayRequest true "Internet gateway update request"
+// @Success 200 {object} response.Response{data=dto.InternetGatewayResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/internet-gateways/{id} [put]
+func (h *InternetGatewayHandler) UpdateInternetGateway(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.UpdateInternetGatewayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	igw, err := h.igwService.UpdateInternetGateway(idStr, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrInternetGatewayNotFound:
+			response.Error(c, http.StatusNotFound, err, "Internet gateway not found")
+		case errors.ErrInternetGatewayExists:
+			response.Error(c, http.StatusConflict, err, "Internet gateway already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Internet gateway updated successfully", dto.ToInternetGatewayResponse(igw))
+}
+
+// DeleteInternetGateway godoc
+// @Summary Delete internet gateway
+// @Description Delete an internet gateway that is not attached to a VPC
+// @Tags InternetGateway
+// @Produce json
+// @Param id path string true "Internet gateway ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/internet-gateways/{id} [delete]
+func (h *InternetGatewayHandler) DeleteInternetGateway(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.igwService.DeleteInternetGateway(idStr, userID); err != nil {
+		switch err {
+		case errors.ErrInternetGatewayNotFound:
+			response.Error(c, http.StatusNotFound, err, "Internet gateway not found")
+		case errors.ErrInternetGatewayAttached:
+			response.Error(c, http.StatusConflict, err, "Internet gateway must be detached before deletion")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Internet gateway deleted successfully", nil)
+}
+
+// AttachInternetGateway godoc
+// @Summary Attach internet gateway to a VPC
+// @Description Patch the VPC bridge to the uplink and program 1:1 NAT for instances with a public IP. A VPC accepts a single internet gateway.
+// @Tags InternetGateway
+// @Accept json
+// @Produce json
+// @Param id path string true "Internet gateway ID"
+// @Param attachment body dto.InternetGatewayAttachmentRequest true "VPC to attach to"
+// @Success 200 {object} response.Response{data=dto.InternetGatewayResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/internet-gateways/{id}/attach [post]
+func (h *InternetGatewayHandler) AttachInternetGateway(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.InternetGatewayAttachmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	igw, err := h.igwService.AttachInternetGateway(idStr, userID, req.VPCID)
+	if err != nil {
+		switch err {
+		case errors.ErrInternetGatewayNotFound:
+			response.Error(c, http.StatusNotFound, err, "Internet gateway not found")
+		case errors.ErrVPCNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC not found")
+		case errors.ErrInternetGatewayAttached:
+			response.Error(c, http.StatusConflict, err, "Internet gateway is already attached to a VPC")
+		case errors.ErrVPCHasInternetGateway:
+			response.Error(c, http.StatusConflict, err, "VPC already has an internet gateway attached")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Internet gateway attached successfully", dto.ToInternetGatewayResponse(igw))
+}
+
+// DetachInternetGateway godoc
+// @Summary Detach internet gateway from its VPC
+// @Description Disconnect the VPC from the uplink. Refused while any route still targets the gateway or the VPC has NAT gateways.
+// @Tags InternetGateway
+// @Produce json
+// @Param id path string true "Internet gateway ID"
+// @Success 200 {object} response.Response{data=dto.InternetGatewayResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/internet-gateways/{id}/detach [post]
+func (h *InternetGatewayHandler) DetachInternetGateway(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	igw, err := h.igwService.DetachInternetGateway(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrInternetGatewayNotFound:
+			response.Error(c, http.StatusNotFound, err, "Internet gateway not found")
+		case errors.ErrInternetGatewayNotAttached:
+			response.Error(c, http.StatusBadRequest, err, "Internet gateway is not attached")
+		case errors.ErrResourceInUse:
+			response.Error(c, http.StatusConflict, err, "Internet gateway is still used by routes or NAT gateways")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Internet gateway detached successfully", dto.ToInternetGatewayResponse(igw))
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type IPAMHandler struct {
+	ipamService services.IPAMService
+	logger      *utils.Logger
+}
+
+func NewIPAMHandler(ipamService services.IPAMService, logger *utils.Logger) *IPAMHandler {
+	return &IPAMHandler{
+		ipamService: ipamService,
+		logger:      logger,
+	}
+}
+
+// AllocateIP godoc
+// @Summary Allocate an IP address
+// @Description Allocate the next free address of a subnet, or a specific address when ip_address is given
+// @Tags IPAM
+// @Accept json
+// @Produce json
+// @Param id path string true "Subnet ID"
+// @Param allocation body dto.AllocateIPRequest false "Allocation request"
+// @Success 201 {object} response.Response{data=dto.IPAllocationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/subnets/{id}/ip-allocations [post]
+func (h *IPAMHandler) AllocateIP(c *gin.Context) {
+	subnetID := c.Param("id")
+
+	var req dto.AllocateIPRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	allocation, err := h.ipamService.AllocateIP(subnetID, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found")
+		case errors.ErrIPAddressUnavailable:
+			response.Error(c, http.StatusConflict, err, "Requested IP address cannot be allocated")
+		case errors.ErrIPAddressExhausted:
+			response.Error(c, http.StatusConflict, err, "Subnet has no free IP addresses")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "IP address allocated successfully", dto.ToIPAllocationResponse(allocation))
+}
+
+// ReleaseIP godoc
+// @Summary Release an IP address
+// @Description Return an allocated address to the subnet pool
+// @Tags IPAM
+// @Produce json
+// @Param id path string true "Subnet ID"
+// @Param ip path string true "IP address"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/subnets/{id}/ip-allocations/{ip} [delete]
+func (h *IPAMHandler) ReleaseIP(c *gin.Context) {
+	subnetID := c.Param("id")
+	ipAddress := c.Param("ip")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.ipamService.ReleaseIP(subnetID, ipAddress, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found")
+		case errors.ErrIPAllocationNotFound:
+			response.Error(c, http.StatusNotFound, err, "IP address is not allocated")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "IP address released successfully", nil)
+}
+
+// ListIPAllocations godoc
+// @Summary List IP allocations
+// @Description List every allocated address of a subnet
+// @Tags IPAM
+// @Produce json
+// @Param id path string true "Subnet ID"
+// @Success 200 {object} response.Response{data=[]dto.IPAllocationResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/subnets/{id}/ip-allocations [get]
+func (h *IPAMHandler) ListIPAllocations(c *gin.Context) {
+	subnetID := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	allocations, err := h.ipamService.ListAllocations(subnetID, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	allocationResponses := make([]dto.IPAllocationResponse, len(allocations))
+	for i, allocation := range allocations {
+		allocationResponses[i] = dto.ToIPAllocationResponse(&allocation)
+	}
+
+	response.Success(c, http.StatusOK, "IP allocations retrieved successfully", allocationResponses)
+}
+
+// GetSubnetUtilization godoc
+// @Summary Get subnet utilization
+// @Description Show how many addresses of a subnet are reserved, allocated and still free
+// @Tags IPAM
+// @Produce json
+// @Param id path string true "Subnet ID"
+// @Success 200 {object} response.Response{data=dto.SubnetUtilizationResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/subnets/{id}/utilization [get]
+func (h *IPAMHandler) GetSubnetUtilization(c *gin.Context) {
+	subnetID := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	utilization, err := h.ipamService.GetUtilization(subnetID, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Subnet utilization retrieved successfully", utilization)
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// Headers of the session token mode, named the way EC2 names them so images
+// built for EC2 work unchanged
+const (
+	metadataTokenHeader    = "X-aws-ec2-metadata-token"
+	metadataTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+)
+
+// MetadataHandler serves the metadata service. It answers in plain text
+// like EC2 does rather than in the API's JSON envelope, and expects the
+// server it runs in to set vpc_id to the VPC the request arrived from.
+type MetadataHandler struct {
+	metadataService services.MetadataService
+	logger          *utils.Logger
+}
+
+func NewMetadataHandler(metadataService services.MetadataService, logger *utils.Logger) *MetadataHandler {
+	return &MetadataHandler{
+		metadataService: metadataService,
+		logger:          logger,
+	}
+}
+
+// CreateToken issues a session token to the calling instance. Requests that
+// went through a proxy are refused, so a token never leaves the instance.
+func (h *MetadataHandler) CreateToken(c *gin.Context) {
+	if c.GetHeader("X-Forwarded-For") != "" {
+		c.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		return
+	}
+
+	ttl, err := strconv.Atoi(c.GetHeader(metadataTokenTTLHeader))
+	if err != nil {
+		c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	metadata, ok := h.identify(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.metadataService.CreateToken(metadata.InstanceID, ttl)
+	if err != nil {
+		if err == errors.ErrInvalidParameter {
+			c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+			return
+		}
+		c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	c.Header(metadataTokenTTLHeader, strconv.Itoa(ttl))
+	c.String(http.StatusOK, token)
+}
+
+// GetMetadata serves /latest/meta-data/ and everything below it. A path
+// naming a category lists what is in it, one entry per line, with
+// categories ending in a slash.
+func (h *MetadataHandler) GetMetadata(c *gin.Context) {
+	metadata, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	path := strings.Trim(c.Param("path"), "/")
+	if path == "public-keys" && metadata.SSHPublicKey != "" {
+		// The keys are listed by index and name
+		c.String(http.StatusOK, "0="+metadata.KeyName)
+		return
+	}
+
+	entries := metadataEntries(metadata)
+	if value, ok := entries[path]; ok {
+		c.String(http.StatusOK, value)
+		return
+	}
+
+	prefix := path + "/"
+	if path == "" {
+		prefix = ""
+	}
+	listed := make(map[string]bool)
+	for key := range entries {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		name, _, isCategory := strings.Cut(strings.TrimPrefix(key, prefix), "/")
+		if isCategory {
+			name += "/"
+		}
+		listed[name] = true
+	}
+	if len(listed) == 0 {
+		c.String(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	names := make([]string, 0, len(listed))
+	for name := range listed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.String(http.StatusOK, strings.Join(names, "\n"))
+}
+
+// GetUserData serves the user data of the calling instance as it was given
+func (h *MetadataHandler) GetUserData(c *gin.Context) {
+	metadata, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	if metadata.UserData == "" {
+		c.String(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/octet-stream", []byte(metadata.UserData))
+}
+
+// identify returns the metadata of the calling instance. It writes the error
+// response itself and returns false when the caller is unknown.
+func (h *MetadataHandler) identify(c *gin.Context) (*dto.InstanceMetadata, bool) {
+	ip, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return nil, false
+	}
+
+	metadata, err := h.metadataService.GetMetadata(c.GetString("vpc_id"), ip)
+	if err != nil {
+		switch err {
+		case errors.ErrInstanceNotFound:
+			c.String(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		default:
+			c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		return nil, false
+	}
+
+	return metadata, true
+}
+
+// authorize identifies the calling instance and checks its session token. A
+// token is only required when the instance says so, but one that is sent
+// must be valid either way.
+func (h *MetadataHandler) authorize(c *gin.Context) (*dto.InstanceMetadata, bool) {
+	metadata, ok := h.identify(c)
+	if !ok {
+		return nil, false
+	}
+
+	token := c.GetHeader(metadataTokenHeader)
+	if token == "" && !metadata.TokensRequired {
+		return metadata, true
+	}
+	if err := h.metadataService.ValidateToken(metadata.InstanceID, token); err != nil {
+		c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return nil, false
+	}
+
+	return metadata, true
+}
+
+// metadataEntries lays the metadata of an instance out in the paths EC2 uses
+// below /latest/meta-data/, leaving out what the instance does not have
+func metadataEntries(metadata *dto.InstanceMetadata) map[string]string {
+	entries := map[string]string{
+		"ami-id":                      metadata.ImageID,
+		"instance-id":                 metadata.InstanceID,
+		"instance-type":               metadata.InstanceType,
+		"local-ipv4":                  metadata.LocalIPv4,
+		"mac":                         metadata.MAC,
+		"placement/availability-zone": metadata.AvailabilityZone,
+		"security-groups":             strings.Join(metadata.SecurityGroups, "\n"),
+	}
+	if metadata.Hostname != "" {
+		entries["hostname"] = metadata.Hostname
+		entries["local-hostname"] = metadata.Hostname
+	}
+	if metadata.PublicIPv4 != "" {
+		entries["public-ipv4"] = metadata.PublicIPv4
+	}
+	if metadata.SSHPublicKey != "" {
+		entries["public-keys/0/openssh-key"] = metadata.SSHPublicKey
+	}
+
+	for key, value := range entries {
+		if value == "" {
+			delete(entries, key)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type NATGatewayHandler struct {
+	natService services.NATGatewayService
+	logger     *utils.Logger
+}
+
+func NewNATGatewayHandler(natService services.NATGatewayService, logger *utils.Logger) *NATGatewayHandler {
+	return &NATGatewayHandler{
+		natService: natService,
+		logger:     logger,
+	}
+}
+
+// CreateNATGateway godoc
+// @Summary Create a new NAT gateway
+// @Description Place a NAT gateway in a public subnet. Private subnets reach the internet through it by routing to target type nat; all their connections share its elastic IP.
+// @Tags NATGateway
+// @Accept json
+// @Produce json
+// @Param nat_gateway body dto.CreateNATGatewayRequest true "NAT gateway creation request"
+// @Success 201 {object} response.Response{data=dto.NATGatewayResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/nat-gateways [post]
+func (h *NATGatewayHandler) CreateNATGateway(c *gin.Context) {
+	var req dto.CreateNATGatewayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	natGateway, err := h.natService.CreateNATGateway(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found")
+		case errors.ErrElasticIPNotFound:
+			response.Error(c, http.StatusNotFound, err, "Elastic IP not found")
+		case errors.ErrSubnetNotPublic:
+			response.Error(c, http.StatusBadRequest, err, "Subnet has no route to an attached internet gateway")
+		case errors.ErrNATGatewayExists:
+			response.Error(c, http.StatusConflict, err, "NAT gateway already exists")
+		case errors.ErrElasticIPAssociated:
+			response.Error(c, http.StatusConflict, err, "Elastic IP is already in use")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "NAT gateway created successfully", dto.ToNATGatewayResponse(natGateway))
+}
+
+// GetNATGateway godoc
+// @Summary Get NAT gateway by ID
+// @Description Get a NAT gateway with its subnet and public address
+// @Tags NATGateway
+// @Produce json
+// @Param id path string true "NAT gateway ID"
+// @Success 200 {object} response.Response{data=dto.NATGatewayResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/nat-gateways/{id} [get]
+func (h *NATGatewayHandler) GetNATGateway(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	natGateway, err := h.natService.GetNATGateway(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrNATGatewayNotFound:
+			response.Error(c, http.StatusNotFound, err, "NAT gateway not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "NAT gateway retrieved successfully", dto.ToNATGatewayResponse(natGateway))
+}
+
+// ListNATGateways godoc
+// @Summary List NAT gateways
+// @Description Get a paginated list of NAT gateways, optionally filtered by VPC
+// @Tags NATGateway
+// @Produce json
+// @Param vpc_id query string false "VPC ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.NATGatewayListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/nat-gateways [get]
+func (h *NATGatewayHandler) ListNATGateways(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var vpcID *string
+	if v := c.Query("vpc_id"); v != "" {
+		vpcID = &v
+	}
+	page, pageSize := getPagination(c)
+
+	result, err := h.natService.ListNATGateways(userID, vpcID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "NAT gateways retrieved successfully", result)
+}
+
+// DeleteNATGateway godoc
+// @Summary Delete NAT gateway
+// @Description Delete a NAT gateway that no route targets. Its elastic IP stays allocated.
+// @Tags NATGateway
+// @Produce json
+// @Param id path string true "NAT gateway ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/nat-gateways/{id} [delete]
+func (h *NATGatewayHandler) DeleteNATGateway(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.natService.DeleteNATGateway(idStr, userID); err != nil {
+		switch err {
+		case errors.ErrNATGatewayNotFound:
+			response.Error(c, http.StatusNotFound, err, "NAT gateway not found")
+		case errors.ErrResourceInUse:
+			response.Error(c, http.StatusConflict, err, "Routes still target the NAT gateway")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "NAT gateway deleted successfully", nil)
+}
+
+// GetNATGatewayConnections godoc
+// @Summary Get NAT gateway connection count
+// @Description Count the connections currently translated by a NAT gateway, for troubleshooting
+// @Tags NATGateway
+// @Produce json
+// @Param id path string true "NAT gateway ID"
+// @Success 200 {object} response.Response{data=dto.NATGatewayConnectionsResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/nat-gateways/{id}/connections [get]
+func (h *NATGatewayHandler) GetNATGatewayConnections(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.natService.GetConnections(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrNATGatewayNotFound:
+			response.Error(c, http.StatusNotFound, err, "NAT gateway not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "NAT gateway connections retrieved successfully", result)
+}
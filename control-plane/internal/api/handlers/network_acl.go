@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type NetworkACLHandler struct {
+	networkACLService services.NetworkACLService
+	logger            *utils.Logger
+}
+
+func NewNetworkACLHandler(networkACLService services.NetworkACLService, logger *utils.Logger) *NetworkACLHandler {
+	return &NetworkACLHandler{
+		networkACLService: networkACLService,
+		logger:            logger,
+	}
+}
+
+// CreateNetworkACL godoc
+// @Summary Create a new network ACL
+// @Description Create an empty network ACL inside a VPC. Until entries are added it denies all traffic of associated subnets.
+// @Tags NetworkACL
+// @Accept json
+// @Produce json
+// @Param network_acl body dto.CreateNetworkACLRequest true "Network ACL creation request"
+// @Success 201 {object} response.Response{data=dto.NetworkACLResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/network-acls [post]
+func (h *NetworkACLHandler) CreateNetworkACL(c *gin.Context) {
+	var req dto.CreateNetworkACLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	acl, err := h.networkACLService.CreateNetworkACL(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrVPCNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC not found")
+		case errors.ErrNetworkACLExists:
+			response.Error(c, http.StatusConflict, err, "Network ACL already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Network ACL created successfully", dto.ToNetworkACLResponse(acl))
+}
+
+// GetNetworkACL godoc
+// @Summary Get network ACL by ID
+// @Description Get a network ACL with its associated subnets and entries in evaluation order
+// @Tags NetworkACL
+// @Produce json
+// @Param id path string true "Network ACL ID"
+// @Success 200 {object} response.Response{data=dto.NetworkACLResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/network-acls/{id} [get]
+func (h *NetworkACLHandler) GetNetworkACL(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	acl, err := h.networkACLService.GetNetworkACL(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrNetworkACLNotFound:
+			response.Error(c, http.StatusNotFound, err, "Network ACL not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Network ACL retrieved successfully", dto.ToNetworkACLResponse(acl))
+}
+
+// ListNetworkACLs godoc
+// @Summary List network ACLs
+// @Description Get a paginated list of network ACLs, optionally filtered by VPC
+// @Tags NetworkACL
+// @Produce json
+// @Param vpc_id query string false "VPC ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.NetworkACLListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/network-acls [get]
+func (h *NetworkACLHandler) ListNetworkACLs(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var vpcID *string
+	if v := c.Query("vpc_id"); v != "" {
+		vpcID = &v
+	}
+	page, pageSize := getPagination(c)
+
+	result, err := h.networkACLService.ListNetworkACLs(userID, vpcID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Network ACLs retrieved successfully", result)
+}
+
+// UpdateNetworkACL godoc
+// @Summary Update network ACL
+// @Description Update the name or description of a network ACL
+// @Tags NetworkACL
+// @Accept json
+// @Produce json
+// @Param id path string true "Network ACL ID"
+// @Param network_acl body dto.UpdateNetworkACLRequest true "Network ACL update request"
+// @Success 200 {object} response.Response{data=dto.NetworkACLResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/network-acls/{id} [put]
+func (h *NetworkACLHandler) UpdateNetworkACL(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.UpdateNetworkACLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	acl, err := h.networkACLService.UpdateNetworkACL(idStr, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrNetworkACLNotFound:
+			response.Error(c, http.StatusNotFound, err, "Network ACL not found")
+		case errors.ErrNetworkACLExists:
+			response.Error(c, http.StatusConflict, err, "Network ACL already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Network ACL updated successfully", dto.ToNetworkACLResponse(acl))
+}
+
+// DeleteNetworkACL godoc
+// @Summary Delete network ACL
+// @Description Delete a network ACL that is not associated with any subnet
+// @Tags NetworkACL
+// @Produce json
+// @Param id path string true "Network ACL ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/network-acls/{id} [delete]
+func (h *NetworkACLHandler) DeleteNetworkACL(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.networkACLService.DeleteNetworkACL(idStr, userID); err != nil {
+		switch err {
+		case errors.ErrNetworkACLNotFound:
+			response.Error(c, http.StatusNotFound, err, "Network ACL not found")
+		case errors.ErrResourceInUse:
+			response.Error(c, http.StatusConflict, err, "Network ACL is still associated with subnets")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Network ACL deleted successfully", nil)
+}
+
+// AddEntry godoc
+// @Summary Add a network ACL entry
+// @Description Add a numbered allow or deny entry. Entries are evaluated from the lowest rule number and the first match wins.
+// @Tags NetworkACL
+// @Accept json
+// @Produce json
+// @Param id path string true "Network ACL ID"
+// @Param entry body dto.NetworkACLEntryRequest true "Network ACL entry"
+// @Success 201 {object} response.Response{data=dto.NetworkACLEntryResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/network-acls/{id}/entries [post]
+func (h *NetworkACLHandler) AddEntry(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.NetworkACLEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	entry, err := h.networkACLService.AddEntry(idStr, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrNetworkACLNotFound:
+			response.Error(c, http.StatusNotFound, err, "Network ACL not found")
+		case errors.ErrInvalidNetworkACLEntry:
+			response.Error(c, http.StatusBadRequest, err, "Invalid network ACL entry")
+		case errors.ErrNetworkACLRuleNumberExists:
+			response.Error(c, http.StatusConflict, err, "Rule number is already used in this direction")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Network ACL entry added successfully", dto.ToNetworkACLEntryResponse(entry))
+}
+
+// RemoveEntry godoc
+// @Summary Remove a network ACL entry
+// @Description Remove an entry and its flows from the VPC bridge
+// @Tags NetworkACL
+// @Produce json
+// @Param id path string true "Network ACL ID"
+// @Param entry_id path string true "Entry ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/network-acls/{id}/entries/{entry_id} [delete]
+func (h *NetworkACLHandler) RemoveEntry(c *gin.Context) {
+	idStr := c.Param("id")
+	entryID := c.Param("entry_id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.networkACLService.RemoveEntry(idStr, entryID, userID); err != nil {
+		switch err {
+		case errors.ErrNetworkACLNotFound:
+			response.Error(c, http.StatusNotFound, err, "Network ACL not found")
+		case errors.ErrNetworkACLEntryNotFound:
+			response.Error(c, http.StatusNotFound, err, "Network ACL entry not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Network ACL entry removed successfully", nil)
+}
+
+// AssociateSubnet godoc
+// @Summary Associate a subnet with a network ACL
+// @Description Filter a subnet with this ACL, replacing any ACL it was associated with before
+// @Tags NetworkACL
+// @Accept json
+// @Produce json
+// @Param id path string true "Network ACL ID"
+// @Param association body dto.NetworkACLAssociationRequest true "Subnet to associate"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/network-acls/{id}/subnets [post]
+func (h *NetworkACLHandler) AssociateSubnet(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.NetworkACLAssociationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.networkACLService.AssociateSubnet(idStr, userID, req.SubnetID); err != nil {
+		switch err {
+		case errors.ErrNetworkACLNotFound:
+			response.Error(c, http.StatusNotFound, err, "Network ACL not found")
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found in the network ACL VPC")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Subnet associated successfully", nil)
+}
+
+// DisassociateSubnet godoc
+// @Summary Disassociate a subnet from a network ACL
+// @Description Stop filtering a subnet with this ACL
+// @Tags NetworkACL
+// @Produce json
+// @Param id path string true "Network ACL ID"
+// @Param subnet_id path string true "Subnet ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/network-acls/{id}/subnets/{subnet_id} [delete]
+func (h *NetworkACLHandler) DisassociateSubnet(c *gin.Context) {
+	idStr := c.Param("id")
+	subnetID := c.Param("subnet_id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.networkACLService.DisassociateSubnet(idStr, userID, subnetID); err != nil {
+		switch err {
+		case errors.ErrNetworkACLNotFound:
+			response.Error(c, http.StatusNotFound, err, "Network ACL not found")
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet is not associated with the network ACL")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Subnet disassociated successfully", nil)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type NetworkLimitsHandler struct {
+	networkLimitsService services.NetworkLimitsService
+	logger               *utils.Logger
+}
+
+func NewNetworkLimitsHandler(networkLimitsService services.NetworkLimitsService, logger *utils.Logger) *NetworkLimitsHandler {
+	return &NetworkLimitsHandler{
+		networkLimitsService: networkLimitsService,
+		logger:               logger,
+	}
+}
+
+// GetNetworkLimits godoc
+// @Summary Get instance network limits
+// @Description Get the bandwidth an instance may receive and send, as its instance type sets it and with any overrides an administrator set. Zero rates are unlimited.
+// @Tags Instance
+// @Produce json
+// @Param id path string true "Instance ID"
+// @Success 200 {object} response.Response{data=dto.NetworkLimitsResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/instances/{id}/network-limits [get]
+func (h *NetworkLimitsHandler) GetNetworkLimits(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	limits, err := h.networkLimitsService.GetNetworkLimits(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrInstanceNotFound:
+			response.Error(c, http.StatusNotFound, err, "Instance not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Instance network limits retrieved successfully", limits)
+}
+
+// UpdateNetworkLimits godoc
+// @Summary Override instance network limits
+// @Description Replace the overrides of the bandwidth an instance may receive and send, e.g. to rein in a noisy neighbor. Limits left out are set by the instance type again. Administrators only.
+// @Tags Instance
+// @Accept json
+// @Produce json
+// @Param id path string true "Instance ID"
+// @Param network_limits body dto.UpdateNetworkLimitsRequest true "Network limit overrides"
+// @Success 200 {object} response.Response{data=dto.NetworkLimitsResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/instances/{id}/network-limits [put]
+func (h *NetworkLimitsHandler) UpdateNetworkLimits(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.UpdateNetworkLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	limits, err := h.networkLimitsService.UpdateNetworkLimits(idStr, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrInstanceNotFound:
+			response.Error(c, http.StatusNotFound, err, "Instance not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Instance network limits updated successfully", limits)
+}
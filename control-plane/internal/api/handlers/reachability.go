@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type ReachabilityHandler struct {
+	reachabilityService services.ReachabilityService
+	logger              *utils.Logger
+}
+
+func NewReachabilityHandler(reachabilityService services.ReachabilityService, logger *utils.Logger) *ReachabilityHandler {
+	return &ReachabilityHandler{
+		reachabilityService: reachabilityService,
+		logger:              logger,
+	}
+}
+
+// AnalyzeReachability godoc
+// @Summary Analyze reachability between two endpoints
+// @Description Walk the first packet of a connection from an instance or address in a VPC to a destination through the network ACLs, security groups and route table, and report the verdict of every hop with the rule that allowed or dropped it. With trace set, the ofproto/trace output of the VPC bridge is included.
+// @Tags Network
+// @Accept json
+// @Produce json
+// @Param request body dto.ReachabilityRequest true "Reachability analysis request"
+// @Success 200 {object} response.Response{data=dto.ReachabilityResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/network/reachability [post]
+func (h *ReachabilityHandler) AnalyzeReachability(c *gin.Context) {
+	var req dto.ReachabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.reachabilityService.Analyze(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrVPCNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC not found")
+		case errors.ErrInvalidEndpoint:
+			response.Error(c, http.StatusBadRequest, err, "Source must be an instance or allocated address in the VPC, destination an instance or IPv4 address")
+		case errors.ErrInvalidRequest:
+			response.Error(c, http.StatusBadRequest, err, "ICMP type must be between 0 and 255")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Reachability analyzed successfully", result)
+}
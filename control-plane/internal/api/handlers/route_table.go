@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type RouteTableHandler struct {
+	routeTableService services.RouteTableService
+	logger            *utils.Logger
+}
+
+func NewRouteTableHandler(routeTableService services.RouteTableService, logger *utils.Logger) *RouteTableHandler {
+	return &RouteTableHandler{
+		routeTableService: routeTableService,
+		logger:            logger,
+	}
+}
+
+// CreateRouteTable godoc
+// @Summary Create a new route table
+// @Description Create a route table inside a VPC. It only holds the local route until routes are added.
+// @Tags RouteTable
+// @Accept json
+// @Produce json
+// @Param route_table body dto.CreateRouteTableRequest true "Route table creation request"
+// @Success 201 {object} response.Response{data=dto.RouteTableResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/route-tables [post]
+func (h *RouteTableHandler) CreateRouteTable(c *gin.Context) {
+	var req dto.CreateRouteTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	routeTable, err := h.routeTableService.CreateRouteTable(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrVPCNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC not found")
+		case errors.ErrRouteTableExists:
+			response.Error(c, http.StatusConflict, err, "Route table already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Route table created successfully", dto.ToRouteTableResponse(routeTable))
+}
+
+// GetRouteTable godoc
+// @Summary Get route table by ID
+// @Description Get a route table with its associated subnets and routes. Routes whose target no longer exists are reported as blackhole.
+// @Tags RouteTable
+// @Produce json
+// @Param id path string true "Route table ID"
+// @Success 200 {object} response.Response{data=dto.RouteTableResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/route-tables/{id} [get]
+func (h *RouteTableHandler) GetRouteTable(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	routeTable, err := h.routeTableService.GetRouteTable(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrRouteTableNotFound:
+			response.Error(c, http.StatusNotFound, err, "Route table not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Route table retrieved successfully", dto.ToRouteTableResponse(routeTable))
+}
+
+// ListRouteTables godoc
+// @Summary List route tables
+// @Description Get a paginated list of route tables, optionally filtered by VPC
+// @Tags RouteTable
+// @Produce json
+// @Param vpc_id query string false "VPC ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.RouteTableListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/route-tables [get]
+func (h *RouteTableHandler) ListRouteTables(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var vpcID *string
+	if v := c.Query("vpc_id"); v != "" {
+		vpcID = &v
+	}
+	page, pageSize := getPagination(c)
+
+	result, err := h.routeTableService.ListRouteTables(userID, vpcID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Route tables retrieved successfully", result)
+}
+
+// UpdateRouteTable godoc
+// @Summary Update route table
+// @Description Update the name of a route table
+// @Tags RouteTable
+// @Accept json
+// @Produce json
+// @Param id path string true "Route table ID"
+// @Param route_table body dto.UpdateRouteTableRequest true "Route table update request"
+// @Success 200 {object} response.Response{data=dto.RouteTableResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/route-tables/{id} [put]
+func (h *RouteTableHandler) UpdateRouteTable(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.UpdateRouteTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	routeTable, err := h.routeTableService.UpdateRouteTable(idStr, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrRouteTableNotFound:
+			response.Error(c, http.StatusNotFound, err, "Route table not found")
+		case errors.ErrRouteTableExists:
+			response.Error(c, http.StatusConflict, err, "Route table already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Route table updated successfully", dto.ToRouteTableResponse(routeTable))
+}
+
+// DeleteRouteTable godoc
+// @Summary Delete route table
+// @Description Delete a route table that is not associated with any subnet. The main route table is deleted with its VPC.
+// @Tags RouteTable
+// @Produce json
+// @Param id path string true "Route table ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/route-tables/{id} [delete]
+func (h *RouteTableHandler) DeleteRouteTable(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.routeTableService.DeleteRouteTable(idStr, userID); err != nil {
+		switch err {
+		case errors.ErrRouteTableNotFound:
+			response.Error(c, http.StatusNotFound, err, "Route table not found")
+		case errors.ErrMainRouteTable:
+			response.Error(c, http.StatusBadRequest, err, "The main route table cannot be deleted")
+		case errors.ErrResourceInUse:
+			response.Error(c, http.StatusConflict, err, "Route table is still associated with subnets")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Route table deleted successfully", nil)
+}
+
+// AddRoute godoc
+// @Summary Add a route
+// @Description Route a destination outside the VPC to an internet gateway, NAT gateway, instance or VPC peering. Peering routes must stay within the peer VPC. The longest matching prefix wins.
+// @Tags RouteTable
+// @Accept json
+// @Produce json
+// @Param id path string true "Route table ID"
+// @Param route body dto.CreateRouteRequest true "Route"
+// @Success 201 {object} response.Response{data=dto.RouteResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/route-tables/{id}/routes [post]
+func (h *RouteTableHandler) AddRoute(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.CreateRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	route, err := h.routeTableService.AddRoute(idStr, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrRouteTableNotFound:
+			response.Error(c, http.StatusNotFound, err, "Route table not found")
+		case errors.ErrInvalidRoute:
+			response.Error(c, http.StatusBadRequest, err, "Route destination must be a valid CIDR block outside the VPC, and inside the peer VPC for peering routes")
+		case errors.ErrInstanceNotFound:
+			response.Error(c, http.StatusNotFound, err, "Target instance not found in the VPC")
+		case errors.ErrInternetGatewayNotFound:
+			response.Error(c, http.StatusNotFound, err, "Target internet gateway is not attached to the VPC")
+		case errors.ErrVPCPeeringNotFound:
+			response.Error(c, http.StatusNotFound, err, "Target VPC peering is not active for the VPC")
+		case errors.ErrRouteExists:
+			response.Error(c, http.StatusConflict, err, "A route to this destination already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Route added successfully", dto.ToRouteResponse(route))
+}
+
+// RemoveRoute godoc
+// @Summary Remove a route
+// @Description Remove a route and reprogram the VPC routing flows
+// @Tags RouteTable
+// @Produce json
+// @Param id path string true "Route table ID"
+// @Param route_id path string true "Route ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/route-tables/{id}/routes/{route_id} [delete]
+func (h *RouteTableHandler) RemoveRoute(c *gin.Context) {
+	idStr := c.Param("id")
+	routeID := c.Param("route_id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.routeTableService.RemoveRoute(idStr, routeID, userID); err != nil {
+		switch err {
+		case errors.ErrRouteTableNotFound:
+			response.Error(c, http.StatusNotFound, err, "Route table not found")
+		case errors.ErrRouteNotFound:
+			response.Error(c, http.StatusNotFound, err, "Route not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Route removed successfully", nil)
+}
+
+// AssociateSubnet godoc
+// @Summary Associate a subnet with a route table
+// @Description Route a subnet's traffic with this table instead of the main route table or a previous association
+// @Tags RouteTable
+// @Accept json
+// @Produce json
+// @Param id path string true "Route table ID"
+// @Param association body dto.RouteTableAssociationRequest true "Subnet to associate"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/route-tables/{id}/subnets [post]
+func (h *RouteTableHandler) AssociateSubnet(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.RouteTableAssociationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.routeTableService.AssociateSubnet(idStr, userID, req.SubnetID); err != nil {
+		switch err {
+		case errors.ErrRouteTableNotFound:
+			response.Error(c, http.StatusNotFound, err, "Route table not found")
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found in the route table VPC")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Subnet associated successfully", nil)
+}
+
+// DisassociateSubnet godoc
+// @Summary Disassociate a subnet from a route table
+// @Description Return a subnet to the main route table of its VPC
+// @Tags RouteTable
+// @Produce json
+// @Param id path string true "Route table ID"
+// @Param subnet_id path string true "Subnet ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/route-tables/{id}/subnets/{subnet_id} [delete]
+func (h *RouteTableHandler) DisassociateSubnet(c *gin.Context) {
+	idStr := c.Param("id")
+	subnetID := c.Param("subnet_id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.routeTableService.DisassociateSubnet(idStr, userID, subnetID); err != nil {
+		switch err {
+		case errors.ErrRouteTableNotFound:
+			response.Error(c, http.StatusNotFound, err, "Route table not found")
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet is not associated with the route table")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Subnet disassociated successfully", nil)
+}
@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type SecurityGroupHandler struct {
+	securityGroupService services.SecurityGroupService
+	logger               *utils.Logger
+}
+
+func NewSecurityGroupHandler(securityGroupService services.SecurityGroupService, logger *utils.Logger) *SecurityGroupHandler {
+	return &SecurityGroupHandler{
+		securityGroupService: securityGroupService,
+		logger:               logger,
+	}
+}
+
+// CreateSecurityGroup godoc
+// @Summary Create a new security group
+// @Description Create an empty security group inside a VPC
+// @Tags SecurityGroup
+// @Accept json
+// @Produce json
+// @Param security_group body dto.CreateSecurityGroupRequest true "Security group creation request"
+// @Success 201 {object} response.Response{data=dto.SecurityGroupResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/security-groups [post]
+func (h *SecurityGroupHandler) CreateSecurityGroup(c *gin.Context) {
+	var req dto.CreateSecurityGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	sg, err := h.securityGroupService.CreateSecurityGroup(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrVPCNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC not found")
+		case errors.ErrSecurityGroupExists:
+			response.Error(c, http.StatusConflict, err, "Security group already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Security group created successfully", dto.ToSecurityGroupResponse(sg))
+}
+
+// GetSecurityGroup godoc
+// @Summary Get security group by ID
+// @Description Get a security group with its inbound and outbound rules
+// @Tags SecurityGroup
+// @Produce json
+// @Param id path string true "Security group ID"
+// @Success 200 {object} response.Response{data=dto.SecurityGroupResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/security-groups/{id} [get]
+func (h *SecurityGroupHandler) GetSecurityGroup(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	sg, err := h.securityGroupService.GetSecurityGroup(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrSecurityGroupNotFound:
+			response.Error(c, http.StatusNotFound, err, "Security group not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Security group retrieved successfully", dto.ToSecurityGroupResponse(sg))
+}
+
+// ListSecurityGroups godoc
+// @Summary List security groups
+// @Description Get a paginated list of security groups, optionally filtered by VPC
+// @Tags SecurityGroup
+// @Produce json
+// @Param vpc_id query string false "VPC ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.SecurityGroupListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/security-groups [get]
+func (h *SecurityGroupHandler) ListSecurityGroups(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var vpcID *string
+	if v := c.Query("vpc_id"); v != "" {
+		vpcID = &v
+	}
+	page, pageSize := getPagination(c)
+
+	result, err := h.securityGroupService.ListSecurityGroups(userID, vpcID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Security groups retrieved successfully", result)
+}
+
+// UpdateSecurityGroup godoc
+// @Summary Update security group
+// @Description Update the name or description of a security group
+// @Tags SecurityGroup
+// @Accept json
+// @Produce json
+// @Param id path string true "Security group ID"
+// @Param security_group body dto.UpdateSecurityGroupRequest true "Security group update request"
+// @Success 200 {object} response.Response{data=dto.SecurityGroupResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/security-groups/{id} [put]
+func (h *SecurityGroupHandler) UpdateSecurityGroup(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.UpdateSecurityGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	sg, err := h.securityGroupService.UpdateSecurityGroup(idStr, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrSecurityGroupNotFound:
+			response.Error(c, http.StatusNotFound, err, "Security group not found")
+		case errors.ErrSecurityGroupExists:
+			response.Error(c, http.StatusConflict, err, "Security group already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Security group updated successfully", dto.ToSecurityGroupResponse(sg))
+}
+
+// DeleteSecurityGroup godoc
+// @Summary Delete security group
+// @Description Delete a security group that has no members and is not referenced by other groups
+// @Tags SecurityGroup
+// @Produce json
+// @Param id path string true "Security group ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/security-groups/{id} [delete]
+func (h *SecurityGroupHandler) DeleteSecurityGroup(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.securityGroupService.DeleteSecurityGroup(idStr, userID); err != nil {
+		switch err {
+		case errors.ErrSecurityGroupNotFound:
+			response.Error(c, http.StatusNotFound, err, "Security group not found")
+		case errors.ErrResourceInUse:
+			response.Error(c, http.StatusConflict, err, "Security group still has members or is referenced by other groups")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Security group deleted successfully", nil)
+}
+
+// AddRule godoc
+// @Summary Add a security group rule
+// @Description Add an inbound or outbound rule. The source is a CIDR block or the ID of a security group in the same VPC.
+// @Tags SecurityGroup
+// @Accept json
+// @Produce json
+// @Param id path string true "Security group ID"
+// @Param rule body dto.SecurityGroupRuleRequest true "Security group rule"
+// @Success 201 {object} response.Response{data=dto.SecurityGroupRuleResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/security-groups/{id}/rules [post]
+func (h *SecurityGroupHandler) AddRule(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.SecurityGroupRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	rule, err := h.securityGroupService.AddRule(idStr, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrSecurityGroupNotFound:
+			response.Error(c, http.StatusNotFound, err, "Security group not found")
+		case errors.ErrInvalidSecurityRule:
+			response.Error(c, http.StatusBadRequest, err, "Invalid security group rule")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Security group rule added successfully", dto.ToSecurityGroupRuleResponse(rule))
+}
+
+// RemoveRule godoc
+// @Summary Remove a security group rule
+// @Description Remove a rule and its flows from the VPC bridge
+// @Tags SecurityGroup
+// @Produce json
+// @Param id path string true "Security group ID"
+// @Param rule_id path string true "Rule ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/security-groups/{id}/rules/{rule_id} [delete]
+func (h *SecurityGroupHandler) RemoveRule(c *gin.Context) {
+	idStr := c.Param("id")
+	ruleID := c.Param("rule_id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.securityGroupService.RemoveRule(idStr, ruleID, userID); err != nil {
+		switch err {
+		case errors.ErrSecurityGroupNotFound:
+			response.Error(c, http.StatusNotFound, err, "Security group not found")
+		case errors.ErrSecurityRuleNotFound:
+			response.Error(c, http.StatusNotFound, err, "Security group rule not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Security group rule removed successfully", nil)
+}
+
+// AddInstance godoc
+// @Summary Attach an instance to a security group
+// @Description Make an instance a member of the group. Its addresses become default-deny except for the group's rules.
+// @Tags SecurityGroup
+// @Accept json
+// @Produce json
+// @Param id path string true "Security group ID"
+// @Param member body dto.SecurityGroupMemberRequest true "Member instance"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/security-groups/{id}/instances [post]
+func (h *SecurityGroupHandler) AddInstance(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.SecurityGroupMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.securityGroupService.AddMember(idStr, userID, req.InstanceID); err != nil {
+		switch err {
+		case errors.ErrSecurityGroupNotFound:
+			response.Error(c, http.StatusNotFound, err, "Security group not found")
+		case errors.ErrInstanceNotFound:
+			response.Error(c, http.StatusNotFound, err, "Instance has no address in the security group VPC")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Instance added to security group successfully", nil)
+}
+
+// RemoveInstance godoc
+// @Summary Detach an instance from a security group
+// @Description Remove an instance from the group and recompile dependent flows
+// @Tags SecurityGroup
+// @Produce json
+// @Param id path string true "Security group ID"
+// @Param instance_id path string true "Instance ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/security-groups/{id}/instances/{instance_id} [delete]
+func (h *SecurityGroupHandler) RemoveInstance(c *gin.Context) {
+	idStr := c.Param("id")
+	instanceID := c.Param("instance_id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.securityGroupService.RemoveMember(idStr, userID, instanceID); err != nil {
+		switch err {
+		case errors.ErrSecurityGroupNotFound:
+			response.Error(c, http.StatusNotFound, err, "Security group not found")
+		case errors.ErrInstanceNotFound:
+			response.Error(c, http.StatusNotFound, err, "Instance is not a member of the security group")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Instance removed from security group successfully", nil)
+}
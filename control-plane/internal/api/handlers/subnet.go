@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type SubnetHandler struct {
+	subnetService services.SubnetService
+	logger        *utils.Logger
+}
+
+func NewSubnetHandler(subnetService services.SubnetService, logger *utils.Logger) *SubnetHandler {
+	return &SubnetHandler{
+		subnetService: subnetService,
+		logger:        logger,
+	}
+}
+
+// CreateSubnet godoc
+// @Summary Create a new subnet
+// @Description Create a subnet inside a VPC. The CIDR block must sit inside the VPC range and must not overlap other subnets. An optional ipv6_cidr_block must be a /64 of the VPC's IPv6 block no other subnet uses.
+// @Tags Subnet
+// @Accept json
+// @Produce json
+// @Param subnet body dto.CreateSubnetRequest true "Subnet creation request"
+// @Success 201 {object} response.Response{data=dto.SubnetResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/subnets [post]
+func (h *SubnetHandler) CreateSubnet(c *gin.Context) {
+	var req dto.CreateSubnetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	if req.VPCID == "" {
+		response.Error(c, http.StatusBadRequest, errors.ErrMissingParameter, "vpc_id is required")
+		return
+	}
+
+	h.createSubnet(c, &req)
+}
+
+// CreateVPCSubnet godoc
+// @Summary Create a subnet in a VPC
+// @Description Create a subnet inside the VPC given in the path
+// @Tags Subnet
+// @Accept json
+// @Produce json
+// @Param id path string true "VPC ID"
+// @Param subnet body dto.CreateSubnetRequest true "Subnet creation request"
+// @Success 201 {object} response.Response{data=dto.SubnetResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/vpcs/{id}/subnets [post]
+func (h *SubnetHandler) CreateVPCSubnet(c *gin.Context) {
+	var req dto.CreateSubnetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	req.VPCID = c.Param("id")
+	h.createSubnet(c, &req)
+}
+
+func (h *SubnetHandler) createSubnet(c *gin.Context, req *dto.CreateSubnetRequest) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	subnet, err := h.subnetService.CreateSubnet(userID, req)
+	if err != nil {
+		switch err {
+		case errors.ErrVPCNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC not found")
+		case errors.ErrInvalidCIDR:
+			response.Error(c, http.StatusBadRequest, err, "Invalid CIDR block")
+		case errors.ErrSubnetCIDROutOfRange:
+			response.Error(c, http.StatusBadRequest, err, "Subnet CIDR block must be inside the VPC CIDR block")
+		case errors.ErrSubnetCIDRConflict:
+			response.Error(c, http.StatusConflict, err, "Subnet CIDR block overlaps with an existing subnet")
+		case errors.ErrSubnetAlreadyExists:
+			response.Error(c, http.StatusConflict, err, "Subnet already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Subnet created successfully", dto.ToSubnetResponse(subnet))
+}
+
+// GetSubnet godoc
+// @Summary Get subnet by ID
+// @Description Get a specific subnet by its ID
+// @Tags Subnet
+// @Produce json
+// @Param id path string true "Subnet ID"
+// @Success 200 {object} response.Response{data=dto.SubnetResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/subnets/{id} [get]
+func (h *SubnetHandler) GetSubnet(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	subnet, err := h.subnetService.GetSubnet(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Subnet retrieved successfully", dto.ToSubnetResponse(subnet))
+}
+
+// ListSubnets godoc
+// @Summary List subnets
+// @Description Get a paginated list of subnets, optionally filtered by VPC
+// @Tags Subnet
+// @Produce json
+// @Param vpc_id query string false "VPC ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.SubnetListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/subnets [get]
+func (h *SubnetHandler) ListSubnets(c *gin.Context) {
+	var vpcID *string
+	if v := c.Query("vpc_id"); v != "" {
+		vpcID = &v
+	}
+	h.listSubnets(c, vpcID)
+}
+
+// ListVPCSubnets godoc
+// @Summary List subnets of a VPC
+// @Description Get a paginated list of subnets inside the VPC given in the path
+// @Tags Subnet
+// @Produce json
+// @Param id path string true "VPC ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.SubnetListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/vpcs/{id}/subnets [get]
+func (h *SubnetHandler) ListVPCSubnets(c *gin.Context) {
+	vpcID := c.Param("id")
+	h.listSubnets(c, &vpcID)
+}
+
+func (h *SubnetHandler) listSubnets(c *gin.Context, vpcID *string) {
+	page, pageSize := getPagination(c)
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	subnetList, err := h.subnetService.ListSubnets(userID, vpcID, page, pageSize)
+	if err != nil {
+		switch err {
+		case errors.ErrVPCNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Subnets retrieved successfully", subnetList)
+}
+
+// UpdateSubnet godoc
+// @Summary Update subnet
+// @Description Update the name or public flag of a subnet. The CIDR block cannot be changed.
+// @Tags Subnet
+// @Accept json
+// @Produce json
+// @Param id path string true "Subnet ID"
+// @Param subnet body dto.UpdateSubnetRequest true "Subnet update request"
+// @Success 200 {object} response.Response{data=dto.SubnetResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/subnets/{id} [put]
+func (h *SubnetHandler) UpdateSubnet(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.UpdateSubnetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	subnet, err := h.subnetService.UpdateSubnet(idStr, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found")
+		case errors.ErrSubnetAlreadyExists:
+			response.Error(c, http.StatusConflict, err, "Subnet already exists")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Subnet updated successfully", dto.ToSubnetResponse(subnet))
+}
+
+// DeleteSubnet godoc
+// @Summary Delete subnet
+// @Description Delete an existing subnet
+// @Tags Subnet
+// @Produce json
+// @Param id path string true "Subnet ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/subnets/{id} [delete]
+func (h *SubnetHandler) DeleteSubnet(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.subnetService.DeleteSubnet(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrSubnetNotFound:
+			response.Error(c, http.StatusNotFound, err, "Subnet not found")
+		case errors.ErrResourceInUse:
+			response.Error(c, http.StatusConflict, err, "Subnet still has allocated IP addresses, a network ACL or a route table association")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Subnet deleted successfully", nil)
+}
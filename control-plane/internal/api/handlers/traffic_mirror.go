@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type TrafficMirrorHandler struct {
+	trafficMirrorService services.TrafficMirrorService
+	logger               *utils.Logger
+}
+
+func NewTrafficMirrorHandler(trafficMirrorService services.TrafficMirrorService, logger *utils.Logger) *TrafficMirrorHandler {
+	return &TrafficMirrorHandler{
+		trafficMirrorService: trafficMirrorService,
+		logger:               logger,
+	}
+}
+
+// CreateTrafficMirrorSession godoc
+// @Summary Create a traffic mirror session
+// @Description Copy the traffic of one of your instances to another instance of its VPC, or to a GRE or ERSPAN endpoint at target_ip with target_key as its GRE key or ERSPAN session ID. A target instance must run on the same node as the source and receives nothing but the mirrored traffic while it is a target. All traffic is mirrored unless filter rules narrow it down.
+// @Tags TrafficMirror
+// @Accept json
+// @Produce json
+// @Param session body dto.CreateTrafficMirrorSessionRequest true "Traffic mirror session creation request"
+// @Success 201 {object} response.Response{data=dto.TrafficMirrorSessionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/traffic-mirror-sessions [post]
+func (h *TrafficMirrorHandler) CreateTrafficMirrorSession(c *gin.Context) {
+	var req dto.CreateTrafficMirrorSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.trafficMirrorService.CreateSession(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrInstanceNotFound:
+			response.Error(c, http.StatusNotFound, err, "Instance not found")
+		case errors.ErrTrafficMirrorNoInterface:
+			response.Error(c, http.StatusBadRequest, err, "Instance has no network interface to mirror")
+		case errors.ErrInvalidTrafficMirrorTarget:
+			response.Error(c, http.StatusBadRequest, err, "Invalid traffic mirror target")
+		case errors.ErrInvalidTrafficMirrorRule:
+			response.Error(c, http.StatusBadRequest, err, "Invalid traffic mirror filter rule")
+		case errors.ErrTrafficMirrorRuleLimit:
+			response.Error(c, http.StatusBadRequest, err, "Traffic mirror session has too many filter rules")
+		case errors.ErrTrafficMirrorConflict:
+			response.Error(c, http.StatusConflict, err, "Instance cannot be both a traffic mirror source and target")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Traffic mirror session created successfully", dto.ToTrafficMirrorSessionResponse(session))
+}
+
+// GetTrafficMirrorSession godoc
+// @Summary Get traffic mirror session by ID
+// @Description Get a traffic mirror session you own along with its filter rules
+// @Tags TrafficMirror
+// @Produce json
+// @Param id path string true "Traffic mirror session ID"
+// @Success 200 {object} response.Response{data=dto.TrafficMirrorSessionResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/traffic-mirror-sessions/{id} [get]
+func (h *TrafficMirrorHandler) GetTrafficMirrorSession(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.trafficMirrorService.GetSession(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrTrafficMirrorSessionNotFound:
+			response.Error(c, http.StatusNotFound, err, "Traffic mirror session not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Traffic mirror session retrieved successfully", dto.ToTrafficMirrorSessionResponse(session))
+}
+
+// ListTrafficMirrorSessions godoc
+// @Summary List traffic mirror sessions
+// @Description Get a paginated list of your traffic mirror sessions, optionally filtered by VPC
+// @Tags TrafficMirror
+// @Produce json
+// @Param vpc_id query string false "VPC ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.TrafficMirrorSessionListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/traffic-mirror-sessions [get]
+func (h *TrafficMirrorHandler) ListTrafficMirrorSessions(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var vpcID *string
+	if v := c.Query("vpc_id"); v != "" {
+		vpcID = &v
+	}
+	page, pageSize := getPagination(c)
+
+	result, err := h.trafficMirrorService.ListSessions(userID, vpcID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Traffic mirror sessions retrieved successfully", result)
+}
+
+// DeleteTrafficMirrorSession godoc
+// @Summary Delete a traffic mirror session
+// @Description Stop mirroring the traffic of a session and delete it along with its filter rules
+// @Tags TrafficMirror
+// @Produce json
+// @Param id path string true "Traffic mirror session ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/traffic-mirror-sessions/{id} [delete]
+func (h *TrafficMirrorHandler) DeleteTrafficMirrorSession(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.trafficMirrorService.DeleteSession(idStr, userID); err != nil {
+		switch err {
+		case errors.ErrTrafficMirrorSessionNotFound:
+			response.Error(c, http.StatusNotFound, err, "Traffic mirror session not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Traffic mirror session deleted successfully", nil)
+}
+
+// AddFilterRule godoc
+// @Summary Add a traffic mirror filter rule
+// @Description Narrow down the traffic a session mirrors. Directions are seen from the source instance and the CIDR block is the remote side of its traffic. A session with rules mirrors only what one of them matches.
+// @Tags TrafficMirror
+// @Accept json
+// @Produce json
+// @Param id path string true "Traffic mirror session ID"
+// @Param rule body dto.TrafficMirrorFilterRuleRequest true "Traffic mirror filter rule"
+// @Success 201 {object} response.Response{data=dto.TrafficMirrorFilterRuleResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/traffic-mirror-sessions/{id}/rules [post]
+func (h *TrafficMirrorHandler) AddFilterRule(c *gin.Context) {
+	idStr := c.Param("id")
+
+	var req dto.TrafficMirrorFilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	rule, err := h.trafficMirrorService.AddFilterRule(idStr, userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrTrafficMirrorSessionNotFound:
+			response.Error(c, http.StatusNotFound, err, "Traffic mirror session not found")
+		case errors.ErrInvalidTrafficMirrorRule:
+			response.Error(c, http.StatusBadRequest, err, "Invalid traffic mirror filter rule")
+		case errors.ErrTrafficMirrorRuleLimit:
+			response.Error(c, http.StatusBadRequest, err, "Traffic mirror session has too many filter rules")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Traffic mirror filter rule added successfully", dto.ToTrafficMirrorFilterRuleResponse(rule))
+}
+
+// RemoveFilterRule godoc
+// @Summary Remove a traffic mirror filter rule
+// @Description Remove a rule from a session. A session left without rules mirrors all traffic again.
+// @Tags TrafficMirror
+// @Produce json
+// @Param id path string true "Traffic mirror session ID"
+// @Param rule_id path string true "Rule ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/traffic-mirror-sessions/{id}/rules/{rule_id} [delete]
+func (h *TrafficMirrorHandler) RemoveFilterRule(c *gin.Context) {
+	idStr := c.Param("id")
+	ruleID := c.Param("rule_id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.trafficMirrorService.DeleteFilterRule(idStr, ruleID, userID); err != nil {
+		switch err {
+		case errors.ErrTrafficMirrorSessionNotFound:
+			response.Error(c, http.StatusNotFound, err, "Traffic mirror session not found")
+		case errors.ErrTrafficMirrorRuleNotFound:
+			response.Error(c, http.StatusNotFound, err, "Traffic mirror filter rule not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Traffic mirror filter rule removed successfully", nil)
+}
@@ -27,7 +27,7 @@ func NewVPCHandler(vpcService services.VPCService, logger *utils.Logger) *VPCHan
 
 // CreateVPC godoc
 // @Summary Create a new VPC
-// @Description Create a new Virtual Private Cloud
+// @Description Create a new Virtual Private Cloud. An optional IPv6 /56 is either given as ipv6_cidr_block, inside fd00::/8 or a prefix delegated to the platform, or generated inside fd00::/8 with assign_ipv6_cidr_block.
 // @Tags VPC
 // @Accept json
 // @Produce json
@@ -227,6 +227,7 @@ func (h *VPCHandler) UpdateVPC(c *gin.Context) {
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /api/v1/vpcs/{id} [delete]
 func (h *VPCHandler) DeleteVPC(c *gin.Context) {
@@ -251,6 +252,8 @@ func (h *VPCHandler) DeleteVPC(c *gin.Context) {
 			response.Error(c, http.StatusNotFound, err, "VPC not found")
 		case errors.ErrUnauthorized:
 			response.Error(c, http.StatusUnauthorized, err, "You don't have permission to delete this VPC")
+		case errors.ErrResourceInUse:
+			response.Error(c, http.StatusConflict, err, "VPC still has an internet gateway attached")
 		default:
 			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
 		}
@@ -259,3 +262,48 @@ func (h *VPCHandler) DeleteVPC(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, "VPC deleted successfully", nil)
 }
+
+// DryRunCIDR godoc
+// @Summary Dry-run a VPC CIDR block
+// @Description Check whether a CIDR block can be used for a new VPC and suggest the next free /16-/28 block inside RFC 1918 space
+// @Tags VPC
+// @Accept json
+// @Produce json
+// @Param request body dto.VPCCIDRDryRunRequest true "CIDR dry-run request"
+// @Success 200 {object} response.Response{data=dto.VPCCIDRDryRunResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/vpcs/dry-run [post]
+func (h *VPCHandler) DryRunCIDR(c *gin.Context) {
+	var req dto.VPCCIDRDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, errors.ErrUnauthorized, "User not authenticated")
+		return
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInvalidID, "Invalid user ID format")
+		return
+	}
+
+	result, err := h.vpcService.DryRunCIDR(userIDStr, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidCIDR:
+			response.Error(c, http.StatusBadRequest, err, "Invalid CIDR block")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "CIDR block checked successfully", result)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type VPCPeeringHandler struct {
+	vpcPeeringService services.VPCPeeringService
+	logger            *utils.Logger
+}
+
+func NewVPCPeeringHandler(vpcPeeringService services.VPCPeeringService, logger *utils.Logger) *VPCPeeringHandler {
+	return &VPCPeeringHandler{
+		vpcPeeringService: vpcPeeringService,
+		logger:            logger,
+	}
+}
+
+// CreateVPCPeering godoc
+// @Summary Request a VPC peering
+// @Description Request a peering of one of your VPCs with another VPC, which may belong to another user named by peer_user_id. The peering is pending until the owner of the peer VPC accepts it. VPCs with overlapping CIDR blocks cannot be peered.
+// @Tags VPCPeering
+// @Accept json
+// @Produce json
+// @Param vpc_peering body dto.CreateVPCPeeringRequest true "VPC peering request"
+// @Success 201 {object} response.Response{data=dto.VPCPeeringResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/vpc-peerings [post]
+func (h *VPCPeeringHandler) CreateVPCPeering(c *gin.Context) {
+	var req dto.CreateVPCPeeringRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	peering, err := h.vpcPeeringService.CreateVPCPeering(userID, &req)
+	if err != nil {
+		switch err {
+		case errors.ErrVPCNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC not found")
+		case errors.ErrVPCPeeringCIDROverlap:
+			response.Error(c, http.StatusBadRequest, err, "VPCs with overlapping CIDR blocks cannot be peered")
+		case errors.ErrVPCPeeringExists:
+			response.Error(c, http.StatusConflict, err, "VPCs are already peered or have a pending peering")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "VPC peering requested successfully", dto.ToVPCPeeringResponse(peering))
+}
+
+// GetVPCPeering godoc
+// @Summary Get VPC peering by ID
+// @Description Get a VPC peering you are the requester or accepter of
+// @Tags VPCPeering
+// @Produce json
+// @Param id path string true "VPC peering ID"
+// @Success 200 {object} response.Response{data=dto.VPCPeeringResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/vpc-peerings/{id} [get]
+func (h *VPCPeeringHandler) GetVPCPeering(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	peering, err := h.vpcPeeringService.GetVPCPeering(idStr, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrVPCPeeringNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC peering not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "VPC peering retrieved successfully", dto.ToVPCPeeringResponse(peering))
+}
+
+// ListVPCPeerings godoc
+// @Summary List VPC peerings
+// @Description Get a paginated list of the VPC peerings you requested or were asked to accept, optionally filtered by VPC
+// @Tags VPCPeering
+// @Produce json
+// @Param vpc_id query string false "VPC ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page" default(20)
+// @Success 200 {object} response.Response{data=dto.VPCPeeringListResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/vpc-peerings [get]
+func (h *VPCPeeringHandler) ListVPCPeerings(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var vpcID *string
+	if v := c.Query("vpc_id"); v != "" {
+		vpcID = &v
+	}
+	page, pageSize := getPagination(c)
+
+	result, err := h.vpcPeeringService.ListVPCPeerings(userID, vpcID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "VPC peerings retrieved successfully", result)
+}
+
+// AcceptVPCPeering godoc
+// @Summary Accept a VPC peering
+// @Description Accept a pending VPC peering as the owner of the peer VPC. The VPCs are connected, and each can route the other's CIDR block to the peering; traffic is never routed onwards through a peered VPC.
+// @Tags VPCPeering
+// @Produce json
+// @Param id path string true "VPC peering ID"
+// @Success 200 {object} response.Response{data=dto.VPCPeeringResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/vpc-peerings/{id}/accept [post]
+func (h *VPCPeeringHandler) AcceptVPCPeering(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	peering, err := h.vpcPeeringService.AcceptVPCPeering(idStr, userID)
+	if err != nil {
+		h.respondDecisionError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "VPC peering accepted successfully", dto.ToVPCPeeringResponse(peering))
+}
+
+// RejectVPCPeering godoc
+// @Summary Reject a VPC peering
+// @Description Reject a pending VPC peering as the owner of the peer VPC
+// @Tags VPCPeering
+// @Produce json
+// @Param id path string true "VPC peering ID"
+// @Success 200 {object} response.Response{data=dto.VPCPeeringResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/vpc-peerings/{id}/reject [post]
+func (h *VPCPeeringHandler) RejectVPCPeering(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	peering, err := h.vpcPeeringService.RejectVPCPeering(idStr, userID)
+	if err != nil {
+		h.respondDecisionError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "VPC peering rejected successfully", dto.ToVPCPeeringResponse(peering))
+}
+
+// DeleteVPCPeering godoc
+// @Summary Delete VPC peering
+// @Description Delete a VPC peering as either of its owners. The VPCs are disconnected and routes targeting the peering become blackholes.
+// @Tags VPCPeering
+// @Produce json
+// @Param id path string true "VPC peering ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/vpc-peerings/{id} [delete]
+func (h *VPCPeeringHandler) DeleteVPCPeering(c *gin.Context) {
+	idStr := c.Param("id")
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.vpcPeeringService.DeleteVPCPeering(idStr, userID); err != nil {
+		switch err {
+		case errors.ErrVPCPeeringNotFound:
+			response.Error(c, http.StatusNotFound, err, "VPC peering not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "VPC peering deleted successfully", nil)
+}
+
+// respondDecisionError writes the response to a failed accept or reject
+func (h *VPCPeeringHandler) respondDecisionError(c *gin.Context, err error) {
+	switch err {
+	case errors.ErrVPCPeeringNotFound:
+		response.Error(c, http.StatusNotFound, err, "VPC peering not found")
+	case errors.ErrVPCPeeringNotAccepter:
+		response.Error(c, http.StatusForbidden, err, "Only the owner of the peer VPC can accept or reject the peering")
+	case errors.ErrVPCPeeringNotPending:
+		response.Error(c, http.StatusConflict, err, "VPC peering is not pending acceptance")
+	default:
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+	}
+}
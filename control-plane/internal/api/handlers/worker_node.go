@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/services"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+	"gon-cloud-platform/control-plane/pkg/response"
+)
+
+type WorkerNodeHandler struct {
+	overlayService services.OverlayService
+	logger         *utils.Logger
+}
+
+func NewWorkerNodeHandler(overlayService services.OverlayService, logger *utils.Logger) *WorkerNodeHandler {
+	return &WorkerNodeHandler{
+		overlayService: overlayService,
+		logger:         logger,
+	}
+}
+
+// RegisterWorkerNode godoc
+// @Summary Register a worker node
+// @Description Add a worker node to the tunnel mesh. VPC bridges exchange tunnel traffic with it over its tunnel IP. Administrators only.
+// @Tags WorkerNode
+// @Accept json
+// @Produce json
+// @Param node body dto.RegisterWorkerNodeRequest true "Worker node registration request"
+// @Success 201 {object} response.Response{data=dto.WorkerNodeResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/admin/worker-nodes [post]
+func (h *WorkerNodeHandler) RegisterWorkerNode(c *gin.Context) {
+	var req dto.RegisterWorkerNodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, errors.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	node, err := h.overlayService.RegisterNode(&req)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidTunnelIP:
+			response.Error(c, http.StatusBadRequest, err, "Tunnel IP must be an IPv4 address")
+		case errors.ErrWorkerNodeExists:
+			response.Error(c, http.StatusConflict, err, "Worker node name or tunnel IP already registered")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Worker node registered successfully", dto.ToWorkerNodeResponse(node))
+}
+
+// ListWorkerNodes godoc
+// @Summary List worker nodes
+// @Description List the worker nodes of the tunnel mesh. Administrators only.
+// @Tags WorkerNode
+// @Produce json
+// @Success 200 {object} response.Response{data=[]dto.WorkerNodeResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/admin/worker-nodes [get]
+func (h *WorkerNodeHandler) ListWorkerNodes(c *gin.Context) {
+	nodes, err := h.overlayService.ListNodes()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Worker nodes retrieved successfully", nodes)
+}
+
+// GetWorkerNode godoc
+// @Summary Get worker node by ID
+// @Description Get a worker node with its tunnel IP. Administrators only.
+// @Tags WorkerNode
+// @Produce json
+// @Param id path string true "Worker node ID"
+// @Success 200 {object} response.Response{data=dto.WorkerNodeResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/worker-nodes/{id} [get]
+func (h *WorkerNodeHandler) GetWorkerNode(c *gin.Context) {
+	idStr := c.Param("id")
+
+	node, err := h.overlayService.GetNode(idStr)
+	if err != nil {
+		switch err {
+		case errors.ErrWorkerNodeNotFound:
+			response.Error(c, http.StatusNotFound, err, "Worker node not found")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Worker node retrieved successfully", dto.ToWorkerNodeResponse(node))
+}
+
+// RemoveWorkerNode godoc
+// @Summary Remove a worker node
+// @Description Take a worker node that runs no instances out of the tunnel mesh. Administrators only.
+// @Tags WorkerNode
+// @Produce json
+// @Param id path string true "Worker node ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/admin/worker-nodes/{id} [delete]
+func (h *WorkerNodeHandler) RemoveWorkerNode(c *gin.Context) {
+	idStr := c.Param("id")
+
+	if err := h.overlayService.RemoveNode(idStr); err != nil {
+		switch err {
+		case errors.ErrWorkerNodeNotFound:
+			response.Error(c, http.StatusNotFound, err, "Worker node not found")
+		case errors.ErrResourceInUse:
+			response.Error(c, http.StatusConflict, err, "Worker node still runs instances")
+		default:
+			response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Worker node removed successfully", nil)
+}
+
+// RebuildTunnelMesh godoc
+// @Summary Rebuild the tunnel mesh
+// @Description Reprogram the tunnel ports and overlay flows of every VPC bridge from the worker node registry. Administrators only.
+// @Tags WorkerNode
+// @Produce json
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/admin/worker-nodes/rebuild-mesh [post]
+func (h *WorkerNodeHandler) RebuildTunnelMesh(c *gin.Context) {
+	if err := h.overlayService.RebuildMesh(); err != nil {
+		response.Error(c, http.StatusInternalServerError, errors.ErrInternalServer, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Tunnel mesh rebuilt successfully", nil)
+}
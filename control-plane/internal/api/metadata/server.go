@@ -0,0 +1,143 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+)
+
+// Server serves the metadata service on the VPC bridges of this node. Each
+// bridge gets a listener on the metadata address in the namespace of its
+// DHCP and DNS responder, so every request is known to come from the VPC of
+// the listener it arrived on.
+type Server struct {
+	handler *handlers.MetadataHandler
+	logger  *utils.Logger
+
+	mu      sync.Mutex
+	servers map[string]*http.Server // by bridge name
+}
+
+func NewServer(handler *handlers.MetadataHandler, logger *utils.Logger) *Server {
+	return &Server{
+		handler: handler,
+		logger:  logger,
+		servers: make(map[string]*http.Server),
+	}
+}
+
+// Serve starts serving the VPC of a bridge unless it is served already, and
+// reports whether it started. The namespace must exist, which syncing the
+// bridge's responder sees to.
+func (s *Server) Serve(bridgeName, vpcID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.servers[bridgeName]; ok {
+		return false, nil
+	}
+
+	address := net.JoinHostPort(network.MetadataAddress, strconv.Itoa(network.MetadataPort))
+	listener, err := network.ListenInNamespace(network.DHCPNamespace(bridgeName), address)
+	if err != nil {
+		return false, err
+	}
+
+	server := &http.Server{
+		Handler:      s.router(vpcID),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	s.servers[bridgeName] = server
+
+	go func() {
+		err := server.Serve(listener)
+		if err == http.ErrServerClosed {
+			return
+		}
+		// Forget the server, so the next reconcile pass starts it again
+		s.logger.Error("Metadata server failed", "error", err, "bridge_name", bridgeName, "vpc_id", vpcID)
+		s.mu.Lock()
+		if s.servers[bridgeName] == server {
+			delete(s.servers, bridgeName)
+		}
+		s.mu.Unlock()
+	}()
+
+	return true, nil
+}
+
+// Stop stops serving the VPC of a bridge
+func (s *Server) Stop(bridgeName string) error {
+	s.mu.Lock()
+	server, ok := s.servers[bridgeName]
+	delete(s.servers, bridgeName)
+	s.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	if err := server.Close(); err != nil {
+		return fmt.Errorf("failed to stop metadata server: %w", err)
+	}
+	return nil
+}
+
+// List returns the bridges being served
+func (s *Server) List() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bridges := make([]string, 0, len(s.servers))
+	for bridgeName := range s.servers {
+		bridges = append(bridges, bridgeName)
+	}
+	return bridges
+}
+
+// Shutdown gracefully stops serving every VPC
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	servers := s.servers
+	s.servers = make(map[string]*http.Server)
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// router returns the routes of the metadata service for the VPC of one
+// listener
+func (s *Server) router(vpcID string) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(func(c *gin.Context) {
+		c.Set("vpc_id", vpcID)
+		c.Next()
+	})
+
+	latest := router.Group("/latest")
+	{
+		latest.PUT("/api/token", s.handler.CreateToken)
+		latest.GET("/meta-data/*path", s.handler.GetMetadata)
+		latest.GET("/user-data", s.handler.GetUserData)
+	}
+
+	return router
+}
@@ -53,3 +53,16 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		}
 	}
 }
+
+// RequireRole rejects requests whose token does not carry the given role.
+// It must run after AuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != role {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
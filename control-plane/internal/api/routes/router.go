@@ -18,20 +18,67 @@ func SetupRoutes(router *gin.Engine, db *database.Connection, mq *messaging.Rabb
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(db.DB)
 	vpcRepo := repositories.NewVPCRepository(db.DB)
+	subnetRepo := repositories.NewSubnetRepository(db.DB)
+	ipAllocationRepo := repositories.NewIPAllocationRepository(db.DB)
+	securityGroupRepo := repositories.NewSecurityGroupRepository(db.DB)
+	networkACLRepo := repositories.NewNetworkACLRepository(db.DB)
+	routeTableRepo := repositories.NewRouteTableRepository(db.DB)
+	igwRepo := repositories.NewInternetGatewayRepository(db.DB)
+	eipRepo := repositories.NewElasticIPRepository(db.DB)
+	natRepo := repositories.NewNATGatewayRepository(db.DB)
+	peeringRepo := repositories.NewVPCPeeringRepository(db.DB)
+	workerNodeRepo := repositories.NewWorkerNodeRepository(db.DB)
+	instanceRepo := repositories.NewInstanceRepository(db.DB)
+	flowLogRepo := repositories.NewFlowLogRepository(db.DB)
+	trafficMirrorRepo := repositories.NewTrafficMirrorRepository(db.DB)
 
 	// Initialize managers
 	ovsManager := network.NewOVSManager()
+	if config.Network.OVSDBAddress != "" {
+		ovsdbManager, err := network.NewOVSDBManager(config.Network.OVSDBAddress)
+		if err != nil {
+			logger.Warn("Failed to connect to OVSDB, falling back to ovs-vsctl", "error", err, "address", config.Network.OVSDBAddress)
+		} else {
+			ovsManager = ovsdbManager
+		}
+	}
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo, config, logger)
-	vpcService := services.NewVPCService(vpcRepo, ovsManager, logger)
+	overlayService := services.NewOverlayService(workerNodeRepo, ovsManager, config.Network.NodeName, config.Network.TunnelType, logger)
+	vpcService := services.NewVPCService(vpcRepo, routeTableRepo, igwRepo, natRepo, overlayService, ovsManager, config.Network.IPv6Prefixes, logger)
+	subnetService := services.NewSubnetService(subnetRepo, vpcRepo, ipAllocationRepo, networkACLRepo, routeTableRepo, natRepo, logger)
+	ipamService := services.NewIPAMService(ipAllocationRepo, subnetRepo, logger)
+	securityGroupService := services.NewSecurityGroupService(securityGroupRepo, vpcRepo, ipAllocationRepo, ovsManager, logger)
+	networkACLService := services.NewNetworkACLService(networkACLRepo, vpcRepo, subnetRepo, ovsManager, logger)
+	routeTableService := services.NewRouteTableService(routeTableRepo, vpcRepo, subnetRepo, ipAllocationRepo, igwRepo, natRepo, peeringRepo, ovsManager, logger)
+	igwService := services.NewInternetGatewayService(igwRepo, vpcRepo, routeTableRepo, natRepo, ovsManager, config.Network.UplinkBridge, logger)
+	eipService := services.NewElasticIPService(eipRepo, igwService, logger)
+	vpcPeeringService := services.NewVPCPeeringService(peeringRepo, vpcRepo, routeTableService, ovsManager, logger)
+	natService := services.NewNATGatewayService(natRepo, subnetRepo, eipRepo, igwRepo, routeTableRepo, igwService, ovsManager, logger)
+	networkLimitsService := services.NewNetworkLimitsService(instanceRepo, vpcRepo, ovsManager, logger)
+	flowLogService := services.NewFlowLogService(flowLogRepo, vpcRepo, subnetRepo, instanceRepo, ovsManager, config.Network.FlowLogCollector, logger)
+	trafficMirrorService := services.NewTrafficMirrorService(trafficMirrorRepo, vpcRepo, subnetRepo, instanceRepo, ovsManager, logger)
+	reachabilityService := services.NewReachabilityService(vpcRepo, subnetRepo, ipAllocationRepo, networkACLRepo, securityGroupRepo, routeTableRepo, igwRepo, natRepo, routeTableService, ovsManager, logger)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, config, logger)
 	vpcHandler := handlers.NewVPCHandler(vpcService, logger)
-	subnetHandler := handlers.NewSubnetHandler(db, mq)
+	subnetHandler := handlers.NewSubnetHandler(subnetService, logger)
+	ipamHandler := handlers.NewIPAMHandler(ipamService, logger)
 	instanceHandler := handlers.NewInstanceHandler(db, mq)
-	securityGroupHandler := handlers.NewSecurityGroupHandler(db, mq)
+	networkLimitsHandler := handlers.NewNetworkLimitsHandler(networkLimitsService, logger)
+	securityGroupHandler := handlers.NewSecurityGroupHandler(securityGroupService, logger)
+	networkACLHandler := handlers.NewNetworkACLHandler(networkACLService, logger)
+	routeTableHandler := handlers.NewRouteTableHandler(routeTableService, logger)
+	igwHandler := handlers.NewInternetGatewayHandler(igwService, logger)
+	eipHandler := handlers.NewElasticIPHandler(eipService, logger)
+	natHandler := handlers.NewNATGatewayHandler(natService, logger)
+	vpcPeeringHandler := handlers.NewVPCPeeringHandler(vpcPeeringService, logger)
+	flowLogHandler := handlers.NewFlowLogHandler(flowLogService, logger)
+	trafficMirrorHandler := handlers.NewTrafficMirrorHandler(trafficMirrorService, logger)
+	reachabilityHandler := handlers.NewReachabilityHandler(reachabilityService, logger)
+	workerNodeHandler := handlers.NewWorkerNodeHandler(overlayService, logger)
 
 	// Middleware
 	router.Use(middleware.CORS())
@@ -63,9 +110,12 @@ func SetupRoutes(router *gin.Engine, db *database.Connection, mq *messaging.Rabb
 		{
 			vpc.GET("", vpcHandler.ListVPCs)
 			vpc.POST("", vpcHandler.CreateVPC)
+			vpc.POST("/dry-run", vpcHandler.DryRunCIDR)
 			vpc.GET("/:id", vpcHandler.GetVPC)
 			vpc.PUT("/:id", vpcHandler.UpdateVPC)
 			vpc.DELETE("/:id", vpcHandler.DeleteVPC)
+			vpc.GET("/:id/subnets", subnetHandler.ListVPCSubnets)
+			vpc.POST("/:id/subnets", subnetHandler.CreateVPCSubnet)
 		}
 
 		// Subnet routes
@@ -76,6 +126,10 @@ func SetupRoutes(router *gin.Engine, db *database.Connection, mq *messaging.Rabb
 			subnet.GET("/:id", subnetHandler.GetSubnet)
 			subnet.PUT("/:id", subnetHandler.UpdateSubnet)
 			subnet.DELETE("/:id", subnetHandler.DeleteSubnet)
+			subnet.GET("/:id/utilization", ipamHandler.GetSubnetUtilization)
+			subnet.GET("/:id/ip-allocations", ipamHandler.ListIPAllocations)
+			subnet.POST("/:id/ip-allocations", ipamHandler.AllocateIP)
+			subnet.DELETE("/:id/ip-allocations/:ip", ipamHandler.ReleaseIP)
 		}
 
 		// Instance routes
@@ -89,6 +143,7 @@ func SetupRoutes(router *gin.Engine, db *database.Connection, mq *messaging.Rabb
 			instance.POST("/:id/start", instanceHandler.StartInstance)
 			instance.POST("/:id/stop", instanceHandler.StopInstance)
 			instance.POST("/:id/restart", instanceHandler.RestartInstance)
+			instance.GET("/:id/network-limits", networkLimitsHandler.GetNetworkLimits)
 		}
 
 		// Security Group routes
@@ -101,6 +156,107 @@ func SetupRoutes(router *gin.Engine, db *database.Connection, mq *messaging.Rabb
 			sg.DELETE("/:id", securityGroupHandler.DeleteSecurityGroup)
 			sg.POST("/:id/rules", securityGroupHandler.AddRule)
 			sg.DELETE("/:id/rules/:rule_id", securityGroupHandler.RemoveRule)
+			sg.POST("/:id/instances", securityGroupHandler.AddInstance)
+			sg.DELETE("/:id/instances/:instance_id", securityGroupHandler.RemoveInstance)
+		}
+
+		// Network ACL routes
+		acl := api.Group("/network-acls")
+		{
+			acl.GET("", networkACLHandler.ListNetworkACLs)
+			acl.POST("", networkACLHandler.CreateNetworkACL)
+			acl.GET("/:id", networkACLHandler.GetNetworkACL)
+			acl.PUT("/:id", networkACLHandler.UpdateNetworkACL)
+			acl.DELETE("/:id", networkACLHandler.DeleteNetworkACL)
+			acl.POST("/:id/entries", networkACLHandler.AddEntry)
+			acl.DELETE("/:id/entries/:entry_id", networkACLHandler.RemoveEntry)
+			acl.POST("/:id/subnets", networkACLHandler.AssociateSubnet)
+			acl.DELETE("/:id/subnets/:subnet_id", networkACLHandler.DisassociateSubnet)
+		}
+
+		// Route table routes
+		rt := api.Group("/route-tables")
+		{
+			rt.GET("", routeTableHandler.ListRouteTables)
+			rt.POST("", routeTableHandler.CreateRouteTable)
+			rt.GET("/:id", routeTableHandler.GetRouteTable)
+			rt.PUT("/:id", routeTableHandler.UpdateRouteTable)
+			rt.DELETE("/:id", routeTableHandler.DeleteRouteTable)
+			rt.POST("/:id/routes", routeTableHandler.AddRoute)
+			rt.DELETE("/:id/routes/:route_id", routeTableHandler.RemoveRoute)
+			rt.POST("/:id/subnets", routeTableHandler.AssociateSubnet)
+			rt.DELETE("/:id/subnets/:subnet_id", routeTableHandler.DisassociateSubnet)
+		}
+
+		// Internet gateway routes
+		igw := api.Group("/internet-gateways")
+		{
+			igw.GET("", igwHandler.ListInternetGateways)
+			igw.POST("", igwHandler.CreateInternetGateway)
+			igw.GET("/:id", igwHandler.GetInternetGateway)
+			igw.PUT("/:id", igwHandler.UpdateInternetGateway)
+			igw.DELETE("/:id", igwHandler.DeleteInternetGateway)
+			igw.POST("/:id/attach", igwHandler.AttachInternetGateway)
+			igw.POST("/:id/detach", igwHandler.DetachInternetGateway)
+		}
+
+		// Elastic IP routes
+		eip := api.Group("/elastic-ips")
+		{
+			eip.GET("", eipHandler.ListElasticIPs)
+			eip.POST("", eipHandler.AllocateElasticIP)
+			eip.GET("/:id", eipHandler.GetElasticIP)
+			eip.DELETE("/:id", eipHandler.ReleaseElasticIP)
+			eip.POST("/:id/associate", eipHandler.AssociateElasticIP)
+			eip.POST("/:id/disassociate", eipHandler.DisassociateElasticIP)
+		}
+
+		// NAT gateway routes
+		nat := api.Group("/nat-gateways")
+		{
+			nat.GET("", natHandler.ListNATGateways)
+			nat.POST("", natHandler.CreateNATGateway)
+			nat.GET("/:id", natHandler.GetNATGateway)
+			nat.DELETE("/:id", natHandler.DeleteNATGateway)
+			nat.GET("/:id/connections", natHandler.GetNATGatewayConnections)
+		}
+
+		// VPC peering routes
+		peering := api.Group("/vpc-peerings")
+		{
+			peering.GET("", vpcPeeringHandler.ListVPCPeerings)
+			peering.POST("", vpcPeeringHandler.CreateVPCPeering)
+			peering.GET("/:id", vpcPeeringHandler.GetVPCPeering)
+			peering.DELETE("/:id", vpcPeeringHandler.DeleteVPCPeering)
+			peering.POST("/:id/accept", vpcPeeringHandler.AcceptVPCPeering)
+			peering.POST("/:id/reject", vpcPeeringHandler.RejectVPCPeering)
+		}
+
+		// Flow log routes
+		flowLogs := api.Group("/flow-logs")
+		{
+			flowLogs.GET("", flowLogHandler.ListFlowLogRecords)
+			flowLogs.GET("/configs", flowLogHandler.ListFlowLogs)
+			flowLogs.POST("/configs", flowLogHandler.CreateFlowLog)
+			flowLogs.GET("/configs/:id", flowLogHandler.GetFlowLog)
+			flowLogs.DELETE("/configs/:id", flowLogHandler.DeleteFlowLog)
+		}
+
+		// Traffic mirror routes
+		trafficMirrors := api.Group("/traffic-mirror-sessions")
+		{
+			trafficMirrors.GET("", trafficMirrorHandler.ListTrafficMirrorSessions)
+			trafficMirrors.POST("", trafficMirrorHandler.CreateTrafficMirrorSession)
+			trafficMirrors.GET("/:id", trafficMirrorHandler.GetTrafficMirrorSession)
+			trafficMirrors.DELETE("/:id", trafficMirrorHandler.DeleteTrafficMirrorSession)
+			trafficMirrors.POST("/:id/rules", trafficMirrorHandler.AddFilterRule)
+			trafficMirrors.DELETE("/:id/rules/:rule_id", trafficMirrorHandler.RemoveFilterRule)
+		}
+
+		// Network diagnostics routes
+		diagnostics := api.Group("/network")
+		{
+			diagnostics.POST("/reachability", reachabilityHandler.AnalyzeReachability)
 		}
 
 		// Instance Types
@@ -109,4 +265,30 @@ func SetupRoutes(router *gin.Engine, db *database.Connection, mq *messaging.Rabb
 		// Images
 		api.GET("/images", instanceHandler.ListImages)
 	}
+
+	// Admin routes (admin role required)
+	admin := router.Group("/api/v1/admin")
+	admin.Use(middleware.AuthMiddleware(config.JWT.Secret), middleware.RequireRole("admin"))
+	{
+		pools := admin.Group("/public-ip-pools")
+		{
+			pools.GET("", eipHandler.ListPublicIPPools)
+			pools.POST("", eipHandler.CreatePublicIPPool)
+			pools.DELETE("/:id", eipHandler.DeletePublicIPPool)
+		}
+
+		instances := admin.Group("/instances")
+		{
+			instances.PUT("/:id/network-limits", networkLimitsHandler.UpdateNetworkLimits)
+		}
+
+		nodes := admin.Group("/worker-nodes")
+		{
+			nodes.GET("", workerNodeHandler.ListWorkerNodes)
+			nodes.POST("", workerNodeHandler.RegisterWorkerNode)
+			nodes.POST("/rebuild-mesh", workerNodeHandler.RebuildTunnelMesh)
+			nodes.GET("/:id", workerNodeHandler.GetWorkerNode)
+			nodes.DELETE("/:id", workerNodeHandler.RemoveWorkerNode)
+		}
+	}
 }
@@ -96,6 +96,14 @@ func (c *Connection) RunMigrations() error {
 			return fmt.Errorf("failed to begin transaction: %w", err)
 		}
 
+		// Data the migration would fail on is reported before it runs
+		if check, ok := migrationChecks[filename]; ok {
+			if err := check(tx); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("cannot apply migration %s: %w", filename, err)
+			}
+		}
+
 		for _, statement := range statements {
 			statement = strings.TrimSpace(statement)
 			if statement == "" {
@@ -0,0 +1,102 @@
+// control-plane/internal/database/migration_checks.go
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// migrationCheck looks at the data a migration is about to constrain and
+// returns an error telling the operator what to fix when the migration would
+// fail on it. It runs in the migration's transaction.
+type migrationCheck func(tx *sql.Tx) error
+
+// migrationChecks are keyed by migration file name
+var migrationChecks = map[string]migrationCheck{
+	"018_exclude_overlapping_subnet_cidrs.sql": checkOverlappingSubnets,
+	"019_exclude_overlapping_vpc_cidrs.sql":    checkOverlappingVPCs,
+}
+
+// checkOverlappingSubnets lists the pairs of subnets of a VPC whose blocks
+// overlap. They predate the service's overlap check.
+func checkOverlappingSubnets(tx *sql.Tx) error {
+	query := `
+		SELECT a.vpc_id, a.id, a.cidr_block, b.id, b.cidr_block
+		FROM subnets a
+		JOIN subnets b ON b.vpc_id = a.vpc_id AND b.id > a.id
+		WHERE a.cidr_block && b.cidr_block
+		ORDER BY a.vpc_id, a.id, b.id
+	`
+	rows, err := tx.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to look for overlapping subnets: %w", err)
+	}
+	defer rows.Close()
+
+	var conflicts []string
+	for rows.Next() {
+		var vpcID, aID, aCIDR, bID, bCIDR string
+		if err := rows.Scan(&vpcID, &aID, &aCIDR, &bID, &bCIDR); err != nil {
+			return fmt.Errorf("failed to scan overlapping subnets: %w", err)
+		}
+		conflicts = append(conflicts, fmt.Sprintf("VPC %s: subnet %s (%s) overlaps subnet %s (%s)",
+			vpcID, aID, aCIDR, bID, bCIDR))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list overlapping subnets: %w", err)
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d pairs of subnets of the same VPC overlap, which is no longer allowed. "+
+			"Delete one subnet of each pair and restart the control plane:\n%s",
+			len(conflicts), strings.Join(conflicts, "\n"))
+	}
+	return nil
+}
+
+// checkOverlappingVPCs lists the pairs of a user's VPCs whose blocks overlap
+// in either family. They predate the service's overlap check.
+func checkOverlappingVPCs(tx *sql.Tx) error {
+	query := `
+		SELECT a.user_id, a.id, a.cidr_block, COALESCE(a.ipv6_cidr_block::text, ''),
+			b.id, b.cidr_block, COALESCE(b.ipv6_cidr_block::text, '')
+		FROM vpcs a
+		JOIN vpcs b ON b.user_id = a.user_id AND b.id > a.id
+		WHERE a.cidr_block && b.cidr_block
+			OR a.ipv6_cidr_block && b.ipv6_cidr_block
+		ORDER BY a.user_id, a.id, b.id
+	`
+	rows, err := tx.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to look for overlapping VPCs: %w", err)
+	}
+	defer rows.Close()
+
+	var conflicts []string
+	for rows.Next() {
+		var userID, aID, aCIDR, aIPv6, bID, bCIDR, bIPv6 string
+		if err := rows.Scan(&userID, &aID, &aCIDR, &aIPv6, &bID, &bCIDR, &bIPv6); err != nil {
+			return fmt.Errorf("failed to scan overlapping VPCs: %w", err)
+		}
+		conflicts = append(conflicts, fmt.Sprintf("user %s: VPC %s (%s) overlaps VPC %s (%s)",
+			userID, aID, vpcBlocks(aCIDR, aIPv6), bID, vpcBlocks(bCIDR, bIPv6)))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list overlapping VPCs: %w", err)
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d pairs of VPCs of the same user overlap, which is no longer allowed. "+
+			"Delete one VPC of each pair and restart the control plane:\n%s",
+			len(conflicts), strings.Join(conflicts, "\n"))
+	}
+	return nil
+}
+
+func vpcBlocks(cidrBlock, ipv6CIDRBlock string) string {
+	if ipv6CIDRBlock == "" {
+		return cidrBlock
+	}
+	return cidrBlock + ", " + ipv6CIDRBlock
+}
@@ -0,0 +1,410 @@
+// control-plane/internal/database/repositories/elastic_ip_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// InstanceAddress is an instance's placement and addresses as seen by the
+// elastic IP service
+type InstanceAddress struct {
+	InstanceID string  `db:"instance_id"`
+	VPCID      string  `db:"vpc_id"`
+	PrivateIP  *string `db:"private_ip"`
+	PublicIP   *string `db:"public_ip"`
+}
+
+type ElasticIPRepository interface {
+	// Pools
+	CreatePool(pool *models.PublicIPPool) error
+	GetPoolByID(id string) (*models.PublicIPPool, error)
+	GetPoolByName(name string) (*models.PublicIPPool, error)
+	ListPools() ([]models.PublicIPPool, error)
+	CountByPool(poolID string) (int, error)
+	DeletePool(id string) error
+
+	// Addresses
+	Allocate(poolID string, userID string, pick IPPicker) (*models.ElasticIP, error)
+	GetByID(id string, userID string) (*models.ElasticIP, error)
+	GetByInstance(instanceID string) (*models.ElasticIP, error)
+	List(userID string, page, pageSize int) ([]models.ElasticIP, int, error)
+	Release(id string, userID string) error
+
+	// Association
+	GetInstanceAddress(instanceID string, userID string) (*InstanceAddress, error)
+	Associate(id string, instanceID string) error
+	Disassociate(id string) error
+}
+
+type elasticIPRepository struct {
+	db *sqlx.DB
+}
+
+func NewElasticIPRepository(db *sqlx.DB) ElasticIPRepository {
+	return &elasticIPRepository{db: db}
+}
+
+func (r *elasticIPRepository) CreatePool(pool *models.PublicIPPool) error {
+	query := `
+		INSERT INTO public_ip_pools (id, name, cidr_block, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Exec(query,
+		pool.ID,
+		pool.Name,
+		pool.CIDRBlock,
+		pool.Description,
+		pool.CreatedAt,
+		pool.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create public IP pool: %w", err)
+	}
+
+	return nil
+}
+
+func (r *elasticIPRepository) GetPoolByID(id string) (*models.PublicIPPool, error) {
+	var pool models.PublicIPPool
+	query := `
+		SELECT id, name, cidr_block::text AS cidr_block, description, created_at, updated_at
+		FROM public_ip_pools
+		WHERE id = $1
+	`
+
+	err := r.db.Get(&pool, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get public IP pool by ID: %w", err)
+	}
+
+	return &pool, nil
+}
+
+func (r *elasticIPRepository) GetPoolByName(name string) (*models.PublicIPPool, error) {
+	var pool models.PublicIPPool
+	query := `
+		SELECT id, name, cidr_block::text AS cidr_block, description, created_at, updated_at
+		FROM public_ip_pools
+		WHERE name = $1
+	`
+
+	err := r.db.Get(&pool, query, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get public IP pool by name: %w", err)
+	}
+
+	return &pool, nil
+}
+
+func (r *elasticIPRepository) ListPools() ([]models.PublicIPPool, error) {
+	var pools []models.PublicIPPool
+	query := `
+		SELECT id, name, cidr_block::text AS cidr_block, description, created_at, updated_at
+		FROM public_ip_pools
+		ORDER BY created_at
+	`
+
+	if err := r.db.Select(&pools, query); err != nil {
+		return nil, fmt.Errorf("failed to list public IP pools: %w", err)
+	}
+
+	return pools, nil
+}
+
+func (r *elasticIPRepository) CountByPool(poolID string) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM elastic_ips WHERE pool_id = $1"
+
+	if err := r.db.Get(&count, query, poolID); err != nil {
+		return 0, fmt.Errorf("failed to count elastic IPs: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *elasticIPRepository) DeletePool(id string) error {
+	query := "DELETE FROM public_ip_pools WHERE id = $1"
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete public IP pool: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("public IP pool not found")
+	}
+
+	return nil
+}
+
+// Allocate takes an address from a pool. The pool row is locked for the
+// duration of the transaction so concurrent allocations never pick the same
+// address; the unique constraint on public_ip backs this up. Errors returned
+// by pick are passed through unchanged.
+func (r *elasticIPRepository) Allocate(poolID string, userID string, pick IPPicker) (*models.ElasticIP, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lockedID string
+	if err := tx.Get(&lockedID, "SELECT id FROM public_ip_pools WHERE id = $1 FOR UPDATE", poolID); err != nil {
+		return nil, fmt.Errorf("failed to lock public IP pool: %w", err)
+	}
+
+	var used []string
+	if err := tx.Select(&used, "SELECT host(public_ip) FROM elastic_ips WHERE pool_id = $1", poolID); err != nil {
+		return nil, fmt.Errorf("failed to list allocated addresses: %w", err)
+	}
+
+	publicIP, err := pick(used)
+	if err != nil {
+		return nil, err
+	}
+
+	eip := &models.ElasticIP{
+		ID:          uuid.New().String(),
+		PoolID:      poolID,
+		PublicIP:    publicIP,
+		UserID:      userID,
+		AllocatedAt: time.Now(),
+	}
+
+	query := `
+		INSERT INTO elastic_ips (id, pool_id, public_ip, user_id, allocated_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.Exec(query,
+		eip.ID,
+		eip.PoolID,
+		eip.PublicIP,
+		eip.UserID,
+		eip.AllocatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert elastic IP: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit elastic IP allocation: %w", err)
+	}
+
+	return eip, nil
+}
+
+func (r *elasticIPRepository) GetByID(id string, userID string) (*models.ElasticIP, error) {
+	var eip models.ElasticIP
+	query := `
+		SELECT e.id, e.pool_id, host(e.public_ip) AS public_ip, e.user_id, e.instance_id, n.id AS nat_gateway_id,
+			e.allocated_at, e.associated_at
+		FROM elastic_ips e
+		LEFT JOIN nat_gateways n ON n.elastic_ip_id = e.id
+		WHERE e.id = $1 AND e.user_id = $2
+	`
+
+	err := r.db.Get(&eip, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get elastic IP by ID: %w", err)
+	}
+
+	return &eip, nil
+}
+
+func (r *elasticIPRepository) GetByInstance(instanceID string) (*models.ElasticIP, error) {
+	var eip models.ElasticIP
+	query := `
+		SELECT e.id, e.pool_id, host(e.public_ip) AS public_ip, e.user_id, e.instance_id, n.id AS nat_gateway_id,
+			e.allocated_at, e.associated_at
+		FROM elastic_ips e
+		LEFT JOIN nat_gateways n ON n.elastic_ip_id = e.id
+		WHERE e.instance_id = $1
+	`
+
+	err := r.db.Get(&eip, query, instanceID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get elastic IP by instance: %w", err)
+	}
+
+	return &eip, nil
+}
+
+func (r *elasticIPRepository) List(userID string, page, pageSize int) ([]models.ElasticIP, int, error) {
+	var eips []models.ElasticIP
+	var total int
+
+	// Get total count
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM elastic_ips WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count elastic IPs: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := `
+		SELECT e.id, e.pool_id, host(e.public_ip) AS public_ip, e.user_id, e.instance_id, n.id AS nat_gateway_id,
+			e.allocated_at, e.associated_at
+		FROM elastic_ips e
+		LEFT JOIN nat_gateways n ON n.elastic_ip_id = e.id
+		WHERE e.user_id = $1
+		ORDER BY e.allocated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	err = r.db.Select(&eips, query, userID, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list elastic IPs: %w", err)
+	}
+
+	return eips, total, nil
+}
+
+func (r *elasticIPRepository) Release(id string, userID string) error {
+	query := `
+		DELETE FROM elastic_ips
+		WHERE id = $1 AND user_id = $2 AND instance_id IS NULL
+			AND NOT EXISTS (SELECT 1 FROM nat_gateways WHERE elastic_ip_id = $1)
+	`
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to release elastic IP: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("elastic IP not found or still associated")
+	}
+
+	return nil
+}
+
+// GetInstanceAddress returns the VPC and addresses of a user's instance that
+// has not been terminated
+func (r *elasticIPRepository) GetInstanceAddress(instanceID string, userID string) (*InstanceAddress, error) {
+	var address InstanceAddress
+	query := `
+		SELECT i.id AS instance_id, s.vpc_id, host(i.private_ip) AS private_ip, host(i.public_ip) AS public_ip
+		FROM instances i
+		JOIN subnets s ON s.id = i.subnet_id
+		WHERE i.id = $1 AND i.user_id = $2 AND i.state <> 'terminated'
+	`
+
+	err := r.db.Get(&address, query, instanceID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get instance address: %w", err)
+	}
+
+	return &address, nil
+}
+
+// Associate points an elastic IP at an instance in a single transaction,
+// taking the address away from the instance it was associated with before.
+// The instance's public_ip column is what the NAT flows are compiled from.
+func (r *elasticIPRepository) Associate(id string, instanceID string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous sql.NullString
+	if err := tx.Get(&previous, "SELECT instance_id FROM elastic_ips WHERE id = $1 FOR UPDATE", id); err != nil {
+		return fmt.Errorf("failed to lock elastic IP: %w", err)
+	}
+
+	var natGateways int
+	if err := tx.Get(&natGateways, "SELECT COUNT(*) FROM nat_gateways WHERE elastic_ip_id = $1", id); err != nil {
+		return fmt.Errorf("failed to check NAT gateway usage: %w", err)
+	}
+	if natGateways > 0 {
+		return fmt.Errorf("elastic IP is in use by a NAT gateway")
+	}
+	if previous.Valid && previous.String != instanceID {
+		if _, err := tx.Exec("UPDATE instances SET public_ip = NULL, updated_at = NOW() WHERE id = $1", previous.String); err != nil {
+			return fmt.Errorf("failed to clear previous instance public IP: %w", err)
+		}
+	}
+
+	query := "UPDATE elastic_ips SET instance_id = $1, associated_at = NOW() WHERE id = $2"
+	if _, err := tx.Exec(query, instanceID, id); err != nil {
+		return fmt.Errorf("failed to associate elastic IP: %w", err)
+	}
+
+	query = `
+		UPDATE instances
+		SET public_ip = (SELECT public_ip FROM elastic_ips WHERE id = $1), updated_at = NOW()
+		WHERE id = $2
+	`
+	if _, err := tx.Exec(query, id, instanceID); err != nil {
+		return fmt.Errorf("failed to set instance public IP: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit elastic IP association: %w", err)
+	}
+
+	return nil
+}
+
+func (r *elasticIPRepository) Disassociate(id string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var instanceID sql.NullString
+	if err := tx.Get(&instanceID, "SELECT instance_id FROM elastic_ips WHERE id = $1 FOR UPDATE", id); err != nil {
+		return fmt.Errorf("failed to lock elastic IP: %w", err)
+	}
+	if !instanceID.Valid {
+		return fmt.Errorf("elastic IP is not associated")
+	}
+
+	if _, err := tx.Exec("UPDATE instances SET public_ip = NULL, updated_at = NOW() WHERE id = $1", instanceID.String); err != nil {
+		return fmt.Errorf("failed to clear instance public IP: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE elastic_ips SET instance_id = NULL, associated_at = NULL WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to disassociate elastic IP: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit elastic IP disassociation: %w", err)
+	}
+
+	return nil
+}
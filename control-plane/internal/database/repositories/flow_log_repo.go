@@ -0,0 +1,363 @@
+// control-plane/internal/database/repositories/flow_log_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// FlowLogScope is the address range a flow log covers on its VPC's bridge:
+// the VPC's or subnet's CIDR block, or the instance's private IP as a /32
+type FlowLogScope struct {
+	Number      int    `db:"number"`
+	TrafficType string `db:"traffic_type"`
+	CIDRBlock   string `db:"cidr_block"`
+}
+
+// FlowLogSample is a record as exported by a node, before it is known which
+// flow log it belongs to. Number and ConntrackZone come from the IPFIX
+// observation point and domain.
+type FlowLogSample struct {
+	Number        int
+	ConntrackZone int
+	Action        string
+	Protocol      int
+	SrcIP         string
+	SrcPort       int
+	DstIP         string
+	DstPort       int
+	Packets       int64
+	Bytes         int64
+	StartTime     time.Time
+	EndTime       time.Time
+	NodeName      string
+}
+
+// FlowLogRecordFilter narrows down the records of a user's flow logs. Nil
+// and empty fields do not filter. SrcIP and DstIP may be addresses or CIDR
+// blocks; Port matches either end.
+type FlowLogRecordFilter struct {
+	FlowLogID *string
+	VPCID     *string
+	Action    *string
+	Protocol  *int
+	SrcIP     *string
+	DstIP     *string
+	Port      *int
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+type FlowLogRepository interface {
+	Create(flowLog *models.FlowLog) error
+	GetByID(id string, userID string) (*models.FlowLog, error)
+	List(userID string, vpcID *string, page, pageSize int) ([]models.FlowLog, int, error)
+	Delete(id string) error
+	ListScopes(vpcID string) ([]FlowLogScope, error)
+
+	// Records
+	InsertRecords(samples []FlowLogSample) (int, error)
+	ListRecords(userID string, filter FlowLogRecordFilter, page, pageSize int) ([]models.FlowLogRecord, int, error)
+	DeleteExpiredRecords() (int64, error)
+}
+
+type flowLogRepository struct {
+	db *sqlx.DB
+}
+
+func NewFlowLogRepository(db *sqlx.DB) FlowLogRepository {
+	return &flowLogRepository{db: db}
+}
+
+const flowLogColumns = `
+	id, name, user_id, vpc_id, resource_type, resource_id, traffic_type, number,
+	retention_days, created_at, updated_at
+`
+
+// Create inserts a flow log and fills in the number the database assigned
+func (r *flowLogRepository) Create(flowLog *models.FlowLog) error {
+	query := `
+		INSERT INTO flow_logs (id, name, user_id, vpc_id, resource_type, resource_id, traffic_type, retention_days, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING number
+	`
+
+	err := r.db.QueryRow(query,
+		flowLog.ID,
+		flowLog.Name,
+		flowLog.UserID,
+		flowLog.VPCID,
+		flowLog.ResourceType,
+		flowLog.ResourceID,
+		flowLog.TrafficType,
+		flowLog.RetentionDays,
+		flowLog.CreatedAt,
+		flowLog.UpdatedAt,
+	).Scan(&flowLog.Number)
+
+	if err != nil {
+		return fmt.Errorf("failed to create flow log: %w", err)
+	}
+
+	return nil
+}
+
+func (r *flowLogRepository) GetByID(id string, userID string) (*models.FlowLog, error) {
+	var flowLog models.FlowLog
+	query := `
+		SELECT ` + flowLogColumns + `
+		FROM flow_logs
+		WHERE id = $1 AND user_id = $2
+	`
+
+	err := r.db.Get(&flowLog, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get flow log by ID: %w", err)
+	}
+
+	return &flowLog, nil
+}
+
+func (r *flowLogRepository) List(userID string, vpcID *string, page, pageSize int) ([]models.FlowLog, int, error) {
+	var flowLogs []models.FlowLog
+	var total int
+
+	where := "WHERE user_id = $1"
+	args := []interface{}{userID}
+	if vpcID != nil {
+		where += " AND vpc_id = $2"
+		args = append(args, *vpcID)
+	}
+
+	// Get total count
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM flow_logs "+where, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count flow logs: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT `+flowLogColumns+`
+		FROM flow_logs
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	err = r.db.Select(&flowLogs, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list flow logs: %w", err)
+	}
+
+	return flowLogs, total, nil
+}
+
+// Delete removes a flow log along with its records
+func (r *flowLogRepository) Delete(id string) error {
+	query := "DELETE FROM flow_logs WHERE id = $1"
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete flow log: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("flow log not found")
+	}
+
+	return nil
+}
+
+// ListScopes returns the ranges the flow logs of a VPC cover. Flow logs of
+// deleted subnets and of instances that were terminated or have no address
+// cover nothing and are left out.
+func (r *flowLogRepository) ListScopes(vpcID string) ([]FlowLogScope, error) {
+	var scopes []FlowLogScope
+	query := `
+		SELECT f.number, f.traffic_type, v.cidr_block::text AS cidr_block
+		FROM flow_logs f
+		JOIN vpcs v ON v.id = f.resource_id
+		WHERE f.resource_type = 'vpc' AND f.vpc_id = $1
+		UNION ALL
+		SELECT f.number, f.traffic_type, s.cidr_block::text AS cidr_block
+		FROM flow_logs f
+		JOIN subnets s ON s.id = f.resource_id AND s.vpc_id = f.vpc_id
+		WHERE f.resource_type = 'subnet' AND f.vpc_id = $1
+		UNION ALL
+		SELECT f.number, f.traffic_type, host(i.private_ip) || '/32' AS cidr_block
+		FROM flow_logs f
+		JOIN instances i ON i.id = f.resource_id
+		JOIN subnets s ON s.id = i.subnet_id AND s.vpc_id = f.vpc_id
+		WHERE f.resource_type = 'instance' AND f.vpc_id = $1
+			AND i.state <> 'terminated' AND i.private_ip IS NOT NULL
+		ORDER BY number
+	`
+
+	if err := r.db.Select(&scopes, query, vpcID); err != nil {
+		return nil, fmt.Errorf("failed to list flow log scopes: %w", err)
+	}
+
+	return scopes, nil
+}
+
+// InsertRecords stores samples in one transaction and returns how many were
+// stored. A sample is stored only if its number and conntrack zone still
+// name a flow log and its VPC; samples of deleted flow logs are dropped.
+func (r *flowLogRepository) InsertRecords(samples []FlowLogSample) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO flow_log_records (flow_log_id, vpc_id, action, protocol, src_ip, src_port, dst_ip, dst_port,
+			packets, bytes, start_time, end_time, node_name)
+		SELECT f.id, f.vpc_id, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+		FROM flow_logs f
+		JOIN vpc_conntrack_zones z ON z.vpc_id = f.vpc_id
+		WHERE f.number = $1 AND z.zone = $2
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare flow log record insert: %w", err)
+	}
+	defer stmt.Close()
+
+	stored := 0
+	for _, sample := range samples {
+		result, err := stmt.Exec(
+			sample.Number,
+			sample.ConntrackZone,
+			sample.Action,
+			sample.Protocol,
+			sample.SrcIP,
+			sample.SrcPort,
+			sample.DstIP,
+			sample.DstPort,
+			sample.Packets,
+			sample.Bytes,
+			sample.StartTime,
+			sample.EndTime,
+			sample.NodeName,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert flow log record: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		stored += int(rowsAffected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return stored, nil
+}
+
+// ListRecords returns the records of the user's flow logs that pass the
+// filter, latest first
+func (r *flowLogRepository) ListRecords(userID string, filter FlowLogRecordFilter, page, pageSize int) ([]models.FlowLogRecord, int, error) {
+	var records []models.FlowLogRecord
+	var total int
+
+	where := "WHERE f.user_id = $1"
+	args := []interface{}{userID}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		where += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if filter.FlowLogID != nil {
+		add("r.flow_log_id = $%d", *filter.FlowLogID)
+	}
+	if filter.VPCID != nil {
+		add("r.vpc_id = $%d", *filter.VPCID)
+	}
+	if filter.Action != nil {
+		add("r.action = $%d", *filter.Action)
+	}
+	if filter.Protocol != nil {
+		add("r.protocol = $%d", *filter.Protocol)
+	}
+	if filter.SrcIP != nil {
+		add("r.src_ip <<= $%d::inet", *filter.SrcIP)
+	}
+	if filter.DstIP != nil {
+		add("r.dst_ip <<= $%d::inet", *filter.DstIP)
+	}
+	if filter.Port != nil {
+		args = append(args, *filter.Port)
+		where += fmt.Sprintf(" AND (r.src_port = $%d OR r.dst_port = $%d)", len(args), len(args))
+	}
+	// Records overlapping the time range
+	if filter.StartTime != nil {
+		add("r.end_time >= $%d", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		add("r.start_time <= $%d", *filter.EndTime)
+	}
+	from := "FROM flow_log_records r JOIN flow_logs f ON f.id = r.flow_log_id "
+
+	// Get total count
+	err := r.db.Get(&total, "SELECT COUNT(*) "+from+where, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count flow log records: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT r.id, r.flow_log_id, r.vpc_id, r.action, r.protocol, host(r.src_ip) AS src_ip, r.src_port,
+			host(r.dst_ip) AS dst_ip, r.dst_port, r.packets, r.bytes, r.start_time, r.end_time, r.node_name
+		%s%s
+		ORDER BY r.end_time DESC, r.id DESC
+		LIMIT $%d OFFSET $%d
+	`, from, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	err = r.db.Select(&records, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list flow log records: %w", err)
+	}
+
+	return records, total, nil
+}
+
+// DeleteExpiredRecords removes the records that ended longer ago than the
+// retention of their flow log and returns how many were removed
+func (r *flowLogRepository) DeleteExpiredRecords() (int64, error) {
+	query := `
+		DELETE FROM flow_log_records r
+		USING flow_logs f
+		WHERE f.id = r.flow_log_id AND r.end_time < NOW() - f.retention_days * INTERVAL '1 day'
+	`
+
+	result, err := r.db.Exec(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired flow log records: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// InstanceNetworkLimits is what the bandwidth limits of an instance derive
+// from: the network of its instance type and the overrides of the instance.
+// Overrides are nil where the instance type decides.
+type InstanceNetworkLimits struct {
+	InstanceID   string `db:"instance_id"`
+	UserID       string `db:"user_id"`
+	VPCID        string `db:"vpc_id"`
+	InstanceType string `db:"instance_type"`
+	TypeNetwork  string `db:"type_network"`
+	InboundMbps  *int   `db:"network_inbound_mbps"`
+	OutboundMbps *int   `db:"network_outbound_mbps"`
+	BurstKb      *int   `db:"network_burst_kb"`
+}
+
+type InstanceRepository interface {
+	GetByID(id string, userID string) (*models.Instance, error)
+	GetByAddress(vpcID string, ipAddress string) (*models.Instance, error)
+	ListSecurityGroupNames(instanceID string) ([]string, error)
+
+	// Network limits
+	GetNetworkLimits(instanceID string) (*InstanceNetworkLimits, error)
+	ListNetworkLimits(vpcID string) ([]InstanceNetworkLimits, error)
+	UpdateNetworkLimits(instanceID string, inboundMbps, outboundMbps, burstKb *int) error
+}
+
+type instanceRepository struct {
+	db *sqlx.DB
+}
+
+func NewInstanceRepository(db *sqlx.DB) InstanceRepository {
+	return &instanceRepository{db: db}
+}
+
+// GetByID returns one of the user's instances, unless it has been
+// terminated
+func (r *instanceRepository) GetByID(id string, userID string) (*models.Instance, error) {
+	var instance models.Instance
+	query := `
+		SELECT i.id, i.name, COALESCE(i.instance_type, '') AS instance_type,
+			COALESCE(i.image_id::text, '') AS image_id, COALESCE(i.subnet_id::text, '') AS subnet_id,
+			COALESCE(host(i.private_ip), '') AS private_ip, COALESCE(host(i.public_ip), '') AS public_ip,
+			i.state, COALESCE(i.worker_node_id::text, '') AS worker_node_id, i.user_id,
+			COALESCE(i.key_pair, '') AS key_pair, COALESCE(i.ssh_public_key, '') AS ssh_public_key,
+			COALESCE(i.user_data, '') AS user_data, i.metadata_tokens, i.created_at, i.updated_at
+		FROM instances i
+		WHERE i.id = $1 AND i.user_id = $2 AND i.state <> 'terminated'
+	`
+
+	err := r.db.Get(&instance, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get instance by ID: %w", err)
+	}
+
+	return &instance, nil
+}
+
+// GetByAddress returns the instance an address of a VPC is reserved for,
+// unless it has been terminated. The instance's private IP is reported as
+// that address, as an instance with addresses in several subnets is known
+// by each of them.
+func (r *instanceRepository) GetByAddress(vpcID string, ipAddress string) (*models.Instance, error) {
+	var instance models.Instance
+	query := `
+		SELECT i.id, i.name, COALESCE(i.instance_type, '') AS instance_type,
+			COALESCE(i.image_id::text, '') AS image_id, a.subnet_id,
+			host(a.ip_address) AS private_ip, COALESCE(host(i.public_ip), '') AS public_ip,
+			i.state, COALESCE(i.worker_node_id::text, '') AS worker_node_id, i.user_id,
+			COALESCE(i.key_pair, '') AS key_pair, COALESCE(i.ssh_public_key, '') AS ssh_public_key,
+			COALESCE(i.user_data, '') AS user_data, i.metadata_tokens, i.created_at, i.updated_at
+		FROM ip_allocations a
+		JOIN subnets s ON s.id = a.subnet_id
+		JOIN instances i ON i.id = a.instance_id
+		WHERE s.vpc_id = $1 AND a.ip_address = $2 AND i.state <> 'terminated'
+	`
+
+	err := r.db.Get(&instance, query, vpcID, ipAddress)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get instance by address: %w", err)
+	}
+
+	return &instance, nil
+}
+
+func (r *instanceRepository) ListSecurityGroupNames(instanceID string) ([]string, error) {
+	var names []string
+	query := `
+		SELECT g.name
+		FROM instance_security_groups m
+		JOIN security_groups g ON g.id = m.security_group_id
+		WHERE m.instance_id = $1
+		ORDER BY g.name
+	`
+
+	if err := r.db.Select(&names, query, instanceID); err != nil {
+		return nil, fmt.Errorf("failed to list instance security groups: %w", err)
+	}
+
+	return names, nil
+}
+
+// instanceNetworkLimitsQuery selects the network limits of instances that
+// have not been terminated
+const instanceNetworkLimitsQuery = `
+	SELECT i.id AS instance_id, i.user_id, COALESCE(s.vpc_id::text, '') AS vpc_id,
+		COALESCE(i.instance_type, '') AS instance_type, COALESCE(t.network, '') AS type_network,
+		i.network_inbound_mbps, i.network_outbound_mbps, i.network_burst_kb
+	FROM instances i
+	LEFT JOIN subnets s ON s.id = i.subnet_id
+	LEFT JOIN instance_types t ON t.name = i.instance_type
+	WHERE i.state <> 'terminated'
+`
+
+func (r *instanceRepository) GetNetworkLimits(instanceID string) (*InstanceNetworkLimits, error) {
+	var limits InstanceNetworkLimits
+	query := instanceNetworkLimitsQuery + " AND i.id = $1"
+
+	err := r.db.Get(&limits, query, instanceID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get instance network limits: %w", err)
+	}
+
+	return &limits, nil
+}
+
+// ListNetworkLimits returns the network limits of the instances of a VPC
+func (r *instanceRepository) ListNetworkLimits(vpcID string) ([]InstanceNetworkLimits, error) {
+	var limits []InstanceNetworkLimits
+	query := instanceNetworkLimitsQuery + " AND s.vpc_id = $1 ORDER BY i.id"
+
+	if err := r.db.Select(&limits, query, vpcID); err != nil {
+		return nil, fmt.Errorf("failed to list instance network limits: %w", err)
+	}
+
+	return limits, nil
+}
+
+// UpdateNetworkLimits replaces the overrides of an instance's network
+// limits, nil handing a limit back to the instance type
+func (r *instanceRepository) UpdateNetworkLimits(instanceID string, inboundMbps, outboundMbps, burstKb *int) error {
+	query := `
+		UPDATE instances
+		SET network_inbound_mbps = $1, network_outbound_mbps = $2, network_burst_kb = $3, updated_at = NOW()
+		WHERE id = $4 AND state <> 'terminated'
+	`
+
+	result, err := r.db.Exec(query, inboundMbps, outboundMbps, burstKb, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to update instance network limits: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("instance not found")
+	}
+
+	return nil
+}
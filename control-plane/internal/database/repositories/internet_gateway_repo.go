@@ -0,0 +1,291 @@
+// control-plane/internal/database/repositories/internet_gateway_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// PublicIPMapping pairs an instance's private address with its public address
+type PublicIPMapping struct {
+	PrivateIP string `db:"private_ip"`
+	PublicIP  string `db:"public_ip"`
+}
+
+type InternetGatewayRepository interface {
+	Create(igw *models.InternetGateway) error
+	GetByID(id string, userID string) (*models.InternetGateway, error)
+	GetByName(userID string, name string) (*models.InternetGateway, error)
+	GetByVPC(vpcID string) (*models.InternetGateway, error)
+	List(userID string, page, pageSize int) ([]models.InternetGateway, int, error)
+	Update(id string, userID string, updates map[string]interface{}) error
+	Delete(id string, userID string) error
+
+	// Attachment
+	Attach(id string, vpcID string) error
+	Detach(id string) error
+	ListPublicIPMappings(vpcID string) ([]PublicIPMapping, error)
+}
+
+type internetGatewayRepository struct {
+	db *sqlx.DB
+}
+
+func NewInternetGatewayRepository(db *sqlx.DB) InternetGatewayRepository {
+	return &internetGatewayRepository{db: db}
+}
+
+func (r *internetGatewayRepository) Create(igw *models.InternetGateway) error {
+	query := `
+		INSERT INTO internet_gateways (id, vpc_id, user_id, name, state, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(query,
+		igw.ID,
+		igw.VPCID,
+		igw.UserID,
+		igw.Name,
+		igw.State,
+		igw.CreatedAt,
+		igw.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create internet gateway: %w", err)
+	}
+
+	return nil
+}
+
+func (r *internetGatewayRepository) GetByID(id string, userID string) (*models.InternetGateway, error) {
+	var igw models.InternetGateway
+	query := `
+		SELECT id, vpc_id, user_id, name, state, created_at, updated_at
+		FROM internet_gateways
+		WHERE id = $1 AND user_id = $2
+	`
+
+	err := r.db.Get(&igw, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get internet gateway by ID: %w", err)
+	}
+
+	return &igw, nil
+}
+
+func (r *internetGatewayRepository) GetByName(userID string, name string) (*models.InternetGateway, error) {
+	var igw models.InternetGateway
+	query := `
+		SELECT id, vpc_id, user_id, name, state, created_at, updated_at
+		FROM internet_gateways
+		WHERE user_id = $1 AND name = $2
+	`
+
+	err := r.db.Get(&igw, query, userID, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get internet gateway by name: %w", err)
+	}
+
+	return &igw, nil
+}
+
+// GetByVPC returns the internet gateway attached to a VPC
+func (r *internetGatewayRepository) GetByVPC(vpcID string) (*models.InternetGateway, error) {
+	var igw models.InternetGateway
+	query := `
+		SELECT id, vpc_id, user_id, name, state, created_at, updated_at
+		FROM internet_gateways
+		WHERE vpc_id = $1
+	`
+
+	err := r.db.Get(&igw, query, vpcID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get internet gateway by VPC: %w", err)
+	}
+
+	return &igw, nil
+}
+
+func (r *internetGatewayRepository) List(userID string, page, pageSize int) ([]models.InternetGateway, int, error) {
+	var igws []models.InternetGateway
+	var total int
+
+	// Get total count
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM internet_gateways WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count internet gateways: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := `
+		SELECT id, vpc_id, user_id, name, state, created_at, updated_at
+		FROM internet_gateways
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	err = r.db.Select(&igws, query, userID, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list internet gateways: %w", err)
+	}
+
+	return igws, total, nil
+}
+
+func (r *internetGatewayRepository) Update(id string, userID string, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// Build dynamic update query
+	setParts := make([]string, 0, len(updates)+1)
+	args := make([]interface{}, 0, len(updates)+3)
+	argIndex := 1
+
+	for field, value := range updates {
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", field, argIndex))
+		args = append(args, value)
+		argIndex++
+	}
+
+	// Always update updated_at
+	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now())
+	argIndex++
+
+	// Add WHERE conditions
+	args = append(args, id, userID)
+
+	query := fmt.Sprintf(`
+		UPDATE internet_gateways
+		SET %s
+		WHERE id = $%d AND user_id = $%d
+	`, strings.Join(setParts, ", "), argIndex, argIndex+1)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update internet gateway: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("internet gateway not found or no permission")
+	}
+
+	return nil
+}
+
+func (r *internetGatewayRepository) Delete(id string, userID string) error {
+	query := "DELETE FROM internet_gateways WHERE id = $1 AND user_id = $2"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete internet gateway: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("internet gateway not found or no permission")
+	}
+
+	return nil
+}
+
+// Attach binds an unattached gateway to a VPC. The unique index on vpc_id
+// rejects a second gateway on the same VPC.
+func (r *internetGatewayRepository) Attach(id string, vpcID string) error {
+	query := `
+		UPDATE internet_gateways
+		SET vpc_id = $1, state = 'attached', updated_at = NOW()
+		WHERE id = $2 AND vpc_id IS NULL
+	`
+
+	result, err := r.db.Exec(query, vpcID, id)
+	if err != nil {
+		return fmt.Errorf("failed to attach internet gateway: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("internet gateway not found or already attached")
+	}
+
+	return nil
+}
+
+func (r *internetGatewayRepository) Detach(id string) error {
+	query := `
+		UPDATE internet_gateways
+		SET vpc_id = NULL, state = 'detached', updated_at = NOW()
+		WHERE id = $1 AND vpc_id IS NOT NULL
+	`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to detach internet gateway: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("internet gateway not found or not attached")
+	}
+
+	return nil
+}
+
+// ListPublicIPMappings returns the private and public addresses of every
+// instance in a VPC that has a public IP
+func (r *internetGatewayRepository) ListPublicIPMappings(vpcID string) ([]PublicIPMapping, error) {
+	var mappings []PublicIPMapping
+	query := `
+		SELECT host(i.private_ip) AS private_ip, host(i.public_ip) AS public_ip
+		FROM instances i
+		JOIN subnets s ON s.id = i.subnet_id
+		WHERE s.vpc_id = $1
+		  AND i.private_ip IS NOT NULL
+		  AND i.public_ip IS NOT NULL
+		  AND i.state <> 'terminated'
+		ORDER BY i.private_ip
+	`
+
+	err := r.db.Select(&mappings, query, vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list public IP mappings: %w", err)
+	}
+
+	return mappings, nil
+}
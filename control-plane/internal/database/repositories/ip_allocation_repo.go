@@ -0,0 +1,221 @@
+// control-plane/internal/database/repositories/ip_allocation_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// IPPicker chooses an address given the addresses already allocated in a subnet
+type IPPicker func(used []string) (string, error)
+
+// SubnetIPPicker chooses an address given the addresses already allocated in
+// a subnet, along with the IPv6 address that goes with it, which is empty
+// when the subnet has no IPv6 block
+type SubnetIPPicker func(used []string) (string, string, error)
+
+// InstanceReservation is an address reserved for an instance, with the name of
+// the instance
+type InstanceReservation struct {
+	InstanceID   string  `db:"instance_id"`
+	IPAddress    string  `db:"ip_address"`
+	IPv6Address  *string `db:"ipv6_address"`
+	InstanceName string  `db:"instance_name"`
+}
+
+type IPAllocationRepository interface {
+	Allocate(subnetID string, instanceID *string, description *string, pick SubnetIPPicker) (*models.IPAllocation, error)
+	Release(subnetID string, ipAddress string) error
+	GetByIP(subnetID string, ipAddress string) (*models.IPAllocation, error)
+	ListBySubnet(subnetID string) ([]models.IPAllocation, error)
+	CountBySubnet(subnetID string) (int, error)
+	ListInstanceIPsInVPC(instanceID string, vpcID string) ([]string, error)
+	ListInstanceReservationsInVPC(vpcID string) ([]InstanceReservation, error)
+}
+
+type ipAllocationRepository struct {
+	db *sqlx.DB
+}
+
+func NewIPAllocationRepository(db *sqlx.DB) IPAllocationRepository {
+	return &ipAllocationRepository{db: db}
+}
+
+// Allocate reserves an address in a subnet. The subnet row is locked for the
+// duration of the transaction so concurrent allocations in the same subnet
+// are serialized and never pick the same address; the unique constraint on
+// (subnet_id, ip_address) backs this up. Errors returned by pick are passed
+// through unchanged.
+func (r *ipAllocationRepository) Allocate(subnetID string, instanceID *string, description *string, pick SubnetIPPicker) (*models.IPAllocation, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lockedID string
+	if err := tx.Get(&lockedID, "SELECT id FROM subnets WHERE id = $1 FOR UPDATE", subnetID); err != nil {
+		return nil, fmt.Errorf("failed to lock subnet: %w", err)
+	}
+
+	var used []string
+	if err := tx.Select(&used, "SELECT host(ip_address) FROM ip_allocations WHERE subnet_id = $1", subnetID); err != nil {
+		return nil, fmt.Errorf("failed to list allocated addresses: %w", err)
+	}
+
+	ipAddress, ipv6Address, err := pick(used)
+	if err != nil {
+		return nil, err
+	}
+
+	allocation := &models.IPAllocation{
+		ID:          uuid.New().String(),
+		SubnetID:    subnetID,
+		IPAddress:   ipAddress,
+		InstanceID:  instanceID,
+		Description: description,
+		AllocatedAt: time.Now(),
+	}
+	if ipv6Address != "" {
+		allocation.IPv6Address = &ipv6Address
+	}
+
+	query := `
+		INSERT INTO ip_allocations (id, subnet_id, ip_address, ipv6_address, instance_id, description, allocated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = tx.Exec(query,
+		allocation.ID,
+		allocation.SubnetID,
+		allocation.IPAddress,
+		allocation.IPv6Address,
+		allocation.InstanceID,
+		allocation.Description,
+		allocation.AllocatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert IP allocation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit IP allocation: %w", err)
+	}
+
+	return allocation, nil
+}
+
+func (r *ipAllocationRepository) Release(subnetID string, ipAddress string) error {
+	query := "DELETE FROM ip_allocations WHERE subnet_id = $1 AND ip_address = $2"
+
+	result, err := r.db.Exec(query, subnetID, ipAddress)
+	if err != nil {
+		return fmt.Errorf("failed to release IP address: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("IP allocation not found")
+	}
+
+	return nil
+}
+
+func (r *ipAllocationRepository) GetByIP(subnetID string, ipAddress string) (*models.IPAllocation, error) {
+	var allocation models.IPAllocation
+	query := `
+		SELECT id, subnet_id, host(ip_address) AS ip_address, host(ipv6_address) AS ipv6_address,
+			instance_id, description, allocated_at
+		FROM ip_allocations
+		WHERE subnet_id = $1 AND ip_address = $2
+	`
+
+	err := r.db.Get(&allocation, query, subnetID, ipAddress)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get IP allocation: %w", err)
+	}
+
+	return &allocation, nil
+}
+
+func (r *ipAllocationRepository) ListBySubnet(subnetID string) ([]models.IPAllocation, error) {
+	var allocations []models.IPAllocation
+	query := `
+		SELECT id, subnet_id, host(ip_address) AS ip_address, host(ipv6_address) AS ipv6_address,
+			instance_id, description, allocated_at
+		FROM ip_allocations
+		WHERE subnet_id = $1
+		ORDER BY ip_address
+	`
+
+	err := r.db.Select(&allocations, query, subnetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP allocations: %w", err)
+	}
+
+	return allocations, nil
+}
+
+func (r *ipAllocationRepository) CountBySubnet(subnetID string) (int, error) {
+	var count int
+	err := r.db.Get(&count, "SELECT COUNT(*) FROM ip_allocations WHERE subnet_id = $1", subnetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count IP allocations: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *ipAllocationRepository) ListInstanceIPsInVPC(instanceID string, vpcID string) ([]string, error) {
+	var ips []string
+	query := `
+		SELECT host(a.ip_address)
+		FROM ip_allocations a
+		JOIN subnets s ON s.id = a.subnet_id
+		WHERE a.instance_id = $1 AND s.vpc_id = $2
+	`
+
+	err := r.db.Select(&ips, query, instanceID, vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instance IP addresses: %w", err)
+	}
+
+	return ips, nil
+}
+
+// ListInstanceReservationsInVPC returns the addresses, and IPv6 addresses if
+// any, reserved for instances in every subnet of a VPC, oldest first.
+// Addresses of terminated instances are left out; the name is empty when the
+// instance row is gone.
+func (r *ipAllocationRepository) ListInstanceReservationsInVPC(vpcID string) ([]InstanceReservation, error) {
+	var addresses []InstanceReservation
+	query := `
+		SELECT a.instance_id, host(a.ip_address) AS ip_address, host(a.ipv6_address) AS ipv6_address,
+			COALESCE(i.name, '') AS instance_name
+		FROM ip_allocations a
+		JOIN subnets s ON s.id = a.subnet_id
+		LEFT JOIN instances i ON i.id = a.instance_id
+		WHERE s.vpc_id = $1 AND a.instance_id IS NOT NULL
+		AND (i.state IS NULL OR i.state <> 'terminated')
+		ORDER BY a.allocated_at, a.ip_address
+	`
+
+	err := r.db.Select(&addresses, query, vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instance reservations: %w", err)
+	}
+
+	return addresses, nil
+}
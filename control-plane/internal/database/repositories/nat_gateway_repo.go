@@ -0,0 +1,244 @@
+// control-plane/internal/database/repositories/nat_gateway_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type NATGatewayRepository interface {
+	Create(natGateway *models.NATGateway) error
+	GetByID(id string, userID string) (*models.NATGateway, error)
+	GetByName(userID string, name string) (*models.NATGateway, error)
+	GetInVPC(id string, vpcID string) (*models.NATGateway, error)
+	List(userID string, vpcID *string, page, pageSize int) ([]models.NATGateway, int, error)
+	ListByVPC(vpcID string) ([]models.NATGateway, error)
+	CountByVPC(vpcID string) (int, error)
+	CountBySubnet(subnetID string) (int, error)
+	Delete(id string, userID string) error
+}
+
+type natGatewayRepository struct {
+	db *sqlx.DB
+}
+
+func NewNATGatewayRepository(db *sqlx.DB) NATGatewayRepository {
+	return &natGatewayRepository{db: db}
+}
+
+const natGatewayColumns = `
+	n.id, n.vpc_id, n.subnet_id, n.elastic_ip_id, host(e.public_ip) AS public_ip, n.user_id,
+	n.name, n.state, n.conntrack_zone, n.created_at, n.updated_at
+`
+
+// Create inserts a NAT gateway with the lowest unused conntrack zone, which
+// is written back to natGateway, and claims its elastic IP. The elastic IP
+// row is locked so it cannot be associated with an instance concurrently.
+func (r *natGatewayRepository) Create(natGateway *models.NATGateway) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var instanceID sql.NullString
+	if err := tx.Get(&instanceID, "SELECT instance_id FROM elastic_ips WHERE id = $1 FOR UPDATE", natGateway.ElasticIPID); err != nil {
+		return fmt.Errorf("failed to lock elastic IP: %w", err)
+	}
+	if instanceID.Valid {
+		return fmt.Errorf("elastic IP is associated with an instance")
+	}
+
+	if err := lockConntrackZones(tx); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO nat_gateways (id, vpc_id, subnet_id, elastic_ip_id, user_id, name, state, conntrack_zone, created_at, updated_at)
+		SELECT $1, $2, $3, $4, $5, $6, $7, s.zone, $8, $9
+		FROM generate_series(1, 65535) AS s(zone)
+		WHERE NOT EXISTS (SELECT 1 FROM vpc_conntrack_zones z WHERE z.zone = s.zone)
+			AND NOT EXISTS (SELECT 1 FROM nat_gateways n WHERE n.conntrack_zone = s.zone)
+		ORDER BY s.zone
+		LIMIT 1
+		RETURNING conntrack_zone
+	`
+	err = tx.Get(&natGateway.ConntrackZone, query,
+		natGateway.ID,
+		natGateway.VPCID,
+		natGateway.SubnetID,
+		natGateway.ElasticIPID,
+		natGateway.UserID,
+		natGateway.Name,
+		natGateway.State,
+		natGateway.CreatedAt,
+		natGateway.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("no free conntrack zone")
+		}
+		return fmt.Errorf("failed to create NAT gateway: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit NAT gateway creation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *natGatewayRepository) GetByID(id string, userID string) (*models.NATGateway, error) {
+	var natGateway models.NATGateway
+	query := `
+		SELECT ` + natGatewayColumns + `
+		FROM nat_gateways n
+		JOIN elastic_ips e ON e.id = n.elastic_ip_id
+		WHERE n.id = $1 AND n.user_id = $2
+	`
+
+	err := r.db.Get(&natGateway, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get NAT gateway by ID: %w", err)
+	}
+
+	return &natGateway, nil
+}
+
+func (r *natGatewayRepository) GetByName(userID string, name string) (*models.NATGateway, error) {
+	var natGateway models.NATGateway
+	query := `
+		SELECT ` + natGatewayColumns + `
+		FROM nat_gateways n
+		JOIN elastic_ips e ON e.id = n.elastic_ip_id
+		WHERE n.user_id = $1 AND n.name = $2
+	`
+
+	err := r.db.Get(&natGateway, query, userID, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get NAT gateway by name: %w", err)
+	}
+
+	return &natGateway, nil
+}
+
+// GetInVPC returns a NAT gateway only if it lives in the given VPC
+func (r *natGatewayRepository) GetInVPC(id string, vpcID string) (*models.NATGateway, error) {
+	var natGateway models.NATGateway
+	query := `
+		SELECT ` + natGatewayColumns + `
+		FROM nat_gateways n
+		JOIN elastic_ips e ON e.id = n.elastic_ip_id
+		WHERE n.id = $1 AND n.vpc_id = $2
+	`
+
+	err := r.db.Get(&natGateway, query, id, vpcID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get NAT gateway in VPC: %w", err)
+	}
+
+	return &natGateway, nil
+}
+
+func (r *natGatewayRepository) List(userID string, vpcID *string, page, pageSize int) ([]models.NATGateway, int, error) {
+	var natGateways []models.NATGateway
+	var total int
+
+	where := "WHERE n.user_id = $1"
+	args := []interface{}{userID}
+	if vpcID != nil {
+		where += " AND n.vpc_id = $2"
+		args = append(args, *vpcID)
+	}
+
+	// Get total count
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM nat_gateways n "+where, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count NAT gateways: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT `+natGatewayColumns+`
+		FROM nat_gateways n
+		JOIN elastic_ips e ON e.id = n.elastic_ip_id
+		%s
+		ORDER BY n.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	err = r.db.Select(&natGateways, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list NAT gateways: %w", err)
+	}
+
+	return natGateways, total, nil
+}
+
+func (r *natGatewayRepository) ListByVPC(vpcID string) ([]models.NATGateway, error) {
+	var natGateways []models.NATGateway
+	query := `
+		SELECT ` + natGatewayColumns + `
+		FROM nat_gateways n
+		JOIN elastic_ips e ON e.id = n.elastic_ip_id
+		WHERE n.vpc_id = $1
+		ORDER BY n.created_at
+	`
+
+	if err := r.db.Select(&natGateways, query, vpcID); err != nil {
+		return nil, fmt.Errorf("failed to list NAT gateways by VPC: %w", err)
+	}
+
+	return natGateways, nil
+}
+
+func (r *natGatewayRepository) CountByVPC(vpcID string) (int, error) {
+	var count int
+	if err := r.db.Get(&count, "SELECT COUNT(*) FROM nat_gateways WHERE vpc_id = $1", vpcID); err != nil {
+		return 0, fmt.Errorf("failed to count NAT gateways: %w", err)
+	}
+	return count, nil
+}
+
+func (r *natGatewayRepository) CountBySubnet(subnetID string) (int, error) {
+	var count int
+	if err := r.db.Get(&count, "SELECT COUNT(*) FROM nat_gateways WHERE subnet_id = $1", subnetID); err != nil {
+		return 0, fmt.Errorf("failed to count NAT gateways: %w", err)
+	}
+	return count, nil
+}
+
+func (r *natGatewayRepository) Delete(id string, userID string) error {
+	query := "DELETE FROM nat_gateways WHERE id = $1 AND user_id = $2"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete NAT gateway: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("NAT gateway not found")
+	}
+
+	return nil
+}
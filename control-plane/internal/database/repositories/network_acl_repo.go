@@ -0,0 +1,395 @@
+// control-plane/internal/database/repositories/network_acl_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type NetworkACLRepository interface {
+	Create(acl *models.NetworkACL) error
+	GetByID(id string, userID string) (*models.NetworkACL, error)
+	GetByName(vpcID string, name string) (*models.NetworkACL, error)
+	List(userID string, vpcID *string, page, pageSize int) ([]models.NetworkACL, int, error)
+	ListByVPC(vpcID string) ([]models.NetworkACL, error)
+	Update(id string, userID string, updates map[string]interface{}) error
+	Delete(id string, userID string) error
+
+	// Entries
+	CreateEntry(entry *models.NetworkACLEntry) error
+	GetEntry(networkACLID string, entryID string) (*models.NetworkACLEntry, error)
+	GetEntryByRuleNumber(networkACLID string, direction string, ruleNumber int) (*models.NetworkACLEntry, error)
+	ListEntries(networkACLID string) ([]models.NetworkACLEntry, error)
+	DeleteEntry(networkACLID string, entryID string) error
+
+	// Subnet associations
+	Associate(networkACLID string, subnetID string) error
+	Disassociate(networkACLID string, subnetID string) error
+	GetAssociatedACLID(subnetID string) (*string, error)
+	ListAssociatedSubnets(networkACLID string) ([]models.Subnet, error)
+}
+
+type networkACLRepository struct {
+	db *sqlx.DB
+}
+
+func NewNetworkACLRepository(db *sqlx.DB) NetworkACLRepository {
+	return &networkACLRepository{db: db}
+}
+
+func (r *networkACLRepository) Create(acl *models.NetworkACL) error {
+	query := `
+		INSERT INTO network_acls (id, name, description, vpc_id, user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(query,
+		acl.ID,
+		acl.Name,
+		acl.Description,
+		acl.VPCID,
+		acl.UserID,
+		acl.CreatedAt,
+		acl.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create network ACL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *networkACLRepository) GetByID(id string, userID string) (*models.NetworkACL, error) {
+	var acl models.NetworkACL
+	query := `
+		SELECT id, name, description, vpc_id, user_id, created_at, updated_at
+		FROM network_acls
+		WHERE id = $1 AND user_id = $2
+	`
+
+	err := r.db.Get(&acl, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get network ACL by ID: %w", err)
+	}
+
+	return &acl, nil
+}
+
+func (r *networkACLRepository) GetByName(vpcID string, name string) (*models.NetworkACL, error) {
+	var acl models.NetworkACL
+	query := `
+		SELECT id, name, description, vpc_id, user_id, created_at, updated_at
+		FROM network_acls
+		WHERE vpc_id = $1 AND name = $2
+	`
+
+	err := r.db.Get(&acl, query, vpcID, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get network ACL by name: %w", err)
+	}
+
+	return &acl, nil
+}
+
+func (r *networkACLRepository) List(userID string, vpcID *string, page, pageSize int) ([]models.NetworkACL, int, error) {
+	var acls []models.NetworkACL
+	var total int
+
+	where := "WHERE user_id = $1"
+	args := []interface{}{userID}
+	if vpcID != nil {
+		where += " AND vpc_id = $2"
+		args = append(args, *vpcID)
+	}
+
+	// Get total count
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM network_acls "+where, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count network ACLs: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT id, name, description, vpc_id, user_id, created_at, updated_at
+		FROM network_acls
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	err = r.db.Select(&acls, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list network ACLs: %w", err)
+	}
+
+	return acls, total, nil
+}
+
+// ListByVPC returns every network ACL of a VPC
+func (r *networkACLRepository) ListByVPC(vpcID string) ([]models.NetworkACL, error) {
+	var acls []models.NetworkACL
+	query := `
+		SELECT id, name, description, vpc_id, user_id, created_at, updated_at
+		FROM network_acls
+		WHERE vpc_id = $1
+		ORDER BY created_at
+	`
+
+	if err := r.db.Select(&acls, query, vpcID); err != nil {
+		return nil, fmt.Errorf("failed to list network ACLs by VPC: %w", err)
+	}
+
+	return acls, nil
+}
+
+func (r *networkACLRepository) Update(id string, userID string, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// Build dynamic update query
+	setParts := make([]string, 0, len(updates)+1)
+	args := make([]interface{}, 0, len(updates)+3)
+	argIndex := 1
+
+	for field, value := range updates {
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", field, argIndex))
+		args = append(args, value)
+		argIndex++
+	}
+
+	// Always update updated_at
+	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now())
+	argIndex++
+
+	// Add WHERE conditions
+	args = append(args, id, userID)
+
+	query := fmt.Sprintf(`
+		UPDATE network_acls
+		SET %s
+		WHERE id = $%d AND user_id = $%d
+	`, strings.Join(setParts, ", "), argIndex, argIndex+1)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update network ACL: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("network ACL not found or no permission")
+	}
+
+	return nil
+}
+
+func (r *networkACLRepository) Delete(id string, userID string) error {
+	query := "DELETE FROM network_acls WHERE id = $1 AND user_id = $2"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete network ACL: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("network ACL not found or no permission")
+	}
+
+	return nil
+}
+
+func (r *networkACLRepository) CreateEntry(entry *models.NetworkACLEntry) error {
+	query := `
+		INSERT INTO network_acl_entries (id, network_acl_id, rule_number, direction, protocol, from_port, to_port, cidr_block, action, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.Exec(query,
+		entry.ID,
+		entry.NetworkACLID,
+		entry.RuleNumber,
+		entry.Direction,
+		entry.Protocol,
+		entry.FromPort,
+		entry.ToPort,
+		entry.CIDRBlock,
+		entry.Action,
+		entry.Description,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create network ACL entry: %w", err)
+	}
+
+	return nil
+}
+
+func (r *networkACLRepository) GetEntry(networkACLID string, entryID string) (*models.NetworkACLEntry, error) {
+	var entry models.NetworkACLEntry
+	query := `
+		SELECT id, network_acl_id, rule_number, direction, protocol, from_port, to_port, cidr_block, action, description
+		FROM network_acl_entries
+		WHERE id = $1 AND network_acl_id = $2
+	`
+
+	err := r.db.Get(&entry, query, entryID, networkACLID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get network ACL entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+func (r *networkACLRepository) GetEntryByRuleNumber(networkACLID string, direction string, ruleNumber int) (*models.NetworkACLEntry, error) {
+	var entry models.NetworkACLEntry
+	query := `
+		SELECT id, network_acl_id, rule_number, direction, protocol, from_port, to_port, cidr_block, action, description
+		FROM network_acl_entries
+		WHERE network_acl_id = $1 AND direction = $2 AND rule_number = $3
+	`
+
+	err := r.db.Get(&entry, query, networkACLID, direction, ruleNumber)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get network ACL entry by rule number: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// ListEntries returns the entries of an ACL in evaluation order
+func (r *networkACLRepository) ListEntries(networkACLID string) ([]models.NetworkACLEntry, error) {
+	var entries []models.NetworkACLEntry
+	query := `
+		SELECT id, network_acl_id, rule_number, direction, protocol, from_port, to_port, cidr_block, action, description
+		FROM network_acl_entries
+		WHERE network_acl_id = $1
+		ORDER BY direction, rule_number
+	`
+
+	err := r.db.Select(&entries, query, networkACLID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network ACL entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *networkACLRepository) DeleteEntry(networkACLID string, entryID string) error {
+	query := "DELETE FROM network_acl_entries WHERE id = $1 AND network_acl_id = $2"
+
+	result, err := r.db.Exec(query, entryID, networkACLID)
+	if err != nil {
+		return fmt.Errorf("failed to delete network ACL entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("network ACL entry not found")
+	}
+
+	return nil
+}
+
+// Associate binds a subnet to an ACL, replacing any previous association
+func (r *networkACLRepository) Associate(networkACLID string, subnetID string) error {
+	query := `
+		INSERT INTO network_acl_associations (subnet_id, network_acl_id)
+		VALUES ($1, $2)
+		ON CONFLICT (subnet_id) DO UPDATE SET network_acl_id = EXCLUDED.network_acl_id, created_at = NOW()
+	`
+
+	if _, err := r.db.Exec(query, subnetID, networkACLID); err != nil {
+		return fmt.Errorf("failed to associate network ACL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *networkACLRepository) Disassociate(networkACLID string, subnetID string) error {
+	query := "DELETE FROM network_acl_associations WHERE subnet_id = $1 AND network_acl_id = $2"
+
+	result, err := r.db.Exec(query, subnetID, networkACLID)
+	if err != nil {
+		return fmt.Errorf("failed to disassociate network ACL: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("network ACL association not found")
+	}
+
+	return nil
+}
+
+func (r *networkACLRepository) GetAssociatedACLID(subnetID string) (*string, error) {
+	var aclID string
+	query := "SELECT network_acl_id FROM network_acl_associations WHERE subnet_id = $1"
+
+	err := r.db.Get(&aclID, query, subnetID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get network ACL association: %w", err)
+	}
+
+	return &aclID, nil
+}
+
+func (r *networkACLRepository) ListAssociatedSubnets(networkACLID string) ([]models.Subnet, error) {
+	var subnets []models.Subnet
+	query := `
+		SELECT s.id, s.vpc_id, s.name, s.cidr_block, s.ipv6_cidr_block::text AS ipv6_cidr_block, s.availability_zone, s.is_public, s.created_at, s.updated_at
+		FROM subnets s
+		JOIN network_acl_associations a ON a.subnet_id = s.id
+		WHERE a.network_acl_id = $1
+		ORDER BY s.cidr_block
+	`
+
+	err := r.db.Select(&subnets, query, networkACLID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list associated subnets: %w", err)
+	}
+
+	return subnets, nil
+}
@@ -0,0 +1,459 @@
+// control-plane/internal/database/repositories/route_table_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// SubnetRouteTable pairs a subnet with the route table that applies to it
+type SubnetRouteTable struct {
+	SubnetID      string  `db:"subnet_id"`
+	CIDRBlock     string  `db:"cidr_block"`
+	IPv6CIDRBlock *string `db:"ipv6_cidr_block"`
+	RouteTableID  string  `db:"route_table_id"`
+}
+
+type RouteTableRepository interface {
+	Create(routeTable *models.RouteTable) error
+	GetByID(id string, userID string) (*models.RouteTable, error)
+	GetByName(vpcID string, name string) (*models.RouteTable, error)
+	GetMain(vpcID string) (*models.RouteTable, error)
+	List(userID string, vpcID *string, page, pageSize int) ([]models.RouteTable, int, error)
+	Update(id string, userID string, updates map[string]interface{}) error
+	Delete(id string, userID string) error
+
+	// Routes
+	CreateRoute(route *models.Route) error
+	GetRoute(routeTableID string, routeID string) (*models.Route, error)
+	GetRouteByDestination(routeTableID string, destinationCIDR string) (*models.Route, error)
+	ListRoutes(routeTableID string) ([]models.Route, error)
+	ListRoutesByVPC(vpcID string) ([]models.Route, error)
+	CountRoutesByTarget(targetType string, targetID string) (int, error)
+	DeleteRoute(routeTableID string, routeID string) error
+
+	// Subnet associations
+	Associate(routeTableID string, subnetID string) error
+	Disassociate(routeTableID string, subnetID string) error
+	GetAssociatedTableID(subnetID string) (*string, error)
+	ListAssociatedSubnetIDs(routeTableID string) ([]string, error)
+	ListSubnetRouteTables(vpcID string) ([]SubnetRouteTable, error)
+}
+
+type routeTableRepository struct {
+	db *sqlx.DB
+}
+
+func NewRouteTableRepository(db *sqlx.DB) RouteTableRepository {
+	return &routeTableRepository{db: db}
+}
+
+func (r *routeTableRepository) Create(routeTable *models.RouteTable) error {
+	query := `
+		INSERT INTO route_tables (id, vpc_id, name, is_main, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Exec(query,
+		routeTable.ID,
+		routeTable.VPCID,
+		routeTable.Name,
+		routeTable.IsMain,
+		routeTable.CreatedAt,
+		routeTable.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create route table: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID returns the route table only when its VPC is owned by userID
+func (r *routeTableRepository) GetByID(id string, userID string) (*models.RouteTable, error) {
+	var routeTable models.RouteTable
+	query := `
+		SELECT rt.id, rt.vpc_id, rt.name, rt.is_main, rt.created_at, rt.updated_at
+		FROM route_tables rt
+		JOIN vpcs v ON v.id = rt.vpc_id
+		WHERE rt.id = $1 AND v.user_id = $2
+	`
+
+	err := r.db.Get(&routeTable, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get route table by ID: %w", err)
+	}
+
+	return &routeTable, nil
+}
+
+func (r *routeTableRepository) GetByName(vpcID string, name string) (*models.RouteTable, error) {
+	var routeTable models.RouteTable
+	query := `
+		SELECT id, vpc_id, name, is_main, created_at, updated_at
+		FROM route_tables
+		WHERE vpc_id = $1 AND name = $2
+	`
+
+	err := r.db.Get(&routeTable, query, vpcID, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get route table by name: %w", err)
+	}
+
+	return &routeTable, nil
+}
+
+func (r *routeTableRepository) GetMain(vpcID string) (*models.RouteTable, error) {
+	var routeTable models.RouteTable
+	query := `
+		SELECT id, vpc_id, name, is_main, created_at, updated_at
+		FROM route_tables
+		WHERE vpc_id = $1 AND is_main
+	`
+
+	err := r.db.Get(&routeTable, query, vpcID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get main route table: %w", err)
+	}
+
+	return &routeTable, nil
+}
+
+func (r *routeTableRepository) List(userID string, vpcID *string, page, pageSize int) ([]models.RouteTable, int, error) {
+	var routeTables []models.RouteTable
+	var total int
+
+	where := "WHERE v.user_id = $1"
+	args := []interface{}{userID}
+	if vpcID != nil {
+		where += " AND rt.vpc_id = $2"
+		args = append(args, *vpcID)
+	}
+
+	// Get total count
+	countQuery := "SELECT COUNT(*) FROM route_tables rt JOIN vpcs v ON v.id = rt.vpc_id " + where
+	err := r.db.Get(&total, countQuery, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count route tables: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT rt.id, rt.vpc_id, rt.name, rt.is_main, rt.created_at, rt.updated_at
+		FROM route_tables rt
+		JOIN vpcs v ON v.id = rt.vpc_id
+		%s
+		ORDER BY rt.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	err = r.db.Select(&routeTables, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list route tables: %w", err)
+	}
+
+	return routeTables, total, nil
+}
+
+func (r *routeTableRepository) Update(id string, userID string, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// Build dynamic update query
+	setParts := make([]string, 0, len(updates)+1)
+	args := make([]interface{}, 0, len(updates)+3)
+	argIndex := 1
+
+	for field, value := range updates {
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", field, argIndex))
+		args = append(args, value)
+		argIndex++
+	}
+
+	// Always update updated_at
+	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now())
+	argIndex++
+
+	// Add WHERE conditions
+	args = append(args, id, userID)
+
+	query := fmt.Sprintf(`
+		UPDATE route_tables rt
+		SET %s
+		FROM vpcs v
+		WHERE v.id = rt.vpc_id AND rt.id = $%d AND v.user_id = $%d
+	`, strings.Join(setParts, ", "), argIndex, argIndex+1)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update route table: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("route table not found or no permission")
+	}
+
+	return nil
+}
+
+func (r *routeTableRepository) Delete(id string, userID string) error {
+	query := `
+		DELETE FROM route_tables rt
+		USING vpcs v
+		WHERE v.id = rt.vpc_id AND rt.id = $1 AND v.user_id = $2
+	`
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete route table: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("route table not found or no permission")
+	}
+
+	return nil
+}
+
+func (r *routeTableRepository) CreateRoute(route *models.Route) error {
+	query := `
+		INSERT INTO routes (id, route_table_id, destination_cidr, target_type, target_id, priority)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Exec(query,
+		route.ID,
+		route.RouteTableID,
+		route.DestinationCIDR,
+		route.TargetType,
+		route.TargetID,
+		route.Priority,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create route: %w", err)
+	}
+
+	return nil
+}
+
+func (r *routeTableRepository) GetRoute(routeTableID string, routeID string) (*models.Route, error) {
+	var route models.Route
+	query := `
+		SELECT id, route_table_id, destination_cidr, target_type, target_id, priority
+		FROM routes
+		WHERE id = $1 AND route_table_id = $2
+	`
+
+	err := r.db.Get(&route, query, routeID, routeTableID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get route: %w", err)
+	}
+
+	return &route, nil
+}
+
+func (r *routeTableRepository) GetRouteByDestination(routeTableID string, destinationCIDR string) (*models.Route, error) {
+	var route models.Route
+	query := `
+		SELECT id, route_table_id, destination_cidr, target_type, target_id, priority
+		FROM routes
+		WHERE route_table_id = $1 AND destination_cidr = $2
+	`
+
+	err := r.db.Get(&route, query, routeTableID, destinationCIDR)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get route by destination: %w", err)
+	}
+
+	return &route, nil
+}
+
+// ListRoutes returns the routes of a table, most specific first
+func (r *routeTableRepository) ListRoutes(routeTableID string) ([]models.Route, error) {
+	var routes []models.Route
+	query := `
+		SELECT id, route_table_id, destination_cidr, target_type, target_id, priority
+		FROM routes
+		WHERE route_table_id = $1
+		ORDER BY priority DESC, destination_cidr
+	`
+
+	err := r.db.Select(&routes, query, routeTableID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	return routes, nil
+}
+
+// ListRoutesByVPC returns the routes of every route table in a VPC
+func (r *routeTableRepository) ListRoutesByVPC(vpcID string) ([]models.Route, error) {
+	var routes []models.Route
+	query := `
+		SELECT ro.id, ro.route_table_id, ro.destination_cidr, ro.target_type, ro.target_id, ro.priority
+		FROM routes ro
+		JOIN route_tables rt ON rt.id = ro.route_table_id
+		WHERE rt.vpc_id = $1
+		ORDER BY ro.priority DESC, ro.destination_cidr
+	`
+
+	err := r.db.Select(&routes, query, vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes by VPC: %w", err)
+	}
+
+	return routes, nil
+}
+
+// CountRoutesByTarget counts the routes of any table that forward to a target
+func (r *routeTableRepository) CountRoutesByTarget(targetType string, targetID string) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM routes WHERE target_type = $1 AND target_id = $2"
+
+	if err := r.db.Get(&count, query, targetType, targetID); err != nil {
+		return 0, fmt.Errorf("failed to count routes by target: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *routeTableRepository) DeleteRoute(routeTableID string, routeID string) error {
+	query := "DELETE FROM routes WHERE id = $1 AND route_table_id = $2"
+
+	result, err := r.db.Exec(query, routeID, routeTableID)
+	if err != nil {
+		return fmt.Errorf("failed to delete route: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("route not found")
+	}
+
+	return nil
+}
+
+// Associate binds a subnet to a route table, replacing any previous association
+func (r *routeTableRepository) Associate(routeTableID string, subnetID string) error {
+	query := `
+		INSERT INTO route_table_associations (subnet_id, route_table_id)
+		VALUES ($1, $2)
+		ON CONFLICT (subnet_id) DO UPDATE SET route_table_id = EXCLUDED.route_table_id, created_at = NOW()
+	`
+
+	if _, err := r.db.Exec(query, subnetID, routeTableID); err != nil {
+		return fmt.Errorf("failed to associate route table: %w", err)
+	}
+
+	return nil
+}
+
+func (r *routeTableRepository) Disassociate(routeTableID string, subnetID string) error {
+	query := "DELETE FROM route_table_associations WHERE subnet_id = $1 AND route_table_id = $2"
+
+	result, err := r.db.Exec(query, subnetID, routeTableID)
+	if err != nil {
+		return fmt.Errorf("failed to disassociate route table: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("route table association not found")
+	}
+
+	return nil
+}
+
+func (r *routeTableRepository) GetAssociatedTableID(subnetID string) (*string, error) {
+	var routeTableID string
+	query := "SELECT route_table_id FROM route_table_associations WHERE subnet_id = $1"
+
+	err := r.db.Get(&routeTableID, query, subnetID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get route table association: %w", err)
+	}
+
+	return &routeTableID, nil
+}
+
+func (r *routeTableRepository) ListAssociatedSubnetIDs(routeTableID string) ([]string, error) {
+	var subnetIDs []string
+	query := "SELECT subnet_id FROM route_table_associations WHERE route_table_id = $1 ORDER BY created_at"
+
+	err := r.db.Select(&subnetIDs, query, routeTableID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list associated subnets: %w", err)
+	}
+
+	return subnetIDs, nil
+}
+
+// ListSubnetRouteTables resolves the effective route table of every subnet
+// in a VPC: its explicit association, or the main route table otherwise
+func (r *routeTableRepository) ListSubnetRouteTables(vpcID string) ([]SubnetRouteTable, error) {
+	var pairs []SubnetRouteTable
+	query := `
+		SELECT s.id AS subnet_id, s.cidr_block, s.ipv6_cidr_block::text AS ipv6_cidr_block,
+			COALESCE(a.route_table_id, m.id) AS route_table_id
+		FROM subnets s
+		LEFT JOIN route_table_associations a ON a.subnet_id = s.id
+		JOIN route_tables m ON m.vpc_id = s.vpc_id AND m.is_main
+		WHERE s.vpc_id = $1
+		ORDER BY s.cidr_block
+	`
+
+	err := r.db.Select(&pairs, query, vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subnet route tables: %w", err)
+	}
+
+	return pairs, nil
+}
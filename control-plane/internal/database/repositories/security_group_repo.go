@@ -0,0 +1,427 @@
+// control-plane/internal/database/repositories/security_group_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type SecurityGroupRepository interface {
+	Create(sg *models.SecurityGroup) error
+	GetByID(id string, userID string) (*models.SecurityGroup, error)
+	GetByName(vpcID string, name string) (*models.SecurityGroup, error)
+	List(userID string, vpcID *string, page, pageSize int) ([]models.SecurityGroup, int, error)
+	ListByVPC(vpcID string) ([]models.SecurityGroup, error)
+	Update(id string, userID string, updates map[string]interface{}) error
+	Delete(id string, userID string) error
+
+	// Rules
+	CreateRule(rule *models.SecurityGroupRule) error
+	GetRule(securityGroupID string, ruleID string) (*models.SecurityGroupRule, error)
+	ListRules(securityGroupID string) ([]models.SecurityGroupRule, error)
+	DeleteRule(securityGroupID string, ruleID string) error
+	ListReferencingGroupIDs(securityGroupID string) ([]string, error)
+
+	// Membership
+	AddMember(securityGroupID string, instanceID string) error
+	RemoveMember(securityGroupID string, instanceID string) error
+	IsMember(securityGroupID string, instanceID string) (bool, error)
+	CountMembers(securityGroupID string) (int, error)
+	CountGroupsForInstance(instanceID string) (int, error)
+	ListMemberIPs(securityGroupID string) ([]string, error)
+	ListGroupIDsForIP(vpcID string, ipAddress string) ([]string, error)
+}
+
+type securityGroupRepository struct {
+	db *sqlx.DB
+}
+
+func NewSecurityGroupRepository(db *sqlx.DB) SecurityGroupRepository {
+	return &securityGroupRepository{db: db}
+}
+
+func (r *securityGroupRepository) Create(sg *models.SecurityGroup) error {
+	query := `
+		INSERT INTO security_groups (id, name, description, vpc_id, user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(query,
+		sg.ID,
+		sg.Name,
+		sg.Description,
+		sg.VPCID,
+		sg.UserID,
+		sg.CreatedAt,
+		sg.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create security group: %w", err)
+	}
+
+	return nil
+}
+
+func (r *securityGroupRepository) GetByID(id string, userID string) (*models.SecurityGroup, error) {
+	var sg models.SecurityGroup
+	query := `
+		SELECT id, name, description, vpc_id, user_id, created_at, updated_at
+		FROM security_groups
+		WHERE id = $1 AND user_id = $2
+	`
+
+	err := r.db.Get(&sg, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get security group by ID: %w", err)
+	}
+
+	return &sg, nil
+}
+
+func (r *securityGroupRepository) GetByName(vpcID string, name string) (*models.SecurityGroup, error) {
+	var sg models.SecurityGroup
+	query := `
+		SELECT id, name, description, vpc_id, user_id, created_at, updated_at
+		FROM security_groups
+		WHERE vpc_id = $1 AND name = $2
+	`
+
+	err := r.db.Get(&sg, query, vpcID, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get security group by name: %w", err)
+	}
+
+	return &sg, nil
+}
+
+func (r *securityGroupRepository) List(userID string, vpcID *string, page, pageSize int) ([]models.SecurityGroup, int, error) {
+	var groups []models.SecurityGroup
+	var total int
+
+	where := "WHERE user_id = $1"
+	args := []interface{}{userID}
+	if vpcID != nil {
+		where += " AND vpc_id = $2"
+		args = append(args, *vpcID)
+	}
+
+	// Get total count
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM security_groups "+where, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count security groups: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT id, name, description, vpc_id, user_id, created_at, updated_at
+		FROM security_groups
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	err = r.db.Select(&groups, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list security groups: %w", err)
+	}
+
+	return groups, total, nil
+}
+
+// ListByVPC returns every security group of a VPC
+func (r *securityGroupRepository) ListByVPC(vpcID string) ([]models.SecurityGroup, error) {
+	var groups []models.SecurityGroup
+	query := `
+		SELECT id, name, description, vpc_id, user_id, created_at, updated_at
+		FROM security_groups
+		WHERE vpc_id = $1
+		ORDER BY created_at, id
+	`
+
+	if err := r.db.Select(&groups, query, vpcID); err != nil {
+		return nil, fmt.Errorf("failed to list security groups by VPC: %w", err)
+	}
+
+	return groups, nil
+}
+
+func (r *securityGroupRepository) Update(id string, userID string, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// Build dynamic update query
+	setParts := make([]string, 0, len(updates)+1)
+	args := make([]interface{}, 0, len(updates)+3)
+	argIndex := 1
+
+	for field, value := range updates {
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", field, argIndex))
+		args = append(args, value)
+		argIndex++
+	}
+
+	// Always update updated_at
+	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now())
+	argIndex++
+
+	// Add WHERE conditions
+	args = append(args, id, userID)
+
+	query := fmt.Sprintf(`
+		UPDATE security_groups
+		SET %s
+		WHERE id = $%d AND user_id = $%d
+	`, strings.Join(setParts, ", "), argIndex, argIndex+1)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update security group: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("security group not found or no permission")
+	}
+
+	return nil
+}
+
+func (r *securityGroupRepository) Delete(id string, userID string) error {
+	query := "DELETE FROM security_groups WHERE id = $1 AND user_id = $2"
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete security group: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("security group not found or no permission")
+	}
+
+	return nil
+}
+
+func (r *securityGroupRepository) CreateRule(rule *models.SecurityGroupRule) error {
+	query := `
+		INSERT INTO security_group_rules (id, security_group_id, direction, protocol, from_port, to_port, source, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(query,
+		rule.ID,
+		rule.SecurityGroupID,
+		rule.Direction,
+		rule.Protocol,
+		rule.FromPort,
+		rule.ToPort,
+		rule.Source,
+		rule.Description,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create security group rule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *securityGroupRepository) GetRule(securityGroupID string, ruleID string) (*models.SecurityGroupRule, error) {
+	var rule models.SecurityGroupRule
+	query := `
+		SELECT id, security_group_id, direction, protocol, from_port, to_port, source, description
+		FROM security_group_rules
+		WHERE id = $1 AND security_group_id = $2
+	`
+
+	err := r.db.Get(&rule, query, ruleID, securityGroupID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get security group rule: %w", err)
+	}
+
+	return &rule, nil
+}
+
+func (r *securityGroupRepository) ListRules(securityGroupID string) ([]models.SecurityGroupRule, error) {
+	var rules []models.SecurityGroupRule
+	query := `
+		SELECT id, security_group_id, direction, protocol, from_port, to_port, source, description
+		FROM security_group_rules
+		WHERE security_group_id = $1
+		ORDER BY created_at
+	`
+
+	err := r.db.Select(&rules, query, securityGroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security group rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *securityGroupRepository) DeleteRule(securityGroupID string, ruleID string) error {
+	query := "DELETE FROM security_group_rules WHERE id = $1 AND security_group_id = $2"
+
+	result, err := r.db.Exec(query, ruleID, securityGroupID)
+	if err != nil {
+		return fmt.Errorf("failed to delete security group rule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("security group rule not found")
+	}
+
+	return nil
+}
+
+// ListReferencingGroupIDs returns the other groups with a rule whose source is
+// the given security group
+func (r *securityGroupRepository) ListReferencingGroupIDs(securityGroupID string) ([]string, error) {
+	var ids []string
+	query := `
+		SELECT DISTINCT security_group_id
+		FROM security_group_rules
+		WHERE source = $1 AND security_group_id != $1
+	`
+
+	err := r.db.Select(&ids, query, securityGroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list referencing security groups: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (r *securityGroupRepository) AddMember(securityGroupID string, instanceID string) error {
+	query := `
+		INSERT INTO instance_security_groups (instance_id, security_group_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := r.db.Exec(query, instanceID, securityGroupID); err != nil {
+		return fmt.Errorf("failed to add security group member: %w", err)
+	}
+
+	return nil
+}
+
+func (r *securityGroupRepository) RemoveMember(securityGroupID string, instanceID string) error {
+	query := "DELETE FROM instance_security_groups WHERE instance_id = $1 AND security_group_id = $2"
+
+	if _, err := r.db.Exec(query, instanceID, securityGroupID); err != nil {
+		return fmt.Errorf("failed to remove security group member: %w", err)
+	}
+
+	return nil
+}
+
+func (r *securityGroupRepository) IsMember(securityGroupID string, instanceID string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM instance_security_groups WHERE instance_id = $1 AND security_group_id = $2"
+
+	if err := r.db.Get(&count, query, instanceID, securityGroupID); err != nil {
+		return false, fmt.Errorf("failed to check security group membership: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *securityGroupRepository) CountMembers(securityGroupID string) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM instance_security_groups WHERE security_group_id = $1"
+
+	if err := r.db.Get(&count, query, securityGroupID); err != nil {
+		return 0, fmt.Errorf("failed to count security group members: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *securityGroupRepository) CountGroupsForInstance(instanceID string) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM instance_security_groups WHERE instance_id = $1"
+
+	if err := r.db.Get(&count, query, instanceID); err != nil {
+		return 0, fmt.Errorf("failed to count instance security groups: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListMemberIPs returns the private addresses, IPv4 and IPv6, of every member
+// instance inside the security group's VPC
+func (r *securityGroupRepository) ListMemberIPs(securityGroupID string) ([]string, error) {
+	var ips []string
+	query := `
+		SELECT DISTINCT host(v.address)
+		FROM instance_security_groups isg
+		JOIN security_groups sg ON sg.id = isg.security_group_id
+		JOIN ip_allocations a ON a.instance_id = isg.instance_id
+		JOIN subnets s ON s.id = a.subnet_id AND s.vpc_id = sg.vpc_id
+		CROSS JOIN LATERAL (VALUES (a.ip_address), (a.ipv6_address)) AS v(address)
+		WHERE isg.security_group_id = $1 AND v.address IS NOT NULL
+	`
+
+	err := r.db.Select(&ips, query, securityGroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security group member IPs: %w", err)
+	}
+
+	return ips, nil
+}
+
+// ListGroupIDsForIP returns the security groups of the instance holding a
+// private address in a VPC
+func (r *securityGroupRepository) ListGroupIDsForIP(vpcID string, ipAddress string) ([]string, error) {
+	var ids []string
+	query := `
+		SELECT DISTINCT isg.security_group_id
+		FROM ip_allocations a
+		JOIN subnets s ON s.id = a.subnet_id
+		JOIN instance_security_groups isg ON isg.instance_id = a.instance_id
+		JOIN security_groups sg ON sg.id = isg.security_group_id AND sg.vpc_id = s.vpc_id
+		WHERE s.vpc_id = $1 AND a.ip_address = $2
+		ORDER BY isg.security_group_id
+	`
+
+	err := r.db.Select(&ids, query, vpcID, ipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security groups for IP: %w", err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,234 @@
+// control-plane/internal/database/repositories/subnet_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+type SubnetRepository interface {
+	Create(subnet *models.Subnet) error
+	GetByID(id string, userID string) (*models.Subnet, error)
+	GetByName(vpcID string, name string) (*models.Subnet, error)
+	List(userID string, vpcID *string, page, pageSize int) ([]models.Subnet, int, error)
+	ListByVPC(vpcID string) ([]models.Subnet, error)
+	Update(id string, userID string, updates map[string]interface{}) error
+	Delete(id string, userID string) error
+}
+
+type subnetRepository struct {
+	db *sqlx.DB
+}
+
+func NewSubnetRepository(db *sqlx.DB) SubnetRepository {
+	return &subnetRepository{db: db}
+}
+
+func (r *subnetRepository) Create(subnet *models.Subnet) error {
+	query := `
+		INSERT INTO subnets (id, vpc_id, name, cidr_block, ipv6_cidr_block, availability_zone, is_public, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Exec(query,
+		subnet.ID,
+		subnet.VPCID,
+		subnet.Name,
+		subnet.CIDRBlock,
+		subnet.IPv6CIDRBlock,
+		subnet.AvailabilityZone,
+		subnet.IsPublic,
+		subnet.CreatedAt,
+		subnet.UpdatedAt,
+	)
+
+	if err != nil {
+		if isExclusionViolation(err, "subnets_cidr_block_excl") {
+			return errors.ErrSubnetCIDRConflict
+		}
+		return fmt.Errorf("failed to create subnet: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID returns the subnet only when its VPC is owned by userID
+func (r *subnetRepository) GetByID(id string, userID string) (*models.Subnet, error) {
+	var subnet models.Subnet
+	query := `
+		SELECT s.id, s.vpc_id, s.name, s.cidr_block, s.ipv6_cidr_block::text AS ipv6_cidr_block, s.availability_zone, s.is_public, s.created_at, s.updated_at
+		FROM subnets s
+		JOIN vpcs v ON v.id = s.vpc_id
+		WHERE s.id = $1 AND v.user_id = $2
+	`
+
+	err := r.db.Get(&subnet, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get subnet by ID: %w", err)
+	}
+
+	return &subnet, nil
+}
+
+func (r *subnetRepository) GetByName(vpcID string, name string) (*models.Subnet, error) {
+	var subnet models.Subnet
+	query := `
+		SELECT id, vpc_id, name, cidr_block, ipv6_cidr_block::text AS ipv6_cidr_block, availability_zone, is_public, created_at, updated_at
+		FROM subnets
+		WHERE vpc_id = $1 AND name = $2
+	`
+
+	err := r.db.Get(&subnet, query, vpcID, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get subnet by name: %w", err)
+	}
+
+	return &subnet, nil
+}
+
+func (r *subnetRepository) List(userID string, vpcID *string, page, pageSize int) ([]models.Subnet, int, error) {
+	var subnets []models.Subnet
+	var total int
+
+	where := "WHERE v.user_id = $1"
+	args := []interface{}{userID}
+	if vpcID != nil {
+		where += " AND s.vpc_id = $2"
+		args = append(args, *vpcID)
+	}
+
+	// Get total count
+	countQuery := "SELECT COUNT(*) FROM subnets s JOIN vpcs v ON v.id = s.vpc_id " + where
+	err := r.db.Get(&total, countQuery, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count subnets: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT s.id, s.vpc_id, s.name, s.cidr_block, s.ipv6_cidr_block::text AS ipv6_cidr_block, s.availability_zone, s.is_public, s.created_at, s.updated_at
+		FROM subnets s
+		JOIN vpcs v ON v.id = s.vpc_id
+		%s
+		ORDER BY s.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	err = r.db.Select(&subnets, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list subnets: %w", err)
+	}
+
+	return subnets, total, nil
+}
+
+// ListByVPC returns every subnet of a VPC, used for CIDR overlap checks
+func (r *subnetRepository) ListByVPC(vpcID string) ([]models.Subnet, error) {
+	var subnets []models.Subnet
+	query := `
+		SELECT id, vpc_id, name, cidr_block, ipv6_cidr_block::text AS ipv6_cidr_block, availability_zone, is_public, created_at, updated_at
+		FROM subnets
+		WHERE vpc_id = $1
+		ORDER BY cidr_block
+	`
+
+	err := r.db.Select(&subnets, query, vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subnets by VPC: %w", err)
+	}
+
+	return subnets, nil
+}
+
+func (r *subnetRepository) Update(id string, userID string, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// Build dynamic update query
+	setParts := make([]string, 0, len(updates)+1)
+	args := make([]interface{}, 0, len(updates)+3)
+	argIndex := 1
+
+	for field, value := range updates {
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", field, argIndex))
+		args = append(args, value)
+		argIndex++
+	}
+
+	// Always update updated_at
+	setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now())
+	argIndex++
+
+	// Add WHERE conditions
+	args = append(args, id, userID)
+
+	query := fmt.Sprintf(`
+		UPDATE subnets s
+		SET %s
+		FROM vpcs v
+		WHERE v.id = s.vpc_id AND s.id = $%d AND v.user_id = $%d
+	`, strings.Join(setParts, ", "), argIndex, argIndex+1)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update subnet: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("subnet not found or no permission")
+	}
+
+	return nil
+}
+
+func (r *subnetRepository) Delete(id string, userID string) error {
+	query := `
+		DELETE FROM subnets s
+		USING vpcs v
+		WHERE v.id = s.vpc_id AND s.id = $1 AND v.user_id = $2
+	`
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete subnet: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("subnet not found or no permission")
+	}
+
+	return nil
+}
+
+// isExclusionViolation reports whether err is a violation of the named
+// PostgreSQL exclusion constraint
+func isExclusionViolation(err error, constraint string) bool {
+	return err != nil && strings.Contains(err.Error(), fmt.Sprintf("violates exclusion constraint %q", constraint))
+}
@@ -0,0 +1,222 @@
+// control-plane/internal/database/repositories/traffic_mirror_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+type TrafficMirrorRepository interface {
+	Create(session *models.TrafficMirrorSession) error
+	GetByID(id string, userID string) (*models.TrafficMirrorSession, error)
+	List(userID string, vpcID *string, page, pageSize int) ([]models.TrafficMirrorSession, int, error)
+	ListByVPC(vpcID string) ([]models.TrafficMirrorSession, error)
+	Delete(id string) error
+
+	// Filter rules
+	CreateRule(rule *models.TrafficMirrorFilterRule) error
+	ListRules(sessionID string) ([]models.TrafficMirrorFilterRule, error)
+	DeleteRule(sessionID string, ruleID string) error
+}
+
+type trafficMirrorRepository struct {
+	db *sqlx.DB
+}
+
+func NewTrafficMirrorRepository(db *sqlx.DB) TrafficMirrorRepository {
+	return &trafficMirrorRepository{db: db}
+}
+
+const trafficMirrorColumns = `
+	id, name, user_id, vpc_id, source_instance_id, target_type,
+	COALESCE(target_instance_id::text, '') AS target_instance_id,
+	COALESCE(host(target_ip), '') AS target_ip, target_key, created_at, updated_at
+`
+
+// Create inserts a session. The target instance and IP are stored as NULL
+// when empty, as only one of them applies to a session.
+func (r *trafficMirrorRepository) Create(session *models.TrafficMirrorSession) error {
+	query := `
+		INSERT INTO traffic_mirror_sessions (id, name, user_id, vpc_id, source_instance_id, target_type,
+			target_instance_id, target_ip, target_key, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::uuid, NULLIF($8, '')::inet, $9, $10, $11)
+	`
+
+	_, err := r.db.Exec(query,
+		session.ID,
+		session.Name,
+		session.UserID,
+		session.VPCID,
+		session.SourceInstanceID,
+		session.TargetType,
+		session.TargetInstanceID,
+		session.TargetIP,
+		session.TargetKey,
+		session.CreatedAt,
+		session.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create traffic mirror session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *trafficMirrorRepository) GetByID(id string, userID string) (*models.TrafficMirrorSession, error) {
+	var session models.TrafficMirrorSession
+	query := `
+		SELECT ` + trafficMirrorColumns + `
+		FROM traffic_mirror_sessions
+		WHERE id = $1 AND user_id = $2
+	`
+
+	err := r.db.Get(&session, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get traffic mirror session by ID: %w", err)
+	}
+
+	return &session, nil
+}
+
+func (r *trafficMirrorRepository) List(userID string, vpcID *string, page, pageSize int) ([]models.TrafficMirrorSession, int, error) {
+	var sessions []models.TrafficMirrorSession
+	var total int
+
+	where := "WHERE user_id = $1"
+	args := []interface{}{userID}
+	if vpcID != nil {
+		where += " AND vpc_id = $2"
+		args = append(args, *vpcID)
+	}
+
+	// Get total count
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM traffic_mirror_sessions "+where, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count traffic mirror sessions: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT `+trafficMirrorColumns+`
+		FROM traffic_mirror_sessions
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	err = r.db.Select(&sessions, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list traffic mirror sessions: %w", err)
+	}
+
+	return sessions, total, nil
+}
+
+// ListByVPC returns every session of a VPC, oldest first
+func (r *trafficMirrorRepository) ListByVPC(vpcID string) ([]models.TrafficMirrorSession, error) {
+	var sessions []models.TrafficMirrorSession
+	query := `
+		SELECT ` + trafficMirrorColumns + `
+		FROM traffic_mirror_sessions
+		WHERE vpc_id = $1
+		ORDER BY created_at, id
+	`
+
+	err := r.db.Select(&sessions, query, vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list traffic mirror sessions of VPC: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Delete removes a session along with its filter rules
+func (r *trafficMirrorRepository) Delete(id string) error {
+	query := "DELETE FROM traffic_mirror_sessions WHERE id = $1"
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete traffic mirror session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("traffic mirror session not found")
+	}
+
+	return nil
+}
+
+func (r *trafficMirrorRepository) CreateRule(rule *models.TrafficMirrorFilterRule) error {
+	query := `
+		INSERT INTO traffic_mirror_filter_rules (id, session_id, direction, protocol, from_port, to_port, cidr_block)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(query,
+		rule.ID,
+		rule.SessionID,
+		rule.Direction,
+		rule.Protocol,
+		rule.FromPort,
+		rule.ToPort,
+		rule.CIDRBlock,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create traffic mirror filter rule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *trafficMirrorRepository) ListRules(sessionID string) ([]models.TrafficMirrorFilterRule, error) {
+	var rules []models.TrafficMirrorFilterRule
+	query := `
+		SELECT id, session_id, direction, protocol, from_port, to_port, cidr_block::text AS cidr_block
+		FROM traffic_mirror_filter_rules
+		WHERE session_id = $1
+		ORDER BY created_at, id
+	`
+
+	err := r.db.Select(&rules, query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list traffic mirror filter rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *trafficMirrorRepository) DeleteRule(sessionID string, ruleID string) error {
+	query := "DELETE FROM traffic_mirror_filter_rules WHERE id = $1 AND session_id = $2"
+
+	result, err := r.db.Exec(query, ruleID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete traffic mirror filter rule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("traffic mirror filter rule not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+// control-plane/internal/database/repositories/vpc_peering_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// VPCPeer is the VPC at the other end of an active peering, with what its
+// bridge needs to be connected to. The conntrack zone is 0 until the peer's
+// dataplane is provisioned.
+type VPCPeer struct {
+	PeeringID     string  `db:"peering_id"`
+	VPCID         string  `db:"vpc_id"`
+	UserID        string  `db:"user_id"`
+	CIDRBlock     string  `db:"cidr_block"`
+	IPv6CIDRBlock *string `db:"ipv6_cidr_block"`
+	BridgeName    string  `db:"bridge_name"`
+	ConntrackZone int     `db:"conntrack_zone"`
+}
+
+type VPCPeeringRepository interface {
+	Create(peering *models.VPCPeering) error
+	GetByID(id string, userID string) (*models.VPCPeering, error)
+	GetBetween(vpcID string, peerVPCID string) (*models.VPCPeering, error)
+	List(userID string, vpcID *string, page, pageSize int) ([]models.VPCPeering, int, error)
+	UpdateState(id string, from string, to string) error
+	Delete(id string) error
+
+	// Peers
+	GetPeer(id string, vpcID string) (*VPCPeer, error)
+	ListPeers(vpcID string) ([]VPCPeer, error)
+}
+
+type vpcPeeringRepository struct {
+	db *sqlx.DB
+}
+
+func NewVPCPeeringRepository(db *sqlx.DB) VPCPeeringRepository {
+	return &vpcPeeringRepository{db: db}
+}
+
+const vpcPeeringColumns = `
+	id, name, requester_vpc_id, requester_user_id, accepter_vpc_id, accepter_user_id,
+	state, created_at, updated_at
+`
+
+func (r *vpcPeeringRepository) Create(peering *models.VPCPeering) error {
+	query := `
+		INSERT INTO vpc_peerings (id, name, requester_vpc_id, requester_user_id, accepter_vpc_id, accepter_user_id, state, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Exec(query,
+		peering.ID,
+		peering.Name,
+		peering.RequesterVPCID,
+		peering.RequesterUserID,
+		peering.AccepterVPCID,
+		peering.AccepterUserID,
+		peering.State,
+		peering.CreatedAt,
+		peering.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create VPC peering: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID returns a peering if the user owns either of its VPCs
+func (r *vpcPeeringRepository) GetByID(id string, userID string) (*models.VPCPeering, error) {
+	var peering models.VPCPeering
+	query := `
+		SELECT ` + vpcPeeringColumns + `
+		FROM vpc_peerings
+		WHERE id = $1 AND (requester_user_id = $2 OR accepter_user_id = $2)
+	`
+
+	err := r.db.Get(&peering, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get VPC peering by ID: %w", err)
+	}
+
+	return &peering, nil
+}
+
+// GetBetween returns the pending or active peering of two VPCs, whichever
+// of them requested it
+func (r *vpcPeeringRepository) GetBetween(vpcID string, peerVPCID string) (*models.VPCPeering, error) {
+	var peering models.VPCPeering
+	query := `
+		SELECT ` + vpcPeeringColumns + `
+		FROM vpc_peerings
+		WHERE state IN ('pending-acceptance', 'active')
+			AND ((requester_vpc_id = $1 AND accepter_vpc_id = $2) OR (requester_vpc_id = $2 AND accepter_vpc_id = $1))
+	`
+
+	err := r.db.Get(&peering, query, vpcID, peerVPCID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get VPC peering between VPCs: %w", err)
+	}
+
+	return &peering, nil
+}
+
+// List returns the peerings the user is a party to, optionally only those
+// of one VPC
+func (r *vpcPeeringRepository) List(userID string, vpcID *string, page, pageSize int) ([]models.VPCPeering, int, error) {
+	var peerings []models.VPCPeering
+	var total int
+
+	where := "WHERE (requester_user_id = $1 OR accepter_user_id = $1)"
+	args := []interface{}{userID}
+	if vpcID != nil {
+		where += " AND (requester_vpc_id = $2 OR accepter_vpc_id = $2)"
+		args = append(args, *vpcID)
+	}
+
+	// Get total count
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM vpc_peerings "+where, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count VPC peerings: %w", err)
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	query := fmt.Sprintf(`
+		SELECT `+vpcPeeringColumns+`
+		FROM vpc_peerings
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, offset)
+
+	err = r.db.Select(&peerings, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list VPC peerings: %w", err)
+	}
+
+	return peerings, total, nil
+}
+
+// UpdateState moves a peering from one state to another. It fails when the
+// peering is no longer in the state it is moved from, so two owners acting
+// at once cannot both succeed.
+func (r *vpcPeeringRepository) UpdateState(id string, from string, to string) error {
+	query := `
+		UPDATE vpc_peerings
+		SET state = $1, updated_at = NOW()
+		WHERE id = $2 AND state = $3
+	`
+
+	result, err := r.db.Exec(query, to, id, from)
+	if err != nil {
+		return fmt.Errorf("failed to update VPC peering state: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("VPC peering not found or not %s", from)
+	}
+
+	return nil
+}
+
+func (r *vpcPeeringRepository) Delete(id string) error {
+	query := "DELETE FROM vpc_peerings WHERE id = $1"
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete VPC peering: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("VPC peering not found")
+	}
+
+	return nil
+}
+
+// vpcPeerQuery selects the VPC at the other end of the active peerings of
+// the VPC in $1
+const vpcPeerQuery = `
+	SELECT p.id AS peering_id, v.id AS vpc_id, v.user_id, v.cidr_block,
+		v.ipv6_cidr_block::text AS ipv6_cidr_block, d.bridge_name,
+		COALESCE(z.zone, 0) AS conntrack_zone
+	FROM vpc_peerings p
+	JOIN vpcs v ON v.id = CASE WHEN p.requester_vpc_id = $1 THEN p.accepter_vpc_id ELSE p.requester_vpc_id END
+	JOIN vpc_dataplanes d ON d.vpc_id = v.id
+	LEFT JOIN vpc_conntrack_zones z ON z.vpc_id = v.id
+	WHERE p.state = 'active' AND $1 IN (p.requester_vpc_id, p.accepter_vpc_id)
+`
+
+// GetPeer returns the peer of a VPC through an active peering, or nil when
+// the peering is not active or the VPC is no party to it
+func (r *vpcPeeringRepository) GetPeer(id string, vpcID string) (*VPCPeer, error) {
+	var peer VPCPeer
+	query := vpcPeerQuery + " AND p.id = $2"
+
+	err := r.db.Get(&peer, query, vpcID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get VPC peer: %w", err)
+	}
+
+	return &peer, nil
+}
+
+// ListPeers returns the peers of a VPC through its active peerings, oldest
+// peering first
+func (r *vpcPeeringRepository) ListPeers(vpcID string) ([]VPCPeer, error) {
+	var peers []VPCPeer
+	query := vpcPeerQuery + " ORDER BY p.created_at"
+
+	if err := r.db.Select(&peers, query, vpcID); err != nil {
+		return nil, fmt.Errorf("failed to list VPC peers: %w", err)
+	}
+
+	return peers, nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/pkg/errors"
 )
 
 type VPCRepository interface {
@@ -17,9 +18,19 @@ type VPCRepository interface {
 	GetByID(id string, userID string) (*models.VPC, error)
 	GetByName(name string, userID string) (*models.VPC, error)
 	List(userID string, page, pageSize int) ([]models.VPC, int, error)
+	ListAll() ([]models.VPC, error)
 	Update(id string, userID string, updates map[string]interface{}) error
 	Delete(id string, userID string) error
 	CheckCIDRConflict(cidrBlock string, userID string, excludeID *string) (bool, error)
+	CheckIPv6CIDRConflict(cidrBlock string, userID *string) (bool, error)
+	ListCIDRBlocks(userID string) ([]string, error)
+	AllocateConntrackZone(vpcID string) (int, error)
+	AllocateVNI(vpcID string) (int, error)
+
+	// Dataplane
+	AllocateDataplane(vpcID string) (*models.VPCDataplane, error)
+	GetDataplane(vpcID string) (*models.VPCDataplane, error)
+	ListDataplanes() ([]models.VPCDataplane, error)
 }
 
 type vpcRepository struct {
@@ -32,14 +43,15 @@ func NewVPCRepository(db *sqlx.DB) VPCRepository {
 
 func (r *vpcRepository) Create(vpc *models.VPC) error {
 	query := `
-		INSERT INTO vpcs (id, name, cidr_block, description, user_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO vpcs (id, name, cidr_block, ipv6_cidr_block, description, user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 
 	_, err := r.db.Exec(query,
 		vpc.ID,
 		vpc.Name,
 		vpc.CIDRBlock,
+		vpc.IPv6CIDRBlock,
 		vpc.Description,
 		vpc.UserID,
 		vpc.CreatedAt,
@@ -47,6 +59,9 @@ func (r *vpcRepository) Create(vpc *models.VPC) error {
 	)
 
 	if err != nil {
+		if isExclusionViolation(err, "vpcs_cidr_block_excl") || isExclusionViolation(err, "vpcs_ipv6_cidr_block_excl") {
+			return errors.ErrCIDRConflict
+		}
 		return fmt.Errorf("failed to create VPC: %w", err)
 	}
 
@@ -56,7 +71,7 @@ func (r *vpcRepository) Create(vpc *models.VPC) error {
 func (r *vpcRepository) GetByID(id string, userID string) (*models.VPC, error) {
 	var vpc models.VPC
 	query := `
-		SELECT id, name, cidr_block, description, user_id, created_at, updated_at
+		SELECT id, name, cidr_block, ipv6_cidr_block::text AS ipv6_cidr_block, description, user_id, created_at, updated_at
 		FROM vpcs 
 		WHERE id = $1 AND user_id = $2
 	`
@@ -75,7 +90,7 @@ func (r *vpcRepository) GetByID(id string, userID string) (*models.VPC, error) {
 func (r *vpcRepository) GetByName(name string, userID string) (*models.VPC, error) {
 	var vpc models.VPC
 	query := `
-		SELECT id, name, cidr_block, description, user_id, created_at, updated_at
+		SELECT id, name, cidr_block, ipv6_cidr_block::text AS ipv6_cidr_block, description, user_id, created_at, updated_at
 		FROM vpcs 
 		WHERE name = $1 AND user_id = $2
 	`
@@ -105,7 +120,7 @@ func (r *vpcRepository) List(userID string, page, pageSize int) ([]models.VPC, i
 	// Get paginated results
 	offset := (page - 1) * pageSize
 	query := `
-		SELECT id, name, cidr_block, description, user_id, created_at, updated_at
+		SELECT id, name, cidr_block, ipv6_cidr_block::text AS ipv6_cidr_block, description, user_id, created_at, updated_at
 		FROM vpcs 
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -120,6 +135,22 @@ func (r *vpcRepository) List(userID string, page, pageSize int) ([]models.VPC, i
 	return vpcs, total, nil
 }
 
+// ListAll returns the VPCs of every user
+func (r *vpcRepository) ListAll() ([]models.VPC, error) {
+	var vpcs []models.VPC
+	query := `
+		SELECT id, name, cidr_block, ipv6_cidr_block::text AS ipv6_cidr_block, description, user_id, created_at, updated_at
+		FROM vpcs
+		ORDER BY created_at
+	`
+
+	if err := r.db.Select(&vpcs, query); err != nil {
+		return nil, fmt.Errorf("failed to list all VPCs: %w", err)
+	}
+
+	return vpcs, nil
+}
+
 func (r *vpcRepository) Update(id string, userID string, updates map[string]interface{}) error {
 	if len(updates) == 0 {
 		return nil
@@ -187,11 +218,14 @@ func (r *vpcRepository) Delete(id string, userID string) error {
 	return nil
 }
 
+// CheckCIDRConflict reports whether cidrBlock overlaps any of the user's VPCs.
+// The && operator matches when either network contains the other, so a /24
+// inside an existing /16 is caught as well as identical blocks.
 func (r *vpcRepository) CheckCIDRConflict(cidrBlock string, userID string, excludeID *string) (bool, error) {
 	query := `
 		SELECT COUNT(*) 
 		FROM vpcs 
-		WHERE user_id = $1 AND cidr_block = $2
+		WHERE user_id = $1 AND cidr_block::cidr && $2::cidr
 	`
 	args := []interface{}{userID, cidrBlock}
 
@@ -208,3 +242,186 @@ func (r *vpcRepository) CheckCIDRConflict(cidrBlock string, userID string, exclu
 
 	return count > 0, nil
 }
+
+// CheckIPv6CIDRConflict reports whether an IPv6 block overlaps the block of
+// any VPC, or of any of the user's VPCs when userID is set. Blocks delegated
+// to the platform are routable beyond it and checked across all users;
+// unique local blocks only need to be unique per user, like IPv4 ranges.
+func (r *vpcRepository) CheckIPv6CIDRConflict(cidrBlock string, userID *string) (bool, error) {
+	query := "SELECT COUNT(*) FROM vpcs WHERE ipv6_cidr_block && $1::cidr"
+	args := []interface{}{cidrBlock}
+
+	if userID != nil {
+		query += " AND user_id = $2"
+		args = append(args, *userID)
+	}
+
+	var count int
+	if err := r.db.Get(&count, query, args...); err != nil {
+		return false, fmt.Errorf("failed to check IPv6 CIDR conflict: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *vpcRepository) ListCIDRBlocks(userID string) ([]string, error) {
+	var cidrBlocks []string
+	query := "SELECT cidr_block FROM vpcs WHERE user_id = $1"
+
+	err := r.db.Select(&cidrBlocks, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VPC CIDR blocks: %w", err)
+	}
+
+	return cidrBlocks, nil
+}
+
+// AllocateConntrackZone assigns the lowest unused conntrack zone to a VPC.
+// NAT gateways draw from the same range. Both zone tables are locked against
+// concurrent allocations for the duration of the transaction, always in the
+// same order; the unique constraints on the zones back this up.
+func (r *vpcRepository) AllocateConntrackZone(vpcID string) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockConntrackZones(tx); err != nil {
+		return 0, err
+	}
+
+	var zone int
+	query := `
+		INSERT INTO vpc_conntrack_zones (vpc_id, zone)
+		SELECT $1, s.zone
+		FROM generate_series(1, 65535) AS s(zone)
+		WHERE NOT EXISTS (SELECT 1 FROM vpc_conntrack_zones z WHERE z.zone = s.zone)
+			AND NOT EXISTS (SELECT 1 FROM nat_gateways n WHERE n.conntrack_zone = s.zone)
+		ORDER BY s.zone
+		LIMIT 1
+		RETURNING zone
+	`
+	if err := tx.Get(&zone, query, vpcID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("no free conntrack zone")
+		}
+		return 0, fmt.Errorf("failed to allocate conntrack zone: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return zone, nil
+}
+
+// AllocateVNI assigns the lowest unused VNI to a VPC. The key table is
+// locked against concurrent allocations for the duration of the transaction.
+func (r *vpcRepository) AllocateVNI(vpcID string) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("LOCK TABLE vpc_tunnel_keys IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return 0, fmt.Errorf("failed to lock tunnel keys: %w", err)
+	}
+
+	// Gaps are searched for instead of enumerating the 24-bit range
+	query := `
+		SELECT CASE
+			WHEN NOT EXISTS (SELECT 1 FROM vpc_tunnel_keys WHERE vni = 1) THEN 1
+			ELSE (
+				SELECT MIN(k.vni + 1)
+				FROM vpc_tunnel_keys k
+				WHERE k.vni < 16777215
+				  AND NOT EXISTS (SELECT 1 FROM vpc_tunnel_keys n WHERE n.vni = k.vni + 1)
+			)
+		END
+	`
+	var next sql.NullInt64
+	if err := tx.Get(&next, query); err != nil {
+		return 0, fmt.Errorf("failed to find free VNI: %w", err)
+	}
+	if !next.Valid {
+		return 0, fmt.Errorf("no free VNI")
+	}
+
+	vni := int(next.Int64)
+	if _, err := tx.Exec("INSERT INTO vpc_tunnel_keys (vpc_id, vni) VALUES ($1, $2)", vpcID, vni); err != nil {
+		return 0, fmt.Errorf("failed to allocate VNI: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return vni, nil
+}
+
+// lockConntrackZones locks every table conntrack zones are allocated from
+func lockConntrackZones(tx *sqlx.Tx) error {
+	for _, table := range []string{"vpc_conntrack_zones", "nat_gateways"} {
+		if _, err := tx.Exec(fmt.Sprintf("LOCK TABLE %s IN SHARE ROW EXCLUSIVE MODE", table)); err != nil {
+			return fmt.Errorf("failed to lock conntrack zones: %w", err)
+		}
+	}
+	return nil
+}
+
+// AllocateDataplane numbers the bridge and datapath of a VPC from the
+// dataplane sequence. The format matches network.VPCBridgePrefix.
+func (r *vpcRepository) AllocateDataplane(vpcID string) (*models.VPCDataplane, error) {
+	var dataplane models.VPCDataplane
+	query := `
+		INSERT INTO vpc_dataplanes (vpc_id, bridge_name, datapath_id)
+		SELECT $1, 'gcp-vpc-' || lpad(to_hex(n), 7, '0'), lpad(to_hex(n), 16, '0')
+		FROM nextval('vpc_dataplane_seq') AS n
+		RETURNING vpc_id, bridge_name, datapath_id
+	`
+
+	if err := r.db.Get(&dataplane, query, vpcID); err != nil {
+		return nil, fmt.Errorf("failed to allocate VPC dataplane: %w", err)
+	}
+
+	return &dataplane, nil
+}
+
+// vpcDataplaneQuery selects the dataplane of VPCs with their VNI and
+// conntrack zone, which read as 0 until they are allocated
+const vpcDataplaneQuery = `
+	SELECT d.vpc_id, d.bridge_name, d.datapath_id,
+		COALESCE(k.vni, 0) AS vni, COALESCE(z.zone, 0) AS conntrack_zone
+	FROM vpc_dataplanes d
+	LEFT JOIN vpc_tunnel_keys k ON k.vpc_id = d.vpc_id
+	LEFT JOIN vpc_conntrack_zones z ON z.vpc_id = d.vpc_id
+`
+
+func (r *vpcRepository) GetDataplane(vpcID string) (*models.VPCDataplane, error) {
+	var dataplane models.VPCDataplane
+	query := vpcDataplaneQuery + "WHERE d.vpc_id = $1"
+
+	err := r.db.Get(&dataplane, query, vpcID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get VPC dataplane: %w", err)
+	}
+
+	return &dataplane, nil
+}
+
+// ListDataplanes returns the dataplane of every VPC
+func (r *vpcRepository) ListDataplanes() ([]models.VPCDataplane, error) {
+	var dataplanes []models.VPCDataplane
+	query := vpcDataplaneQuery + "ORDER BY d.bridge_name"
+
+	if err := r.db.Select(&dataplanes, query); err != nil {
+		return nil, fmt.Errorf("failed to list VPC dataplanes: %w", err)
+	}
+
+	return dataplanes, nil
+}
@@ -0,0 +1,243 @@
+// control-plane/internal/database/repositories/worker_node_repo.go
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// VPCTunnel holds what the overlay of a VPC bridge is keyed by
+type VPCTunnel struct {
+	VPCID      string `db:"vpc_id"`
+	BridgeName string `db:"bridge_name"`
+	VNI        int    `db:"vni"`
+	Zone       int    `db:"zone"`
+}
+
+// TunnelEndpoint is an instance together with the tunnel endpoint of the
+// worker node it runs on
+type TunnelEndpoint struct {
+	PrivateIP string `db:"private_ip"`
+	TunnelIP  string `db:"tunnel_ip"`
+}
+
+type WorkerNodeRepository interface {
+	Create(node *models.WorkerNode) error
+	GetByID(id string) (*models.WorkerNode, error)
+	GetByName(name string) (*models.WorkerNode, error)
+	GetByTunnelIP(tunnelIP string) (*models.WorkerNode, error)
+	List() ([]models.WorkerNode, error)
+	ListPeers(localName string) ([]models.WorkerNode, error)
+	CountInstances(id string) (int, error)
+	Delete(id string) error
+
+	// Overlay
+	GetVPCTunnel(vpcID string) (*VPCTunnel, error)
+	ListVPCTunnels() ([]VPCTunnel, error)
+	ListRemoteEndpoints(vpcID string, localName string) ([]TunnelEndpoint, error)
+}
+
+type workerNodeRepository struct {
+	db *sqlx.DB
+}
+
+func NewWorkerNodeRepository(db *sqlx.DB) WorkerNodeRepository {
+	return &workerNodeRepository{db: db}
+}
+
+const workerNodeColumns = `id, name, host(tunnel_ip) AS tunnel_ip, state, created_at, updated_at`
+
+func (r *workerNodeRepository) Create(node *models.WorkerNode) error {
+	query := `
+		INSERT INTO worker_nodes (id, name, tunnel_ip, state, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Exec(query,
+		node.ID,
+		node.Name,
+		node.TunnelIP,
+		node.State,
+		node.CreatedAt,
+		node.UpdatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create worker node: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workerNodeRepository) GetByID(id string) (*models.WorkerNode, error) {
+	var node models.WorkerNode
+	query := "SELECT " + workerNodeColumns + " FROM worker_nodes WHERE id = $1"
+
+	err := r.db.Get(&node, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get worker node by ID: %w", err)
+	}
+
+	return &node, nil
+}
+
+func (r *workerNodeRepository) GetByName(name string) (*models.WorkerNode, error) {
+	var node models.WorkerNode
+	query := "SELECT " + workerNodeColumns + " FROM worker_nodes WHERE name = $1"
+
+	err := r.db.Get(&node, query, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get worker node by name: %w", err)
+	}
+
+	return &node, nil
+}
+
+func (r *workerNodeRepository) GetByTunnelIP(tunnelIP string) (*models.WorkerNode, error) {
+	var node models.WorkerNode
+	query := "SELECT " + workerNodeColumns + " FROM worker_nodes WHERE tunnel_ip = $1"
+
+	err := r.db.Get(&node, query, tunnelIP)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get worker node by tunnel IP: %w", err)
+	}
+
+	return &node, nil
+}
+
+func (r *workerNodeRepository) List() ([]models.WorkerNode, error) {
+	var nodes []models.WorkerNode
+	query := "SELECT " + workerNodeColumns + " FROM worker_nodes ORDER BY name"
+
+	if err := r.db.Select(&nodes, query); err != nil {
+		return nil, fmt.Errorf("failed to list worker nodes: %w", err)
+	}
+
+	return nodes, nil
+}
+
+// ListPeers returns every active worker node other than localName, which
+// are the nodes the local VPC bridges exchange tunnel traffic with
+func (r *workerNodeRepository) ListPeers(localName string) ([]models.WorkerNode, error) {
+	var nodes []models.WorkerNode
+	query := `
+		SELECT ` + workerNodeColumns + `
+		FROM worker_nodes
+		WHERE name <> $1 AND state = 'active'
+		ORDER BY tunnel_ip
+	`
+
+	if err := r.db.Select(&nodes, query, localName); err != nil {
+		return nil, fmt.Errorf("failed to list worker node peers: %w", err)
+	}
+
+	return nodes, nil
+}
+
+// CountInstances counts the instances placed on a worker node that have not
+// been terminated
+func (r *workerNodeRepository) CountInstances(id string) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM instances WHERE worker_node_id = $1 AND state <> 'terminated'"
+
+	if err := r.db.Get(&count, query, id); err != nil {
+		return 0, fmt.Errorf("failed to count instances on worker node: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *workerNodeRepository) Delete(id string) error {
+	query := "DELETE FROM worker_nodes WHERE id = $1"
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete worker node: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("worker node not found")
+	}
+
+	return nil
+}
+
+func (r *workerNodeRepository) GetVPCTunnel(vpcID string) (*VPCTunnel, error) {
+	var tunnel VPCTunnel
+	query := `
+		SELECT k.vpc_id, d.bridge_name, k.vni, z.zone
+		FROM vpc_tunnel_keys k
+		JOIN vpc_conntrack_zones z ON z.vpc_id = k.vpc_id
+		JOIN vpc_dataplanes d ON d.vpc_id = k.vpc_id
+		WHERE k.vpc_id = $1
+	`
+
+	err := r.db.Get(&tunnel, query, vpcID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get VPC tunnel: %w", err)
+	}
+
+	return &tunnel, nil
+}
+
+func (r *workerNodeRepository) ListVPCTunnels() ([]VPCTunnel, error) {
+	var tunnels []VPCTunnel
+	query := `
+		SELECT k.vpc_id, d.bridge_name, k.vni, z.zone
+		FROM vpc_tunnel_keys k
+		JOIN vpc_conntrack_zones z ON z.vpc_id = k.vpc_id
+		JOIN vpc_dataplanes d ON d.vpc_id = k.vpc_id
+		ORDER BY k.vni
+	`
+
+	if err := r.db.Select(&tunnels, query); err != nil {
+		return nil, fmt.Errorf("failed to list VPC tunnels: %w", err)
+	}
+
+	return tunnels, nil
+}
+
+// ListRemoteEndpoints returns the instances of a VPC that run on active
+// worker nodes other than localName, with the tunnel endpoint of their node
+func (r *workerNodeRepository) ListRemoteEndpoints(vpcID string, localName string) ([]TunnelEndpoint, error) {
+	var endpoints []TunnelEndpoint
+	query := `
+		SELECT host(i.private_ip) AS private_ip, host(w.tunnel_ip) AS tunnel_ip
+		FROM instances i
+		JOIN subnets s ON s.id = i.subnet_id
+		JOIN worker_nodes w ON w.id = i.worker_node_id
+		WHERE s.vpc_id = $1
+		  AND w.name <> $2
+		  AND w.state = 'active'
+		  AND i.private_ip IS NOT NULL
+		  AND i.state <> 'terminated'
+		ORDER BY i.private_ip
+	`
+
+	if err := r.db.Select(&endpoints, query, vpcID, localName); err != nil {
+		return nil, fmt.Errorf("failed to list remote endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
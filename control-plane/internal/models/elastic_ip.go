@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+type PublicIPPool struct {
+	ID          string    `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	CIDRBlock   string    `json:"cidr_block" db:"cidr_block"`
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type ElasticIP struct {
+	ID           string     `json:"id" db:"id"`
+	PoolID       string     `json:"pool_id" db:"pool_id"`
+	PublicIP     string     `json:"public_ip" db:"public_ip"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	InstanceID   *string    `json:"instance_id" db:"instance_id"`
+	NATGatewayID *string    `json:"nat_gateway_id" db:"nat_gateway_id"`
+	AllocatedAt  time.Time  `json:"allocated_at" db:"allocated_at"`
+	AssociatedAt *time.Time `json:"associated_at" db:"associated_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// FlowLog captures the traffic of a VPC, a subnet or an instance interface
+type FlowLog struct {
+	ID            string    `json:"id" db:"id"`
+	Name          string    `json:"name" db:"name"`
+	UserID        string    `json:"user_id" db:"user_id"`
+	VPCID         string    `json:"vpc_id" db:"vpc_id"`
+	ResourceType  string    `json:"resource_type" db:"resource_type"` // vpc, subnet, instance
+	ResourceID    string    `json:"resource_id" db:"resource_id"`
+	TrafficType   string    `json:"traffic_type" db:"traffic_type"` // accept, reject, all
+	Number        int       `json:"number" db:"number"`
+	RetentionDays int       `json:"retention_days" db:"retention_days"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FlowLogRecord is the traffic of one connection in one direction over the
+// interval from StartTime to EndTime
+type FlowLogRecord struct {
+	ID        int64     `json:"id" db:"id"`
+	FlowLogID string    `json:"flow_log_id" db:"flow_log_id"`
+	VPCID     string    `json:"vpc_id" db:"vpc_id"`
+	Action    string    `json:"action" db:"action"` // accept, reject
+	Protocol  int       `json:"protocol" db:"protocol"`
+	SrcIP     string    `json:"src_ip" db:"src_ip"`
+	SrcPort   int       `json:"src_port" db:"src_port"`
+	DstIP     string    `json:"dst_ip" db:"dst_ip"`
+	DstPort   int       `json:"dst_port" db:"dst_port"`
+	Packets   int64     `json:"packets" db:"packets"`
+	Bytes     int64     `json:"bytes" db:"bytes"`
+	StartTime time.Time `json:"start_time" db:"start_time"`
+	EndTime   time.Time `json:"end_time" db:"end_time"`
+	NodeName  string    `json:"node_name" db:"node_name"`
+}
@@ -16,6 +16,9 @@ type Instance struct {
 	WorkerNodeID   string            `json:"worker_node_id" db:"worker_node_id"`
 	UserID         string            `json:"user_id" db:"user_id"`
 	KeyPair        string            `json:"key_pair" db:"key_pair"`
+	SSHPublicKey   string            `json:"ssh_public_key" db:"ssh_public_key"`
+	UserData       string            `json:"-" db:"user_data"`
+	MetadataTokens string            `json:"metadata_tokens" db:"metadata_tokens"` // optional, required
 	SecurityGroups []string          `json:"security_groups" db:"-"`
 	Tags           map[string]string `json:"tags" db:"-"`
 	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+type IPAllocation struct {
+	ID          string    `json:"id" db:"id"`
+	SubnetID    string    `json:"subnet_id" db:"subnet_id"`
+	IPAddress   string    `json:"ip_address" db:"ip_address"`
+	IPv6Address *string   `json:"ipv6_address" db:"ipv6_address"`
+	InstanceID  *string   `json:"instance_id" db:"instance_id"`
+	Description *string   `json:"description" db:"description"`
+	AllocatedAt time.Time `json:"allocated_at" db:"allocated_at"`
+}
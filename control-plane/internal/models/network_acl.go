@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+type NetworkACL struct {
+	ID              string            `json:"id" db:"id"`
+	Name            string            `json:"name" db:"name"`
+	Description     string            `json:"description" db:"description"`
+	VPCID           string            `json:"vpc_id" db:"vpc_id"`
+	UserID          string            `json:"user_id" db:"user_id"`
+	SubnetIDs       []string          `json:"subnet_ids" db:"-"`
+	InboundEntries  []NetworkACLEntry `json:"inbound_entries" db:"-"`
+	OutboundEntries []NetworkACLEntry `json:"outbound_entries" db:"-"`
+	CreatedAt       time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+type NetworkACLEntry struct {
+	ID           string `json:"id" db:"id"`
+	NetworkACLID string `json:"network_acl_id" db:"network_acl_id"`
+	RuleNumber   int    `json:"rule_number" db:"rule_number"`
+	Direction    string `json:"direction" db:"direction"` // inbound, outbound
+	Protocol     string `json:"protocol" db:"protocol"`   // tcp, udp, icmp, all
+	FromPort     int    `json:"from_port" db:"from_port"`
+	ToPort       int    `json:"to_port" db:"to_port"`
+	CIDRBlock    string `json:"cidr_block" db:"cidr_block"`
+	Action       string `json:"action" db:"action"` // allow, deny
+	Description  string `json:"description" db:"description"`
+}
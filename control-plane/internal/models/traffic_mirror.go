@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// TrafficMirrorSession copies the traffic of an instance's interface to
+// another instance or to a GRE or ERSPAN endpoint
+type TrafficMirrorSession struct {
+	ID               string                    `json:"id" db:"id"`
+	Name             string                    `json:"name" db:"name"`
+	UserID           string                    `json:"user_id" db:"user_id"`
+	VPCID            string                    `json:"vpc_id" db:"vpc_id"`
+	SourceInstanceID string                    `json:"source_instance_id" db:"source_instance_id"`
+	TargetType       string                    `json:"target_type" db:"target_type"` // instance, gre, erspan
+	TargetInstanceID string                    `json:"target_instance_id" db:"target_instance_id"`
+	TargetIP         string                    `json:"target_ip" db:"target_ip"`
+	TargetKey        int                       `json:"target_key" db:"target_key"` // GRE key or ERSPAN session ID
+	FilterRules      []TrafficMirrorFilterRule `json:"filter_rules" db:"-"`
+	CreatedAt        time.Time                 `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time                 `json:"updated_at" db:"updated_at"`
+}
+
+type TrafficMirrorFilterRule struct {
+	ID        string `json:"id" db:"id"`
+	SessionID string `json:"session_id" db:"session_id"`
+	Direction string `json:"direction" db:"direction"` // inbound, outbound
+	Protocol  string `json:"protocol" db:"protocol"`   // tcp, udp, icmp, all
+	FromPort  int    `json:"from_port" db:"from_port"`
+	ToPort    int    `json:"to_port" db:"to_port"`
+	CIDRBlock string `json:"cidr_block" db:"cidr_block"`
+}
@@ -5,13 +5,25 @@ import (
 )
 
 type VPC struct {
-	ID          string    `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	CIDRBlock   string    `json:"cidr_block" db:"cidr_block"`
-	Description *string   `json:"description" db:"description"`
-	UserID      string    `json:"user_id" db:"user_id"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID            string        `json:"id" db:"id"`
+	Name          string        `json:"name" db:"name"`
+	CIDRBlock     string        `json:"cidr_block" db:"cidr_block"`
+	IPv6CIDRBlock *string       `json:"ipv6_cidr_block" db:"ipv6_cidr_block"`
+	Description   *string       `json:"description" db:"description"`
+	UserID        string        `json:"user_id" db:"user_id"`
+	Dataplane     *VPCDataplane `json:"dataplane,omitempty" db:"-"`
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// VPCDataplane is what backs a VPC in Open vSwitch. The VNI and conntrack
+// zone are 0 until the VPC's dataplane is provisioned.
+type VPCDataplane struct {
+	VPCID         string `json:"vpc_id" db:"vpc_id"`
+	BridgeName    string `json:"bridge_name" db:"bridge_name"`
+	DatapathID    string `json:"datapath_id" db:"datapath_id"`
+	VNI           int    `json:"vni" db:"vni"`
+	ConntrackZone int    `json:"conntrack_zone" db:"conntrack_zone"`
 }
 
 type Subnet struct {
@@ -19,6 +31,7 @@ type Subnet struct {
 	VPCID            string    `json:"vpc_id" db:"vpc_id"`
 	Name             string    `json:"name" db:"name"`
 	CIDRBlock        string    `json:"cidr_block" db:"cidr_block"`
+	IPv6CIDRBlock    *string   `json:"ipv6_cidr_block" db:"ipv6_cidr_block"`
 	AvailabilityZone string    `json:"availability_zone" db:"availability_zone"`
 	IsPublic         bool      `json:"is_public" db:"is_public"`
 	CreatedAt        time.Time `json:"created_at" db:"created_at"`
@@ -30,6 +43,8 @@ type RouteTable struct {
 	VPCID     string    `json:"vpc_id" db:"vpc_id"`
 	Name      string    `json:"name" db:"name"`
 	IsMain    bool      `json:"is_main" db:"is_main"`
+	Routes    []Route   `json:"routes" db:"-"`
+	SubnetIDs []string  `json:"subnet_ids" db:"-"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -38,16 +53,44 @@ type Route struct {
 	ID              string `json:"id" db:"id"`
 	RouteTableID    string `json:"route_table_id" db:"route_table_id"`
 	DestinationCIDR string `json:"destination_cidr" db:"destination_cidr"`
-	TargetType      string `json:"target_type" db:"target_type"` // igw, nat, instance
+	TargetType      string `json:"target_type" db:"target_type"` // igw, nat, instance, peering
 	TargetID        string `json:"target_id" db:"target_id"`
 	Priority        int    `json:"priority" db:"priority"`
+	State           string `json:"state" db:"-"` // active, blackhole
 }
 
 type InternetGateway struct {
 	ID        string    `json:"id" db:"id"`
-	VPCID     string    `json:"vpc_id" db:"vpc_id"`
+	VPCID     *string   `json:"vpc_id" db:"vpc_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
 	Name      string    `json:"name" db:"name"`
 	State     string    `json:"state" db:"state"` // available, attached, detached
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
+
+type NATGateway struct {
+	ID            string    `json:"id" db:"id"`
+	VPCID         string    `json:"vpc_id" db:"vpc_id"`
+	SubnetID      string    `json:"subnet_id" db:"subnet_id"`
+	ElasticIPID   string    `json:"elastic_ip_id" db:"elastic_ip_id"`
+	PublicIP      string    `json:"public_ip" db:"public_ip"`
+	UserID        string    `json:"user_id" db:"user_id"`
+	Name          string    `json:"name" db:"name"`
+	State         string    `json:"state" db:"state"` // available
+	ConntrackZone int       `json:"conntrack_zone" db:"conntrack_zone"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type VPCPeering struct {
+	ID              string    `json:"id" db:"id"`
+	Name            string    `json:"name" db:"name"`
+	RequesterVPCID  string    `json:"requester_vpc_id" db:"requester_vpc_id"`
+	RequesterUserID string    `json:"requester_user_id" db:"requester_user_id"`
+	AccepterVPCID   string    `json:"accepter_vpc_id" db:"accepter_vpc_id"`
+	AccepterUserID  string    `json:"accepter_user_id" db:"accepter_user_id"`
+	State           string    `json:"state" db:"state"` // pending-acceptance, active, rejected
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
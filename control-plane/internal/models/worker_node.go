@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+type WorkerNode struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	TunnelIP  string    `json:"tunnel_ip" db:"tunnel_ip"`
+	State     string    `json:"state" db:"state"` // active
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,163 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Network ACL tables run before conntrack, so they see every packet,
+// including replies, and filter statelessly at the subnet boundary
+const (
+	// TableNetworkACLEgress filters traffic leaving a subnet
+	TableNetworkACLEgress = 2
+	// TableNetworkACLIngress filters traffic entering a subnet
+	TableNetworkACLIngress = 3
+)
+
+// Rule numbers accepted for network ACL entries
+const (
+	MinNetworkACLRuleNumber = 1
+	MaxNetworkACLRuleNumber = 32766
+)
+
+// Flow priorities used by the network ACL tables. Entry priorities are
+// derived from the rule number so lower numbers are evaluated first.
+const (
+	PriorityNetworkACLIntraSubnet = 40000
+	PriorityNetworkACLRuleBase    = 32800
+	PriorityNetworkACLDefaultDeny = 10
+	PriorityNetworkACLDefault     = 1
+)
+
+// NetworkACLRule is a numbered network ACL entry. CIDRBlock is the remote
+// side: the source for inbound entries and the destination for outbound ones.
+type NetworkACLRule struct {
+	RuleNumber int
+	Direction  string // inbound, outbound
+	Protocol   string // tcp, udp, icmp, icmpv6, all
+	FromPort   int
+	ToPort     int
+	CIDRBlock  string
+	Action     string // allow, deny
+}
+
+// NetworkACLRulePriority returns the flow priority of an entry
+func NetworkACLRulePriority(ruleNumber int) int {
+	return PriorityNetworkACLRuleBase - ruleNumber
+}
+
+// CompileNetworkACL expands the entries of an ACL into flows for every
+// associated subnet block. Traffic that stays inside a block is not
+// filtered and traffic no entry matches is denied. Entries only apply to
+// blocks of the family of their CIDR block.
+func CompileNetworkACL(subnetCIDRs []string, rules []NetworkACLRule) ([]Flow, error) {
+	egressPass := fmt.Sprintf("goto_table:%d", TableNetworkACLIngress)
+	ingressPass := fmt.Sprintf("goto_table:%d", TableConntrack)
+
+	flows := make([]Flow, 0)
+	for _, cidr := range subnetCIDRs {
+		family, err := familyOf(cidr)
+		if err != nil {
+			return nil, err
+		}
+		subnet, err := remoteCIDRMatch(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		flows = append(flows,
+			Flow{
+				Table:    TableNetworkACLEgress,
+				Priority: PriorityNetworkACLIntraSubnet,
+				Match:    fmt.Sprintf("%s,%s=%s,%s=%s", family.ip, family.src, subnet, family.dst, subnet),
+				Actions:  egressPass,
+			},
+			Flow{
+				Table:    TableNetworkACLIngress,
+				Priority: PriorityNetworkACLIntraSubnet,
+				Match:    fmt.Sprintf("%s,%s=%s,%s=%s", family.ip, family.src, subnet, family.dst, subnet),
+				Actions:  ingressPass,
+			},
+			Flow{
+				Table:    TableNetworkACLEgress,
+				Priority: PriorityNetworkACLDefaultDeny,
+				Match:    fmt.Sprintf("%s,%s=%s", family.ip, family.src, subnet),
+				Actions:  RejectActions,
+			},
+			Flow{
+				Table:    TableNetworkACLIngress,
+				Priority: PriorityNetworkACLDefaultDeny,
+				Match:    fmt.Sprintf("%s,%s=%s", family.ip, family.dst, subnet),
+				Actions:  RejectActions,
+			},
+		)
+
+		for _, rule := range rules {
+			if rule.RuleNumber < MinNetworkACLRuleNumber || rule.RuleNumber > MaxNetworkACLRuleNumber {
+				return nil, fmt.Errorf("invalid rule number: %d", rule.RuleNumber)
+			}
+
+			ruleFamily, err := familyOf(rule.CIDRBlock)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule CIDR %s: %w", rule.CIDRBlock, err)
+			}
+			if ruleFamily != family {
+				continue
+			}
+
+			protoMatches, err := protocolMatches(FirewallRule{
+				Protocol: rule.Protocol,
+				FromPort: rule.FromPort,
+				ToPort:   rule.ToPort,
+			}, family)
+			if err != nil {
+				return nil, err
+			}
+
+			remote, err := remoteCIDRMatch(rule.CIDRBlock)
+			if err != nil {
+				return nil, err
+			}
+
+			var table int
+			var localField, remoteField, pass string
+			switch rule.Direction {
+			case "inbound":
+				table = TableNetworkACLIngress
+				localField, remoteField = family.dst, family.src
+				pass = ingressPass
+			case "outbound":
+				table = TableNetworkACLEgress
+				localField, remoteField = family.src, family.dst
+				pass = egressPass
+			default:
+				return nil, fmt.Errorf("invalid rule direction: %s", rule.Direction)
+			}
+
+			var actions string
+			switch rule.Action {
+			case "allow":
+				actions = pass
+			case "deny":
+				actions = RejectActions
+			default:
+				return nil, fmt.Errorf("invalid rule action: %s", rule.Action)
+			}
+
+			for _, proto := range protoMatches {
+				parts := []string{proto, fmt.Sprintf("%s=%s", localField, subnet)}
+				if remote != "" {
+					parts = append(parts, fmt.Sprintf("%s=%s", remoteField, remote))
+				}
+				flows = append(flows, Flow{
+					Table:    table,
+					Priority: NetworkACLRulePriority(rule.RuleNumber),
+					Match:    strings.Join(parts, ","),
+					Actions:  actions,
+				})
+			}
+		}
+	}
+
+	return flows, nil
+}
@@ -0,0 +1,13 @@
+package network
+
+import "strings"
+
+// VPCBridgePrefix starts the name of every bridge backing a VPC. The rest of
+// the name is allocated with the VPC and also names the ports the VPC owns,
+// so they are as unique as the bridge.
+const VPCBridgePrefix = "gcp-vpc-"
+
+// vpcBridgeSuffix returns the part of a VPC bridge name allocated to the VPC
+func vpcBridgeSuffix(bridgeName string) string {
+	return strings.TrimPrefix(bridgeName, VPCBridgePrefix)
+}
@@ -0,0 +1,134 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ParseIPv4CIDR parses an IPv4 CIDR block and returns its canonical network
+func ParseIPv4CIDR(cidr string) (*net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if ipNet.IP.To4() == nil {
+		return nil, fmt.Errorf("%s is not an IPv4 CIDR block", cidr)
+	}
+	return ipNet, nil
+}
+
+// ParseIPv6CIDR parses an IPv6 CIDR block and returns its canonical network
+func ParseIPv6CIDR(cidr string) (*net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if ipNet.IP.To4() != nil {
+		return nil, fmt.Errorf("%s is not an IPv6 CIDR block", cidr)
+	}
+	return ipNet, nil
+}
+
+// IsIPv6 reports whether an address or CIDR block is an IPv6 one
+func IsIPv6(address string) bool {
+	address, _, _ = strings.Cut(address, "/")
+	ip := net.ParseIP(address)
+	return ip != nil && ip.To4() == nil
+}
+
+// CIDRContains reports whether child lies entirely within parent
+func CIDRContains(parent, child *net.IPNet) bool {
+	parentOnes, parentBits := parent.Mask.Size()
+	childOnes, childBits := child.Mask.Size()
+	if parentBits != childBits || childOnes < parentOnes {
+		return false
+	}
+	return parent.Contains(child.IP)
+}
+
+// CIDROverlaps reports whether two networks share at least one address
+func CIDROverlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// PrivateIPv4Ranges are the RFC 1918 address blocks VPCs may be carved from
+var PrivateIPv4Ranges = []string{
+	"10.0.0.0/8",     // 10.0.0.0 - 10.255.255.255
+	"172.16.0.0/12",  // 172.16.0.0 - 172.31.255.255
+	"192.168.0.0/16", // 192.168.0.0 - 192.168.255.255
+}
+
+// cidrInterval is the inclusive numeric address range of an IPv4 network
+type cidrInterval struct {
+	start uint32
+	end   uint32
+}
+
+func toInterval(ipNet *net.IPNet) cidrInterval {
+	start := ipToUint32(ipNet.IP)
+	return cidrInterval{start: start, end: start | ^binary.BigEndian.Uint32(ipNet.Mask)}
+}
+
+// NextFreeCIDR returns the lowest block of the given prefix length inside the
+// RFC 1918 ranges that overlaps none of the used networks. Used networks are
+// sorted into an interval list so each candidate skips straight past the
+// block it collides with instead of probing every aligned position.
+func NextFreeCIDR(used []*net.IPNet, prefixLength int) (*net.IPNet, bool) {
+	if prefixLength < 8 || prefixLength > 32 {
+		return nil, false
+	}
+
+	intervals := make([]cidrInterval, 0, len(used))
+	for _, u := range used {
+		if u.IP.To4() != nil {
+			intervals = append(intervals, toInterval(u))
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start < intervals[j].start })
+
+	blockSize := uint64(1) << uint(32-prefixLength)
+	mask := net.CIDRMask(prefixLength, 32)
+
+	for _, r := range PrivateIPv4Ranges {
+		_, rangeNet, _ := net.ParseCIDR(r)
+		space := toInterval(rangeNet)
+		if rangeOnes, _ := rangeNet.Mask.Size(); prefixLength < rangeOnes {
+			continue
+		}
+
+		candidate := uint64(space.start)
+		idx := 0
+		for candidate+blockSize-1 <= uint64(space.end) {
+			end := candidate + blockSize - 1
+
+			// Skip intervals that end before the candidate block
+			for idx < len(intervals) && uint64(intervals[idx].end) < candidate {
+				idx++
+			}
+
+			// Find the furthest-reaching interval overlapping the candidate
+			blockedUntil := uint64(0)
+			blocked := false
+			for j := idx; j < len(intervals) && uint64(intervals[j].start) <= end; j++ {
+				if uint64(intervals[j].end) >= candidate {
+					blocked = true
+					if uint64(intervals[j].end) > blockedUntil {
+						blockedUntil = uint64(intervals[j].end)
+					}
+				}
+			}
+
+			if !blocked {
+				return &net.IPNet{IP: uint32ToIP(uint32(candidate)), Mask: mask}, true
+			}
+
+			// Jump to the next aligned block after the collision
+			candidate = (blockedUntil/blockSize + 1) * blockSize
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,74 @@
+package network
+
+import "fmt"
+
+// Conntrack stages of the VPC bridge pipeline. Every IP packet that passes
+// the network ACLs is sent through ct() in the VPC's zone before the
+// security group tables, and packets that pass both tables are committed so
+// their replies are recognised as established.
+const (
+	// TableConntrack sends IP traffic through ct() in the VPC's zone
+	TableConntrack = 5
+	// TableConntrackState dispatches on the connection state after ct()
+	TableConntrackState = 10
+	// TableConntrackCommit commits accepted connections and passes them on
+	// to routing
+	TableConntrackCommit = 40
+)
+
+// Flow priorities used by the conntrack tables
+const (
+	PriorityConntrackInvalid     = 300
+	PriorityConntrackEstablished = 200
+	PriorityConntrackNew         = 100
+)
+
+// ConntrackFlows returns the flows that track connections of either family
+// in the given zone, which must be non-zero so it does not share state with
+// the default zone. Established and related packets skip the security group
+// tables and go straight on to routing, invalid packets are dropped and new
+// connections are evaluated by the groups.
+func ConntrackFlows(zone int) []Flow {
+	flows := make([]Flow, 0, 12)
+	for _, family := range []ipFamily{ipv4Family, ipv6Family} {
+		flows = append(flows,
+			Flow{
+				Table:    TableConntrack,
+				Priority: PriorityConntrackNew,
+				Match:    family.ip,
+				Actions:  fmt.Sprintf("ct(table=%d,zone=%d)", TableConntrackState, zone),
+			},
+			Flow{
+				Table:    TableConntrackState,
+				Priority: PriorityConntrackInvalid,
+				Match:    family.ip + ",ct_state=+trk+inv",
+				Actions:  "drop",
+			},
+			Flow{
+				Table:    TableConntrackState,
+				Priority: PriorityConntrackEstablished,
+				Match:    family.ip + ",ct_state=+trk+est",
+				Actions:  fmt.Sprintf("goto_table:%d", TableFlowLogAccept),
+			},
+			Flow{
+				Table:    TableConntrackState,
+				Priority: PriorityConntrackEstablished,
+				Match:    family.ip + ",ct_state=+trk+rel",
+				Actions:  fmt.Sprintf("goto_table:%d", TableFlowLogAccept),
+			},
+			Flow{
+				Table:    TableConntrackState,
+				Priority: PriorityConntrackNew,
+				Match:    family.ip + ",ct_state=+trk+new",
+				Actions:  fmt.Sprintf("goto_table:%d", TableSecurityGroupEgress),
+			},
+			Flow{
+				Table:    TableConntrackCommit,
+				Priority: PriorityConntrackNew,
+				Match:    family.ip,
+				Actions:  fmt.Sprintf("ct(commit,zone=%d),goto_table:%d", zone, TableFlowLogAccept),
+			},
+		)
+	}
+	return flows
+}
@@ -0,0 +1,125 @@
+package network
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// CookieKind is the kind of resource that owns a flow. Flow cookies hold it
+// in their top byte and the leading 56 bits of the owning resource's UUID
+// below, so the flows of one resource, or of every resource of a kind, can
+// be selected with a cookie mask.
+type CookieKind uint64
+
+// Kinds of flow owners
+const (
+	CookieKindPipeline CookieKind = iota + 1
+	CookieKindSecurityGroup
+	CookieKindNetworkACL
+	CookieKindRoute
+	CookieKindInternetGateway
+	CookieKindOverlay
+	CookieKindDHCP
+	CookieKindMetadata
+	CookieKindPeering
+	CookieKindFlowLog
+)
+
+// Cookie masks selecting the flows of one resource or of a whole kind
+const (
+	CookieMaskResource = "0xffffffffffffffff"
+	CookieMaskKind     = "0xff00000000000000"
+)
+
+const cookieKindShift = 56
+
+// FlowCookie returns the cookie of the flows a resource owns, written the
+// way ovs-ofctl prints it
+func FlowCookie(kind CookieKind, resourceID string) string {
+	id, err := strconv.ParseUint(firstHexDigits(resourceID, 14), 16, 64)
+	if err != nil {
+		// Not a UUID: fall back to a hash of the whole ID
+		h := fnv.New64a()
+		h.Write([]byte(resourceID))
+		id = h.Sum64() & (1<<cookieKindShift - 1)
+	}
+	return fmt.Sprintf("0x%x", uint64(kind)<<cookieKindShift|id)
+}
+
+// KindCookie returns the cookie that, with CookieMaskKind, selects every
+// flow of a kind
+func KindCookie(kind CookieKind) string {
+	return fmt.Sprintf("0x%x", uint64(kind)<<cookieKindShift)
+}
+
+// WithCookie returns a copy of flows, all owned by cookie
+func WithCookie(flows []Flow, cookie string) []Flow {
+	owned := make([]Flow, len(flows))
+	for i, flow := range flows {
+		flow.Cookie = cookie
+		owned[i] = flow
+	}
+	return owned
+}
+
+// CookieMatches reports whether a flow cookie is selected by want/mask
+func CookieMatches(cookie, want, mask string) (bool, error) {
+	values := make([]uint64, 3)
+	for i, s := range []string{cookie, want, mask} {
+		if s == "" {
+			continue // flows without a cookie carry 0
+		}
+		v, err := ParseCookie(s)
+		if err != nil {
+			return false, err
+		}
+		values[i] = v
+	}
+	return values[0]&values[2] == values[1]&values[2], nil
+}
+
+// ParseCookie parses a cookie or mask in the hexadecimal or decimal notation
+// ovs-ofctl accepts
+func ParseCookie(s string) (uint64, error) {
+	if s == "-1" {
+		return ^uint64(0), nil
+	}
+	v, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cookie: %s", s)
+	}
+	return v, nil
+}
+
+// firstHexDigits returns the first n digits of a UUID-like ID with the
+// dashes removed, or an empty string when it is too short
+func firstHexDigits(id string, n int) string {
+	digits := strings.ReplaceAll(id, "-", "")
+	if len(digits) < n {
+		return ""
+	}
+	return digits[:n]
+}
+
+// ownedFlows gives flows without a cookie the cookie of their owner and
+// checks that every flow is selected by cookie/mask, so replacing them
+// cannot leave flows behind that the next replacement would not remove
+func ownedFlows(cookie, mask string, flows []Flow) ([]Flow, error) {
+	owned := make([]Flow, len(flows))
+	for i, flow := range flows {
+		if flow.Cookie == "" {
+			flow.Cookie = cookie
+		}
+		matches, err := CookieMatches(flow.Cookie, cookie, mask)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			return nil, fmt.Errorf("flow cookie %s is outside %s/%s", flow.Cookie, cookie, mask)
+		}
+		owned[i] = flow
+	}
+	return owned, nil
+}
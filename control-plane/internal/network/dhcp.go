@@ -0,0 +1,240 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Every VPC bridge has a DHCP and DNS responder on an internal port of its
+// own, which holds the DNS address of every subnet of the VPC and the
+// metadata address. Requests for it are classified above the pipeline, so
+// security groups and network ACLs never filter them, and its replies are
+// switched straight to instances.
+const PriorityClassifierDHCP = 300
+
+// DHCPLeaseTime is how long instances keep the address DHCP hands them
+const DHCPLeaseTime = "12h"
+
+// Router advertisement timing of the responder, in seconds: how often it
+// advertises and how long instances keep it as their router
+const (
+	RAInterval       = 60
+	RARouterLifetime = 1800
+)
+
+// InternalDomain is the domain instance names resolve under, following the
+// VPC name: <instance>.<vpc>.internal
+const InternalDomain = "internal"
+
+// DHCPSubnet is a subnet DHCP serves addresses in. Subnets with an IPv6
+// block have it advertised for SLAAC as well.
+type DHCPSubnet struct {
+	CIDRBlock     string
+	IPv6CIDRBlock string
+}
+
+// DHCPHost is an address IPAM reserved for an instance. Hostname is empty
+// when the instance's name is not a valid DNS label.
+type DHCPHost struct {
+	IPAddress   string
+	IPv6Address string // empty when the instance's subnet has no IPv6 block
+	Hostname    string
+}
+
+// DHCPConfig is everything the responder of a VPC bridge serves
+type DHCPConfig struct {
+	Interface string
+	Domain    string
+	Gateway   string // address of the VPC bridge, which forwarded queries leave through
+	Subnets   []DHCPSubnet
+	Hosts     []DHCPHost
+	Upstream  []string // resolvers that names outside the domain are forwarded to
+}
+
+// DHCPPortName returns the port of a VPC bridge its DHCP and DNS responder
+// listens on
+func DHCPPortName(bridgeName string) string {
+	return "dns-" + vpcBridgeSuffix(bridgeName)
+}
+
+// DNSLabel turns a name into a DNS label: lowercased, with every character
+// but letters, digits and hyphens replaced by a hyphen. It returns an empty
+// string when nothing usable is left.
+func DNSLabel(name string) string {
+	label := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '-'
+		}
+	}, name)
+	if len(label) > 63 {
+		label = label[:63]
+	}
+	return strings.Trim(label, "-")
+}
+
+// DHCPAddresses returns the addresses the responder's port holds: the DNS
+// addresses of every subnet, with the subnet's prefix lengths
+func DHCPAddresses(subnets []DHCPSubnet) ([]string, error) {
+	addresses := make([]string, 0, len(subnets))
+	for _, subnet := range subnets {
+		allocator, err := NewIPAllocator(subnet.CIDRBlock)
+		if err != nil {
+			return nil, err
+		}
+		ones, _ := allocator.network.Mask.Size()
+		addresses = append(addresses, fmt.Sprintf("%s/%d", allocator.DNSAddress(), ones))
+
+		if subnet.IPv6CIDRBlock != "" {
+			dns, err := IPv6DNSAddress(subnet.IPv6CIDRBlock)
+			if err != nil {
+				return nil, err
+			}
+			addresses = append(addresses, fmt.Sprintf("%s/%d", dns, SubnetIPv6PrefixLength))
+		}
+	}
+	return addresses, nil
+}
+
+// RenderDNSMasqConfig writes a dnsmasq configuration serving config. Only
+// reserved addresses are handed out, each to the MAC InstanceMAC derives
+// from it, and every subnet gets its gateway as router and its DNS address
+// as resolver. IPv6 blocks are advertised off-link for SLAAC, with the
+// responder as router and stateless DHCPv6 for the resolver. Names in the
+// domain are answered from the reservations alone.
+func RenderDNSMasqConfig(config DHCPConfig) (string, error) {
+	var b strings.Builder
+	b.WriteString("# Generated by the network controller, do not edit\n")
+	fmt.Fprintf(&b, "interface=%s\n", config.Interface)
+	b.WriteString("bind-interfaces\n")
+	b.WriteString("no-hosts\n")
+	b.WriteString("no-resolv\n")
+	b.WriteString("dhcp-authoritative\n")
+	fmt.Fprintf(&b, "domain=%s\n", config.Domain)
+	fmt.Fprintf(&b, "local=/%s/\n", config.Domain)
+	for _, server := range config.Upstream {
+		if net.ParseIP(server) == nil {
+			return "", fmt.Errorf("invalid upstream resolver: %s", server)
+		}
+		fmt.Fprintf(&b, "server=%s\n", server)
+	}
+
+	ipv6 := false
+	for i, subnet := range config.Subnets {
+		allocator, err := NewIPAllocator(subnet.CIDRBlock)
+		if err != nil {
+			return "", err
+		}
+		tag := fmt.Sprintf("subnet%d", i)
+		fmt.Fprintf(&b, "dhcp-range=set:%s,%s,static,%s,%s\n", tag, allocator.network.IP, net.IP(allocator.network.Mask), DHCPLeaseTime)
+		fmt.Fprintf(&b, "dhcp-option=tag:%s,option:router,%s\n", tag, allocator.GatewayAddress())
+		fmt.Fprintf(&b, "dhcp-option=tag:%s,option:dns-server,%s\n", tag, allocator.DNSAddress())
+		fmt.Fprintf(&b, "dhcp-option=tag:%s,option:domain-search,%s\n", tag, config.Domain)
+
+		if subnet.IPv6CIDRBlock == "" {
+			continue
+		}
+		ipNet, err := ParseIPv6CIDR(subnet.IPv6CIDRBlock)
+		if err != nil {
+			return "", err
+		}
+		dns, err := IPv6DNSAddress(subnet.IPv6CIDRBlock)
+		if err != nil {
+			return "", err
+		}
+		ipv6 = true
+		fmt.Fprintf(&b, "dhcp-range=set:%s,%s,ra-stateless,off-link,%d,%s\n", tag, ipNet.IP, SubnetIPv6PrefixLength, DHCPLeaseTime)
+		fmt.Fprintf(&b, "dhcp-option=tag:%s,option6:dns-server,[%s]\n", tag, dns)
+		fmt.Fprintf(&b, "dhcp-option=tag:%s,option6:domain-search,%s\n", tag, config.Domain)
+	}
+	if ipv6 {
+		// dnsmasq advertises a router lifetime of zero on hosts without an
+		// IPv6 default route, which the responder never has
+		b.WriteString("enable-ra\n")
+		fmt.Fprintf(&b, "ra-param=%s,%d,%d\n", config.Interface, RAInterval, RARouterLifetime)
+	}
+
+	for _, host := range config.Hosts {
+		mac, err := InstanceMAC(host.IPAddress)
+		if err != nil {
+			return "", err
+		}
+		if host.Hostname == "" {
+			fmt.Fprintf(&b, "dhcp-host=%s,%s\n", mac, host.IPAddress)
+			continue
+		}
+		fmt.Fprintf(&b, "dhcp-host=%s,%s,%s\n", mac, host.IPAddress, host.Hostname)
+		if host.IPv6Address != "" {
+			fmt.Fprintf(&b, "host-record=%s.%s,%s,%s\n", host.Hostname, config.Domain, host.IPAddress, host.IPv6Address)
+			continue
+		}
+		fmt.Fprintf(&b, "host-record=%s.%s,%s\n", host.Hostname, config.Domain, host.IPAddress)
+	}
+
+	return b.String(), nil
+}
+
+// CompileDHCP builds the classifier flows that hand DHCP and DHCPv6
+// requests and DNS queries for the subnets' DNS addresses to the responder's
+// port, and let everything the responder sends, router advertisements
+// included, bypass the pipeline
+func CompileDHCP(port string, subnets []DHCPSubnet) ([]Flow, error) {
+	flows := []Flow{
+		{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierDHCP,
+			Match:    "udp,tp_src=68,tp_dst=67",
+			Actions:  fmt.Sprintf("output:%s", port),
+		},
+		{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierDHCP,
+			Match:    "udp6,tp_src=546,tp_dst=547",
+			Actions:  fmt.Sprintf("output:%s", port),
+		},
+		{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierDHCP,
+			Match:    fmt.Sprintf("in_port=%s", port),
+			Actions:  "NORMAL",
+		},
+	}
+
+	for _, subnet := range subnets {
+		allocator, err := NewIPAllocator(subnet.CIDRBlock)
+		if err != nil {
+			return nil, err
+		}
+		for _, proto := range []string{"udp", "tcp"} {
+			flows = append(flows, Flow{
+				Table:    TableClassifier,
+				Priority: PriorityClassifierDHCP,
+				Match:    fmt.Sprintf("%s,nw_dst=%s,tp_dst=53", proto, allocator.DNSAddress()),
+				Actions:  fmt.Sprintf("output:%s", port),
+			})
+		}
+
+		if subnet.IPv6CIDRBlock == "" {
+			continue
+		}
+		dns, err := IPv6DNSAddress(subnet.IPv6CIDRBlock)
+		if err != nil {
+			return nil, err
+		}
+		for _, proto := range []string{"udp6", "tcp6"} {
+			flows = append(flows, Flow{
+				Table:    TableClassifier,
+				Priority: PriorityClassifierDHCP,
+				Match:    fmt.Sprintf("%s,ipv6_dst=%s,tp_dst=53", proto, dns),
+				Actions:  fmt.Sprintf("output:%s", port),
+			})
+		}
+	}
+
+	return flows, nil
+}
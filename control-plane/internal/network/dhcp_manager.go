@@ -0,0 +1,280 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DHCPManager runs the DHCP and DNS responders of the VPC bridges of a node
+type DHCPManager interface {
+	// Sync makes the responder of a bridge serve config, starting it or
+	// restarting it on a new configuration, and reports whether it did
+	Sync(bridgeName string, config DHCPConfig) (bool, error)
+	// Stop stops the responder of a bridge and removes everything it left
+	Stop(bridgeName string) error
+	// List returns the bridges that have a responder
+	List() ([]string, error)
+}
+
+// dnsmasqManager implements DHCPManager with one dnsmasq per VPC bridge. The
+// responder's port is moved into a network namespace of its own, so dnsmasq
+// sees only its VPC and never collides with the host's or another VPC's
+// addresses.
+type dnsmasqManager struct {
+	dir     string
+	timeout time.Duration
+}
+
+// NewDNSMasqManager creates a DHCP manager keeping dnsmasq configuration,
+// pid and lease files in dir
+func NewDNSMasqManager(dir string) DHCPManager {
+	return &dnsmasqManager{
+		dir:     dir,
+		timeout: 30 * time.Second,
+	}
+}
+
+// DHCPNamespace returns the network namespace of the responder of a bridge,
+// which the metadata service listens in as well
+func DHCPNamespace(bridgeName string) string {
+	return "gcp-" + DHCPPortName(bridgeName)
+}
+
+// Sync renders config and compares it with the configuration the responder
+// runs on. dnsmasq reads reservations from its configuration only at start,
+// so a changed configuration means a restart; leases are kept in the lease
+// file and every address is static, so instances do not notice. A port that
+// left the namespace, as internal ports do when Open vSwitch restarts, or
+// lost an address means a restart too.
+func (m *dnsmasqManager) Sync(bridgeName string, config DHCPConfig) (bool, error) {
+	rendered, err := RenderDNSMasqConfig(config)
+	if err != nil {
+		return false, fmt.Errorf("failed to render dnsmasq configuration: %w", err)
+	}
+	addresses, err := DHCPAddresses(config.Subnets)
+	if err != nil {
+		return false, err
+	}
+	addresses = append(addresses, MetadataAddress+"/32")
+
+	confPath := m.path(bridgeName, "conf")
+	current, err := os.ReadFile(confPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("failed to read dnsmasq configuration: %w", err)
+	}
+	namespace := DHCPNamespace(bridgeName)
+	if string(current) == rendered && m.running(bridgeName) && m.configured(namespace, config.Interface, addresses) {
+		return false, nil
+	}
+
+	if err := m.setupNamespace(namespace, config.Interface, addresses, config.Gateway); err != nil {
+		return false, err
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", m.dir, err)
+	}
+	if err := os.WriteFile(confPath, []byte(rendered), 0o644); err != nil {
+		return false, fmt.Errorf("failed to write dnsmasq configuration: %w", err)
+	}
+
+	if err := m.kill(bridgeName); err != nil {
+		return false, err
+	}
+	cmd := exec.Command("ip", "netns", "exec", namespace, "dnsmasq",
+		"--conf-file="+confPath,
+		"--pid-file="+m.path(bridgeName, "pid"),
+		"--dhcp-leasefile="+m.path(bridgeName, "leases"))
+	if err := m.runCommand(cmd); err != nil {
+		return false, fmt.Errorf("failed to start dnsmasq: %w", err)
+	}
+
+	return true, nil
+}
+
+// setupNamespace moves the responder's port into its namespace and gives it
+// addresses and the MAC metadata requests are sent to. Forwarded queries
+// leave through the VPC bridge's own address, which is outside every
+// subnet, hence onlink.
+func (m *dnsmasqManager) setupNamespace(namespace, port string, addresses []string, gateway string) error {
+	if _, err := os.Stat(filepath.Join(netnsDir, namespace)); err != nil {
+		if err := m.runCommand(exec.Command("ip", "netns", "add", namespace)); err != nil {
+			return fmt.Errorf("failed to create namespace %s: %w", namespace, err)
+		}
+	}
+
+	if !m.inNamespace(namespace, port) {
+		if err := m.runCommand(exec.Command("ip", "link", "set", port, "netns", namespace)); err != nil {
+			return fmt.Errorf("failed to move port %s into namespace %s: %w", port, namespace, err)
+		}
+	}
+
+	commands := [][]string{
+		{"ip", "-n", namespace, "link", "set", "lo", "up"},
+		{"ip", "-n", namespace, "link", "set", port, "address", MetadataMAC()},
+		{"ip", "-n", namespace, "addr", "flush", "dev", port},
+	}
+	for _, address := range addresses {
+		command := []string{"ip", "-n", namespace, "addr", "add", address, "dev", port}
+		if IsIPv6(address) {
+			// Nothing else on the bridge holds the address, and dnsmasq
+			// cannot bind to it while duplicate address detection runs
+			command = append(command, "nodad")
+		}
+		commands = append(commands, command)
+	}
+	commands = append(commands, []string{"ip", "-n", namespace, "link", "set", port, "up"})
+	if gateway != "" {
+		commands = append(commands, []string{"ip", "-n", namespace, "route", "replace", "default", "via", gateway, "dev", port, "onlink"})
+	}
+
+	for _, args := range commands {
+		if err := m.runCommand(exec.Command(args[0], args[1:]...)); err != nil {
+			return fmt.Errorf("failed to configure namespace %s: %s: %w", namespace, strings.Join(args[3:], " "), err)
+		}
+	}
+
+	return nil
+}
+
+// inNamespace reports whether port is in namespace
+func (m *dnsmasqManager) inNamespace(namespace, port string) bool {
+	return m.runCommand(exec.Command("ip", "-n", namespace, "link", "show", port)) == nil
+}
+
+// configured reports whether port is in namespace and holds every address
+func (m *dnsmasqManager) configured(namespace, port string, addresses []string) bool {
+	cmd := exec.Command("ip", "-n", namespace, "-o", "addr", "show", "dev", port)
+	output, err := m.runCommandWithOutput(cmd)
+	if err != nil {
+		return false
+	}
+	held := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[i] == "inet" || fields[i] == "inet6" {
+				held[fields[i+1]] = true
+			}
+		}
+	}
+	for _, address := range addresses {
+		if !held[address] {
+			return false
+		}
+	}
+	return true
+}
+
+// Stop kills the responder of a bridge and removes its namespace and files
+func (m *dnsmasqManager) Stop(bridgeName string) error {
+	if err := m.kill(bridgeName); err != nil {
+		return err
+	}
+
+	namespace := DHCPNamespace(bridgeName)
+	if _, err := os.Stat(filepath.Join(netnsDir, namespace)); err == nil {
+		if err := m.runCommand(exec.Command("ip", "netns", "del", namespace)); err != nil {
+			return fmt.Errorf("failed to delete namespace %s: %w", namespace, err)
+		}
+	}
+
+	for _, ext := range []string{"leases", "conf"} {
+		if err := os.Remove(m.path(bridgeName, ext)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove dnsmasq %s file: %w", ext, err)
+		}
+	}
+
+	return nil
+}
+
+// List returns the bridges that have a dnsmasq configuration
+func (m *dnsmasqManager) List() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(m.dir, VPCBridgePrefix+"*.conf"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dnsmasq configurations: %w", err)
+	}
+
+	bridges := make([]string, 0, len(paths))
+	for _, path := range paths {
+		bridges = append(bridges, strings.TrimSuffix(filepath.Base(path), ".conf"))
+	}
+	return bridges, nil
+}
+
+// path returns the dnsmasq file of a bridge with the given extension
+func (m *dnsmasqManager) path(bridgeName, ext string) string {
+	return filepath.Join(m.dir, bridgeName+"."+ext)
+}
+
+// pid returns the process ID in the pid file of a bridge, or 0 if there is
+// none
+func (m *dnsmasqManager) pid(bridgeName string) int {
+	data, err := os.ReadFile(m.path(bridgeName, "pid"))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
+
+// running reports whether the responder of a bridge is alive
+func (m *dnsmasqManager) running(bridgeName string) bool {
+	pid := m.pid(bridgeName)
+	return pid != 0 && syscall.Kill(pid, 0) == nil
+}
+
+// kill stops the responder of a bridge and waits for it to exit, so a new
+// one can bind the same addresses
+func (m *dnsmasqManager) kill(bridgeName string) error {
+	pid := m.pid(bridgeName)
+	if pid != 0 {
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("failed to stop dnsmasq %d: %w", pid, err)
+		}
+		deadline := time.Now().Add(m.timeout)
+		for syscall.Kill(pid, 0) == nil {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("dnsmasq %d did not exit", pid)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	if err := os.Remove(m.path(bridgeName, "pid")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove dnsmasq pid file: %w", err)
+	}
+	return nil
+}
+
+// runCommand executes a command, killing it when it outlives the timeout
+func (m *dnsmasqManager) runCommand(cmd *exec.Cmd) error {
+	timer := time.AfterFunc(m.timeout, func() {
+		cmd.Process.Kill()
+	})
+	defer timer.Stop()
+
+	return cmd.Run()
+}
+
+// runCommandWithOutput executes a command and returns its output, killing it
+// when it outlives the timeout
+func (m *dnsmasqManager) runCommandWithOutput(cmd *exec.Cmd) (string, error) {
+	timer := time.AfterFunc(m.timeout, func() {
+		cmd.Process.Kill()
+	})
+	defer timer.Stop()
+
+	output, err := cmd.Output()
+	return string(output), err
+}
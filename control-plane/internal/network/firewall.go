@@ -0,0 +1,319 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// OpenFlow tables of the VPC bridge pipeline. The network ACL and conntrack
+// tables in between are defined in acl.go and conntrack.go.
+const (
+	// TableClassifier is the entry table every packet starts in
+	TableClassifier = 0
+	// TableSecurityGroupEgress filters traffic leaving an instance
+	TableSecurityGroupEgress = 20
+	// TableSecurityGroupIngress filters traffic arriving at an instance
+	TableSecurityGroupIngress = 30
+)
+
+// Flow priorities used by the security group tables
+const (
+	PriorityFirewallDefault = 1
+	PriorityFirewallIsolate = 10
+	PriorityFirewallAllow   = 100
+	PriorityClassifierARP   = 200
+	PriorityClassifierIP    = 100
+)
+
+// FirewallRule is a security group rule whose remote side has been resolved
+// to CIDR blocks. For inbound rules the remotes are sources, for outbound
+// rules they are destinations.
+type FirewallRule struct {
+	Direction string // inbound, outbound
+	Protocol  string // tcp, udp, icmp, icmpv6, all
+	FromPort  int
+	ToPort    int
+	Remotes   []string
+}
+
+// PortMask is a port/mask pair matching an aligned block of ports
+type PortMask struct {
+	Port uint16
+	Mask uint16
+}
+
+// String formats the pair the way ovs-ofctl expects for tp_src/tp_dst
+func (pm PortMask) String() string {
+	if pm.Mask == 0xffff {
+		return fmt.Sprintf("%d", pm.Port)
+	}
+	return fmt.Sprintf("0x%04x/0x%04x", pm.Port, pm.Mask)
+}
+
+// PortRangeMasks splits the inclusive range [from, to] into the minimal set of
+// port/mask pairs, so a range like 1000-1999 becomes 7 flows instead of 1000
+func PortRangeMasks(from, to int) []PortMask {
+	masks := make([]PortMask, 0)
+	if from < 0 || to > 0xffff || from > to {
+		return masks
+	}
+
+	for from <= to {
+		// Largest power-of-two block aligned at from
+		size := from & -from
+		if from == 0 {
+			size = 0x10000
+		}
+		for size > to-from+1 {
+			size >>= 1
+		}
+
+		masks = append(masks, PortMask{
+			Port: uint16(from),
+			Mask: uint16(0xffff &^ (size - 1)),
+		})
+		from += size
+	}
+
+	return masks
+}
+
+// BasePipelineFlows returns the flows every VPC bridge needs before any
+// security group or network ACL is programmed: ARP and neighbor discovery
+// are switched normally, IP traffic of either family passes the network ACL
+// tables, is tracked in the VPC's conntrack zone and new connections are
+// sent through the egress and ingress security group tables before being
+// routed. Router advertisements are dropped, so only the VPC's responder,
+// whose traffic the DHCP flows let through, advertises routers. Subnets
+// without an ACL and addresses that are not security group members fall
+// through their tables untouched, and no traffic is sampled by the flow log
+// tables.
+func BasePipelineFlows(ctZone int) []Flow {
+	flows := []Flow{
+		{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierARP,
+			Match:    "arp",
+			Actions:  "NORMAL",
+		},
+	}
+	for _, icmpType := range []int{ICMPv6RouterSolicitation, ICMPv6NeighborSolicitation, ICMPv6NeighborAdvertisement} {
+		flows = append(flows, Flow{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierARP,
+			Match:    fmt.Sprintf("icmp6,icmpv6_type=%d", icmpType),
+			Actions:  "NORMAL",
+		})
+	}
+	flows = append(flows, Flow{
+		Table:    TableClassifier,
+		Priority: PriorityClassifierARP,
+		Match:    fmt.Sprintf("icmp6,icmpv6_type=%d", ICMPv6RouterAdvertisement),
+		Actions:  "drop",
+	})
+	for _, family := range []ipFamily{ipv4Family, ipv6Family} {
+		flows = append(flows, Flow{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierIP,
+			Match:    family.ip,
+			Actions:  fmt.Sprintf("goto_table:%d", TableNetworkACLEgress),
+		})
+	}
+
+	flows = append(flows,
+		Flow{
+			Table:    TableNetworkACLEgress,
+			Priority: PriorityNetworkACLDefault,
+			Actions:  fmt.Sprintf("goto_table:%d", TableNetworkACLIngress),
+		},
+		Flow{
+			Table:    TableNetworkACLIngress,
+			Priority: PriorityNetworkACLDefault,
+			Actions:  fmt.Sprintf("goto_table:%d", TableConntrack),
+		},
+		Flow{
+			Table:    TableSecurityGroupEgress,
+			Priority: PriorityFirewallDefault,
+			Actions:  fmt.Sprintf("goto_table:%d", TableSecurityGroupIngress),
+		},
+		Flow{
+			Table:    TableSecurityGroupIngress,
+			Priority: PriorityFirewallDefault,
+			Actions:  fmt.Sprintf("goto_table:%d", TableConntrackCommit),
+		},
+	)
+
+	flows = append(flows, ConntrackFlows(ctZone)...)
+	return append(flows, FlowLogBaseFlows()...)
+}
+
+// IsolationFlows returns the default-deny flows for an address that belongs
+// to at least one security group. Rule flows sit above them.
+func IsolationFlows(memberIP string) []Flow {
+	family := ipv4Family
+	if IsIPv6(memberIP) {
+		family = ipv6Family
+	}
+	return []Flow{
+		{
+			Table:    TableSecurityGroupEgress,
+			Priority: PriorityFirewallIsolate,
+			Match:    fmt.Sprintf("%s,%s=%s", family.ip, family.src, memberIP),
+			Actions:  RejectActions,
+		},
+		{
+			Table:    TableSecurityGroupIngress,
+			Priority: PriorityFirewallIsolate,
+			Match:    fmt.Sprintf("%s,%s=%s", family.ip, family.dst, memberIP),
+			Actions:  RejectActions,
+		},
+	}
+}
+
+// CompileFirewallRule expands one rule into the allow flows for every member
+// address. Port ranges are expanded with PortRangeMasks. Rules only see new
+// connections; replies are admitted by the conntrack state table. Members
+// are only paired with remotes of their own family, and icmp and icmpv6
+// rules only with remotes of theirs.
+func CompileFirewallRule(memberIPs []string, rule FirewallRule) ([]Flow, error) {
+	type remoteMatch struct {
+		family ipFamily
+		match  string
+	}
+	remotes := make([]remoteMatch, 0, len(rule.Remotes))
+	for _, remote := range rule.Remotes {
+		family, err := familyOf(remote)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote CIDR %s: %w", remote, err)
+		}
+		if !family.carries(rule.Protocol) {
+			continue
+		}
+		match, err := remoteCIDRMatch(remote)
+		if err != nil {
+			return nil, err
+		}
+		remotes = append(remotes, remoteMatch{family: family, match: match})
+	}
+
+	var table int
+	var actions string
+	inbound := false
+	switch rule.Direction {
+	case "inbound":
+		table = TableSecurityGroupIngress
+		actions = fmt.Sprintf("goto_table:%d", TableConntrackCommit)
+		inbound = true
+	case "outbound":
+		table = TableSecurityGroupEgress
+		actions = fmt.Sprintf("goto_table:%d", TableSecurityGroupIngress)
+	default:
+		return nil, fmt.Errorf("invalid rule direction: %s", rule.Direction)
+	}
+
+	protoMatches := make(map[string][]string)
+	flows := make([]Flow, 0)
+	for _, member := range memberIPs {
+		family, err := familyOf(member)
+		if err != nil {
+			return nil, err
+		}
+		localField, remoteField := family.src, family.dst
+		if inbound {
+			localField, remoteField = family.dst, family.src
+		}
+
+		for _, remote := range remotes {
+			if remote.family != family {
+				continue
+			}
+			matches, ok := protoMatches[family.ip]
+			if !ok {
+				if matches, err = protocolMatches(rule, family); err != nil {
+					return nil, err
+				}
+				protoMatches[family.ip] = matches
+			}
+
+			for _, proto := range matches {
+				parts := []string{proto, fmt.Sprintf("%s=%s", localField, member)}
+				if remote.match != "" {
+					parts = append(parts, fmt.Sprintf("%s=%s", remoteField, remote.match))
+				}
+				flows = append(flows, Flow{
+					Table:    table,
+					Priority: PriorityFirewallAllow,
+					Match:    strings.Join(parts, ","),
+					Actions:  actions,
+				})
+			}
+		}
+	}
+
+	return flows, nil
+}
+
+// protocolMatches returns the protocol part of each match for traffic of a
+// family, one per port mask
+func protocolMatches(rule FirewallRule, family ipFamily) ([]string, error) {
+	switch rule.Protocol {
+	case "all":
+		return []string{family.ip}, nil
+	case "icmp", "icmpv6":
+		if !family.carries(rule.Protocol) {
+			return nil, fmt.Errorf("protocol %s does not apply to %s traffic", rule.Protocol, family.ip)
+		}
+		match := family.icmp
+		if rule.FromPort >= 0 {
+			match += fmt.Sprintf(",%s=%d", family.icmpType, rule.FromPort)
+			if rule.ToPort >= 0 {
+				match += fmt.Sprintf(",%s=%d", family.icmpCode, rule.ToPort)
+			}
+		}
+		return []string{match}, nil
+	case "tcp", "udp":
+		keyword := family.tcp
+		if rule.Protocol == "udp" {
+			keyword = family.udp
+		}
+		masks := PortRangeMasks(rule.FromPort, rule.ToPort)
+		if len(masks) == 0 {
+			return nil, fmt.Errorf("invalid port range %d-%d", rule.FromPort, rule.ToPort)
+		}
+		if rule.FromPort == 0 && rule.ToPort == 0xffff {
+			return []string{keyword}, nil
+		}
+		matches := make([]string, len(masks))
+		for i, mask := range masks {
+			matches[i] = fmt.Sprintf("%s,tp_dst=%s", keyword, mask)
+		}
+		return matches, nil
+	default:
+		return nil, fmt.Errorf("unsupported protocol: %s", rule.Protocol)
+	}
+}
+
+// remoteCIDRMatch returns the source or destination value matching an
+// address or CIDR block of either family, or an empty string when the block
+// matches every address of its family
+func remoteCIDRMatch(cidr string) (string, error) {
+	if ip := net.ParseIP(cidr); ip != nil {
+		return ip.String(), nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("invalid remote CIDR %s: %w", cidr, err)
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	switch ones {
+	case 0:
+		return "", nil
+	case bits:
+		return ipNet.IP.String(), nil
+	default:
+		return ipNet.String(), nil
+	}
+}
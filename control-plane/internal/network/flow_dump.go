@@ -0,0 +1,180 @@
+package network
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultFlowPriority is the priority of flows added without one. Dumps
+// omit the priority of such flows.
+const DefaultFlowPriority = 32768
+
+// Flags ovs-ofctl prints as bare keywords in front of the match
+var flowFlags = map[string]bool{
+	"send_flow_rem":    true,
+	"check_overlap":    true,
+	"reset_counts":     true,
+	"no_packet_counts": true,
+	"no_byte_counts":   true,
+}
+
+// dumpReplyHeader matches the reply headers of every OpenFlow version, such
+// as "NXST_FLOW reply (xid=0x4):" and "OFPST_FLOW reply (OF1.3) (xid=0x2):"
+var dumpReplyHeader = regexp.MustCompile(`^[A-Z]+_FLOW reply\b`)
+
+// actionsPrefix finds where the actions start: after a space in dumps and
+// after a comma in add-flow specifications
+var actionsPrefix = regexp.MustCompile(`(^|[ ,])actions=`)
+
+// ParseDumpFlows parses the output of ovs-ofctl dump-flows, with or without
+// --no-stats, for any OpenFlow version. Reply headers and blank lines are
+// skipped; any other line that is not a flow is an error.
+func ParseDumpFlows(output string) ([]Flow, error) {
+	flows := make([]Flow, 0)
+	for i, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || dumpReplyHeader.MatchString(line) {
+			continue
+		}
+
+		flow, err := ParseFlowLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		flows = append(flows, flow)
+	}
+
+	return flows, nil
+}
+
+// ParseFlowLine parses one flow as printed by ovs-ofctl dump-flows or as
+// written by FormatFlowSpec. Statistics and flow properties fill the fields
+// of the same name, every other term before the actions is kept, in order,
+// as the match.
+func ParseFlowLine(line string) (Flow, error) {
+	flow := Flow{Priority: DefaultFlowPriority}
+
+	loc := actionsPrefix.FindStringIndex(line)
+	if loc == nil {
+		return Flow{}, fmt.Errorf("flow has no actions: %s", line)
+	}
+	flow.Actions = strings.TrimSpace(line[loc[1]:])
+	if flow.Actions == "" {
+		return Flow{}, fmt.Errorf("flow has empty actions: %s", line)
+	}
+
+	var match []string
+	for _, term := range splitFlowTerms(line[:loc[0]]) {
+		name, value, hasValue := strings.Cut(term, "=")
+		if !hasValue {
+			if flowFlags[name] {
+				flow.Flags = append(flow.Flags, name)
+			} else {
+				match = append(match, term)
+			}
+			continue
+		}
+
+		var err error
+		switch name {
+		case "cookie":
+			flow.Cookie = value
+		case "duration":
+			flow.Duration, err = strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+		case "table":
+			flow.Table, err = strconv.Atoi(value)
+		case "n_packets":
+			flow.PacketCount, err = strconv.ParseInt(value, 10, 64)
+		case "n_bytes":
+			flow.ByteCount, err = strconv.ParseInt(value, 10, 64)
+		case "idle_age":
+			flow.IdleAge, err = strconv.Atoi(value)
+		case "hard_age":
+			flow.HardAge, err = strconv.Atoi(value)
+		case "idle_timeout":
+			flow.IdleTimeout, err = strconv.Atoi(value)
+		case "hard_timeout":
+			flow.HardTimeout, err = strconv.Atoi(value)
+		case "importance":
+			flow.Importance, err = strconv.Atoi(value)
+		case "priority":
+			flow.Priority, err = strconv.Atoi(value)
+		default:
+			match = append(match, term)
+		}
+		if err != nil {
+			return Flow{}, fmt.Errorf("invalid %s: %s", name, value)
+		}
+	}
+	flow.Match = strings.Join(match, ",")
+
+	return flow, nil
+}
+
+// FormatFlowSpec builds the ovs-ofctl add-flow specification of a flow.
+// Statistics are left out; everything else survives a round trip through
+// ParseFlowLine. The priority is always written, so priority 0 stays 0
+// rather than becoming DefaultFlowPriority.
+func FormatFlowSpec(flow Flow) string {
+	parts := make([]string, 0, 8)
+
+	if flow.Cookie != "" {
+		parts = append(parts, "cookie="+flow.Cookie)
+	}
+	if flow.Table > 0 {
+		parts = append(parts, fmt.Sprintf("table=%d", flow.Table))
+	}
+	if flow.IdleTimeout > 0 {
+		parts = append(parts, fmt.Sprintf("idle_timeout=%d", flow.IdleTimeout))
+	}
+	if flow.HardTimeout > 0 {
+		parts = append(parts, fmt.Sprintf("hard_timeout=%d", flow.HardTimeout))
+	}
+	if flow.Importance > 0 {
+		parts = append(parts, fmt.Sprintf("importance=%d", flow.Importance))
+	}
+	parts = append(parts, flow.Flags...)
+	parts = append(parts, fmt.Sprintf("priority=%d", flow.Priority))
+	if flow.Match != "" {
+		parts = append(parts, flow.Match)
+	}
+	if flow.Actions != "" {
+		parts = append(parts, "actions="+flow.Actions)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// splitFlowTerms splits the part of a flow in front of its actions into
+// terms. Dumps separate them with commas and spaces alike, as in
+// "n_bytes=0, reset_counts priority=0,ip"; separators inside parentheses or
+// quoted port names do not split.
+func splitFlowTerms(s string) []string {
+	var terms []string
+	depth, quoted, start := 0, false, 0
+	flush := func(end int) {
+		if term := strings.TrimSpace(s[start:end]); term != "" {
+			terms = append(terms, term)
+		}
+	}
+
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case (c == ',' || c == ' ') && depth == 0:
+			flush(i)
+			start = i + 1
+		}
+	}
+	flush(len(s))
+
+	return terms
+}
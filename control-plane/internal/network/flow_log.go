@@ -0,0 +1,149 @@
+package network
+
+import (
+	"fmt"
+)
+
+// Flow log stages of the VPC bridge pipeline. Traffic the network ACLs and
+// security groups let through passes the accept table on its way to
+// routing, and traffic they turn away ends in the reject table. Traffic of
+// a range a flow log covers is sampled in either table and exported over
+// IPFIX, whose flow cache adds up the packets of a connection into one
+// record. Each packet is logged on the node whose pipeline decided about it.
+const (
+	// TableFlowLogAccept samples accepted traffic and routes it
+	TableFlowLogAccept = 45
+	// TableFlowLogReject samples rejected traffic and drops it
+	TableFlowLogReject = 90
+)
+
+// Flow priorities used by the flow log tables. Flow logs of narrower ranges
+// sit above broader ones, so a packet is logged once, by the most specific
+// flow log that covers it.
+const (
+	PriorityFlowLogBase    = 100
+	PriorityFlowLogDefault = 1
+)
+
+// RejectActions are the actions of flows that turn traffic away on behalf
+// of a network ACL or security group
+var RejectActions = fmt.Sprintf("goto_table:%d", TableFlowLogReject)
+
+// FlowLogCollectorSetID is the collector set flow log samples are exported
+// through. Every VPC bridge has its own set with this ID.
+const FlowLogCollectorSetID = 1
+
+// Traffic types a flow log can capture
+const (
+	FlowLogTrafficAccept = "accept"
+	FlowLogTrafficReject = "reject"
+	FlowLogTrafficAll    = "all"
+)
+
+// Actions of flow log records
+const (
+	FlowLogActionAccept = "accept"
+	FlowLogActionReject = "reject"
+)
+
+// FlowLogScope is the range a flow log covers on a VPC bridge
+type FlowLogScope struct {
+	// Number identifies the flow log in the records it is exported with
+	Number int
+	// CIDRBlock is the VPC, subnet or instance address range
+	CIDRBlock string
+	// TrafficType is accept, reject or all
+	TrafficType string
+}
+
+// FlowLogObservationPoint returns the IPFIX observation point ID the samples
+// of a flow log are exported with: the number of the flow log, with the
+// lowest bit set for rejected traffic
+func FlowLogObservationPoint(number int, action string) uint32 {
+	point := uint32(number) << 1
+	if action == FlowLogActionReject {
+		point |= 1
+	}
+	return point
+}
+
+// ParseFlowLogObservationPoint returns the flow log number and action an
+// observation point ID was made from
+func ParseFlowLogObservationPoint(point uint32) (int, string) {
+	if point&1 == 1 {
+		return int(point >> 1), FlowLogActionReject
+	}
+	return int(point >> 1), FlowLogActionAccept
+}
+
+// FlowLogBaseFlows returns the defaults of the flow log tables every VPC
+// bridge needs: accepted traffic is routed and rejected traffic dropped
+func FlowLogBaseFlows() []Flow {
+	return []Flow{
+		{
+			Table:    TableFlowLogAccept,
+			Priority: PriorityFlowLogDefault,
+			Actions:  fmt.Sprintf("goto_table:%d", TableRouting),
+		},
+		{
+			Table:    TableFlowLogReject,
+			Priority: PriorityFlowLogDefault,
+			Actions:  "drop",
+		},
+	}
+}
+
+// CompileFlowLogs builds the flows that sample the traffic of the flow logs
+// of a VPC bridge. Samples carry the VPC's conntrack zone as their
+// observation domain, which tells the collector the VPC they belong to.
+func CompileFlowLogs(ctZone int, scopes []FlowLogScope) ([]Flow, error) {
+	if ctZone < 1 || ctZone > 65535 {
+		return nil, fmt.Errorf("invalid conntrack zone: %d", ctZone)
+	}
+
+	flows := make([]Flow, 0)
+	for _, scope := range scopes {
+		ipNet, err := ParseIPv4CIDR(scope.CIDRBlock)
+		if err != nil {
+			return nil, err
+		}
+		if scope.Number < 1 || scope.Number > 0x7fffffff {
+			return nil, fmt.Errorf("invalid flow log number: %d", scope.Number)
+		}
+		prefixLen, _ := ipNet.Mask.Size()
+		priority := PriorityFlowLogBase + prefixLen
+
+		switch scope.TrafficType {
+		case FlowLogTrafficAccept, FlowLogTrafficReject, FlowLogTrafficAll:
+		default:
+			return nil, fmt.Errorf("invalid traffic type: %s", scope.TrafficType)
+		}
+		stages := []struct {
+			table   int
+			action  string
+			next    string
+			enabled bool
+		}{
+			{TableFlowLogAccept, FlowLogActionAccept, fmt.Sprintf(",goto_table:%d", TableRouting), scope.TrafficType != FlowLogTrafficReject},
+			{TableFlowLogReject, FlowLogActionReject, "", scope.TrafficType != FlowLogTrafficAccept},
+		}
+
+		for _, stage := range stages {
+			if !stage.enabled {
+				continue
+			}
+			sample := fmt.Sprintf("sample(probability=65535,collector_set_id=%d,obs_domain_id=%d,obs_point_id=%d)",
+				FlowLogCollectorSetID, ctZone, FlowLogObservationPoint(scope.Number, stage.action))
+			for _, field := range []string{"nw_src", "nw_dst"} {
+				flows = append(flows, Flow{
+					Table:    stage.table,
+					Priority: priority,
+					Match:    fmt.Sprintf("ip,%s=%s", field, ipNet.String()),
+					Actions:  sample + stage.next,
+				})
+			}
+		}
+	}
+
+	return flows, nil
+}
@@ -0,0 +1,278 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FlowSet is everything one owner, or every owner of a kind, wants on a
+// bridge: once applied with ReplaceFlows, the flows selected by Cookie/Mask
+// are exactly Flows
+type FlowSet struct {
+	Cookie string
+	Mask   string
+	Flows  []Flow
+}
+
+// Selects reports whether a flow on the bridge belongs to the set
+func (s *FlowSet) Selects(flow Flow) (bool, error) {
+	return CookieMatches(flow.Cookie, s.Cookie, s.Mask)
+}
+
+// Apply replaces the flows of the set on a bridge in one bundle
+func (s *FlowSet) Apply(m OVSManager, bridgeName string) error {
+	return m.ReplaceFlows(bridgeName, s.Cookie, s.Mask, s.Flows)
+}
+
+// Drift compares the set with the flows of a bridge. It returns the flows of
+// the set the bridge lacks and the flows of the bridge selected by the set
+// that the set does not contain. Flows are compared by owner, table,
+// priority, match and actions; statistics and timeouts are ignored.
+func (s *FlowSet) Drift(actual []Flow) (missing []Flow, stale []Flow, err error) {
+	wanted := make(map[string]struct{}, len(s.Flows))
+	for _, flow := range s.Flows {
+		if flow.Cookie == "" {
+			flow.Cookie = s.Cookie
+		}
+		key, err := flowSignature(flow)
+		if err != nil {
+			return nil, nil, err
+		}
+		wanted[key] = struct{}{}
+	}
+
+	present := make(map[string]struct{}, len(actual))
+	for _, flow := range actual {
+		selected, err := s.Selects(flow)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !selected {
+			continue
+		}
+		key, err := flowSignature(flow)
+		if err != nil {
+			return nil, nil, err
+		}
+		present[key] = struct{}{}
+		if _, ok := wanted[key]; !ok {
+			stale = append(stale, flow)
+		}
+	}
+
+	for _, flow := range s.Flows {
+		if flow.Cookie == "" {
+			flow.Cookie = s.Cookie
+		}
+		key, _ := flowSignature(flow)
+		if _, ok := present[key]; !ok {
+			missing = append(missing, flow)
+		}
+	}
+
+	return missing, stale, nil
+}
+
+// UniqueFlows drops the flows that match and act like an earlier flow,
+// whatever their owner. OVS keeps one flow per table, priority and match,
+// so owners compiling the same flow would otherwise take turns owning it.
+func UniqueFlows(flows []Flow) []Flow {
+	seen := make(map[string]struct{}, len(flows))
+	unique := make([]Flow, 0, len(flows))
+	for _, flow := range flows {
+		owner := flow
+		owner.Cookie = ""
+		key, _ := flowSignature(owner)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, flow)
+	}
+	return unique
+}
+
+// flowSignature identifies a flow by what it does. Cookies are compared by
+// value, and match terms and actions are brought into one notation and the
+// terms sorted, since ovs-ofctl prints all of them in its own notation and
+// order.
+func flowSignature(flow Flow) (string, error) {
+	var cookie uint64
+	if flow.Cookie != "" {
+		var err error
+		if cookie, err = ParseCookie(flow.Cookie); err != nil {
+			return "", err
+		}
+	}
+
+	terms := splitFlowTerms(flow.Match)
+	for i, term := range terms {
+		terms[i] = canonicalMatchTerm(term)
+	}
+	sort.Strings(terms)
+
+	return fmt.Sprintf("%x/%d/%d/%s/%s", cookie, flow.Table, flow.Priority, strings.Join(terms, ","), canonicalActions(flow.Actions)), nil
+}
+
+// matchFieldAliases maps match field names to the ones ovs-ofctl prints.
+// ICMPv6 type and code are printed under the ICMP names.
+var matchFieldAliases = map[string]string{
+	"eth_src":     "dl_src",
+	"eth_dst":     "dl_dst",
+	"eth_type":    "dl_type",
+	"ip_src":      "nw_src",
+	"ip_dst":      "nw_dst",
+	"ip_proto":    "nw_proto",
+	"icmpv6_type": "icmp_type",
+	"icmpv6_code": "icmp_code",
+}
+
+// ctStateFlags are the ct_state flags in bit order, which is the order
+// ovs-ofctl prints them in
+var ctStateFlags = []string{"new", "est", "rel", "rpl", "inv", "trk", "snat", "dnat"}
+
+// canonicalMatchTerm rewrites a match term into one notation: field aliases
+// are resolved, ct_state flags put in bit order, numbers and masks written
+// as unpadded hex, and addresses, MACs and CIDR blocks in their canonical
+// form, with host prefixes written as bare addresses
+func canonicalMatchTerm(term string) string {
+	name, value, ok := strings.Cut(term, "=")
+	if !ok {
+		return term
+	}
+	if alias, ok := matchFieldAliases[name]; ok {
+		name = alias
+	}
+
+	switch name {
+	case "ct_state":
+		value = canonicalCTState(value)
+	case "in_port":
+		value = strings.Trim(value, `"`)
+	default:
+		value = canonicalMatchValue(value)
+	}
+	return name + "=" + value
+}
+
+// canonicalCTState orders ct_state flags such as +trk+est by bit
+func canonicalCTState(value string) string {
+	signs := make(map[string]byte)
+	for rest := value; rest != ""; {
+		sign := rest[0]
+		if sign != '+' && sign != '-' {
+			return value
+		}
+		rest = rest[1:]
+		end := strings.IndexAny(rest, "+-")
+		if end < 0 {
+			end = len(rest)
+		}
+		signs[rest[:end]] = sign
+		rest = rest[end:]
+	}
+
+	var b strings.Builder
+	for _, flag := range ctStateFlags {
+		if sign, ok := signs[flag]; ok {
+			b.WriteByte(sign)
+			b.WriteString(flag)
+			delete(signs, flag)
+		}
+	}
+	if len(signs) > 0 {
+		// Flags this version does not know are left as they were written
+		return value
+	}
+	return b.String()
+}
+
+// canonicalMatchValue rewrites a value, with or without a mask, of any
+// field but ct_state
+func canonicalMatchValue(value string) string {
+	if _, ipNet, err := net.ParseCIDR(value); err == nil {
+		if ones, bits := ipNet.Mask.Size(); ones == bits {
+			return ipNet.IP.String()
+		}
+		return ipNet.String()
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.String()
+	}
+
+	v, mask, masked := strings.Cut(value, "/")
+	if n, err := parseFlowNumber(v); err == nil {
+		if !masked {
+			return fmt.Sprintf("%#x", n)
+		}
+		if m, err := parseFlowNumber(mask); err == nil {
+			return fmt.Sprintf("%#x/%#x", n, m)
+		}
+	}
+	if hw, err := net.ParseMAC(v); err == nil {
+		if !masked {
+			return hw.String()
+		}
+		if hwMask, err := net.ParseMAC(mask); err == nil {
+			return hw.String() + "/" + hwMask.String()
+		}
+	}
+	return value
+}
+
+// ctArgumentOrder is the order ovs-ofctl prints the leading arguments of
+// ct() in; nat() and anything else follow
+var ctArgumentOrder = []string{"commit", "force", "table", "zone"}
+
+// canonicalActions writes the values of load actions as unpadded hex and the
+// arguments of ct() in order, the way ovs-ofctl prints them
+func canonicalActions(actions string) string {
+	terms := splitFlowTerms(actions)
+	for i, term := range terms {
+		if args, ok := strings.CutPrefix(term, "ct("); ok && strings.HasSuffix(args, ")") {
+			terms[i] = "ct(" + canonicalCTArguments(strings.TrimSuffix(args, ")")) + ")"
+			continue
+		}
+
+		arg, ok := strings.CutPrefix(term, "load:")
+		if !ok {
+			continue
+		}
+		value, target, ok := strings.Cut(arg, "->")
+		if !ok {
+			continue
+		}
+		if n, err := parseFlowNumber(value); err == nil {
+			terms[i] = fmt.Sprintf("load:%#x->%s", n, target)
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+// canonicalCTArguments orders the arguments of a ct() action
+func canonicalCTArguments(args string) string {
+	rank := func(arg string) int {
+		name, _, _ := strings.Cut(arg, "=")
+		for i, known := range ctArgumentOrder {
+			if name == known {
+				return i
+			}
+		}
+		return len(ctArgumentOrder)
+	}
+
+	terms := splitFlowTerms(args)
+	sort.SliceStable(terms, func(i, j int) bool { return rank(terms[i]) < rank(terms[j]) })
+	return strings.Join(terms, ",")
+}
+
+// parseFlowNumber parses a decimal or 0x-prefixed hexadecimal number
+func parseFlowNumber(s string) (uint64, error) {
+	if hex, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		return strconv.ParseUint(hex, 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
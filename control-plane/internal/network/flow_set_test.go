@@ -0,0 +1,179 @@
+package network
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	driftVPCID = "a1b2c3d4-e5f6-4711-8899-aabbccddeeff"
+	driftSGID  = "0badf00d-1234-4567-89ab-cdef01234567"
+)
+
+// driftDump is what ovs-ofctl --names dump-flows prints for the flow sets of
+// driftSets: cookies, ct_state flags, masks, tunnel IDs, load values and
+// ct() arguments in its own notation, and match terms in its own order
+const driftDump = `NXST_FLOW reply (xid=0x4):
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=0, n_packets=4, n_bytes=168, idle_age=3, priority=200,arp actions=NORMAL
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=0, n_packets=0, n_bytes=0, idle_age=12, priority=200,icmp6,icmp_type=133 actions=NORMAL
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=0, n_packets=0, n_bytes=0, idle_age=12, priority=200,icmp6,icmp_type=135 actions=NORMAL
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=0, n_packets=0, n_bytes=0, idle_age=12, priority=200,icmp6,icmp_type=136 actions=NORMAL
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=0, n_packets=0, n_bytes=0, idle_age=12, priority=200,icmp6,icmp_type=134 actions=drop
+ cookie=0x6a1b2c3d4e5f647, duration=12.1s, table=0, n_packets=0, n_bytes=0, idle_age=12, priority=400,tun_id=0xa,tun_src=192.168.1.2,ip,in_port=tun0 actions=ct(table=80,zone=5)
+ cookie=0x6a1b2c3d4e5f647, duration=12.1s, table=0, n_packets=0, n_bytes=0, idle_age=12, priority=400,tun_id=0xa,tun_src=192.168.1.2,ipv6,in_port=tun0 actions=ct(table=80,zone=5)
+ cookie=0x6a1b2c3d4e5f647, duration=12.1s, table=0, n_packets=0, n_bytes=0, idle_age=12, priority=390,in_port=tun0 actions=drop
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=0, n_packets=9, n_bytes=882, idle_age=1, priority=100,ip actions=goto_table:2
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=0, n_packets=0, n_bytes=0, idle_age=12, priority=100,ipv6 actions=goto_table:2
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=2, n_packets=9, n_bytes=882, idle_age=1, priority=1 actions=goto_table:3
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=3, n_packets=9, n_bytes=882, idle_age=1, priority=1 actions=goto_table:5
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=5, n_packets=9, n_bytes=882, idle_age=1, priority=100,ip actions=ct(table=10,zone=5)
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=5, n_packets=0, n_bytes=0, idle_age=12, priority=100,ipv6 actions=ct(table=10,zone=5)
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=10, n_packets=0, n_bytes=0, idle_age=12, priority=300,ct_state=+inv+trk,ip actions=drop
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=10, n_packets=0, n_bytes=0, idle_age=12, priority=300,ct_state=+inv+trk,ipv6 actions=drop
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=10, n_packets=6, n_bytes=588, idle_age=1, priority=200,ct_state=+est+trk,ip actions=goto_table:45
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=10, n_packets=0, n_bytes=0, idle_age=12, priority=200,ct_state=+rel+trk,ip actions=goto_table:45
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=10, n_packets=0, n_bytes=0, idle_age=12, priority=200,ct_state=+est+trk,ipv6 actions=goto_table:45
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=10, n_packets=0, n_bytes=0, idle_age=12, priority=200,ct_state=+rel+trk,ipv6 actions=goto_table:45
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=10, n_packets=3, n_bytes=294, idle_age=2, priority=100,ct_state=+new+trk,ip actions=goto_table:20
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=10, n_packets=0, n_bytes=0, idle_age=12, priority=100,ct_state=+new+trk,ipv6 actions=goto_table:20
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=20, n_packets=3, n_bytes=294, idle_age=2, priority=1 actions=goto_table:30
+ cookie=0x20badf00d123445, duration=10.002s, table=30, n_packets=0, n_bytes=0, idle_age=10, priority=100,tcp,nw_src=10.0.0.0/16,nw_dst=10.0.1.5,tp_dst=0x400/0xfc00 actions=goto_table:40
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=30, n_packets=3, n_bytes=294, idle_age=2, priority=1 actions=goto_table:40
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=40, n_packets=3, n_bytes=294, idle_age=2, priority=100,ip actions=ct(commit,zone=5),goto_table:45
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=40, n_packets=0, n_bytes=0, idle_age=12, priority=100,ipv6 actions=ct(commit,zone=5),goto_table:45
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=45, n_packets=9, n_bytes=882, idle_age=1, priority=1 actions=goto_table:50
+ cookie=0x4a1b2c3d4e5f647, duration=11.9s, table=50, n_packets=9, n_bytes=882, idle_age=1, priority=1000,ip,nw_dst=10.0.0.0/16 actions=goto_table:55
+ cookie=0x4a1b2c3d4e5f647, duration=11.9s, table=50, n_packets=0, n_bytes=0, idle_age=11, priority=1000,ipv6,ipv6_dst=fd12:3456:7800::/56 actions=load:0x200->NXM_OF_ETH_DST[32..47],move:NXM_NX_IPV6_DST[40..47]->NXM_OF_ETH_DST[24..31],move:NXM_NX_IPV6_DST[0..23]->NXM_OF_ETH_DST[0..23],goto_table:55
+ cookie=0x4a1b2c3d4e5f647, duration=11.9s, table=50, n_packets=0, n_bytes=0, idle_age=11, priority=1 actions=drop
+ cookie=0x6a1b2c3d4e5f647, duration=12.1s, table=55, n_packets=9, n_bytes=882, idle_age=1, priority=1 actions=NORMAL
+ cookie=0x6a1b2c3d4e5f647, duration=12.1s, table=80, n_packets=0, n_bytes=0, idle_age=12, priority=300,ct_state=+inv+trk,ip actions=drop
+ cookie=0x6a1b2c3d4e5f647, duration=12.1s, table=80, n_packets=0, n_bytes=0, idle_age=12, priority=300,ct_state=+inv+trk,ipv6 actions=drop
+ cookie=0x6a1b2c3d4e5f647, duration=12.1s, table=80, n_packets=0, n_bytes=0, idle_age=12, priority=100,ct_state=+trk,ip actions=ct(commit,zone=5),NORMAL
+ cookie=0x6a1b2c3d4e5f647, duration=12.1s, table=80, n_packets=0, n_bytes=0, idle_age=12, priority=100,ct_state=+trk,ipv6 actions=ct(commit,zone=5),NORMAL
+ cookie=0x1a1b2c3d4e5f647, duration=12.345s, table=90, n_packets=0, n_bytes=0, idle_age=12, priority=1 actions=drop
+`
+
+// driftSets returns the flow sets the bridge of driftDump was programmed
+// with
+func driftSets(t *testing.T) map[string]*FlowSet {
+	t.Helper()
+
+	rule, err := CompileFirewallRule([]string{"10.0.1.5"}, FirewallRule{
+		Direction: "inbound",
+		Protocol:  "tcp",
+		FromPort:  1024,
+		ToPort:    2047,
+		Remotes:   []string{"10.0.0.0/16"},
+	})
+	if err != nil {
+		t.Fatalf("CompileFirewallRule: %v", err)
+	}
+	routes, err := CompileRoutes([]string{"10.0.0.0/16", "fd12:3456:7800::/56"}, nil, nil)
+	if err != nil {
+		t.Fatalf("CompileRoutes: %v", err)
+	}
+	overlay, err := CompileOverlay("tun0", 10, 5, []string{"192.168.1.2"}, nil)
+	if err != nil {
+		t.Fatalf("CompileOverlay: %v", err)
+	}
+
+	set := func(kind CookieKind, id string, flows []Flow) *FlowSet {
+		return &FlowSet{Cookie: FlowCookie(kind, id), Mask: CookieMaskResource, Flows: flows}
+	}
+	return map[string]*FlowSet{
+		"pipeline":       set(CookieKindPipeline, driftVPCID, BasePipelineFlows(5)),
+		"security group": set(CookieKindSecurityGroup, driftSGID, rule),
+		"routes":         set(CookieKindRoute, driftVPCID, routes),
+		"overlay":        set(CookieKindOverlay, driftVPCID, overlay),
+	}
+}
+
+func TestDriftIgnoresOVSNotation(t *testing.T) {
+	actual, err := ParseDumpFlows(driftDump)
+	if err != nil {
+		t.Fatalf("ParseDumpFlows: %v", err)
+	}
+
+	for name, set := range driftSets(t) {
+		missing, stale, err := set.Drift(actual)
+		if err != nil {
+			t.Fatalf("%s: Drift: %v", name, err)
+		}
+		for _, flow := range missing {
+			t.Errorf("%s: reported missing: table=%d priority=%d %s actions=%s", name, flow.Table, flow.Priority, flow.Match, flow.Actions)
+		}
+		for _, flow := range stale {
+			t.Errorf("%s: reported stale: table=%d priority=%d %s actions=%s", name, flow.Table, flow.Priority, flow.Match, flow.Actions)
+		}
+	}
+}
+
+func TestDriftFindsChangedFlows(t *testing.T) {
+	// The bridge lost the port range rule and still has an older one
+	dump := strings.Replace(driftDump, "tp_dst=0x400/0xfc00", "tp_dst=0x800/0xfc00", 1)
+	actual, err := ParseDumpFlows(dump)
+	if err != nil {
+		t.Fatalf("ParseDumpFlows: %v", err)
+	}
+
+	missing, stale, err := driftSets(t)["security group"].Drift(actual)
+	if err != nil {
+		t.Fatalf("Drift: %v", err)
+	}
+	if len(missing) != 1 || !strings.Contains(missing[0].Match, "tp_dst=0x0400/0xfc00") {
+		t.Errorf("missing = %+v, want the 1024-2047 rule", missing)
+	}
+	if len(stale) != 1 || !strings.Contains(stale[0].Match, "tp_dst=0x800/0xfc00") {
+		t.Errorf("stale = %+v, want the 2048-3071 rule", stale)
+	}
+}
+
+func TestCanonicalMatchTerm(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"ct_state=+trk+est", "ct_state=+est+trk"},
+		{"ct_state=-new+trk+est", "ct_state=-new+est+trk"},
+		{"tp_dst=0x0400/0xfc00", "tp_dst=0x400/0xfc00"},
+		{"tp_dst=22", "tp_dst=0x16"},
+		{"tun_id=10", "tun_id=0xa"},
+		{"nw_src=10.0.1.5/32", "nw_src=10.0.1.5"},
+		{"nw_src=10.0.1.5/16", "nw_src=10.0.0.0/16"},
+		{"ipv6_dst=fd12:3456:7800:0::/56", "ipv6_dst=fd12:3456:7800::/56"},
+		{"eth_dst=02:00:0A:00:01:05", "dl_dst=02:00:0a:00:01:05"},
+		{"icmpv6_type=134", "icmp_type=0x86"},
+		{`in_port="vm1"`, "in_port=vm1"},
+		{"ip", "ip"},
+	}
+	for _, tt := range tests {
+		if got := canonicalMatchTerm(tt.term); got != tt.want {
+			t.Errorf("canonicalMatchTerm(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueFlowsKeepsFirstOwner(t *testing.T) {
+	rule, err := CompileFirewallRule([]string{"10.0.1.5"}, FirewallRule{
+		Direction: "inbound",
+		Protocol:  "tcp",
+		FromPort:  22,
+		ToPort:    22,
+		Remotes:   []string{"10.0.0.0/16"},
+	})
+	if err != nil {
+		t.Fatalf("CompileFirewallRule: %v", err)
+	}
+	first := WithCookie(rule, FlowCookie(CookieKindSecurityGroup, driftSGID))
+	second := WithCookie(rule, FlowCookie(CookieKindSecurityGroup, driftVPCID))
+
+	unique := UniqueFlows(append(append([]Flow{}, first...), second...))
+	if len(unique) != len(rule) {
+		t.Fatalf("got %d flows, want %d", len(unique), len(rule))
+	}
+	for _, flow := range unique {
+		if flow.Cookie != first[0].Cookie {
+			t.Errorf("flow owned by %s, want %s", flow.Cookie, first[0].Cookie)
+		}
+	}
+}
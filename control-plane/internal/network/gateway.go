@@ -0,0 +1,97 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// TableInternetGateway translates traffic routed to an internet gateway and
+// sends it out of the uplink patch port
+const TableInternetGateway = 60
+
+// Flow priorities used by the internet gateway. Translated inbound traffic
+// is classified above ARP and IP so nothing from the uplink bypasses NAT.
+const (
+	PriorityClassifierGatewayNAT  = 310
+	PriorityClassifierGatewayDrop = 300
+	PriorityGatewayNAT            = 100
+	PriorityGatewayDefault        = 1
+)
+
+// NATMapping pairs an instance's private address with its public address
+type NATMapping struct {
+	PrivateIP string
+	PublicIP  string
+}
+
+// UplinkPortPrefix starts the name of every gateway patch port on the uplink
+// bridge
+const UplinkPortPrefix = "igx-"
+
+// InternetGatewayPorts returns the patch port pair connecting a VPC bridge to
+// the uplink bridge: the port on the VPC bridge and its peer on the uplink
+func InternetGatewayPorts(bridgeName string) (string, string) {
+	suffix := vpcBridgeSuffix(bridgeName)
+	return "igw-" + suffix, UplinkPortPrefix + suffix
+}
+
+// InternetGatewayRouteActions returns the actions of routes that target an
+// attached internet gateway
+func InternetGatewayRouteActions() string {
+	return fmt.Sprintf("goto_table:%d", TableInternetGateway)
+}
+
+// CompileInternetGateway builds the 1:1 NAT flows of an attached internet
+// gateway. Outbound traffic from an instance with a public address has its
+// source rewritten and leaves through patchPort; inbound traffic to a public
+// address is rewritten to the instance and enters the pipeline like any other
+// packet. Instances without a public address reach the internet only through
+// the NAT gateways in natGateways, and anything else arriving from the uplink
+// is dropped.
+func CompileInternetGateway(patchPort string, mappings []NATMapping, natGateways []SourceNAT) ([]Flow, error) {
+	flows := []Flow{
+		{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierGatewayDrop,
+			Match:    fmt.Sprintf("in_port=%s", patchPort),
+			Actions:  "drop",
+		},
+		{
+			Table:    TableInternetGateway,
+			Priority: PriorityGatewayDefault,
+			Actions:  "drop",
+		},
+	}
+
+	for _, mapping := range mappings {
+		if net.ParseIP(mapping.PublicIP).To4() == nil {
+			return nil, fmt.Errorf("invalid public IP address: %s", mapping.PublicIP)
+		}
+		mac, err := InstanceMAC(mapping.PrivateIP)
+		if err != nil {
+			return nil, err
+		}
+
+		flows = append(flows,
+			Flow{
+				Table:    TableClassifier,
+				Priority: PriorityClassifierGatewayNAT,
+				Match:    fmt.Sprintf("ip,in_port=%s,nw_dst=%s", patchPort, mapping.PublicIP),
+				Actions:  fmt.Sprintf("mod_nw_dst:%s,mod_dl_dst:%s,goto_table:%d", mapping.PrivateIP, mac, TableNetworkACLEgress),
+			},
+			Flow{
+				Table:    TableInternetGateway,
+				Priority: PriorityGatewayNAT,
+				Match:    fmt.Sprintf("ip,nw_src=%s", mapping.PrivateIP),
+				Actions:  fmt.Sprintf("mod_nw_src:%s,output:%s", mapping.PublicIP, patchPort),
+			},
+		)
+	}
+
+	natFlows, err := compileSourceNAT(patchPort, natGateways)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(flows, natFlows...), nil
+}
@@ -0,0 +1,168 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrNoFreeAddress is returned when every usable address of a subnet is taken
+var ErrNoFreeAddress = errors.New("no free address in subnet")
+
+// ErrAddressUnavailable is returned when a requested address is reserved,
+// outside the subnet or already allocated
+var ErrAddressUnavailable = errors.New("address is not available for allocation")
+
+// IPAllocator computes reserved and free addresses of an IPv4 subnet.
+// It holds no allocation state; callers pass in the addresses already in use.
+type IPAllocator struct {
+	network  *net.IPNet
+	first    uint32 // network address
+	last     uint32 // broadcast address
+	reserved map[uint32]string
+}
+
+// NewIPAllocator creates an allocator for the given IPv4 CIDR block
+func NewIPAllocator(cidr string) (*IPAllocator, error) {
+	ipNet, err := ParseIPv4CIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones < 2 {
+		return nil, fmt.Errorf("subnet %s is too small for allocation", cidr)
+	}
+
+	first := ipToUint32(ipNet.IP)
+	last := first | ^binary.BigEndian.Uint32(ipNet.Mask)
+
+	a := &IPAllocator{
+		network:  ipNet,
+		first:    first,
+		last:     last,
+		reserved: make(map[uint32]string),
+	}
+
+	// Network, gateway (same address OVSManager.CreateBridge assigns to the
+	// bridge), DNS resolver and broadcast are never handed out
+	a.reserved[first] = "network"
+	a.reserved[first+1] = "gateway"
+	a.reserved[first+2] = "dns"
+	a.reserved[last] = "broadcast"
+
+	return a, nil
+}
+
+// NewPublicIPAllocator creates an allocator for a public address pool. Only
+// the network, upstream gateway and broadcast addresses are reserved.
+func NewPublicIPAllocator(cidr string) (*IPAllocator, error) {
+	a, err := NewIPAllocator(cidr)
+	if err != nil {
+		return nil, err
+	}
+	delete(a.reserved, a.first+2)
+	return a, nil
+}
+
+// Network returns the canonical CIDR block of the subnet
+func (a *IPAllocator) Network() string {
+	return a.network.String()
+}
+
+// GatewayAddress returns the address of the subnet gateway
+func (a *IPAllocator) GatewayAddress() string {
+	return uint32ToIP(a.first + 1).String()
+}
+
+// DNSAddress returns the address of the subnet DNS resolver
+func (a *IPAllocator) DNSAddress() string {
+	return uint32ToIP(a.first + 2).String()
+}
+
+// BroadcastAddress returns the broadcast address of the subnet
+func (a *IPAllocator) BroadcastAddress() string {
+	return uint32ToIP(a.last).String()
+}
+
+// ReservedAddresses maps each reserved address to its purpose
+func (a *IPAllocator) ReservedAddresses() map[string]string {
+	reserved := make(map[string]string, len(a.reserved))
+	for ip, purpose := range a.reserved {
+		reserved[uint32ToIP(ip).String()] = purpose
+	}
+	return reserved
+}
+
+// TotalAddresses returns the number of addresses in the subnet
+func (a *IPAllocator) TotalAddresses() int {
+	return int(a.last-a.first) + 1
+}
+
+// Capacity returns the number of addresses that can be allocated
+func (a *IPAllocator) Capacity() int {
+	return a.TotalAddresses() - len(a.reserved)
+}
+
+// Contains reports whether ip lies inside the subnet
+func (a *IPAllocator) Contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() != nil && a.network.Contains(parsed)
+}
+
+// IsReserved reports whether ip is one of the reserved subnet addresses
+func (a *IPAllocator) IsReserved(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.To4() == nil {
+		return false
+	}
+	_, reserved := a.reserved[ipToUint32(parsed)]
+	return reserved
+}
+
+// Validate checks that ip can be allocated given the addresses already in use
+func (a *IPAllocator) Validate(ip string, used []string) error {
+	if !a.Contains(ip) || a.IsReserved(ip) {
+		return ErrAddressUnavailable
+	}
+	canonical := net.ParseIP(ip).To4().String()
+	for _, u := range used {
+		if u == canonical {
+			return ErrAddressUnavailable
+		}
+	}
+	return nil
+}
+
+// NextFree returns the lowest usable address not present in used
+func (a *IPAllocator) NextFree(used []string) (string, error) {
+	taken := make(map[uint32]struct{}, len(used))
+	for _, u := range used {
+		if parsed := net.ParseIP(u); parsed != nil && parsed.To4() != nil {
+			taken[ipToUint32(parsed)] = struct{}{}
+		}
+	}
+
+	for ip := a.first; ip <= a.last && ip >= a.first; ip++ {
+		if _, reserved := a.reserved[ip]; reserved {
+			continue
+		}
+		if _, inUse := taken[ip]; inUse {
+			continue
+		}
+		return uint32ToIP(ip).String(), nil
+	}
+
+	return "", ErrNoFreeAddress
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uint32ToIP(n uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
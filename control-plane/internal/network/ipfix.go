@@ -0,0 +1,343 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// IPFIXExport is a collector set of a bridge: the samples of flows whose
+// sample actions name the set are aggregated in the bridge's flow cache and
+// exported over IPFIX to the target
+type IPFIXExport struct {
+	CollectorSetID int    `json:"collector_set_id"`
+	Target         string `json:"target"` // ip:port of the UDP collector
+	// CacheActiveTimeout is how often, in seconds, a flow that is still
+	// going is exported
+	CacheActiveTimeout int `json:"cache_active_timeout"`
+	CacheMaxFlows      int `json:"cache_max_flows"`
+}
+
+// IPFIXRecord is a flow record decoded from an IPFIX message. Fields the
+// exporter's template lacks are left zero.
+type IPFIXRecord struct {
+	ObservationDomain uint32
+	ObservationPoint  uint32
+	Protocol          uint8
+	SrcIP             string
+	DstIP             string
+	SrcPort           uint16
+	DstPort           uint16
+	Packets           uint64
+	Bytes             uint64
+	Start             time.Time
+	End               time.Time
+}
+
+// Information elements of the IANA registry the decoder reads
+const (
+	ipfixOctetDeltaCount            = 1
+	ipfixPacketDeltaCount           = 2
+	ipfixProtocolIdentifier         = 4
+	ipfixSourceTransportPort        = 7
+	ipfixSourceIPv4Address          = 8
+	ipfixDestinationTransportPort   = 11
+	ipfixDestinationIPv4Address     = 12
+	ipfixObservationPointID         = 138
+	ipfixFlowStartSeconds           = 150
+	ipfixFlowEndSeconds             = 151
+	ipfixFlowStartMilliseconds      = 152
+	ipfixFlowEndMilliseconds        = 153
+	ipfixFlowStartDeltaMicroseconds = 158
+	ipfixFlowEndDeltaMicroseconds   = 159
+)
+
+const (
+	ipfixVersion             = 10
+	ipfixMessageHeaderLength = 16
+	ipfixSetHeaderLength     = 4
+	ipfixTemplateSetID       = 2
+	ipfixOptionsTemplateSet  = 3
+	ipfixMinDataSetID        = 256
+	ipfixVariableLength      = 0xffff
+)
+
+// ipfixField is a field specifier of a template
+type ipfixField struct {
+	id         uint16
+	enterprise uint32
+	length     uint16
+}
+
+type ipfixTemplateKey struct {
+	exporter   string
+	domain     uint32
+	templateID uint16
+}
+
+// IPFIXDecoder decodes IPFIX messages (RFC 7011). Templates are remembered
+// per exporter and observation domain, as data sets refer to templates sent
+// in earlier messages.
+type IPFIXDecoder struct {
+	mu        sync.Mutex
+	templates map[ipfixTemplateKey][]ipfixField
+}
+
+// NewIPFIXDecoder creates a decoder that knows no templates yet
+func NewIPFIXDecoder() *IPFIXDecoder {
+	return &IPFIXDecoder{templates: make(map[ipfixTemplateKey][]ipfixField)}
+}
+
+// Decode returns the flow records of a message from an exporter. Data sets
+// whose template has not been seen yet are skipped; exporters resend their
+// templates periodically.
+func (d *IPFIXDecoder) Decode(exporter string, msg []byte) ([]IPFIXRecord, error) {
+	if len(msg) < ipfixMessageHeaderLength {
+		return nil, fmt.Errorf("IPFIX message too short: %d bytes", len(msg))
+	}
+	if version := binary.BigEndian.Uint16(msg[0:2]); version != ipfixVersion {
+		return nil, fmt.Errorf("unsupported IPFIX version: %d", version)
+	}
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	if length < ipfixMessageHeaderLength || length > len(msg) {
+		return nil, fmt.Errorf("invalid IPFIX message length: %d", length)
+	}
+	exportTime := time.Unix(int64(binary.BigEndian.Uint32(msg[4:8])), 0)
+	domain := binary.BigEndian.Uint32(msg[12:16])
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var records []IPFIXRecord
+	for offset := ipfixMessageHeaderLength; offset < length; {
+		if length-offset < ipfixSetHeaderLength {
+			return nil, fmt.Errorf("truncated IPFIX set header")
+		}
+		setID := binary.BigEndian.Uint16(msg[offset : offset+2])
+		setLength := int(binary.BigEndian.Uint16(msg[offset+2 : offset+4]))
+		if setLength < ipfixSetHeaderLength || offset+setLength > length {
+			return nil, fmt.Errorf("invalid IPFIX set length: %d", setLength)
+		}
+		body := msg[offset+ipfixSetHeaderLength : offset+setLength]
+		offset += setLength
+
+		switch {
+		case setID == ipfixTemplateSetID || setID == ipfixOptionsTemplateSet:
+			if err := d.readTemplates(exporter, domain, body, setID == ipfixOptionsTemplateSet); err != nil {
+				return nil, err
+			}
+		case setID >= ipfixMinDataSetID:
+			fields, ok := d.templates[ipfixTemplateKey{exporter, domain, setID}]
+			if !ok {
+				continue
+			}
+			decoded, err := decodeIPFIXDataSet(body, fields, domain, exportTime)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, decoded...)
+		}
+	}
+
+	return records, nil
+}
+
+// readTemplates stores the templates of a template set. Options templates
+// describe records about the exporter rather than flows, so they are read
+// past and their data sets skipped. A template without fields withdraws the
+// template.
+func (d *IPFIXDecoder) readTemplates(exporter string, domain uint32, body []byte, options bool) error {
+	headerLength := 4
+	if options {
+		headerLength = 6
+	}
+
+	for len(body) >= headerLength {
+		templateID := binary.BigEndian.Uint16(body[0:2])
+		count := int(binary.BigEndian.Uint16(body[2:4]))
+		body = body[headerLength:]
+		key := ipfixTemplateKey{exporter, domain, templateID}
+		if count == 0 {
+			delete(d.templates, key)
+			continue
+		}
+
+		fields := make([]ipfixField, 0, count)
+		for i := 0; i < count; i++ {
+			if len(body) < 4 {
+				return fmt.Errorf("truncated IPFIX template %d", templateID)
+			}
+			field := ipfixField{
+				id:     binary.BigEndian.Uint16(body[0:2]),
+				length: binary.BigEndian.Uint16(body[2:4]),
+			}
+			body = body[4:]
+			if field.id&0x8000 != 0 {
+				if len(body) < 4 {
+					return fmt.Errorf("truncated IPFIX template %d", templateID)
+				}
+				field.id &^= 0x8000
+				field.enterprise = binary.BigEndian.Uint32(body[0:4])
+				body = body[4:]
+			}
+			fields = append(fields, field)
+		}
+		if !options {
+			d.templates[key] = fields
+		}
+	}
+
+	return nil
+}
+
+// decodeIPFIXDataSet decodes the records of a data set. Whatever is left
+// that is too short for a record is padding.
+func decodeIPFIXDataSet(body []byte, fields []ipfixField, domain uint32, exportTime time.Time) ([]IPFIXRecord, error) {
+	minLength := 0
+	for _, field := range fields {
+		if field.length == ipfixVariableLength {
+			minLength++
+		} else {
+			minLength += int(field.length)
+		}
+	}
+	if minLength == 0 {
+		return nil, nil
+	}
+
+	var records []IPFIXRecord
+	for len(body) >= minLength {
+		record := IPFIXRecord{ObservationDomain: domain}
+		for _, field := range fields {
+			length := int(field.length)
+			if field.length == ipfixVariableLength {
+				if len(body) < 1 {
+					return nil, fmt.Errorf("truncated IPFIX record")
+				}
+				length, body = int(body[0]), body[1:]
+				if length == 255 {
+					if len(body) < 2 {
+						return nil, fmt.Errorf("truncated IPFIX record")
+					}
+					length, body = int(binary.BigEndian.Uint16(body[0:2])), body[2:]
+				}
+			}
+			if len(body) < length {
+				return nil, fmt.Errorf("truncated IPFIX record")
+			}
+			value := body[:length]
+			body = body[length:]
+
+			if field.enterprise == 0 {
+				record.set(field.id, value, exportTime)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// set stores the value of an information element the record carries
+func (r *IPFIXRecord) set(id uint16, value []byte, exportTime time.Time) {
+	switch id {
+	case ipfixSourceIPv4Address:
+		if len(value) == net.IPv4len {
+			r.SrcIP = net.IP(value).String()
+		}
+		return
+	case ipfixDestinationIPv4Address:
+		if len(value) == net.IPv4len {
+			r.DstIP = net.IP(value).String()
+		}
+		return
+	}
+
+	// Unsigned values may be sent in fewer bytes than their type has
+	if len(value) > 8 {
+		return
+	}
+	var n uint64
+	for _, b := range value {
+		n = n<<8 | uint64(b)
+	}
+
+	switch id {
+	case ipfixOctetDeltaCount:
+		r.Bytes = n
+	case ipfixPacketDeltaCount:
+		r.Packets = n
+	case ipfixProtocolIdentifier:
+		r.Protocol = uint8(n)
+	case ipfixSourceTransportPort:
+		r.SrcPort = uint16(n)
+	case ipfixDestinationTransportPort:
+		r.DstPort = uint16(n)
+	case ipfixObservationPointID:
+		r.ObservationPoint = uint32(n)
+	case ipfixFlowStartSeconds:
+		r.Start = time.Unix(int64(n), 0)
+	case ipfixFlowEndSeconds:
+		r.End = time.Unix(int64(n), 0)
+	case ipfixFlowStartMilliseconds:
+		r.Start = time.UnixMilli(int64(n))
+	case ipfixFlowEndMilliseconds:
+		r.End = time.UnixMilli(int64(n))
+	case ipfixFlowStartDeltaMicroseconds:
+		r.Start = exportTime.Add(-time.Duration(n) * time.Microsecond)
+	case ipfixFlowEndDeltaMicroseconds:
+		r.End = exportTime.Add(-time.Duration(n) * time.Microsecond)
+	}
+}
+
+// IPFIXCollector receives IPFIX messages over UDP and decodes them
+type IPFIXCollector struct {
+	conn    *net.UDPConn
+	decoder *IPFIXDecoder
+}
+
+// ListenIPFIX opens a collector on a UDP address such as 127.0.0.1:4739
+func ListenIPFIX(address string) (*IPFIXCollector, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IPFIX collector address %s: %w", address, err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for IPFIX on %s: %w", address, err)
+	}
+
+	return &IPFIXCollector{conn: conn, decoder: NewIPFIXDecoder()}, nil
+}
+
+// Serve hands the records of every message received to handle, or the
+// error decoding it, until the collector is closed
+func (c *IPFIXCollector) Serve(handle func(records []IPFIXRecord, err error)) error {
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := c.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to receive IPFIX message: %w", err)
+		}
+
+		records, err := c.decoder.Decode(from.String(), buf[:n])
+		if err != nil {
+			handle(nil, fmt.Errorf("IPFIX message from %s: %w", from, err))
+			continue
+		}
+		if len(records) > 0 {
+			handle(records, nil)
+		}
+	}
+}
+
+// Close stops the collector
+func (c *IPFIXCollector) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,148 @@
+package network
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IPv6 on VPC bridges. A VPC may hold an IPv6 /56 beside its IPv4 range,
+// and each of its subnets a /64 of it. Instances configure the EUI-64
+// address of their port's MAC in their subnet's /64 themselves, with SLAAC
+// from the router advertisements of the DHCP and DNS responder. Prefixes
+// are advertised off-link, so instances send all their IPv6 traffic, even
+// traffic for their own subnet, to the responder's MAC as their router and
+// never look each other up with neighbor discovery. The routing table then
+// derives the destination MAC from the interface identifier of the
+// destination address, the way the ARP responder derives it from an IPv4
+// address.
+//
+// Security groups and routes know instances by their EUI-64 addresses, so
+// instances must not use privacy or stable-privacy addresses.
+
+// Prefix lengths of the IPv6 CIDR blocks of VPCs and subnets
+const (
+	VPCIPv6PrefixLength    = 56
+	SubnetIPv6PrefixLength = 64
+)
+
+// UniqueLocalIPv6Range is the RFC 4193 range generated VPC blocks are
+// taken from
+const UniqueLocalIPv6Range = "fd00::/8"
+
+// ICMPv6 types of neighbor discovery
+const (
+	ICMPv6RouterSolicitation    = 133
+	ICMPv6RouterAdvertisement   = 134
+	ICMPv6NeighborSolicitation  = 135
+	ICMPv6NeighborAdvertisement = 136
+)
+
+// ipv6RouteActions set the destination MAC of IPv6 traffic to the one
+// InstanceMAC gives the instance whose EUI-64 address it is sent to: 02:00,
+// the third byte of the interface identifier and its last three bytes
+const ipv6RouteActions = "load:0x0200->NXM_OF_ETH_DST[32..47]," +
+	"move:NXM_NX_IPV6_DST[40..47]->NXM_OF_ETH_DST[24..31]," +
+	"move:NXM_NX_IPV6_DST[0..23]->NXM_OF_ETH_DST[0..23]"
+
+// ipFamily holds the match keywords and field names of an address family
+type ipFamily struct {
+	ip   string // keyword matching every packet of the family
+	tcp  string
+	udp  string
+	icmp string // keyword matching the family's ICMP
+	// icmpProtocol is the protocol rules name the family's ICMP by
+	icmpProtocol string
+	icmpType     string
+	icmpCode     string
+	src          string
+	dst          string
+}
+
+var (
+	ipv4Family = ipFamily{
+		ip: "ip", tcp: "tcp", udp: "udp", icmp: "icmp",
+		icmpProtocol: "icmp", icmpType: "icmp_type", icmpCode: "icmp_code",
+		src: "nw_src", dst: "nw_dst",
+	}
+	ipv6Family = ipFamily{
+		ip: "ipv6", tcp: "tcp6", udp: "udp6", icmp: "icmp6",
+		icmpProtocol: "icmpv6", icmpType: "icmpv6_type", icmpCode: "icmpv6_code",
+		src: "ipv6_src", dst: "ipv6_dst",
+	}
+)
+
+// familyOf returns the family of an address or CIDR block
+func familyOf(address string) (ipFamily, error) {
+	host, _, _ := strings.Cut(address, "/")
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return ipFamily{}, fmt.Errorf("invalid address: %s", address)
+	case ip.To4() != nil:
+		return ipv4Family, nil
+	default:
+		return ipv6Family, nil
+	}
+}
+
+// carries reports whether rules of a protocol apply to traffic of the
+// family: icmp is IPv4 only and icmpv6 IPv6 only
+func (f ipFamily) carries(protocol string) bool {
+	switch protocol {
+	case ipv4Family.icmpProtocol, ipv6Family.icmpProtocol:
+		return protocol == f.icmpProtocol
+	default:
+		return true
+	}
+}
+
+// NewUniqueLocalIPv6CIDR returns the first /56 of a random RFC 4193 /48:
+// fd followed by a 40 bit random global ID
+func NewUniqueLocalIPv6CIDR() (string, error) {
+	ip := make(net.IP, net.IPv6len)
+	ip[0] = 0xfd
+	if _, err := rand.Read(ip[1:6]); err != nil {
+		return "", fmt.Errorf("failed to generate global ID: %w", err)
+	}
+	ipNet := net.IPNet{IP: ip, Mask: net.CIDRMask(VPCIPv6PrefixLength, 8*net.IPv6len)}
+	return ipNet.String(), nil
+}
+
+// InstanceIPv6 returns the EUI-64 address in a subnet's IPv6 block of the
+// instance whose port InstanceMAC derives from an IPv4 address
+func InstanceIPv6(subnetCIDR, ipv4 string) (string, error) {
+	ipNet, err := ParseIPv6CIDR(subnetCIDR)
+	if err != nil {
+		return "", err
+	}
+	if ones, _ := ipNet.Mask.Size(); ones != SubnetIPv6PrefixLength {
+		return "", fmt.Errorf("subnet %s is not a /%d", subnetCIDR, SubnetIPv6PrefixLength)
+	}
+	v4 := net.ParseIP(ipv4).To4()
+	if v4 == nil {
+		return "", fmt.Errorf("invalid IPv4 address: %s", ipv4)
+	}
+
+	// The MAC is 02:00 and the IPv4 octets; EUI-64 flips the universal/local
+	// bit of 02 and puts ff:fe in the middle
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, ipNet.IP.To16()[:8])
+	copy(ip[8:], []byte{0x00, 0x00, v4[0], 0xff, 0xfe, v4[1], v4[2], v4[3]})
+	return ip.String(), nil
+}
+
+// IPv6DNSAddress returns the address the responder holds in a subnet's IPv6
+// block, which instances resolve names with: ::2, at the offset of the IPv4
+// resolver
+func IPv6DNSAddress(subnetCIDR string) (string, error) {
+	ipNet, err := ParseIPv6CIDR(subnetCIDR)
+	if err != nil {
+		return "", err
+	}
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, ipNet.IP.To16())
+	ip[net.IPv6len-1] |= 2
+	return ip.String(), nil
+}
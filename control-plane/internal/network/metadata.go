@@ -0,0 +1,59 @@
+package network
+
+import (
+	"fmt"
+)
+
+// Instances reach the metadata service at a link-local address, which the
+// port of the DHCP and DNS responder holds as well. The classifier steers a
+// request to that port only when it comes in on the instance's own port with
+// the instance's MAC and address, and the port security flows drop whatever
+// an instance sends from another address, so the address a request comes
+// from identifies the instance asking.
+const (
+	MetadataAddress = "169.254.169.254"
+	MetadataPort    = 80
+)
+
+// Flow priorities of the metadata flows. Metadata requests no flow admits
+// are dropped rather than routed.
+const (
+	PriorityClassifierMetadata     = 300
+	PriorityClassifierMetadataDrop = 290
+)
+
+// MetadataMAC returns the MAC of the responder's port, the one instances
+// address metadata requests to once the classifier rewrote them
+func MetadataMAC() string {
+	mac, _ := InstanceMAC(MetadataAddress)
+	return mac
+}
+
+// CompileMetadata builds the classifier flows that hand the metadata requests
+// instances send from their ports to the responder's port. Replies leave
+// the port through the DHCP flows.
+func CompileMetadata(port string, instances []InstancePort) ([]Flow, error) {
+	flows := []Flow{
+		{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierMetadataDrop,
+			Match:    fmt.Sprintf("ip,nw_dst=%s", MetadataAddress),
+			Actions:  "drop",
+		},
+	}
+
+	for _, instance := range instances {
+		mac, err := InstanceMAC(instance.IPv4)
+		if err != nil {
+			return nil, err
+		}
+		flows = append(flows, Flow{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierMetadata,
+			Match:    fmt.Sprintf("tcp,in_port=%s,dl_src=%s,nw_src=%s,nw_dst=%s,tp_dst=%d", instance.Name, mac, instance.IPv4, MetadataAddress, MetadataPort),
+			Actions:  fmt.Sprintf("mod_dl_dst:%s,output:%s", MetadataMAC(), port),
+		})
+	}
+
+	return flows, nil
+}
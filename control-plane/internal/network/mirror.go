@@ -0,0 +1,235 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Traffic mirroring copies the packets of an instance port to a target with
+// OVS mirrors. A mirror selects the packets the bridge receives from its
+// source ports and the packets it sends to its destination ports, narrowed
+// by an OpenFlow match filter, and sends a copy of each out of its output
+// port. Copies are taken at the port, so they are the packets the instance
+// actually sent or received, before or after the pipeline decided about
+// them.
+//
+// The output port of a mirror carries mirrored traffic only; OVS neither
+// forwards to it nor learns from it. A target instance must therefore run
+// on the same node as the source, and cannot be used for anything else
+// while it is a target. GRE and ERSPAN endpoints are reached through a
+// mirror tunnel port per session.
+//
+// Mirror filters need OVS 3.4 or later. Each filter rule becomes a mirror of
+// its own, so a packet matching several rules is copied once per rule.
+
+// Mirror name and port prefixes. The tunnel port of a session fits the 15
+// characters of a Linux interface name.
+const (
+	MirrorNamePrefix       = "mir-"
+	MirrorTunnelPortPrefix = "mt-"
+)
+
+// Mirror target types
+const (
+	MirrorTargetInstance = "instance"
+	MirrorTargetGRE      = "gre"
+	MirrorTargetERSPAN   = "erspan"
+)
+
+// Mirror filter rule directions, as seen from the source instance
+const (
+	MirrorDirectionInbound  = "inbound"
+	MirrorDirectionOutbound = "outbound"
+)
+
+// Mirror is an OVS mirror of a bridge
+type Mirror struct {
+	Name string `json:"name"`
+	// SrcPorts are the ports whose received packets are mirrored
+	SrcPorts []string `json:"src_ports"`
+	// DstPorts are the ports whose sent packets are mirrored
+	DstPorts []string `json:"dst_ports"`
+	// Filter is the OpenFlow match mirrored packets must satisfy; empty
+	// mirrors every packet
+	Filter     string `json:"filter,omitempty"`
+	OutputPort string `json:"output_port"`
+}
+
+// Equal reports whether two mirrors select the same packets and send them
+// to the same port
+func (m Mirror) Equal(other Mirror) bool {
+	return m.Name == other.Name &&
+		m.Filter == other.Filter &&
+		m.OutputPort == other.OutputPort &&
+		samePorts(m.SrcPorts, other.SrcPorts) &&
+		samePorts(m.DstPorts, other.DstPorts)
+}
+
+// MirrorEndpoint is the remote end of a mirror tunnel port
+type MirrorEndpoint struct {
+	Type     string `json:"type"` // gre or erspan
+	RemoteIP string `json:"remote_ip"`
+	// Key is the GRE key, or the ERSPAN session ID
+	Key int `json:"key"`
+}
+
+// Validate checks the type, address and key of an endpoint
+func (e MirrorEndpoint) Validate() error {
+	switch e.Type {
+	case MirrorTargetGRE:
+		if e.Key < 0 || int64(e.Key) > 0xffffffff {
+			return fmt.Errorf("invalid GRE key: %d", e.Key)
+		}
+	case MirrorTargetERSPAN:
+		if e.Key < 0 || e.Key > 1023 {
+			return fmt.Errorf("invalid ERSPAN session ID: %d", e.Key)
+		}
+	default:
+		return fmt.Errorf("unsupported mirror endpoint type: %s", e.Type)
+	}
+	if ip := net.ParseIP(e.RemoteIP); ip == nil || ip.To4() == nil {
+		return fmt.Errorf("invalid remote IP: %s", e.RemoteIP)
+	}
+	return nil
+}
+
+// Options returns the interface options of a tunnel port to the endpoint
+func (e MirrorEndpoint) Options() map[string]string {
+	options := map[string]string{
+		"remote_ip": e.RemoteIP,
+		"key":       fmt.Sprintf("%d", e.Key),
+	}
+	if e.Type == MirrorTargetERSPAN {
+		options["erspan_ver"] = "1"
+	}
+	return options
+}
+
+// MirrorFilterRule narrows the traffic a session mirrors. For icmp rules
+// FromPort and ToPort carry the ICMP type and code (-1 for any).
+type MirrorFilterRule struct {
+	Direction string // inbound, outbound
+	Protocol  string // tcp, udp, icmp, all
+	FromPort  int
+	ToPort    int
+	// CIDRBlock is the remote side: the source of inbound traffic or the
+	// destination of outbound traffic
+	CIDRBlock string
+}
+
+// MirrorSession is a traffic mirror session as it is programmed on a VPC
+// bridge
+type MirrorSession struct {
+	ID string
+	// SourcePort is the port of the mirrored instance
+	SourcePort string
+	// TargetPort is the port of the target instance, or the session's
+	// mirror tunnel port
+	TargetPort string
+	// Endpoint is the remote end of the tunnel port, nil for instance targets
+	Endpoint *MirrorEndpoint
+	Rules    []MirrorFilterRule
+}
+
+// MirrorTunnelPortName returns the mirror tunnel port of a session
+func MirrorTunnelPortName(sessionID string) string {
+	return MirrorTunnelPortPrefix + sessionSuffix(sessionID)
+}
+
+// MirrorSessionPrefix starts the name of every mirror of a session
+func MirrorSessionPrefix(sessionID string) string {
+	return MirrorNamePrefix + sessionSuffix(sessionID) + "-"
+}
+
+// CompileMirrorSession builds the mirrors of a session. A session without
+// rules mirrors all traffic of its source port. Outbound rules select what
+// the bridge receives from the source port, inbound rules what it sends to
+// it.
+func CompileMirrorSession(session MirrorSession) ([]Mirror, error) {
+	if session.SourcePort == "" || session.TargetPort == "" {
+		return nil, fmt.Errorf("mirror session %s needs a source and a target port", session.ID)
+	}
+	if session.SourcePort == session.TargetPort {
+		return nil, fmt.Errorf("mirror session %s mirrors a port to itself", session.ID)
+	}
+	prefix := MirrorSessionPrefix(session.ID)
+
+	if len(session.Rules) == 0 {
+		return []Mirror{{
+			Name:       prefix + "0",
+			SrcPorts:   []string{session.SourcePort},
+			DstPorts:   []string{session.SourcePort},
+			OutputPort: session.TargetPort,
+		}}, nil
+	}
+
+	mirrors := make([]Mirror, 0, len(session.Rules))
+	for _, rule := range session.Rules {
+		matches, err := protocolMatches(FirewallRule{
+			Direction: rule.Direction,
+			Protocol:  rule.Protocol,
+			FromPort:  rule.FromPort,
+			ToPort:    rule.ToPort,
+		}, ipv4Family)
+		if err != nil {
+			return nil, err
+		}
+		remote, err := remoteCIDRMatch(rule.CIDRBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		mirror := Mirror{OutputPort: session.TargetPort}
+		remoteField := ""
+		switch rule.Direction {
+		case MirrorDirectionOutbound:
+			mirror.SrcPorts, mirror.DstPorts = []string{session.SourcePort}, []string{}
+			remoteField = "nw_dst"
+		case MirrorDirectionInbound:
+			mirror.SrcPorts, mirror.DstPorts = []string{}, []string{session.SourcePort}
+			remoteField = "nw_src"
+		default:
+			return nil, fmt.Errorf("invalid mirror filter direction: %s", rule.Direction)
+		}
+
+		for _, match := range matches {
+			if remote != "" {
+				match += fmt.Sprintf(",%s=%s", remoteField, remote)
+			}
+			mirror.Name = fmt.Sprintf("%s%d", prefix, len(mirrors))
+			mirror.Filter = match
+			mirrors = append(mirrors, mirror)
+		}
+	}
+
+	return mirrors, nil
+}
+
+// sessionSuffix shortens a session ID to what fits the names of its mirrors
+// and port
+func sessionSuffix(sessionID string) string {
+	id := strings.ReplaceAll(sessionID, "-", "")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return id
+}
+
+// samePorts reports whether two port lists hold the same ports in any order
+func samePorts(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// netnsDir is where ip netns keeps the namespaces it names
+const netnsDir = "/var/run/netns"
+
+// ListenInNamespace opens a TCP listener in a namespace created with ip
+// netns. A socket stays in the namespace it was created in, so the listener
+// can be served from any goroutine afterwards.
+func ListenInNamespace(namespace, address string) (net.Listener, error) {
+	type result struct {
+		listener net.Listener
+		err      error
+	}
+	done := make(chan result, 1)
+
+	// Switch namespaces on a thread of its own. Should switching back fail,
+	// the goroutine exits still locked to the thread, which makes the
+	// runtime throw the thread away instead of reusing it.
+	go func() {
+		runtime.LockOSThread()
+
+		origin, err := os.Open("/proc/thread-self/ns/net")
+		if err != nil {
+			runtime.UnlockOSThread()
+			done <- result{err: fmt.Errorf("failed to open current namespace: %w", err)}
+			return
+		}
+		defer origin.Close()
+
+		target, err := os.Open(filepath.Join(netnsDir, namespace))
+		if err != nil {
+			runtime.UnlockOSThread()
+			done <- result{err: fmt.Errorf("failed to open namespace %s: %w", namespace, err)}
+			return
+		}
+		defer target.Close()
+
+		if err := setns(target); err != nil {
+			runtime.UnlockOSThread()
+			done <- result{err: fmt.Errorf("failed to enter namespace %s: %w", namespace, err)}
+			return
+		}
+
+		listener, err := net.Listen("tcp", address)
+		if restoreErr := setns(origin); restoreErr != nil {
+			if listener != nil {
+				listener.Close()
+			}
+			done <- result{err: fmt.Errorf("failed to leave namespace %s: %w", namespace, restoreErr)}
+			return
+		}
+		runtime.UnlockOSThread()
+
+		if err != nil {
+			err = fmt.Errorf("failed to listen on %s in namespace %s: %w", address, namespace, err)
+		}
+		done <- result{listener: listener, err: err}
+	}()
+
+	r := <-done
+	return r.listener, r.err
+}
+
+// setns moves the calling thread into the network namespace of ns
+func setns(ns *os.File) error {
+	return unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET)
+}
@@ -0,0 +1,98 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// NAT gateway stages of the VPC bridge pipeline. Routed traffic is source
+// NATed in the gateway's conntrack zone and continues in TableNATGateway;
+// replies arriving from the uplink are translated back in the same zone and
+// continue in TableNATGatewayReturn.
+const (
+	TableNATGateway       = 70
+	TableNATGatewayReturn = 71
+)
+
+// Flow priorities used by the NAT gateway tables
+const (
+	PriorityNATGatewayEgress      = 100
+	PriorityNATGatewayEstablished = 100
+	PriorityNATGatewayDefault     = 1
+)
+
+// SourceNAT is a NAT gateway as seen by the dataplane: the public address
+// private traffic is translated to and the conntrack zone holding the
+// translations
+type SourceNAT struct {
+	PublicIP string
+	Zone     int
+}
+
+// NATGatewayRouteActions returns the actions of routes that target a NAT
+// gateway. The connection is committed with a source translation to the
+// gateway's public address; conntrack picks a free source port when several
+// instances share it.
+func NATGatewayRouteActions(gateway SourceNAT) string {
+	return fmt.Sprintf("ct(commit,zone=%d,nat(src=%s),table=%d)", gateway.Zone, gateway.PublicIP, TableNATGateway)
+}
+
+// compileSourceNAT builds the NAT gateway flows of a VPC bridge connected to
+// the uplink through patchPort. Only replies to connections opened from the
+// inside are let back in; their destination MAC is derived from the restored
+// private address the same way InstanceMAC does. Replies re-enter the
+// pipeline with resubmit, as goto_table can only move to a later table.
+func compileSourceNAT(patchPort string, gateways []SourceNAT) ([]Flow, error) {
+	restoreMAC := "move:NXM_OF_IP_DST[]->NXM_OF_ETH_DST[0..31],load:0x0200->NXM_OF_ETH_DST[32..47]"
+
+	flows := []Flow{
+		{
+			Table:    TableNATGateway,
+			Priority: PriorityNATGatewayDefault,
+			Actions:  "drop",
+		},
+		{
+			Table:    TableNATGatewayReturn,
+			Priority: PriorityNATGatewayEstablished,
+			Match:    "ip,ct_state=+trk+est",
+			Actions:  fmt.Sprintf("%s,resubmit(,%d)", restoreMAC, TableNetworkACLEgress),
+		},
+		{
+			Table:    TableNATGatewayReturn,
+			Priority: PriorityNATGatewayEstablished,
+			Match:    "ip,ct_state=+trk+rel",
+			Actions:  fmt.Sprintf("%s,resubmit(,%d)", restoreMAC, TableNetworkACLEgress),
+		},
+		{
+			Table:    TableNATGatewayReturn,
+			Priority: PriorityNATGatewayDefault,
+			Actions:  "drop",
+		},
+	}
+
+	for _, gateway := range gateways {
+		if net.ParseIP(gateway.PublicIP).To4() == nil {
+			return nil, fmt.Errorf("invalid public IP address: %s", gateway.PublicIP)
+		}
+		if gateway.Zone < 1 || gateway.Zone > 65535 {
+			return nil, fmt.Errorf("invalid conntrack zone: %d", gateway.Zone)
+		}
+
+		flows = append(flows,
+			Flow{
+				Table:    TableClassifier,
+				Priority: PriorityClassifierGatewayNAT,
+				Match:    fmt.Sprintf("ip,in_port=%s,nw_dst=%s", patchPort, gateway.PublicIP),
+				Actions:  fmt.Sprintf("ct(zone=%d,nat,table=%d)", gateway.Zone, TableNATGatewayReturn),
+			},
+			Flow{
+				Table:    TableNATGateway,
+				Priority: PriorityNATGatewayEgress,
+				Match:    fmt.Sprintf("ip,nw_src=%s", gateway.PublicIP),
+				Actions:  fmt.Sprintf("output:%s", patchPort),
+			},
+		)
+	}
+
+	return flows, nil
+}
@@ -0,0 +1,161 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// Overlay stages of the VPC bridge pipeline. Instances of a VPC on different
+// worker nodes are joined by a tunnel port on each node's VPC bridge, keyed
+// with the VPC's VNI. Traffic is fully filtered by the pipeline of the node
+// it starts on, so traffic arriving from a tunnel is only tracked, so that
+// replies are recognised as established, and delivered.
+const (
+	// TableOverlay sends routed traffic for instances on other nodes into
+	// the tunnel and switches everything else locally
+	TableOverlay = 55
+	// TableTunnelIngress tracks and delivers traffic arriving from a tunnel
+	TableTunnelIngress = 80
+)
+
+// Flow priorities used by the overlay. Tunnel traffic is classified above
+// the internet gateway so nothing from a tunnel enters the local pipeline.
+const (
+	PriorityClassifierTunnel      = 400
+	PriorityClassifierTunnelDrop  = 390
+	PriorityClassifierARPResponse = 250
+	PriorityOverlayRemote         = 100
+	PriorityOverlayDefault        = 1
+	PriorityTunnelInvalid         = 300
+	PriorityTunnelDeliver         = 100
+)
+
+// Tunnel encapsulations supported between worker nodes
+const (
+	TunnelTypeVXLAN  = "vxlan"
+	TunnelTypeGeneve = "geneve"
+)
+
+// MaxVNI is the largest virtual network identifier VXLAN and Geneve carry
+const MaxVNI = 1<<24 - 1
+
+// RemoteEndpoint is an instance of the VPC that runs on another worker node,
+// reached through the tunnel endpoint of that node
+type RemoteEndpoint struct {
+	PrivateIP string
+	TunnelIP  string
+}
+
+// ValidTunnelType reports whether tunnelType is a supported encapsulation
+func ValidTunnelType(tunnelType string) bool {
+	return tunnelType == TunnelTypeVXLAN || tunnelType == TunnelTypeGeneve
+}
+
+// TunnelPortName returns the tunnel port of a VPC bridge
+func TunnelPortName(bridgeName string) string {
+	return "tun-" + vpcBridgeSuffix(bridgeName)
+}
+
+// OverlayActions returns the actions that hand routed traffic to the overlay
+// stage, which delivers it locally or tunnels it to the instance's node
+func OverlayActions() string {
+	return fmt.Sprintf("goto_table:%d", TableOverlay)
+}
+
+// arpResponderActions turns an ARP request into the reply of the instance
+// it asks for, whose MAC is derived from the requested address like
+// InstanceMAC does, and sends it back out of the port it came in on
+const arpResponderActions = "move:NXM_OF_ETH_SRC[]->NXM_OF_ETH_DST[]," +
+	"move:NXM_OF_ARP_TPA[]->NXM_OF_ETH_SRC[0..31]," +
+	"load:0x0200->NXM_OF_ETH_SRC[32..47]," +
+	"load:0x2->NXM_OF_ARP_OP[]," +
+	"move:NXM_NX_ARP_SHA[]->NXM_NX_ARP_THA[]," +
+	"move:NXM_OF_ETH_SRC[]->NXM_NX_ARP_SHA[]," +
+	"push:NXM_OF_ARP_TPA[]," +
+	"move:NXM_OF_ARP_SPA[]->NXM_OF_ARP_TPA[]," +
+	"pop:NXM_OF_ARP_SPA[]," +
+	"IN_PORT"
+
+// CompileOverlay builds the overlay flows of a VPC bridge. The tunnel port
+// is flow based, so the bridge only accepts traffic with the VPC's VNI from
+// the tunnel endpoints of peers, and only sends traffic to the node of a
+// remote instance. ARP for remote instances is answered locally and IPv6
+// instances never use neighbor discovery for each other, so broadcasts never
+// cross the tunnel: NORMAL flooding out of a flow based tunnel port without
+// a destination is dropped, which also keeps a mesh of nodes free of loops.
+func CompileOverlay(tunnelPort string, vni int, ctZone int, peers []string, remotes []RemoteEndpoint) ([]Flow, error) {
+	if vni < 1 || vni > MaxVNI {
+		return nil, fmt.Errorf("invalid VNI: %d", vni)
+	}
+
+	flows := []Flow{
+		{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierTunnelDrop,
+			Match:    fmt.Sprintf("in_port=%s", tunnelPort),
+			Actions:  "drop",
+		},
+		{
+			Table:    TableOverlay,
+			Priority: PriorityOverlayDefault,
+			Actions:  "NORMAL",
+		},
+	}
+	for _, family := range []ipFamily{ipv4Family, ipv6Family} {
+		flows = append(flows,
+			Flow{
+				Table:    TableTunnelIngress,
+				Priority: PriorityTunnelInvalid,
+				Match:    family.ip + ",ct_state=+trk+inv",
+				Actions:  "drop",
+			},
+			Flow{
+				Table:    TableTunnelIngress,
+				Priority: PriorityTunnelDeliver,
+				Match:    family.ip + ",ct_state=+trk",
+				Actions:  fmt.Sprintf("ct(commit,zone=%d),NORMAL", ctZone),
+			},
+		)
+	}
+
+	for _, peer := range peers {
+		if net.ParseIP(peer).To4() == nil {
+			return nil, fmt.Errorf("invalid tunnel endpoint: %s", peer)
+		}
+		for _, family := range []ipFamily{ipv4Family, ipv6Family} {
+			flows = append(flows, Flow{
+				Table:    TableClassifier,
+				Priority: PriorityClassifierTunnel,
+				Match:    fmt.Sprintf("%s,in_port=%s,tun_src=%s,tun_id=%d", family.ip, tunnelPort, peer, vni),
+				Actions:  fmt.Sprintf("ct(zone=%d,table=%d)", ctZone, TableTunnelIngress),
+			})
+		}
+	}
+
+	for _, remote := range remotes {
+		if net.ParseIP(remote.TunnelIP).To4() == nil {
+			return nil, fmt.Errorf("invalid tunnel endpoint: %s", remote.TunnelIP)
+		}
+		mac, err := InstanceMAC(remote.PrivateIP)
+		if err != nil {
+			return nil, err
+		}
+
+		flows = append(flows,
+			Flow{
+				Table:    TableClassifier,
+				Priority: PriorityClassifierARPResponse,
+				Match:    fmt.Sprintf("arp,arp_op=1,arp_tpa=%s", remote.PrivateIP),
+				Actions:  arpResponderActions,
+			},
+			Flow{
+				Table:    TableOverlay,
+				Priority: PriorityOverlayRemote,
+				Match:    fmt.Sprintf("dl_dst=%s", mac),
+				Actions:  fmt.Sprintf("set_field:%s->tun_dst,output:%s", remote.TunnelIP, tunnelPort),
+			},
+		)
+	}
+
+	return flows, nil
+}
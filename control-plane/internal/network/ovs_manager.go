@@ -5,6 +5,7 @@ import (
 	"net"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,21 +18,45 @@ type OVSManager interface {
 	DeleteBridge(name string) error
 	ListBridges() ([]Bridge, error)
 	BridgeExists(name string) (bool, error)
+	SetDatapathID(bridgeName, datapathID string) error
 
 	// Port management
 	AddPort(bridgeName, portName, portType string) error
+	AddPatchPort(bridgeName, portName, peerName string) error
+	AddTunnelPort(bridgeName, portName, tunnelType string, key int) error
 	DeletePort(bridgeName, portName string) error
 	ListPorts(bridgeName string) ([]Port, error)
 
 	// Flow management
 	AddFlow(bridgeName string, flow Flow) error
 	DeleteFlow(bridgeName string, flow Flow) error
+	ReplaceFlows(bridgeName, cookie, mask string, flows []Flow) error
+	DeleteFlowsByCookie(bridgeName, cookie, mask string) error
 	ListFlows(bridgeName string) ([]Flow, error)
+	TraceFlow(bridgeName, flow string) (string, error)
+
+	// Conntrack
+	CountConnections(zone int) (int, error)
 
 	// VLAN management
 	SetPortVLAN(bridgeName, portName string, vlan int) error
 	GetPortVLAN(bridgeName, portName string) (int, error)
 
+	// QoS management
+	SetPortQoS(bridgeName, portName string, qos PortQoS) error
+	GetPortQoS(bridgeName, portName string) (PortQoS, error)
+
+	// Flow export
+	SetIPFIXExport(bridgeName string, export IPFIXExport) error
+	GetIPFIXExport(bridgeName string, collectorSetID int) (*IPFIXExport, error)
+	DeleteIPFIXExport(bridgeName string, collectorSetID int) error
+
+	// Mirroring
+	AddMirrorPort(bridgeName, portName string, endpoint MirrorEndpoint) error
+	SetMirror(bridgeName string, mirror Mirror) error
+	ListMirrors(bridgeName string) ([]Mirror, error)
+	DeleteMirror(bridgeName, mirrorName string) error
+
 	// Utility methods
 	GetBridgeInfo(name string) (*BridgeInfo, error)
 	SetController(bridgeName, controller string) error
@@ -58,15 +83,20 @@ type Port struct {
 
 // Flow represents an OVS flow rule
 type Flow struct {
-	Priority    int    `json:"priority"`
-	Match       string `json:"match"`
-	Actions     string `json:"actions"`
-	Table       int    `json:"table"`
-	IdleAge     int    `json:"idle_age"`
-	HardAge     int    `json:"hard_age"`
-	Cookie      string `json:"cookie"`
-	PacketCount int64  `json:"packet_count"`
-	ByteCount   int64  `json:"byte_count"`
+	Priority    int      `json:"priority"`
+	Match       string   `json:"match"`
+	Actions     string   `json:"actions"`
+	Table       int      `json:"table"`
+	IdleAge     int      `json:"idle_age"`
+	HardAge     int      `json:"hard_age"`
+	IdleTimeout int      `json:"idle_timeout,omitempty"`
+	HardTimeout int      `json:"hard_timeout,omitempty"`
+	Importance  int      `json:"importance,omitempty"`
+	Flags       []string `json:"flags,omitempty"` // send_flow_rem, check_overlap, reset_counts, ...
+	Cookie      string   `json:"cookie"`
+	Duration    float64  `json:"duration"` // seconds since the flow was added
+	PacketCount int64    `json:"packet_count"`
+	ByteCount   int64    `json:"byte_count"`
 }
 
 // BridgeInfo contains detailed information about a bridge
@@ -144,7 +174,7 @@ func (m *ovsManager) CreateBridge(name string, cidr string) error {
 		"stp_enable":                "false",
 		"mcast_snooping":            "false",
 		"rstp_enable":               "false",
-		"protocols":                 "OpenFlow13",
+		"protocols":                 "OpenFlow13,OpenFlow14",
 		"fail_mode":                 "secure",
 		"other_config:forward-bpdu": "false",
 	}
@@ -170,8 +200,22 @@ func (m *ovsManager) DeleteBridge(name string) error {
 		return nil // Bridge doesn't exist, nothing to do
 	}
 
+	// The QoS and queue records of its ports outlive the bridge otherwise
+	args := []string{"del-br", name}
+	ports, err := m.ListPorts(name)
+	if err != nil {
+		return fmt.Errorf("failed to delete bridge: %w", err)
+	}
+	for _, port := range ports {
+		qosArgs, err := m.portQoSDestroys(port.Name)
+		if err != nil {
+			return fmt.Errorf("failed to delete bridge: %w", err)
+		}
+		args = append(args, qosArgs...)
+	}
+
 	// Delete bridge
-	cmd := exec.Command("ovs-vsctl", "del-br", name)
+	cmd := exec.Command("ovs-vsctl", args...)
 	if err := m.runCommand(cmd); err != nil {
 		return fmt.Errorf("failed to delete bridge: %w", err)
 	}
@@ -251,9 +295,61 @@ func (m *ovsManager) AddPort(bridgeName, portName, portType string) error {
 	return nil
 }
 
-// DeletePort removes a port from a bridge
+// AddPatchPort adds a patch port to a bridge that is connected to peerName,
+// which must be added to the other bridge the same way
+func (m *ovsManager) AddPatchPort(bridgeName, portName, peerName string) error {
+	exists, err := m.BridgeExists(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to check bridge existence: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bridge %s does not exist", bridgeName)
+	}
+
+	cmd := exec.Command("ovs-vsctl", "--may-exist", "add-port", bridgeName, portName,
+		"--", "set", "interface", portName, "type=patch", fmt.Sprintf("options:peer=%s", peerName))
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to add patch port %s to bridge %s: %w", portName, bridgeName, err)
+	}
+
+	return nil
+}
+
+// AddTunnelPort adds a flow based tunnel port of the given type to a bridge.
+// Every packet it carries uses key as its tunnel ID, while the remote
+// endpoint is chosen per packet by setting tun_dst in the flow actions.
+func (m *ovsManager) AddTunnelPort(bridgeName, portName, tunnelType string, key int) error {
+	if !ValidTunnelType(tunnelType) {
+		return fmt.Errorf("unsupported tunnel type: %s", tunnelType)
+	}
+
+	exists, err := m.BridgeExists(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to check bridge existence: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bridge %s does not exist", bridgeName)
+	}
+
+	cmd := exec.Command("ovs-vsctl", "--may-exist", "add-port", bridgeName, portName,
+		"--", "set", "interface", portName, fmt.Sprintf("type=%s", tunnelType),
+		"options:remote_ip=flow", fmt.Sprintf("options:key=%d", key))
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to add tunnel port %s to bridge %s: %w", portName, bridgeName, err)
+	}
+
+	return nil
+}
+
+// DeletePort removes a port from a bridge, together with its QoS and queue
+// records
 func (m *ovsManager) DeletePort(bridgeName, portName string) error {
-	cmd := exec.Command("ovs-vsctl", "del-port", bridgeName, portName)
+	qosArgs, err := m.portQoSDestroys(portName)
+	if err != nil {
+		return fmt.Errorf("failed to delete port %s from bridge %s: %w", portName, bridgeName, err)
+	}
+
+	cmd := exec.Command("ovs-vsctl", append([]string{"del-port", bridgeName, portName}, qosArgs...)...)
 	if err := m.runCommand(cmd); err != nil {
 		// Check if port doesn't exist
 		if strings.Contains(err.Error(), "no port named") {
@@ -290,7 +386,7 @@ func (m *ovsManager) ListPorts(bridgeName string) ([]Port, error) {
 
 // AddFlow adds a flow rule to a bridge
 func (m *ovsManager) AddFlow(bridgeName string, flow Flow) error {
-	flowSpec := m.buildFlowSpec(flow)
+	flowSpec := FormatFlowSpec(flow)
 	cmd := exec.Command("ovs-ofctl", "add-flow", bridgeName, flowSpec)
 	if err := m.runCommand(cmd); err != nil {
 		return fmt.Errorf("failed to add flow to bridge %s: %w", bridgeName, err)
@@ -310,15 +406,88 @@ func (m *ovsManager) DeleteFlow(bridgeName string, flow Flow) error {
 	return nil
 }
 
-// ListFlows returns all flow rules for a bridge
+// ReplaceFlows atomically replaces the flows selected by cookie/mask with
+// flows, which must all be selected by it. Flows without a cookie get
+// cookie. The deletion and the additions go to the bridge as one OpenFlow
+// 1.4 bundle, so the flows of an owner change all at once and flows of
+// other owners are never touched. ovs-ofctl replace-flows is not used as it
+// replaces every flow of the bridge, whatever its cookie.
+func (m *ovsManager) ReplaceFlows(bridgeName, cookie, mask string, flows []Flow) error {
+	owned, err := ownedFlows(cookie, mask, flows)
+	if err != nil {
+		return fmt.Errorf("failed to replace flows on bridge %s: %w", bridgeName, err)
+	}
+
+	var bundle strings.Builder
+	fmt.Fprintf(&bundle, "delete cookie=%s/%s\n", cookie, mask)
+	for _, flow := range owned {
+		fmt.Fprintf(&bundle, "add %s\n", FormatFlowSpec(flow))
+	}
+
+	cmd := exec.Command("ovs-ofctl", "-O", "OpenFlow14", "--bundle", "add-flows", bridgeName, "-")
+	cmd.Stdin = strings.NewReader(bundle.String())
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to replace flows on bridge %s: %w", bridgeName, err)
+	}
+
+	return nil
+}
+
+// DeleteFlowsByCookie removes every flow of a bridge selected by cookie/mask
+func (m *ovsManager) DeleteFlowsByCookie(bridgeName, cookie, mask string) error {
+	cmd := exec.Command("ovs-ofctl", "del-flows", bridgeName, fmt.Sprintf("cookie=%s/%s", cookie, mask))
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to delete flows from bridge %s: %w", bridgeName, err)
+	}
+
+	return nil
+}
+
+// ListFlows returns all flow rules for a bridge. Ports are printed by name,
+// the way flows are written.
 func (m *ovsManager) ListFlows(bridgeName string) ([]Flow, error) {
-	cmd := exec.Command("ovs-ofctl", "dump-flows", bridgeName)
+	cmd := exec.Command("ovs-ofctl", "--names", "dump-flows", bridgeName)
 	output, err := m.runCommandWithOutput(cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list flows for bridge %s: %w", bridgeName, err)
 	}
 
-	return m.parseFlows(output), nil
+	flows, err := ParseDumpFlows(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse flows of bridge %s: %w", bridgeName, err)
+	}
+
+	return flows, nil
+}
+
+// TraceFlow runs a microflow through a bridge's flow tables with
+// ofproto/trace and returns the report. Nothing is sent or committed.
+func (m *ovsManager) TraceFlow(bridgeName, flow string) (string, error) {
+	cmd := exec.Command("ovs-appctl", "ofproto/trace", bridgeName, flow)
+	output, err := m.runCommandWithOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to trace flow on bridge %s: %w", bridgeName, err)
+	}
+
+	return output, nil
+}
+
+// CountConnections returns the number of datapath conntrack entries in a zone
+func (m *ovsManager) CountConnections(zone int) (int, error) {
+	cmd := exec.Command("ovs-appctl", "dpctl/dump-conntrack", fmt.Sprintf("zone=%d", zone))
+	output, err := m.runCommandWithOutput(cmd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to dump conntrack zone %d: %w", zone, err)
+	}
+
+	count := 0
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+
+	return count, nil
 }
 
 // SetPortVLAN sets VLAN tag for a port
@@ -352,6 +521,339 @@ func (m *ovsManager) GetPortVLAN(bridgeName, portName string) (int, error) {
 	return vlan, nil
 }
 
+// SetPortQoS polices what a port receives and shapes what it sends with a
+// single linux-htb queue. Every port gets a QoS and queue record of its own,
+// and the ones it had before are destroyed in the same transaction.
+func (m *ovsManager) SetPortQoS(bridgeName, portName string, qos PortQoS) error {
+	qosArgs, err := m.portQoSDestroys(portName)
+	if err != nil {
+		return fmt.Errorf("failed to set QoS for port %s: %w", portName, err)
+	}
+
+	args := []string{
+		"set", "interface", portName,
+		fmt.Sprintf("ingress_policing_rate=%d", qos.IngressPolicingRate),
+		fmt.Sprintf("ingress_policing_burst=%d", qos.IngressPolicingBurst),
+	}
+	if qos.EgressMaxRate > 0 {
+		args = append(args,
+			"--", "set", "port", portName, "qos=@qos",
+			"--", "--id=@qos", "create", "qos", "type=linux-htb",
+			fmt.Sprintf("other_config:max-rate=%d", qos.EgressMaxRate), "queues:0=@queue",
+			"--", "--id=@queue", "create", "queue",
+			fmt.Sprintf("other_config:max-rate=%d", qos.EgressMaxRate),
+		)
+	} else {
+		args = append(args, "--", "clear", "port", portName, "qos")
+	}
+	args = append(args, qosArgs...)
+
+	cmd := exec.Command("ovs-vsctl", args...)
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to set QoS for port %s: %w", portName, err)
+	}
+
+	return nil
+}
+
+// GetPortQoS gets the policing and egress rate of a port
+func (m *ovsManager) GetPortQoS(bridgeName, portName string) (PortQoS, error) {
+	var qos PortQoS
+
+	cmd := exec.Command("ovs-vsctl", "get", "interface", portName, "ingress_policing_rate", "ingress_policing_burst")
+	output, err := m.runCommandWithOutput(cmd)
+	if err != nil {
+		return qos, fmt.Errorf("failed to get QoS for port %s: %w", portName, err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return qos, fmt.Errorf("invalid policing output for port %s: %s", portName, output)
+	}
+	if qos.IngressPolicingRate, err = strconv.Atoi(fields[0]); err != nil {
+		return qos, fmt.Errorf("invalid policing rate: %s", fields[0])
+	}
+	if qos.IngressPolicingBurst, err = strconv.Atoi(fields[1]); err != nil {
+		return qos, fmt.Errorf("invalid policing burst: %s", fields[1])
+	}
+
+	cmd = exec.Command("ovs-vsctl", "get", "port", portName, "qos")
+	output, err = m.runCommandWithOutput(cmd)
+	if err != nil {
+		return qos, fmt.Errorf("failed to get QoS for port %s: %w", portName, err)
+	}
+	qosUUID := strings.TrimSpace(output)
+	if qosUUID == "[]" || qosUUID == "" {
+		return qos, nil // No egress QoS
+	}
+
+	cmd = exec.Command("ovs-vsctl", "--if-exists", "get", "qos", qosUUID, "other_config:max-rate")
+	output, err = m.runCommandWithOutput(cmd)
+	if err != nil {
+		return qos, fmt.Errorf("failed to get QoS for port %s: %w", portName, err)
+	}
+	if rate := strings.Trim(strings.TrimSpace(output), "\""); rate != "" {
+		if qos.EgressMaxRate, err = strconv.ParseInt(rate, 10, 64); err != nil {
+			return qos, fmt.Errorf("invalid max rate: %s", rate)
+		}
+	}
+
+	return qos, nil
+}
+
+// SetIPFIXExport creates or replaces a collector set of a bridge
+func (m *ovsManager) SetIPFIXExport(bridgeName string, export IPFIXExport) error {
+	bridgeUUID, setUUID, err := m.findCollectorSet(bridgeName, export.CollectorSetID)
+	if err != nil {
+		return fmt.Errorf("failed to set IPFIX export for bridge %s: %w", bridgeName, err)
+	}
+
+	var args []string
+	if setUUID != "" {
+		args = append(args, "--", "destroy", "flow_sample_collector_set", setUUID)
+	}
+	args = append(args,
+		"--", "--id=@ipfix", "create", "ipfix",
+		fmt.Sprintf("targets=\"%s\"", export.Target),
+		fmt.Sprintf("cache_active_timeout=%d", export.CacheActiveTimeout),
+		fmt.Sprintf("cache_max_flows=%d", export.CacheMaxFlows),
+		"--", "create", "flow_sample_collector_set",
+		fmt.Sprintf("bridge=%s", bridgeUUID),
+		fmt.Sprintf("id=%d", export.CollectorSetID),
+		"ipfix=@ipfix",
+	)
+
+	cmd := exec.Command("ovs-vsctl", args...)
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to set IPFIX export for bridge %s: %w", bridgeName, err)
+	}
+
+	return nil
+}
+
+// GetIPFIXExport returns a collector set of a bridge, or nil if the bridge
+// has no set with that ID
+func (m *ovsManager) GetIPFIXExport(bridgeName string, collectorSetID int) (*IPFIXExport, error) {
+	_, setUUID, err := m.findCollectorSet(bridgeName, collectorSetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPFIX export for bridge %s: %w", bridgeName, err)
+	}
+	if setUUID == "" {
+		return nil, nil
+	}
+
+	export := &IPFIXExport{CollectorSetID: collectorSetID}
+
+	cmd := exec.Command("ovs-vsctl", "get", "flow_sample_collector_set", setUUID, "ipfix")
+	output, err := m.runCommandWithOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPFIX export for bridge %s: %w", bridgeName, err)
+	}
+	ipfixUUID := strings.TrimSpace(output)
+	if ipfixUUID == "[]" || ipfixUUID == "" {
+		return export, nil
+	}
+
+	cmd = exec.Command("ovs-vsctl", "get", "ipfix", ipfixUUID, "targets", "cache_active_timeout", "cache_max_flows")
+	output, err = m.runCommandWithOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IPFIX export for bridge %s: %w", bridgeName, err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 {
+		return nil, fmt.Errorf("invalid IPFIX output for bridge %s: %s", bridgeName, output)
+	}
+	targets := strings.Split(strings.Trim(strings.TrimSpace(lines[0]), "[]"), ",")
+	export.Target = strings.Trim(strings.TrimSpace(targets[0]), "\"")
+	export.CacheActiveTimeout, _ = strconv.Atoi(strings.TrimSpace(lines[1]))
+	export.CacheMaxFlows, _ = strconv.Atoi(strings.TrimSpace(lines[2]))
+
+	return export, nil
+}
+
+// DeleteIPFIXExport removes a collector set from a bridge
+func (m *ovsManager) DeleteIPFIXExport(bridgeName string, collectorSetID int) error {
+	_, setUUID, err := m.findCollectorSet(bridgeName, collectorSetID)
+	if err != nil {
+		return fmt.Errorf("failed to delete IPFIX export for bridge %s: %w", bridgeName, err)
+	}
+	if setUUID == "" {
+		return nil // No collector set, nothing to do
+	}
+
+	cmd := exec.Command("ovs-vsctl", "destroy", "flow_sample_collector_set", setUUID)
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to delete IPFIX export for bridge %s: %w", bridgeName, err)
+	}
+
+	return nil
+}
+
+// AddMirrorPort adds a GRE or ERSPAN port that carries mirrored traffic to
+// a fixed remote endpoint, or updates an existing one
+func (m *ovsManager) AddMirrorPort(bridgeName, portName string, endpoint MirrorEndpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return fmt.Errorf("invalid mirror endpoint: %w", err)
+	}
+
+	exists, err := m.BridgeExists(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to check bridge existence: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bridge %s does not exist", bridgeName)
+	}
+
+	args := []string{"--may-exist", "add-port", bridgeName, portName,
+		"--", "set", "interface", portName, fmt.Sprintf("type=%s", endpoint.Type)}
+	for key, value := range endpoint.Options() {
+		args = append(args, fmt.Sprintf("options:%s=%s", key, value))
+	}
+
+	cmd := exec.Command("ovs-vsctl", args...)
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to add mirror port %s to bridge %s: %w", portName, bridgeName, err)
+	}
+
+	return nil
+}
+
+// SetMirror creates or replaces a mirror of a bridge. The mirror it
+// replaces is garbage collected by OVSDB.
+func (m *ovsManager) SetMirror(bridgeName string, mirror Mirror) error {
+	mirrorUUID, err := m.findMirror(bridgeName, mirror.Name)
+	if err != nil {
+		return fmt.Errorf("failed to set mirror %s on bridge %s: %w", mirror.Name, bridgeName, err)
+	}
+
+	var args []string
+	if mirrorUUID != "" {
+		args = append(args, "--", "remove", "bridge", bridgeName, "mirrors", mirrorUUID)
+	}
+
+	// Every port the mirror refers to is looked up once
+	refs := make(map[string]string)
+	ref := func(portName string) string {
+		if id, ok := refs[portName]; ok {
+			return id
+		}
+		id := fmt.Sprintf("@p%d", len(refs))
+		refs[portName] = id
+		args = append(args, "--", fmt.Sprintf("--id=%s", id), "get", "port", portName)
+		return id
+	}
+	portRefs := func(portNames []string) string {
+		ids := make([]string, len(portNames))
+		for i, name := range portNames {
+			ids[i] = ref(name)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	create := []string{"--", "--id=@m", "create", "mirror", fmt.Sprintf("name=%s", mirror.Name)}
+	if len(mirror.SrcPorts) > 0 {
+		create = append(create, fmt.Sprintf("select_src_port=%s", portRefs(mirror.SrcPorts)))
+	}
+	if len(mirror.DstPorts) > 0 {
+		create = append(create, fmt.Sprintf("select_dst_port=%s", portRefs(mirror.DstPorts)))
+	}
+	if mirror.Filter != "" {
+		create = append(create, fmt.Sprintf("filter=\"%s\"", mirror.Filter))
+	}
+	create = append(create, fmt.Sprintf("output_port=%s", ref(mirror.OutputPort)))
+
+	args = append(args, create...)
+	args = append(args, "--", "add", "bridge", bridgeName, "mirrors", "@m")
+
+	cmd := exec.Command("ovs-vsctl", args...)
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to set mirror %s on bridge %s: %w", mirror.Name, bridgeName, err)
+	}
+
+	return nil
+}
+
+// ListMirrors returns the mirrors of a bridge
+func (m *ovsManager) ListMirrors(bridgeName string) ([]Mirror, error) {
+	cmd := exec.Command("ovs-vsctl", "get", "bridge", bridgeName, "mirrors")
+	output, err := m.runCommandWithOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mirrors for bridge %s: %w", bridgeName, err)
+	}
+
+	portNames := make(map[string]string)
+	resolve := func(uuids []string) ([]string, error) {
+		names := make([]string, 0, len(uuids))
+		for _, uuid := range uuids {
+			name, ok := portNames[uuid]
+			if !ok {
+				cmd := exec.Command("ovs-vsctl", "get", "port", uuid, "name")
+				output, err := m.runCommandWithOutput(cmd)
+				if err != nil {
+					return nil, err
+				}
+				name = unquoteVsctl(output)
+				portNames[uuid] = name
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+
+	mirrors := make([]Mirror, 0)
+	for _, mirrorUUID := range splitVsctlSet(output) {
+		cmd := exec.Command("ovs-vsctl", "get", "mirror", mirrorUUID,
+			"name", "filter", "select_src_port", "select_dst_port", "output_port")
+		output, err := m.runCommandWithOutput(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get mirror %s: %w", mirrorUUID, err)
+		}
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 5 {
+			return nil, fmt.Errorf("invalid mirror output for bridge %s: %s", bridgeName, output)
+		}
+
+		mirror := Mirror{Name: unquoteVsctl(lines[0])}
+		if filter := strings.TrimSpace(lines[1]); filter != "[]" {
+			mirror.Filter = unquoteVsctl(filter)
+		}
+		if mirror.SrcPorts, err = resolve(splitVsctlSet(lines[2])); err != nil {
+			return nil, fmt.Errorf("failed to resolve ports of mirror %s: %w", mirror.Name, err)
+		}
+		if mirror.DstPorts, err = resolve(splitVsctlSet(lines[3])); err != nil {
+			return nil, fmt.Errorf("failed to resolve ports of mirror %s: %w", mirror.Name, err)
+		}
+		outputPorts, err := resolve(splitVsctlSet(lines[4]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve ports of mirror %s: %w", mirror.Name, err)
+		}
+		if len(outputPorts) > 0 {
+			mirror.OutputPort = outputPorts[0]
+		}
+		mirrors = append(mirrors, mirror)
+	}
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].Name < mirrors[j].Name })
+
+	return mirrors, nil
+}
+
+// DeleteMirror removes a mirror from a bridge
+func (m *ovsManager) DeleteMirror(bridgeName, mirrorName string) error {
+	mirrorUUID, err := m.findMirror(bridgeName, mirrorName)
+	if err != nil {
+		return fmt.Errorf("failed to delete mirror %s from bridge %s: %w", mirrorName, bridgeName, err)
+	}
+	if mirrorUUID == "" {
+		return nil // Mirror doesn't exist, nothing to do
+	}
+
+	cmd := exec.Command("ovs-vsctl", "remove", "bridge", bridgeName, "mirrors", mirrorUUID)
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to delete mirror %s from bridge %s: %w", mirrorName, bridgeName, err)
+	}
+
+	return nil
+}
+
 // GetBridgeInfo returns detailed information about a bridge
 func (m *ovsManager) GetBridgeInfo(name string) (*BridgeInfo, error) {
 	exists, err := m.BridgeExists(name)
@@ -392,13 +894,24 @@ func (m *ovsManager) GetBridgeInfo(name string) (*BridgeInfo, error) {
 	return &BridgeInfo{
 		Name:       name,
 		UUID:       strings.TrimSpace(uuidOutput),
-		DataPath:   strings.TrimSpace(datapathOutput),
+		DataPath:   strings.Trim(datapathOutput, "\" \n"),
 		Controller: strings.TrimSpace(controllerOutput),
 		Ports:      ports,
 		Status:     "active",
 	}, nil
 }
 
+// SetDatapathID pins the OpenFlow datapath ID of a bridge, 16 hex digits,
+// instead of letting OVS derive it from the bridge's MAC address
+func (m *ovsManager) SetDatapathID(bridgeName, datapathID string) error {
+	cmd := exec.Command("ovs-vsctl", "set", "bridge", bridgeName, "other-config:datapath-id="+datapathID)
+	if err := m.runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to set datapath ID for bridge %s: %w", bridgeName, err)
+	}
+
+	return nil
+}
+
 // SetController sets the OpenFlow controller for a bridge
 func (m *ovsManager) SetController(bridgeName, controller string) error {
 	cmd := exec.Command("ovs-vsctl", "set-controller", bridgeName, controller)
@@ -499,7 +1012,7 @@ func (m *ovsManager) getBridgeDetails(name string) (*Bridge, error) {
 	return &Bridge{
 		Name:     name,
 		UUID:     strings.TrimSpace(uuidOutput),
-		DataPath: strings.TrimSpace(datapathOutput),
+		DataPath: strings.Trim(datapathOutput, "\" \n"),
 		Ports:    ports,
 		Created:  time.Now(), // This would need to be retrieved from OVS DB for accuracy
 	}, nil
@@ -534,27 +1047,104 @@ func (m *ovsManager) getPortDetails(bridgeName, portName string) (*Port, error)
 	}, nil
 }
 
-// buildFlowSpec builds flow specification string
-func (m *ovsManager) buildFlowSpec(flow Flow) string {
-	spec := ""
+// findCollectorSet returns the UUID of a bridge and of its collector set
+// with the given ID, which is empty if there is none
+func (m *ovsManager) findCollectorSet(bridgeName string, collectorSetID int) (string, string, error) {
+	cmd := exec.Command("ovs-vsctl", "get", "bridge", bridgeName, "_uuid")
+	output, err := m.runCommandWithOutput(cmd)
+	if err != nil {
+		return "", "", err
+	}
+	bridgeUUID := strings.TrimSpace(output)
 
-	if flow.Table > 0 {
-		spec += fmt.Sprintf("table=%d,", flow.Table)
+	cmd = exec.Command("ovs-vsctl", "--bare", "--columns=_uuid", "find", "flow_sample_collector_set",
+		fmt.Sprintf("id=%d", collectorSetID), fmt.Sprintf("bridge=%s", bridgeUUID))
+	output, err = m.runCommandWithOutput(cmd)
+	if err != nil {
+		return "", "", err
 	}
 
-	if flow.Priority > 0 {
-		spec += fmt.Sprintf("priority=%d,", flow.Priority)
+	return bridgeUUID, strings.TrimSpace(output), nil
+}
+
+// portQoSDestroys returns the ovs-vsctl commands that destroy the QoS record
+// of a port and its queues. OVSDB keeps QoS and queue records after the last
+// reference is gone. A port that does not exist has none.
+func (m *ovsManager) portQoSDestroys(portName string) ([]string, error) {
+	cmd := exec.Command("ovs-vsctl", "--if-exists", "get", "port", portName, "qos")
+	output, err := m.runCommandWithOutput(cmd)
+	if err != nil {
+		return nil, err
+	}
+	qosUUID := strings.TrimSpace(output)
+	if qosUUID == "[]" || qosUUID == "" {
+		return nil, nil
 	}
 
-	if flow.Match != "" {
-		spec += flow.Match + ","
+	// The queues map reads as 0=<uuid> pairs
+	cmd = exec.Command("ovs-vsctl", "--bare", "--columns=queues", "list", "qos", qosUUID)
+	output, err = m.runCommandWithOutput(cmd)
+	if err != nil {
+		return nil, err
 	}
 
-	if flow.Actions != "" {
-		spec += "actions=" + flow.Actions
+	args := []string{"--", "destroy", "qos", qosUUID}
+	for _, pair := range strings.Fields(output) {
+		if _, queueUUID, ok := strings.Cut(pair, "="); ok {
+			args = append(args, "--", "destroy", "queue", queueUUID)
+		}
 	}
+	return args, nil
+}
 
-	return strings.TrimSuffix(spec, ",")
+// findMirror returns the UUID of the named mirror of a bridge, which is
+// empty if the bridge has no such mirror
+func (m *ovsManager) findMirror(bridgeName, mirrorName string) (string, error) {
+	cmd := exec.Command("ovs-vsctl", "get", "bridge", bridgeName, "mirrors")
+	output, err := m.runCommandWithOutput(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	for _, mirrorUUID := range splitVsctlSet(output) {
+		cmd := exec.Command("ovs-vsctl", "get", "mirror", mirrorUUID, "name")
+		name, err := m.runCommandWithOutput(cmd)
+		if err != nil {
+			return "", err
+		}
+		if unquoteVsctl(name) == mirrorName {
+			return mirrorUUID, nil
+		}
+	}
+	return "", nil
+}
+
+// splitVsctlSet splits a set as ovs-vsctl get prints it, e.g. [a, b]
+func splitVsctlSet(s string) []string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") {
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+	elements := make([]string, 0)
+	for _, element := range strings.Split(strings.Trim(s, "[]"), ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// unquoteVsctl returns a string as ovs-vsctl get prints it without the
+// quotes it adds to strings that are not plain identifiers
+func unquoteVsctl(s string) string {
+	s = strings.TrimSpace(s)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
 }
 
 // buildFlowMatchSpec builds flow match specification for deletion
@@ -575,47 +1165,3 @@ func (m *ovsManager) buildFlowMatchSpec(flow Flow) string {
 
 	return strings.TrimSuffix(spec, ",")
 }
-
-// parseFlows parses ovs-ofctl dump-flows output
-func (m *ovsManager) parseFlows(output string) []Flow {
-	lines := strings.Split(output, "\n")
-	flows := make([]Flow, 0)
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "NXST_FLOW") {
-			continue
-		}
-
-		flow := m.parseFlowLine(line)
-		if flow != nil {
-			flows = append(flows, *flow)
-		}
-	}
-
-	return flows
-}
-
-// parseFlowLine parses a single flow line
-func (m *ovsManager) parseFlowLine(line string) *Flow {
-	// This is a simplified parser - in production you'd want more robust parsing
-	flow := &Flow{}
-
-	// Extract priority
-	if idx := strings.Index(line, "priority="); idx >= 0 {
-		priorityStr := line[idx+9:]
-		if comma := strings.Index(priorityStr, ","); comma >= 0 {
-			priorityStr = priorityStr[:comma]
-		}
-		if priority, err := strconv.Atoi(priorityStr); err == nil {
-			flow.Priority = priority
-		}
-	}
-
-	// Extract actions
-	if idx := strings.Index(line, "actions="); idx >= 0 {
-		flow.Actions = line[idx+8:]
-	}
-
-	return flow
-}
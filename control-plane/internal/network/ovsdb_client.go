@@ -0,0 +1,523 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ovsdbDatabase is the schema every Open vSwitch ovsdb-server serves
+const ovsdbDatabase = "Open_vSwitch"
+
+// OVSDBRow is a table row as sent by ovsdb-server, with column values in
+// the OVSDB JSON notation of RFC 7047
+type OVSDBRow map[string]interface{}
+
+// OVSDBOperation is a single operation of a transaction
+type OVSDBOperation struct {
+	Op        string          `json:"op"`
+	Table     string          `json:"table"`
+	Where     [][]interface{} `json:"where,omitempty"`
+	Row       OVSDBRow        `json:"row,omitempty"`
+	Rows      interface{}     `json:"rows,omitempty"` // wait only, where an empty list is meaningful
+	Columns   []string        `json:"columns,omitempty"`
+	Mutations [][]interface{} `json:"mutations,omitempty"`
+	UUIDName  string          `json:"uuid-name,omitempty"`
+	Timeout   *int            `json:"timeout,omitempty"`
+	Until     string          `json:"until,omitempty"`
+}
+
+// OVSDBResult is the result of a single operation of a transaction
+type OVSDBResult struct {
+	Count   int        `json:"count,omitempty"`
+	UUID    []string   `json:"uuid,omitempty"`
+	Rows    []OVSDBRow `json:"rows,omitempty"`
+	Error   string     `json:"error,omitempty"`
+	Details string     `json:"details,omitempty"`
+}
+
+// Values in the OVSDB JSON notation
+type (
+	// OVSDBUUID references a row by UUID
+	OVSDBUUID string
+	// OVSDBNamedUUID references a row inserted earlier in the same transaction
+	OVSDBNamedUUID string
+	// OVSDBSet is a set of atoms
+	OVSDBSet []interface{}
+	// OVSDBMap is a map of string keys and values
+	OVSDBMap map[string]string
+)
+
+func (u OVSDBUUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{"uuid", string(u)})
+}
+
+func (u OVSDBNamedUUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{"named-uuid", string(u)})
+}
+
+func (s OVSDBSet) MarshalJSON() ([]byte, error) {
+	elements := []interface{}(s)
+	if elements == nil {
+		elements = []interface{}{}
+	}
+	return json.Marshal([]interface{}{"set", elements})
+}
+
+func (m OVSDBMap) MarshalJSON() ([]byte, error) {
+	pairs := make([][]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, []string{key, value})
+	}
+	return json.Marshal([]interface{}{"map", pairs})
+}
+
+// ovsdbMessage is a JSON-RPC 1.0 request, response or notification
+type ovsdbMessage struct {
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// ovsdbRowUpdate is the change of a single row in a monitor update
+type ovsdbRowUpdate struct {
+	Old OVSDBRow `json:"old"`
+	New OVSDBRow `json:"new"`
+}
+
+// ovsdbTableUpdates maps table name to row UUID to the change of that row
+type ovsdbTableUpdates map[string]map[string]ovsdbRowUpdate
+
+// OVSDBClient speaks the OVSDB management protocol (RFC 7047) to
+// ovsdb-server. The tables passed to Monitor are mirrored into a local
+// cache that the server keeps up to date, so reads never leave the process.
+type OVSDBClient struct {
+	address string
+	timeout time.Duration
+
+	writeMu sync.Mutex
+	conn    net.Conn
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan *ovsdbMessage
+	closed  bool
+	err     error
+
+	cacheMu    sync.RWMutex
+	changed    *sync.Cond
+	generation int
+	cache      map[string]map[string]OVSDBRow
+}
+
+// DialOVSDB connects to ovsdb-server at address, given the way ovs-vsctl
+// takes --db: unix:<path> or tcp:<host>:<port>
+func DialOVSDB(address string, timeout time.Duration) (*OVSDBClient, error) {
+	var network, target string
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		network, target = "unix", strings.TrimPrefix(address, "unix:")
+	case strings.HasPrefix(address, "tcp:"):
+		network, target = "tcp", strings.TrimPrefix(address, "tcp:")
+	default:
+		return nil, fmt.Errorf("unsupported OVSDB address: %s", address)
+	}
+
+	conn, err := net.DialTimeout(network, target, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to OVSDB at %s: %w", address, err)
+	}
+
+	c := &OVSDBClient{
+		address: address,
+		timeout: timeout,
+		conn:    conn,
+		pending: make(map[int]chan *ovsdbMessage),
+		cache:   make(map[string]map[string]OVSDBRow),
+	}
+	c.changed = sync.NewCond(c.cacheMu.RLocker())
+
+	go c.readLoop()
+	return c, nil
+}
+
+// Close closes the connection. Calls in flight fail.
+func (c *OVSDBClient) Close() error {
+	return c.conn.Close()
+}
+
+// Err returns the error that broke the connection, or nil while it is up
+func (c *OVSDBClient) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Transact runs operations as a single atomic transaction. It fails if any
+// operation fails, in which case none of them took effect.
+func (c *OVSDBClient) Transact(ops ...OVSDBOperation) ([]OVSDBResult, error) {
+	params := make([]interface{}, 0, len(ops)+1)
+	params = append(params, ovsdbDatabase)
+	for _, op := range ops {
+		params = append(params, op)
+	}
+
+	var results []OVSDBResult
+	if err := c.call("transact", params, &results); err != nil {
+		return nil, err
+	}
+
+	// A result beyond the last operation reports a failed commit
+	for i, result := range results {
+		if result.Error == "" {
+			continue
+		}
+		if i < len(ops) {
+			return nil, fmt.Errorf("OVSDB %s on %s failed: %s: %s", ops[i].Op, ops[i].Table, result.Error, result.Details)
+		}
+		return nil, fmt.Errorf("OVSDB transaction failed: %s: %s", result.Error, result.Details)
+	}
+	if len(results) < len(ops) {
+		return nil, fmt.Errorf("OVSDB transaction returned %d results for %d operations", len(results), len(ops))
+	}
+
+	return results, nil
+}
+
+// Monitor mirrors the given columns of the given tables into the cache and
+// keeps them current. It returns once the initial contents are cached.
+func (c *OVSDBClient) Monitor(tables map[string][]string) error {
+	requests := make(map[string]interface{}, len(tables))
+	for table, columns := range tables {
+		requests[table] = map[string]interface{}{"columns": columns}
+	}
+
+	var initial ovsdbTableUpdates
+	if err := c.call("monitor", []interface{}{ovsdbDatabase, nil, requests}, &initial); err != nil {
+		return err
+	}
+
+	c.applyUpdates(initial)
+	return nil
+}
+
+// Rows returns a snapshot of the cached rows of a table keyed by UUID
+func (c *OVSDBClient) Rows(table string) map[string]OVSDBRow {
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+
+	rows := make(map[string]OVSDBRow, len(c.cache[table]))
+	for uuid, row := range c.cache[table] {
+		rows[uuid] = row
+	}
+	return rows
+}
+
+// Row returns a cached row, or nil if the table has no row with that UUID
+func (c *OVSDBClient) Row(table string, uuid string) OVSDBRow {
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+	return c.cache[table][uuid]
+}
+
+// WaitUntil blocks until check reports true, re-evaluating it whenever the
+// cache changes. ovsdb-server sends monitor updates independently of
+// transaction replies, so a read right after a write needs this.
+func (c *OVSDBClient) WaitUntil(check func() bool) error {
+	deadline := time.Now().Add(c.timeout)
+	timer := time.AfterFunc(c.timeout, func() {
+		c.cacheMu.Lock()
+		c.changed.Broadcast()
+		c.cacheMu.Unlock()
+	})
+	defer timer.Stop()
+
+	for {
+		c.cacheMu.RLock()
+		generation := c.generation
+		c.cacheMu.RUnlock()
+
+		if check() {
+			return nil
+		}
+		if err := c.Err(); err != nil {
+			return fmt.Errorf("OVSDB connection lost: %w", err)
+		}
+
+		c.cacheMu.RLock()
+		for c.generation == generation && c.Err() == nil && time.Now().Before(deadline) {
+			c.changed.Wait()
+		}
+		c.cacheMu.RUnlock()
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("timed out waiting for OVSDB")
+		}
+	}
+}
+
+// call sends a request and decodes the result of its response into result
+func (c *OVSDBClient) call(method string, params interface{}, result interface{}) error {
+	c.mu.Lock()
+	if c.closed {
+		err := c.err
+		c.mu.Unlock()
+		return fmt.Errorf("OVSDB connection is closed: %w", err)
+	}
+	c.nextID++
+	id := c.nextID
+	reply := make(chan *ovsdbMessage, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		c.forget(id)
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	if err := c.send(&ovsdbMessage{Method: method, Params: rawParams, ID: json.RawMessage(fmt.Sprintf("%d", id))}); err != nil {
+		c.forget(id)
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	select {
+	case msg, ok := <-reply:
+		if !ok {
+			return fmt.Errorf("OVSDB connection lost during %s: %w", method, c.Err())
+		}
+		if len(msg.Error) > 0 && !bytes.Equal(msg.Error, []byte("null")) {
+			return fmt.Errorf("OVSDB %s failed: %s", method, string(msg.Error))
+		}
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", method, err)
+		}
+		return nil
+	case <-time.After(c.timeout):
+		c.forget(id)
+		return fmt.Errorf("OVSDB %s timed out", method)
+	}
+}
+
+func (c *OVSDBClient) send(msg *ovsdbMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err = c.conn.Write(data)
+	return err
+}
+
+func (c *OVSDBClient) forget(id int) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// readLoop dispatches everything the server sends until the connection
+// breaks: responses to their callers, monitor updates to the cache and
+// echo requests straight back as keepalive replies
+func (c *OVSDBClient) readLoop() {
+	decoder := json.NewDecoder(c.conn)
+
+	for {
+		var msg ovsdbMessage
+		if err := decoder.Decode(&msg); err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		switch msg.Method {
+		case "":
+			var id int
+			if err := json.Unmarshal(msg.ID, &id); err != nil {
+				continue
+			}
+			c.mu.Lock()
+			reply, ok := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ok {
+				reply <- &msg
+			}
+		case "echo":
+			c.send(&ovsdbMessage{Result: msg.Params, Error: json.RawMessage("null"), ID: msg.ID})
+		case "update":
+			var params []json.RawMessage
+			if err := json.Unmarshal(msg.Params, &params); err != nil || len(params) != 2 {
+				continue
+			}
+			var updates ovsdbTableUpdates
+			if err := json.Unmarshal(params[1], &updates); err != nil {
+				continue
+			}
+			c.applyUpdates(updates)
+		}
+	}
+}
+
+// shutdown fails every call in flight and wakes everyone waiting on the cache
+func (c *OVSDBClient) shutdown(err error) {
+	c.mu.Lock()
+	c.closed = true
+	c.err = err
+	for id, reply := range c.pending {
+		close(reply)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+
+	c.cacheMu.Lock()
+	c.changed.Broadcast()
+	c.cacheMu.Unlock()
+}
+
+// applyUpdates merges monitor updates into the cache. With monitor version 1
+// a row's new state carries every monitored column, so it replaces the row.
+func (c *OVSDBClient) applyUpdates(updates ovsdbTableUpdates) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	for table, rows := range updates {
+		if c.cache[table] == nil {
+			c.cache[table] = make(map[string]OVSDBRow)
+		}
+		for uuid, update := range rows {
+			if update.New == nil {
+				delete(c.cache[table], uuid)
+				continue
+			}
+			c.cache[table][uuid] = update.New
+		}
+	}
+
+	c.generation++
+	c.changed.Broadcast()
+}
+
+// Column decoding helpers for cached rows
+
+// ovsdbString returns a string column, or an empty string for an empty
+// optional value
+func ovsdbString(row OVSDBRow, column string) string {
+	if s, ok := row[column].(string); ok {
+		return s
+	}
+	return ""
+}
+
+// ovsdbInt returns an integer column, or false for an empty optional value
+func ovsdbInt(row OVSDBRow, column string) (int, bool) {
+	if n, ok := row[column].(float64); ok {
+		return int(n), true
+	}
+	return 0, false
+}
+
+// ovsdbUUIDs returns the UUIDs of a reference column, which holds a single
+// ["uuid", ...] atom or a ["set", [...]] of them
+func ovsdbUUIDs(row OVSDBRow, column string) []string {
+	value, ok := row[column].([]interface{})
+	if !ok || len(value) != 2 {
+		return nil
+	}
+
+	switch value[0] {
+	case "uuid":
+		if uuid, ok := value[1].(string); ok {
+			return []string{uuid}
+		}
+	case "set":
+		elements, _ := value[1].([]interface{})
+		uuids := make([]string, 0, len(elements))
+		for _, element := range elements {
+			atom, ok := element.([]interface{})
+			if !ok || len(atom) != 2 || atom[0] != "uuid" {
+				continue
+			}
+			if uuid, ok := atom[1].(string); ok {
+				uuids = append(uuids, uuid)
+			}
+		}
+		return uuids
+	}
+	return nil
+}
+
+// ovsdbMapUUIDs returns the UUID values of a map column, such as the queues
+// of a QoS row
+func ovsdbMapUUIDs(row OVSDBRow, column string) []string {
+	value, ok := row[column].([]interface{})
+	if !ok || len(value) != 2 || value[0] != "map" {
+		return nil
+	}
+
+	pairs, _ := value[1].([]interface{})
+	uuids := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		kv, ok := pair.([]interface{})
+		if !ok || len(kv) != 2 {
+			continue
+		}
+		atom, ok := kv[1].([]interface{})
+		if !ok || len(atom) != 2 || atom[0] != "uuid" {
+			continue
+		}
+		if uuid, ok := atom[1].(string); ok {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids
+}
+
+// ovsdbStrings returns a set column of strings, which holds a single string
+// atom or a ["set", [...]] of them
+func ovsdbStrings(row OVSDBRow, column string) []string {
+	switch value := row[column].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		if len(value) != 2 || value[0] != "set" {
+			return nil
+		}
+		elements, _ := value[1].([]interface{})
+		strs := make([]string, 0, len(elements))
+		for _, element := range elements {
+			if s, ok := element.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	}
+	return nil
+}
+
+// ovsdbStringMap returns a map column with string keys and values
+func ovsdbStringMap(row OVSDBRow, column string) map[string]string {
+	result := make(map[string]string)
+	value, ok := row[column].([]interface{})
+	if !ok || len(value) != 2 || value[0] != "map" {
+		return result
+	}
+
+	pairs, _ := value[1].([]interface{})
+	for _, pair := range pairs {
+		kv, ok := pair.([]interface{})
+		if !ok || len(kv) != 2 {
+			continue
+		}
+		key, _ := kv[0].(string)
+		val, _ := kv[1].(string)
+		result[key] = val
+	}
+	return result
+}
@@ -0,0 +1,1059 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ovsdbMonitoredColumns are the columns the OVSDB manager mirrors locally,
+// which is everything the OVSManager interface reads
+var ovsdbMonitoredColumns = map[string][]string{
+	"Open_vSwitch":              {"bridges", "next_cfg", "cur_cfg"},
+	"Bridge":                    {"name", "ports", "mirrors", "datapath_id", "controller"},
+	"Port":                      {"name", "interfaces", "tag", "qos"},
+	"Interface":                 {"name", "type", "options", "ingress_policing_rate", "ingress_policing_burst"},
+	"Controller":                {"target"},
+	"QoS":                       {"other_config", "queues"},
+	"IPFIX":                     {"targets", "cache_active_timeout", "cache_max_flows"},
+	"Flow_Sample_Collector_Set": {"id", "bridge", "ipfix"},
+	"Mirror":                    {"name"},
+}
+
+// ovsdbManager implements OVSManager on top of the OVSDB protocol instead of
+// ovs-vsctl. Every change is a single transaction and every read is served
+// from the monitor cache. OpenFlow and the datapath are not part of OVSDB,
+// so flows and conntrack still go through ovs-ofctl and ovs-appctl.
+type ovsdbManager struct {
+	*ovsManager
+
+	address string
+	mu      sync.Mutex
+	client  *OVSDBClient
+}
+
+// NewOVSDBManager creates an OVS manager that talks to ovsdb-server at
+// address, e.g. unix:/var/run/openvswitch/db.sock or tcp:127.0.0.1:6640
+func NewOVSDBManager(address string) (OVSManager, error) {
+	m := &ovsdbManager{
+		ovsManager: &ovsManager{timeout: 30 * time.Second},
+		address:    address,
+	}
+	if _, err := m.connection(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CreateBridge creates a new OVS bridge with specified CIDR. The bridge, its
+// local port and its options are created in one transaction, which fails if
+// a bridge with the same name appears concurrently.
+func (m *ovsdbManager) CreateBridge(name string, cidr string) error {
+	if err := m.validateBridgeName(name); err != nil {
+		return fmt.Errorf("invalid bridge name: %w", err)
+	}
+
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid CIDR: %w", err)
+	}
+
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+	if uuid, _ := findByName(c, "Bridge", name); uuid != "" {
+		return fmt.Errorf("bridge %s already exists", name)
+	}
+
+	timeout := 0
+	ops := []OVSDBOperation{
+		{
+			Op:      "wait",
+			Table:   "Bridge",
+			Timeout: &timeout,
+			Where:   [][]interface{}{{"name", "==", name}},
+			Columns: []string{"name"},
+			Until:   "==",
+			Rows:    []OVSDBRow{},
+		},
+		{
+			Op:       "insert",
+			Table:    "Interface",
+			Row:      OVSDBRow{"name": name, "type": "internal"},
+			UUIDName: "iface",
+		},
+		{
+			Op:       "insert",
+			Table:    "Port",
+			Row:      OVSDBRow{"name": name, "interfaces": OVSDBNamedUUID("iface")},
+			UUIDName: "port",
+		},
+		{
+			Op:    "insert",
+			Table: "Bridge",
+			Row: OVSDBRow{
+				"name":                  name,
+				"ports":                 OVSDBNamedUUID("port"),
+				"protocols":             OVSDBSet{"OpenFlow13", "OpenFlow14"},
+				"fail_mode":             "secure",
+				"stp_enable":            false,
+				"rstp_enable":           false,
+				"mcast_snooping_enable": false,
+				"other_config":          OVSDBMap{"forward-bpdu": "false"},
+			},
+			UUIDName: "bridge",
+		},
+		{
+			Op:        "mutate",
+			Table:     "Open_vSwitch",
+			Where:     [][]interface{}{{"_uuid", "==", OVSDBUUID(rootUUID(c))}},
+			Mutations: [][]interface{}{{"bridges", "insert", OVSDBSet{OVSDBNamedUUID("bridge")}}},
+		},
+	}
+	if err := m.commit(c, ops...); err != nil {
+		return fmt.Errorf("failed to create bridge: %w", err)
+	}
+
+	// Addresses are kernel state, not OVSDB state
+	cmd := exec.Command("ip", "link", "set", "dev", name, "up")
+	if err := m.runCommand(cmd); err != nil {
+		m.DeleteBridge(name)
+		return fmt.Errorf("failed to bring bridge up: %w", err)
+	}
+
+	bridgeIP := m.getFirstIP(ipNet)
+	if bridgeIP != "" {
+		cmd = exec.Command("ip", "addr", "add", fmt.Sprintf("%s/%d", bridgeIP, m.getPrefixLength(ipNet)), "dev", name)
+		if err := m.runCommand(cmd); err != nil {
+			fmt.Printf("Warning: failed to set bridge IP: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteBridge removes an OVS bridge. Its ports and interfaces are garbage
+// collected by ovsdb-server once the bridge no longer references them. The
+// QoS and queue rows of its ports are deleted in the same transaction.
+func (m *ovsdbManager) DeleteBridge(name string) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	uuid, bridge := findByName(c, "Bridge", name)
+	if uuid == "" {
+		return nil // Bridge doesn't exist, nothing to do
+	}
+
+	ops := []OVSDBOperation{{
+		Op:        "mutate",
+		Table:     "Open_vSwitch",
+		Where:     [][]interface{}{{"_uuid", "==", OVSDBUUID(rootUUID(c))}},
+		Mutations: [][]interface{}{{"bridges", "delete", OVSDBSet{OVSDBUUID(uuid)}}},
+	}}
+	for _, portUUID := range ovsdbUUIDs(bridge, "ports") {
+		ops = append(ops, portQoSDeletes(c, c.Row("Port", portUUID))...)
+	}
+
+	if err := m.commit(c, ops...); err != nil {
+		return fmt.Errorf("failed to delete bridge: %w", err)
+	}
+
+	return nil
+}
+
+// ListBridges returns all OVS bridges
+func (m *ovsdbManager) ListBridges() ([]Bridge, error) {
+	c, err := m.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := c.Rows("Bridge")
+	bridges := make([]Bridge, 0, len(rows))
+	for uuid, row := range rows {
+		bridges = append(bridges, Bridge{
+			Name:     ovsdbString(row, "name"),
+			UUID:     uuid,
+			DataPath: ovsdbString(row, "datapath_id"),
+			Ports:    portNames(c, row),
+		})
+	}
+	sort.Slice(bridges, func(i, j int) bool { return bridges[i].Name < bridges[j].Name })
+
+	return bridges, nil
+}
+
+// BridgeExists checks if a bridge exists
+func (m *ovsdbManager) BridgeExists(name string) (bool, error) {
+	c, err := m.connection()
+	if err != nil {
+		return false, err
+	}
+
+	uuid, _ := findByName(c, "Bridge", name)
+	return uuid != "", nil
+}
+
+// AddPort adds a port to a bridge
+func (m *ovsdbManager) AddPort(bridgeName, portName, portType string) error {
+	var interfaceType string
+	switch portType {
+	case "internal":
+		interfaceType = "internal"
+	case "patch":
+		return fmt.Errorf("patch ports require additional configuration, use AddPatchPort method")
+	}
+
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+	bridgeUUID, _ := findByName(c, "Bridge", bridgeName)
+	if bridgeUUID == "" {
+		return fmt.Errorf("bridge %s does not exist", bridgeName)
+	}
+
+	if err := m.commit(c, insertPortOps(bridgeUUID, portName, interfaceType, nil)...); err != nil {
+		return fmt.Errorf("failed to add port %s to bridge %s: %w", portName, bridgeName, err)
+	}
+
+	return nil
+}
+
+// AddPatchPort adds a patch port to a bridge that is connected to peerName,
+// which must be added to the other bridge the same way
+func (m *ovsdbManager) AddPatchPort(bridgeName, portName, peerName string) error {
+	if err := m.ensurePort(bridgeName, portName, "patch", OVSDBMap{"peer": peerName}); err != nil {
+		return fmt.Errorf("failed to add patch port %s to bridge %s: %w", portName, bridgeName, err)
+	}
+	return nil
+}
+
+// AddTunnelPort adds a flow based tunnel port of the given type to a bridge.
+// Every packet it carries uses key as its tunnel ID, while the remote
+// endpoint is chosen per packet by setting tun_dst in the flow actions.
+func (m *ovsdbManager) AddTunnelPort(bridgeName, portName, tunnelType string, key int) error {
+	if !ValidTunnelType(tunnelType) {
+		return fmt.Errorf("unsupported tunnel type: %s", tunnelType)
+	}
+
+	options := OVSDBMap{"remote_ip": "flow", "key": fmt.Sprintf("%d", key)}
+	if err := m.ensurePort(bridgeName, portName, tunnelType, options); err != nil {
+		return fmt.Errorf("failed to add tunnel port %s to bridge %s: %w", portName, bridgeName, err)
+	}
+	return nil
+}
+
+// DeletePort removes a port from a bridge, together with its QoS and queue
+// rows
+func (m *ovsdbManager) DeletePort(bridgeName, portName string) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	bridgeUUID, bridge := findByName(c, "Bridge", bridgeName)
+	portUUID := bridgePort(c, bridge, portName)
+	if bridgeUUID == "" || portUUID == "" {
+		return nil // Port doesn't exist, nothing to do
+	}
+
+	ops := []OVSDBOperation{{
+		Op:        "mutate",
+		Table:     "Bridge",
+		Where:     [][]interface{}{{"_uuid", "==", OVSDBUUID(bridgeUUID)}},
+		Mutations: [][]interface{}{{"ports", "delete", OVSDBSet{OVSDBUUID(portUUID)}}},
+	}}
+	ops = append(ops, portQoSDeletes(c, c.Row("Port", portUUID))...)
+
+	if err := m.commit(c, ops...); err != nil {
+		return fmt.Errorf("failed to delete port %s from bridge %s: %w", portName, bridgeName, err)
+	}
+
+	return nil
+}
+
+// ListPorts returns all ports on a bridge
+func (m *ovsdbManager) ListPorts(bridgeName string) ([]Port, error) {
+	c, err := m.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	bridgeUUID, bridge := findByName(c, "Bridge", bridgeName)
+	if bridgeUUID == "" {
+		return nil, fmt.Errorf("failed to list ports for bridge %s: bridge does not exist", bridgeName)
+	}
+
+	ports := make([]Port, 0)
+	for _, portUUID := range ovsdbUUIDs(bridge, "ports") {
+		row := c.Row("Port", portUUID)
+		if row == nil {
+			continue
+		}
+		// The bridge's own local port is not listed, like ovs-vsctl list-ports
+		if ovsdbString(row, "name") == bridgeName {
+			continue
+		}
+		ports = append(ports, portFromRow(c, portUUID, row))
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Name < ports[j].Name })
+
+	return ports, nil
+}
+
+// SetPortVLAN sets VLAN tag for a port
+func (m *ovsdbManager) SetPortVLAN(bridgeName, portName string, vlan int) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	err = m.commit(c, OVSDBOperation{
+		Op:    "update",
+		Table: "Port",
+		Where: [][]interface{}{{"name", "==", portName}},
+		Row:   OVSDBRow{"tag": vlan},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set VLAN %d for port %s: %w", vlan, portName, err)
+	}
+
+	return nil
+}
+
+// GetPortVLAN gets VLAN tag for a port
+func (m *ovsdbManager) GetPortVLAN(bridgeName, portName string) (int, error) {
+	c, err := m.connection()
+	if err != nil {
+		return 0, err
+	}
+
+	uuid, row := findByName(c, "Port", portName)
+	if uuid == "" {
+		return 0, fmt.Errorf("failed to get VLAN for port %s: port does not exist", portName)
+	}
+
+	vlan, _ := ovsdbInt(row, "tag")
+	return vlan, nil // No VLAN tag reads as 0
+}
+
+// SetPortQoS polices what a port receives and shapes what it sends with a
+// single linux-htb queue, in one transaction. Every port gets a QoS and queue
+// row of its own, and the ones it had before are deleted.
+func (m *ovsdbManager) SetPortQoS(bridgeName, portName string, qos PortQoS) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	uuid, row := findByName(c, "Port", portName)
+	if uuid == "" {
+		return fmt.Errorf("failed to set QoS for port %s: port does not exist", portName)
+	}
+
+	ops := []OVSDBOperation{{
+		Op:    "update",
+		Table: "Interface",
+		Where: [][]interface{}{{"name", "==", portName}},
+		Row: OVSDBRow{
+			"ingress_policing_rate":  qos.IngressPolicingRate,
+			"ingress_policing_burst": qos.IngressPolicingBurst,
+		},
+	}}
+	ops = append(ops, portQoSDeletes(c, row)...)
+	if qos.EgressMaxRate > 0 {
+		maxRate := OVSDBMap{"max-rate": fmt.Sprintf("%d", qos.EgressMaxRate)}
+		ops = append(ops,
+			OVSDBOperation{
+				Op:       "insert",
+				Table:    "Queue",
+				Row:      OVSDBRow{"other_config": maxRate},
+				UUIDName: "queue",
+			},
+			OVSDBOperation{
+				Op:    "insert",
+				Table: "QoS",
+				Row: OVSDBRow{
+					"type":         "linux-htb",
+					"other_config": maxRate,
+					"queues":       []interface{}{"map", []interface{}{[]interface{}{0, OVSDBNamedUUID("queue")}}},
+				},
+				UUIDName: "qos",
+			},
+			OVSDBOperation{
+				Op:    "update",
+				Table: "Port",
+				Where: [][]interface{}{{"name", "==", portName}},
+				Row:   OVSDBRow{"qos": OVSDBNamedUUID("qos")},
+			},
+		)
+	} else {
+		ops = append(ops, OVSDBOperation{
+			Op:    "update",
+			Table: "Port",
+			Where: [][]interface{}{{"name", "==", portName}},
+			Row:   OVSDBRow{"qos": OVSDBSet{}},
+		})
+	}
+
+	if err := m.commit(c, ops...); err != nil {
+		return fmt.Errorf("failed to set QoS for port %s: %w", portName, err)
+	}
+
+	return nil
+}
+
+// GetPortQoS gets the policing and egress rate of a port
+func (m *ovsdbManager) GetPortQoS(bridgeName, portName string) (PortQoS, error) {
+	var qos PortQoS
+
+	c, err := m.connection()
+	if err != nil {
+		return qos, err
+	}
+
+	uuid, row := findByName(c, "Port", portName)
+	if uuid == "" {
+		return qos, fmt.Errorf("failed to get QoS for port %s: port does not exist", portName)
+	}
+
+	if interfaces := ovsdbUUIDs(row, "interfaces"); len(interfaces) > 0 {
+		if iface := c.Row("Interface", interfaces[0]); iface != nil {
+			qos.IngressPolicingRate, _ = ovsdbInt(iface, "ingress_policing_rate")
+			qos.IngressPolicingBurst, _ = ovsdbInt(iface, "ingress_policing_burst")
+		}
+	}
+	if qosUUIDs := ovsdbUUIDs(row, "qos"); len(qosUUIDs) > 0 {
+		if record := c.Row("QoS", qosUUIDs[0]); record != nil {
+			if rate := ovsdbStringMap(record, "other_config")["max-rate"]; rate != "" {
+				if qos.EgressMaxRate, err = strconv.ParseInt(rate, 10, 64); err != nil {
+					return qos, fmt.Errorf("invalid max rate: %s", rate)
+				}
+			}
+		}
+	}
+
+	return qos, nil
+}
+
+// SetIPFIXExport creates or replaces a collector set of a bridge in one
+// transaction. The IPFIX row of a replaced set is garbage collected by
+// ovsdb-server.
+func (m *ovsdbManager) SetIPFIXExport(bridgeName string, export IPFIXExport) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	bridgeUUID, _ := findByName(c, "Bridge", bridgeName)
+	if bridgeUUID == "" {
+		return fmt.Errorf("failed to set IPFIX export for bridge %s: bridge does not exist", bridgeName)
+	}
+
+	var ops []OVSDBOperation
+	if setUUID, _ := findCollectorSet(c, bridgeUUID, export.CollectorSetID); setUUID != "" {
+		ops = append(ops, OVSDBOperation{
+			Op:    "delete",
+			Table: "Flow_Sample_Collector_Set",
+			Where: [][]interface{}{{"_uuid", "==", OVSDBUUID(setUUID)}},
+		})
+	}
+	ops = append(ops,
+		OVSDBOperation{
+			Op:    "insert",
+			Table: "IPFIX",
+			Row: OVSDBRow{
+				"targets":              export.Target,
+				"cache_active_timeout": export.CacheActiveTimeout,
+				"cache_max_flows":      export.CacheMaxFlows,
+			},
+			UUIDName: "ipfix",
+		},
+		OVSDBOperation{
+			Op:    "insert",
+			Table: "Flow_Sample_Collector_Set",
+			Row: OVSDBRow{
+				"id":     export.CollectorSetID,
+				"bridge": OVSDBUUID(bridgeUUID),
+				"ipfix":  OVSDBNamedUUID("ipfix"),
+			},
+		},
+	)
+
+	if err := m.commit(c, ops...); err != nil {
+		return fmt.Errorf("failed to set IPFIX export for bridge %s: %w", bridgeName, err)
+	}
+
+	return nil
+}
+
+// GetIPFIXExport returns a collector set of a bridge, or nil if the bridge
+// has no set with that ID
+func (m *ovsdbManager) GetIPFIXExport(bridgeName string, collectorSetID int) (*IPFIXExport, error) {
+	c, err := m.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	bridgeUUID, _ := findByName(c, "Bridge", bridgeName)
+	if bridgeUUID == "" {
+		return nil, fmt.Errorf("failed to get IPFIX export for bridge %s: bridge does not exist", bridgeName)
+	}
+
+	setUUID, set := findCollectorSet(c, bridgeUUID, collectorSetID)
+	if setUUID == "" {
+		return nil, nil
+	}
+
+	export := &IPFIXExport{CollectorSetID: collectorSetID}
+	if refs := ovsdbUUIDs(set, "ipfix"); len(refs) > 0 {
+		if ipfix := c.Row("IPFIX", refs[0]); ipfix != nil {
+			if targets := ovsdbStrings(ipfix, "targets"); len(targets) > 0 {
+				export.Target = targets[0]
+			}
+			export.CacheActiveTimeout, _ = ovsdbInt(ipfix, "cache_active_timeout")
+			export.CacheMaxFlows, _ = ovsdbInt(ipfix, "cache_max_flows")
+		}
+	}
+
+	return export, nil
+}
+
+// DeleteIPFIXExport removes a collector set from a bridge
+func (m *ovsdbManager) DeleteIPFIXExport(bridgeName string, collectorSetID int) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	bridgeUUID, _ := findByName(c, "Bridge", bridgeName)
+	setUUID, _ := findCollectorSet(c, bridgeUUID, collectorSetID)
+	if bridgeUUID == "" || setUUID == "" {
+		return nil // No collector set, nothing to do
+	}
+
+	err = m.commit(c, OVSDBOperation{
+		Op:    "delete",
+		Table: "Flow_Sample_Collector_Set",
+		Where: [][]interface{}{{"_uuid", "==", OVSDBUUID(setUUID)}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete IPFIX export for bridge %s: %w", bridgeName, err)
+	}
+
+	return nil
+}
+
+// AddMirrorPort adds a GRE or ERSPAN port that carries mirrored traffic to
+// a fixed remote endpoint, or updates an existing one
+func (m *ovsdbManager) AddMirrorPort(bridgeName, portName string, endpoint MirrorEndpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return fmt.Errorf("invalid mirror endpoint: %w", err)
+	}
+
+	if err := m.ensurePort(bridgeName, portName, endpoint.Type, OVSDBMap(endpoint.Options())); err != nil {
+		return fmt.Errorf("failed to add mirror port %s to bridge %s: %w", portName, bridgeName, err)
+	}
+	return nil
+}
+
+// SetMirror creates or replaces a mirror of a bridge in one transaction.
+// The mirror it replaces is garbage collected by ovsdb-server.
+func (m *ovsdbManager) SetMirror(bridgeName string, mirror Mirror) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	bridgeUUID, bridge := findByName(c, "Bridge", bridgeName)
+	if bridgeUUID == "" {
+		return fmt.Errorf("failed to set mirror %s on bridge %s: bridge does not exist", mirror.Name, bridgeName)
+	}
+
+	portRefs := func(portNames []string) (OVSDBSet, error) {
+		refs := make(OVSDBSet, 0, len(portNames))
+		for _, name := range portNames {
+			portUUID := bridgePort(c, bridge, name)
+			if portUUID == "" {
+				return nil, fmt.Errorf("port %s does not exist", name)
+			}
+			refs = append(refs, OVSDBUUID(portUUID))
+		}
+		return refs, nil
+	}
+	srcPorts, err := portRefs(mirror.SrcPorts)
+	if err != nil {
+		return fmt.Errorf("failed to set mirror %s on bridge %s: %w", mirror.Name, bridgeName, err)
+	}
+	dstPorts, err := portRefs(mirror.DstPorts)
+	if err != nil {
+		return fmt.Errorf("failed to set mirror %s on bridge %s: %w", mirror.Name, bridgeName, err)
+	}
+	outputPort, err := portRefs([]string{mirror.OutputPort})
+	if err != nil {
+		return fmt.Errorf("failed to set mirror %s on bridge %s: %w", mirror.Name, bridgeName, err)
+	}
+
+	row := OVSDBRow{
+		"name":            mirror.Name,
+		"select_src_port": srcPorts,
+		"select_dst_port": dstPorts,
+		"output_port":     outputPort[0],
+	}
+	// Schemas before OVS 3.4 lack the column, so it is only set when used
+	if mirror.Filter != "" {
+		row["filter"] = mirror.Filter
+	}
+
+	var ops []OVSDBOperation
+	if mirrorUUID := bridgeMirror(c, bridge, mirror.Name); mirrorUUID != "" {
+		ops = append(ops, OVSDBOperation{
+			Op:        "mutate",
+			Table:     "Bridge",
+			Where:     [][]interface{}{{"_uuid", "==", OVSDBUUID(bridgeUUID)}},
+			Mutations: [][]interface{}{{"mirrors", "delete", OVSDBSet{OVSDBUUID(mirrorUUID)}}},
+		})
+	}
+	ops = append(ops,
+		OVSDBOperation{
+			Op:       "insert",
+			Table:    "Mirror",
+			Row:      row,
+			UUIDName: "mirror",
+		},
+		OVSDBOperation{
+			Op:        "mutate",
+			Table:     "Bridge",
+			Where:     [][]interface{}{{"_uuid", "==", OVSDBUUID(bridgeUUID)}},
+			Mutations: [][]interface{}{{"mirrors", "insert", OVSDBSet{OVSDBNamedUUID("mirror")}}},
+		},
+	)
+
+	if err := m.commit(c, ops...); err != nil {
+		return fmt.Errorf("failed to set mirror %s on bridge %s: %w", mirror.Name, bridgeName, err)
+	}
+
+	return nil
+}
+
+// ListMirrors returns the mirrors of a bridge. The filter column is not
+// monitored, as schemas before OVS 3.4 lack it, so the mirrors are read
+// with a select instead of from the cache.
+func (m *ovsdbManager) ListMirrors(bridgeName string) ([]Mirror, error) {
+	c, err := m.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	bridgeUUID, bridge := findByName(c, "Bridge", bridgeName)
+	if bridgeUUID == "" {
+		return nil, fmt.Errorf("failed to list mirrors for bridge %s: bridge does not exist", bridgeName)
+	}
+
+	mirrorUUIDs := ovsdbUUIDs(bridge, "mirrors")
+	mirrors := make([]Mirror, 0, len(mirrorUUIDs))
+	if len(mirrorUUIDs) == 0 {
+		return mirrors, nil
+	}
+
+	ops := make([]OVSDBOperation, len(mirrorUUIDs))
+	for i, mirrorUUID := range mirrorUUIDs {
+		ops[i] = OVSDBOperation{
+			Op:    "select",
+			Table: "Mirror",
+			Where: [][]interface{}{{"_uuid", "==", OVSDBUUID(mirrorUUID)}},
+		}
+	}
+	results, err := c.Transact(ops...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mirrors for bridge %s: %w", bridgeName, err)
+	}
+
+	names := func(row OVSDBRow, column string) []string {
+		ports := make([]string, 0)
+		for _, portUUID := range ovsdbUUIDs(row, column) {
+			if port := c.Row("Port", portUUID); port != nil {
+				ports = append(ports, ovsdbString(port, "name"))
+			}
+		}
+		return ports
+	}
+	for _, result := range results {
+		if len(result.Rows) != 1 {
+			continue
+		}
+		row := result.Rows[0]
+		mirror := Mirror{
+			Name:     ovsdbString(row, "name"),
+			SrcPorts: names(row, "select_src_port"),
+			DstPorts: names(row, "select_dst_port"),
+			Filter:   ovsdbString(row, "filter"),
+		}
+		if output := names(row, "output_port"); len(output) > 0 {
+			mirror.OutputPort = output[0]
+		}
+		mirrors = append(mirrors, mirror)
+	}
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].Name < mirrors[j].Name })
+
+	return mirrors, nil
+}
+
+// DeleteMirror removes a mirror from a bridge
+func (m *ovsdbManager) DeleteMirror(bridgeName, mirrorName string) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	bridgeUUID, bridge := findByName(c, "Bridge", bridgeName)
+	mirrorUUID := bridgeMirror(c, bridge, mirrorName)
+	if bridgeUUID == "" || mirrorUUID == "" {
+		return nil // Mirror doesn't exist, nothing to do
+	}
+
+	err = m.commit(c, OVSDBOperation{
+		Op:        "mutate",
+		Table:     "Bridge",
+		Where:     [][]interface{}{{"_uuid", "==", OVSDBUUID(bridgeUUID)}},
+		Mutations: [][]interface{}{{"mirrors", "delete", OVSDBSet{OVSDBUUID(mirrorUUID)}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete mirror %s from bridge %s: %w", mirrorName, bridgeName, err)
+	}
+
+	return nil
+}
+
+// GetBridgeInfo returns detailed information about a bridge
+func (m *ovsdbManager) GetBridgeInfo(name string) (*BridgeInfo, error) {
+	c, err := m.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	uuid, row := findByName(c, "Bridge", name)
+	if uuid == "" {
+		return nil, fmt.Errorf("bridge %s does not exist", name)
+	}
+
+	targets := make([]string, 0)
+	for _, controllerUUID := range ovsdbUUIDs(row, "controller") {
+		if controller := c.Row("Controller", controllerUUID); controller != nil {
+			targets = append(targets, ovsdbString(controller, "target"))
+		}
+	}
+
+	ports, err := m.ListPorts(name)
+	if err != nil {
+		ports = []Port{}
+	}
+
+	return &BridgeInfo{
+		Name:       name,
+		UUID:       uuid,
+		DataPath:   ovsdbString(row, "datapath_id"),
+		Controller: strings.Join(targets, ","),
+		Ports:      ports,
+		Status:     "active",
+	}, nil
+}
+
+// SetDatapathID pins the OpenFlow datapath ID of a bridge, 16 hex digits,
+// instead of letting OVS derive it from the bridge's MAC address
+func (m *ovsdbManager) SetDatapathID(bridgeName, datapathID string) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	bridgeUUID, _ := findByName(c, "Bridge", bridgeName)
+	if bridgeUUID == "" {
+		return fmt.Errorf("failed to set datapath ID for bridge %s: bridge does not exist", bridgeName)
+	}
+
+	// Inserting into a map leaves existing keys alone, so the old ID goes first
+	err = m.commit(c, OVSDBOperation{
+		Op:    "mutate",
+		Table: "Bridge",
+		Where: [][]interface{}{{"_uuid", "==", OVSDBUUID(bridgeUUID)}},
+		Mutations: [][]interface{}{
+			{"other_config", "delete", OVSDBSet{"datapath-id"}},
+			{"other_config", "insert", OVSDBMap{"datapath-id": datapathID}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set datapath ID for bridge %s: %w", bridgeName, err)
+	}
+
+	return nil
+}
+
+// SetController sets the OpenFlow controller for a bridge, replacing any
+// controller it had
+func (m *ovsdbManager) SetController(bridgeName, controller string) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	bridgeUUID, _ := findByName(c, "Bridge", bridgeName)
+	if bridgeUUID == "" {
+		return fmt.Errorf("failed to set controller for bridge %s: bridge does not exist", bridgeName)
+	}
+
+	err = m.commit(c,
+		OVSDBOperation{
+			Op:       "insert",
+			Table:    "Controller",
+			Row:      OVSDBRow{"target": controller},
+			UUIDName: "controller",
+		},
+		OVSDBOperation{
+			Op:    "update",
+			Table: "Bridge",
+			Where: [][]interface{}{{"_uuid", "==", OVSDBUUID(bridgeUUID)}},
+			Row:   OVSDBRow{"controller": OVSDBSet{OVSDBNamedUUID("controller")}},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set controller for bridge %s: %w", bridgeName, err)
+	}
+
+	return nil
+}
+
+// Helper methods
+
+// connection returns the client, reconnecting and rebuilding the cache if
+// the previous connection broke
+func (m *ovsdbManager) connection() (*OVSDBClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.client != nil && m.client.Err() == nil {
+		return m.client, nil
+	}
+	if m.client != nil {
+		m.client.Close()
+		m.client = nil
+	}
+
+	client, err := DialOVSDB(m.address, m.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Monitor(ovsdbMonitoredColumns); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to monitor OVSDB: %w", err)
+	}
+
+	m.client = client
+	return client, nil
+}
+
+// commit runs ops in one transaction that also asks ovs-vswitchd to
+// reconfigure, and waits until it has, the way ovs-vsctl does. Ports exist
+// in the datapath and the cache reflects the change once it returns.
+func (m *ovsdbManager) commit(c *OVSDBClient, ops ...OVSDBOperation) error {
+	root := OVSDBUUID(rootUUID(c))
+	ops = append(ops,
+		OVSDBOperation{
+			Op:        "mutate",
+			Table:     "Open_vSwitch",
+			Where:     [][]interface{}{{"_uuid", "==", root}},
+			Mutations: [][]interface{}{{"next_cfg", "+=", 1}},
+		},
+		OVSDBOperation{
+			Op:      "select",
+			Table:   "Open_vSwitch",
+			Where:   [][]interface{}{{"_uuid", "==", root}},
+			Columns: []string{"next_cfg"},
+		},
+	)
+
+	results, err := c.Transact(ops...)
+	if err != nil {
+		return err
+	}
+
+	selected := results[len(results)-1].Rows
+	if len(selected) != 1 {
+		return fmt.Errorf("failed to read next_cfg")
+	}
+	target, _ := ovsdbInt(selected[0], "next_cfg")
+
+	return c.WaitUntil(func() bool {
+		current, ok := ovsdbInt(c.Row("Open_vSwitch", string(root)), "cur_cfg")
+		return ok && current >= target
+	})
+}
+
+// ensurePort creates a port with a single interface of the given type and
+// options, or updates the interface if the bridge already has the port
+func (m *ovsdbManager) ensurePort(bridgeName, portName, interfaceType string, options OVSDBMap) error {
+	c, err := m.connection()
+	if err != nil {
+		return err
+	}
+
+	bridgeUUID, bridge := findByName(c, "Bridge", bridgeName)
+	if bridgeUUID == "" {
+		return fmt.Errorf("bridge %s does not exist", bridgeName)
+	}
+
+	if bridgePort(c, bridge, portName) == "" {
+		return m.commit(c, insertPortOps(bridgeUUID, portName, interfaceType, options)...)
+	}
+
+	return m.commit(c, OVSDBOperation{
+		Op:    "update",
+		Table: "Interface",
+		Where: [][]interface{}{{"name", "==", portName}},
+		Row:   OVSDBRow{"type": interfaceType, "options": options},
+	})
+}
+
+// insertPortOps returns the operations that add a port with a single
+// interface to a bridge
+func insertPortOps(bridgeUUID, portName, interfaceType string, options OVSDBMap) []OVSDBOperation {
+	iface := OVSDBRow{"name": portName, "type": interfaceType}
+	if options != nil {
+		iface["options"] = options
+	}
+
+	return []OVSDBOperation{
+		{
+			Op:       "insert",
+			Table:    "Interface",
+			Row:      iface,
+			UUIDName: "iface",
+		},
+		{
+			Op:       "insert",
+			Table:    "Port",
+			Row:      OVSDBRow{"name": portName, "interfaces": OVSDBNamedUUID("iface")},
+			UUIDName: "port",
+		},
+		{
+			Op:        "mutate",
+			Table:     "Bridge",
+			Where:     [][]interface{}{{"_uuid", "==", OVSDBUUID(bridgeUUID)}},
+			Mutations: [][]interface{}{{"ports", "insert", OVSDBSet{OVSDBNamedUUID("port")}}},
+		},
+	}
+}
+
+// rootUUID returns the UUID of the single Open_vSwitch row
+func rootUUID(c *OVSDBClient) string {
+	for uuid := range c.Rows("Open_vSwitch") {
+		return uuid
+	}
+	return ""
+}
+
+// findByName returns the cached row of a table with the given name column
+func findByName(c *OVSDBClient, table string, name string) (string, OVSDBRow) {
+	for uuid, row := range c.Rows(table) {
+		if ovsdbString(row, "name") == name {
+			return uuid, row
+		}
+	}
+	return "", nil
+}
+
+// bridgePort returns the UUID of the named port if the bridge has it
+func bridgePort(c *OVSDBClient, bridge OVSDBRow, portName string) string {
+	for _, portUUID := range ovsdbUUIDs(bridge, "ports") {
+		if row := c.Row("Port", portUUID); row != nil && ovsdbString(row, "name") == portName {
+			return portUUID
+		}
+	}
+	return ""
+}
+
+// bridgeMirror returns the UUID of the named mirror if the bridge has it
+func bridgeMirror(c *OVSDBClient, bridge OVSDBRow, mirrorName string) string {
+	for _, mirrorUUID := range ovsdbUUIDs(bridge, "mirrors") {
+		if row := c.Row("Mirror", mirrorUUID); row != nil && ovsdbString(row, "name") == mirrorName {
+			return mirrorUUID
+		}
+	}
+	return ""
+}
+
+// portQoSDeletes deletes the QoS row of a port and its queues. QoS and Queue
+// are root tables, so ovsdb-server keeps their rows after the last reference
+// is gone.
+func portQoSDeletes(c *OVSDBClient, port OVSDBRow) []OVSDBOperation {
+	var ops []OVSDBOperation
+	for _, qosUUID := range ovsdbUUIDs(port, "qos") {
+		ops = append(ops, OVSDBOperation{
+			Op:    "delete",
+			Table: "QoS",
+			Where: [][]interface{}{{"_uuid", "==", OVSDBUUID(qosUUID)}},
+		})
+		for _, queueUUID := range ovsdbMapUUIDs(c.Row("QoS", qosUUID), "queues") {
+			ops = append(ops, OVSDBOperation{
+				Op:    "delete",
+				Table: "Queue",
+				Where: [][]interface{}{{"_uuid", "==", OVSDBUUID(queueUUID)}},
+			})
+		}
+	}
+	return ops
+}
+
+// findCollectorSet returns the cached collector set of a bridge with the
+// given ID
+func findCollectorSet(c *OVSDBClient, bridgeUUID string, collectorSetID int) (string, OVSDBRow) {
+	for uuid, row := range c.Rows("Flow_Sample_Collector_Set") {
+		id, _ := ovsdbInt(row, "id")
+		if refs := ovsdbUUIDs(row, "bridge"); id == collectorSetID && len(refs) == 1 && refs[0] == bridgeUUID {
+			return uuid, row
+		}
+	}
+	return "", nil
+}
+
+// portNames returns the names of a bridge's ports
+func portNames(c *OVSDBClient, bridge OVSDBRow) []string {
+	names := make([]string, 0)
+	for _, portUUID := range ovsdbUUIDs(bridge, "ports") {
+		if row := c.Row("Port", portUUID); row != nil {
+			names = append(names, ovsdbString(row, "name"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// portFromRow converts a cached port and its first interface
+func portFromRow(c *OVSDBClient, uuid string, row OVSDBRow) Port {
+	vlan, _ := ovsdbInt(row, "tag")
+	port := Port{
+		Name:    ovsdbString(row, "name"),
+		UUID:    uuid,
+		VLAN:    vlan,
+		Options: make(map[string]string),
+	}
+
+	if interfaces := ovsdbUUIDs(row, "interfaces"); len(interfaces) > 0 {
+		if iface := c.Row("Interface", interfaces[0]); iface != nil {
+			port.Interface = ovsdbString(iface, "name")
+			port.Type = ovsdbString(iface, "type")
+			port.Options = ovsdbStringMap(iface, "options")
+		}
+	}
+
+	return port
+}
@@ -0,0 +1,358 @@
+package ovssim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Action kinds
+const (
+	actionDrop = iota
+	actionNormal
+	actionOutput
+	actionInPort
+	actionGotoTable
+	actionResubmit
+	actionSetField
+	actionLoad
+	actionMove
+	actionPush
+	actionPop
+	actionCT
+	actionSample
+)
+
+// action is one parsed OpenFlow action
+type action struct {
+	kind  int
+	port  string
+	table int
+	value uint64
+	src   fieldRef
+	dst   fieldRef
+	ct    *ctAction
+	smp   *sampleAction
+}
+
+// fieldRef addresses a bit range of a header field, as in NXM_OF_ETH_DST[0..31]
+type fieldRef struct {
+	field field
+	start int
+	end   int
+}
+
+// ctAction holds the arguments of a ct() action
+type ctAction struct {
+	commit bool
+	zone   int
+	table  int // -1 when the action does not recirculate
+	nat    bool
+	natSrc uint64
+	natDst uint64
+}
+
+// sampleAction holds the arguments of a sample() action
+type sampleAction struct {
+	probability    int
+	collectorSetID int
+	obsDomainID    uint32
+	obsPointID     uint32
+}
+
+// parseActions parses the actions part of an ovs-ofctl flow specification
+func parseActions(spec string) ([]action, error) {
+	var actions []action
+	for _, term := range splitTopLevel(spec) {
+		a, err := parseAction(term)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, a)
+	}
+	return actions, nil
+}
+
+func parseAction(term string) (action, error) {
+	switch term {
+	case "drop":
+		return action{kind: actionDrop}, nil
+	case "NORMAL", "normal":
+		return action{kind: actionNormal}, nil
+	case "IN_PORT", "in_port":
+		return action{kind: actionInPort}, nil
+	}
+
+	if strings.HasPrefix(term, "ct(") && strings.HasSuffix(term, ")") {
+		ct, err := parseCT(term[3 : len(term)-1])
+		if err != nil {
+			return action{}, err
+		}
+		return action{kind: actionCT, ct: ct}, nil
+	}
+	if strings.HasPrefix(term, "sample(") && strings.HasSuffix(term, ")") {
+		smp, err := parseSample(term[7 : len(term)-1])
+		if err != nil {
+			return action{}, err
+		}
+		return action{kind: actionSample, smp: smp}, nil
+	}
+	if strings.HasPrefix(term, "resubmit(") && strings.HasSuffix(term, ")") {
+		portStr, tableStr, ok := strings.Cut(term[9:len(term)-1], ",")
+		if !ok {
+			return action{}, fmt.Errorf("invalid resubmit: %s", term)
+		}
+		table, err := strconv.Atoi(tableStr)
+		if err != nil || table < 0 || table > 254 {
+			return action{}, fmt.Errorf("invalid resubmit table: %s", term)
+		}
+		return action{kind: actionResubmit, port: portStr, table: table}, nil
+	}
+	if term == "ct" {
+		return action{kind: actionCT, ct: &ctAction{table: -1}}, nil
+	}
+
+	name, arg, ok := strings.Cut(term, ":")
+	if !ok {
+		return action{}, fmt.Errorf("unsupported action: %s", term)
+	}
+
+	switch name {
+	case "output":
+		if arg == "" {
+			return action{}, fmt.Errorf("output action requires a port")
+		}
+		return action{kind: actionOutput, port: arg}, nil
+
+	case "goto_table":
+		table, err := strconv.Atoi(arg)
+		if err != nil || table < 0 || table > 254 {
+			return action{}, fmt.Errorf("invalid goto_table: %s", arg)
+		}
+		return action{kind: actionGotoTable, table: table}, nil
+
+	case "mod_dl_src", "mod_dl_dst", "mod_nw_src", "mod_nw_dst", "mod_tp_src", "mod_tp_dst":
+		f := fields[strings.TrimPrefix(name, "mod_")]
+		v, err := parseFieldValue(f, arg)
+		if err != nil {
+			return action{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		return action{kind: actionSetField, dst: fullRef(f), value: v}, nil
+
+	case "set_field":
+		valueStr, target, ok := strings.Cut(arg, "->")
+		if !ok {
+			return action{}, fmt.Errorf("invalid set_field: %s", arg)
+		}
+		f, ok := fields[target]
+		if !ok {
+			return action{}, fmt.Errorf("unsupported set_field target: %s", target)
+		}
+		v, err := parseFieldValue(f, valueStr)
+		if err != nil {
+			return action{}, fmt.Errorf("invalid set_field value: %w", err)
+		}
+		return action{kind: actionSetField, dst: fullRef(f), value: v}, nil
+
+	case "load":
+		valueStr, target, ok := strings.Cut(arg, "->")
+		if !ok {
+			return action{}, fmt.Errorf("invalid load: %s", arg)
+		}
+		v, err := parseNumber(valueStr)
+		if err != nil {
+			return action{}, fmt.Errorf("invalid load value: %s", valueStr)
+		}
+		dst, err := parseFieldRef(target)
+		if err != nil {
+			return action{}, err
+		}
+		if v&^widthMask(dst.width()) != 0 {
+			return action{}, fmt.Errorf("load value %s does not fit %s", valueStr, target)
+		}
+		return action{kind: actionLoad, dst: dst, value: v}, nil
+
+	case "move":
+		srcStr, dstStr, ok := strings.Cut(arg, "->")
+		if !ok {
+			return action{}, fmt.Errorf("invalid move: %s", arg)
+		}
+		src, err := parseFieldRef(srcStr)
+		if err != nil {
+			return action{}, err
+		}
+		dst, err := parseFieldRef(dstStr)
+		if err != nil {
+			return action{}, err
+		}
+		if src.width() != dst.width() {
+			return action{}, fmt.Errorf("move source and destination widths differ: %s", arg)
+		}
+		return action{kind: actionMove, src: src, dst: dst}, nil
+
+	case "push", "pop":
+		ref, err := parseFieldRef(arg)
+		if err != nil {
+			return action{}, err
+		}
+		if name == "push" {
+			return action{kind: actionPush, src: ref}, nil
+		}
+		return action{kind: actionPop, dst: ref}, nil
+	}
+
+	return action{}, fmt.Errorf("unsupported action: %s", term)
+}
+
+// parseSample parses the arguments of a sample() action
+func parseSample(args string) (*sampleAction, error) {
+	smp := &sampleAction{}
+	for _, arg := range strings.Split(args, ",") {
+		key, valueStr, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid sample argument: %s", arg)
+		}
+		value, err := strconv.ParseUint(valueStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample %s: %s", key, valueStr)
+		}
+		switch key {
+		case "probability":
+			if value < 1 || value > 65535 {
+				return nil, fmt.Errorf("invalid sample probability: %s", valueStr)
+			}
+			smp.probability = int(value)
+		case "collector_set_id":
+			smp.collectorSetID = int(value)
+		case "obs_domain_id":
+			smp.obsDomainID = uint32(value)
+		case "obs_point_id":
+			smp.obsPointID = uint32(value)
+		default:
+			return nil, fmt.Errorf("unsupported sample argument: %s", key)
+		}
+	}
+	if smp.probability == 0 {
+		return nil, fmt.Errorf("sample requires a probability")
+	}
+	return smp, nil
+}
+
+// parseCT parses the arguments of a ct() action
+func parseCT(args string) (*ctAction, error) {
+	ct := &ctAction{table: -1}
+	for _, arg := range splitTopLevel(args) {
+		switch {
+		case arg == "":
+		case arg == "commit":
+			ct.commit = true
+		case arg == "nat":
+			ct.nat = true
+		case strings.HasPrefix(arg, "nat(") && strings.HasSuffix(arg, ")"):
+			ct.nat = true
+			kind, addr, ok := strings.Cut(arg[4:len(arg)-1], "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid ct nat: %s", arg)
+			}
+			// Only single addresses are modelled; ranges and ports are not
+			v, err := parseIP(addr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ct nat address: %w", err)
+			}
+			switch kind {
+			case "src":
+				ct.natSrc = v
+			case "dst":
+				ct.natDst = v
+			default:
+				return nil, fmt.Errorf("invalid ct nat: %s", arg)
+			}
+		case strings.HasPrefix(arg, "zone="):
+			zone, err := strconv.Atoi(arg[5:])
+			if err != nil || zone < 0 || zone > 65535 {
+				return nil, fmt.Errorf("invalid ct zone: %s", arg)
+			}
+			ct.zone = zone
+		case strings.HasPrefix(arg, "table="):
+			table, err := strconv.Atoi(arg[6:])
+			if err != nil || table < 0 || table > 254 {
+				return nil, fmt.Errorf("invalid ct table: %s", arg)
+			}
+			ct.table = table
+		default:
+			return nil, fmt.Errorf("unsupported ct argument: %s", arg)
+		}
+	}
+
+	if (ct.natSrc != 0 || ct.natDst != 0) && !ct.commit {
+		return nil, fmt.Errorf("ct nat with an address requires commit")
+	}
+	return ct, nil
+}
+
+// parseFieldRef parses NAME[], NAME[bit] or NAME[start..end]
+func parseFieldRef(s string) (fieldRef, error) {
+	open := strings.Index(s, "[")
+	if open < 0 || !strings.HasSuffix(s, "]") {
+		return fieldRef{}, fmt.Errorf("invalid field reference: %s", s)
+	}
+	f, ok := fields[s[:open]]
+	if !ok {
+		return fieldRef{}, fmt.Errorf("unsupported field: %s", s[:open])
+	}
+
+	ref := fullRef(f)
+	bits := s[open+1 : len(s)-1]
+	if bits == "" {
+		return ref, nil
+	}
+
+	startStr, endStr, isRange := strings.Cut(bits, "..")
+	if !isRange {
+		endStr = startStr
+	}
+	start, err1 := strconv.Atoi(startStr)
+	end, err2 := strconv.Atoi(endStr)
+	if err1 != nil || err2 != nil || start < 0 || end < start || end >= f.width {
+		return fieldRef{}, fmt.Errorf("invalid bit range: %s", s)
+	}
+	ref.start, ref.end = start, end
+	return ref, nil
+}
+
+func fullRef(f field) fieldRef {
+	return fieldRef{field: f, start: 0, end: f.width - 1}
+}
+
+func (r fieldRef) width() int {
+	return r.end - r.start + 1
+}
+
+func (r fieldRef) read(h *headers) uint64 {
+	return r.field.get(h) >> uint(r.start) & widthMask(r.width())
+}
+
+func (r fieldRef) write(h *headers, v uint64) {
+	mask := widthMask(r.width()) << uint(r.start)
+	r.field.set(h, r.field.get(h)&^mask|v<<uint(r.start)&mask)
+}
+
+// parseFieldValue parses a value written in the notation of its field
+func parseFieldValue(f field, s string) (uint64, error) {
+	switch f.width {
+	case 48:
+		return parseMAC(s)
+	case 32:
+		if strings.Contains(s, ".") {
+			return parseIP(s)
+		}
+	}
+	v, err := parseNumber(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %s", s)
+	}
+	if v&^widthMask(f.width) != 0 {
+		return 0, fmt.Errorf("value %s out of range", s)
+	}
+	return v, nil
+}
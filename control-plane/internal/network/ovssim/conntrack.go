@@ -0,0 +1,141 @@
+package ovssim
+
+// ICMP echo types, whose request and reply belong to one connection
+const (
+	icmpEchoReply     = 0
+	icmpEchoRequest   = 8
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// tuple identifies one direction of a connection. IPv6 addresses keep their
+// low halves in src and dst.
+type tuple struct {
+	ethType uint64
+	proto   uint64
+	srcHi   uint64
+	src     uint64
+	dstHi   uint64
+	dst     uint64
+	sport   uint64
+	dport   uint64
+}
+
+// connection is a committed conntrack entry. reply is the tuple replies
+// arrive with, which differs from the reversed original when NAT applies.
+type connection struct {
+	zone  int
+	orig  tuple
+	reply tuple
+}
+
+// conntrack models the datapath connection tracker, which is shared by
+// all bridges and partitioned into zones
+type conntrack struct {
+	connections []*connection
+}
+
+func newConntrack() *conntrack {
+	return &conntrack{}
+}
+
+func (ct *conntrack) count(zone int) int {
+	n := 0
+	for _, c := range ct.connections {
+		if c.zone == zone {
+			n++
+		}
+	}
+	return n
+}
+
+// lookup finds the connection a tuple belongs to in a zone, reporting
+// whether it travels in the reply direction
+func (ct *conntrack) lookup(zone int, t tuple) (*connection, bool) {
+	for _, c := range ct.connections {
+		if c.zone != zone {
+			continue
+		}
+		if c.orig == t {
+			return c, false
+		}
+		if c.reply == t {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// execute runs a ct() action on a packet: it sets ct_state and ct_zone,
+// commits new connections and applies NAT
+func (ct *conntrack) execute(h *headers, a *ctAction) {
+	h.ctZone = uint64(a.zone)
+	if h.ethType != ethTypeIP && h.ethType != ethTypeIPv6 {
+		h.ctState = ctTrk | ctInv
+		return
+	}
+
+	t := tupleOf(h)
+	conn, reply := ct.lookup(a.zone, t)
+
+	h.ctState = ctTrk
+	switch {
+	case conn == nil:
+		h.ctState |= ctNew
+	case reply:
+		h.ctState |= ctEst | ctRpl
+	default:
+		h.ctState |= ctEst
+	}
+
+	if a.commit && conn == nil {
+		conn = &connection{zone: a.zone, orig: t, reply: t.reverse()}
+		if a.natSrc != 0 {
+			conn.reply.dst = a.natSrc
+		}
+		if a.natDst != 0 {
+			conn.reply.src = a.natDst
+		}
+		ct.connections = append(ct.connections, conn)
+	}
+
+	// NAT only translates IPv4 addresses
+	if a.nat && conn != nil && h.ethType == ethTypeIP {
+		if reply {
+			h.ipSrc, h.ipDst = conn.orig.dst, conn.orig.src
+		} else {
+			h.ipSrc, h.ipDst = conn.reply.dst, conn.reply.src
+		}
+	}
+}
+
+func tupleOf(h *headers) tuple {
+	t := tuple{ethType: h.ethType, proto: h.nwProto, src: h.ipSrc, dst: h.ipDst, sport: h.tpSrc, dport: h.tpDst}
+	if h.ethType == ethTypeIPv6 {
+		t.srcHi, t.src, t.dstHi, t.dst = h.ipv6SrcHi, h.ipv6SrcLo, h.ipv6DstHi, h.ipv6DstLo
+	}
+	return t
+}
+
+// reverse returns the tuple of a reply to t
+func (t tuple) reverse() tuple {
+	r := tuple{
+		ethType: t.ethType, proto: t.proto,
+		srcHi: t.dstHi, src: t.dst, dstHi: t.srcHi, dst: t.src,
+		sport: t.dport, dport: t.sport,
+	}
+	switch t.proto {
+	case protoICMP:
+		// ICMP tuples hold the type and code, and only echo has a reply
+		r.sport, r.dport = t.sport, t.dport
+		if t.sport == icmpEchoRequest {
+			r.sport = icmpEchoReply
+		}
+	case protoICMPv6:
+		r.sport, r.dport = t.sport, t.dport
+		if t.sport == icmpv6EchoRequest {
+			r.sport = icmpv6EchoReply
+		}
+	}
+	return r
+}
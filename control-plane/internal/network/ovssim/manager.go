@@ -0,0 +1,978 @@
+package ovssim
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/network"
+)
+
+// Port types the manager distinguishes
+const (
+	portTypeInternal = "internal"
+	portTypePatch    = "patch"
+)
+
+// ofportLocal is the OpenFlow port number of a bridge's local port
+const ofportLocal = 65534
+
+var bridgeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+var datapathIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{16}$`)
+
+// Manager is an in-memory network.OVSManager. It keeps bridges, ports,
+// VLAN tags, QoS and flow tables the way ovs-vswitchd would, and can push a
+// packet through the resulting pipeline with Simulate.
+type Manager struct {
+	mu        sync.Mutex
+	bridges   map[string]*bridge
+	conntrack *conntrack
+	nextID    int
+}
+
+type bridge struct {
+	name       string
+	uuid       string
+	datapathID string
+	controller string
+	created    time.Time
+	ports      map[string]*port
+	nextOFPort int
+	flows      []*flowEntry
+	flowSeq    int
+	macs       map[uint64]string
+	exports    map[int]network.IPFIXExport
+	mirrors    map[string]*mirror
+}
+
+type port struct {
+	name    string
+	uuid    string
+	typ     string
+	ofport  int
+	vlan    int
+	options map[string]string
+	mac     uint64
+	qos     network.PortQoS
+}
+
+// mirror is an OVS mirror. Ports are referred to by name, and a deleted
+// port drops out of the mirrors that selected it, as OVSDB weak references
+// do.
+type mirror struct {
+	name      string
+	srcPorts  []string
+	dstPorts  []string
+	filter    *match
+	filterStr string
+	output    string
+}
+
+type flowEntry struct {
+	cookie   uint64
+	table    int
+	priority int
+	match    *match
+	matchStr string
+	actions  []action
+	actStr   string
+	seq      int
+	packets  int64
+}
+
+// Ensure Manager satisfies the OVSManager interface
+var _ network.OVSManager = (*Manager)(nil)
+
+// NewManager creates an empty in-memory OVS manager
+func NewManager() *Manager {
+	return &Manager{
+		bridges:   make(map[string]*bridge),
+		conntrack: newConntrack(),
+	}
+}
+
+// CreateBridge creates a bridge with its local port
+func (m *Manager) CreateBridge(name string, cidr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := validateBridgeName(name); err != nil {
+		return fmt.Errorf("invalid bridge name: %w", err)
+	}
+	if _, exists := m.bridges[name]; exists {
+		return fmt.Errorf("bridge %s already exists", name)
+	}
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return fmt.Errorf("invalid CIDR: %w", err)
+	}
+	if m.findPort(name) != nil {
+		return fmt.Errorf("port %s already exists", name)
+	}
+
+	br := &bridge{
+		name:       name,
+		uuid:       m.newUUID(),
+		created:    time.Now(),
+		ports:      make(map[string]*port),
+		nextOFPort: 1,
+		macs:       make(map[uint64]string),
+	}
+	br.ports[name] = &port{name: name, uuid: m.newUUID(), typ: portTypeInternal, ofport: ofportLocal}
+	m.bridges[name] = br
+
+	return nil
+}
+
+// DeleteBridge removes a bridge with its ports and flows
+func (m *Manager) DeleteBridge(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.bridges, name)
+	return nil
+}
+
+// ListBridges returns all bridges ordered by name
+func (m *Manager) ListBridges() ([]network.Bridge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.bridges))
+	for name := range m.bridges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bridges := make([]network.Bridge, 0, len(names))
+	for _, name := range names {
+		br := m.bridges[name]
+		ports := make([]string, 0, len(br.ports))
+		for _, p := range br.sortedPorts() {
+			ports = append(ports, p.name)
+		}
+		bridges = append(bridges, network.Bridge{
+			Name:     br.name,
+			UUID:     br.uuid,
+			DataPath: br.datapathID,
+			Ports:    ports,
+			Created:  br.created,
+		})
+	}
+
+	return bridges, nil
+}
+
+// BridgeExists checks if a bridge exists
+func (m *Manager) BridgeExists(name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, exists := m.bridges[name]
+	return exists, nil
+}
+
+// AddPort adds a port to a bridge. Like ovs-vsctl add-port it fails when a
+// port of the same name exists anywhere on the switch.
+func (m *Manager) AddPort(bridgeName, portName, portType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return err
+	}
+	if portType == portTypePatch {
+		return fmt.Errorf("patch ports require additional configuration, use AddPatchPort method")
+	}
+	if m.findPort(portName) != nil {
+		return fmt.Errorf("failed to add port %s to bridge %s: port already exists", portName, bridgeName)
+	}
+
+	typ := ""
+	if portType == portTypeInternal {
+		typ = portTypeInternal
+	}
+	br.addPort(&port{name: portName, uuid: m.newUUID(), typ: typ})
+
+	return nil
+}
+
+// AddPatchPort adds a patch port connected to peerName, or updates the
+// peer of an existing one
+func (m *Manager) AddPatchPort(bridgeName, portName, peerName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ensurePort(bridgeName, portName, portTypePatch, map[string]string{"peer": peerName})
+}
+
+// AddTunnelPort adds a flow based tunnel port, or updates an existing one
+func (m *Manager) AddTunnelPort(bridgeName, portName, tunnelType string, key int) error {
+	if !network.ValidTunnelType(tunnelType) {
+		return fmt.Errorf("unsupported tunnel type: %s", tunnelType)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ensurePort(bridgeName, portName, tunnelType, map[string]string{
+		"remote_ip": "flow",
+		"key":       strconv.Itoa(key),
+	})
+}
+
+// DeletePort removes a port from a bridge. A missing port is not an error.
+func (m *Manager) DeletePort(bridgeName, portName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return err
+	}
+	if portName == bridgeName {
+		return fmt.Errorf("cannot delete local port of bridge %s", bridgeName)
+	}
+
+	delete(br.ports, portName)
+	for mac, name := range br.macs {
+		if name == portName {
+			delete(br.macs, mac)
+		}
+	}
+	for _, mr := range br.mirrors {
+		mr.srcPorts = removePort(mr.srcPorts, portName)
+		mr.dstPorts = removePort(mr.dstPorts, portName)
+		if mr.output == portName {
+			mr.output = ""
+		}
+	}
+	return nil
+}
+
+// ListPorts returns the ports of a bridge, leaving out its local port
+func (m *Manager) ListPorts(bridgeName string) ([]network.Port, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ports for bridge %s: %w", bridgeName, err)
+	}
+
+	ports := make([]network.Port, 0, len(br.ports))
+	for _, p := range br.sortedPorts() {
+		ports = append(ports, p.toPort())
+	}
+	return ports, nil
+}
+
+// AddFlow adds a flow, replacing any flow with the same table, priority
+// and match. The flow is rejected if it does not parse, refers to a port
+// the bridge does not have or jumps to an earlier table, as ovs-ofctl
+// would reject it.
+func (m *Manager) AddFlow(bridgeName string, flow network.Flow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to add flow to bridge %s: %w", bridgeName, err)
+	}
+
+	entry, err := br.compileFlow(flow)
+	if err != nil {
+		return fmt.Errorf("failed to add flow to bridge %s: %w", bridgeName, err)
+	}
+
+	br.addFlow(entry)
+	return nil
+}
+
+// DeleteFlow removes flows the way non-strict ovs-ofctl del-flows does:
+// every flow whose match is at least as specific as the given one goes,
+// whatever its priority. Table 0 selects flows in all tables, since the
+// table is left out of the deletion spec in that case.
+func (m *Manager) DeleteFlow(bridgeName string, flow network.Flow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to delete flow from bridge %s: %w", bridgeName, err)
+	}
+
+	spec, err := parseMatch(flow.Match)
+	if err != nil {
+		return fmt.Errorf("failed to delete flow from bridge %s: %w", bridgeName, err)
+	}
+
+	kept := br.flows[:0]
+	for _, entry := range br.flows {
+		if (flow.Table == 0 || entry.table == flow.Table) && spec.covers(entry.match) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	br.flows = kept
+
+	return nil
+}
+
+// ReplaceFlows replaces the flows selected by cookie/mask with flows the
+// way an OpenFlow bundle does: every flow is validated first, and the bridge
+// is left untouched if any of them is rejected.
+func (m *Manager) ReplaceFlows(bridgeName, cookie, mask string, flows []network.Flow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to replace flows on bridge %s: %w", bridgeName, err)
+	}
+
+	want, wantMask, err := parseCookieMask(cookie, mask)
+	if err != nil {
+		return fmt.Errorf("failed to replace flows on bridge %s: %w", bridgeName, err)
+	}
+
+	entries := make([]*flowEntry, 0, len(flows))
+	for _, flow := range flows {
+		if flow.Cookie == "" {
+			flow.Cookie = cookie
+		}
+		entry, err := br.compileFlow(flow)
+		if err != nil {
+			return fmt.Errorf("failed to replace flows on bridge %s: %w", bridgeName, err)
+		}
+		if entry.cookie&wantMask != want&wantMask {
+			return fmt.Errorf("failed to replace flows on bridge %s: flow cookie %s is outside %s/%s", bridgeName, flow.Cookie, cookie, mask)
+		}
+		entries = append(entries, entry)
+	}
+
+	br.deleteByCookie(want, wantMask)
+	for _, entry := range entries {
+		br.addFlow(entry)
+	}
+
+	return nil
+}
+
+// DeleteFlowsByCookie removes every flow of a bridge selected by cookie/mask
+func (m *Manager) DeleteFlowsByCookie(bridgeName, cookie, mask string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to delete flows from bridge %s: %w", bridgeName, err)
+	}
+
+	want, wantMask, err := parseCookieMask(cookie, mask)
+	if err != nil {
+		return fmt.Errorf("failed to delete flows from bridge %s: %w", bridgeName, err)
+	}
+	br.deleteByCookie(want, wantMask)
+
+	return nil
+}
+
+// ListFlows returns the flows of a bridge by table and descending priority
+func (m *Manager) ListFlows(bridgeName string) ([]network.Flow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list flows for bridge %s: %w", bridgeName, err)
+	}
+
+	flows := make([]network.Flow, 0, len(br.flows))
+	for _, entry := range br.flows {
+		flows = append(flows, network.Flow{
+			Cookie:      fmt.Sprintf("0x%x", entry.cookie),
+			Table:       entry.table,
+			Priority:    entry.priority,
+			Match:       entry.matchStr,
+			Actions:     entry.actStr,
+			PacketCount: entry.packets,
+		})
+	}
+	return flows, nil
+}
+
+// CountConnections returns the number of simulated conntrack entries in a zone
+func (m *Manager) CountConnections(zone int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.conntrack.count(zone), nil
+}
+
+// SetPortVLAN sets the VLAN tag of a port, 0 removing it
+func (m *Manager) SetPortVLAN(bridgeName, portName string, vlan int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if vlan < 0 || vlan > 4095 {
+		return fmt.Errorf("failed to set VLAN %d for port %s: invalid VLAN", vlan, portName)
+	}
+	p := m.findPort(portName)
+	if p == nil {
+		return fmt.Errorf("failed to set VLAN %d for port %s: no port named %s", vlan, portName, portName)
+	}
+
+	p.vlan = vlan
+	return nil
+}
+
+// GetPortVLAN gets the VLAN tag of a port
+func (m *Manager) GetPortVLAN(bridgeName, portName string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.findPort(portName)
+	if p == nil {
+		return 0, fmt.Errorf("failed to get VLAN for port %s: no port named %s", portName, portName)
+	}
+	return p.vlan, nil
+}
+
+// SetPortQoS sets the policing and egress rate of a port. Packets are not
+// rate limited by Simulate.
+func (m *Manager) SetPortQoS(bridgeName, portName string, qos network.PortQoS) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if qos.IngressPolicingRate < 0 || qos.IngressPolicingBurst < 0 || qos.EgressMaxRate < 0 {
+		return fmt.Errorf("failed to set QoS for port %s: negative rate", portName)
+	}
+	p := m.findPort(portName)
+	if p == nil {
+		return fmt.Errorf("failed to set QoS for port %s: no port named %s", portName, portName)
+	}
+
+	p.qos = qos
+	return nil
+}
+
+// GetPortQoS gets the policing and egress rate of a port
+func (m *Manager) GetPortQoS(bridgeName, portName string) (network.PortQoS, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.findPort(portName)
+	if p == nil {
+		return network.PortQoS{}, fmt.Errorf("failed to get QoS for port %s: no port named %s", portName, portName)
+	}
+	return p.qos, nil
+}
+
+// SetIPFIXExport creates or replaces a collector set of a bridge. Nothing
+// is exported; Simulate reports the samples a packet was taken in.
+func (m *Manager) SetIPFIXExport(bridgeName string, export network.IPFIXExport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return err
+	}
+	if br.exports == nil {
+		br.exports = make(map[int]network.IPFIXExport)
+	}
+	br.exports[export.CollectorSetID] = export
+	return nil
+}
+
+// GetIPFIXExport returns a collector set of a bridge, or nil if the bridge
+// has no set with that ID
+func (m *Manager) GetIPFIXExport(bridgeName string, collectorSetID int) (*network.IPFIXExport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return nil, err
+	}
+	export, ok := br.exports[collectorSetID]
+	if !ok {
+		return nil, nil
+	}
+	return &export, nil
+}
+
+// DeleteIPFIXExport removes a collector set from a bridge
+func (m *Manager) DeleteIPFIXExport(bridgeName string, collectorSetID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return err
+	}
+	delete(br.exports, collectorSetID)
+	return nil
+}
+
+// AddMirrorPort adds a GRE or ERSPAN port to a fixed remote endpoint, or
+// updates an existing one
+func (m *Manager) AddMirrorPort(bridgeName, portName string, endpoint network.MirrorEndpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return fmt.Errorf("invalid mirror endpoint: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ensurePort(bridgeName, portName, endpoint.Type, endpoint.Options())
+}
+
+// SetMirror creates or replaces a mirror of a bridge. Simulate reports the
+// copies of a packet its mirrors took.
+func (m *Manager) SetMirror(bridgeName string, want network.Mirror) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return err
+	}
+	for _, name := range append(append([]string{want.OutputPort}, want.SrcPorts...), want.DstPorts...) {
+		if _, ok := br.ports[name]; !ok {
+			return fmt.Errorf("failed to set mirror %s on bridge %s: port %s does not exist", want.Name, bridgeName, name)
+		}
+	}
+	filter, err := parseMatch(want.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to set mirror %s on bridge %s: invalid filter: %w", want.Name, bridgeName, err)
+	}
+
+	if br.mirrors == nil {
+		br.mirrors = make(map[string]*mirror)
+	}
+	br.mirrors[want.Name] = &mirror{
+		name:      want.Name,
+		srcPorts:  append([]string{}, want.SrcPorts...),
+		dstPorts:  append([]string{}, want.DstPorts...),
+		filter:    filter,
+		filterStr: want.Filter,
+		output:    want.OutputPort,
+	}
+	return nil
+}
+
+// ListMirrors returns the mirrors of a bridge ordered by name
+func (m *Manager) ListMirrors(bridgeName string) ([]network.Mirror, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mirrors for bridge %s: %w", bridgeName, err)
+	}
+
+	mirrors := make([]network.Mirror, 0, len(br.mirrors))
+	for _, mr := range br.sortedMirrors() {
+		mirrors = append(mirrors, mr.toMirror())
+	}
+	return mirrors, nil
+}
+
+// DeleteMirror removes a mirror from a bridge. A missing mirror is not an
+// error.
+func (m *Manager) DeleteMirror(bridgeName, mirrorName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return err
+	}
+	delete(br.mirrors, mirrorName)
+	return nil
+}
+
+// GetBridgeInfo returns detailed information about a bridge
+func (m *Manager) GetBridgeInfo(name string) (*network.BridgeInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make([]network.Port, 0, len(br.ports))
+	for _, p := range br.sortedPorts() {
+		ports = append(ports, p.toPort())
+	}
+
+	return &network.BridgeInfo{
+		Name:       br.name,
+		UUID:       br.uuid,
+		DataPath:   br.datapathID,
+		Controller: br.controller,
+		Ports:      ports,
+		Options:    make(map[string]string),
+		Status:     "active",
+	}, nil
+}
+
+// SetDatapathID pins the OpenFlow datapath ID of a bridge. Like ovs-vswitchd
+// it only accepts exactly 16 hex digits.
+func (m *Manager) SetDatapathID(bridgeName, datapathID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to set datapath ID for bridge %s: %w", bridgeName, err)
+	}
+	if !datapathIDPattern.MatchString(datapathID) {
+		return fmt.Errorf("failed to set datapath ID for bridge %s: invalid datapath ID %s", bridgeName, datapathID)
+	}
+
+	br.datapathID = strings.ToLower(datapathID)
+	return nil
+}
+
+// SetController sets the OpenFlow controller of a bridge
+func (m *Manager) SetController(bridgeName, controller string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to set controller for bridge %s: %w", bridgeName, err)
+	}
+
+	br.controller = controller
+	return nil
+}
+
+// AttachMAC records the MAC address of the machine behind a port, so that
+// NORMAL forwarding can deliver frames for it without flooding
+func (m *Manager) AttachMAC(bridgeName, portName, mac string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return err
+	}
+	p, ok := br.ports[portName]
+	if !ok {
+		return fmt.Errorf("bridge %s has no port %s", bridgeName, portName)
+	}
+	v, err := parseMAC(mac)
+	if err != nil {
+		return err
+	}
+
+	p.mac = v
+	return nil
+}
+
+// FlushConntrack forgets every simulated connection
+func (m *Manager) FlushConntrack() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.conntrack = newConntrack()
+}
+
+// Helper methods
+
+func (m *Manager) bridge(name string) (*bridge, error) {
+	br, ok := m.bridges[name]
+	if !ok {
+		return nil, fmt.Errorf("bridge %s does not exist", name)
+	}
+	return br, nil
+}
+
+// findPort looks a port up by name across all bridges, as port names are
+// unique switch-wide
+func (m *Manager) findPort(name string) *port {
+	for _, br := range m.bridges {
+		if p, ok := br.ports[name]; ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// findPeer returns the bridge and patch port at the other end of a patch port
+func (m *Manager) findPeer(p *port) (*bridge, *port) {
+	peerName := p.options["peer"]
+	for _, br := range m.bridges {
+		if peer, ok := br.ports[peerName]; ok && peer.typ == portTypePatch && peer.options["peer"] == p.name {
+			return br, peer
+		}
+	}
+	return nil, nil
+}
+
+// ensurePort creates a port with the given type and options, or updates
+// them on an existing port, matching ovs-vsctl --may-exist add-port
+func (m *Manager) ensurePort(bridgeName, portName, typ string, options map[string]string) error {
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return err
+	}
+
+	if p := m.findPort(portName); p != nil {
+		if _, ok := br.ports[portName]; !ok {
+			return fmt.Errorf("port %s already exists on another bridge", portName)
+		}
+		p.typ, p.options = typ, options
+		return nil
+	}
+
+	br.addPort(&port{name: portName, uuid: m.newUUID(), typ: typ, options: options})
+	return nil
+}
+
+func (m *Manager) newUUID() string {
+	m.nextID++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012x", m.nextID)
+}
+
+func (br *bridge) addPort(p *port) {
+	p.ofport = br.nextOFPort
+	br.nextOFPort++
+	br.ports[p.name] = p
+}
+
+// resolvePort finds a port by name or OpenFlow port number
+func (br *bridge) resolvePort(ref string) *port {
+	if p, ok := br.ports[ref]; ok {
+		return p
+	}
+	if ref == "LOCAL" {
+		return br.ports[br.name]
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		for _, p := range br.ports {
+			if p.ofport == n {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// sortedPorts returns the bridge's ports by OpenFlow port number, without
+// its local port
+func (br *bridge) sortedPorts() []*port {
+	ports := make([]*port, 0, len(br.ports))
+	for _, p := range br.ports {
+		if p.ofport != ofportLocal {
+			ports = append(ports, p)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].ofport < ports[j].ofport })
+	return ports
+}
+
+// compileFlow parses and validates a flow against the bridge
+func (br *bridge) compileFlow(flow network.Flow) (*flowEntry, error) {
+	if flow.Table < 0 || flow.Table > 254 {
+		return nil, fmt.Errorf("invalid table %d", flow.Table)
+	}
+	priority := flow.Priority
+	if priority == 0 {
+		priority = network.DefaultFlowPriority
+	}
+
+	mt, err := parseMatch(flow.Match)
+	if err != nil {
+		return nil, err
+	}
+	if mt.inPort != "" && br.resolvePort(mt.inPort) == nil {
+		return nil, fmt.Errorf("%s: unknown port", mt.inPort)
+	}
+
+	actions, err := parseActions(flow.Actions)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range actions {
+		switch a.kind {
+		case actionOutput, actionResubmit:
+			if a.port != "" && br.resolvePort(a.port) == nil {
+				return nil, fmt.Errorf("%s: unknown port", a.port)
+			}
+		case actionGotoTable:
+			if a.table <= flow.Table {
+				return nil, fmt.Errorf("goto_table:%d must jump to a later table than %d", a.table, flow.Table)
+			}
+		}
+	}
+
+	var cookie uint64
+	if flow.Cookie != "" {
+		if cookie, err = network.ParseCookie(flow.Cookie); err != nil {
+			return nil, err
+		}
+	}
+
+	return &flowEntry{
+		cookie:   cookie,
+		table:    flow.Table,
+		priority: priority,
+		match:    mt,
+		matchStr: flow.Match,
+		actions:  actions,
+		actStr:   flow.Actions,
+	}, nil
+}
+
+// addFlow adds a compiled flow, overwriting the flow with the same table,
+// priority and match the way ovs-ofctl add-flow does
+func (br *bridge) addFlow(entry *flowEntry) {
+	key := entry.match.key()
+	for i, existing := range br.flows {
+		if existing.table == entry.table && existing.priority == entry.priority && existing.match.key() == key {
+			entry.seq, entry.packets = existing.seq, existing.packets
+			br.flows[i] = entry
+			return
+		}
+	}
+
+	br.flowSeq++
+	entry.seq = br.flowSeq
+	br.flows = append(br.flows, entry)
+	br.sortFlows()
+}
+
+// deleteByCookie removes the flows whose cookie matches want under mask
+func (br *bridge) deleteByCookie(want, mask uint64) {
+	kept := br.flows[:0]
+	for _, entry := range br.flows {
+		if entry.cookie&mask == want&mask {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	br.flows = kept
+}
+
+// parseCookieMask parses the cookie and mask of a cookie selection
+func parseCookieMask(cookie, mask string) (uint64, uint64, error) {
+	want, err := network.ParseCookie(cookie)
+	if err != nil {
+		return 0, 0, err
+	}
+	wantMask, err := network.ParseCookie(mask)
+	if err != nil {
+		return 0, 0, err
+	}
+	return want, wantMask, nil
+}
+
+// sortFlows orders flows by table, then by descending priority, then by age
+func (br *bridge) sortFlows() {
+	sort.SliceStable(br.flows, func(i, j int) bool {
+		a, b := br.flows[i], br.flows[j]
+		if a.table != b.table {
+			return a.table < b.table
+		}
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		return a.seq < b.seq
+	})
+}
+
+// lookup returns the highest priority flow of a table matching the packet
+func (br *bridge) lookup(table int, h *headers) *flowEntry {
+	ofport := 0
+	if p, ok := br.ports[h.inPort]; ok {
+		ofport = p.ofport
+	}
+	for _, entry := range br.flows {
+		if entry.table == table && entry.match.matches(h, ofport) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// sortedMirrors returns the mirrors of the bridge ordered by name
+func (br *bridge) sortedMirrors() []*mirror {
+	mirrors := make([]*mirror, 0, len(br.mirrors))
+	for _, mr := range br.mirrors {
+		mirrors = append(mirrors, mr)
+	}
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].name < mirrors[j].name })
+	return mirrors
+}
+
+// isMirrorOutput reports whether a port is the output port of a mirror,
+// which carries mirrored traffic only
+func (br *bridge) isMirrorOutput(name string) bool {
+	for _, mr := range br.mirrors {
+		if mr.output == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (mr *mirror) toMirror() network.Mirror {
+	return network.Mirror{
+		Name:       mr.name,
+		SrcPorts:   append([]string{}, mr.srcPorts...),
+		DstPorts:   append([]string{}, mr.dstPorts...),
+		Filter:     mr.filterStr,
+		OutputPort: mr.output,
+	}
+}
+
+// removePort returns ports without name
+func removePort(ports []string, name string) []string {
+	kept := make([]string, 0, len(ports))
+	for _, p := range ports {
+		if p != name {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func (p *port) toPort() network.Port {
+	options := make(map[string]string, len(p.options))
+	for k, v := range p.options {
+		options[k] = v
+	}
+	return network.Port{
+		Name:      p.name,
+		UUID:      p.uuid,
+		Interface: p.name,
+		VLAN:      p.vlan,
+		Type:      p.typ,
+		Options:   options,
+	}
+}
+
+func validateBridgeName(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("bridge name cannot be empty")
+	}
+	if len(name) > 15 {
+		return fmt.Errorf("bridge name cannot exceed 15 characters")
+	}
+	if !bridgeNamePattern.MatchString(name) {
+		return fmt.Errorf("bridge name can only contain alphanumeric characters, hyphens, and underscores")
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package ovssim
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// matchField is one field=value/mask term of a flow match
+type matchField struct {
+	name  string
+	value uint64
+	mask  uint64
+}
+
+// match is a parsed flow match. The protocol keywords are expanded into the
+// dl_type and nw_proto terms they stand for, the way ovs-ofctl expands them.
+type match struct {
+	inPort string
+	terms  map[string]matchField
+}
+
+var protocolKeywords = map[string][]matchField{
+	"ip":    {{name: "dl_type", value: ethTypeIP}},
+	"arp":   {{name: "dl_type", value: ethTypeARP}},
+	"icmp":  {{name: "dl_type", value: ethTypeIP}, {name: "nw_proto", value: protoICMP}},
+	"tcp":   {{name: "dl_type", value: ethTypeIP}, {name: "nw_proto", value: protoTCP}},
+	"udp":   {{name: "dl_type", value: ethTypeIP}, {name: "nw_proto", value: protoUDP}},
+	"ipv6":  {{name: "dl_type", value: ethTypeIPv6}},
+	"icmp6": {{name: "dl_type", value: ethTypeIPv6}, {name: "nw_proto", value: protoICMPv6}},
+	"tcp6":  {{name: "dl_type", value: ethTypeIPv6}, {name: "nw_proto", value: protoTCP}},
+	"udp6":  {{name: "dl_type", value: ethTypeIPv6}, {name: "nw_proto", value: protoUDP}},
+}
+
+// parseMatch parses the match part of an ovs-ofctl flow specification
+func parseMatch(spec string) (*match, error) {
+	m := &match{terms: make(map[string]matchField)}
+
+	for _, term := range splitTopLevel(spec) {
+		if term == "" {
+			continue
+		}
+
+		if terms, ok := protocolKeywords[term]; ok {
+			for _, t := range terms {
+				t.mask = widthMask(fields[t.name].width)
+				if err := m.add(t); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		name, value, ok := strings.Cut(term, "=")
+		if !ok {
+			return nil, fmt.Errorf("unsupported match keyword: %s", term)
+		}
+
+		if name == "in_port" {
+			m.inPort = value
+			continue
+		}
+
+		if name == "ipv6_src" || name == "ipv6_dst" {
+			terms, err := parseIPv6MatchField(name, value)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range terms {
+				if err := m.add(t); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		t, err := parseMatchField(name, value)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.add(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *match) add(t matchField) error {
+	if existing, ok := m.terms[t.name]; ok && existing != t {
+		return fmt.Errorf("conflicting values for match field %s", t.name)
+	}
+	m.terms[t.name] = t
+	return nil
+}
+
+// parseMatchField parses a single name=value match term
+func parseMatchField(name, value string) (matchField, error) {
+	f, ok := fields[name]
+	if !ok || strings.HasPrefix(name, "NXM_") || strings.HasPrefix(name, "ipv6_") {
+		return matchField{}, fmt.Errorf("unsupported match field: %s", name)
+	}
+	full := widthMask(f.width)
+
+	switch name {
+	case "ct_state":
+		return parseCTState(value)
+
+	case "nw_src", "nw_dst", "ip_src", "ip_dst", "arp_spa", "arp_tpa", "tun_src", "tun_dst":
+		addr, prefix, hasPrefix := strings.Cut(value, "/")
+		v, err := parseIP(addr)
+		if err != nil {
+			return matchField{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		mask := full
+		if hasPrefix {
+			if mask, err = parseIPMask(prefix); err != nil {
+				return matchField{}, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+		return matchField{name: f.name, value: v & mask, mask: mask}, nil
+
+	case "dl_src", "dl_dst", "eth_src", "eth_dst", "arp_sha", "arp_tha":
+		addr, maskStr, hasMask := strings.Cut(value, "/")
+		v, err := parseMAC(addr)
+		if err != nil {
+			return matchField{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		mask := full
+		if hasMask {
+			if mask, err = parseMAC(maskStr); err != nil {
+				return matchField{}, fmt.Errorf("invalid %s mask: %w", name, err)
+			}
+		}
+		return matchField{name: f.name, value: v & mask, mask: mask}, nil
+
+	default:
+		valueStr, maskStr, hasMask := strings.Cut(value, "/")
+		v, err := parseNumber(valueStr)
+		if err != nil {
+			return matchField{}, fmt.Errorf("invalid %s: %s", name, value)
+		}
+		mask := full
+		if hasMask {
+			if mask, err = parseNumber(maskStr); err != nil {
+				return matchField{}, fmt.Errorf("invalid %s mask: %s", name, maskStr)
+			}
+		}
+		if v&^full != 0 {
+			return matchField{}, fmt.Errorf("%s value %s out of range", name, valueStr)
+		}
+		return matchField{name: f.name, value: v & mask, mask: mask}, nil
+	}
+}
+
+// parseIPv6MatchField parses an ipv6_src or ipv6_dst term into terms on the
+// halves of the address. A half the prefix does not reach is left out.
+func parseIPv6MatchField(name, value string) ([]matchField, error) {
+	addr, prefixStr, hasPrefix := strings.Cut(value, "/")
+	hi, lo, err := parseIPv6(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	prefix := 128
+	if hasPrefix {
+		if prefix, err = strconv.Atoi(prefixStr); err != nil || prefix < 0 || prefix > 128 {
+			return nil, fmt.Errorf("invalid %s: invalid prefix length: %s", name, prefixStr)
+		}
+	}
+
+	hiMask := ^widthMask(64 - min(prefix, 64))
+	loMask := ^widthMask(128 - max(prefix, 64))
+	terms := make([]matchField, 0, 2)
+	if hiMask != 0 {
+		terms = append(terms, matchField{name: name + "_hi", value: hi & hiMask, mask: hiMask})
+	}
+	if loMask != 0 {
+		terms = append(terms, matchField{name: name + "_lo", value: lo & loMask, mask: loMask})
+	}
+	return terms, nil
+}
+
+// parseIPMask parses either a prefix length or a dotted netmask
+func parseIPMask(s string) (uint64, error) {
+	if strings.Contains(s, ".") {
+		return parseIP(s)
+	}
+	prefix, err := strconv.Atoi(s)
+	if err != nil || prefix < 0 || prefix > 32 {
+		return 0, fmt.Errorf("invalid prefix length: %s", s)
+	}
+	return widthMask(32) &^ widthMask(32-prefix), nil
+}
+
+// parseCTState parses ct_state flags such as +trk+est or -new
+func parseCTState(value string) (matchField, error) {
+	t := matchField{name: "ct_state"}
+	for value != "" {
+		sign := value[0]
+		if sign != '+' && sign != '-' {
+			return matchField{}, fmt.Errorf("invalid ct_state: %s", value)
+		}
+		value = value[1:]
+
+		end := strings.IndexAny(value, "+-")
+		if end < 0 {
+			end = len(value)
+		}
+		bit, ok := ctStateBits[value[:end]]
+		if !ok {
+			return matchField{}, fmt.Errorf("unknown ct_state flag: %s", value[:end])
+		}
+		value = value[end:]
+
+		t.mask |= bit
+		if sign == '+' {
+			t.value |= bit
+		}
+	}
+	return t, nil
+}
+
+// matches reports whether packet headers satisfy the match. ofport is
+// the OpenFlow port number of the packet's ingress port.
+func (m *match) matches(h *headers, ofport int) bool {
+	if m.inPort != "" && m.inPort != h.inPort && m.inPort != strconv.Itoa(ofport) {
+		return false
+	}
+	for _, t := range m.terms {
+		if fields[t.name].get(h)&t.mask != t.value {
+			return false
+		}
+	}
+	return true
+}
+
+// covers reports whether every term of m is at least as specific in other.
+// This is how non-strict ovs-ofctl del-flows selects the flows it removes.
+func (m *match) covers(other *match) bool {
+	if m.inPort != "" && m.inPort != other.inPort {
+		return false
+	}
+	for name, t := range m.terms {
+		o, ok := other.terms[name]
+		if !ok || o.mask&t.mask != t.mask || o.value&t.mask != t.value {
+			return false
+		}
+	}
+	return true
+}
+
+// key returns a canonical form of the match used to identify flows
+func (m *match) key() string {
+	parts := make([]string, 0, len(m.terms)+1)
+	if m.inPort != "" {
+		parts = append(parts, "in_port="+m.inPort)
+	}
+	for _, t := range m.terms {
+		parts = append(parts, fmt.Sprintf("%s=%#x/%#x", t.name, t.value, t.mask))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// splitTopLevel splits a comma separated list, leaving commas nested in
+// parentheses or brackets alone
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
@@ -0,0 +1,366 @@
+package ovssim
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Packet describes the headers of a simulated packet. Fields that do not
+// apply to the packet's type are left empty.
+type Packet struct {
+	InPort   string `json:"in_port"`
+	EthSrc   string `json:"eth_src"`
+	EthDst   string `json:"eth_dst"`
+	EthType  string `json:"eth_type"`           // ip, ipv6, arp
+	Protocol string `json:"protocol,omitempty"` // tcp, udp, icmp, icmp6; empty for any other IP protocol
+
+	IPSrc    string `json:"ip_src,omitempty"`
+	IPDst    string `json:"ip_dst,omitempty"`
+	SrcPort  int    `json:"src_port,omitempty"`
+	DstPort  int    `json:"dst_port,omitempty"`
+	ICMPType int    `json:"icmp_type,omitempty"`
+	ICMPCode int    `json:"icmp_code,omitempty"`
+
+	ARPOp  int    `json:"arp_op,omitempty"`
+	ARPSHA string `json:"arp_sha,omitempty"`
+	ARPTHA string `json:"arp_tha,omitempty"`
+	ARPSPA string `json:"arp_spa,omitempty"`
+	ARPTPA string `json:"arp_tpa,omitempty"`
+
+	TunSrc string `json:"tun_src,omitempty"`
+	TunDst string `json:"tun_dst,omitempty"`
+	TunID  int    `json:"tun_id,omitempty"`
+}
+
+// Ethernet types and IP protocol numbers the simulator understands
+const (
+	ethTypeIP   = 0x0800
+	ethTypeARP  = 0x0806
+	ethTypeIPv6 = 0x86dd
+
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+)
+
+// Connection tracking state bits, numbered the way OVS numbers them
+const (
+	ctNew = 1 << iota
+	ctEst
+	ctRel
+	ctRpl
+	ctInv
+	ctTrk
+)
+
+var ctStateBits = map[string]uint64{
+	"new": ctNew,
+	"est": ctEst,
+	"rel": ctRel,
+	"rpl": ctRpl,
+	"inv": ctInv,
+	"trk": ctTrk,
+}
+
+// headers is the numeric form of a packet the pipeline works on
+type headers struct {
+	inPort  string
+	ethSrc  uint64
+	ethDst  uint64
+	ethType uint64
+	nwProto uint64
+	ipSrc   uint64
+	ipDst   uint64
+	tpSrc   uint64
+	tpDst   uint64
+	arpOp   uint64
+	arpSHA  uint64
+	arpTHA  uint64
+	arpSPA  uint64
+	arpTPA  uint64
+	tunSrc  uint64
+	tunDst  uint64
+	tunID   uint64
+	ctState uint64
+	ctZone  uint64
+	reg0    uint64
+
+	// IPv6 addresses, in halves
+	ipv6SrcHi uint64
+	ipv6SrcLo uint64
+	ipv6DstHi uint64
+	ipv6DstLo uint64
+}
+
+// field describes a header field the matches and actions can address
+type field struct {
+	name  string // canonical name, so aliases such as eth_dst and dl_dst compare equal
+	width int
+	get   func(h *headers) uint64
+	set   func(h *headers, v uint64)
+}
+
+// fields maps the match and set_field names of every supported field, and
+// the NXM names used by move, load, push and pop, to its header
+var fields = map[string]field{}
+
+func init() {
+	register := func(width int, ptr func(h *headers) *uint64, names ...string) {
+		f := field{
+			name:  names[0],
+			width: width,
+			get:   func(h *headers) uint64 { return *ptr(h) },
+			set:   func(h *headers, v uint64) { *ptr(h) = v & widthMask(width) },
+		}
+		for _, name := range names {
+			fields[name] = f
+		}
+	}
+
+	register(48, func(h *headers) *uint64 { return &h.ethSrc }, "dl_src", "eth_src", "NXM_OF_ETH_SRC")
+	register(48, func(h *headers) *uint64 { return &h.ethDst }, "dl_dst", "eth_dst", "NXM_OF_ETH_DST")
+	register(16, func(h *headers) *uint64 { return &h.ethType }, "dl_type", "eth_type", "NXM_OF_ETH_TYPE")
+	register(8, func(h *headers) *uint64 { return &h.nwProto }, "nw_proto", "ip_proto", "NXM_OF_IP_PROTO")
+	register(32, func(h *headers) *uint64 { return &h.ipSrc }, "nw_src", "ip_src", "NXM_OF_IP_SRC")
+	register(32, func(h *headers) *uint64 { return &h.ipDst }, "nw_dst", "ip_dst", "NXM_OF_IP_DST")
+	// Matches address IPv6 addresses as ipv6_src and ipv6_dst, which expand
+	// into terms on both halves. NXM_NX_IPV6_SRC and NXM_NX_IPV6_DST only
+	// reach the low half, the interface identifier, which is all the
+	// pipeline moves.
+	register(64, func(h *headers) *uint64 { return &h.ipv6SrcHi }, "ipv6_src_hi")
+	register(64, func(h *headers) *uint64 { return &h.ipv6SrcLo }, "ipv6_src_lo", "NXM_NX_IPV6_SRC")
+	register(64, func(h *headers) *uint64 { return &h.ipv6DstHi }, "ipv6_dst_hi")
+	register(64, func(h *headers) *uint64 { return &h.ipv6DstLo }, "ipv6_dst_lo", "NXM_NX_IPV6_DST")
+	register(16, func(h *headers) *uint64 { return &h.tpSrc }, "tp_src", "icmp_type", "icmpv6_type", "NXM_OF_TCP_SRC", "NXM_OF_UDP_SRC")
+	register(16, func(h *headers) *uint64 { return &h.tpDst }, "tp_dst", "icmp_code", "icmpv6_code", "NXM_OF_TCP_DST", "NXM_OF_UDP_DST")
+	register(16, func(h *headers) *uint64 { return &h.arpOp }, "arp_op", "NXM_OF_ARP_OP")
+	register(48, func(h *headers) *uint64 { return &h.arpSHA }, "arp_sha", "NXM_NX_ARP_SHA")
+	register(48, func(h *headers) *uint64 { return &h.arpTHA }, "arp_tha", "NXM_NX_ARP_THA")
+	register(32, func(h *headers) *uint64 { return &h.arpSPA }, "arp_spa", "NXM_OF_ARP_SPA")
+	register(32, func(h *headers) *uint64 { return &h.arpTPA }, "arp_tpa", "NXM_OF_ARP_TPA")
+	register(32, func(h *headers) *uint64 { return &h.tunSrc }, "tun_src", "NXM_NX_TUN_IPV4_SRC")
+	register(32, func(h *headers) *uint64 { return &h.tunDst }, "tun_dst", "NXM_NX_TUN_IPV4_DST")
+	register(64, func(h *headers) *uint64 { return &h.tunID }, "tun_id", "NXM_NX_TUN_ID")
+	register(8, func(h *headers) *uint64 { return &h.ctState }, "ct_state")
+	register(16, func(h *headers) *uint64 { return &h.ctZone }, "ct_zone", "NXM_NX_CT_ZONE")
+	register(32, func(h *headers) *uint64 { return &h.reg0 }, "reg0", "NXM_NX_REG0")
+}
+
+func widthMask(width int) uint64 {
+	if width >= 64 {
+		return ^uint64(0)
+	}
+	return 1<<uint(width) - 1
+}
+
+// toHeaders validates a packet and converts it to its numeric form
+func (p Packet) toHeaders() (*headers, error) {
+	h := &headers{inPort: p.InPort}
+
+	var err error
+	if h.ethSrc, err = parseMAC(p.EthSrc); err != nil {
+		return nil, fmt.Errorf("invalid eth_src: %w", err)
+	}
+	if h.ethDst, err = parseMAC(p.EthDst); err != nil {
+		return nil, fmt.Errorf("invalid eth_dst: %w", err)
+	}
+	if h.tunSrc, err = parseOptionalIP(p.TunSrc); err != nil {
+		return nil, fmt.Errorf("invalid tun_src: %w", err)
+	}
+	if h.tunDst, err = parseOptionalIP(p.TunDst); err != nil {
+		return nil, fmt.Errorf("invalid tun_dst: %w", err)
+	}
+	h.tunID = uint64(p.TunID)
+
+	switch p.EthType {
+	case "ip":
+		h.ethType = ethTypeIP
+		if h.ipSrc, err = parseIP(p.IPSrc); err != nil {
+			return nil, fmt.Errorf("invalid ip_src: %w", err)
+		}
+		if h.ipDst, err = parseIP(p.IPDst); err != nil {
+			return nil, fmt.Errorf("invalid ip_dst: %w", err)
+		}
+		switch p.Protocol {
+		case "tcp":
+			h.nwProto, h.tpSrc, h.tpDst = protoTCP, uint64(p.SrcPort), uint64(p.DstPort)
+		case "udp":
+			h.nwProto, h.tpSrc, h.tpDst = protoUDP, uint64(p.SrcPort), uint64(p.DstPort)
+		case "icmp":
+			h.nwProto, h.tpSrc, h.tpDst = protoICMP, uint64(p.ICMPType), uint64(p.ICMPCode)
+		case "":
+		default:
+			return nil, fmt.Errorf("unsupported protocol: %s", p.Protocol)
+		}
+	case "ipv6":
+		h.ethType = ethTypeIPv6
+		if h.ipv6SrcHi, h.ipv6SrcLo, err = parseIPv6(p.IPSrc); err != nil {
+			return nil, fmt.Errorf("invalid ip_src: %w", err)
+		}
+		if h.ipv6DstHi, h.ipv6DstLo, err = parseIPv6(p.IPDst); err != nil {
+			return nil, fmt.Errorf("invalid ip_dst: %w", err)
+		}
+		switch p.Protocol {
+		case "tcp":
+			h.nwProto, h.tpSrc, h.tpDst = protoTCP, uint64(p.SrcPort), uint64(p.DstPort)
+		case "udp":
+			h.nwProto, h.tpSrc, h.tpDst = protoUDP, uint64(p.SrcPort), uint64(p.DstPort)
+		case "icmp6":
+			h.nwProto, h.tpSrc, h.tpDst = protoICMPv6, uint64(p.ICMPType), uint64(p.ICMPCode)
+		case "":
+		default:
+			return nil, fmt.Errorf("unsupported protocol: %s", p.Protocol)
+		}
+	case "arp":
+		h.ethType = ethTypeARP
+		h.arpOp = uint64(p.ARPOp)
+		if h.arpSHA, err = parseOptionalMAC(p.ARPSHA); err != nil {
+			return nil, fmt.Errorf("invalid arp_sha: %w", err)
+		}
+		if h.arpTHA, err = parseOptionalMAC(p.ARPTHA); err != nil {
+			return nil, fmt.Errorf("invalid arp_tha: %w", err)
+		}
+		if h.arpSPA, err = parseIP(p.ARPSPA); err != nil {
+			return nil, fmt.Errorf("invalid arp_spa: %w", err)
+		}
+		if h.arpTPA, err = parseIP(p.ARPTPA); err != nil {
+			return nil, fmt.Errorf("invalid arp_tpa: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported eth_type: %s", p.EthType)
+	}
+
+	return h, nil
+}
+
+// toPacket converts headers back to their readable form
+func (h *headers) toPacket() Packet {
+	p := Packet{
+		InPort: h.inPort,
+		EthSrc: formatMAC(h.ethSrc),
+		EthDst: formatMAC(h.ethDst),
+		TunID:  int(h.tunID),
+	}
+	if h.tunSrc != 0 {
+		p.TunSrc = formatIP(h.tunSrc)
+	}
+	if h.tunDst != 0 {
+		p.TunDst = formatIP(h.tunDst)
+	}
+
+	switch h.ethType {
+	case ethTypeIP:
+		p.EthType = "ip"
+		p.IPSrc = formatIP(h.ipSrc)
+		p.IPDst = formatIP(h.ipDst)
+		switch h.nwProto {
+		case protoTCP:
+			p.Protocol, p.SrcPort, p.DstPort = "tcp", int(h.tpSrc), int(h.tpDst)
+		case protoUDP:
+			p.Protocol, p.SrcPort, p.DstPort = "udp", int(h.tpSrc), int(h.tpDst)
+		case protoICMP:
+			p.Protocol, p.ICMPType, p.ICMPCode = "icmp", int(h.tpSrc), int(h.tpDst)
+		}
+	case ethTypeIPv6:
+		p.EthType = "ipv6"
+		p.IPSrc = formatIPv6(h.ipv6SrcHi, h.ipv6SrcLo)
+		p.IPDst = formatIPv6(h.ipv6DstHi, h.ipv6DstLo)
+		switch h.nwProto {
+		case protoTCP:
+			p.Protocol, p.SrcPort, p.DstPort = "tcp", int(h.tpSrc), int(h.tpDst)
+		case protoUDP:
+			p.Protocol, p.SrcPort, p.DstPort = "udp", int(h.tpSrc), int(h.tpDst)
+		case protoICMPv6:
+			p.Protocol, p.ICMPType, p.ICMPCode = "icmp6", int(h.tpSrc), int(h.tpDst)
+		}
+	case ethTypeARP:
+		p.EthType = "arp"
+		p.ARPOp = int(h.arpOp)
+		p.ARPSHA = formatMAC(h.arpSHA)
+		p.ARPTHA = formatMAC(h.arpTHA)
+		p.ARPSPA = formatIP(h.arpSPA)
+		p.ARPTPA = formatIP(h.arpTPA)
+	}
+
+	return p
+}
+
+func (h *headers) clone() *headers {
+	c := *h
+	return &c
+}
+
+// Value parsing helpers
+
+func parseMAC(s string) (uint64, error) {
+	hw, err := net.ParseMAC(s)
+	if err != nil || len(hw) != 6 {
+		return 0, fmt.Errorf("invalid MAC address: %q", s)
+	}
+	var v uint64
+	for _, b := range hw {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func parseOptionalMAC(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return parseMAC(s)
+}
+
+func formatMAC(v uint64) string {
+	return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x",
+		byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func parseIP(s string) (uint64, error) {
+	ip := net.ParseIP(s).To4()
+	if ip == nil {
+		return 0, fmt.Errorf("invalid IPv4 address: %q", s)
+	}
+	return uint64(binary.BigEndian.Uint32(ip)), nil
+}
+
+func parseOptionalIP(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return parseIP(s)
+}
+
+func formatIP(v uint64) string {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, uint32(v))
+	return ip.String()
+}
+
+// parseIPv6 parses an IPv6 address into its high and low halves
+func parseIPv6(s string) (uint64, uint64, error) {
+	ip := net.ParseIP(s)
+	if ip == nil || ip.To4() != nil {
+		return 0, 0, fmt.Errorf("invalid IPv6 address: %q", s)
+	}
+	return binary.BigEndian.Uint64(ip[:8]), binary.BigEndian.Uint64(ip[8:]), nil
+}
+
+func formatIPv6(hi, lo uint64) string {
+	ip := make(net.IP, net.IPv6len)
+	binary.BigEndian.PutUint64(ip[:8], hi)
+	binary.BigEndian.PutUint64(ip[8:], lo)
+	return ip.String()
+}
+
+// parseNumber parses a decimal or 0x-prefixed hexadecimal number
+func parseNumber(s string) (uint64, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return strconv.ParseUint(s[2:], 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
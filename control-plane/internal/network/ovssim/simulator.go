@@ -0,0 +1,380 @@
+// Package ovssim provides an in-memory Open vSwitch for exercising the
+// dataplane without root or a running ovs-vswitchd. Its Manager satisfies
+// network.OVSManager and models bridges, ports, VLAN tags, flow tables and
+// conntrack closely enough to answer whether a packet would be forwarded,
+// and where.
+package ovssim
+
+import (
+	"fmt"
+
+	"gon-cloud-platform/control-plane/internal/network"
+)
+
+// maxRecirculations bounds the patch port hops and conntrack
+// recirculations of one simulated packet
+const maxRecirculations = 64
+
+// Trace is the path a simulated packet took through the switch
+type Trace struct {
+	Steps   []TraceStep `json:"steps"`
+	Outputs []Output    `json:"outputs"`
+	Samples []Sample    `json:"samples,omitempty"`
+	// Mirrored are the copies of the packet mirrors sent out of their
+	// output ports
+	Mirrored []Output `json:"mirrored,omitempty"`
+	Dropped  bool     `json:"dropped"`
+}
+
+// TraceStep is one flow table lookup
+type TraceStep struct {
+	Bridge   string `json:"bridge"`
+	Table    int    `json:"table"`
+	Priority int    `json:"priority,omitempty"`
+	Match    string `json:"match,omitempty"`
+	Actions  string `json:"actions,omitempty"`
+	Miss     bool   `json:"miss,omitempty"` // no flow matched, so the packet was dropped
+	CTState  string `json:"ct_state,omitempty"`
+}
+
+// Sample is a sample the packet was exported in. Every sample is taken,
+// whatever its probability.
+type Sample struct {
+	Bridge            string `json:"bridge"`
+	CollectorSetID    int    `json:"collector_set_id"`
+	ObservationDomain uint32 `json:"obs_domain_id"`
+	ObservationPoint  uint32 `json:"obs_point_id"`
+}
+
+// Output is a copy of the packet leaving the switch through a port
+type Output struct {
+	Bridge string `json:"bridge"`
+	Port   string `json:"port"`
+	VLAN   int    `json:"vlan,omitempty"`
+	Packet Packet `json:"packet"`
+}
+
+// OutputPorts returns the ports the packet left through
+func (t *Trace) OutputPorts() []string {
+	ports := make([]string, 0, len(t.Outputs))
+	for _, o := range t.Outputs {
+		ports = append(ports, o.Port)
+	}
+	return ports
+}
+
+// Simulate pushes a packet entering bridgeName through pkt.InPort through
+// the flow tables. Like the real datapath it updates flow packet counters,
+// MAC learning and conntrack, so a reply simulated afterwards is seen as
+// part of the same connection.
+func (m *Manager) Simulate(bridgeName string, pkt Packet) (*Trace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return nil, err
+	}
+	in := br.resolvePort(pkt.InPort)
+	if in == nil {
+		return nil, fmt.Errorf("bridge %s has no port %s", bridgeName, pkt.InPort)
+	}
+	pkt.InPort = in.name
+
+	h, err := pkt.toHeaders()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &simulation{m: m, trace: &Trace{}}
+	if err := s.receive(br, in, h); err != nil {
+		return nil, err
+	}
+	s.trace.Dropped = len(s.trace.Outputs) == 0
+
+	return s.trace, nil
+}
+
+// simulation is the state of one Simulate call
+type simulation struct {
+	m             *Manager
+	trace         *Trace
+	recirculation int
+	dryRun        bool            // leave flow counters and MAC learning untouched
+	mirrored      map[string]bool // mirrors that already copied the packet
+}
+
+// receive starts the pipeline of a bridge for a packet arriving on a port
+func (s *simulation) receive(br *bridge, in *port, h *headers) error {
+	h.inPort = in.name
+	if in.typ == portTypePatch {
+		// Patch ports carry no tunnel metadata or conntrack state across
+		h.tunSrc, h.tunDst, h.tunID = 0, 0, 0
+	}
+	// Registers and conntrack state start out clear on every bridge
+	h.ctState, h.ctZone, h.reg0 = 0, 0, 0
+	s.mirror(br, in, h, true)
+	return s.run(br, h, 0)
+}
+
+// run executes flow tables from table onwards
+func (s *simulation) run(br *bridge, h *headers, table int) error {
+	s.recirculation++
+	if s.recirculation > maxRecirculations {
+		return fmt.Errorf("packet exceeded %d recirculations", maxRecirculations)
+	}
+
+	var stack []uint64
+	for {
+		entry := br.lookup(table, h)
+		if entry == nil {
+			s.trace.Steps = append(s.trace.Steps, TraceStep{
+				Bridge:  br.name,
+				Table:   table,
+				Miss:    true,
+				CTState: formatCTState(h.ctState),
+			})
+			return nil
+		}
+		if !s.dryRun {
+			entry.packets++
+		}
+		s.trace.Steps = append(s.trace.Steps, TraceStep{
+			Bridge:   br.name,
+			Table:    table,
+			Priority: entry.priority,
+			Match:    entry.matchStr,
+			Actions:  entry.actStr,
+			CTState:  formatCTState(h.ctState),
+		})
+
+		next := -1
+		for _, a := range entry.actions {
+			switch a.kind {
+			case actionDrop:
+				return nil
+			case actionNormal:
+				if err := s.normal(br, h.clone()); err != nil {
+					return err
+				}
+			case actionOutput:
+				p := br.resolvePort(a.port)
+				if p == nil || p.name == h.inPort {
+					// Output to a removed port or back out the ingress port is dropped
+					continue
+				}
+				if err := s.output(br, p, h.clone(), 0); err != nil {
+					return err
+				}
+			case actionInPort:
+				if p, ok := br.ports[h.inPort]; ok {
+					if err := s.output(br, p, h.clone(), 0); err != nil {
+						return err
+					}
+				}
+			case actionGotoTable:
+				next = a.table
+			case actionResubmit:
+				// The nested lookup works on the same packet, so its
+				// modifications stay in effect afterwards
+				inPort := h.inPort
+				if a.port != "" {
+					p := br.resolvePort(a.port)
+					if p == nil {
+						continue
+					}
+					h.inPort = p.name
+				}
+				err := s.run(br, h, a.table)
+				h.inPort = inPort
+				if err != nil {
+					return err
+				}
+			case actionSetField, actionLoad:
+				a.dst.write(h, a.value)
+			case actionMove:
+				a.dst.write(h, a.src.read(h))
+			case actionPush:
+				stack = append(stack, a.src.read(h))
+			case actionPop:
+				if len(stack) == 0 {
+					return fmt.Errorf("pop from empty stack in table %d of bridge %s", table, br.name)
+				}
+				a.dst.write(h, stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			case actionSample:
+				s.trace.Samples = append(s.trace.Samples, Sample{
+					Bridge:            br.name,
+					CollectorSetID:    a.smp.collectorSetID,
+					ObservationDomain: a.smp.obsDomainID,
+					ObservationPoint:  a.smp.obsPointID,
+				})
+			case actionCT:
+				if a.ct.table < 0 {
+					s.m.conntrack.execute(h, a.ct)
+					continue
+				}
+				recirculated := h.clone()
+				s.m.conntrack.execute(recirculated, a.ct)
+				if err := s.run(br, recirculated, a.ct.table); err != nil {
+					return err
+				}
+			}
+		}
+
+		if next < 0 {
+			return nil
+		}
+		table = next
+	}
+}
+
+// normal implements the NORMAL action: a learning L2 switch that keeps
+// VLANs apart
+func (s *simulation) normal(br *bridge, h *headers) error {
+	in, ok := br.ports[h.inPort]
+	if !ok || br.isMirrorOutput(in.name) {
+		return nil
+	}
+	vlan := in.vlan
+
+	if !s.dryRun && !isMulticastMAC(h.ethSrc) {
+		br.macs[h.ethSrc] = in.name
+	}
+
+	if !isMulticastMAC(h.ethDst) {
+		if p := br.portForMAC(h.ethDst); p != nil {
+			if p.name == in.name || !p.carries(vlan) || br.isMirrorOutput(p.name) {
+				return nil
+			}
+			return s.output(br, p, h, vlan)
+		}
+	}
+
+	// Broadcast, multicast and unknown unicast frames are flooded
+	for _, p := range append(br.sortedPorts(), br.ports[br.name]) {
+		if p.name == in.name || !p.carries(vlan) || br.isMirrorOutput(p.name) {
+			continue
+		}
+		if err := s.output(br, p, h.clone(), vlan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// output sends a packet out of a port. Patch ports hand it to the peer
+// bridge's pipeline, flow based tunnel ports need tun_dst to be set and
+// every other port delivers it.
+func (s *simulation) output(br *bridge, p *port, h *headers, vlan int) error {
+	s.mirror(br, p, h, false)
+
+	switch {
+	case p.typ == portTypePatch:
+		peerBridge, peer := s.m.findPeer(p)
+		if peer == nil {
+			return nil
+		}
+		return s.receive(peerBridge, peer, h)
+
+	case network.ValidTunnelType(p.typ):
+		if p.options["remote_ip"] == "flow" && h.tunDst == 0 {
+			return nil
+		}
+		if key, err := parseNumber(p.options["key"]); err == nil {
+			h.tunID = key
+		}
+	}
+
+	if p.vlan != 0 {
+		vlan = 0 // access ports strip the tag
+	}
+	s.trace.Outputs = append(s.trace.Outputs, Output{
+		Bridge: br.name,
+		Port:   p.name,
+		VLAN:   vlan,
+		Packet: h.toPacket(),
+	})
+	return nil
+}
+
+// mirror sends a copy of a packet the bridge receives from or sends to a
+// port out of the output port of every mirror that selects it. Each mirror
+// copies a packet once, however many of its ports the packet passes.
+func (s *simulation) mirror(br *bridge, p *port, h *headers, received bool) {
+	ofport := 0
+	if in, ok := br.ports[h.inPort]; ok {
+		ofport = in.ofport
+	}
+
+	for _, mr := range br.sortedMirrors() {
+		key := br.name + "/" + mr.name
+		if s.mirrored[key] || mr.output == "" || mr.output == p.name {
+			continue
+		}
+		selected := mr.dstPorts
+		if received {
+			selected = mr.srcPorts
+		}
+		if !containsPort(selected, p.name) || !mr.filter.matches(h, ofport) {
+			continue
+		}
+
+		if s.mirrored == nil {
+			s.mirrored = make(map[string]bool)
+		}
+		s.mirrored[key] = true
+		s.trace.Mirrored = append(s.trace.Mirrored, Output{
+			Bridge: br.name,
+			Port:   mr.output,
+			Packet: h.toPacket(),
+		})
+	}
+}
+
+// containsPort reports whether ports holds name
+func containsPort(ports []string, name string) bool {
+	for _, p := range ports {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// portForMAC finds the port a MAC address lives behind, preferring
+// attached addresses over learned ones
+func (br *bridge) portForMAC(mac uint64) *port {
+	for _, p := range br.ports {
+		if p.mac != 0 && p.mac == mac {
+			return p
+		}
+	}
+	if name, ok := br.macs[mac]; ok {
+		return br.ports[name]
+	}
+	return nil
+}
+
+// carries reports whether a port forwards frames of a VLAN. Ports without a
+// tag are trunks and carry every VLAN.
+func (p *port) carries(vlan int) bool {
+	return p.vlan == 0 || p.vlan == vlan
+}
+
+func isMulticastMAC(mac uint64) bool {
+	return mac>>40&1 == 1
+}
+
+func formatCTState(state uint64) string {
+	if state == 0 {
+		return ""
+	}
+	s := ""
+	for _, name := range []string{"new", "est", "rel", "rpl", "inv", "trk"} {
+		if state&ctStateBits[name] != 0 {
+			s += "+" + name
+		}
+	}
+	return s
+}
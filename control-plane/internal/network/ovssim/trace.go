@@ -0,0 +1,89 @@
+package ovssim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TraceFlow runs a microflow through a bridge the way ovs-appctl
+// ofproto/trace does and renders a report in the same spirit. The flow is
+// a comma separated list of exact field=value terms and protocol keywords,
+// such as in_port=vm1,tcp,nw_src=10.0.1.5,nw_dst=10.0.2.7,tp_dst=22.
+// Unlike Simulate it leaves counters, MAC learning and conntrack as they were.
+func (m *Manager) TraceFlow(bridgeName, flow string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	br, err := m.bridge(bridgeName)
+	if err != nil {
+		return "", err
+	}
+
+	mt, err := parseMatch(flow)
+	if err != nil {
+		return "", fmt.Errorf("invalid flow: %w", err)
+	}
+	in := br.resolvePort(mt.inPort)
+	if in == nil {
+		return "", fmt.Errorf("bridge %s has no port %s", bridgeName, mt.inPort)
+	}
+
+	h := &headers{}
+	for _, t := range mt.terms {
+		f := fields[t.name]
+		if t.mask != widthMask(f.width) {
+			return "", fmt.Errorf("microflow field %s must not be masked", t.name)
+		}
+		f.set(h, t.value)
+	}
+
+	connections := m.conntrack.connections
+	s := &simulation{m: m, trace: &Trace{}, dryRun: true}
+	err = s.receive(br, in, h)
+	m.conntrack.connections = connections
+	if err != nil {
+		return "", err
+	}
+	s.trace.Dropped = len(s.trace.Outputs) == 0
+
+	return formatTrace(flow, s.trace), nil
+}
+
+func formatTrace(flow string, t *Trace) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Flow: %s\n", flow)
+
+	bridge, ctState := "", ""
+	for _, step := range t.Steps {
+		if step.Bridge != bridge {
+			bridge = step.Bridge
+			header := fmt.Sprintf("bridge(%q)", bridge)
+			fmt.Fprintf(&b, "\n%s\n%s\n", header, strings.Repeat("-", len(header)))
+		}
+		if step.CTState != ctState {
+			ctState = step.CTState
+			fmt.Fprintf(&b, " ct_state=%s\n", strings.TrimPrefix(step.CTState, "+"))
+		}
+		if step.Miss {
+			fmt.Fprintf(&b, " %d. No match.\n    drop\n", step.Table)
+			continue
+		}
+		match := step.Match
+		if match == "" {
+			match = "(any)"
+		}
+		fmt.Fprintf(&b, " %d. %s, priority %d\n    %s\n", step.Table, match, step.Priority, step.Actions)
+	}
+
+	actions := "drop"
+	if !t.Dropped {
+		outputs := make([]string, 0, len(t.Outputs))
+		for _, o := range t.Outputs {
+			outputs = append(outputs, fmt.Sprintf("%s:%s", o.Bridge, o.Port))
+		}
+		actions = "output(" + strings.Join(outputs, "),output(") + ")"
+	}
+	fmt.Fprintf(&b, "\nDatapath actions: %s\n", actions)
+
+	return b.String()
+}
@@ -0,0 +1,155 @@
+package network
+
+import (
+	"fmt"
+)
+
+// Peering stages of the VPC bridge pipeline. Peered VPCs are joined by a
+// pair of patch ports between their bridges on every node. Traffic crosses
+// on the node it starts on: the routing table of its VPC sends it over, and
+// it then runs the whole pipeline of the peer, whose network ACLs and
+// security groups decide whether it is let in, before the peer's overlay
+// delivers it. The peer only admits traffic for its own range from the
+// range of the VPC it is peered with, so no peering leads any further.
+//
+// A reply crosses on the node it starts on too, which need not be where its
+// connection was seen by the VPC it returns to. Traffic from a peer arriving
+// from a tunnel is therefore also committed in the zone of the peer, so the
+// reply is recognised as established when it crosses back.
+const (
+	// TablePeeringCommit commits traffic from a peer in the peer's zone
+	// and delivers it
+	TablePeeringCommit = 81
+)
+
+// Flow priorities used by peerings. Traffic from a peer is classified above
+// the internet gateway, DHCP and metadata flows, so nothing from a peer
+// reaches the VPC's own services.
+const (
+	PriorityClassifierPeering     = 370
+	PriorityClassifierPeeringDrop = 360
+	PriorityTunnelPeering         = 200
+	PriorityPeeringCommit         = 100
+	PriorityPeeringCommitDefault  = 1
+)
+
+// PeeringPortPrefix starts the name of every peering patch port
+const PeeringPortPrefix = "pcx-"
+
+// PeerNetwork is a VPC peered with the VPC of a bridge
+type PeerNetwork struct {
+	// Port is the patch port leading to the peer's bridge
+	Port string
+	// CIDRBlock is the range of the peer
+	CIDRBlock string
+	// IPv6CIDRBlock is the IPv6 block of the peer, empty if it has none
+	IPv6CIDRBlock string
+	// Zone is the conntrack zone of the peer
+	Zone int
+}
+
+// PeeringPorts returns the patch port pair connecting a VPC bridge to the
+// bridge of a peer: the port on the VPC bridge and its peer on the other.
+// Each is named after the bridge it leads to.
+func PeeringPorts(bridgeName, peerBridgeName string) (string, string) {
+	return PeeringPortPrefix + vpcBridgeSuffix(peerBridgeName), PeeringPortPrefix + vpcBridgeSuffix(bridgeName)
+}
+
+// PeeringRouteActions returns the actions of routes that target a peering.
+// The destination MAC is set to the one the destination instance has, like
+// InstanceMAC derives it, so the peer's overlay can deliver the packet
+// wherever the instance runs.
+func PeeringRouteActions(port string) string {
+	return "move:NXM_OF_IP_DST[]->NXM_OF_ETH_DST[0..31]," +
+		"load:0x0200->NXM_OF_ETH_DST[32..47]," +
+		"output:" + port
+}
+
+// PeeringIPv6RouteActions returns the actions of IPv6 routes that target a
+// peering, which set the destination MAC from the interface identifier of
+// the destination address
+func PeeringIPv6RouteActions(port string) string {
+	return ipv6RouteActions + ",output:" + port
+}
+
+// CompilePeering builds the flows of a VPC bridge that admit traffic from
+// its peers. Traffic from a peer's range for the VPC's own range enters the
+// pipeline, and so does IPv6 traffic between their IPv6 blocks when both
+// have one; anything else arriving from a peer, including ARP and neighbor
+// discovery, is dropped.
+func CompilePeering(vpcCIDR, vpcIPv6CIDR string, ctZone int, peers []PeerNetwork) ([]Flow, error) {
+	vpcNet, err := ParseIPv4CIDR(vpcCIDR)
+	if err != nil {
+		return nil, err
+	}
+	local := map[ipFamily]string{ipv4Family: vpcNet.String()}
+	if vpcIPv6CIDR != "" {
+		vpcIPv6Net, err := ParseIPv6CIDR(vpcIPv6CIDR)
+		if err != nil {
+			return nil, err
+		}
+		local[ipv6Family] = vpcIPv6Net.String()
+	}
+
+	flows := []Flow{
+		{
+			Table:    TablePeeringCommit,
+			Priority: PriorityPeeringCommitDefault,
+			Actions:  "NORMAL",
+		},
+	}
+
+	for _, peer := range peers {
+		if peer.Zone < 1 || peer.Zone > 65535 {
+			return nil, fmt.Errorf("invalid conntrack zone: %d", peer.Zone)
+		}
+		peerNet, err := ParseIPv4CIDR(peer.CIDRBlock)
+		if err != nil {
+			return nil, err
+		}
+		remote := map[ipFamily]string{ipv4Family: peerNet.String()}
+		if peer.IPv6CIDRBlock != "" {
+			peerIPv6Net, err := ParseIPv6CIDR(peer.IPv6CIDRBlock)
+			if err != nil {
+				return nil, err
+			}
+			remote[ipv6Family] = peerIPv6Net.String()
+		}
+
+		flows = append(flows, Flow{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierPeeringDrop,
+			Match:    fmt.Sprintf("in_port=%s", peer.Port),
+			Actions:  "drop",
+		})
+
+		for _, family := range []ipFamily{ipv4Family, ipv6Family} {
+			vpcRange, peerRange := local[family], remote[family]
+			if vpcRange == "" || peerRange == "" {
+				continue
+			}
+			flows = append(flows,
+				Flow{
+					Table:    TableClassifier,
+					Priority: PriorityClassifierPeering,
+					Match:    fmt.Sprintf("%s,in_port=%s,%s=%s,%s=%s", family.ip, peer.Port, family.src, peerRange, family.dst, vpcRange),
+					Actions:  fmt.Sprintf("goto_table:%d", TableNetworkACLEgress),
+				},
+				Flow{
+					Table:    TableTunnelIngress,
+					Priority: PriorityTunnelPeering,
+					Match:    fmt.Sprintf("%s,ct_state=+trk,%s=%s", family.ip, family.src, peerRange),
+					Actions:  fmt.Sprintf("ct(commit,zone=%d),ct(zone=%d,table=%d)", ctZone, peer.Zone, TablePeeringCommit),
+				},
+				Flow{
+					Table:    TablePeeringCommit,
+					Priority: PriorityPeeringCommit,
+					Match:    fmt.Sprintf("%s,%s=%s", family.ip, family.src, peerRange),
+					Actions:  fmt.Sprintf("ct(commit,zone=%d),NORMAL", peer.Zone),
+				},
+			)
+		}
+	}
+
+	return flows, nil
+}
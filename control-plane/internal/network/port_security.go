@@ -0,0 +1,84 @@
+package network
+
+import (
+	"fmt"
+)
+
+// Flow priorities of the port security flows. They sit above every other
+// classifier flow, so nothing an instance sends is classified before its
+// addresses have been checked.
+const (
+	PriorityClassifierPortSecurity     = 500
+	PriorityClassifierPortSecurityDrop = 490
+)
+
+// Packets whose addresses have been checked get bit 0 of reg0 set and are
+// resubmitted to the classifier, where the port security flows no longer
+// match them
+const (
+	portSecurityUnchecked = "reg0=0x0/0x1"
+	portSecurityPass      = "load:0x1->NXM_NX_REG0[0]"
+)
+
+// InstancePort is the port of an instance on a VPC bridge and the addresses
+// the instance holds
+type InstancePort struct {
+	Name string
+	IPv4 string
+	IPv6 string // empty when the subnet has no IPv6 block
+	// Router instances are route targets and forward traffic from other
+	// addresses, so only their MAC is checked
+	Router bool
+}
+
+// CompilePortSecurity builds the classifier flows that drop what instances
+// send from a MAC or an address other than their own. ARP must announce the
+// instance's own binding, and DHCP discovery, IPv6 duplicate address
+// detection and link-local neighbor discovery are let through before the
+// instance holds an address.
+func CompilePortSecurity(ports []InstancePort) ([]Flow, error) {
+	var flows []Flow
+	for _, port := range ports {
+		mac, err := InstanceMAC(port.IPv4)
+		if err != nil {
+			return nil, err
+		}
+
+		matches := []string{
+			fmt.Sprintf("arp,arp_spa=%s,arp_sha=%s", port.IPv4, mac),
+			"udp,nw_src=0.0.0.0,tp_src=68,tp_dst=67",
+			fmt.Sprintf("icmp6,ipv6_src=::,icmpv6_type=%d", ICMPv6NeighborSolicitation),
+		}
+		if port.Router {
+			matches = append(matches, "ip", "ipv6")
+		} else {
+			linkLocal, err := InstanceIPv6("fe80::/64", port.IPv4)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches,
+				fmt.Sprintf("ip,nw_src=%s", port.IPv4),
+				fmt.Sprintf("ipv6,ipv6_src=%s", linkLocal),
+			)
+			if port.IPv6 != "" {
+				matches = append(matches, fmt.Sprintf("ipv6,ipv6_src=%s", port.IPv6))
+			}
+		}
+
+		for _, match := range matches {
+			flows = append(flows, Flow{
+				Table:    TableClassifier,
+				Priority: PriorityClassifierPortSecurity,
+				Match:    fmt.Sprintf("%s,%s,in_port=%s,dl_src=%s", match, portSecurityUnchecked, port.Name, mac),
+				Actions:  fmt.Sprintf("%s,resubmit(,%d)", portSecurityPass, TableClassifier),
+			})
+		}
+		flows = append(flows, Flow{
+			Table:    TableClassifier,
+			Priority: PriorityClassifierPortSecurityDrop,
+			Match:    fmt.Sprintf("%s,in_port=%s", portSecurityUnchecked, port.Name),
+			Actions:  "drop",
+		})
+	}
+	return flows, nil
+}
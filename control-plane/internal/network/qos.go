@@ -0,0 +1,96 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// InstancePortPrefix starts the name of every instance port on a VPC bridge
+const InstancePortPrefix = "vm-"
+
+// PortQoS limits the bandwidth of a port. OVS polices what it receives from
+// a port and shapes what it sends to it, so on an instance port the ingress
+// policing applies to what the instance sends and the egress rate to what it
+// receives. Zero rates are unlimited.
+type PortQoS struct {
+	// IngressPolicingRate is the rate received traffic is policed to, in kbps
+	IngressPolicingRate int `json:"ingress_policing_rate"`
+	// IngressPolicingBurst is the burst the policer allows, in kb
+	IngressPolicingBurst int `json:"ingress_policing_burst"`
+	// EgressMaxRate is the maximum rate of the port's HTB queue, in bps
+	EgressMaxRate int64 `json:"egress_max_rate"`
+}
+
+// IsZero reports whether the QoS leaves the port unlimited
+func (q PortQoS) IsZero() bool {
+	return q.IngressPolicingRate == 0 && q.EgressMaxRate == 0
+}
+
+// MaxBandwidthMbps is the highest rate a port can be limited to. Policing
+// rates are kbps and may not exceed 2^32 - 1.
+const MaxBandwidthMbps = 4000000
+
+var bandwidthPattern = regexp.MustCompile(`^(?i)(?:up\s+to\s+)?([0-9]+(?:\.[0-9]+)?)\s*([kmg])(?:bps|bit/s)$`)
+
+// ParseBandwidth returns the rate in Mbps of a bandwidth as instance types
+// give it, e.g. "500 Mbps", "10 Gbps" or "Up to 25 Gbps". Bursts beyond an
+// "up to" rate are not granted, so it is the limit.
+func ParseBandwidth(s string) (int, error) {
+	m := bandwidthPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid bandwidth: %q", s)
+	}
+
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth: %q", s)
+	}
+	switch strings.ToLower(m[2]) {
+	case "k":
+		value /= 1000
+	case "g":
+		value *= 1000
+	}
+
+	if value < 1 || value > MaxBandwidthMbps {
+		return 0, fmt.Errorf("bandwidth out of range: %q", s)
+	}
+	return int(math.Round(value)), nil
+}
+
+// DefaultBurstKb returns the burst allowed at a policing rate when none is
+// given, a tenth of a second's worth of traffic. Policing bursts much
+// smaller than that throttle TCP well below the rate.
+func DefaultBurstKb(mbps int) int {
+	return mbps * 100
+}
+
+// InstancePortName returns the name of the port of an instance on a VPC
+// bridge, which the hypervisor creates when it starts the instance. It fits
+// the 15 characters of a Linux interface name.
+func InstancePortName(instanceID string) string {
+	id := strings.ReplaceAll(instanceID, "-", "")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return InstancePortPrefix + id
+}
+
+// InstanceQoS returns the QoS of an instance port that limits what the
+// instance receives to inboundMbps and what it sends to outboundMbps, in
+// bursts of up to burstKb. Zero rates are unlimited, and a zero burst is the
+// default for the outbound rate.
+func InstanceQoS(inboundMbps, outboundMbps, burstKb int) PortQoS {
+	qos := PortQoS{EgressMaxRate: int64(inboundMbps) * 1000000}
+	if outboundMbps > 0 {
+		if burstKb == 0 {
+			burstKb = DefaultBurstKb(outboundMbps)
+		}
+		qos.IngressPolicingRate = outboundMbps * 1000
+		qos.IngressPolicingBurst = burstKb
+	}
+	return qos
+}
@@ -0,0 +1,77 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// Probe is the first packet of a connection as seen by the reachability
+// analyzer. Port is the destination port, or the ICMP type for icmp probes,
+// which are assumed to carry code 0.
+type Probe struct {
+	Protocol string // tcp, udp, icmp
+	Port     int
+}
+
+// MatchesRule reports whether a security group rule or network ACL entry
+// with the given protocol and port range admits the probe. The port range
+// is read the way protocolMatches compiles it.
+func (p Probe) MatchesRule(protocol string, fromPort, toPort int) bool {
+	switch protocol {
+	case "all":
+		return true
+	case "icmp":
+		if p.Protocol != "icmp" {
+			return false
+		}
+		if fromPort < 0 {
+			return true
+		}
+		return p.Port == fromPort && toPort <= 0
+	case "tcp", "udp":
+		return p.Protocol == protocol && p.Port >= fromPort && p.Port <= toPort
+	default:
+		return false
+	}
+}
+
+// CIDRContainsIP reports whether an IPv4 address lies in a CIDR block or
+// equals a bare address. IPv6 blocks never contain it.
+func CIDRContainsIP(cidr string, ip string) (bool, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.To4() == nil {
+		return false, fmt.Errorf("invalid IPv4 address: %s", ip)
+	}
+	if bare := net.ParseIP(cidr); bare != nil {
+		return bare.Equal(addr), nil
+	}
+	if IsIPv6(cidr) {
+		return false, nil
+	}
+
+	ipNet, err := ParseIPv4CIDR(cidr)
+	if err != nil {
+		return false, err
+	}
+	return ipNet.Contains(addr), nil
+}
+
+// EvaluateNetworkACL returns the index of the entry a network ACL applies
+// to a probe crossing it in direction, or -1 when the default deny applies.
+// Rules must be sorted by rule number; remoteIP is the address on the far
+// side of the subnet boundary.
+func EvaluateNetworkACL(rules []NetworkACLRule, direction string, remoteIP string, probe Probe) (int, error) {
+	for i, rule := range rules {
+		if rule.Direction != direction || !probe.MatchesRule(rule.Protocol, rule.FromPort, rule.ToPort) {
+			continue
+		}
+		contains, err := CIDRContainsIP(rule.CIDRBlock, remoteIP)
+		if err != nil {
+			return -1, err
+		}
+		if contains {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
@@ -0,0 +1,171 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// TableRouting forwards committed traffic according to the route table of
+// the source subnet
+const TableRouting = 50
+
+// Flow priorities used by the routing table. Route priorities grow with the
+// destination prefix length so the longest prefix wins, and routes of subnets
+// with their own route table sit above the main table's routes, IPv6 /128
+// routes included.
+const (
+	PriorityRouteLocal   = 1000
+	PriorityRouteSubnet  = 700
+	PriorityRouteIsolate = 699
+	PriorityRouteBase    = 500
+	PriorityRouteDefault = 1
+)
+
+// BlackholeActions drops traffic for routes whose target no longer exists
+const BlackholeActions = "drop"
+
+// RouteEntry is a route whose target has been resolved to flow actions. An
+// empty SourceCIDR marks a route of the main table, which applies to every
+// subnet without a table of its own. Cookie names the route that owns the
+// flow.
+type RouteEntry struct {
+	SourceCIDR      string
+	DestinationCIDR string
+	Actions         string
+	Cookie          string
+}
+
+// RoutePriority returns the flow priority of a route to destinationCIDR
+func RoutePriority(destinationCIDR string) (int, error) {
+	_, ipNet, err := net.ParseCIDR(destinationCIDR)
+	if err != nil {
+		return 0, err
+	}
+	ones, _ := ipNet.Mask.Size()
+	return PriorityRouteBase + ones, nil
+}
+
+// InstanceMAC returns the locally administered MAC address instance ports
+// are given for a private address: 02:00 followed by the IPv4 octets
+func InstanceMAC(ip string) (string, error) {
+	ipv4 := net.ParseIP(ip).To4()
+	if ipv4 == nil {
+		return "", fmt.Errorf("invalid IPv4 address: %s", ip)
+	}
+	return fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", ipv4[0], ipv4[1], ipv4[2], ipv4[3]), nil
+}
+
+// InstanceRouteActions returns the actions that hand traffic to an instance
+// acting as a router, wherever it runs
+func InstanceRouteActions(ip string) (string, error) {
+	mac, err := InstanceMAC(ip)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("mod_dl_dst:%s,%s", mac, OverlayActions()), nil
+}
+
+// IPv6RouteActions returns the actions of routes that hand IPv6 traffic to
+// the overlay stage. Instances send it to their router, so the destination
+// MAC is set to the one of the instance it is for first.
+func IPv6RouteActions() string {
+	return ipv6RouteActions + "," + OverlayActions()
+}
+
+// CompileRoutes builds the whole routing table of a VPC bridge: traffic to
+// the VPC's ranges, its IPv4 range and its IPv6 block if it has one, is
+// handed to the overlay stage, routes are matched longest prefix first and
+// everything else is dropped. Subnets listed in isolated have their own
+// route table, so main table routes never apply to them. The source of a
+// subnet route must be of the family of its destination.
+func CompileRoutes(localCIDRs []string, routes []RouteEntry, isolated []string) ([]Flow, error) {
+	flows := []Flow{
+		{
+			Table:    TableRouting,
+			Priority: PriorityRouteDefault,
+			Actions:  "drop",
+		},
+	}
+
+	for _, cidr := range localCIDRs {
+		family, err := familyOf(cidr)
+		if err != nil {
+			return nil, err
+		}
+		local, err := remoteCIDRMatch(cidr)
+		if err != nil {
+			return nil, err
+		}
+		actions := OverlayActions()
+		if family == ipv6Family {
+			actions = IPv6RouteActions()
+		}
+		flows = append(flows, Flow{
+			Table:    TableRouting,
+			Priority: PriorityRouteLocal,
+			Match:    fmt.Sprintf("%s,%s=%s", family.ip, family.dst, local),
+			Actions:  actions,
+		})
+	}
+
+	for _, cidr := range isolated {
+		family, err := familyOf(cidr)
+		if err != nil {
+			return nil, err
+		}
+		source, err := remoteCIDRMatch(cidr)
+		if err != nil {
+			return nil, err
+		}
+		flows = append(flows, Flow{
+			Table:    TableRouting,
+			Priority: PriorityRouteIsolate,
+			Match:    fmt.Sprintf("%s,%s=%s", family.ip, family.src, source),
+			Actions:  "drop",
+		})
+	}
+
+	for _, route := range routes {
+		priority, err := RoutePriority(route.DestinationCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route destination %s: %w", route.DestinationCIDR, err)
+		}
+		family, err := familyOf(route.DestinationCIDR)
+		if err != nil {
+			return nil, err
+		}
+		destination, err := remoteCIDRMatch(route.DestinationCIDR)
+		if err != nil {
+			return nil, err
+		}
+
+		match := family.ip
+		if route.SourceCIDR != "" {
+			sourceFamily, err := familyOf(route.SourceCIDR)
+			if err != nil {
+				return nil, err
+			}
+			if sourceFamily != family {
+				return nil, fmt.Errorf("route source %s and destination %s are of different families", route.SourceCIDR, route.DestinationCIDR)
+			}
+			source, err := remoteCIDRMatch(route.SourceCIDR)
+			if err != nil {
+				return nil, err
+			}
+			match += fmt.Sprintf(",%s=%s", family.src, source)
+			priority += PriorityRouteSubnet - PriorityRouteBase
+		}
+		if destination != "" {
+			match += fmt.Sprintf(",%s=%s", family.dst, destination)
+		}
+		flows = append(flows, Flow{
+			Cookie:   route.Cookie,
+			Table:    TableRouting,
+			Priority: priority,
+			Match:    match,
+			Actions:  route.Actions,
+		})
+	}
+
+	return flows, nil
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/network/ovssim"
+	"gon-cloud-platform/control-plane/internal/utils"
+)
+
+const testUserID = "user-1"
+
+// testGatewayMAC is the MAC instances send traffic for outside the VPC to
+const testGatewayMAC = "02:00:00:00:00:01"
+
+// testUplinkBridge connects the internet gateways to the outside
+const testUplinkBridge = "br-uplink"
+
+// dataplane programs VPCs through the services against the simulated
+// switch, with the database held by a fakeStore
+type dataplane struct {
+	t     *testing.T
+	store *fakeStore
+	mgr   *ovssim.Manager
+	ovs   *faultyManager
+
+	vpcs             VPCService
+	securityGroups   SecurityGroupService
+	networkACLs      NetworkACLService
+	routeTables      RouteTableService
+	internetGateways InternetGatewayService
+	elasticIPs       ElasticIPService
+
+	vpc     *models.VPC // the first VPC
+	bridge  string
+	ports   map[string]string // instance address to port
+	bridges map[string]string // instance address to bridge
+}
+
+// faultyManager fails the flow replacements fail picks, so tests can break
+// a change halfway
+type faultyManager struct {
+	*ovssim.Manager
+	fail func(bridgeName string, cookie string) error
+}
+
+func (m *faultyManager) ReplaceFlows(bridgeName, cookie, mask string, flows []network.Flow) error {
+	if m.fail != nil {
+		if err := m.fail(bridgeName, cookie); err != nil {
+			return err
+		}
+	}
+	return m.Manager.ReplaceFlows(bridgeName, cookie, mask, flows)
+}
+
+func newDataplane(t *testing.T, cidrBlock string) *dataplane {
+	t.Helper()
+
+	store := newFakeStore()
+	mgr := ovssim.NewManager()
+	ovs := &faultyManager{Manager: mgr}
+	logger := utils.NewLogger("error")
+	if err := mgr.CreateBridge(testUplinkBridge, "203.0.113.0/24"); err != nil {
+		t.Fatalf("CreateBridge: %v", err)
+	}
+
+	vpcRepo := &fakeVPCRepo{store: store}
+	routeTableRepo := &fakeRouteTableRepo{store: store}
+	subnetRepo := &fakeSubnetRepo{store: store}
+	ipRepo := &fakeIPAllocationRepo{store: store}
+	igwRepo := &fakeInternetGatewayRepo{store: store}
+	natRepo := &fakeNATGatewayRepo{store: store}
+
+	overlay := NewOverlayService(&fakeWorkerNodeRepo{store: store}, ovs, "node-1", "vxlan", logger)
+	internetGateways := NewInternetGatewayService(igwRepo, vpcRepo, routeTableRepo, natRepo, ovs, testUplinkBridge, logger)
+
+	d := &dataplane{
+		t:                t,
+		store:            store,
+		mgr:              mgr,
+		ovs:              ovs,
+		vpcs:             NewVPCService(vpcRepo, routeTableRepo, igwRepo, natRepo, overlay, ovs, nil, logger),
+		securityGroups:   NewSecurityGroupService(&fakeSecurityGroupRepo{store: store}, vpcRepo, ipRepo, ovs, logger),
+		networkACLs:      NewNetworkACLService(&fakeNetworkACLRepo{store: store}, vpcRepo, subnetRepo, ovs, logger),
+		routeTables:      NewRouteTableService(routeTableRepo, vpcRepo, subnetRepo, ipRepo, igwRepo, natRepo, nil, ovs, logger),
+		internetGateways: internetGateways,
+		elasticIPs:       NewElasticIPService(&fakeElasticIPRepo{store: store}, internetGateways, logger),
+		ports:            make(map[string]string),
+		bridges:          make(map[string]string),
+	}
+	d.vpc = d.addVPC("test", cidrBlock)
+	d.bridge = d.vpc.Dataplane.BridgeName
+	return d
+}
+
+// addVPC creates a VPC through the VPC service
+func (d *dataplane) addVPC(name string, cidrBlock string) *models.VPC {
+	d.t.Helper()
+	vpc, err := d.vpcs.CreateVPC(testUserID, &dto.CreateVPCRequest{Name: name, CIDRBlock: cidrBlock})
+	if err != nil {
+		d.t.Fatalf("CreateVPC: %v", err)
+	}
+	return vpc
+}
+
+// addSubnet stores a subnet of the VPC whose block contains it and returns
+// its ID
+func (d *dataplane) addSubnet(cidrBlock string) string {
+	d.t.Helper()
+
+	_, block, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		d.t.Fatal(err)
+	}
+	for _, vpc := range d.store.vpcs {
+		_, vpcNet, err := net.ParseCIDR(vpc.CIDRBlock)
+		if err != nil || !network.CIDRContains(vpcNet, block) {
+			continue
+		}
+		subnet := &models.Subnet{
+			ID:        uuid.New().String(),
+			VPCID:     vpc.ID,
+			Name:      cidrBlock,
+			CIDRBlock: cidrBlock,
+		}
+		d.store.subnets[subnet.ID] = subnet
+		return subnet.ID
+	}
+	d.t.Fatalf("no VPC contains %s", cidrBlock)
+	return ""
+}
+
+// attachInternetGateway attaches a new internet gateway to a VPC through the
+// internet gateway service
+func (d *dataplane) attachInternetGateway(vpc *models.VPC) *models.InternetGateway {
+	d.t.Helper()
+	igw := &models.InternetGateway{ID: uuid.New().String(), UserID: testUserID, Name: "igw-" + vpc.Name}
+	d.store.igws[igw.ID] = igw
+	attached, err := d.internetGateways.AttachInternetGateway(igw.ID, testUserID, vpc.ID)
+	if err != nil {
+		d.t.Fatalf("AttachInternetGateway: %v", err)
+	}
+	return attached
+}
+
+// addInstance reserves an address in a subnet for a new instance and plugs
+// the instance into the bridge. It returns the instance ID.
+func (d *dataplane) addInstance(subnetID string, ip string) string {
+	d.t.Helper()
+
+	instanceID := uuid.New().String()
+	d.store.allocations = append(d.store.allocations, fakeAllocation{subnetID: subnetID, instanceID: instanceID, ip: ip})
+
+	port := network.InstancePortName(instanceID)
+	mac, err := network.InstanceMAC(ip)
+	if err != nil {
+		d.t.Fatal(err)
+	}
+	bridge := d.store.dataplanes[d.store.subnetVPC(subnetID)].BridgeName
+	if err := d.mgr.AddPort(bridge, port, "system"); err != nil {
+		d.t.Fatalf("AddPort: %v", err)
+	}
+	if err := d.mgr.AttachMAC(bridge, port, mac); err != nil {
+		d.t.Fatalf("AttachMAC: %v", err)
+	}
+	d.ports[ip] = port
+	d.bridges[ip] = bridge
+	return instanceID
+}
+
+// send simulates a TCP packet from an instance. Packets for addresses of
+// the VPC go to the instance's MAC, as the ARP responder answers, and any
+// other to the gateway.
+func (d *dataplane) send(src string, dst string, srcPort int, dstPort int) *ovssim.Trace {
+	d.t.Helper()
+
+	srcMAC, err := network.InstanceMAC(src)
+	if err != nil {
+		d.t.Fatal(err)
+	}
+	dstMAC := testGatewayMAC
+	if _, ok := d.ports[dst]; ok {
+		if dstMAC, err = network.InstanceMAC(dst); err != nil {
+			d.t.Fatal(err)
+		}
+	}
+
+	trace, err := d.mgr.Simulate(d.bridges[src], ovssim.Packet{
+		InPort:   d.ports[src],
+		EthSrc:   srcMAC,
+		EthDst:   dstMAC,
+		EthType:  "ip",
+		Protocol: "tcp",
+		IPSrc:    src,
+		IPDst:    dst,
+		SrcPort:  srcPort,
+		DstPort:  dstPort,
+	})
+	if err != nil {
+		d.t.Fatalf("Simulate: %v", err)
+	}
+	return trace
+}
+
+// receive simulates a TCP packet from the internet arriving at a VPC bridge
+// through its internet gateway
+func (d *dataplane) receive(vpc *models.VPC, src string, dst string, dstPort int) *ovssim.Trace {
+	d.t.Helper()
+
+	bridge := vpc.Dataplane.BridgeName
+	patchPort, _ := network.InternetGatewayPorts(bridge)
+	trace, err := d.mgr.Simulate(bridge, ovssim.Packet{
+		InPort:   patchPort,
+		EthSrc:   testGatewayMAC,
+		EthDst:   "02:00:00:00:00:02",
+		EthType:  "ip",
+		Protocol: "tcp",
+		IPSrc:    src,
+		IPDst:    dst,
+		SrcPort:  40000,
+		DstPort:  dstPort,
+	})
+	if err != nil {
+		d.t.Fatalf("Simulate: %v", err)
+	}
+	return trace
+}
+
+// expectDelivered fails unless the packet left through the port of dst only
+func (d *dataplane) expectDelivered(trace *ovssim.Trace, dst string) {
+	d.t.Helper()
+	if got := trace.OutputPorts(); !slices.Equal(got, []string{d.ports[dst]}) {
+		d.t.Errorf("packet to %s left through %v, want %s\n%s", dst, got, d.ports[dst], formatSteps(trace))
+	}
+}
+
+// expectDropped fails unless the packet was dropped
+func (d *dataplane) expectDropped(trace *ovssim.Trace) {
+	d.t.Helper()
+	if !trace.Dropped {
+		d.t.Errorf("packet left through %v, want dropped\n%s", trace.OutputPorts(), formatSteps(trace))
+	}
+}
+
+// reached reports whether the packet was looked up in a table
+func reached(trace *ovssim.Trace, table int) bool {
+	for _, step := range trace.Steps {
+		if step.Table == table {
+			return true
+		}
+	}
+	return false
+}
+
+// lastTable returns the last table the packet was looked up in
+func lastTable(trace *ovssim.Trace) int {
+	if len(trace.Steps) == 0 {
+		return -1
+	}
+	return trace.Steps[len(trace.Steps)-1].Table
+}
+
+// formatSteps lists the flows a packet hit, for failure messages
+func formatSteps(trace *ovssim.Trace) string {
+	var b strings.Builder
+	for _, step := range trace.Steps {
+		fmt.Fprintf(&b, "  table=%d priority=%d %s actions=%s\n", step.Table, step.Priority, step.Match, step.Actions)
+	}
+	return b.String()
+}
+
+func TestDataplaneSameSubnetAllow(t *testing.T) {
+	d := newDataplane(t, "10.0.0.0/16")
+	subnet := d.addSubnet("10.0.1.0/24")
+	d.addInstance(subnet, "10.0.1.5")
+	d.addInstance(subnet, "10.0.1.6")
+
+	d.expectDelivered(d.send("10.0.1.5", "10.0.1.6", 40000, 80), "10.0.1.6")
+	d.expectDelivered(d.send("10.0.1.6", "10.0.1.5", 40000, 22), "10.0.1.5")
+}
+
+func TestDataplaneSecurityGroupDeny(t *testing.T) {
+	d := newDataplane(t, "10.0.0.0/16")
+	subnet := d.addSubnet("10.0.1.0/24")
+	d.addInstance(subnet, "10.0.1.5")
+	web := d.addInstance(subnet, "10.0.1.6")
+
+	sg, err := d.securityGroups.CreateSecurityGroup(testUserID, &dto.CreateSecurityGroupRequest{VPCID: d.vpc.ID, Name: "web"})
+	if err != nil {
+		t.Fatalf("CreateSecurityGroup: %v", err)
+	}
+	_, err = d.securityGroups.AddRule(sg.ID, testUserID, &dto.SecurityGroupRuleRequest{
+		Direction: "inbound",
+		Protocol:  "tcp",
+		FromPort:  80,
+		ToPort:    80,
+		Source:    "10.0.0.0/16",
+	})
+	if err != nil {
+		t.Fatalf("AddRule: %v", err)
+	}
+	if err := d.securityGroups.AddMember(sg.ID, testUserID, web); err != nil {
+		t.Fatalf("AddMember: %v", err)
+	}
+
+	d.expectDelivered(d.send("10.0.1.5", "10.0.1.6", 40000, 80), "10.0.1.6")
+
+	denied := d.send("10.0.1.5", "10.0.1.6", 40001, 22)
+	d.expectDropped(denied)
+	if !reached(denied, network.TableSecurityGroupIngress) || reached(denied, network.TableConntrackCommit) {
+		t.Errorf("packet was not rejected by the security group\n%s", formatSteps(denied))
+	}
+}
+
+func TestDataplaneNetworkACLDeny(t *testing.T) {
+	d := newDataplane(t, "10.0.0.0/16")
+	clients := d.addSubnet("10.0.1.0/24")
+	servers := d.addSubnet("10.0.2.0/24")
+	d.addInstance(clients, "10.0.1.5")
+	d.addInstance(servers, "10.0.2.6")
+
+	acl, err := d.networkACLs.CreateNetworkACL(testUserID, &dto.CreateNetworkACLRequest{VPCID: d.vpc.ID, Name: "servers"})
+	if err != nil {
+		t.Fatalf("CreateNetworkACL: %v", err)
+	}
+	for _, entry := range []dto.NetworkACLEntryRequest{
+		{RuleNumber: 100, Direction: "inbound", Protocol: "tcp", FromPort: 22, ToPort: 22, CIDRBlock: "10.0.1.0/24", Action: "deny"},
+		{RuleNumber: 200, Direction: "inbound", Protocol: "all", CIDRBlock: "0.0.0.0/0", Action: "allow"},
+		{RuleNumber: 100, Direction: "outbound", Protocol: "all", CIDRBlock: "0.0.0.0/0", Action: "allow"},
+	} {
+		if _, err := d.networkACLs.AddEntry(acl.ID, testUserID, &entry); err != nil {
+			t.Fatalf("AddEntry %d %s: %v", entry.RuleNumber, entry.Direction, err)
+		}
+	}
+	if err := d.networkACLs.AssociateSubnet(acl.ID, testUserID, servers); err != nil {
+		t.Fatalf("AssociateSubnet: %v", err)
+	}
+
+	denied := d.send("10.0.1.5", "10.0.2.6", 40000, 22)
+	d.expectDropped(denied)
+	if !reached(denied, network.TableNetworkACLIngress) || reached(denied, network.TableConntrack) {
+		t.Errorf("packet was not denied by the network ACL\n%s", formatSteps(denied))
+	}
+
+	d.expectDelivered(d.send("10.0.1.5", "10.0.2.6", 40001, 80), "10.0.2.6")
+}
+
+func TestDataplaneLongestPrefixRoute(t *testing.T) {
+	d := newDataplane(t, "10.0.0.0/16")
+	subnet := d.addSubnet("10.0.1.0/24")
+	d.addInstance(subnet, "10.0.1.5")
+
+	d.store.igws["igw-1"] = &models.InternetGateway{ID: "igw-1", VPCID: &d.vpc.ID}
+	d.store.natGateways["nat-1"] = &models.NATGateway{ID: "nat-1", VPCID: d.vpc.ID, PublicIP: "203.0.113.10", ConntrackZone: 900}
+	d.store.natGateways["nat-2"] = &models.NATGateway{ID: "nat-2", VPCID: d.vpc.ID, PublicIP: "203.0.113.20", ConntrackZone: 901}
+
+	mainRouteTable, err := (&fakeRouteTableRepo{store: d.store}).GetMain(d.vpc.ID)
+	if err != nil || mainRouteTable == nil {
+		t.Fatalf("GetMain: %v", err)
+	}
+	for _, route := range []dto.CreateRouteRequest{
+		{DestinationCIDR: "0.0.0.0/0", TargetType: "igw", TargetID: "igw-1"},
+		{DestinationCIDR: "8.8.0.0/16", TargetType: "nat", TargetID: "nat-1"},
+		{DestinationCIDR: "8.8.8.0/24", TargetType: "nat", TargetID: "nat-2"},
+	} {
+		if _, err := d.routeTables.AddRoute(mainRouteTable.ID, testUserID, &route); err != nil {
+			t.Fatalf("AddRoute %s: %v", route.DestinationCIDR, err)
+		}
+	}
+
+	// A route whose target is gone is programmed as a blackhole, which
+	// still wins over the shorter prefixes
+	delete(d.store.natGateways, "nat-2")
+	if err := d.routeTables.SyncVPCRoutes(d.vpc.ID, testUserID); err != nil {
+		t.Fatalf("SyncVPCRoutes: %v", err)
+	}
+
+	tests := []struct {
+		dst   string
+		table int
+	}{
+		{dst: "1.1.1.1", table: network.TableInternetGateway},
+		{dst: "8.8.4.4", table: network.TableNATGateway},
+		{dst: "8.8.8.8", table: network.TableRouting},
+	}
+	for _, tt := range tests {
+		trace := d.send("10.0.1.5", tt.dst, 40000, 443)
+		d.expectDropped(trace)
+		if table := lastTable(trace); table != tt.table {
+			t.Errorf("packet to %s last looked up in table %d, want %d\n%s", tt.dst, table, tt.table, formatSteps(trace))
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// DHCPService compiles what the DHCP and DNS responder of a VPC serves: the
+// addresses IPAM reserved for instances, handed out by MAC, and the names of
+// those instances under <vpc>.internal. The network controller runs the
+// responders; see ReconcileService.
+type DHCPService interface {
+	DesiredConfig(vpc *models.VPC) (*network.DHCPConfig, error)
+	DesiredFlows(vpc *models.VPC) (*network.FlowSet, error)
+}
+
+type dhcpService struct {
+	vpcRepo          repositories.VPCRepository
+	subnetRepo       repositories.SubnetRepository
+	ipAllocationRepo repositories.IPAllocationRepository
+	upstream         []string
+	logger           *utils.Logger
+}
+
+func NewDHCPService(
+	vpcRepo repositories.VPCRepository,
+	subnetRepo repositories.SubnetRepository,
+	ipAllocationRepo repositories.IPAllocationRepository,
+	upstream []string,
+	logger *utils.Logger,
+) DHCPService {
+	return &dhcpService{
+		vpcRepo:          vpcRepo,
+		subnetRepo:       subnetRepo,
+		ipAllocationRepo: ipAllocationRepo,
+		upstream:         upstream,
+		logger:           logger,
+	}
+}
+
+// DesiredConfig returns the configuration of the responder of a VPC. An
+// instance whose name is no valid DNS label, or is taken by an older
+// instance, still gets its address but no name.
+func (s *dhcpService) DesiredConfig(vpc *models.VPC) (*network.DHCPConfig, error) {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpc.ID)
+	if err != nil {
+		return nil, err
+	}
+	subnets, err := s.dhcpSubnets(vpc.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	allocator, err := network.NewIPAllocator(vpc.CIDRBlock)
+	if err != nil {
+		s.logger.Error("Invalid VPC CIDR block", "error", err, "vpc_id", vpc.ID, "cidr_block", vpc.CIDRBlock)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile DHCP configuration")
+	}
+
+	addresses, err := s.ipAllocationRepo.ListInstanceReservationsInVPC(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to list instance reservations", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list instance reservations")
+	}
+
+	config := &network.DHCPConfig{
+		Interface: network.DHCPPortName(bridgeName),
+		Domain:    vpcDomain(vpc),
+		Gateway:   allocator.GatewayAddress(),
+		Subnets:   subnets,
+		Hosts:     make([]network.DHCPHost, 0, len(addresses)),
+		Upstream:  s.upstream,
+	}
+	named := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		hostname := network.DNSLabel(address.InstanceName)
+		if named[hostname] {
+			hostname = ""
+		} else if hostname != "" {
+			named[hostname] = true
+		}
+		host := network.DHCPHost{IPAddress: address.IPAddress, Hostname: hostname}
+		if address.IPv6Address != nil {
+			host.IPv6Address = *address.IPv6Address
+		}
+		config.Hosts = append(config.Hosts, host)
+	}
+
+	return config, nil
+}
+
+// DesiredFlows returns the flows that steer DHCP and DNS traffic of a VPC
+// bridge to its responder
+func (s *dhcpService) DesiredFlows(vpc *models.VPC) (*network.FlowSet, error) {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpc.ID)
+	if err != nil {
+		return nil, err
+	}
+	subnets, err := s.dhcpSubnets(vpc.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	flows, err := network.CompileDHCP(network.DHCPPortName(bridgeName), subnets)
+	if err != nil {
+		s.logger.Error("Failed to compile DHCP flows", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile DHCP flows")
+	}
+
+	cookie := network.FlowCookie(network.CookieKindDHCP, vpc.ID)
+	return &network.FlowSet{
+		Cookie: cookie,
+		Mask:   network.CookieMaskResource,
+		Flows:  network.WithCookie(flows, cookie),
+	}, nil
+}
+
+// vpcDomain returns the domain the names of a VPC's instances resolve
+// under. A VPC with nothing usable in its name is named by its ID.
+func vpcDomain(vpc *models.VPC) string {
+	label := network.DNSLabel(vpc.Name)
+	if label == "" {
+		label = vpc.ID
+	}
+	return label + "." + network.InternalDomain
+}
+
+// dhcpSubnets returns the subnets of a VPC as DHCP serves them
+func (s *dhcpService) dhcpSubnets(vpcID string) ([]network.DHCPSubnet, error) {
+	subnets, err := s.subnetRepo.ListByVPC(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list subnets", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list subnets")
+	}
+
+	dhcpSubnets := make([]network.DHCPSubnet, len(subnets))
+	for i, subnet := range subnets {
+		dhcpSubnets[i] = network.DHCPSubnet{CIDRBlock: subnet.CIDRBlock}
+		if subnet.IPv6CIDRBlock != nil {
+			dhcpSubnets[i].IPv6CIDRBlock = *subnet.IPv6CIDRBlock
+		}
+	}
+	return dhcpSubnets, nil
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+type ElasticIPService interface {
+	// Pools (administrators only)
+	CreatePool(req *dto.CreatePublicIPPoolRequest) (*dto.PublicIPPoolResponse, error)
+	ListPools() ([]dto.PublicIPPoolResponse, error)
+	DeletePool(id string) error
+
+	// Addresses
+	AllocateElasticIP(userID string, req *dto.AllocateElasticIPRequest) (*models.ElasticIP, error)
+	GetElasticIP(id string, userID string) (*models.ElasticIP, error)
+	ListElasticIPs(userID string, page, pageSize int) (*dto.ElasticIPListResponse, error)
+	ReleaseElasticIP(id string, userID string) error
+	AssociateElasticIP(id string, userID string, instanceID string) (*models.ElasticIP, error)
+	DisassociateElasticIP(id string, userID string) (*models.ElasticIP, error)
+}
+
+type elasticIPService struct {
+	eipRepo    repositories.ElasticIPRepository
+	igwService InternetGatewayService
+	logger     *utils.Logger
+}
+
+func NewElasticIPService(eipRepo repositories.ElasticIPRepository, igwService InternetGatewayService, logger *utils.Logger) ElasticIPService {
+	return &elasticIPService{
+		eipRepo:    eipRepo,
+		igwService: igwService,
+		logger:     logger,
+	}
+}
+
+func (s *elasticIPService) CreatePool(req *dto.CreatePublicIPPoolRequest) (*dto.PublicIPPoolResponse, error) {
+	s.logger.Info("Creating public IP pool", "name", req.Name, "cidr", req.CIDRBlock)
+
+	allocator, err := network.NewPublicIPAllocator(req.CIDRBlock)
+	if err != nil {
+		s.logger.Warn("Invalid public IP pool CIDR", "error", err, "cidr", req.CIDRBlock)
+		return nil, errors.ErrInvalidCIDR
+	}
+	poolNet, _ := network.ParseIPv4CIDR(allocator.Network())
+
+	existing, err := s.eipRepo.GetPoolByName(req.Name)
+	if err != nil {
+		s.logger.Error("Failed to check name conflict", "error", err, "name", req.Name)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check public IP pool name")
+	}
+	if existing != nil {
+		return nil, errors.ErrPublicIPPoolExists
+	}
+
+	// An address must belong to exactly one pool
+	pools, err := s.eipRepo.ListPools()
+	if err != nil {
+		s.logger.Error("Failed to list public IP pools", "error", err)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list public IP pools")
+	}
+	for _, pool := range pools {
+		otherNet, err := network.ParseIPv4CIDR(pool.CIDRBlock)
+		if err != nil {
+			continue
+		}
+		if network.CIDROverlaps(poolNet, otherNet) {
+			s.logger.Warn("Public IP pool overlaps existing pool", "cidr", poolNet.String(), "pool_id", pool.ID)
+			return nil, errors.ErrPublicIPPoolConflict
+		}
+	}
+
+	now := time.Now()
+	pool := &models.PublicIPPool{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		CIDRBlock:   poolNet.String(),
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.eipRepo.CreatePool(pool); err != nil {
+		s.logger.Error("Failed to create public IP pool", "error", err, "pool_id", pool.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create public IP pool")
+	}
+
+	s.logger.Info("Public IP pool created successfully", "pool_id", pool.ID, "cidr", pool.CIDRBlock)
+	resp := dto.ToPublicIPPoolResponse(pool, allocator.Capacity(), 0)
+	return &resp, nil
+}
+
+func (s *elasticIPService) ListPools() ([]dto.PublicIPPoolResponse, error) {
+	pools, err := s.eipRepo.ListPools()
+	if err != nil {
+		s.logger.Error("Failed to list public IP pools", "error", err)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list public IP pools")
+	}
+
+	responses := make([]dto.PublicIPPoolResponse, len(pools))
+	for i := range pools {
+		allocator, err := network.NewPublicIPAllocator(pools[i].CIDRBlock)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_CIDR", "Invalid public IP pool CIDR block")
+		}
+		allocated, err := s.eipRepo.CountByPool(pools[i].ID)
+		if err != nil {
+			s.logger.Error("Failed to count elastic IPs", "error", err, "pool_id", pools[i].ID)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to count elastic IPs")
+		}
+		responses[i] = dto.ToPublicIPPoolResponse(&pools[i], allocator.Capacity(), allocated)
+	}
+
+	return responses, nil
+}
+
+func (s *elasticIPService) DeletePool(id string) error {
+	s.logger.Info("Deleting public IP pool", "pool_id", id)
+
+	pool, err := s.eipRepo.GetPoolByID(id)
+	if err != nil {
+		s.logger.Error("Failed to get public IP pool", "error", err, "pool_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get public IP pool")
+	}
+	if pool == nil {
+		return errors.ErrPublicIPPoolNotFound
+	}
+
+	allocated, err := s.eipRepo.CountByPool(pool.ID)
+	if err != nil {
+		s.logger.Error("Failed to count elastic IPs", "error", err, "pool_id", pool.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check public IP pool usage")
+	}
+	if allocated > 0 {
+		s.logger.Warn("Public IP pool still has allocated addresses", "pool_id", pool.ID, "allocated", allocated)
+		return errors.ErrResourceInUse
+	}
+
+	if err := s.eipRepo.DeletePool(pool.ID); err != nil {
+		s.logger.Error("Failed to delete public IP pool", "error", err, "pool_id", pool.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete public IP pool")
+	}
+
+	s.logger.Info("Public IP pool deleted successfully", "pool_id", pool.ID)
+	return nil
+}
+
+func (s *elasticIPService) AllocateElasticIP(userID string, req *dto.AllocateElasticIPRequest) (*models.ElasticIP, error) {
+	s.logger.Info("Allocating elastic IP", "user_id", userID)
+
+	var pools []models.PublicIPPool
+	if req.PoolID != nil {
+		pool, err := s.eipRepo.GetPoolByID(*req.PoolID)
+		if err != nil {
+			s.logger.Error("Failed to get public IP pool", "error", err, "pool_id", *req.PoolID)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get public IP pool")
+		}
+		if pool == nil {
+			return nil, errors.ErrPublicIPPoolNotFound
+		}
+		pools = append(pools, *pool)
+	} else {
+		var err error
+		pools, err = s.eipRepo.ListPools()
+		if err != nil {
+			s.logger.Error("Failed to list public IP pools", "error", err)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list public IP pools")
+		}
+	}
+
+	// Fall through to the next pool when one is full
+	for _, pool := range pools {
+		allocator, err := network.NewPublicIPAllocator(pool.CIDRBlock)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_CIDR", "Invalid public IP pool CIDR block")
+		}
+
+		pick := func(used []string) (string, error) {
+			ip, err := allocator.NextFree(used)
+			if err != nil {
+				return "", errors.ErrPublicIPPoolExhausted
+			}
+			return ip, nil
+		}
+
+		eip, err := s.eipRepo.Allocate(pool.ID, userID, pick)
+		if err == errors.ErrPublicIPPoolExhausted {
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Failed to allocate elastic IP", "error", err, "pool_id", pool.ID)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to allocate elastic IP")
+		}
+
+		s.logger.Info("Elastic IP allocated", "elastic_ip_id", eip.ID, "public_ip", eip.PublicIP)
+		return eip, nil
+	}
+
+	s.logger.Warn("No free public IP addresses", "user_id", userID)
+	return nil, errors.ErrPublicIPPoolExhausted
+}
+
+func (s *elasticIPService) GetElasticIP(id string, userID string) (*models.ElasticIP, error) {
+	eip, err := s.eipRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get elastic IP", "error", err, "elastic_ip_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get elastic IP")
+	}
+	if eip == nil {
+		return nil, errors.ErrElasticIPNotFound
+	}
+	return eip, nil
+}
+
+func (s *elasticIPService) ListElasticIPs(userID string, page, pageSize int) (*dto.ElasticIPListResponse, error) {
+	s.logger.Info("Listing elastic IPs", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	eips, total, err := s.eipRepo.List(userID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list elastic IPs", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list elastic IPs")
+	}
+
+	eipResponses := make([]dto.ElasticIPResponse, len(eips))
+	for i := range eips {
+		eipResponses[i] = dto.ToElasticIPResponse(&eips[i])
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.ElasticIPListResponse{
+		ElasticIPs: eipResponses,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+func (s *elasticIPService) ReleaseElasticIP(id string, userID string) error {
+	s.logger.Info("Releasing elastic IP", "elastic_ip_id", id, "user_id", userID)
+
+	eip, err := s.GetElasticIP(id, userID)
+	if err != nil {
+		return err
+	}
+	if eip.InstanceID != nil || eip.NATGatewayID != nil {
+		s.logger.Warn("Elastic IP is still associated", "elastic_ip_id", id)
+		return errors.ErrElasticIPAssociated
+	}
+
+	if err := s.eipRepo.Release(eip.ID, userID); err != nil {
+		s.logger.Error("Failed to release elastic IP", "error", err, "elastic_ip_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to release elastic IP")
+	}
+
+	s.logger.Info("Elastic IP released", "elastic_ip_id", id, "public_ip", eip.PublicIP)
+	return nil
+}
+
+// AssociateElasticIP points an elastic IP at an instance. An address that is
+// already associated moves to the new instance: within a VPC its inbound NAT
+// flow is rewritten in place, and between VPCs it is mapped in the new VPC
+// before it is unmapped in the old one, so the address keeps answering
+// throughout. If either VPC cannot be programmed the move is undone.
+func (s *elasticIPService) AssociateElasticIP(id string, userID string, instanceID string) (*models.ElasticIP, error) {
+	s.logger.Info("Associating elastic IP", "elastic_ip_id", id, "instance_id", instanceID)
+
+	eip, err := s.GetElasticIP(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if eip.InstanceID != nil && *eip.InstanceID == instanceID {
+		return eip, nil
+	}
+	if eip.NATGatewayID != nil {
+		s.logger.Warn("Elastic IP is in use by a NAT gateway", "elastic_ip_id", id, "nat_gateway_id", *eip.NATGatewayID)
+		return nil, errors.ErrElasticIPAssociated
+	}
+
+	target, err := s.eipRepo.GetInstanceAddress(instanceID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get instance address", "error", err, "instance_id", instanceID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get instance")
+	}
+	if target == nil || target.PrivateIP == nil {
+		s.logger.Warn("Instance not found or has no private address", "instance_id", instanceID)
+		return nil, errors.ErrInstanceNotFound
+	}
+	if target.PublicIP != nil {
+		s.logger.Warn("Instance already has a public IP", "instance_id", instanceID, "public_ip", *target.PublicIP)
+		return nil, errors.ErrInstanceHasPublicIP
+	}
+
+	// The VPC the address moves to comes first
+	vpcIDs := []string{target.VPCID}
+	if eip.InstanceID != nil {
+		vpcIDs, err = s.withInstanceVPC(vpcIDs, *eip.InstanceID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	associated := false
+	err = s.igwService.UpdateNAT(vpcIDs, func() error {
+		if err := s.eipRepo.Associate(eip.ID, instanceID); err != nil {
+			s.logger.Error("Failed to associate elastic IP", "error", err, "elastic_ip_id", eip.ID)
+			return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to associate elastic IP")
+		}
+		associated = true
+		return nil
+	})
+	if err != nil {
+		if associated {
+			s.rollbackAssociation(eip, vpcIDs)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Elastic IP associated", "elastic_ip_id", eip.ID, "public_ip", eip.PublicIP, "instance_id", instanceID)
+	return s.GetElasticIP(id, userID)
+}
+
+func (s *elasticIPService) DisassociateElasticIP(id string, userID string) (*models.ElasticIP, error) {
+	s.logger.Info("Disassociating elastic IP", "elastic_ip_id", id)
+
+	eip, err := s.GetElasticIP(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if eip.InstanceID == nil {
+		return nil, errors.ErrElasticIPNotAssociated
+	}
+
+	vpcIDs, err := s.withInstanceVPC(nil, *eip.InstanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.igwService.UpdateNAT(vpcIDs, func() error {
+		if err := s.eipRepo.Disassociate(eip.ID); err != nil {
+			s.logger.Error("Failed to disassociate elastic IP", "error", err, "elastic_ip_id", eip.ID)
+			return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to disassociate elastic IP")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Elastic IP disassociated", "elastic_ip_id", eip.ID, "instance_id", *eip.InstanceID)
+	return s.GetElasticIP(id, userID)
+}
+
+// rollbackAssociation gives an elastic IP back to the instance it was
+// associated with, if any, and reprograms the VPCs whose NAT flows the
+// failed association may have changed
+func (s *elasticIPService) rollbackAssociation(eip *models.ElasticIP, vpcIDs []string) {
+	var err error
+	if eip.InstanceID != nil {
+		err = s.eipRepo.Associate(eip.ID, *eip.InstanceID)
+	} else {
+		err = s.eipRepo.Disassociate(eip.ID)
+	}
+	if err != nil {
+		s.logger.Error("Failed to rollback elastic IP association", "error", err, "elastic_ip_id", eip.ID)
+		return
+	}
+
+	for _, vpcID := range vpcIDs {
+		if err := s.igwService.SyncNAT(vpcID); err != nil {
+			s.logger.Error("Failed to rollback NAT flows", "error", err, "vpc_id", vpcID)
+		}
+	}
+}
+
+// withInstanceVPC adds the VPC of an instance to vpcIDs. Terminated instances
+// have no NAT flows and add nothing.
+func (s *elasticIPService) withInstanceVPC(vpcIDs []string, instanceID string, userID string) ([]string, error) {
+	address, err := s.eipRepo.GetInstanceAddress(instanceID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get instance address", "error", err, "instance_id", instanceID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get instance")
+	}
+	if address == nil {
+		return vpcIDs, nil
+	}
+	for _, vpcID := range vpcIDs {
+		if vpcID == address.VPCID {
+			return vpcIDs, nil
+		}
+	}
+	return append(vpcIDs, address.VPCID), nil
+}
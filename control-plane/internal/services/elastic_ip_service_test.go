@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+)
+
+const testPublicIP = "198.51.100.7"
+
+// elasticIPMove is an elastic IP associated with an instance in one VPC, about
+// to move to an instance in another
+type elasticIPMove struct {
+	d          *dataplane
+	eip        *models.ElasticIP
+	from, to   *models.VPC
+	oldID      string
+	newID      string
+	oldPrivate string
+	newPrivate string
+}
+
+func newElasticIPMove(t *testing.T) *elasticIPMove {
+	d := newDataplane(t, "10.0.0.0/16")
+	other := d.addVPC("other", "10.1.0.0/16")
+	d.attachInternetGateway(d.vpc)
+	d.attachInternetGateway(other)
+
+	m := &elasticIPMove{
+		d:          d,
+		eip:        &models.ElasticIP{ID: "eip-1", PublicIP: testPublicIP, UserID: testUserID},
+		from:       d.vpc,
+		to:         other,
+		oldPrivate: "10.0.1.5",
+		newPrivate: "10.1.1.5",
+	}
+	m.oldID = d.addInstance(d.addSubnet("10.0.1.0/24"), m.oldPrivate)
+	m.newID = d.addInstance(d.addSubnet("10.1.1.0/24"), m.newPrivate)
+	d.store.elasticIPs[m.eip.ID] = m.eip
+
+	if _, err := d.elasticIPs.AssociateElasticIP(m.eip.ID, testUserID, m.oldID); err != nil {
+		t.Fatalf("AssociateElasticIP: %v", err)
+	}
+	d.expectDelivered(d.receive(m.from, "192.0.2.1", testPublicIP, 22), m.oldPrivate)
+	d.expectDropped(d.receive(m.to, "192.0.2.1", testPublicIP, 22))
+	return m
+}
+
+// failNATOnce fails the first NAT flow replacement on a bridge
+func (m *elasticIPMove) failNATOnce(bridgeName string) {
+	failed := false
+	m.d.ovs.fail = func(bridge string, cookie string) error {
+		if failed || bridge != bridgeName || cookie != network.KindCookie(network.CookieKindInternetGateway) {
+			return nil
+		}
+		failed = true
+		return fmt.Errorf("bundle failed on %s", bridge)
+	}
+}
+
+func TestElasticIPMoveBetweenVPCs(t *testing.T) {
+	m := newElasticIPMove(t)
+	d := m.d
+
+	// When the address is unmapped in the old VPC it must already be
+	// mapped in the new one
+	checked := false
+	d.ovs.fail = func(bridge string, cookie string) error {
+		if bridge == m.from.Dataplane.BridgeName && cookie == network.KindCookie(network.CookieKindInternetGateway) {
+			checked = true
+			d.expectDelivered(d.receive(m.to, "192.0.2.1", testPublicIP, 22), m.newPrivate)
+		}
+		return nil
+	}
+
+	if _, err := d.elasticIPs.AssociateElasticIP(m.eip.ID, testUserID, m.newID); err != nil {
+		t.Fatalf("AssociateElasticIP: %v", err)
+	}
+	if !checked {
+		t.Fatal("NAT flows of the old VPC were not reprogrammed")
+	}
+
+	d.expectDelivered(d.receive(m.to, "192.0.2.1", testPublicIP, 22), m.newPrivate)
+	d.expectDropped(d.receive(m.from, "192.0.2.1", testPublicIP, 22))
+}
+
+func TestElasticIPMoveRollsBack(t *testing.T) {
+	tests := []struct {
+		name   string
+		bridge func(m *elasticIPMove) string
+	}{
+		{name: "new VPC fails", bridge: func(m *elasticIPMove) string { return m.to.Dataplane.BridgeName }},
+		{name: "old VPC fails", bridge: func(m *elasticIPMove) string { return m.from.Dataplane.BridgeName }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newElasticIPMove(t)
+			d := m.d
+			m.failNATOnce(tt.bridge(m))
+
+			if _, err := d.elasticIPs.AssociateElasticIP(m.eip.ID, testUserID, m.newID); err == nil {
+				t.Fatal("AssociateElasticIP succeeded")
+			}
+
+			if instanceID := d.store.elasticIPs[m.eip.ID].InstanceID; instanceID == nil || *instanceID != m.oldID {
+				t.Errorf("elastic IP associated with %v, want %s", instanceID, m.oldID)
+			}
+			d.expectDelivered(d.receive(m.from, "192.0.2.1", testPublicIP, 22), m.oldPrivate)
+			d.expectDropped(d.receive(m.to, "192.0.2.1", testPublicIP, 22))
+		})
+	}
+}
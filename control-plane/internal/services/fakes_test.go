@@ -0,0 +1,603 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+)
+
+// fakeStore is an in-memory stand-in for the database behind the fake
+// repositories. Each fake implements the repository methods the services
+// under test call; the embedded interface makes any other call panic.
+type fakeStore struct {
+	vpcs        map[string]*models.VPC
+	dataplanes  map[string]*models.VPCDataplane
+	subnets     map[string]*models.Subnet
+	allocations []fakeAllocation
+
+	routeTables       map[string]*models.RouteTable
+	routes            []models.Route
+	routeAssociations map[string]string // subnet ID to route table ID
+	igws              map[string]*models.InternetGateway
+	natGateways       map[string]*models.NATGateway
+	elasticIPs        map[string]*models.ElasticIP
+
+	groups  []models.SecurityGroup
+	rules   []models.SecurityGroupRule
+	members map[string][]string // group ID to instance IDs
+
+	acls            []models.NetworkACL
+	aclEntries      []models.NetworkACLEntry
+	aclAssociations map[string]string // subnet ID to network ACL ID
+
+	sequence int
+}
+
+// fakeAllocation is an address reserved in a subnet for an instance
+type fakeAllocation struct {
+	subnetID   string
+	instanceID string
+	ip         string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		vpcs:              make(map[string]*models.VPC),
+		dataplanes:        make(map[string]*models.VPCDataplane),
+		subnets:           make(map[string]*models.Subnet),
+		routeTables:       make(map[string]*models.RouteTable),
+		routeAssociations: make(map[string]string),
+		igws:              make(map[string]*models.InternetGateway),
+		natGateways:       make(map[string]*models.NATGateway),
+		elasticIPs:        make(map[string]*models.ElasticIP),
+		members:           make(map[string][]string),
+		aclAssociations:   make(map[string]string),
+	}
+}
+
+func (s *fakeStore) next() int {
+	s.sequence++
+	return s.sequence
+}
+
+// publicIP returns the elastic IP associated with an instance, if any
+func (s *fakeStore) publicIP(instanceID string) *string {
+	for _, eip := range s.elasticIPs {
+		if eip.InstanceID != nil && *eip.InstanceID == instanceID {
+			publicIP := eip.PublicIP
+			return &publicIP
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) subnetVPC(subnetID string) string {
+	if subnet, ok := s.subnets[subnetID]; ok {
+		return subnet.VPCID
+	}
+	return ""
+}
+
+type fakeVPCRepo struct {
+	repositories.VPCRepository
+	store *fakeStore
+}
+
+func (r *fakeVPCRepo) Create(vpc *models.VPC) error {
+	stored := *vpc
+	r.store.vpcs[vpc.ID] = &stored
+	return nil
+}
+
+func (r *fakeVPCRepo) GetByID(id string, userID string) (*models.VPC, error) {
+	vpc, ok := r.store.vpcs[id]
+	if !ok || vpc.UserID != userID {
+		return nil, nil
+	}
+	found := *vpc
+	return &found, nil
+}
+
+func (r *fakeVPCRepo) GetByName(name string, userID string) (*models.VPC, error) {
+	for _, vpc := range r.store.vpcs {
+		if vpc.Name == name && vpc.UserID == userID {
+			found := *vpc
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeVPCRepo) Delete(id string, userID string) error {
+	delete(r.store.vpcs, id)
+	delete(r.store.dataplanes, id)
+	return nil
+}
+
+func (r *fakeVPCRepo) CheckCIDRConflict(cidrBlock string, userID string, excludeID *string) (bool, error) {
+	_, block, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		return false, err
+	}
+	for _, vpc := range r.store.vpcs {
+		if vpc.UserID != userID || (excludeID != nil && vpc.ID == *excludeID) {
+			continue
+		}
+		_, other, err := net.ParseCIDR(vpc.CIDRBlock)
+		if err != nil {
+			return false, err
+		}
+		if other.Contains(block.IP) || block.Contains(other.IP) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeVPCRepo) AllocateDataplane(vpcID string) (*models.VPCDataplane, error) {
+	n := r.store.next()
+	dataplane := &models.VPCDataplane{
+		VPCID:      vpcID,
+		BridgeName: fmt.Sprintf("gcp-vpc-%07x", n),
+		DatapathID: fmt.Sprintf("%016x", n),
+	}
+	r.store.dataplanes[vpcID] = dataplane
+	found := *dataplane
+	return &found, nil
+}
+
+func (r *fakeVPCRepo) GetDataplane(vpcID string) (*models.VPCDataplane, error) {
+	dataplane, ok := r.store.dataplanes[vpcID]
+	if !ok {
+		return nil, nil
+	}
+	found := *dataplane
+	return &found, nil
+}
+
+func (r *fakeVPCRepo) AllocateConntrackZone(vpcID string) (int, error) {
+	zone := r.store.next()
+	r.store.dataplanes[vpcID].ConntrackZone = zone
+	return zone, nil
+}
+
+func (r *fakeVPCRepo) AllocateVNI(vpcID string) (int, error) {
+	vni := 1000 + r.store.next()
+	r.store.dataplanes[vpcID].VNI = vni
+	return vni, nil
+}
+
+type fakeWorkerNodeRepo struct {
+	repositories.WorkerNodeRepository
+	store *fakeStore
+}
+
+func (r *fakeWorkerNodeRepo) GetVPCTunnel(vpcID string) (*repositories.VPCTunnel, error) {
+	dataplane, ok := r.store.dataplanes[vpcID]
+	if !ok {
+		return nil, nil
+	}
+	return &repositories.VPCTunnel{
+		VPCID:      vpcID,
+		BridgeName: dataplane.BridgeName,
+		VNI:        dataplane.VNI,
+		Zone:       dataplane.ConntrackZone,
+	}, nil
+}
+
+func (r *fakeWorkerNodeRepo) ListPeers(localName string) ([]models.WorkerNode, error) {
+	return nil, nil
+}
+
+func (r *fakeWorkerNodeRepo) ListRemoteEndpoints(vpcID string, localName string) ([]repositories.TunnelEndpoint, error) {
+	return nil, nil
+}
+
+type fakeSubnetRepo struct {
+	repositories.SubnetRepository
+	store *fakeStore
+}
+
+func (r *fakeSubnetRepo) GetByID(id string, userID string) (*models.Subnet, error) {
+	subnet, ok := r.store.subnets[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *subnet
+	return &found, nil
+}
+
+func (r *fakeSubnetRepo) ListByVPC(vpcID string) ([]models.Subnet, error) {
+	subnets := make([]models.Subnet, 0)
+	for _, subnet := range r.store.subnets {
+		if subnet.VPCID == vpcID {
+			subnets = append(subnets, *subnet)
+		}
+	}
+	sort.Slice(subnets, func(i, j int) bool { return subnets[i].CIDRBlock < subnets[j].CIDRBlock })
+	return subnets, nil
+}
+
+type fakeIPAllocationRepo struct {
+	repositories.IPAllocationRepository
+	store *fakeStore
+}
+
+func (r *fakeIPAllocationRepo) ListInstanceIPsInVPC(instanceID string, vpcID string) ([]string, error) {
+	ips := make([]string, 0)
+	for _, allocation := range r.store.allocations {
+		if allocation.instanceID == instanceID && r.store.subnetVPC(allocation.subnetID) == vpcID {
+			ips = append(ips, allocation.ip)
+		}
+	}
+	return ips, nil
+}
+
+func (r *fakeIPAllocationRepo) ListInstanceReservationsInVPC(vpcID string) ([]repositories.InstanceReservation, error) {
+	reservations := make([]repositories.InstanceReservation, 0)
+	for _, allocation := range r.store.allocations {
+		if r.store.subnetVPC(allocation.subnetID) == vpcID {
+			reservations = append(reservations, repositories.InstanceReservation{
+				InstanceID: allocation.instanceID,
+				IPAddress:  allocation.ip,
+			})
+		}
+	}
+	return reservations, nil
+}
+
+type fakeRouteTableRepo struct {
+	repositories.RouteTableRepository
+	store *fakeStore
+}
+
+func (r *fakeRouteTableRepo) Create(routeTable *models.RouteTable) error {
+	stored := *routeTable
+	r.store.routeTables[routeTable.ID] = &stored
+	return nil
+}
+
+func (r *fakeRouteTableRepo) GetByID(id string, userID string) (*models.RouteTable, error) {
+	routeTable, ok := r.store.routeTables[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *routeTable
+	return &found, nil
+}
+
+func (r *fakeRouteTableRepo) GetMain(vpcID string) (*models.RouteTable, error) {
+	for _, routeTable := range r.store.routeTables {
+		if routeTable.VPCID == vpcID && routeTable.IsMain {
+			found := *routeTable
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRouteTableRepo) CreateRoute(route *models.Route) error {
+	r.store.routes = append(r.store.routes, *route)
+	return nil
+}
+
+func (r *fakeRouteTableRepo) GetRouteByDestination(routeTableID string, destinationCIDR string) (*models.Route, error) {
+	for _, route := range r.store.routes {
+		if route.RouteTableID == routeTableID && route.DestinationCIDR == destinationCIDR {
+			found := route
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRouteTableRepo) DeleteRoute(routeTableID string, routeID string) error {
+	for i, route := range r.store.routes {
+		if route.RouteTableID == routeTableID && route.ID == routeID {
+			r.store.routes = append(r.store.routes[:i], r.store.routes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *fakeRouteTableRepo) ListRoutesByVPC(vpcID string) ([]models.Route, error) {
+	routes := make([]models.Route, 0)
+	for _, route := range r.store.routes {
+		if routeTable, ok := r.store.routeTables[route.RouteTableID]; ok && routeTable.VPCID == vpcID {
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+func (r *fakeRouteTableRepo) ListSubnetRouteTables(vpcID string) ([]repositories.SubnetRouteTable, error) {
+	pairs := make([]repositories.SubnetRouteTable, 0)
+	for subnetID, routeTableID := range r.store.routeAssociations {
+		subnet, ok := r.store.subnets[subnetID]
+		if !ok || subnet.VPCID != vpcID {
+			continue
+		}
+		pairs = append(pairs, repositories.SubnetRouteTable{
+			SubnetID:      subnet.ID,
+			CIDRBlock:     subnet.CIDRBlock,
+			IPv6CIDRBlock: subnet.IPv6CIDRBlock,
+			RouteTableID:  routeTableID,
+		})
+	}
+	return pairs, nil
+}
+
+type fakeInternetGatewayRepo struct {
+	repositories.InternetGatewayRepository
+	store *fakeStore
+}
+
+func (r *fakeInternetGatewayRepo) GetByID(id string, userID string) (*models.InternetGateway, error) {
+	igw, ok := r.store.igws[id]
+	if !ok || igw.UserID != userID {
+		return nil, nil
+	}
+	found := *igw
+	return &found, nil
+}
+
+func (r *fakeInternetGatewayRepo) Attach(id string, vpcID string) error {
+	r.store.igws[id].VPCID = &vpcID
+	return nil
+}
+
+func (r *fakeInternetGatewayRepo) Detach(id string) error {
+	r.store.igws[id].VPCID = nil
+	return nil
+}
+
+func (r *fakeInternetGatewayRepo) ListPublicIPMappings(vpcID string) ([]repositories.PublicIPMapping, error) {
+	mappings := make([]repositories.PublicIPMapping, 0)
+	for _, allocation := range r.store.allocations {
+		if r.store.subnetVPC(allocation.subnetID) != vpcID {
+			continue
+		}
+		if publicIP := r.store.publicIP(allocation.instanceID); publicIP != nil {
+			mappings = append(mappings, repositories.PublicIPMapping{PrivateIP: allocation.ip, PublicIP: *publicIP})
+		}
+	}
+	return mappings, nil
+}
+
+func (r *fakeInternetGatewayRepo) GetByVPC(vpcID string) (*models.InternetGateway, error) {
+	for _, igw := range r.store.igws {
+		if igw.VPCID != nil && *igw.VPCID == vpcID {
+			found := *igw
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+type fakeNATGatewayRepo struct {
+	repositories.NATGatewayRepository
+	store *fakeStore
+}
+
+func (r *fakeNATGatewayRepo) GetInVPC(id string, vpcID string) (*models.NATGateway, error) {
+	natGateway, ok := r.store.natGateways[id]
+	if !ok || natGateway.VPCID != vpcID {
+		return nil, nil
+	}
+	found := *natGateway
+	return &found, nil
+}
+
+func (r *fakeNATGatewayRepo) ListByVPC(vpcID string) ([]models.NATGateway, error) {
+	natGateways := make([]models.NATGateway, 0)
+	for _, natGateway := range r.store.natGateways {
+		if natGateway.VPCID == vpcID {
+			natGateways = append(natGateways, *natGateway)
+		}
+	}
+	return natGateways, nil
+}
+
+type fakeSecurityGroupRepo struct {
+	repositories.SecurityGroupRepository
+	store *fakeStore
+}
+
+func (r *fakeSecurityGroupRepo) Create(sg *models.SecurityGroup) error {
+	r.store.groups = append(r.store.groups, *sg)
+	return nil
+}
+
+func (r *fakeSecurityGroupRepo) GetByID(id string, userID string) (*models.SecurityGroup, error) {
+	for _, sg := range r.store.groups {
+		if sg.ID == id && sg.UserID == userID {
+			found := sg
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSecurityGroupRepo) GetByName(vpcID string, name string) (*models.SecurityGroup, error) {
+	for _, sg := range r.store.groups {
+		if sg.VPCID == vpcID && sg.Name == name {
+			found := sg
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSecurityGroupRepo) ListByVPC(vpcID string) ([]models.SecurityGroup, error) {
+	groups := make([]models.SecurityGroup, 0)
+	for _, sg := range r.store.groups {
+		if sg.VPCID == vpcID {
+			groups = append(groups, sg)
+		}
+	}
+	return groups, nil
+}
+
+func (r *fakeSecurityGroupRepo) CreateRule(rule *models.SecurityGroupRule) error {
+	r.store.rules = append(r.store.rules, *rule)
+	return nil
+}
+
+func (r *fakeSecurityGroupRepo) ListRules(securityGroupID string) ([]models.SecurityGroupRule, error) {
+	rules := make([]models.SecurityGroupRule, 0)
+	for _, rule := range r.store.rules {
+		if rule.SecurityGroupID == securityGroupID {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (r *fakeSecurityGroupRepo) AddMember(securityGroupID string, instanceID string) error {
+	r.store.members[securityGroupID] = append(r.store.members[securityGroupID], instanceID)
+	return nil
+}
+
+func (r *fakeSecurityGroupRepo) ListMemberIPs(securityGroupID string) ([]string, error) {
+	ips := make([]string, 0)
+	for _, instanceID := range r.store.members[securityGroupID] {
+		for _, allocation := range r.store.allocations {
+			if allocation.instanceID == instanceID {
+				ips = append(ips, allocation.ip)
+			}
+		}
+	}
+	return ips, nil
+}
+
+type fakeNetworkACLRepo struct {
+	repositories.NetworkACLRepository
+	store *fakeStore
+}
+
+func (r *fakeNetworkACLRepo) Create(acl *models.NetworkACL) error {
+	r.store.acls = append(r.store.acls, *acl)
+	return nil
+}
+
+func (r *fakeNetworkACLRepo) GetByID(id string, userID string) (*models.NetworkACL, error) {
+	for _, acl := range r.store.acls {
+		if acl.ID == id && acl.UserID == userID {
+			found := acl
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeNetworkACLRepo) GetByName(vpcID string, name string) (*models.NetworkACL, error) {
+	for _, acl := range r.store.acls {
+		if acl.VPCID == vpcID && acl.Name == name {
+			found := acl
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeNetworkACLRepo) ListByVPC(vpcID string) ([]models.NetworkACL, error) {
+	acls := make([]models.NetworkACL, 0)
+	for _, acl := range r.store.acls {
+		if acl.VPCID == vpcID {
+			acls = append(acls, acl)
+		}
+	}
+	return acls, nil
+}
+
+func (r *fakeNetworkACLRepo) CreateEntry(entry *models.NetworkACLEntry) error {
+	r.store.aclEntries = append(r.store.aclEntries, *entry)
+	return nil
+}
+
+func (r *fakeNetworkACLRepo) GetEntryByRuleNumber(networkACLID string, direction string, ruleNumber int) (*models.NetworkACLEntry, error) {
+	for _, entry := range r.store.aclEntries {
+		if entry.NetworkACLID == networkACLID && entry.Direction == direction && entry.RuleNumber == ruleNumber {
+			found := entry
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeNetworkACLRepo) ListEntries(networkACLID string) ([]models.NetworkACLEntry, error) {
+	entries := make([]models.NetworkACLEntry, 0)
+	for _, entry := range r.store.aclEntries {
+		if entry.NetworkACLID == networkACLID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].RuleNumber < entries[j].RuleNumber })
+	return entries, nil
+}
+
+func (r *fakeNetworkACLRepo) Associate(networkACLID string, subnetID string) error {
+	r.store.aclAssociations[subnetID] = networkACLID
+	return nil
+}
+
+func (r *fakeNetworkACLRepo) GetAssociatedACLID(subnetID string) (*string, error) {
+	aclID, ok := r.store.aclAssociations[subnetID]
+	if !ok {
+		return nil, nil
+	}
+	return &aclID, nil
+}
+
+func (r *fakeNetworkACLRepo) ListAssociatedSubnets(networkACLID string) ([]models.Subnet, error) {
+	subnets := make([]models.Subnet, 0)
+	for subnetID, aclID := range r.store.aclAssociations {
+		if subnet, ok := r.store.subnets[subnetID]; ok && aclID == networkACLID {
+			subnets = append(subnets, *subnet)
+		}
+	}
+	return subnets, nil
+}
+
+type fakeElasticIPRepo struct {
+	repositories.ElasticIPRepository
+	store *fakeStore
+}
+
+func (r *fakeElasticIPRepo) GetByID(id string, userID string) (*models.ElasticIP, error) {
+	eip, ok := r.store.elasticIPs[id]
+	if !ok || eip.UserID != userID {
+		return nil, nil
+	}
+	found := *eip
+	return &found, nil
+}
+
+func (r *fakeElasticIPRepo) GetInstanceAddress(instanceID string, userID string) (*repositories.InstanceAddress, error) {
+	for _, allocation := range r.store.allocations {
+		if allocation.instanceID == instanceID {
+			privateIP := allocation.ip
+			return &repositories.InstanceAddress{
+				InstanceID: instanceID,
+				VPCID:      r.store.subnetVPC(allocation.subnetID),
+				PrivateIP:  &privateIP,
+				PublicIP:   r.store.publicIP(instanceID),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeElasticIPRepo) Associate(id string, instanceID string) error {
+	r.store.elasticIPs[id].InstanceID = &instanceID
+	return nil
+}
+
+func (r *fakeElasticIPRepo) Disassociate(id string) error {
+	r.store.elasticIPs[id].InstanceID = nil
+	return nil
+}
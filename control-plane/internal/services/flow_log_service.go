@@ -0,0 +1,429 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// Resources a flow log can capture the traffic of
+const (
+	FlowLogResourceVPC      = "vpc"
+	FlowLogResourceSubnet   = "subnet"
+	FlowLogResourceInstance = "instance"
+)
+
+// Flow log defaults and the flow cache of the bridges exporting records
+const (
+	DefaultFlowLogRetentionDays = 14
+	flowLogActiveTimeout        = 60 // seconds
+	flowLogCacheMaxFlows        = 4096
+)
+
+// FlowLogService records the traffic of VPCs, subnets and instance
+// interfaces. The bridge of a VPC samples the traffic of the ranges its flow
+// logs cover as the network ACLs and security groups accept or reject it,
+// and exports the samples over IPFIX to the network controller of its node,
+// which stores them as records. Records older than the retention of their
+// flow log are purged.
+type FlowLogService interface {
+	CreateFlowLog(userID string, req *dto.CreateFlowLogRequest) (*models.FlowLog, error)
+	GetFlowLog(id string, userID string) (*models.FlowLog, error)
+	ListFlowLogs(userID string, vpcID *string, page, pageSize int) (*dto.FlowLogListResponse, error)
+	DeleteFlowLog(id string, userID string) error
+	ListRecords(userID string, query *dto.FlowLogRecordQuery, page, pageSize int) (*dto.FlowLogRecordListResponse, error)
+	DesiredFlows(vpc *models.VPC) (*network.FlowSet, error)
+	DesiredIPFIXExport() *network.IPFIXExport
+	IngestRecords(nodeName string, records []network.IPFIXRecord) error
+	PurgeExpiredRecords() error
+}
+
+type flowLogService struct {
+	flowLogRepo  repositories.FlowLogRepository
+	vpcRepo      repositories.VPCRepository
+	subnetRepo   repositories.SubnetRepository
+	instanceRepo repositories.InstanceRepository
+	ovsManager   network.OVSManager
+	collector    string
+	logger       *utils.Logger
+}
+
+// NewFlowLogService creates the flow log service. collector is the UDP
+// address bridges export samples to; when it is empty bridges export
+// nothing, though flow logs can still be managed.
+func NewFlowLogService(
+	flowLogRepo repositories.FlowLogRepository,
+	vpcRepo repositories.VPCRepository,
+	subnetRepo repositories.SubnetRepository,
+	instanceRepo repositories.InstanceRepository,
+	ovsManager network.OVSManager,
+	collector string,
+	logger *utils.Logger,
+) FlowLogService {
+	return &flowLogService{
+		flowLogRepo:  flowLogRepo,
+		vpcRepo:      vpcRepo,
+		subnetRepo:   subnetRepo,
+		instanceRepo: instanceRepo,
+		ovsManager:   ovsManager,
+		collector:    collector,
+		logger:       logger,
+	}
+}
+
+// CreateFlowLog turns on a flow log for one of the user's VPCs, subnets or
+// instances and starts sampling its traffic
+func (s *flowLogService) CreateFlowLog(userID string, req *dto.CreateFlowLogRequest) (*models.FlowLog, error) {
+	s.logger.Info("Creating flow log", "user_id", userID, "resource_type", req.ResourceType, "resource_id", req.ResourceID)
+
+	vpcID, err := s.resourceVPC(userID, req.ResourceType, req.ResourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	trafficType := req.TrafficType
+	if trafficType == "" {
+		trafficType = network.FlowLogTrafficAll
+	}
+	retentionDays := req.RetentionDays
+	if retentionDays == 0 {
+		retentionDays = DefaultFlowLogRetentionDays
+	}
+
+	now := time.Now()
+	flowLog := &models.FlowLog{
+		ID:            uuid.New().String(),
+		Name:          req.Name,
+		UserID:        userID,
+		VPCID:         vpcID,
+		ResourceType:  req.ResourceType,
+		ResourceID:    req.ResourceID,
+		TrafficType:   trafficType,
+		RetentionDays: retentionDays,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := s.flowLogRepo.Create(flowLog); err != nil {
+		s.logger.Error("Failed to create flow log in database", "error", err, "flow_log_id", flowLog.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create flow log")
+	}
+
+	if err := s.syncFlows(vpcID, userID); err != nil {
+		// Rollback database changes
+		if delErr := s.flowLogRepo.Delete(flowLog.ID); delErr != nil {
+			s.logger.Error("Failed to rollback flow log creation", "error", delErr, "flow_log_id", flowLog.ID)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Flow log created successfully", "flow_log_id", flowLog.ID, "number", flowLog.Number)
+	return flowLog, nil
+}
+
+func (s *flowLogService) GetFlowLog(id string, userID string) (*models.FlowLog, error) {
+	flowLog, err := s.flowLogRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get flow log", "error", err, "flow_log_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get flow log")
+	}
+	if flowLog == nil {
+		return nil, errors.ErrFlowLogNotFound
+	}
+	return flowLog, nil
+}
+
+func (s *flowLogService) ListFlowLogs(userID string, vpcID *string, page, pageSize int) (*dto.FlowLogListResponse, error) {
+	s.logger.Info("Listing flow logs", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	flowLogs, total, err := s.flowLogRepo.List(userID, vpcID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list flow logs", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list flow logs")
+	}
+
+	flowLogResponses := make([]dto.FlowLogResponse, len(flowLogs))
+	for i := range flowLogs {
+		flowLogResponses[i] = dto.ToFlowLogResponse(&flowLogs[i])
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.FlowLogListResponse{
+		FlowLogs:   flowLogResponses,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// DeleteFlowLog stops sampling the traffic of a flow log and deletes it
+// along with its records
+func (s *flowLogService) DeleteFlowLog(id string, userID string) error {
+	s.logger.Info("Deleting flow log", "flow_log_id", id, "user_id", userID)
+
+	flowLog, err := s.GetFlowLog(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.flowLogRepo.Delete(flowLog.ID); err != nil {
+		s.logger.Error("Failed to delete flow log", "error", err, "flow_log_id", flowLog.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete flow log")
+	}
+
+	if err := s.syncFlows(flowLog.VPCID, userID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Flow log deleted successfully", "flow_log_id", flowLog.ID)
+	return nil
+}
+
+// ListRecords returns the records of the user's flow logs that match a
+// query, latest first
+func (s *flowLogService) ListRecords(userID string, query *dto.FlowLogRecordQuery, page, pageSize int) (*dto.FlowLogRecordListResponse, error) {
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := repositories.FlowLogRecordFilter{
+		FlowLogID: query.FlowLogID,
+		VPCID:     query.VPCID,
+		Action:    query.Action,
+		Protocol:  query.Protocol,
+		SrcIP:     query.SrcIP,
+		DstIP:     query.DstIP,
+		Port:      query.Port,
+		StartTime: query.StartTime,
+		EndTime:   query.EndTime,
+	}
+	records, total, err := s.flowLogRepo.ListRecords(userID, filter, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list flow log records", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list flow log records")
+	}
+	if records == nil {
+		records = []models.FlowLogRecord{}
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.FlowLogRecordListResponse{
+		Records:    records,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// DesiredFlows returns the flows that sample the traffic of the flow logs
+// of a VPC
+func (s *flowLogService) DesiredFlows(vpc *models.VPC) (*network.FlowSet, error) {
+	dataplane, err := s.vpcRepo.GetDataplane(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC dataplane", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC dataplane")
+	}
+	if dataplane == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+
+	rows, err := s.flowLogRepo.ListScopes(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to list flow log scopes", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list flow log scopes")
+	}
+	scopes := make([]network.FlowLogScope, len(rows))
+	for i, row := range rows {
+		scopes[i] = network.FlowLogScope{Number: row.Number, CIDRBlock: row.CIDRBlock, TrafficType: row.TrafficType}
+	}
+
+	flows, err := network.CompileFlowLogs(dataplane.ConntrackZone, scopes)
+	if err != nil {
+		s.logger.Error("Failed to compile flow log flows", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile flow log flows")
+	}
+
+	cookie := network.FlowCookie(network.CookieKindFlowLog, vpc.ID)
+	return &network.FlowSet{
+		Cookie: cookie,
+		Mask:   network.CookieMaskResource,
+		Flows:  network.WithCookie(flows, cookie),
+	}, nil
+}
+
+// DesiredIPFIXExport returns the collector set every VPC bridge exports
+// flow log samples through, or nil when no collector is configured
+func (s *flowLogService) DesiredIPFIXExport() *network.IPFIXExport {
+	if s.collector == "" {
+		return nil
+	}
+	return &network.IPFIXExport{
+		CollectorSetID:     network.FlowLogCollectorSetID,
+		Target:             s.collector,
+		CacheActiveTimeout: flowLogActiveTimeout,
+		CacheMaxFlows:      flowLogCacheMaxFlows,
+	}
+}
+
+// IngestRecords stores the IPFIX records a node's bridges exported. Records
+// of flow logs deleted since their traffic was sampled are dropped.
+func (s *flowLogService) IngestRecords(nodeName string, records []network.IPFIXRecord) error {
+	now := time.Now()
+	samples := make([]repositories.FlowLogSample, 0, len(records))
+	for _, record := range records {
+		number, action := network.ParseFlowLogObservationPoint(record.ObservationPoint)
+		if number == 0 || record.SrcIP == "" || record.DstIP == "" {
+			continue
+		}
+		start, end := record.Start, record.End
+		if end.IsZero() {
+			end = now
+		}
+		if start.IsZero() || start.After(end) {
+			start = end
+		}
+		samples = append(samples, repositories.FlowLogSample{
+			Number:        number,
+			ConntrackZone: int(record.ObservationDomain),
+			Action:        action,
+			Protocol:      int(record.Protocol),
+			SrcIP:         record.SrcIP,
+			SrcPort:       int(record.SrcPort),
+			DstIP:         record.DstIP,
+			DstPort:       int(record.DstPort),
+			Packets:       int64(record.Packets),
+			Bytes:         int64(record.Bytes),
+			StartTime:     start,
+			EndTime:       end,
+			NodeName:      nodeName,
+		})
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	stored, err := s.flowLogRepo.InsertRecords(samples)
+	if err != nil {
+		s.logger.Error("Failed to store flow log records", "error", err, "records", len(samples))
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to store flow log records")
+	}
+	if dropped := len(samples) - stored; dropped > 0 {
+		s.logger.Debug("Dropped records of deleted flow logs", "records", dropped)
+	}
+	return nil
+}
+
+// PurgeExpiredRecords deletes the records older than the retention of their
+// flow log
+func (s *flowLogService) PurgeExpiredRecords() error {
+	deleted, err := s.flowLogRepo.DeleteExpiredRecords()
+	if err != nil {
+		s.logger.Error("Failed to purge expired flow log records", "error", err)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to purge expired flow log records")
+	}
+	if deleted > 0 {
+		s.logger.Info("Purged expired flow log records", "records", deleted)
+	}
+	return nil
+}
+
+// resourceVPC returns the VPC of a resource of the user a flow log is to
+// capture the traffic of
+func (s *flowLogService) resourceVPC(userID string, resourceType string, resourceID string) (string, error) {
+	switch resourceType {
+	case FlowLogResourceVPC:
+		vpc, err := s.getVPC(resourceID, userID)
+		if err != nil {
+			return "", err
+		}
+		return vpc.ID, nil
+	case FlowLogResourceSubnet:
+		return s.subnetVPC(resourceID, userID)
+	case FlowLogResourceInstance:
+		instance, err := s.instanceRepo.GetByID(resourceID, userID)
+		if err != nil {
+			s.logger.Error("Failed to get instance", "error", err, "instance_id", resourceID)
+			return "", errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get instance")
+		}
+		if instance == nil {
+			return "", errors.ErrInstanceNotFound
+		}
+		if instance.SubnetID == "" {
+			return "", errors.ErrFlowLogNoInterface
+		}
+		return s.subnetVPC(instance.SubnetID, userID)
+	default:
+		return "", errors.ErrInvalidParameter
+	}
+}
+
+func (s *flowLogService) subnetVPC(subnetID string, userID string) (string, error) {
+	subnet, err := s.subnetRepo.GetByID(subnetID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet", "error", err, "subnet_id", subnetID)
+		return "", errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet")
+	}
+	if subnet == nil {
+		return "", errors.ErrSubnetNotFound
+	}
+	return subnet.VPCID, nil
+}
+
+// syncFlows replaces the flow log flows of a VPC bridge in one bundle
+func (s *flowLogService) syncFlows(vpcID string, userID string) error {
+	vpc, err := s.getVPC(vpcID, userID)
+	if err != nil {
+		return err
+	}
+
+	set, err := s.DesiredFlows(vpc)
+	if err != nil {
+		return err
+	}
+
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpc.ID)
+	if err != nil {
+		return err
+	}
+	if err := set.Apply(s.ovsManager, bridgeName); err != nil {
+		s.logger.Error("Failed to program flow log flows", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to program flow log flows")
+	}
+	return nil
+}
+
+func (s *flowLogService) getVPC(vpcID string, userID string) (*models.VPC, error) {
+	vpc, err := s.vpcRepo.GetByID(vpcID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if vpc == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+	return vpc, nil
+}
@@ -0,0 +1,456 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// Internet gateway lifecycle states
+const (
+	InternetGatewayStateAvailable = "available"
+	InternetGatewayStateAttached  = "attached"
+	InternetGatewayStateDetached  = "detached"
+)
+
+type InternetGatewayService interface {
+	CreateInternetGateway(userID string, req *dto.CreateInternetGatewayRequest) (*models.InternetGateway, error)
+	GetInternetGateway(id string, userID string) (*models.InternetGateway, error)
+	ListInternetGateways(userID string, page, pageSize int) (*dto.InternetGatewayListResponse, error)
+	UpdateInternetGateway(id string, userID string, req *dto.UpdateInternetGatewayRequest) (*models.InternetGateway, error)
+	DeleteInternetGateway(id string, userID string) error
+	AttachInternetGateway(id string, userID string, vpcID string) (*models.InternetGateway, error)
+	DetachInternetGateway(id string, userID string) (*models.InternetGateway, error)
+	SyncNAT(vpcID string) error
+	UpdateNAT(vpcIDs []string, change func() error) error
+	DesiredFlows(vpc *models.VPC) (*network.FlowSet, error)
+}
+
+type internetGatewayService struct {
+	igwRepo        repositories.InternetGatewayRepository
+	vpcRepo        repositories.VPCRepository
+	routeTableRepo repositories.RouteTableRepository
+	natRepo        repositories.NATGatewayRepository
+	ovsManager     network.OVSManager
+	uplinkBridge   string
+	logger         *utils.Logger
+}
+
+func NewInternetGatewayService(
+	igwRepo repositories.InternetGatewayRepository,
+	vpcRepo repositories.VPCRepository,
+	routeTableRepo repositories.RouteTableRepository,
+	natRepo repositories.NATGatewayRepository,
+	ovsManager network.OVSManager,
+	uplinkBridge string,
+	logger *utils.Logger,
+) InternetGatewayService {
+	return &internetGatewayService{
+		igwRepo:        igwRepo,
+		vpcRepo:        vpcRepo,
+		routeTableRepo: routeTableRepo,
+		natRepo:        natRepo,
+		ovsManager:     ovsManager,
+		uplinkBridge:   uplinkBridge,
+		logger:         logger,
+	}
+}
+
+func (s *internetGatewayService) CreateInternetGateway(userID string, req *dto.CreateInternetGatewayRequest) (*models.InternetGateway, error) {
+	s.logger.Info("Creating new internet gateway", "user_id", userID, "name", req.Name)
+
+	existing, err := s.igwRepo.GetByName(userID, req.Name)
+	if err != nil {
+		s.logger.Error("Failed to check name conflict", "error", err, "name", req.Name)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check internet gateway name")
+	}
+	if existing != nil {
+		s.logger.Warn("Internet gateway name already exists", "name", req.Name, "user_id", userID)
+		return nil, errors.ErrInternetGatewayExists
+	}
+
+	now := time.Now()
+	igw := &models.InternetGateway{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      req.Name,
+		State:     InternetGatewayStateAvailable,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.igwRepo.Create(igw); err != nil {
+		s.logger.Error("Failed to create internet gateway in database", "error", err, "internet_gateway_id", igw.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create internet gateway")
+	}
+
+	s.logger.Info("Internet gateway created successfully", "internet_gateway_id", igw.ID)
+	return igw, nil
+}
+
+func (s *internetGatewayService) GetInternetGateway(id string, userID string) (*models.InternetGateway, error) {
+	igw, err := s.igwRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get internet gateway", "error", err, "internet_gateway_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get internet gateway")
+	}
+	if igw == nil {
+		return nil, errors.ErrInternetGatewayNotFound
+	}
+	return igw, nil
+}
+
+func (s *internetGatewayService) ListInternetGateways(userID string, page, pageSize int) (*dto.InternetGatewayListResponse, error) {
+	s.logger.Info("Listing internet gateways", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	igws, total, err := s.igwRepo.List(userID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list internet gateways", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list internet gateways")
+	}
+
+	igwResponses := make([]dto.InternetGatewayResponse, len(igws))
+	for i := range igws {
+		igwResponses[i] = dto.ToInternetGatewayResponse(&igws[i])
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.InternetGatewayListResponse{
+		InternetGateways: igwResponses,
+		Total:            total,
+		Page:             page,
+		PageSize:         pageSize,
+		TotalPages:       totalPages,
+	}, nil
+}
+
+func (s *internetGatewayService) UpdateInternetGateway(id string, userID string, req *dto.UpdateInternetGatewayRequest) (*models.InternetGateway, error) {
+	s.logger.Info("Updating internet gateway", "internet_gateway_id", id, "user_id", userID)
+
+	igw, err := s.GetInternetGateway(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+
+	if req.Name != nil && *req.Name != igw.Name {
+		conflict, err := s.igwRepo.GetByName(userID, *req.Name)
+		if err != nil {
+			s.logger.Error("Failed to check name conflict", "error", err, "name", *req.Name)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check internet gateway name")
+		}
+		if conflict != nil && conflict.ID != id {
+			return nil, errors.ErrInternetGatewayExists
+		}
+		updates["name"] = *req.Name
+	}
+
+	if len(updates) > 0 {
+		if err := s.igwRepo.Update(id, userID, updates); err != nil {
+			s.logger.Error("Failed to update internet gateway", "error", err, "internet_gateway_id", id)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to update internet gateway")
+		}
+	}
+
+	return s.GetInternetGateway(id, userID)
+}
+
+func (s *internetGatewayService) DeleteInternetGateway(id string, userID string) error {
+	s.logger.Info("Deleting internet gateway", "internet_gateway_id", id, "user_id", userID)
+
+	igw, err := s.GetInternetGateway(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if igw.VPCID != nil {
+		s.logger.Warn("Internet gateway is still attached", "internet_gateway_id", id, "vpc_id", *igw.VPCID)
+		return errors.ErrInternetGatewayAttached
+	}
+
+	if err := s.igwRepo.Delete(id, userID); err != nil {
+		s.logger.Error("Failed to delete internet gateway", "error", err, "internet_gateway_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete internet gateway")
+	}
+
+	s.logger.Info("Internet gateway deleted successfully", "internet_gateway_id", id)
+	return nil
+}
+
+func (s *internetGatewayService) AttachInternetGateway(id string, userID string, vpcID string) (*models.InternetGateway, error) {
+	s.logger.Info("Attaching internet gateway", "internet_gateway_id", id, "vpc_id", vpcID)
+
+	igw, err := s.GetInternetGateway(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if igw.VPCID != nil {
+		s.logger.Warn("Internet gateway is already attached", "internet_gateway_id", id, "vpc_id", *igw.VPCID)
+		return nil, errors.ErrInternetGatewayAttached
+	}
+
+	vpc, err := s.vpcRepo.GetByID(vpcID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if vpc == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+
+	// A VPC has a single way out to the internet
+	attached, err := s.igwRepo.GetByVPC(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to get attached internet gateway", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check VPC internet gateway")
+	}
+	if attached != nil {
+		s.logger.Warn("VPC already has an internet gateway", "vpc_id", vpc.ID, "internet_gateway_id", attached.ID)
+		return nil, errors.ErrVPCHasInternetGateway
+	}
+
+	if err := s.igwRepo.Attach(igw.ID, vpc.ID); err != nil {
+		s.logger.Error("Failed to attach internet gateway", "error", err, "internet_gateway_id", igw.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to attach internet gateway")
+	}
+
+	if err := s.connect(vpc.ID); err != nil {
+		// Rollback bridge and database changes
+		if discErr := s.disconnect(vpc.ID); discErr != nil {
+			s.logger.Error("Failed to rollback uplink connection", "error", discErr, "vpc_id", vpc.ID)
+		}
+		if detachErr := s.igwRepo.Detach(igw.ID); detachErr != nil {
+			s.logger.Error("Failed to rollback internet gateway attachment", "error", detachErr, "internet_gateway_id", igw.ID)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Internet gateway attached", "internet_gateway_id", igw.ID, "vpc_id", vpc.ID)
+	return s.GetInternetGateway(id, userID)
+}
+
+func (s *internetGatewayService) DetachInternetGateway(id string, userID string) (*models.InternetGateway, error) {
+	s.logger.Info("Detaching internet gateway", "internet_gateway_id", id)
+
+	igw, err := s.GetInternetGateway(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if igw.VPCID == nil {
+		return nil, errors.ErrInternetGatewayNotAttached
+	}
+
+	// Routes would silently start blackholing traffic
+	routes, err := s.routeTableRepo.CountRoutesByTarget("igw", igw.ID)
+	if err != nil {
+		s.logger.Error("Failed to count routes to internet gateway", "error", err, "internet_gateway_id", igw.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check internet gateway usage")
+	}
+	if routes > 0 {
+		s.logger.Warn("Internet gateway is still a route target", "internet_gateway_id", igw.ID, "routes", routes)
+		return nil, errors.ErrResourceInUse
+	}
+
+	// NAT gateways send their traffic out of the same patch port
+	natGateways, err := s.natRepo.CountByVPC(*igw.VPCID)
+	if err != nil {
+		s.logger.Error("Failed to count NAT gateways", "error", err, "vpc_id", *igw.VPCID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check internet gateway usage")
+	}
+	if natGateways > 0 {
+		s.logger.Warn("VPC still has NAT gateways", "internet_gateway_id", igw.ID, "nat_gateways", natGateways)
+		return nil, errors.ErrResourceInUse
+	}
+
+	if err := s.disconnect(*igw.VPCID); err != nil {
+		return nil, err
+	}
+
+	if err := s.igwRepo.Detach(igw.ID); err != nil {
+		s.logger.Error("Failed to detach internet gateway", "error", err, "internet_gateway_id", igw.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to detach internet gateway")
+	}
+
+	s.logger.Info("Internet gateway detached", "internet_gateway_id", igw.ID, "vpc_id", *igw.VPCID)
+	return s.GetInternetGateway(id, userID)
+}
+
+// SyncNAT reprograms the NAT flows of the gateway attached to a VPC from the
+// public addresses of its instances and its NAT gateways, in one bundle. VPCs
+// without a gateway are left alone.
+func (s *internetGatewayService) SyncNAT(vpcID string) error {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpcID)
+	if err != nil {
+		return err
+	}
+
+	flows, attached, err := s.compileNAT(vpcID, bridgeName)
+	if err != nil || !attached {
+		return err
+	}
+
+	if err := natFlowSet(flows).Apply(s.ovsManager, bridgeName); err != nil {
+		s.logger.Error("Failed to program NAT flows", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to program NAT flows")
+	}
+
+	s.logger.Info("Internet gateway NAT synchronized", "vpc_id", vpcID)
+	return nil
+}
+
+// UpdateNAT applies a change to public addresses and reprograms the NAT
+// flows of the given VPCs in order, stopping at the first that fails. Each
+// bridge gets its flows in one bundle, so a public address that moves
+// between instances of a VPC is rewritten in place.
+func (s *internetGatewayService) UpdateNAT(vpcIDs []string, change func() error) error {
+	if err := change(); err != nil {
+		return err
+	}
+
+	for _, vpcID := range vpcIDs {
+		if err := s.SyncNAT(vpcID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DesiredFlows returns the NAT flows of a VPC bridge, which has none without
+// an attached gateway
+func (s *internetGatewayService) DesiredFlows(vpc *models.VPC) (*network.FlowSet, error) {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpc.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	flows, _, err := s.compileNAT(vpc.ID, bridgeName)
+	if err != nil {
+		return nil, err
+	}
+	return natFlowSet(flows), nil
+}
+
+// compileNAT returns the NAT flows of the gateway attached to a VPC on its
+// bridge, or false when the VPC has no gateway
+func (s *internetGatewayService) compileNAT(vpcID string, bridgeName string) ([]network.Flow, bool, error) {
+	igw, err := s.igwRepo.GetByVPC(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to get attached internet gateway", "error", err, "vpc_id", vpcID)
+		return nil, false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get internet gateway")
+	}
+	if igw == nil {
+		return nil, false, nil
+	}
+
+	rows, err := s.igwRepo.ListPublicIPMappings(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list public IP mappings", "error", err, "vpc_id", vpcID)
+		return nil, false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list public IP addresses")
+	}
+	mappings := make([]network.NATMapping, len(rows))
+	for i, row := range rows {
+		mappings[i] = network.NATMapping{PrivateIP: row.PrivateIP, PublicIP: row.PublicIP}
+	}
+
+	natGateways, err := s.natRepo.ListByVPC(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list NAT gateways", "error", err, "vpc_id", vpcID)
+		return nil, false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list NAT gateways")
+	}
+	sourceNAT := make([]network.SourceNAT, len(natGateways))
+	for i, natGateway := range natGateways {
+		sourceNAT[i] = network.SourceNAT{PublicIP: natGateway.PublicIP, Zone: natGateway.ConntrackZone}
+	}
+
+	vpcPort, _ := network.InternetGatewayPorts(bridgeName)
+	flows, err := network.CompileInternetGateway(vpcPort, mappings, sourceNAT)
+	if err != nil {
+		s.logger.Error("Failed to compile NAT flows", "error", err, "vpc_id", vpcID)
+		return nil, false, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile NAT flows")
+	}
+
+	return network.WithCookie(flows, network.FlowCookie(network.CookieKindInternetGateway, igw.ID)), true, nil
+}
+
+// connect patches the VPC bridge to the uplink bridge and programs NAT
+func (s *internetGatewayService) connect(vpcID string) error {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpcID)
+	if err != nil {
+		return err
+	}
+	vpcPort, uplinkPort := network.InternetGatewayPorts(bridgeName)
+
+	if err := s.ovsManager.AddPatchPort(bridgeName, vpcPort, uplinkPort); err != nil {
+		s.logger.Error("Failed to add VPC patch port", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to connect VPC to uplink")
+	}
+	if err := s.ovsManager.AddPatchPort(s.uplinkBridge, uplinkPort, vpcPort); err != nil {
+		s.logger.Error("Failed to add uplink patch port", "error", err, "bridge_name", s.uplinkBridge)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to connect VPC to uplink")
+	}
+
+	return s.SyncNAT(vpcID)
+}
+
+// disconnect removes the NAT flows and both patch ports
+func (s *internetGatewayService) disconnect(vpcID string) error {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpcID)
+	if err != nil {
+		return err
+	}
+	vpcPort, uplinkPort := network.InternetGatewayPorts(bridgeName)
+
+	if err := s.clearFlows(bridgeName); err != nil {
+		return err
+	}
+	if err := s.ovsManager.DeletePort(s.uplinkBridge, uplinkPort); err != nil {
+		s.logger.Error("Failed to delete uplink patch port", "error", err, "bridge_name", s.uplinkBridge)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to disconnect VPC from uplink")
+	}
+	if err := s.ovsManager.DeletePort(bridgeName, vpcPort); err != nil {
+		s.logger.Error("Failed to delete VPC patch port", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to disconnect VPC from uplink")
+	}
+
+	return nil
+}
+
+// natFlowSet returns the flow set of the NAT flows of a VPC bridge, which
+// also covers the flows of its NAT gateways
+func natFlowSet(flows []network.Flow) *network.FlowSet {
+	return &network.FlowSet{
+		Cookie: network.KindCookie(network.CookieKindInternetGateway),
+		Mask:   network.CookieMaskKind,
+		Flows:  flows,
+	}
+}
+
+// clearFlows deletes the gateway and NAT gateway flows, including the
+// classifier flows matching traffic from the uplink
+func (s *internetGatewayService) clearFlows(bridgeName string) error {
+	cookie := network.KindCookie(network.CookieKindInternetGateway)
+	if err := s.ovsManager.DeleteFlowsByCookie(bridgeName, cookie, network.CookieMaskKind); err != nil {
+		s.logger.Error("Failed to clear NAT flows", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to clear NAT flows")
+	}
+	return nil
+}
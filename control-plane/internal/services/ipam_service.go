@@ -0,0 +1,175 @@
+package services
+
+import (
+	"math"
+	"net"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+type IPAMService interface {
+	AllocateIP(subnetID string, userID string, req *dto.AllocateIPRequest) (*models.IPAllocation, error)
+	ReleaseIP(subnetID string, ipAddress string, userID string) error
+	ListAllocations(subnetID string, userID string) ([]models.IPAllocation, error)
+	GetUtilization(subnetID string, userID string) (*dto.SubnetUtilizationResponse, error)
+}
+
+type ipamService struct {
+	ipAllocationRepo repositories.IPAllocationRepository
+	subnetRepo       repositories.SubnetRepository
+	logger           *utils.Logger
+}
+
+func NewIPAMService(ipAllocationRepo repositories.IPAllocationRepository, subnetRepo repositories.SubnetRepository, logger *utils.Logger) IPAMService {
+	return &ipamService{
+		ipAllocationRepo: ipAllocationRepo,
+		subnetRepo:       subnetRepo,
+		logger:           logger,
+	}
+}
+
+func (s *ipamService) AllocateIP(subnetID string, userID string, req *dto.AllocateIPRequest) (*models.IPAllocation, error) {
+	s.logger.Info("Allocating IP address", "subnet_id", subnetID, "user_id", userID)
+
+	subnet, allocator, err := s.getSubnetAllocator(subnetID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pick := func(used []string) (string, string, error) {
+		ip := ""
+		if req.IPAddress != nil {
+			if err := allocator.Validate(*req.IPAddress, used); err != nil {
+				return "", "", errors.ErrIPAddressUnavailable
+			}
+			ip = net.ParseIP(*req.IPAddress).To4().String()
+		} else {
+			next, err := allocator.NextFree(used)
+			if err != nil {
+				return "", "", errors.ErrIPAddressExhausted
+			}
+			ip = next
+		}
+
+		// The IPv6 address is the EUI-64 one instances configure with SLAAC
+		// from the MAC their port gets for the IPv4 address
+		if subnet.IPv6CIDRBlock == nil {
+			return ip, "", nil
+		}
+		ipv6, err := network.InstanceIPv6(*subnet.IPv6CIDRBlock, ip)
+		if err != nil {
+			return "", "", err
+		}
+		return ip, ipv6, nil
+	}
+
+	allocation, err := s.ipAllocationRepo.Allocate(subnet.ID, req.InstanceID, req.Description, pick)
+	if err != nil {
+		if err == errors.ErrIPAddressUnavailable || err == errors.ErrIPAddressExhausted {
+			s.logger.Warn("IP allocation rejected", "error", err, "subnet_id", subnet.ID)
+			return nil, err
+		}
+		s.logger.Error("Failed to allocate IP address", "error", err, "subnet_id", subnet.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to allocate IP address")
+	}
+
+	s.logger.Info("IP address allocated", "subnet_id", subnet.ID, "ip_address", allocation.IPAddress)
+	return allocation, nil
+}
+
+func (s *ipamService) ReleaseIP(subnetID string, ipAddress string, userID string) error {
+	s.logger.Info("Releasing IP address", "subnet_id", subnetID, "ip_address", ipAddress, "user_id", userID)
+
+	if net.ParseIP(ipAddress) == nil {
+		return errors.ErrIPAllocationNotFound
+	}
+
+	if _, _, err := s.getSubnetAllocator(subnetID, userID); err != nil {
+		return err
+	}
+
+	allocation, err := s.ipAllocationRepo.GetByIP(subnetID, ipAddress)
+	if err != nil {
+		s.logger.Error("Failed to get IP allocation", "error", err, "ip_address", ipAddress)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get IP allocation")
+	}
+	if allocation == nil {
+		return errors.ErrIPAllocationNotFound
+	}
+
+	if err := s.ipAllocationRepo.Release(subnetID, ipAddress); err != nil {
+		s.logger.Error("Failed to release IP address", "error", err, "ip_address", ipAddress)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to release IP address")
+	}
+
+	s.logger.Info("IP address released", "subnet_id", subnetID, "ip_address", ipAddress)
+	return nil
+}
+
+func (s *ipamService) ListAllocations(subnetID string, userID string) ([]models.IPAllocation, error) {
+	if _, _, err := s.getSubnetAllocator(subnetID, userID); err != nil {
+		return nil, err
+	}
+
+	allocations, err := s.ipAllocationRepo.ListBySubnet(subnetID)
+	if err != nil {
+		s.logger.Error("Failed to list IP allocations", "error", err, "subnet_id", subnetID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list IP allocations")
+	}
+
+	return allocations, nil
+}
+
+func (s *ipamService) GetUtilization(subnetID string, userID string) (*dto.SubnetUtilizationResponse, error) {
+	subnet, allocator, err := s.getSubnetAllocator(subnetID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	allocated, err := s.ipAllocationRepo.CountBySubnet(subnet.ID)
+	if err != nil {
+		s.logger.Error("Failed to count IP allocations", "error", err, "subnet_id", subnet.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to count IP allocations")
+	}
+
+	capacity := allocator.Capacity()
+	utilization := 0.0
+	if capacity > 0 {
+		utilization = math.Round(float64(allocated)/float64(capacity)*10000) / 100
+	}
+
+	return &dto.SubnetUtilizationResponse{
+		SubnetID:           subnet.ID,
+		CIDRBlock:          subnet.CIDRBlock,
+		TotalAddresses:     allocator.TotalAddresses(),
+		ReservedAddresses:  allocator.ReservedAddresses(),
+		AllocatedAddresses: allocated,
+		AvailableAddresses: capacity - allocated,
+		UtilizationPercent: utilization,
+	}, nil
+}
+
+// getSubnetAllocator loads a subnet owned by userID and builds its allocator
+func (s *ipamService) getSubnetAllocator(subnetID string, userID string) (*models.Subnet, *network.IPAllocator, error) {
+	subnet, err := s.subnetRepo.GetByID(subnetID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet", "error", err, "subnet_id", subnetID)
+		return nil, nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet")
+	}
+	if subnet == nil {
+		return nil, nil, errors.ErrSubnetNotFound
+	}
+
+	allocator, err := network.NewIPAllocator(subnet.CIDRBlock)
+	if err != nil {
+		s.logger.Error("Invalid subnet CIDR block", "error", err, "subnet_id", subnet.ID)
+		return nil, nil, errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_SUBNET_CIDR", "Subnet has an invalid CIDR block")
+	}
+
+	return subnet, allocator, nil
+}
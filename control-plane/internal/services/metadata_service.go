@@ -0,0 +1,260 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// Lifetimes a metadata session token may be requested with, in seconds
+const (
+	MinMetadataTokenTTL = 1
+	MaxMetadataTokenTTL = 21600
+)
+
+// MetadataService answers instances asking the metadata service about
+// themselves. Instances are known by the address they ask from in their
+// VPC, which the metadata and port security flows tie to the instance's
+// port and MAC.
+//
+// Session tokens are signed with a key drawn when the service starts, so no
+// token outlives the process that issued it; callers simply ask for a new
+// one, as they must when a token expires.
+type MetadataService interface {
+	GetMetadata(vpcID, ipAddress string) (*dto.InstanceMetadata, error)
+	CreateToken(instanceID string, ttlSeconds int) (string, error)
+	ValidateToken(instanceID, token string) error
+	DesiredFlows(vpc *models.VPC) (*network.FlowSet, error)
+}
+
+type metadataService struct {
+	instanceRepo     repositories.InstanceRepository
+	vpcRepo          repositories.VPCRepository
+	subnetRepo       repositories.SubnetRepository
+	ipAllocationRepo repositories.IPAllocationRepository
+	routeTableRepo   repositories.RouteTableRepository
+	ovsManager       network.OVSManager
+	tokenKey         []byte
+	logger           *utils.Logger
+}
+
+func NewMetadataService(
+	instanceRepo repositories.InstanceRepository,
+	vpcRepo repositories.VPCRepository,
+	subnetRepo repositories.SubnetRepository,
+	ipAllocationRepo repositories.IPAllocationRepository,
+	routeTableRepo repositories.RouteTableRepository,
+	ovsManager network.OVSManager,
+	logger *utils.Logger,
+) MetadataService {
+	tokenKey := make([]byte, sha256.Size)
+	rand.Read(tokenKey)
+
+	return &metadataService{
+		instanceRepo:     instanceRepo,
+		vpcRepo:          vpcRepo,
+		subnetRepo:       subnetRepo,
+		ipAllocationRepo: ipAllocationRepo,
+		routeTableRepo:   routeTableRepo,
+		ovsManager:       ovsManager,
+		tokenKey:         tokenKey,
+		logger:           logger,
+	}
+}
+
+// GetMetadata returns the metadata of the instance an address of a VPC is
+// reserved for. The hostname is the name the VPC's DNS resolves.
+func (s *metadataService) GetMetadata(vpcID, ipAddress string) (*dto.InstanceMetadata, error) {
+	instance, err := s.instanceRepo.GetByAddress(vpcID, ipAddress)
+	if err != nil {
+		s.logger.Error("Failed to get instance by address", "error", err, "vpc_id", vpcID, "ip_address", ipAddress)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get instance")
+	}
+	if instance == nil {
+		return nil, errors.ErrInstanceNotFound
+	}
+
+	vpc, err := s.vpcRepo.GetByID(vpcID, instance.UserID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if vpc == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+
+	subnet, err := s.subnetRepo.GetByID(instance.SubnetID, instance.UserID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet", "error", err, "subnet_id", instance.SubnetID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet")
+	}
+	if subnet == nil {
+		return nil, errors.ErrSubnetNotFound
+	}
+
+	securityGroups, err := s.instanceRepo.ListSecurityGroupNames(instance.ID)
+	if err != nil {
+		s.logger.Error("Failed to list instance security groups", "error", err, "instance_id", instance.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list instance security groups")
+	}
+
+	mac, err := network.InstanceMAC(instance.PrivateIP)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Invalid instance address")
+	}
+
+	metadata := &dto.InstanceMetadata{
+		InstanceID:       instance.ID,
+		InstanceType:     instance.InstanceType,
+		ImageID:          instance.ImageID,
+		LocalIPv4:        instance.PrivateIP,
+		PublicIPv4:       instance.PublicIP,
+		MAC:              mac,
+		AvailabilityZone: subnet.AvailabilityZone,
+		KeyName:          instance.KeyPair,
+		SSHPublicKey:     instance.SSHPublicKey,
+		SecurityGroups:   securityGroups,
+		UserData:         instance.UserData,
+		TokensRequired:   instance.MetadataTokens == "required",
+	}
+	if label := network.DNSLabel(instance.Name); label != "" {
+		metadata.Hostname = label + "." + vpcDomain(vpc)
+	}
+
+	return metadata, nil
+}
+
+// CreateToken issues a session token for an instance. A token holds its
+// expiry and a MAC over the expiry and the instance ID, so it is only good
+// for the instance it was issued to.
+func (s *metadataService) CreateToken(instanceID string, ttlSeconds int) (string, error) {
+	if ttlSeconds < MinMetadataTokenTTL || ttlSeconds > MaxMetadataTokenTTL {
+		return "", errors.ErrInvalidParameter
+	}
+
+	token := make([]byte, 8, 8+sha256.Size)
+	binary.BigEndian.PutUint64(token, uint64(time.Now().Add(time.Duration(ttlSeconds)*time.Second).Unix()))
+	token = append(token, s.signToken(instanceID, token)...)
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// ValidateToken checks that a session token was issued to an instance and
+// has not expired
+func (s *metadataService) ValidateToken(instanceID, token string) error {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 8+sha256.Size {
+		return errors.ErrInvalidToken
+	}
+	if !hmac.Equal(raw[8:], s.signToken(instanceID, raw[:8])) {
+		return errors.ErrInvalidToken
+	}
+	if time.Now().Unix() >= int64(binary.BigEndian.Uint64(raw[:8])) {
+		return errors.ErrTokenExpired
+	}
+	return nil
+}
+
+// signToken returns the MAC of a token's expiry for an instance
+func (s *metadataService) signToken(instanceID string, expiry []byte) []byte {
+	mac := hmac.New(sha256.New, s.tokenKey)
+	mac.Write(expiry)
+	mac.Write([]byte(instanceID))
+	return mac.Sum(nil)
+}
+
+// DesiredFlows returns the port security flows of every instance port on
+// the bridge of a VPC and the flows that steer their metadata requests to
+// the responder's port. Ports the hypervisor has yet to create are left out
+// until a later reconcile pass finds them.
+func (s *metadataService) DesiredFlows(vpc *models.VPC) (*network.FlowSet, error) {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpc.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := s.instancePorts(vpc.ID, bridgeName)
+	if err != nil {
+		return nil, err
+	}
+
+	flows, err := network.CompilePortSecurity(instances)
+	if err != nil {
+		s.logger.Error("Failed to compile port security flows", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile port security flows")
+	}
+	metadataFlows, err := network.CompileMetadata(network.DHCPPortName(bridgeName), instances)
+	if err != nil {
+		s.logger.Error("Failed to compile metadata flows", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile metadata flows")
+	}
+	flows = append(flows, metadataFlows...)
+
+	cookie := network.FlowCookie(network.CookieKindMetadata, vpc.ID)
+	return &network.FlowSet{
+		Cookie: cookie,
+		Mask:   network.CookieMaskResource,
+		Flows:  network.WithCookie(network.UniqueFlows(flows), cookie),
+	}, nil
+}
+
+// instancePorts returns the ports on a VPC bridge of the instances with
+// addresses in the VPC. Instances that are the target of a route forward
+// traffic for other addresses and are marked as routers.
+func (s *metadataService) instancePorts(vpcID, bridgeName string) ([]network.InstancePort, error) {
+	reservations, err := s.ipAllocationRepo.ListInstanceReservationsInVPC(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list instance reservations", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list instance reservations")
+	}
+
+	routes, err := s.routeTableRepo.ListRoutesByVPC(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list routes", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list routes")
+	}
+	routers := make(map[string]bool)
+	for _, route := range routes {
+		if route.TargetType == "instance" {
+			routers[route.TargetID] = true
+		}
+	}
+
+	ports, err := s.ovsManager.ListPorts(bridgeName)
+	if err != nil {
+		s.logger.Error("Failed to list ports", "error", err, "bridge_name", bridgeName)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to list ports")
+	}
+	attached := make(map[string]bool, len(ports))
+	for _, port := range ports {
+		attached[port.Name] = true
+	}
+
+	instances := make([]network.InstancePort, 0, len(reservations))
+	for _, reservation := range reservations {
+		name := network.InstancePortName(reservation.InstanceID)
+		if !attached[name] {
+			continue
+		}
+		instance := network.InstancePort{
+			Name:   name,
+			IPv4:   reservation.IPAddress,
+			Router: routers[reservation.InstanceID],
+		}
+		if reservation.IPv6Address != nil {
+			instance.IPv6 = *reservation.IPv6Address
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
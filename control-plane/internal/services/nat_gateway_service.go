@@ -0,0 +1,291 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// NAT gateway lifecycle states
+const (
+	NATGatewayStateAvailable = "available"
+)
+
+type NATGatewayService interface {
+	CreateNATGateway(userID string, req *dto.CreateNATGatewayRequest) (*models.NATGateway, error)
+	GetNATGateway(id string, userID string) (*models.NATGateway, error)
+	ListNATGateways(userID string, vpcID *string, page, pageSize int) (*dto.NATGatewayListResponse, error)
+	DeleteNATGateway(id string, userID string) error
+	GetConnections(id string, userID string) (*dto.NATGatewayConnectionsResponse, error)
+}
+
+type natGatewayService struct {
+	natRepo        repositories.NATGatewayRepository
+	subnetRepo     repositories.SubnetRepository
+	eipRepo        repositories.ElasticIPRepository
+	igwRepo        repositories.InternetGatewayRepository
+	routeTableRepo repositories.RouteTableRepository
+	igwService     InternetGatewayService
+	ovsManager     network.OVSManager
+	logger         *utils.Logger
+}
+
+func NewNATGatewayService(
+	natRepo repositories.NATGatewayRepository,
+	subnetRepo repositories.SubnetRepository,
+	eipRepo repositories.ElasticIPRepository,
+	igwRepo repositories.InternetGatewayRepository,
+	routeTableRepo repositories.RouteTableRepository,
+	igwService InternetGatewayService,
+	ovsManager network.OVSManager,
+	logger *utils.Logger,
+) NATGatewayService {
+	return &natGatewayService{
+		natRepo:        natRepo,
+		subnetRepo:     subnetRepo,
+		eipRepo:        eipRepo,
+		igwRepo:        igwRepo,
+		routeTableRepo: routeTableRepo,
+		igwService:     igwService,
+		ovsManager:     ovsManager,
+		logger:         logger,
+	}
+}
+
+// CreateNATGateway places a NAT gateway in a public subnet. Its flows are
+// added to the VPC's internet gateway flows without touching the existing
+// ones, so instances with a public IP keep their connectivity.
+func (s *natGatewayService) CreateNATGateway(userID string, req *dto.CreateNATGatewayRequest) (*models.NATGateway, error) {
+	s.logger.Info("Creating new NAT gateway", "user_id", userID, "subnet_id", req.SubnetID, "name", req.Name)
+
+	existing, err := s.natRepo.GetByName(userID, req.Name)
+	if err != nil {
+		s.logger.Error("Failed to check name conflict", "error", err, "name", req.Name)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check NAT gateway name")
+	}
+	if existing != nil {
+		s.logger.Warn("NAT gateway name already exists", "name", req.Name, "user_id", userID)
+		return nil, errors.ErrNATGatewayExists
+	}
+
+	subnet, err := s.subnetRepo.GetByID(req.SubnetID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet", "error", err, "subnet_id", req.SubnetID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet")
+	}
+	if subnet == nil {
+		return nil, errors.ErrSubnetNotFound
+	}
+
+	eip, err := s.eipRepo.GetByID(req.ElasticIPID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get elastic IP", "error", err, "elastic_ip_id", req.ElasticIPID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get elastic IP")
+	}
+	if eip == nil {
+		return nil, errors.ErrElasticIPNotFound
+	}
+	if eip.InstanceID != nil || eip.NATGatewayID != nil {
+		s.logger.Warn("Elastic IP is already in use", "elastic_ip_id", eip.ID)
+		return nil, errors.ErrElasticIPAssociated
+	}
+
+	public, err := s.isPublicSubnet(subnet)
+	if err != nil {
+		return nil, err
+	}
+	if !public {
+		s.logger.Warn("Subnet has no route to an internet gateway", "subnet_id", subnet.ID)
+		return nil, errors.ErrSubnetNotPublic
+	}
+
+	now := time.Now()
+	natGateway := &models.NATGateway{
+		ID:          uuid.New().String(),
+		VPCID:       subnet.VPCID,
+		SubnetID:    subnet.ID,
+		ElasticIPID: eip.ID,
+		PublicIP:    eip.PublicIP,
+		UserID:      userID,
+		Name:        req.Name,
+		State:       NATGatewayStateAvailable,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	created := false
+	err = s.igwService.UpdateNAT([]string{natGateway.VPCID}, func() error {
+		if err := s.natRepo.Create(natGateway); err != nil {
+			s.logger.Error("Failed to create NAT gateway in database", "error", err, "nat_gateway_id", natGateway.ID)
+			return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create NAT gateway")
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		if created {
+			// Rollback database changes and whatever flows made it in
+			if delErr := s.natRepo.Delete(natGateway.ID, userID); delErr != nil {
+				s.logger.Error("Failed to rollback NAT gateway creation", "error", delErr, "nat_gateway_id", natGateway.ID)
+			} else if syncErr := s.igwService.SyncNAT(natGateway.VPCID); syncErr != nil {
+				s.logger.Error("Failed to rollback NAT gateway flows", "error", syncErr, "vpc_id", natGateway.VPCID)
+			}
+		}
+		return nil, err
+	}
+
+	s.logger.Info("NAT gateway created successfully", "nat_gateway_id", natGateway.ID, "public_ip", natGateway.PublicIP, "conntrack_zone", natGateway.ConntrackZone)
+	return natGateway, nil
+}
+
+func (s *natGatewayService) GetNATGateway(id string, userID string) (*models.NATGateway, error) {
+	natGateway, err := s.natRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get NAT gateway", "error", err, "nat_gateway_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get NAT gateway")
+	}
+	if natGateway == nil {
+		return nil, errors.ErrNATGatewayNotFound
+	}
+	return natGateway, nil
+}
+
+func (s *natGatewayService) ListNATGateways(userID string, vpcID *string, page, pageSize int) (*dto.NATGatewayListResponse, error) {
+	s.logger.Info("Listing NAT gateways", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	natGateways, total, err := s.natRepo.List(userID, vpcID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list NAT gateways", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list NAT gateways")
+	}
+
+	natResponses := make([]dto.NATGatewayResponse, len(natGateways))
+	for i := range natGateways {
+		natResponses[i] = dto.ToNATGatewayResponse(&natGateways[i])
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.NATGatewayListResponse{
+		NATGateways: natResponses,
+		Total:       total,
+		Page:        page,
+		PageSize:    pageSize,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+func (s *natGatewayService) DeleteNATGateway(id string, userID string) error {
+	s.logger.Info("Deleting NAT gateway", "nat_gateway_id", id, "user_id", userID)
+
+	natGateway, err := s.GetNATGateway(id, userID)
+	if err != nil {
+		return err
+	}
+
+	// Routes would silently start blackholing traffic
+	routes, err := s.routeTableRepo.CountRoutesByTarget("nat", natGateway.ID)
+	if err != nil {
+		s.logger.Error("Failed to count routes to NAT gateway", "error", err, "nat_gateway_id", natGateway.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check NAT gateway usage")
+	}
+	if routes > 0 {
+		s.logger.Warn("NAT gateway is still a route target", "nat_gateway_id", natGateway.ID, "routes", routes)
+		return errors.ErrResourceInUse
+	}
+
+	err = s.igwService.UpdateNAT([]string{natGateway.VPCID}, func() error {
+		if err := s.natRepo.Delete(natGateway.ID, userID); err != nil {
+			s.logger.Error("Failed to delete NAT gateway", "error", err, "nat_gateway_id", natGateway.ID)
+			return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete NAT gateway")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("NAT gateway deleted successfully", "nat_gateway_id", natGateway.ID)
+	return nil
+}
+
+// GetConnections counts the connections the NAT gateway is translating,
+// read from its conntrack zone
+func (s *natGatewayService) GetConnections(id string, userID string) (*dto.NATGatewayConnectionsResponse, error) {
+	natGateway, err := s.GetNATGateway(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	connections, err := s.ovsManager.CountConnections(natGateway.ConntrackZone)
+	if err != nil {
+		s.logger.Error("Failed to count NAT gateway connections", "error", err, "nat_gateway_id", natGateway.ID, "conntrack_zone", natGateway.ConntrackZone)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to read NAT gateway connections")
+	}
+
+	return &dto.NATGatewayConnectionsResponse{
+		NATGatewayID:      natGateway.ID,
+		PublicIP:          natGateway.PublicIP,
+		ConntrackZone:     natGateway.ConntrackZone,
+		ActiveConnections: connections,
+	}, nil
+}
+
+// isPublicSubnet reports whether the route table in effect for a subnet has
+// a route to the internet gateway attached to its VPC
+func (s *natGatewayService) isPublicSubnet(subnet *models.Subnet) (bool, error) {
+	igw, err := s.igwRepo.GetByVPC(subnet.VPCID)
+	if err != nil {
+		s.logger.Error("Failed to get attached internet gateway", "error", err, "vpc_id", subnet.VPCID)
+		return false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get internet gateway")
+	}
+	if igw == nil {
+		return false, nil
+	}
+
+	routeTableID, err := s.routeTableRepo.GetAssociatedTableID(subnet.ID)
+	if err != nil {
+		s.logger.Error("Failed to get route table association", "error", err, "subnet_id", subnet.ID)
+		return false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet route table")
+	}
+	if routeTableID == nil {
+		mainRouteTable, err := s.routeTableRepo.GetMain(subnet.VPCID)
+		if err != nil {
+			s.logger.Error("Failed to get main route table", "error", err, "vpc_id", subnet.VPCID)
+			return false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet route table")
+		}
+		if mainRouteTable == nil {
+			return false, nil
+		}
+		routeTableID = &mainRouteTable.ID
+	}
+
+	routes, err := s.routeTableRepo.ListRoutes(*routeTableID)
+	if err != nil {
+		s.logger.Error("Failed to list routes", "error", err, "route_table_id", *routeTableID)
+		return false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list routes")
+	}
+	for _, route := range routes {
+		if route.TargetType == "igw" && route.TargetID == igw.ID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,506 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+type NetworkACLService interface {
+	CreateNetworkACL(userID string, req *dto.CreateNetworkACLRequest) (*models.NetworkACL, error)
+	GetNetworkACL(id string, userID string) (*models.NetworkACL, error)
+	ListNetworkACLs(userID string, vpcID *string, page, pageSize int) (*dto.NetworkACLListResponse, error)
+	UpdateNetworkACL(id string, userID string, req *dto.UpdateNetworkACLRequest) (*models.NetworkACL, error)
+	DeleteNetworkACL(id string, userID string) error
+	AddEntry(id string, userID string, req *dto.NetworkACLEntryRequest) (*models.NetworkACLEntry, error)
+	RemoveEntry(id string, entryID string, userID string) error
+	AssociateSubnet(id string, userID string, subnetID string) error
+	DisassociateSubnet(id string, userID string, subnetID string) error
+	DesiredFlows(vpc *models.VPC) (*network.FlowSet, error)
+}
+
+type networkACLService struct {
+	aclRepo    repositories.NetworkACLRepository
+	vpcRepo    repositories.VPCRepository
+	subnetRepo repositories.SubnetRepository
+	ovsManager network.OVSManager
+	logger     *utils.Logger
+}
+
+func NewNetworkACLService(
+	aclRepo repositories.NetworkACLRepository,
+	vpcRepo repositories.VPCRepository,
+	subnetRepo repositories.SubnetRepository,
+	ovsManager network.OVSManager,
+	logger *utils.Logger,
+) NetworkACLService {
+	return &networkACLService{
+		aclRepo:    aclRepo,
+		vpcRepo:    vpcRepo,
+		subnetRepo: subnetRepo,
+		ovsManager: ovsManager,
+		logger:     logger,
+	}
+}
+
+func (s *networkACLService) CreateNetworkACL(userID string, req *dto.CreateNetworkACLRequest) (*models.NetworkACL, error) {
+	s.logger.Info("Creating new network ACL", "user_id", userID, "vpc_id", req.VPCID, "name", req.Name)
+
+	vpc, err := s.vpcRepo.GetByID(req.VPCID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC", "error", err, "vpc_id", req.VPCID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if vpc == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+
+	existing, err := s.aclRepo.GetByName(vpc.ID, req.Name)
+	if err != nil {
+		s.logger.Error("Failed to check name conflict", "error", err, "name", req.Name)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check network ACL name")
+	}
+	if existing != nil {
+		s.logger.Warn("Network ACL name already exists", "name", req.Name, "vpc_id", vpc.ID)
+		return nil, errors.ErrNetworkACLExists
+	}
+
+	now := time.Now()
+	acl := &models.NetworkACL{
+		ID:              uuid.New().String(),
+		Name:            req.Name,
+		Description:     req.Description,
+		VPCID:           vpc.ID,
+		UserID:          userID,
+		SubnetIDs:       []string{},
+		InboundEntries:  []models.NetworkACLEntry{},
+		OutboundEntries: []models.NetworkACLEntry{},
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if err := s.aclRepo.Create(acl); err != nil {
+		s.logger.Error("Failed to create network ACL in database", "error", err, "network_acl_id", acl.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create network ACL")
+	}
+
+	s.logger.Info("Network ACL created successfully", "network_acl_id", acl.ID)
+	return acl, nil
+}
+
+func (s *networkACLService) GetNetworkACL(id string, userID string) (*models.NetworkACL, error) {
+	acl, err := s.getNetworkACL(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadDetails(acl); err != nil {
+		return nil, err
+	}
+
+	return acl, nil
+}
+
+func (s *networkACLService) ListNetworkACLs(userID string, vpcID *string, page, pageSize int) (*dto.NetworkACLListResponse, error) {
+	s.logger.Info("Listing network ACLs", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	acls, total, err := s.aclRepo.List(userID, vpcID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list network ACLs", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list network ACLs")
+	}
+
+	aclResponses := make([]dto.NetworkACLResponse, len(acls))
+	for i := range acls {
+		if err := s.loadDetails(&acls[i]); err != nil {
+			return nil, err
+		}
+		aclResponses[i] = dto.ToNetworkACLResponse(&acls[i])
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.NetworkACLListResponse{
+		NetworkACLs: aclResponses,
+		Total:       total,
+		Page:        page,
+		PageSize:    pageSize,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+func (s *networkACLService) UpdateNetworkACL(id string, userID string, req *dto.UpdateNetworkACLRequest) (*models.NetworkACL, error) {
+	s.logger.Info("Updating network ACL", "network_acl_id", id, "user_id", userID)
+
+	acl, err := s.getNetworkACL(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+
+	if req.Name != nil && *req.Name != acl.Name {
+		conflict, err := s.aclRepo.GetByName(acl.VPCID, *req.Name)
+		if err != nil {
+			s.logger.Error("Failed to check name conflict", "error", err, "name", *req.Name)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check network ACL name")
+		}
+		if conflict != nil && conflict.ID != id {
+			return nil, errors.ErrNetworkACLExists
+		}
+		updates["name"] = *req.Name
+	}
+
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	if len(updates) > 0 {
+		if err := s.aclRepo.Update(id, userID, updates); err != nil {
+			s.logger.Error("Failed to update network ACL", "error", err, "network_acl_id", id)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to update network ACL")
+		}
+	}
+
+	return s.GetNetworkACL(id, userID)
+}
+
+func (s *networkACLService) DeleteNetworkACL(id string, userID string) error {
+	s.logger.Info("Deleting network ACL", "network_acl_id", id, "user_id", userID)
+
+	if _, err := s.getNetworkACL(id, userID); err != nil {
+		return err
+	}
+
+	subnets, err := s.aclRepo.ListAssociatedSubnets(id)
+	if err != nil {
+		s.logger.Error("Failed to list associated subnets", "error", err, "network_acl_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check network ACL usage")
+	}
+	if len(subnets) > 0 {
+		s.logger.Warn("Network ACL is still associated", "network_acl_id", id, "subnets", len(subnets))
+		return errors.ErrResourceInUse
+	}
+
+	// Without associations the ACL has no flows on the bridge
+	if err := s.aclRepo.Delete(id, userID); err != nil {
+		s.logger.Error("Failed to delete network ACL", "error", err, "network_acl_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete network ACL")
+	}
+
+	s.logger.Info("Network ACL deleted successfully", "network_acl_id", id)
+	return nil
+}
+
+func (s *networkACLService) AddEntry(id string, userID string, req *dto.NetworkACLEntryRequest) (*models.NetworkACLEntry, error) {
+	s.logger.Info("Adding network ACL entry", "network_acl_id", id, "rule_number", req.RuleNumber, "direction", req.Direction)
+
+	acl, err := s.getNetworkACL(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.NetworkACLEntry{
+		ID:           uuid.New().String(),
+		NetworkACLID: acl.ID,
+		RuleNumber:   req.RuleNumber,
+		Direction:    req.Direction,
+		Protocol:     req.Protocol,
+		FromPort:     req.FromPort,
+		ToPort:       req.ToPort,
+		CIDRBlock:    req.CIDRBlock,
+		Action:       req.Action,
+		Description:  req.Description,
+	}
+	if err := validateNetworkACLEntry(entry); err != nil {
+		s.logger.Warn("Invalid network ACL entry", "error", err, "network_acl_id", id)
+		return nil, errors.ErrInvalidNetworkACLEntry
+	}
+
+	existing, err := s.aclRepo.GetEntryByRuleNumber(acl.ID, entry.Direction, entry.RuleNumber)
+	if err != nil {
+		s.logger.Error("Failed to check rule number", "error", err, "rule_number", entry.RuleNumber)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check network ACL rule number")
+	}
+	if existing != nil {
+		s.logger.Warn("Network ACL rule number already in use", "network_acl_id", acl.ID, "rule_number", entry.RuleNumber)
+		return nil, errors.ErrNetworkACLRuleNumberExists
+	}
+
+	err = s.syncFlows(acl, func() error {
+		return s.aclRepo.CreateEntry(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Network ACL entry added", "network_acl_id", acl.ID, "entry_id", entry.ID)
+	return entry, nil
+}
+
+func (s *networkACLService) RemoveEntry(id string, entryID string, userID string) error {
+	s.logger.Info("Removing network ACL entry", "network_acl_id", id, "entry_id", entryID)
+
+	acl, err := s.getNetworkACL(id, userID)
+	if err != nil {
+		return err
+	}
+
+	entry, err := s.aclRepo.GetEntry(acl.ID, entryID)
+	if err != nil {
+		s.logger.Error("Failed to get network ACL entry", "error", err, "entry_id", entryID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get network ACL entry")
+	}
+	if entry == nil {
+		return errors.ErrNetworkACLEntryNotFound
+	}
+
+	return s.syncFlows(acl, func() error {
+		return s.aclRepo.DeleteEntry(acl.ID, entry.ID)
+	})
+}
+
+func (s *networkACLService) AssociateSubnet(id string, userID string, subnetID string) error {
+	s.logger.Info("Associating subnet with network ACL", "network_acl_id", id, "subnet_id", subnetID)
+
+	acl, err := s.getNetworkACL(id, userID)
+	if err != nil {
+		return err
+	}
+
+	subnet, err := s.subnetRepo.GetByID(subnetID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet", "error", err, "subnet_id", subnetID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet")
+	}
+	if subnet == nil || subnet.VPCID != acl.VPCID {
+		s.logger.Warn("Subnet not found in network ACL VPC", "subnet_id", subnetID, "vpc_id", acl.VPCID)
+		return errors.ErrSubnetNotFound
+	}
+
+	// A previous association is replaced, so that ACL loses the subnet in
+	// the same bundle
+	previous, err := s.aclRepo.GetAssociatedACLID(subnet.ID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet association", "error", err, "subnet_id", subnet.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet association")
+	}
+	if previous != nil && *previous == acl.ID {
+		return nil
+	}
+
+	return s.syncFlows(acl, func() error {
+		return s.aclRepo.Associate(acl.ID, subnet.ID)
+	})
+}
+
+func (s *networkACLService) DisassociateSubnet(id string, userID string, subnetID string) error {
+	s.logger.Info("Disassociating subnet from network ACL", "network_acl_id", id, "subnet_id", subnetID)
+
+	acl, err := s.getNetworkACL(id, userID)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.aclRepo.GetAssociatedACLID(subnetID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet association", "error", err, "subnet_id", subnetID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet association")
+	}
+	if current == nil || *current != acl.ID {
+		return errors.ErrSubnetNotFound
+	}
+
+	return s.syncFlows(acl, func() error {
+		return s.aclRepo.Disassociate(acl.ID, subnetID)
+	})
+}
+
+// syncFlows applies a change and replaces the network ACL flows of the VPC
+// bridge with those of every ACL of the VPC in one bundle, so a subnet
+// moving between ACLs is never left with both or neither
+func (s *networkACLService) syncFlows(acl *models.NetworkACL, change func() error) error {
+	if err := change(); err != nil {
+		s.logger.Error("Failed to update network ACL", "error", err, "network_acl_id", acl.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to update network ACL")
+	}
+
+	set, err := s.compileVPC(acl.VPCID)
+	if err != nil {
+		return err
+	}
+
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, acl.VPCID)
+	if err != nil {
+		return err
+	}
+	if err := set.Apply(s.ovsManager, bridgeName); err != nil {
+		s.logger.Error("Failed to program network ACL flows", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to program network ACL flows")
+	}
+
+	return nil
+}
+
+// DesiredFlows returns the network ACL flows of a VPC bridge
+func (s *networkACLService) DesiredFlows(vpc *models.VPC) (*network.FlowSet, error) {
+	return s.compileVPC(vpc.ID)
+}
+
+// compileVPC compiles every network ACL of a VPC
+func (s *networkACLService) compileVPC(vpcID string) (*network.FlowSet, error) {
+	acls, err := s.aclRepo.ListByVPC(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list network ACLs", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list network ACLs")
+	}
+
+	set := &network.FlowSet{
+		Cookie: network.KindCookie(network.CookieKindNetworkACL),
+		Mask:   network.CookieMaskKind,
+	}
+	for _, acl := range acls {
+		aclFlows, err := s.compileACL(acl.ID)
+		if err != nil {
+			return nil, err
+		}
+		cookie := network.FlowCookie(network.CookieKindNetworkACL, acl.ID)
+		set.Flows = append(set.Flows, network.WithCookie(aclFlows, cookie)...)
+	}
+
+	return set, nil
+}
+
+// compileACL compiles the entries of an ACL for its subnets
+func (s *networkACLService) compileACL(aclID string) ([]network.Flow, error) {
+	subnets, err := s.aclRepo.ListAssociatedSubnets(aclID)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list associated subnets")
+	}
+	if len(subnets) == 0 {
+		return nil, nil
+	}
+	entries, err := s.aclRepo.ListEntries(aclID)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list network ACL entries")
+	}
+
+	subnetCIDRs := make([]string, 0, len(subnets))
+	for _, subnet := range subnets {
+		subnetCIDRs = append(subnetCIDRs, subnet.CIDRBlock)
+		if subnet.IPv6CIDRBlock != nil {
+			subnetCIDRs = append(subnetCIDRs, *subnet.IPv6CIDRBlock)
+		}
+	}
+	rules := make([]network.NetworkACLRule, len(entries))
+	for i, entry := range entries {
+		rules[i] = network.NetworkACLRule{
+			RuleNumber: entry.RuleNumber,
+			Direction:  entry.Direction,
+			Protocol:   entry.Protocol,
+			FromPort:   entry.FromPort,
+			ToPort:     entry.ToPort,
+			CIDRBlock:  entry.CIDRBlock,
+			Action:     entry.Action,
+		}
+	}
+
+	flows, err := network.CompileNetworkACL(subnetCIDRs, rules)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile network ACL")
+	}
+	return flows, nil
+}
+
+// validateNetworkACLEntry checks ports against the protocol, and the ICMP
+// version against the family of the CIDR block, and normalizes the CIDR block
+func validateNetworkACLEntry(entry *models.NetworkACLEntry) error {
+	switch entry.Protocol {
+	case "tcp", "udp":
+		if entry.FromPort < 0 || entry.ToPort < 0 || entry.FromPort > entry.ToPort {
+			return fmt.Errorf("invalid port range %d-%d", entry.FromPort, entry.ToPort)
+		}
+	case "icmp", "icmpv6":
+		if entry.FromPort > 255 || entry.ToPort > 255 {
+			return fmt.Errorf("invalid ICMP type/code %d/%d", entry.FromPort, entry.ToPort)
+		}
+	case "all":
+		entry.FromPort, entry.ToPort = 0, 65535
+	default:
+		return fmt.Errorf("unsupported protocol %s", entry.Protocol)
+	}
+
+	_, ipNet, err := net.ParseCIDR(entry.CIDRBlock)
+	if err != nil {
+		return err
+	}
+	ipv6 := ipNet.IP.To4() == nil
+	if (entry.Protocol == "icmp" && ipv6) || (entry.Protocol == "icmpv6" && !ipv6) {
+		return fmt.Errorf("protocol %s does not apply to %s", entry.Protocol, entry.CIDRBlock)
+	}
+	entry.CIDRBlock = ipNet.String()
+
+	return nil
+}
+
+func (s *networkACLService) getNetworkACL(id string, userID string) (*models.NetworkACL, error) {
+	acl, err := s.aclRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get network ACL", "error", err, "network_acl_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get network ACL")
+	}
+	if acl == nil {
+		return nil, errors.ErrNetworkACLNotFound
+	}
+	return acl, nil
+}
+
+// loadDetails fills in the associated subnets and the entries of both
+// directions in evaluation order
+func (s *networkACLService) loadDetails(acl *models.NetworkACL) error {
+	subnets, err := s.aclRepo.ListAssociatedSubnets(acl.ID)
+	if err != nil {
+		s.logger.Error("Failed to list associated subnets", "error", err, "network_acl_id", acl.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list associated subnets")
+	}
+	acl.SubnetIDs = make([]string, len(subnets))
+	for i, subnet := range subnets {
+		acl.SubnetIDs[i] = subnet.ID
+	}
+
+	entries, err := s.aclRepo.ListEntries(acl.ID)
+	if err != nil {
+		s.logger.Error("Failed to list network ACL entries", "error", err, "network_acl_id", acl.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list network ACL entries")
+	}
+
+	acl.InboundEntries = []models.NetworkACLEntry{}
+	acl.OutboundEntries = []models.NetworkACLEntry{}
+	for _, entry := range entries {
+		if entry.Direction == "inbound" {
+			acl.InboundEntries = append(acl.InboundEntries, entry)
+		} else {
+			acl.OutboundEntries = append(acl.OutboundEntries, entry)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// NetworkLimitsService decides how much bandwidth instances get. The network
+// of an instance's type limits both what the instance receives and what it
+// sends, unless an administrator overrides either for the instance. The
+// limits are enforced on the instance's port: OVS polices what the port
+// receives and shapes what it sends with an HTB queue.
+type NetworkLimitsService interface {
+	GetNetworkLimits(instanceID, userID string) (*dto.NetworkLimitsResponse, error)
+	UpdateNetworkLimits(instanceID string, req *dto.UpdateNetworkLimitsRequest) (*dto.NetworkLimitsResponse, error)
+	DesiredPortQoS(vpcID string) (map[string]network.PortQoS, error)
+}
+
+type networkLimitsService struct {
+	instanceRepo repositories.InstanceRepository
+	vpcRepo      repositories.VPCRepository
+	ovsManager   network.OVSManager
+	logger       *utils.Logger
+}
+
+func NewNetworkLimitsService(
+	instanceRepo repositories.InstanceRepository,
+	vpcRepo repositories.VPCRepository,
+	ovsManager network.OVSManager,
+	logger *utils.Logger,
+) NetworkLimitsService {
+	return &networkLimitsService{
+		instanceRepo: instanceRepo,
+		vpcRepo:      vpcRepo,
+		ovsManager:   ovsManager,
+		logger:       logger,
+	}
+}
+
+// GetNetworkLimits returns the network limits of one of the user's instances
+func (s *networkLimitsService) GetNetworkLimits(instanceID, userID string) (*dto.NetworkLimitsResponse, error) {
+	limits, err := s.getLimits(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if limits.UserID != userID {
+		return nil, errors.ErrInstanceNotFound
+	}
+
+	return s.resolve(limits), nil
+}
+
+// UpdateNetworkLimits replaces the overrides of an instance's network
+// limits. The port is limited right away if the instance runs on this node;
+// elsewhere the network controller of its node catches up.
+func (s *networkLimitsService) UpdateNetworkLimits(instanceID string, req *dto.UpdateNetworkLimitsRequest) (*dto.NetworkLimitsResponse, error) {
+	s.logger.Info("Updating instance network limits", "instance_id", instanceID)
+
+	limits, err := s.getLimits(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.instanceRepo.UpdateNetworkLimits(instanceID, req.InboundMbps, req.OutboundMbps, req.BurstKb); err != nil {
+		s.logger.Error("Failed to update instance network limits", "error", err, "instance_id", instanceID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to update instance network limits")
+	}
+	limits.InboundMbps = req.InboundMbps
+	limits.OutboundMbps = req.OutboundMbps
+	limits.BurstKb = req.BurstKb
+
+	result := s.resolve(limits)
+	if limits.VPCID != "" {
+		if err := s.applyPortQoS(limits.VPCID, instanceID, result.Effective); err != nil {
+			s.logger.Warn("Failed to limit instance port, leaving it to the network controller", "error", err, "instance_id", instanceID)
+		}
+	}
+
+	s.logger.Info("Instance network limits updated", "instance_id", instanceID, "inbound_mbps", result.Effective.InboundMbps, "outbound_mbps", result.Effective.OutboundMbps)
+	return result, nil
+}
+
+// DesiredPortQoS returns the QoS of the port of every instance of a VPC
+// that has not been terminated, by port name
+func (s *networkLimitsService) DesiredPortQoS(vpcID string) (map[string]network.PortQoS, error) {
+	rows, err := s.instanceRepo.ListNetworkLimits(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list instance network limits", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list instance network limits")
+	}
+
+	ports := make(map[string]network.PortQoS, len(rows))
+	for i := range rows {
+		effective := s.resolve(&rows[i]).Effective
+		ports[network.InstancePortName(rows[i].InstanceID)] = network.InstanceQoS(effective.InboundMbps, effective.OutboundMbps, effective.BurstKb)
+	}
+	return ports, nil
+}
+
+// resolve works out the limits in effect on an instance. The limit of an
+// instance type whose network gives no rate is left unenforced rather than
+// guessed.
+func (s *networkLimitsService) resolve(limits *repositories.InstanceNetworkLimits) *dto.NetworkLimitsResponse {
+	typeMbps := 0
+	if limits.TypeNetwork != "" {
+		mbps, err := network.ParseBandwidth(limits.TypeNetwork)
+		if err != nil {
+			s.logger.Debug("Instance type network gives no bandwidth", "error", err, "instance_type", limits.InstanceType)
+		} else {
+			typeMbps = mbps
+		}
+	}
+
+	effective := dto.NetworkLimits{InboundMbps: typeMbps, OutboundMbps: typeMbps}
+	if limits.InboundMbps != nil {
+		effective.InboundMbps = *limits.InboundMbps
+	}
+	if limits.OutboundMbps != nil {
+		effective.OutboundMbps = *limits.OutboundMbps
+	}
+	// Bursts only apply to policed traffic
+	if effective.OutboundMbps > 0 {
+		effective.BurstKb = network.DefaultBurstKb(effective.OutboundMbps)
+		if limits.BurstKb != nil {
+			effective.BurstKb = *limits.BurstKb
+		}
+	}
+
+	return &dto.NetworkLimitsResponse{
+		InstanceID:        limits.InstanceID,
+		InstanceType:      limits.InstanceType,
+		TypeNetwork:       limits.TypeNetwork,
+		TypeBandwidthMbps: typeMbps,
+		Overrides: dto.NetworkLimitOverrides{
+			InboundMbps:  limits.InboundMbps,
+			OutboundMbps: limits.OutboundMbps,
+			BurstKb:      limits.BurstKb,
+		},
+		Effective: effective,
+	}
+}
+
+// applyPortQoS limits the port of an instance if it is on this node's
+// bridge of the VPC
+func (s *networkLimitsService) applyPortQoS(vpcID, instanceID string, limits dto.NetworkLimits) error {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpcID)
+	if err != nil {
+		return err
+	}
+
+	ports, err := s.ovsManager.ListPorts(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to list ports: %w", err)
+	}
+	portName := network.InstancePortName(instanceID)
+	for _, port := range ports {
+		if port.Name == portName {
+			return s.ovsManager.SetPortQoS(bridgeName, portName, network.InstanceQoS(limits.InboundMbps, limits.OutboundMbps, limits.BurstKb))
+		}
+	}
+	return nil
+}
+
+// getLimits returns what the network limits of an instance derive from
+func (s *networkLimitsService) getLimits(instanceID string) (*repositories.InstanceNetworkLimits, error) {
+	limits, err := s.instanceRepo.GetNetworkLimits(instanceID)
+	if err != nil {
+		s.logger.Error("Failed to get instance network limits", "error", err, "instance_id", instanceID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get instance network limits")
+	}
+	if limits == nil {
+		return nil, errors.ErrInstanceNotFound
+	}
+	return limits, nil
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// Worker node lifecycle states
+const (
+	WorkerNodeStateActive = "active"
+)
+
+// OverlayService keeps the worker node registry and the tunnel overlay that
+// joins the bridges of a VPC across nodes. Whatever places instances on
+// nodes calls SyncVPC once an instance's node or address changes.
+type OverlayService interface {
+	RegisterNode(req *dto.RegisterWorkerNodeRequest) (*models.WorkerNode, error)
+	GetNode(id string) (*models.WorkerNode, error)
+	ListNodes() ([]dto.WorkerNodeResponse, error)
+	RemoveNode(id string) error
+	SyncVPC(vpcID string) error
+	RebuildMesh() error
+	DesiredFlows(vpc *models.VPC) (*network.FlowSet, error)
+}
+
+type overlayService struct {
+	nodeRepo   repositories.WorkerNodeRepository
+	ovsManager network.OVSManager
+	nodeName   string
+	tunnelType string
+	logger     *utils.Logger
+}
+
+func NewOverlayService(
+	nodeRepo repositories.WorkerNodeRepository,
+	ovsManager network.OVSManager,
+	nodeName string,
+	tunnelType string,
+	logger *utils.Logger,
+) OverlayService {
+	return &overlayService{
+		nodeRepo:   nodeRepo,
+		ovsManager: ovsManager,
+		nodeName:   nodeName,
+		tunnelType: tunnelType,
+		logger:     logger,
+	}
+}
+
+// RegisterNode adds a worker node to the mesh. Every local VPC bridge starts
+// accepting tunnel traffic from it right away; traffic is sent to it once
+// instances are placed there.
+func (s *overlayService) RegisterNode(req *dto.RegisterWorkerNodeRequest) (*models.WorkerNode, error) {
+	s.logger.Info("Registering worker node", "name", req.Name, "tunnel_ip", req.TunnelIP)
+
+	tunnelIP := net.ParseIP(req.TunnelIP).To4()
+	if tunnelIP == nil {
+		s.logger.Warn("Invalid tunnel IP address", "tunnel_ip", req.TunnelIP)
+		return nil, errors.ErrInvalidTunnelIP
+	}
+
+	existing, err := s.nodeRepo.GetByName(req.Name)
+	if err != nil {
+		s.logger.Error("Failed to check name conflict", "error", err, "name", req.Name)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check worker node name")
+	}
+	if existing == nil {
+		existing, err = s.nodeRepo.GetByTunnelIP(tunnelIP.String())
+		if err != nil {
+			s.logger.Error("Failed to check tunnel IP conflict", "error", err, "tunnel_ip", tunnelIP.String())
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check worker node tunnel IP")
+		}
+	}
+	if existing != nil {
+		s.logger.Warn("Worker node already exists", "name", req.Name, "tunnel_ip", tunnelIP.String())
+		return nil, errors.ErrWorkerNodeExists
+	}
+
+	now := time.Now()
+	node := &models.WorkerNode{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		TunnelIP:  tunnelIP.String(),
+		State:     WorkerNodeStateActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = s.updateMesh(func() error {
+		if err := s.nodeRepo.Create(node); err != nil {
+			s.logger.Error("Failed to create worker node in database", "error", err, "node_id", node.ID)
+			return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to register worker node")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Worker node registered successfully", "node_id", node.ID, "name", node.Name)
+	return node, nil
+}
+
+func (s *overlayService) GetNode(id string) (*models.WorkerNode, error) {
+	node, err := s.nodeRepo.GetByID(id)
+	if err != nil {
+		s.logger.Error("Failed to get worker node", "error", err, "node_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get worker node")
+	}
+	if node == nil {
+		return nil, errors.ErrWorkerNodeNotFound
+	}
+	return node, nil
+}
+
+func (s *overlayService) ListNodes() ([]dto.WorkerNodeResponse, error) {
+	nodes, err := s.nodeRepo.List()
+	if err != nil {
+		s.logger.Error("Failed to list worker nodes", "error", err)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list worker nodes")
+	}
+
+	nodeResponses := make([]dto.WorkerNodeResponse, len(nodes))
+	for i := range nodes {
+		nodeResponses[i] = dto.ToWorkerNodeResponse(&nodes[i])
+	}
+	return nodeResponses, nil
+}
+
+// RemoveNode takes a worker node out of the mesh. Nodes that still run
+// instances are refused, their instances would become unreachable.
+func (s *overlayService) RemoveNode(id string) error {
+	s.logger.Info("Removing worker node", "node_id", id)
+
+	node, err := s.GetNode(id)
+	if err != nil {
+		return err
+	}
+
+	instances, err := s.nodeRepo.CountInstances(node.ID)
+	if err != nil {
+		s.logger.Error("Failed to count instances on worker node", "error", err, "node_id", node.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check worker node usage")
+	}
+	if instances > 0 {
+		s.logger.Warn("Worker node still runs instances", "node_id", node.ID, "instances", instances)
+		return errors.ErrResourceInUse
+	}
+
+	err = s.updateMesh(func() error {
+		if err := s.nodeRepo.Delete(node.ID); err != nil {
+			s.logger.Error("Failed to delete worker node", "error", err, "node_id", node.ID)
+			return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to remove worker node")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Worker node removed successfully", "node_id", node.ID, "name", node.Name)
+	return nil
+}
+
+// SyncVPC makes sure the VPC bridge has its tunnel port and replaces its
+// overlay flows in one bundle
+func (s *overlayService) SyncVPC(vpcID string) error {
+	tunnel, err := s.nodeRepo.GetVPCTunnel(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC tunnel", "error", err, "vpc_id", vpcID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC tunnel key")
+	}
+	if tunnel == nil {
+		s.logger.Error("VPC has no tunnel key", "vpc_id", vpcID)
+		return errors.New(errors.ErrorTypeInternal, "DB_ERROR", "VPC has no tunnel key")
+	}
+
+	peers, err := s.listPeers()
+	if err != nil {
+		return err
+	}
+	set, err := s.compileOverlay(tunnel, peers)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureTunnelPort(tunnel); err != nil {
+		return err
+	}
+	if err := s.replaceFlows(tunnel, set); err != nil {
+		return err
+	}
+
+	s.logger.Info("VPC overlay synchronized", "vpc_id", vpcID, "vni", tunnel.VNI, "peers", len(peers))
+	return nil
+}
+
+// RebuildMesh reprograms the overlay of every VPC, for recovery after the
+// bridges and the registry went out of sync
+func (s *overlayService) RebuildMesh() error {
+	s.logger.Info("Rebuilding tunnel mesh", "node_name", s.nodeName)
+
+	tunnels, err := s.nodeRepo.ListVPCTunnels()
+	if err != nil {
+		s.logger.Error("Failed to list VPC tunnels", "error", err)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list VPC tunnels")
+	}
+
+	for _, tunnel := range tunnels {
+		if err := s.SyncVPC(tunnel.VPCID); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("Tunnel mesh rebuilt", "vpcs", len(tunnels))
+	return nil
+}
+
+// updateMesh applies a change to the worker nodes and reprograms the overlay
+// flows of every VPC. Each bridge gets its flows in one bundle, so traffic to
+// nodes that stay in the mesh is never interrupted.
+func (s *overlayService) updateMesh(change func() error) error {
+	if err := change(); err != nil {
+		return err
+	}
+
+	tunnels, err := s.nodeRepo.ListVPCTunnels()
+	if err != nil {
+		s.logger.Error("Failed to list VPC tunnels", "error", err)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list VPC tunnels")
+	}
+	peers, err := s.listPeers()
+	if err != nil {
+		return err
+	}
+
+	for i := range tunnels {
+		set, err := s.compileOverlay(&tunnels[i], peers)
+		if err != nil {
+			return err
+		}
+		if err := s.ensureTunnelPort(&tunnels[i]); err != nil {
+			return err
+		}
+		if err := s.replaceFlows(&tunnels[i], set); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listPeers returns the tunnel endpoints of every other active worker node
+func (s *overlayService) listPeers() ([]string, error) {
+	nodes, err := s.nodeRepo.ListPeers(s.nodeName)
+	if err != nil {
+		s.logger.Error("Failed to list worker node peers", "error", err, "node_name", s.nodeName)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list worker nodes")
+	}
+
+	peers := make([]string, len(nodes))
+	for i, node := range nodes {
+		peers[i] = node.TunnelIP
+	}
+	return peers, nil
+}
+
+// DesiredFlows returns the overlay flows of a VPC bridge
+func (s *overlayService) DesiredFlows(vpc *models.VPC) (*network.FlowSet, error) {
+	tunnel, err := s.nodeRepo.GetVPCTunnel(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC tunnel", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC tunnel key")
+	}
+	if tunnel == nil {
+		return nil, errors.New(errors.ErrorTypeInternal, "DB_ERROR", "VPC has no tunnel key")
+	}
+
+	peers, err := s.listPeers()
+	if err != nil {
+		return nil, err
+	}
+	return s.compileOverlay(tunnel, peers)
+}
+
+// compileOverlay returns the overlay flows of a VPC bridge for the given peers
+func (s *overlayService) compileOverlay(tunnel *repositories.VPCTunnel, peers []string) (*network.FlowSet, error) {
+	rows, err := s.nodeRepo.ListRemoteEndpoints(tunnel.VPCID, s.nodeName)
+	if err != nil {
+		s.logger.Error("Failed to list remote endpoints", "error", err, "vpc_id", tunnel.VPCID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list remote instances")
+	}
+	remotes := make([]network.RemoteEndpoint, len(rows))
+	for i, row := range rows {
+		remotes[i] = network.RemoteEndpoint{PrivateIP: row.PrivateIP, TunnelIP: row.TunnelIP}
+	}
+
+	flows, err := network.CompileOverlay(network.TunnelPortName(tunnel.BridgeName), tunnel.VNI, tunnel.Zone, peers, remotes)
+	if err != nil {
+		s.logger.Error("Failed to compile overlay flows", "error", err, "vpc_id", tunnel.VPCID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile overlay flows")
+	}
+	return &network.FlowSet{
+		Cookie: network.FlowCookie(network.CookieKindOverlay, tunnel.VPCID),
+		Mask:   network.CookieMaskResource,
+		Flows:  flows,
+	}, nil
+}
+
+// ensureTunnelPort adds the tunnel port of a VPC bridge if it is missing
+func (s *overlayService) ensureTunnelPort(tunnel *repositories.VPCTunnel) error {
+	bridgeName := tunnel.BridgeName
+	tunnelPort := network.TunnelPortName(bridgeName)
+	if err := s.ovsManager.AddTunnelPort(bridgeName, tunnelPort, s.tunnelType, tunnel.VNI); err != nil {
+		s.logger.Error("Failed to add tunnel port", "error", err, "bridge_name", bridgeName, "port", tunnelPort)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to add tunnel port")
+	}
+	return nil
+}
+
+// replaceFlows swaps the overlay flows of a VPC bridge for set
+func (s *overlayService) replaceFlows(tunnel *repositories.VPCTunnel, set *network.FlowSet) error {
+	bridgeName := tunnel.BridgeName
+	if err := set.Apply(s.ovsManager, bridgeName); err != nil {
+		s.logger.Error("Failed to program overlay flows", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to program overlay flows")
+	}
+	return nil
+}
@@ -0,0 +1,533 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// Stages of the path reported by the reachability analyzer, in the order
+// the VPC bridge pipeline applies them
+const (
+	ReachabilityStageACLEgress   = "network_acl_egress"
+	ReachabilityStageACLIngress  = "network_acl_ingress"
+	ReachabilityStageSGEgress    = "security_group_egress"
+	ReachabilityStageSGIngress   = "security_group_ingress"
+	ReachabilityStageRouteTable  = "route_table"
+	ReachabilityStageGateway     = "gateway"
+	ReachabilityStageDestination = "destination"
+)
+
+// Hop verdicts
+const (
+	ReachabilityAllow   = "allow"
+	ReachabilityDeny    = "deny"
+	ReachabilityForward = "forward"
+)
+
+type ReachabilityService interface {
+	Analyze(userID string, req *dto.ReachabilityRequest) (*dto.ReachabilityResponse, error)
+}
+
+type reachabilityService struct {
+	vpcRepo           repositories.VPCRepository
+	subnetRepo        repositories.SubnetRepository
+	ipAllocationRepo  repositories.IPAllocationRepository
+	aclRepo           repositories.NetworkACLRepository
+	sgRepo            repositories.SecurityGroupRepository
+	routeTableRepo    repositories.RouteTableRepository
+	igwRepo           repositories.InternetGatewayRepository
+	natRepo           repositories.NATGatewayRepository
+	routeTableService RouteTableService
+	ovsManager        network.OVSManager
+	logger            *utils.Logger
+}
+
+func NewReachabilityService(
+	vpcRepo repositories.VPCRepository,
+	subnetRepo repositories.SubnetRepository,
+	ipAllocationRepo repositories.IPAllocationRepository,
+	aclRepo repositories.NetworkACLRepository,
+	sgRepo repositories.SecurityGroupRepository,
+	routeTableRepo repositories.RouteTableRepository,
+	igwRepo repositories.InternetGatewayRepository,
+	natRepo repositories.NATGatewayRepository,
+	routeTableService RouteTableService,
+	ovsManager network.OVSManager,
+	logger *utils.Logger,
+) ReachabilityService {
+	return &reachabilityService{
+		vpcRepo:           vpcRepo,
+		subnetRepo:        subnetRepo,
+		ipAllocationRepo:  ipAllocationRepo,
+		aclRepo:           aclRepo,
+		sgRepo:            sgRepo,
+		routeTableRepo:    routeTableRepo,
+		igwRepo:           igwRepo,
+		natRepo:           natRepo,
+		routeTableService: routeTableService,
+		ovsManager:        ovsManager,
+		logger:            logger,
+	}
+}
+
+// endpoint is a resolved source or destination. subnet is nil for
+// addresses outside every subnet of the VPC and allocation is nil for
+// addresses no instance holds.
+type endpoint struct {
+	ip         string
+	subnet     *models.Subnet
+	allocation *models.IPAllocation
+}
+
+// Analyze walks the first packet of a new connection through the network
+// ACLs, security groups and route table that apply to it, in pipeline order,
+// and stops at the first stage that drops it. Replies are not analyzed:
+// security groups admit them through conntrack, but network ACLs are
+// stateless and need their own entries for the return traffic.
+func (s *reachabilityService) Analyze(userID string, req *dto.ReachabilityRequest) (*dto.ReachabilityResponse, error) {
+	s.logger.Info("Analyzing reachability", "user_id", userID, "vpc_id", req.VPCID,
+		"source", req.Source, "destination", req.Destination, "protocol", req.Protocol, "port", req.Port)
+
+	if req.Protocol == "icmp" && req.Port > 255 {
+		return nil, errors.ErrInvalidRequest
+	}
+
+	vpc, err := s.vpcRepo.GetByID(req.VPCID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC", "error", err, "vpc_id", req.VPCID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if vpc == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+
+	subnets, err := s.subnetRepo.ListByVPC(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to list subnets", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list subnets")
+	}
+
+	src, err := s.resolveEndpoint(vpc, subnets, req.Source)
+	if err != nil {
+		return nil, err
+	}
+	if src.allocation == nil {
+		s.logger.Warn("Source is not an address in the VPC", "source", req.Source, "vpc_id", vpc.ID)
+		return nil, errors.ErrInvalidEndpoint
+	}
+	dst, err := s.resolveEndpoint(vpc, subnets, req.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.ReachabilityResponse{
+		SourceIP:      src.ip,
+		DestinationIP: dst.ip,
+		Protocol:      req.Protocol,
+		Port:          req.Port,
+	}
+	if result.Hops, result.Reachable, err = s.walk(vpc, userID, src, dst, network.Probe{Protocol: req.Protocol, Port: req.Port}); err != nil {
+		return nil, err
+	}
+
+	if req.Trace {
+		flow, err := traceFlow(src.ip, dst.ip, req.Protocol, req.Port)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to build trace flow")
+		}
+		bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpc.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Trace, err = s.ovsManager.TraceFlow(bridgeName, flow)
+		if err != nil {
+			s.logger.Error("Failed to trace flow", "error", err, "vpc_id", vpc.ID, "flow", flow)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to trace flow")
+		}
+	}
+
+	s.logger.Info("Reachability analyzed", "vpc_id", vpc.ID, "source_ip", src.ip,
+		"destination_ip", dst.ip, "reachable", result.Reachable)
+	return result, nil
+}
+
+// walk evaluates every stage in pipeline order until one denies the probe
+func (s *reachabilityService) walk(vpc *models.VPC, userID string, src, dst *endpoint, probe network.Probe) ([]dto.ReachabilityHop, bool, error) {
+	hops := make([]dto.ReachabilityHop, 0)
+	stages := []func() ([]dto.ReachabilityHop, error){
+		func() ([]dto.ReachabilityHop, error) {
+			return s.checkNetworkACL(ReachabilityStageACLEgress, "outbound", src, dst, probe)
+		},
+		func() ([]dto.ReachabilityHop, error) {
+			return s.checkNetworkACL(ReachabilityStageACLIngress, "inbound", dst, src, probe)
+		},
+		func() ([]dto.ReachabilityHop, error) {
+			return s.checkSecurityGroups(ReachabilityStageSGEgress, "outbound", vpc.ID, src.ip, dst.ip, probe)
+		},
+		func() ([]dto.ReachabilityHop, error) {
+			return s.checkSecurityGroups(ReachabilityStageSGIngress, "inbound", vpc.ID, dst.ip, src.ip, probe)
+		},
+		func() ([]dto.ReachabilityHop, error) {
+			return s.checkRoute(vpc, userID, src, dst)
+		},
+	}
+
+	for _, stage := range stages {
+		stageHops, err := stage()
+		if err != nil {
+			return nil, false, err
+		}
+		hops = append(hops, stageHops...)
+		if len(stageHops) > 0 && stageHops[len(stageHops)-1].Verdict == ReachabilityDeny {
+			return hops, false, nil
+		}
+	}
+
+	return hops, true, nil
+}
+
+// resolveEndpoint turns an instance ID or IPv4 address into an endpoint
+func (s *reachabilityService) resolveEndpoint(vpc *models.VPC, subnets []models.Subnet, value string) (*endpoint, error) {
+	ep := &endpoint{ip: value}
+	if ip := net.ParseIP(value); ip == nil || ip.To4() == nil {
+		if _, err := uuid.Parse(value); err != nil {
+			return nil, errors.ErrInvalidEndpoint
+		}
+		ips, err := s.ipAllocationRepo.ListInstanceIPsInVPC(value, vpc.ID)
+		if err != nil {
+			s.logger.Error("Failed to list instance IPs", "error", err, "instance_id", value)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list instance IPs")
+		}
+		if len(ips) == 0 {
+			s.logger.Warn("Endpoint is not an instance in the VPC", "endpoint", value, "vpc_id", vpc.ID)
+			return nil, errors.ErrInvalidEndpoint
+		}
+		ep.ip = ips[0]
+	} else {
+		ep.ip = ip.To4().String()
+	}
+
+	for i := range subnets {
+		contains, err := network.CIDRContainsIP(subnets[i].CIDRBlock, ep.ip)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to match subnet")
+		}
+		if contains {
+			ep.subnet = &subnets[i]
+			break
+		}
+	}
+	if ep.subnet == nil {
+		return ep, nil
+	}
+
+	allocation, err := s.ipAllocationRepo.GetByIP(ep.subnet.ID, ep.ip)
+	if err != nil {
+		s.logger.Error("Failed to get IP allocation", "error", err, "ip_address", ep.ip)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get IP allocation")
+	}
+	ep.allocation = allocation
+
+	return ep, nil
+}
+
+// checkNetworkACL evaluates the ACL of local's subnet for traffic crossing
+// its boundary in direction. Traffic that stays inside the subnet and
+// addresses outside every subnet are not filtered.
+func (s *reachabilityService) checkNetworkACL(stage, direction string, local, remote *endpoint, probe network.Probe) ([]dto.ReachabilityHop, error) {
+	if local.subnet == nil {
+		return nil, nil
+	}
+	hop := dto.ReachabilityHop{Stage: stage, ResourceType: "subnet", ResourceID: local.subnet.ID}
+	if remote.subnet != nil && remote.subnet.ID == local.subnet.ID {
+		hop.Verdict = ReachabilityAllow
+		hop.Explanation = fmt.Sprintf("Traffic stays inside subnet %s, which network ACLs do not filter", local.subnet.CIDRBlock)
+		return []dto.ReachabilityHop{hop}, nil
+	}
+
+	aclID, err := s.aclRepo.GetAssociatedACLID(local.subnet.ID)
+	if err != nil {
+		s.logger.Error("Failed to get associated network ACL", "error", err, "subnet_id", local.subnet.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get associated network ACL")
+	}
+	if aclID == nil {
+		hop.Verdict = ReachabilityAllow
+		hop.Explanation = fmt.Sprintf("Subnet %s has no network ACL", local.subnet.CIDRBlock)
+		return []dto.ReachabilityHop{hop}, nil
+	}
+
+	entries, err := s.aclRepo.ListEntries(*aclID)
+	if err != nil {
+		s.logger.Error("Failed to list network ACL entries", "error", err, "network_acl_id", *aclID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list network ACL entries")
+	}
+	rules := make([]network.NetworkACLRule, len(entries))
+	for i, entry := range entries {
+		rules[i] = network.NetworkACLRule{
+			RuleNumber: entry.RuleNumber,
+			Direction:  entry.Direction,
+			Protocol:   entry.Protocol,
+			FromPort:   entry.FromPort,
+			ToPort:     entry.ToPort,
+			CIDRBlock:  entry.CIDRBlock,
+			Action:     entry.Action,
+		}
+	}
+
+	index, err := network.EvaluateNetworkACL(rules, direction, remote.ip, probe)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to evaluate network ACL")
+	}
+
+	hop.ResourceType, hop.ResourceID = "network_acl", *aclID
+	if index < 0 {
+		hop.Verdict = ReachabilityDeny
+		hop.Explanation = fmt.Sprintf("No %s entry matches, so the default deny applies", direction)
+		return []dto.ReachabilityHop{hop}, nil
+	}
+
+	entry := entries[index]
+	hop.RuleID = entry.ID
+	hop.Verdict = ReachabilityAllow
+	if entry.Action != "allow" {
+		hop.Verdict = ReachabilityDeny
+	}
+	hop.Explanation = fmt.Sprintf("Entry %d (%s %s %s) %ss the traffic",
+		entry.RuleNumber, direction, entry.Protocol, entry.CIDRBlock, entry.Action)
+	return []dto.ReachabilityHop{hop}, nil
+}
+
+// checkSecurityGroups evaluates the rules of every group local belongs to.
+// Addresses outside every group are not filtered; members need a rule in
+// direction whose source admits remote.
+func (s *reachabilityService) checkSecurityGroups(stage, direction, vpcID, local, remote string, probe network.Probe) ([]dto.ReachabilityHop, error) {
+	groupIDs, err := s.sgRepo.ListGroupIDsForIP(vpcID, local)
+	if err != nil {
+		s.logger.Error("Failed to list security groups", "error", err, "ip_address", local)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list security groups")
+	}
+	if len(groupIDs) == 0 {
+		return []dto.ReachabilityHop{{
+			Stage:       stage,
+			Verdict:     ReachabilityAllow,
+			Explanation: fmt.Sprintf("%s is not a member of any security group", local),
+		}}, nil
+	}
+
+	for _, groupID := range groupIDs {
+		rules, err := s.sgRepo.ListRules(groupID)
+		if err != nil {
+			s.logger.Error("Failed to list security group rules", "error", err, "security_group_id", groupID)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list security group rules")
+		}
+		for _, rule := range rules {
+			if rule.Direction != direction || !probe.MatchesRule(rule.Protocol, rule.FromPort, rule.ToPort) {
+				continue
+			}
+			admits, err := s.ruleAdmits(&rule, remote)
+			if err != nil {
+				return nil, err
+			}
+			if admits {
+				return []dto.ReachabilityHop{{
+					Stage:        stage,
+					Verdict:      ReachabilityAllow,
+					ResourceType: "security_group",
+					ResourceID:   groupID,
+					RuleID:       rule.ID,
+					Explanation:  fmt.Sprintf("Rule %s %s %d-%d with remote %s allows the traffic", direction, rule.Protocol, rule.FromPort, rule.ToPort, rule.Source),
+				}}, nil
+			}
+		}
+	}
+
+	return []dto.ReachabilityHop{{
+		Stage:        stage,
+		Verdict:      ReachabilityDeny,
+		ResourceType: "security_group",
+		ResourceID:   groupIDs[0],
+		Explanation:  fmt.Sprintf("No %s rule of security groups %s allows the traffic", direction, strings.Join(groupIDs, ", ")),
+	}}, nil
+}
+
+// ruleAdmits reports whether a rule's source, a CIDR block or a security
+// group, covers the remote address
+func (s *reachabilityService) ruleAdmits(rule *models.SecurityGroupRule, remote string) (bool, error) {
+	if _, _, err := net.ParseCIDR(rule.Source); err == nil {
+		contains, err := network.CIDRContainsIP(rule.Source, remote)
+		if err != nil {
+			return false, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to match security group rule")
+		}
+		return contains, nil
+	}
+
+	memberIPs, err := s.sgRepo.ListMemberIPs(rule.Source)
+	if err != nil {
+		s.logger.Error("Failed to list security group members", "error", err, "security_group_id", rule.Source)
+		return false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list security group members")
+	}
+	for _, ip := range memberIPs {
+		if ip == remote {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkRoute looks the destination up in the route table of the source
+// subnet the way the routing table does: the local route wins, otherwise the
+// longest prefix. It then follows the chosen target.
+func (s *reachabilityService) checkRoute(vpc *models.VPC, userID string, src, dst *endpoint) ([]dto.ReachabilityHop, error) {
+	tableID, err := s.routeTableRepo.GetAssociatedTableID(src.subnet.ID)
+	if err != nil {
+		s.logger.Error("Failed to get associated route table", "error", err, "subnet_id", src.subnet.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get associated route table")
+	}
+	if tableID == nil {
+		main, err := s.routeTableRepo.GetMain(vpc.ID)
+		if err != nil {
+			s.logger.Error("Failed to get main route table", "error", err, "vpc_id", vpc.ID)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get main route table")
+		}
+		if main == nil {
+			return nil, errors.ErrRouteTableNotFound
+		}
+		tableID = &main.ID
+	}
+
+	routeTable, err := s.routeTableService.GetRouteTable(*tableID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *models.Route
+	bestPrefix := -1
+	for i := range routeTable.Routes {
+		route := &routeTable.Routes[i]
+		ipNet, err := network.ParseIPv4CIDR(route.DestinationCIDR)
+		if err != nil || !ipNet.Contains(net.ParseIP(dst.ip)) {
+			continue
+		}
+		if route.TargetType == "local" {
+			best = route
+			break
+		}
+		if ones, _ := ipNet.Mask.Size(); ones > bestPrefix {
+			best, bestPrefix = route, ones
+		}
+	}
+
+	hop := dto.ReachabilityHop{Stage: ReachabilityStageRouteTable, ResourceType: "route_table", ResourceID: routeTable.ID}
+	if best == nil {
+		hop.Verdict = ReachabilityDeny
+		hop.Explanation = fmt.Sprintf("Route table %s has no route to %s", routeTable.Name, dst.ip)
+		return []dto.ReachabilityHop{hop}, nil
+	}
+	hop.RuleID = best.ID
+	if best.State == RouteStateBlackhole {
+		hop.Verdict = ReachabilityDeny
+		hop.Explanation = fmt.Sprintf("Route to %s is a blackhole: its %s target %s no longer exists", best.DestinationCIDR, best.TargetType, best.TargetID)
+		return []dto.ReachabilityHop{hop}, nil
+	}
+	hop.Verdict = ReachabilityForward
+	hop.Explanation = fmt.Sprintf("Route to %s targets %s %s", best.DestinationCIDR, best.TargetType, best.TargetID)
+	hops := []dto.ReachabilityHop{hop}
+
+	next, err := s.followTarget(vpc, best, src, dst)
+	if err != nil {
+		return nil, err
+	}
+	return append(hops, next), nil
+}
+
+// followTarget reports what happens to the packet at the target of its route
+func (s *reachabilityService) followTarget(vpc *models.VPC, route *models.Route, src, dst *endpoint) (dto.ReachabilityHop, error) {
+	switch route.TargetType {
+	case "local":
+		hop := dto.ReachabilityHop{Stage: ReachabilityStageDestination}
+		if dst.allocation == nil {
+			hop.Verdict = ReachabilityDeny
+			hop.Explanation = fmt.Sprintf("No instance in the VPC holds %s", dst.ip)
+			return hop, nil
+		}
+		hop.Verdict = ReachabilityAllow
+		hop.ResourceType, hop.ResourceID = "subnet", dst.subnet.ID
+		if dst.allocation.InstanceID != nil {
+			hop.ResourceType, hop.ResourceID = "instance", *dst.allocation.InstanceID
+		}
+		hop.Explanation = fmt.Sprintf("Delivered to %s in subnet %s", dst.ip, dst.subnet.CIDRBlock)
+		return hop, nil
+
+	case "igw":
+		hop := dto.ReachabilityHop{Stage: ReachabilityStageGateway, ResourceType: "internet_gateway", ResourceID: route.TargetID}
+		mappings, err := s.igwRepo.ListPublicIPMappings(vpc.ID)
+		if err != nil {
+			s.logger.Error("Failed to list public IP mappings", "error", err, "vpc_id", vpc.ID)
+			return hop, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list public IP mappings")
+		}
+		for _, mapping := range mappings {
+			if mapping.PrivateIP == src.ip {
+				hop.Verdict = ReachabilityAllow
+				hop.Explanation = fmt.Sprintf("Source is translated to its public IP %s and leaves through the internet gateway", mapping.PublicIP)
+				return hop, nil
+			}
+		}
+		hop.Verdict = ReachabilityDeny
+		hop.Explanation = fmt.Sprintf("%s has no public IP, so the internet gateway drops its traffic", src.ip)
+		return hop, nil
+
+	case "nat":
+		hop := dto.ReachabilityHop{Stage: ReachabilityStageGateway, ResourceType: "nat_gateway", ResourceID: route.TargetID}
+		natGateway, err := s.natRepo.GetInVPC(route.TargetID, vpc.ID)
+		if err != nil {
+			s.logger.Error("Failed to get NAT gateway", "error", err, "nat_gateway_id", route.TargetID)
+			return hop, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get NAT gateway")
+		}
+		if natGateway == nil {
+			hop.Verdict = ReachabilityDeny
+			hop.Explanation = "NAT gateway no longer exists"
+			return hop, nil
+		}
+		hop.Verdict = ReachabilityAllow
+		hop.Explanation = fmt.Sprintf("Source is translated to the NAT gateway's public IP %s and leaves through the internet gateway", natGateway.PublicIP)
+		return hop, nil
+
+	default:
+		return dto.ReachabilityHop{
+			Stage:        ReachabilityStageGateway,
+			Verdict:      ReachabilityForward,
+			ResourceType: route.TargetType,
+			ResourceID:   route.TargetID,
+			Explanation:  "Handed to the route target, which forwards it onwards; later hops are not analyzed",
+		}, nil
+	}
+}
+
+// traceFlow builds the microflow the source instance would send, for
+// ofproto/trace
+func traceFlow(srcIP, dstIP, protocol string, port int) (string, error) {
+	srcMAC, err := network.InstanceMAC(srcIP)
+	if err != nil {
+		return "", err
+	}
+	dstMAC, err := network.InstanceMAC(dstIP)
+	if err != nil {
+		return "", err
+	}
+
+	flow := fmt.Sprintf("in_port=LOCAL,%s,dl_src=%s,dl_dst=%s,nw_src=%s,nw_dst=%s", protocol, srcMAC, dstMAC, srcIP, dstIP)
+	if protocol == "icmp" {
+		return flow + fmt.Sprintf(",icmp_type=%d,icmp_code=0", port), nil
+	}
+	return flow + fmt.Sprintf(",tp_dst=%d", port), nil
+}
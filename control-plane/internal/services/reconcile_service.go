@@ -0,0 +1,664 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// ReconcileService drives the OVS state of this node towards the desired
+// state in the database. The API programs OVS as it goes; reconciling
+// repairs whatever a failure halfway through, a restart of OVS or a manual
+// change left behind.
+type ReconcileService interface {
+	Reconcile() (*dto.ReconcileReport, error)
+}
+
+// MetadataServer serves the metadata service on the VPC bridges of a node
+type MetadataServer interface {
+	Serve(bridgeName, vpcID string) (bool, error)
+	Stop(bridgeName string) error
+	List() []string
+}
+
+type reconcileService struct {
+	vpcRepo              repositories.VPCRepository
+	igwRepo              repositories.InternetGatewayRepository
+	securityGroupService SecurityGroupService
+	networkACLService    NetworkACLService
+	routeTableService    RouteTableService
+	igwService           InternetGatewayService
+	overlayService       OverlayService
+	dhcpService          DHCPService
+	metadataService      MetadataService
+	vpcPeeringService    VPCPeeringService
+	networkLimitsService NetworkLimitsService
+	flowLogService       FlowLogService
+	trafficMirrorService TrafficMirrorService
+	ovsManager           network.OVSManager
+	dhcpManager          network.DHCPManager
+	metadataServer       MetadataServer
+	uplinkBridge         string
+	logger               *utils.Logger
+}
+
+func NewReconcileService(
+	vpcRepo repositories.VPCRepository,
+	igwRepo repositories.InternetGatewayRepository,
+	securityGroupService SecurityGroupService,
+	networkACLService NetworkACLService,
+	routeTableService RouteTableService,
+	igwService InternetGatewayService,
+	overlayService OverlayService,
+	dhcpService DHCPService,
+	metadataService MetadataService,
+	vpcPeeringService VPCPeeringService,
+	networkLimitsService NetworkLimitsService,
+	flowLogService FlowLogService,
+	trafficMirrorService TrafficMirrorService,
+	ovsManager network.OVSManager,
+	dhcpManager network.DHCPManager,
+	metadataServer MetadataServer,
+	uplinkBridge string,
+	logger *utils.Logger,
+) ReconcileService {
+	return &reconcileService{
+		vpcRepo:              vpcRepo,
+		igwRepo:              igwRepo,
+		securityGroupService: securityGroupService,
+		networkACLService:    networkACLService,
+		routeTableService:    routeTableService,
+		igwService:           igwService,
+		overlayService:       overlayService,
+		dhcpService:          dhcpService,
+		metadataService:      metadataService,
+		vpcPeeringService:    vpcPeeringService,
+		networkLimitsService: networkLimitsService,
+		flowLogService:       flowLogService,
+		trafficMirrorService: trafficMirrorService,
+		ovsManager:           ovsManager,
+		dhcpManager:          dhcpManager,
+		metadataServer:       metadataServer,
+		uplinkBridge:         uplinkBridge,
+		logger:               logger,
+	}
+}
+
+// vpcFlowSet is the flow set of one kind of owner on a VPC bridge
+type vpcFlowSet struct {
+	name string
+	set  *network.FlowSet
+}
+
+// Reconcile makes one pass over every VPC: missing bridges and ports are
+// created, flows, DHCP responders, the QoS of instance ports, mirrors and
+// flow log exports that differ from what the database compiles to are
+// replaced, the metadata service is served on every bridge, and bridges,
+// gateway, peering and mirror ports, mirrors, flows, responders and
+// metadata listeners nothing owns are removed.
+// VPCs whose dataplane was never provisioned are left alone, as they are
+// still being created or their creation is being rolled back.
+func (s *reconcileService) Reconcile() (*dto.ReconcileReport, error) {
+	report := &dto.ReconcileReport{
+		StartedAt: time.Now(),
+		Changes:   []dto.ReconcileChange{},
+		Errors:    []string{},
+	}
+
+	vpcs, err := s.vpcRepo.ListAll()
+	if err != nil {
+		s.logger.Error("Failed to list VPCs", "error", err)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list VPCs")
+	}
+	rows, err := s.vpcRepo.ListDataplanes()
+	if err != nil {
+		s.logger.Error("Failed to list VPC dataplanes", "error", err)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list VPC dataplanes")
+	}
+	dataplanes := make(map[string]*models.VPCDataplane, len(rows))
+	owned := make(map[string]bool, len(rows))
+	for i := range rows {
+		dataplanes[rows[i].VPCID] = &rows[i]
+		owned[rows[i].BridgeName] = true
+	}
+
+	bridges, err := s.ovsManager.ListBridges()
+	if err != nil {
+		s.logger.Error("Failed to list bridges", "error", err)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to list bridges")
+	}
+	existing := make(map[string]*network.Bridge, len(bridges))
+	for i := range bridges {
+		existing[bridges[i].Name] = &bridges[i]
+	}
+
+	uplinkPorts := make(map[string]string) // uplink port -> peer on the VPC bridge
+	for i := range vpcs {
+		vpc := &vpcs[i]
+		dataplane := dataplanes[vpc.ID]
+		if dataplane == nil || dataplane.VNI == 0 || dataplane.ConntrackZone == 0 {
+			continue
+		}
+		report.VPCs++
+
+		attached, err := s.reconcileVPC(report, vpc, dataplane, existing[dataplane.BridgeName])
+		if err != nil {
+			s.logger.Error("Failed to reconcile VPC", "error", err, "vpc_id", vpc.ID)
+			report.Errors = append(report.Errors, fmt.Sprintf("vpc %s: %v", vpc.ID, err))
+		}
+		if attached {
+			vpcPort, uplinkPort := network.InternetGatewayPorts(dataplane.BridgeName)
+			uplinkPorts[uplinkPort] = vpcPort
+		}
+	}
+
+	// Bridges of deleted VPCs, e.g. when DeleteVPC failed to remove them
+	for _, bridge := range bridges {
+		if !strings.HasPrefix(bridge.Name, network.VPCBridgePrefix) || owned[bridge.Name] {
+			continue
+		}
+		if err := s.ovsManager.DeleteBridge(bridge.Name); err != nil {
+			s.logger.Error("Failed to delete orphaned bridge", "error", err, "bridge_name", bridge.Name)
+			report.Errors = append(report.Errors, fmt.Sprintf("bridge %s: %v", bridge.Name, err))
+			continue
+		}
+		s.record(report, dto.ReconcileChange{Resource: "bridge", Name: bridge.Name, Action: "deleted", Detail: "no VPC owns the bridge"})
+	}
+
+	// Responders of deleted VPCs
+	responders, err := s.dhcpManager.List()
+	if err != nil {
+		s.logger.Error("Failed to list DHCP responders", "error", err)
+		report.Errors = append(report.Errors, fmt.Sprintf("dhcp: %v", err))
+	}
+	for _, bridgeName := range responders {
+		if owned[bridgeName] {
+			continue
+		}
+		if err := s.dhcpManager.Stop(bridgeName); err != nil {
+			s.logger.Error("Failed to stop orphaned DHCP responder", "error", err, "bridge_name", bridgeName)
+			report.Errors = append(report.Errors, fmt.Sprintf("dhcp %s: %v", bridgeName, err))
+			continue
+		}
+		s.record(report, dto.ReconcileChange{Resource: "dhcp", Name: bridgeName, Action: "deleted", Detail: "no VPC owns the bridge"})
+	}
+	for _, bridgeName := range s.metadataServer.List() {
+		if owned[bridgeName] {
+			continue
+		}
+		if err := s.metadataServer.Stop(bridgeName); err != nil {
+			s.logger.Error("Failed to stop orphaned metadata server", "error", err, "bridge_name", bridgeName)
+			report.Errors = append(report.Errors, fmt.Sprintf("metadata %s: %v", bridgeName, err))
+			continue
+		}
+		s.record(report, dto.ReconcileChange{Resource: "metadata", Name: bridgeName, Action: "deleted", Detail: "no VPC owns the bridge"})
+	}
+
+	if err := s.reconcileUplink(report, uplinkPorts); err != nil {
+		s.logger.Error("Failed to reconcile uplink bridge", "error", err, "bridge_name", s.uplinkBridge)
+		report.Errors = append(report.Errors, fmt.Sprintf("bridge %s: %v", s.uplinkBridge, err))
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+	s.logger.Info("Network reconciled", "vpcs", report.VPCs, "changes", len(report.Changes), "errors", len(report.Errors), "duration", report.Duration)
+	return report, nil
+}
+
+// reconcileVPC brings the bridge of a provisioned VPC in line and reports
+// whether the VPC has an internet gateway attached. bridge is nil when the
+// bridge does not exist.
+func (s *reconcileService) reconcileVPC(report *dto.ReconcileReport, vpc *models.VPC, dataplane *models.VPCDataplane, bridge *network.Bridge) (bool, error) {
+	bridgeName := dataplane.BridgeName
+	if bridge == nil {
+		if err := s.ovsManager.CreateBridge(bridgeName, vpc.CIDRBlock); err != nil {
+			return false, fmt.Errorf("failed to create bridge: %w", err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "bridge", Name: bridgeName, Action: "created", VPCID: vpc.ID})
+	}
+	if bridge == nil || !strings.EqualFold(bridge.DataPath, dataplane.DatapathID) {
+		if err := s.ovsManager.SetDatapathID(bridgeName, dataplane.DatapathID); err != nil {
+			return false, fmt.Errorf("failed to set datapath ID: %w", err)
+		}
+		if bridge != nil {
+			s.record(report, dto.ReconcileChange{Resource: "bridge", Name: bridgeName, Action: "replaced", VPCID: vpc.ID, Detail: fmt.Sprintf("datapath ID %s", dataplane.DatapathID)})
+		}
+	}
+
+	igw, err := s.igwRepo.GetByVPC(vpc.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get internet gateway: %w", err)
+	}
+	attached := igw != nil
+
+	if err := s.reconcilePorts(report, vpc, bridgeName, attached); err != nil {
+		return attached, err
+	}
+	if err := s.reconcileQoS(report, vpc, bridgeName); err != nil {
+		return attached, err
+	}
+	if err := s.reconcileMirrors(report, vpc, bridgeName); err != nil {
+		return attached, err
+	}
+	if err := s.reconcileFlows(report, vpc, dataplane); err != nil {
+		return attached, err
+	}
+	if err := s.reconcileIPFIX(report, vpc, bridgeName); err != nil {
+		return attached, err
+	}
+	if err := s.reconcileDHCP(report, vpc, bridgeName); err != nil {
+		return attached, err
+	}
+
+	// The metadata service listens in the responder's namespace
+	started, err := s.metadataServer.Serve(bridgeName, vpc.ID)
+	if err != nil {
+		return attached, fmt.Errorf("failed to serve metadata: %w", err)
+	}
+	if started {
+		s.record(report, dto.ReconcileChange{Resource: "metadata", Name: bridgeName, Action: "created", VPCID: vpc.ID})
+	}
+	return attached, nil
+}
+
+// reconcilePorts adds the tunnel port, the DHCP port and the gateway and
+// peering patch ports a VPC bridge lacks and removes the patch ports of a
+// detached gateway or a peering that is no longer active. Instance ports are
+// not the controller's business.
+func (s *reconcileService) reconcilePorts(report *dto.ReconcileReport, vpc *models.VPC, bridgeName string, attached bool) error {
+	ports, err := s.ovsManager.ListPorts(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to list ports: %w", err)
+	}
+	present := make(map[string]bool, len(ports))
+	for _, port := range ports {
+		present[port.Name] = true
+	}
+
+	tunnelPort := network.TunnelPortName(bridgeName)
+	if !present[tunnelPort] {
+		// Syncing the overlay adds the port along with its flows
+		if err := s.overlayService.SyncVPC(vpc.ID); err != nil {
+			return fmt.Errorf("failed to add tunnel port: %w", err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: tunnelPort, Action: "created", VPCID: vpc.ID})
+	}
+
+	dhcpPort := network.DHCPPortName(bridgeName)
+	if !present[dhcpPort] {
+		if err := s.ovsManager.AddPort(bridgeName, dhcpPort, "internal"); err != nil {
+			return fmt.Errorf("failed to add DHCP port: %w", err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: dhcpPort, Action: "created", VPCID: vpc.ID})
+	}
+
+	vpcPort, uplinkPort := network.InternetGatewayPorts(bridgeName)
+	switch {
+	case attached && !present[vpcPort]:
+		if err := s.ovsManager.AddPatchPort(bridgeName, vpcPort, uplinkPort); err != nil {
+			return fmt.Errorf("failed to add gateway patch port: %w", err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: vpcPort, Action: "created", VPCID: vpc.ID})
+	case !attached && present[vpcPort]:
+		if err := s.ovsManager.DeletePort(bridgeName, vpcPort); err != nil {
+			return fmt.Errorf("failed to delete gateway patch port: %w", err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: vpcPort, Action: "deleted", VPCID: vpc.ID, Detail: "no internet gateway is attached"})
+	}
+
+	peeringPorts, err := s.vpcPeeringService.PatchPorts(vpc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list peering patch ports: %w", err)
+	}
+	for _, port := range ports {
+		if _, ok := peeringPorts[port.Name]; ok || !strings.HasPrefix(port.Name, network.PeeringPortPrefix) {
+			continue
+		}
+		if err := s.ovsManager.DeletePort(bridgeName, port.Name); err != nil {
+			return fmt.Errorf("failed to delete peering patch port %s: %w", port.Name, err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: port.Name, Action: "deleted", VPCID: vpc.ID, Detail: "no active peering uses the port"})
+	}
+	for name, peer := range peeringPorts {
+		if present[name] {
+			continue
+		}
+		if err := s.ovsManager.AddPatchPort(bridgeName, name, peer); err != nil {
+			return fmt.Errorf("failed to add peering patch port %s: %w", name, err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: name, Action: "created", VPCID: vpc.ID})
+	}
+
+	return nil
+}
+
+// reconcileQoS limits the instance ports on a VPC bridge to what their
+// instances are allowed. Ports of instances the database does not know are
+// left as they are.
+func (s *reconcileService) reconcileQoS(report *dto.ReconcileReport, vpc *models.VPC, bridgeName string) error {
+	desired, err := s.networkLimitsService.DesiredPortQoS(vpc.ID)
+	if err != nil {
+		return err
+	}
+
+	ports, err := s.ovsManager.ListPorts(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to list ports: %w", err)
+	}
+	for _, port := range ports {
+		want, ok := desired[port.Name]
+		if !ok {
+			continue
+		}
+		actual, err := s.ovsManager.GetPortQoS(bridgeName, port.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get QoS of port %s: %w", port.Name, err)
+		}
+		if actual == want {
+			continue
+		}
+		if err := s.ovsManager.SetPortQoS(bridgeName, port.Name, want); err != nil {
+			return fmt.Errorf("failed to set QoS of port %s: %w", port.Name, err)
+		}
+		s.record(report, dto.ReconcileChange{
+			Resource: "qos",
+			Name:     port.Name,
+			Action:   "replaced",
+			VPCID:    vpc.ID,
+			Detail:   fmt.Sprintf("policing %d kbps burst %d kb, egress %d bps", want.IngressPolicingRate, want.IngressPolicingBurst, want.EgressMaxRate),
+		})
+	}
+
+	return nil
+}
+
+// reconcileMirrors programs the traffic mirror sessions whose instances run
+// on this node: mirror tunnel ports are added, mirrors that are missing or
+// drifted are replaced, and the mirrors and tunnel ports of sessions that
+// are gone or whose instances run elsewhere are removed
+func (s *reconcileService) reconcileMirrors(report *dto.ReconcileReport, vpc *models.VPC, bridgeName string) error {
+	sessions, err := s.trafficMirrorService.DesiredSessions(vpc.ID)
+	if err != nil {
+		return err
+	}
+
+	ports, err := s.ovsManager.ListPorts(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to list ports: %w", err)
+	}
+	present := make(map[string]bool, len(ports))
+	for _, port := range ports {
+		present[port.Name] = true
+	}
+
+	wantedPorts := make(map[string]network.MirrorEndpoint)
+	wanted := make(map[string]network.Mirror)
+	for _, session := range sessions {
+		if !present[session.SourcePort] || (session.Endpoint == nil && !present[session.TargetPort]) {
+			continue
+		}
+		mirrors, err := network.CompileMirrorSession(session)
+		if err != nil {
+			return fmt.Errorf("failed to compile traffic mirror session %s: %w", session.ID, err)
+		}
+		if session.Endpoint != nil {
+			wantedPorts[session.TargetPort] = *session.Endpoint
+		}
+		for _, mirror := range mirrors {
+			wanted[mirror.Name] = mirror
+		}
+	}
+
+	actual, err := s.ovsManager.ListMirrors(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to list mirrors: %w", err)
+	}
+	existing := make(map[string]network.Mirror, len(actual))
+	for _, mirror := range actual {
+		existing[mirror.Name] = mirror
+		if _, ok := wanted[mirror.Name]; ok || !strings.HasPrefix(mirror.Name, network.MirrorNamePrefix) {
+			continue
+		}
+		if err := s.ovsManager.DeleteMirror(bridgeName, mirror.Name); err != nil {
+			return fmt.Errorf("failed to delete mirror %s: %w", mirror.Name, err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "mirror", Name: mirror.Name, Action: "deleted", VPCID: vpc.ID, Detail: "no traffic mirror session on this node uses the mirror"})
+	}
+
+	for _, port := range ports {
+		if _, ok := wantedPorts[port.Name]; ok || !strings.HasPrefix(port.Name, network.MirrorTunnelPortPrefix) {
+			continue
+		}
+		if err := s.ovsManager.DeletePort(bridgeName, port.Name); err != nil {
+			return fmt.Errorf("failed to delete mirror port %s: %w", port.Name, err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: port.Name, Action: "deleted", VPCID: vpc.ID, Detail: "no traffic mirror session on this node uses the port"})
+	}
+	for name, endpoint := range wantedPorts {
+		if present[name] {
+			continue
+		}
+		if err := s.ovsManager.AddMirrorPort(bridgeName, name, endpoint); err != nil {
+			return fmt.Errorf("failed to add mirror port %s: %w", name, err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: name, Action: "created", VPCID: vpc.ID})
+	}
+
+	for name, mirror := range wanted {
+		current, ok := existing[name]
+		if ok && current.Equal(mirror) {
+			continue
+		}
+		if err := s.ovsManager.SetMirror(bridgeName, mirror); err != nil {
+			return fmt.Errorf("failed to set mirror %s: %w", name, err)
+		}
+		action := "replaced"
+		if !ok {
+			action = "created"
+		}
+		s.record(report, dto.ReconcileChange{Resource: "mirror", Name: name, Action: action, VPCID: vpc.ID, Detail: fmt.Sprintf("output %s", mirror.OutputPort)})
+	}
+
+	return nil
+}
+
+// reconcileFlows replaces every flow set of a VPC bridge that drifted from
+// the database and deletes the flows no set owns, such as flows installed
+// without a cookie
+func (s *reconcileService) reconcileFlows(report *dto.ReconcileReport, vpc *models.VPC, dataplane *models.VPCDataplane) error {
+	sets, err := s.desiredFlows(vpc, dataplane.ConntrackZone)
+	if err != nil {
+		return err
+	}
+
+	bridgeName := dataplane.BridgeName
+	actual, err := s.ovsManager.ListFlows(bridgeName)
+	if err != nil {
+		return fmt.Errorf("failed to list flows: %w", err)
+	}
+
+	for _, owner := range sets {
+		missing, stale, err := owner.set.Drift(actual)
+		if err != nil {
+			return fmt.Errorf("failed to compare %s flows: %w", owner.name, err)
+		}
+		if len(missing) == 0 && len(stale) == 0 {
+			continue
+		}
+		if err := owner.set.Apply(s.ovsManager, bridgeName); err != nil {
+			return fmt.Errorf("failed to replace %s flows: %w", owner.name, err)
+		}
+		s.record(report, dto.ReconcileChange{
+			Resource: "flows",
+			Name:     bridgeName,
+			Action:   "replaced",
+			VPCID:    vpc.ID,
+			Detail:   fmt.Sprintf("%s: %d missing, %d stale", owner.name, len(missing), len(stale)),
+		})
+	}
+
+	strays := make(map[string]int)
+	var order []string
+	for _, flow := range actual {
+		owned := false
+		for _, owner := range sets {
+			if owned, err = owner.set.Selects(flow); err != nil {
+				return fmt.Errorf("failed to match flow cookie: %w", err)
+			}
+			if owned {
+				break
+			}
+		}
+		if owned {
+			continue
+		}
+		cookie := flow.Cookie
+		if cookie == "" {
+			cookie = "0x0"
+		}
+		if strays[cookie] == 0 {
+			order = append(order, cookie)
+		}
+		strays[cookie]++
+	}
+	for _, cookie := range order {
+		if err := s.ovsManager.DeleteFlowsByCookie(bridgeName, cookie, network.CookieMaskResource); err != nil {
+			return fmt.Errorf("failed to delete flows with cookie %s: %w", cookie, err)
+		}
+		s.record(report, dto.ReconcileChange{
+			Resource: "flows",
+			Name:     bridgeName,
+			Action:   "deleted",
+			VPCID:    vpc.ID,
+			Detail:   fmt.Sprintf("cookie %s: %d flows no resource owns", cookie, strays[cookie]),
+		})
+	}
+
+	return nil
+}
+
+// desiredFlows compiles every flow set of a VPC bridge from the database
+func (s *reconcileService) desiredFlows(vpc *models.VPC, zone int) ([]vpcFlowSet, error) {
+	sets := []vpcFlowSet{{name: "pipeline", set: pipelineFlowSet(vpc.ID, zone)}}
+	for _, owner := range []struct {
+		name    string
+		compile func(*models.VPC) (*network.FlowSet, error)
+	}{
+		{"route", s.routeTableService.DesiredFlows},
+		{"network ACL", s.networkACLService.DesiredFlows},
+		{"security group", s.securityGroupService.DesiredFlows},
+		{"internet gateway", s.igwService.DesiredFlows},
+		{"overlay", s.overlayService.DesiredFlows},
+		{"DHCP", s.dhcpService.DesiredFlows},
+		{"metadata", s.metadataService.DesiredFlows},
+		{"peering", s.vpcPeeringService.DesiredFlows},
+		{"flow log", s.flowLogService.DesiredFlows},
+	} {
+		set, err := owner.compile(vpc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile %s flows: %w", owner.name, err)
+		}
+		sets = append(sets, vpcFlowSet{name: owner.name, set: set})
+	}
+	return sets, nil
+}
+
+// reconcileIPFIX points the flow log collector set of a VPC bridge at the
+// collector, or removes it when no collector is configured
+func (s *reconcileService) reconcileIPFIX(report *dto.ReconcileReport, vpc *models.VPC, bridgeName string) error {
+	want := s.flowLogService.DesiredIPFIXExport()
+	actual, err := s.ovsManager.GetIPFIXExport(bridgeName, network.FlowLogCollectorSetID)
+	if err != nil {
+		return fmt.Errorf("failed to get IPFIX export: %w", err)
+	}
+
+	switch {
+	case want == nil && actual != nil:
+		if err := s.ovsManager.DeleteIPFIXExport(bridgeName, network.FlowLogCollectorSetID); err != nil {
+			return fmt.Errorf("failed to delete IPFIX export: %w", err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "ipfix", Name: bridgeName, Action: "deleted", VPCID: vpc.ID, Detail: "no flow log collector is configured"})
+	case want != nil && (actual == nil || *actual != *want):
+		if err := s.ovsManager.SetIPFIXExport(bridgeName, *want); err != nil {
+			return fmt.Errorf("failed to set IPFIX export: %w", err)
+		}
+		action := "replaced"
+		if actual == nil {
+			action = "created"
+		}
+		s.record(report, dto.ReconcileChange{Resource: "ipfix", Name: bridgeName, Action: action, VPCID: vpc.ID, Detail: fmt.Sprintf("collector %s", want.Target)})
+	}
+
+	return nil
+}
+
+// reconcileDHCP makes the DHCP and DNS responder of a VPC bridge serve the
+// VPC's current reservations, starting it if it is not running
+func (s *reconcileService) reconcileDHCP(report *dto.ReconcileReport, vpc *models.VPC, bridgeName string) error {
+	config, err := s.dhcpService.DesiredConfig(vpc)
+	if err != nil {
+		return fmt.Errorf("failed to compile DHCP configuration: %w", err)
+	}
+
+	changed, err := s.dhcpManager.Sync(bridgeName, *config)
+	if err != nil {
+		return fmt.Errorf("failed to sync DHCP responder: %w", err)
+	}
+	if changed {
+		s.record(report, dto.ReconcileChange{
+			Resource: "dhcp",
+			Name:     bridgeName,
+			Action:   "replaced",
+			VPCID:    vpc.ID,
+			Detail:   fmt.Sprintf("%d subnets, %d reservations", len(config.Subnets), len(config.Hosts)),
+		})
+	}
+
+	return nil
+}
+
+// reconcileUplink adds the uplink end of the gateway patch ports of attached
+// gateways and removes the ones whose VPC has no gateway attached anymore.
+// wanted maps each uplink port to its peer on the VPC bridge.
+func (s *reconcileService) reconcileUplink(report *dto.ReconcileReport, wanted map[string]string) error {
+	ports, err := s.ovsManager.ListPorts(s.uplinkBridge)
+	if err != nil {
+		return fmt.Errorf("failed to list ports: %w", err)
+	}
+
+	present := make(map[string]bool, len(ports))
+	for _, port := range ports {
+		present[port.Name] = true
+		if _, ok := wanted[port.Name]; ok || !strings.HasPrefix(port.Name, network.UplinkPortPrefix) {
+			continue
+		}
+		if err := s.ovsManager.DeletePort(s.uplinkBridge, port.Name); err != nil {
+			return fmt.Errorf("failed to delete port %s: %w", port.Name, err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: port.Name, Action: "deleted", Detail: "no internet gateway uses the port"})
+	}
+
+	for name, peer := range wanted {
+		if present[name] {
+			continue
+		}
+		if err := s.ovsManager.AddPatchPort(s.uplinkBridge, name, peer); err != nil {
+			return fmt.Errorf("failed to add port %s: %w", name, err)
+		}
+		s.record(report, dto.ReconcileChange{Resource: "port", Name: name, Action: "created"})
+	}
+
+	return nil
+}
+
+// record adds a change to the report and logs it
+func (s *reconcileService) record(report *dto.ReconcileReport, change dto.ReconcileChange) {
+	s.logger.Info("Corrected network drift", "resource", change.Resource, "name", change.Name, "action", change.Action, "vpc_id", change.VPCID, "detail", change.Detail)
+	report.Changes = append(report.Changes, change)
+}
@@ -0,0 +1,693 @@
+package services
+
+import (
+	"math"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// Route states reported by the API
+const (
+	RouteStateActive    = "active"
+	RouteStateBlackhole = "blackhole"
+)
+
+type RouteTableService interface {
+	CreateRouteTable(userID string, req *dto.CreateRouteTableRequest) (*models.RouteTable, error)
+	GetRouteTable(id string, userID string) (*models.RouteTable, error)
+	ListRouteTables(userID string, vpcID *string, page, pageSize int) (*dto.RouteTableListResponse, error)
+	UpdateRouteTable(id string, userID string, req *dto.UpdateRouteTableRequest) (*models.RouteTable, error)
+	DeleteRouteTable(id string, userID string) error
+	AddRoute(id string, userID string, req *dto.CreateRouteRequest) (*models.Route, error)
+	RemoveRoute(id string, routeID string, userID string) error
+	AssociateSubnet(id string, userID string, subnetID string) error
+	DisassociateSubnet(id string, userID string, subnetID string) error
+	SyncVPCRoutes(vpcID string, userID string) error
+	DesiredFlows(vpc *models.VPC) (*network.FlowSet, error)
+}
+
+type routeTableService struct {
+	routeTableRepo   repositories.RouteTableRepository
+	vpcRepo          repositories.VPCRepository
+	subnetRepo       repositories.SubnetRepository
+	ipAllocationRepo repositories.IPAllocationRepository
+	igwRepo          repositories.InternetGatewayRepository
+	natRepo          repositories.NATGatewayRepository
+	peeringRepo      repositories.VPCPeeringRepository
+	ovsManager       network.OVSManager
+	logger           *utils.Logger
+}
+
+func NewRouteTableService(
+	routeTableRepo repositories.RouteTableRepository,
+	vpcRepo repositories.VPCRepository,
+	subnetRepo repositories.SubnetRepository,
+	ipAllocationRepo repositories.IPAllocationRepository,
+	igwRepo repositories.InternetGatewayRepository,
+	natRepo repositories.NATGatewayRepository,
+	peeringRepo repositories.VPCPeeringRepository,
+	ovsManager network.OVSManager,
+	logger *utils.Logger,
+) RouteTableService {
+	return &routeTableService{
+		routeTableRepo:   routeTableRepo,
+		vpcRepo:          vpcRepo,
+		subnetRepo:       subnetRepo,
+		ipAllocationRepo: ipAllocationRepo,
+		igwRepo:          igwRepo,
+		natRepo:          natRepo,
+		peeringRepo:      peeringRepo,
+		ovsManager:       ovsManager,
+		logger:           logger,
+	}
+}
+
+func (s *routeTableService) CreateRouteTable(userID string, req *dto.CreateRouteTableRequest) (*models.RouteTable, error) {
+	s.logger.Info("Creating new route table", "user_id", userID, "vpc_id", req.VPCID, "name", req.Name)
+
+	vpc, err := s.getVPC(req.VPCID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.routeTableRepo.GetByName(vpc.ID, req.Name)
+	if err != nil {
+		s.logger.Error("Failed to check name conflict", "error", err, "name", req.Name)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check route table name")
+	}
+	if existing != nil {
+		s.logger.Warn("Route table name already exists", "name", req.Name, "vpc_id", vpc.ID)
+		return nil, errors.ErrRouteTableExists
+	}
+
+	now := time.Now()
+	routeTable := &models.RouteTable{
+		ID:        uuid.New().String(),
+		VPCID:     vpc.ID,
+		Name:      req.Name,
+		IsMain:    false,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.routeTableRepo.Create(routeTable); err != nil {
+		s.logger.Error("Failed to create route table in database", "error", err, "route_table_id", routeTable.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create route table")
+	}
+
+	if err := s.loadDetails(routeTable, vpc); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Route table created successfully", "route_table_id", routeTable.ID)
+	return routeTable, nil
+}
+
+func (s *routeTableService) GetRouteTable(id string, userID string) (*models.RouteTable, error) {
+	routeTable, err := s.getRouteTable(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	vpc, err := s.getVPC(routeTable.VPCID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadDetails(routeTable, vpc); err != nil {
+		return nil, err
+	}
+
+	return routeTable, nil
+}
+
+func (s *routeTableService) ListRouteTables(userID string, vpcID *string, page, pageSize int) (*dto.RouteTableListResponse, error) {
+	s.logger.Info("Listing route tables", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	routeTables, total, err := s.routeTableRepo.List(userID, vpcID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list route tables", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list route tables")
+	}
+
+	vpcs := make(map[string]*models.VPC)
+	routeTableResponses := make([]dto.RouteTableResponse, len(routeTables))
+	for i := range routeTables {
+		vpc, ok := vpcs[routeTables[i].VPCID]
+		if !ok {
+			vpc, err = s.getVPC(routeTables[i].VPCID, userID)
+			if err != nil {
+				return nil, err
+			}
+			vpcs[vpc.ID] = vpc
+		}
+		if err := s.loadDetails(&routeTables[i], vpc); err != nil {
+			return nil, err
+		}
+		routeTableResponses[i] = dto.ToRouteTableResponse(&routeTables[i])
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.RouteTableListResponse{
+		RouteTables: routeTableResponses,
+		Total:       total,
+		Page:        page,
+		PageSize:    pageSize,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+func (s *routeTableService) UpdateRouteTable(id string, userID string, req *dto.UpdateRouteTableRequest) (*models.RouteTable, error) {
+	s.logger.Info("Updating route table", "route_table_id", id, "user_id", userID)
+
+	routeTable, err := s.getRouteTable(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+
+	if req.Name != nil && *req.Name != routeTable.Name {
+		conflict, err := s.routeTableRepo.GetByName(routeTable.VPCID, *req.Name)
+		if err != nil {
+			s.logger.Error("Failed to check name conflict", "error", err, "name", *req.Name)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check route table name")
+		}
+		if conflict != nil && conflict.ID != id {
+			return nil, errors.ErrRouteTableExists
+		}
+		updates["name"] = *req.Name
+	}
+
+	if len(updates) > 0 {
+		if err := s.routeTableRepo.Update(id, userID, updates); err != nil {
+			s.logger.Error("Failed to update route table", "error", err, "route_table_id", id)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to update route table")
+		}
+	}
+
+	return s.GetRouteTable(id, userID)
+}
+
+func (s *routeTableService) DeleteRouteTable(id string, userID string) error {
+	s.logger.Info("Deleting route table", "route_table_id", id, "user_id", userID)
+
+	routeTable, err := s.getRouteTable(id, userID)
+	if err != nil {
+		return err
+	}
+
+	// The main table is removed together with its VPC
+	if routeTable.IsMain {
+		s.logger.Warn("Refusing to delete main route table", "route_table_id", id)
+		return errors.ErrMainRouteTable
+	}
+
+	subnetIDs, err := s.routeTableRepo.ListAssociatedSubnetIDs(id)
+	if err != nil {
+		s.logger.Error("Failed to list associated subnets", "error", err, "route_table_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check route table usage")
+	}
+	if len(subnetIDs) > 0 {
+		s.logger.Warn("Route table is still associated", "route_table_id", id, "subnets", len(subnetIDs))
+		return errors.ErrResourceInUse
+	}
+
+	// Without associations the table has no flows on the bridge
+	if err := s.routeTableRepo.Delete(id, userID); err != nil {
+		s.logger.Error("Failed to delete route table", "error", err, "route_table_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete route table")
+	}
+
+	s.logger.Info("Route table deleted successfully", "route_table_id", id)
+	return nil
+}
+
+func (s *routeTableService) AddRoute(id string, userID string, req *dto.CreateRouteRequest) (*models.Route, error) {
+	s.logger.Info("Adding route", "route_table_id", id, "destination_cidr", req.DestinationCIDR, "target_type", req.TargetType)
+
+	routeTable, err := s.getRouteTable(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	vpc, err := s.getVPC(routeTable.VPCID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Destinations inside the VPC are covered by the local routes
+	_, destination, err := net.ParseCIDR(req.DestinationCIDR)
+	if err != nil {
+		return nil, errors.ErrInvalidRoute
+	}
+	for _, cidr := range vpcLocalCIDRs(vpc) {
+		_, vpcNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_CIDR", "Invalid VPC CIDR block")
+		}
+		if network.CIDRContains(vpcNet, destination) {
+			s.logger.Warn("Route destination inside VPC range", "destination_cidr", destination.String(), "vpc_id", vpc.ID)
+			return nil, errors.ErrInvalidRoute
+		}
+	}
+	ipv6 := destination.IP.To4() == nil
+
+	// Gateways translate to public IPv4 addresses only
+	if ipv6 && (req.TargetType == "igw" || req.TargetType == "nat") {
+		s.logger.Warn("IPv6 route destination for an IPv4 only gateway", "destination_cidr", destination.String(), "target_type", req.TargetType)
+		return nil, errors.ErrInvalidRoute
+	}
+
+	existing, err := s.routeTableRepo.GetRouteByDestination(routeTable.ID, destination.String())
+	if err != nil {
+		s.logger.Error("Failed to check route conflict", "error", err, "destination_cidr", destination.String())
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check route conflict")
+	}
+	if existing != nil {
+		return nil, errors.ErrRouteExists
+	}
+
+	priority, err := network.RoutePriority(destination.String())
+	if err != nil {
+		return nil, errors.ErrInvalidRoute
+	}
+
+	route := &models.Route{
+		ID:              uuid.New().String(),
+		RouteTableID:    routeTable.ID,
+		DestinationCIDR: destination.String(),
+		TargetType:      req.TargetType,
+		TargetID:        req.TargetID,
+		Priority:        priority,
+	}
+
+	// Instance, gateway and peering targets must exist when the route is
+	// created
+	switch route.TargetType {
+	case "instance":
+		if _, active, err := s.resolveTarget(vpc.ID, route); err != nil {
+			return nil, err
+		} else if !active {
+			s.logger.Warn("Route target instance has no address in VPC", "instance_id", route.TargetID, "vpc_id", vpc.ID)
+			return nil, errors.ErrInstanceNotFound
+		}
+	case "igw":
+		if _, active, err := s.resolveTarget(vpc.ID, route); err != nil {
+			return nil, err
+		} else if !active {
+			s.logger.Warn("Route target internet gateway not attached to VPC", "internet_gateway_id", route.TargetID, "vpc_id", vpc.ID)
+			return nil, errors.ErrInternetGatewayNotFound
+		}
+	case "nat":
+		if _, active, err := s.resolveTarget(vpc.ID, route); err != nil {
+			return nil, err
+		} else if !active {
+			s.logger.Warn("Route target NAT gateway not in VPC", "nat_gateway_id", route.TargetID, "vpc_id", vpc.ID)
+			return nil, errors.ErrNATGatewayNotFound
+		}
+	case "peering":
+		peer, err := s.peeringRepo.GetPeer(route.TargetID, vpc.ID)
+		if err != nil {
+			s.logger.Error("Failed to get VPC peer", "error", err, "vpc_peering_id", route.TargetID)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to resolve route target")
+		}
+		if peer == nil {
+			s.logger.Warn("Route target VPC peering not active for VPC", "vpc_peering_id", route.TargetID, "vpc_id", vpc.ID)
+			return nil, errors.ErrVPCPeeringNotFound
+		}
+		// The peer drops whatever is not for its own ranges
+		peerCIDR := peer.CIDRBlock
+		if ipv6 {
+			if peer.IPv6CIDRBlock == nil || vpc.IPv6CIDRBlock == nil {
+				s.logger.Warn("IPv6 route destination over a peering without IPv6", "destination_cidr", destination.String(), "vpc_peering_id", route.TargetID)
+				return nil, errors.ErrInvalidRoute
+			}
+			peerCIDR = *peer.IPv6CIDRBlock
+		}
+		_, peerNet, err := net.ParseCIDR(peerCIDR)
+		if err != nil || !network.CIDRContains(peerNet, destination) {
+			s.logger.Warn("Route destination outside peer VPC range", "destination_cidr", destination.String(), "peer_vpc_id", peer.VPCID)
+			return nil, errors.ErrInvalidRoute
+		}
+	}
+
+	if err := s.routeTableRepo.CreateRoute(route); err != nil {
+		s.logger.Error("Failed to create route", "error", err, "route_table_id", routeTable.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create route")
+	}
+
+	if err := s.SyncVPCRoutes(vpc.ID, userID); err != nil {
+		// Rollback database changes
+		if delErr := s.routeTableRepo.DeleteRoute(routeTable.ID, route.ID); delErr != nil {
+			s.logger.Error("Failed to rollback route creation", "error", delErr, "route_id", route.ID)
+		}
+		return nil, err
+	}
+
+	route.State = s.routeState(vpc.ID, route)
+
+	s.logger.Info("Route added", "route_table_id", routeTable.ID, "route_id", route.ID, "state", route.State)
+	return route, nil
+}
+
+func (s *routeTableService) RemoveRoute(id string, routeID string, userID string) error {
+	s.logger.Info("Removing route", "route_table_id", id, "route_id", routeID)
+
+	routeTable, err := s.getRouteTable(id, userID)
+	if err != nil {
+		return err
+	}
+
+	route, err := s.routeTableRepo.GetRoute(routeTable.ID, routeID)
+	if err != nil {
+		s.logger.Error("Failed to get route", "error", err, "route_id", routeID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get route")
+	}
+	if route == nil {
+		return errors.ErrRouteNotFound
+	}
+
+	if err := s.routeTableRepo.DeleteRoute(routeTable.ID, route.ID); err != nil {
+		s.logger.Error("Failed to delete route", "error", err, "route_id", route.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete route")
+	}
+
+	return s.SyncVPCRoutes(routeTable.VPCID, userID)
+}
+
+func (s *routeTableService) AssociateSubnet(id string, userID string, subnetID string) error {
+	s.logger.Info("Associating subnet with route table", "route_table_id", id, "subnet_id", subnetID)
+
+	routeTable, err := s.getRouteTable(id, userID)
+	if err != nil {
+		return err
+	}
+
+	subnet, err := s.subnetRepo.GetByID(subnetID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet", "error", err, "subnet_id", subnetID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet")
+	}
+	if subnet == nil || subnet.VPCID != routeTable.VPCID {
+		s.logger.Warn("Subnet not found in route table VPC", "subnet_id", subnetID, "vpc_id", routeTable.VPCID)
+		return errors.ErrSubnetNotFound
+	}
+
+	if err := s.routeTableRepo.Associate(routeTable.ID, subnet.ID); err != nil {
+		s.logger.Error("Failed to associate route table", "error", err, "route_table_id", routeTable.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to associate route table")
+	}
+
+	return s.SyncVPCRoutes(routeTable.VPCID, userID)
+}
+
+func (s *routeTableService) DisassociateSubnet(id string, userID string, subnetID string) error {
+	s.logger.Info("Disassociating subnet from route table", "route_table_id", id, "subnet_id", subnetID)
+
+	routeTable, err := s.getRouteTable(id, userID)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.routeTableRepo.GetAssociatedTableID(subnetID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet association", "error", err, "subnet_id", subnetID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet association")
+	}
+	if current == nil || *current != routeTable.ID {
+		return errors.ErrSubnetNotFound
+	}
+
+	// The subnet falls back to the main route table
+	if err := s.routeTableRepo.Disassociate(routeTable.ID, subnetID); err != nil {
+		s.logger.Error("Failed to disassociate route table", "error", err, "route_table_id", routeTable.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to disassociate route table")
+	}
+
+	return s.SyncVPCRoutes(routeTable.VPCID, userID)
+}
+
+// SyncVPCRoutes recompiles the routing table of a VPC bridge and replaces it
+// as a whole, in one bundle
+func (s *routeTableService) SyncVPCRoutes(vpcID string, userID string) error {
+	vpc, err := s.getVPC(vpcID, userID)
+	if err != nil {
+		return err
+	}
+
+	set, err := s.DesiredFlows(vpc)
+	if err != nil {
+		return err
+	}
+
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpc.ID)
+	if err != nil {
+		return err
+	}
+	if err := set.Apply(s.ovsManager, bridgeName); err != nil {
+		s.logger.Error("Failed to program routes", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to program routes")
+	}
+
+	s.logger.Info("VPC routes synchronized", "vpc_id", vpc.ID, "flows", len(set.Flows))
+	return nil
+}
+
+// DesiredFlows compiles the routing table of a VPC bridge. Main table routes
+// apply to every subnet without an association, the other tables are
+// compiled for their associated subnets. Routes whose target cannot be
+// resolved are programmed as blackholes. Flows of no particular route, such
+// as the local route, belong to the VPC.
+func (s *routeTableService) DesiredFlows(vpc *models.VPC) (*network.FlowSet, error) {
+	mainRouteTable, err := s.routeTableRepo.GetMain(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to get main route table", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get main route table")
+	}
+	if mainRouteTable == nil {
+		return nil, errors.ErrRouteTableNotFound
+	}
+
+	pairs, err := s.routeTableRepo.ListSubnetRouteTables(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to list subnet route tables", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list subnet route tables")
+	}
+	routes, err := s.routeTableRepo.ListRoutesByVPC(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to list routes", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list routes")
+	}
+
+	// Resolve each route once, however many subnets use its table
+	entries := make([]network.RouteEntry, 0)
+	routesByTable := make(map[string][]network.RouteEntry)
+	for i := range routes {
+		route := &routes[i]
+		actions, active, err := s.resolveTarget(vpc.ID, route)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			s.logger.Warn("Route target not found, blackholing route", "route_id", route.ID, "target_type", route.TargetType, "target_id", route.TargetID)
+			actions = network.BlackholeActions
+		}
+
+		entry := network.RouteEntry{
+			DestinationCIDR: route.DestinationCIDR,
+			Actions:         actions,
+			Cookie:          network.FlowCookie(network.CookieKindRoute, route.ID),
+		}
+		if route.RouteTableID == mainRouteTable.ID {
+			entries = append(entries, entry)
+		} else {
+			routesByTable[route.RouteTableID] = append(routesByTable[route.RouteTableID], entry)
+		}
+	}
+
+	isolated := make([]string, 0)
+	for _, pair := range pairs {
+		if pair.RouteTableID == mainRouteTable.ID {
+			continue
+		}
+		isolated = append(isolated, pair.CIDRBlock)
+		if pair.IPv6CIDRBlock != nil {
+			isolated = append(isolated, *pair.IPv6CIDRBlock)
+		}
+		// A route applies to the subnet's block of its destination's family
+		for _, entry := range routesByTable[pair.RouteTableID] {
+			entry.SourceCIDR = pair.CIDRBlock
+			if network.IsIPv6(entry.DestinationCIDR) {
+				if pair.IPv6CIDRBlock == nil {
+					continue
+				}
+				entry.SourceCIDR = *pair.IPv6CIDRBlock
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	flows, err := network.CompileRoutes(vpcLocalCIDRs(vpc), entries, isolated)
+	if err != nil {
+		s.logger.Error("Failed to compile routes", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile routes")
+	}
+
+	return &network.FlowSet{
+		Cookie: network.FlowCookie(network.CookieKindRoute, vpc.ID),
+		Mask:   network.CookieMaskKind,
+		Flows:  flows,
+	}, nil
+}
+
+// resolveTarget returns the flow actions that forward to a route's target,
+// or false when the target does not exist in the VPC
+func (s *routeTableService) resolveTarget(vpcID string, route *models.Route) (string, bool, error) {
+	switch route.TargetType {
+	case "instance":
+		ips, err := s.ipAllocationRepo.ListInstanceIPsInVPC(route.TargetID, vpcID)
+		if err != nil {
+			return "", false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to resolve route target")
+		}
+		if len(ips) == 0 {
+			return "", false, nil
+		}
+		actions, err := network.InstanceRouteActions(ips[0])
+		if err != nil {
+			return "", false, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to resolve route target")
+		}
+		return actions, true, nil
+	case "igw":
+		igw, err := s.igwRepo.GetByVPC(vpcID)
+		if err != nil {
+			return "", false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to resolve route target")
+		}
+		if igw == nil || igw.ID != route.TargetID {
+			return "", false, nil
+		}
+		return network.InternetGatewayRouteActions(), true, nil
+	case "nat":
+		natGateway, err := s.natRepo.GetInVPC(route.TargetID, vpcID)
+		if err != nil {
+			return "", false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to resolve route target")
+		}
+		if natGateway == nil {
+			return "", false, nil
+		}
+		return network.NATGatewayRouteActions(network.SourceNAT{
+			PublicIP: natGateway.PublicIP,
+			Zone:     natGateway.ConntrackZone,
+		}), true, nil
+	case "peering":
+		peer, err := s.peeringRepo.GetPeer(route.TargetID, vpcID)
+		if err != nil {
+			return "", false, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to resolve route target")
+		}
+		if peer == nil {
+			return "", false, nil
+		}
+		bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpcID)
+		if err != nil {
+			return "", false, err
+		}
+		port, _ := network.PeeringPorts(bridgeName, peer.BridgeName)
+		if network.IsIPv6(route.DestinationCIDR) {
+			return network.PeeringIPv6RouteActions(port), true, nil
+		}
+		return network.PeeringRouteActions(port), true, nil
+	default:
+		// No gateway of this type is attached to the VPC
+		return "", false, nil
+	}
+}
+
+// routeState reports whether a route currently forwards or blackholes
+func (s *routeTableService) routeState(vpcID string, route *models.Route) string {
+	_, active, err := s.resolveTarget(vpcID, route)
+	if err != nil {
+		s.logger.Error("Failed to resolve route target", "error", err, "route_id", route.ID)
+		return RouteStateBlackhole
+	}
+	if !active {
+		return RouteStateBlackhole
+	}
+	return RouteStateActive
+}
+
+func (s *routeTableService) getRouteTable(id string, userID string) (*models.RouteTable, error) {
+	routeTable, err := s.routeTableRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get route table", "error", err, "route_table_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get route table")
+	}
+	if routeTable == nil {
+		return nil, errors.ErrRouteTableNotFound
+	}
+	return routeTable, nil
+}
+
+func (s *routeTableService) getVPC(vpcID string, userID string) (*models.VPC, error) {
+	vpc, err := s.vpcRepo.GetByID(vpcID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if vpc == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+	return vpc, nil
+}
+
+// loadDetails fills in the associated subnets and the routes with their
+// current state, led by the implicit local routes
+func (s *routeTableService) loadDetails(routeTable *models.RouteTable, vpc *models.VPC) error {
+	subnetIDs, err := s.routeTableRepo.ListAssociatedSubnetIDs(routeTable.ID)
+	if err != nil {
+		s.logger.Error("Failed to list associated subnets", "error", err, "route_table_id", routeTable.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list associated subnets")
+	}
+	routeTable.SubnetIDs = subnetIDs
+
+	routes, err := s.routeTableRepo.ListRoutes(routeTable.ID)
+	if err != nil {
+		s.logger.Error("Failed to list routes", "error", err, "route_table_id", routeTable.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list routes")
+	}
+
+	routeTable.Routes = make([]models.Route, 0, len(routes)+2)
+	for _, cidr := range vpcLocalCIDRs(vpc) {
+		routeTable.Routes = append(routeTable.Routes, models.Route{
+			RouteTableID:    routeTable.ID,
+			DestinationCIDR: cidr,
+			TargetType:      "local",
+			TargetID:        "local",
+			Priority:        network.PriorityRouteLocal,
+			State:           RouteStateActive,
+		})
+	}
+	for _, route := range routes {
+		route.State = s.routeState(vpc.ID, &route)
+		routeTable.Routes = append(routeTable.Routes, route)
+	}
+
+	return nil
+}
@@ -0,0 +1,534 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+type SecurityGroupService interface {
+	CreateSecurityGroup(userID string, req *dto.CreateSecurityGroupRequest) (*models.SecurityGroup, error)
+	GetSecurityGroup(id string, userID string) (*models.SecurityGroup, error)
+	ListSecurityGroups(userID string, vpcID *string, page, pageSize int) (*dto.SecurityGroupListResponse, error)
+	UpdateSecurityGroup(id string, userID string, req *dto.UpdateSecurityGroupRequest) (*models.SecurityGroup, error)
+	DeleteSecurityGroup(id string, userID string) error
+	AddRule(id string, userID string, req *dto.SecurityGroupRuleRequest) (*models.SecurityGroupRule, error)
+	RemoveRule(id string, ruleID string, userID string) error
+	AddMember(id string, userID string, instanceID string) error
+	RemoveMember(id string, userID string, instanceID string) error
+	DesiredFlows(vpc *models.VPC) (*network.FlowSet, error)
+}
+
+type securityGroupService struct {
+	sgRepo           repositories.SecurityGroupRepository
+	vpcRepo          repositories.VPCRepository
+	ipAllocationRepo repositories.IPAllocationRepository
+	ovsManager       network.OVSManager
+	logger           *utils.Logger
+}
+
+func NewSecurityGroupService(
+	sgRepo repositories.SecurityGroupRepository,
+	vpcRepo repositories.VPCRepository,
+	ipAllocationRepo repositories.IPAllocationRepository,
+	ovsManager network.OVSManager,
+	logger *utils.Logger,
+) SecurityGroupService {
+	return &securityGroupService{
+		sgRepo:           sgRepo,
+		vpcRepo:          vpcRepo,
+		ipAllocationRepo: ipAllocationRepo,
+		ovsManager:       ovsManager,
+		logger:           logger,
+	}
+}
+
+func (s *securityGroupService) CreateSecurityGroup(userID string, req *dto.CreateSecurityGroupRequest) (*models.SecurityGroup, error) {
+	s.logger.Info("Creating new security group", "user_id", userID, "vpc_id", req.VPCID, "name", req.Name)
+
+	vpc, err := s.vpcRepo.GetByID(req.VPCID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC", "error", err, "vpc_id", req.VPCID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if vpc == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+
+	existing, err := s.sgRepo.GetByName(vpc.ID, req.Name)
+	if err != nil {
+		s.logger.Error("Failed to check name conflict", "error", err, "name", req.Name)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check security group name")
+	}
+	if existing != nil {
+		s.logger.Warn("Security group name already exists", "name", req.Name, "vpc_id", vpc.ID)
+		return nil, errors.ErrSecurityGroupExists
+	}
+
+	now := time.Now()
+	sg := &models.SecurityGroup{
+		ID:            uuid.New().String(),
+		Name:          req.Name,
+		Description:   req.Description,
+		VPCID:         vpc.ID,
+		UserID:        userID,
+		InboundRules:  []models.SecurityGroupRule{},
+		OutboundRules: []models.SecurityGroupRule{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := s.sgRepo.Create(sg); err != nil {
+		s.logger.Error("Failed to create security group in database", "error", err, "security_group_id", sg.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create security group")
+	}
+
+	s.logger.Info("Security group created successfully", "security_group_id", sg.ID)
+	return sg, nil
+}
+
+func (s *securityGroupService) GetSecurityGroup(id string, userID string) (*models.SecurityGroup, error) {
+	sg, err := s.getSecurityGroup(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadRules(sg); err != nil {
+		return nil, err
+	}
+
+	return sg, nil
+}
+
+func (s *securityGroupService) ListSecurityGroups(userID string, vpcID *string, page, pageSize int) (*dto.SecurityGroupListResponse, error) {
+	s.logger.Info("Listing security groups", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	groups, total, err := s.sgRepo.List(userID, vpcID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list security groups", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list security groups")
+	}
+
+	groupResponses := make([]dto.SecurityGroupResponse, len(groups))
+	for i := range groups {
+		if err := s.loadRules(&groups[i]); err != nil {
+			return nil, err
+		}
+		groupResponses[i] = dto.ToSecurityGroupResponse(&groups[i])
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.SecurityGroupListResponse{
+		SecurityGroups: groupResponses,
+		Total:          total,
+		Page:           page,
+		PageSize:       pageSize,
+		TotalPages:     totalPages,
+	}, nil
+}
+
+func (s *securityGroupService) UpdateSecurityGroup(id string, userID string, req *dto.UpdateSecurityGroupRequest) (*models.SecurityGroup, error) {
+	s.logger.Info("Updating security group", "security_group_id", id, "user_id", userID)
+
+	sg, err := s.getSecurityGroup(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+
+	if req.Name != nil && *req.Name != sg.Name {
+		conflict, err := s.sgRepo.GetByName(sg.VPCID, *req.Name)
+		if err != nil {
+			s.logger.Error("Failed to check name conflict", "error", err, "name", *req.Name)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check security group name")
+		}
+		if conflict != nil && conflict.ID != id {
+			return nil, errors.ErrSecurityGroupExists
+		}
+		updates["name"] = *req.Name
+	}
+
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	if len(updates) > 0 {
+		if err := s.sgRepo.Update(id, userID, updates); err != nil {
+			s.logger.Error("Failed to update security group", "error", err, "security_group_id", id)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to update security group")
+		}
+	}
+
+	return s.GetSecurityGroup(id, userID)
+}
+
+func (s *securityGroupService) DeleteSecurityGroup(id string, userID string) error {
+	s.logger.Info("Deleting security group", "security_group_id", id, "user_id", userID)
+
+	if _, err := s.getSecurityGroup(id, userID); err != nil {
+		return err
+	}
+
+	// Members and rules of other groups would silently lose their filtering
+	members, err := s.sgRepo.CountMembers(id)
+	if err != nil {
+		s.logger.Error("Failed to count security group members", "error", err, "security_group_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check security group usage")
+	}
+	referencing, err := s.sgRepo.ListReferencingGroupIDs(id)
+	if err != nil {
+		s.logger.Error("Failed to list referencing security groups", "error", err, "security_group_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check security group usage")
+	}
+	if members > 0 || len(referencing) > 0 {
+		s.logger.Warn("Security group is in use", "security_group_id", id, "members", members, "referenced_by", len(referencing))
+		return errors.ErrResourceInUse
+	}
+
+	// Without members the group has no flows on the bridge
+	if err := s.sgRepo.Delete(id, userID); err != nil {
+		s.logger.Error("Failed to delete security group", "error", err, "security_group_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete security group")
+	}
+
+	s.logger.Info("Security group deleted successfully", "security_group_id", id)
+	return nil
+}
+
+func (s *securityGroupService) AddRule(id string, userID string, req *dto.SecurityGroupRuleRequest) (*models.SecurityGroupRule, error) {
+	s.logger.Info("Adding security group rule", "security_group_id", id, "direction", req.Direction, "protocol", req.Protocol)
+
+	sg, err := s.getSecurityGroup(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &models.SecurityGroupRule{
+		ID:              uuid.New().String(),
+		SecurityGroupID: sg.ID,
+		Direction:       req.Direction,
+		Protocol:        req.Protocol,
+		FromPort:        req.FromPort,
+		ToPort:          req.ToPort,
+		Source:          req.Source,
+		Description:     req.Description,
+	}
+	if err := s.validateRule(sg, rule, userID); err != nil {
+		s.logger.Warn("Invalid security group rule", "error", err, "security_group_id", id)
+		return nil, errors.ErrInvalidSecurityRule
+	}
+
+	members, err := s.sgRepo.ListMemberIPs(sg.ID)
+	if err != nil {
+		s.logger.Error("Failed to list security group members", "error", err, "security_group_id", sg.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list security group members")
+	}
+	flows, err := s.compileRule(rule, members)
+	if err != nil {
+		s.logger.Error("Failed to compile security group rule", "error", err, "rule_id", rule.ID)
+		return nil, errors.ErrInvalidSecurityRule
+	}
+
+	if err := s.sgRepo.CreateRule(rule); err != nil {
+		s.logger.Error("Failed to create security group rule", "error", err, "security_group_id", sg.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create security group rule")
+	}
+
+	if err := s.syncFlows(sg.VPCID); err != nil {
+		// Rollback database changes
+		if delErr := s.sgRepo.DeleteRule(sg.ID, rule.ID); delErr != nil {
+			s.logger.Error("Failed to rollback security group rule", "error", delErr, "rule_id", rule.ID)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Security group rule added", "security_group_id", sg.ID, "rule_id", rule.ID, "flows", len(flows))
+	return rule, nil
+}
+
+func (s *securityGroupService) RemoveRule(id string, ruleID string, userID string) error {
+	s.logger.Info("Removing security group rule", "security_group_id", id, "rule_id", ruleID)
+
+	sg, err := s.getSecurityGroup(id, userID)
+	if err != nil {
+		return err
+	}
+
+	rule, err := s.sgRepo.GetRule(sg.ID, ruleID)
+	if err != nil {
+		s.logger.Error("Failed to get security group rule", "error", err, "rule_id", ruleID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get security group rule")
+	}
+	if rule == nil {
+		return errors.ErrSecurityRuleNotFound
+	}
+
+	if err := s.sgRepo.DeleteRule(sg.ID, rule.ID); err != nil {
+		s.logger.Error("Failed to delete security group rule", "error", err, "rule_id", rule.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete security group rule")
+	}
+
+	if err := s.syncFlows(sg.VPCID); err != nil {
+		// Restore the rule so it stays visible while its flows remain
+		if createErr := s.sgRepo.CreateRule(rule); createErr != nil {
+			s.logger.Error("Failed to restore security group rule", "error", createErr, "rule_id", rule.ID)
+		}
+		return err
+	}
+
+	s.logger.Info("Security group rule removed", "security_group_id", sg.ID, "rule_id", rule.ID)
+	return nil
+}
+
+func (s *securityGroupService) AddMember(id string, userID string, instanceID string) error {
+	s.logger.Info("Adding instance to security group", "security_group_id", id, "instance_id", instanceID)
+
+	sg, err := s.getSecurityGroup(id, userID)
+	if err != nil {
+		return err
+	}
+
+	instanceIPs, err := s.ipAllocationRepo.ListInstanceIPsInVPC(instanceID, sg.VPCID)
+	if err != nil {
+		s.logger.Error("Failed to list instance addresses", "error", err, "instance_id", instanceID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list instance addresses")
+	}
+	if len(instanceIPs) == 0 {
+		s.logger.Warn("Instance has no address in security group VPC", "instance_id", instanceID, "vpc_id", sg.VPCID)
+		return errors.ErrInstanceNotFound
+	}
+
+	return s.changeMembership(sg, func() error {
+		return s.sgRepo.AddMember(sg.ID, instanceID)
+	})
+}
+
+func (s *securityGroupService) RemoveMember(id string, userID string, instanceID string) error {
+	s.logger.Info("Removing instance from security group", "security_group_id", id, "instance_id", instanceID)
+
+	sg, err := s.getSecurityGroup(id, userID)
+	if err != nil {
+		return err
+	}
+
+	isMember, err := s.sgRepo.IsMember(sg.ID, instanceID)
+	if err != nil {
+		s.logger.Error("Failed to check membership", "error", err, "instance_id", instanceID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check security group membership")
+	}
+	if !isMember {
+		return errors.ErrInstanceNotFound
+	}
+
+	// The default deny of the instance goes with its last group
+	return s.changeMembership(sg, func() error {
+		return s.sgRepo.RemoveMember(sg.ID, instanceID)
+	})
+}
+
+// changeMembership applies a membership change and reprograms the VPC, which
+// also covers every group whose rules use the group as a source
+func (s *securityGroupService) changeMembership(sg *models.SecurityGroup, change func() error) error {
+	if err := change(); err != nil {
+		s.logger.Error("Failed to update security group membership", "error", err, "security_group_id", sg.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to update security group membership")
+	}
+
+	return s.syncFlows(sg.VPCID)
+}
+
+// DesiredFlows returns the security group flows of a VPC bridge
+func (s *securityGroupService) DesiredFlows(vpc *models.VPC) (*network.FlowSet, error) {
+	return s.compileVPC(vpc.ID)
+}
+
+// syncFlows replaces the security group flows of a VPC bridge in one bundle
+func (s *securityGroupService) syncFlows(vpcID string) error {
+	set, err := s.compileVPC(vpcID)
+	if err != nil {
+		return err
+	}
+
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpcID)
+	if err != nil {
+		return err
+	}
+	if err := set.Apply(s.ovsManager, bridgeName); err != nil {
+		s.logger.Error("Failed to program security group flows", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to program security group flows")
+	}
+
+	return nil
+}
+
+// compileVPC compiles every security group of a VPC. Groups with members or
+// rules in common compile to the same flows, so the set covers the whole
+// kind: replacing a single group would delete flows another group needs.
+// The default deny of every member is owned by the VPC, and a rule flow by
+// the first group that compiles it, so shared flows keep one owner.
+func (s *securityGroupService) compileVPC(vpcID string) (*network.FlowSet, error) {
+	groups, err := s.sgRepo.ListByVPC(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list security groups", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list security groups")
+	}
+
+	var isolation, rules []network.Flow
+	isolated := make(map[string]struct{})
+	for _, group := range groups {
+		members, groupRules, err := s.compileGroup(group.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range members {
+			if _, ok := isolated[ip]; ok {
+				continue
+			}
+			isolated[ip] = struct{}{}
+			isolation = append(isolation, network.IsolationFlows(ip)...)
+		}
+		cookie := network.FlowCookie(network.CookieKindSecurityGroup, group.ID)
+		rules = append(rules, network.WithCookie(groupRules, cookie)...)
+	}
+
+	set := &network.FlowSet{
+		Cookie: network.KindCookie(network.CookieKindSecurityGroup),
+		Mask:   network.CookieMaskKind,
+	}
+	set.Flows = network.WithCookie(isolation, network.FlowCookie(network.CookieKindSecurityGroup, vpcID))
+	set.Flows = append(set.Flows, network.UniqueFlows(rules)...)
+	return set, nil
+}
+
+// compileGroup returns the member addresses of a group and the flows of
+// every rule of the group against them
+func (s *securityGroupService) compileGroup(groupID string) ([]string, []network.Flow, error) {
+	members, err := s.sgRepo.ListMemberIPs(groupID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list security group members")
+	}
+	rules, err := s.sgRepo.ListRules(groupID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list security group rules")
+	}
+
+	var flows []network.Flow
+	for i := range rules {
+		ruleFlows, err := s.compileRule(&rules[i], members)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile security group rule")
+		}
+		flows = append(flows, ruleFlows...)
+	}
+	return members, flows, nil
+}
+
+// compileRule resolves the rule source and expands it into flows
+func (s *securityGroupService) compileRule(rule *models.SecurityGroupRule, memberIPs []string) ([]network.Flow, error) {
+	remotes := []string{rule.Source}
+	if _, _, err := net.ParseCIDR(rule.Source); err != nil {
+		// Security group source: match the addresses of its members
+		remotes, err = s.sgRepo.ListMemberIPs(rule.Source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return network.CompileFirewallRule(memberIPs, network.FirewallRule{
+		Direction: rule.Direction,
+		Protocol:  rule.Protocol,
+		FromPort:  rule.FromPort,
+		ToPort:    rule.ToPort,
+		Remotes:   remotes,
+	})
+}
+
+// validateRule checks ports against the protocol and normalizes the source
+func (s *securityGroupService) validateRule(sg *models.SecurityGroup, rule *models.SecurityGroupRule, userID string) error {
+	switch rule.Protocol {
+	case "tcp", "udp":
+		if rule.FromPort < 0 || rule.ToPort < 0 || rule.FromPort > rule.ToPort {
+			return fmt.Errorf("invalid port range %d-%d", rule.FromPort, rule.ToPort)
+		}
+	case "icmp", "icmpv6":
+		if rule.FromPort > 255 || rule.ToPort > 255 {
+			return fmt.Errorf("invalid ICMP type/code %d/%d", rule.FromPort, rule.ToPort)
+		}
+	case "all":
+		rule.FromPort, rule.ToPort = 0, 65535
+	default:
+		return fmt.Errorf("unsupported protocol %s", rule.Protocol)
+	}
+
+	if _, ipNet, err := net.ParseCIDR(rule.Source); err == nil {
+		ipv6 := ipNet.IP.To4() == nil
+		if (rule.Protocol == "icmp" && ipv6) || (rule.Protocol == "icmpv6" && !ipv6) {
+			return fmt.Errorf("protocol %s does not apply to source %s", rule.Protocol, rule.Source)
+		}
+		rule.Source = ipNet.String()
+		return nil
+	}
+
+	source, err := s.sgRepo.GetByID(rule.Source, userID)
+	if err != nil {
+		return err
+	}
+	if source == nil || source.VPCID != sg.VPCID {
+		return fmt.Errorf("source %s is neither a CIDR block nor a security group in the same VPC", rule.Source)
+	}
+
+	return nil
+}
+
+func (s *securityGroupService) getSecurityGroup(id string, userID string) (*models.SecurityGroup, error) {
+	sg, err := s.sgRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get security group", "error", err, "security_group_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get security group")
+	}
+	if sg == nil {
+		return nil, errors.ErrSecurityGroupNotFound
+	}
+	return sg, nil
+}
+
+// loadRules splits the stored rules into inbound and outbound lists
+func (s *securityGroupService) loadRules(sg *models.SecurityGroup) error {
+	rules, err := s.sgRepo.ListRules(sg.ID)
+	if err != nil {
+		s.logger.Error("Failed to list security group rules", "error", err, "security_group_id", sg.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list security group rules")
+	}
+
+	sg.InboundRules = []models.SecurityGroupRule{}
+	sg.OutboundRules = []models.SecurityGroupRule{}
+	for _, rule := range rules {
+		if rule.Direction == "inbound" {
+			sg.InboundRules = append(sg.InboundRules, rule)
+		} else {
+			sg.OutboundRules = append(sg.OutboundRules, rule)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+type SubnetService interface {
+	CreateSubnet(userID string, req *dto.CreateSubnetRequest) (*models.Subnet, error)
+	GetSubnet(id string, userID string) (*models.Subnet, error)
+	ListSubnets(userID string, vpcID *string, page, pageSize int) (*dto.SubnetListResponse, error)
+	UpdateSubnet(id string, userID string, req *dto.UpdateSubnetRequest) (*models.Subnet, error)
+	DeleteSubnet(id string, userID string) error
+}
+
+type subnetService struct {
+	subnetRepo       repositories.SubnetRepository
+	vpcRepo          repositories.VPCRepository
+	ipAllocationRepo repositories.IPAllocationRepository
+	networkACLRepo   repositories.NetworkACLRepository
+	routeTableRepo   repositories.RouteTableRepository
+	natRepo          repositories.NATGatewayRepository
+	logger           *utils.Logger
+}
+
+func NewSubnetService(subnetRepo repositories.SubnetRepository, vpcRepo repositories.VPCRepository, ipAllocationRepo repositories.IPAllocationRepository, networkACLRepo repositories.NetworkACLRepository, routeTableRepo repositories.RouteTableRepository, natRepo repositories.NATGatewayRepository, logger *utils.Logger) SubnetService {
+	return &subnetService{
+		subnetRepo:       subnetRepo,
+		vpcRepo:          vpcRepo,
+		ipAllocationRepo: ipAllocationRepo,
+		networkACLRepo:   networkACLRepo,
+		routeTableRepo:   routeTableRepo,
+		natRepo:          natRepo,
+		logger:           logger,
+	}
+}
+
+func (s *subnetService) CreateSubnet(userID string, req *dto.CreateSubnetRequest) (*models.Subnet, error) {
+	s.logger.Info("Creating new subnet", "user_id", userID, "vpc_id", req.VPCID, "name", req.Name)
+
+	// Check parent VPC ownership
+	vpc, err := s.vpcRepo.GetByID(req.VPCID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC", "error", err, "vpc_id", req.VPCID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if vpc == nil {
+		s.logger.Warn("VPC not found", "vpc_id", req.VPCID)
+		return nil, errors.ErrVPCNotFound
+	}
+
+	// Validate CIDR block against the VPC and its other subnets
+	cidrBlock, err := s.validateSubnetCIDR(vpc, req.CIDRBlock)
+	if err != nil {
+		s.logger.Warn("Subnet CIDR rejected", "error", err, "cidr", req.CIDRBlock, "vpc_id", vpc.ID)
+		return nil, err
+	}
+	var ipv6CIDRBlock *string
+	if req.IPv6CIDRBlock != nil {
+		cidr, err := s.validateSubnetIPv6CIDR(vpc, *req.IPv6CIDRBlock)
+		if err != nil {
+			s.logger.Warn("Subnet IPv6 CIDR rejected", "error", err, "cidr", *req.IPv6CIDRBlock, "vpc_id", vpc.ID)
+			return nil, err
+		}
+		ipv6CIDRBlock = &cidr
+	}
+
+	// Check for name conflicts
+	existingSubnet, err := s.subnetRepo.GetByName(vpc.ID, req.Name)
+	if err != nil {
+		s.logger.Error("Failed to check name conflict", "error", err, "name", req.Name)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check subnet name")
+	}
+	if existingSubnet != nil {
+		s.logger.Warn("Subnet name already exists", "name", req.Name, "vpc_id", vpc.ID)
+		return nil, errors.ErrSubnetAlreadyExists
+	}
+
+	// Create subnet model
+	now := time.Now()
+	subnet := &models.Subnet{
+		ID:               uuid.New().String(),
+		VPCID:            vpc.ID,
+		Name:             req.Name,
+		CIDRBlock:        cidrBlock,
+		IPv6CIDRBlock:    ipv6CIDRBlock,
+		AvailabilityZone: req.AvailabilityZone,
+		IsPublic:         req.IsPublic,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	// A concurrent request may have taken an overlapping block since the
+	// check above; the database rejects it
+	if err := s.subnetRepo.Create(subnet); err != nil {
+		if err == errors.ErrSubnetCIDRConflict {
+			s.logger.Warn("Subnet CIDR overlaps a concurrently created subnet", "cidr", subnet.CIDRBlock, "vpc_id", vpc.ID)
+			return nil, err
+		}
+		s.logger.Error("Failed to create subnet in database", "error", err, "subnet_id", subnet.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create subnet")
+	}
+
+	s.logger.Info("Subnet created successfully", "subnet_id", subnet.ID, "cidr", subnet.CIDRBlock)
+	return subnet, nil
+}
+
+func (s *subnetService) GetSubnet(id string, userID string) (*models.Subnet, error) {
+	s.logger.Info("Getting subnet", "subnet_id", id, "user_id", userID)
+
+	subnet, err := s.subnetRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet", "error", err, "subnet_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet")
+	}
+	if subnet == nil {
+		s.logger.Warn("Subnet not found", "subnet_id", id)
+		return nil, errors.ErrSubnetNotFound
+	}
+
+	return subnet, nil
+}
+
+func (s *subnetService) ListSubnets(userID string, vpcID *string, page, pageSize int) (*dto.SubnetListResponse, error) {
+	s.logger.Info("Listing subnets", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	// Scope the listing to a VPC the user owns
+	if vpcID != nil {
+		vpc, err := s.vpcRepo.GetByID(*vpcID, userID)
+		if err != nil {
+			s.logger.Error("Failed to get VPC", "error", err, "vpc_id", *vpcID)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+		}
+		if vpc == nil {
+			return nil, errors.ErrVPCNotFound
+		}
+	}
+
+	subnets, total, err := s.subnetRepo.List(userID, vpcID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list subnets", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list subnets")
+	}
+
+	subnetResponses := make([]dto.SubnetResponse, len(subnets))
+	for i, subnet := range subnets {
+		subnetResponses[i] = dto.ToSubnetResponse(&subnet)
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.SubnetListResponse{
+		Subnets:    subnetResponses,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+func (s *subnetService) UpdateSubnet(id string, userID string, req *dto.UpdateSubnetRequest) (*models.Subnet, error) {
+	s.logger.Info("Updating subnet", "subnet_id", id, "user_id", userID)
+
+	existingSubnet, err := s.GetSubnet(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+
+	if req.Name != nil && *req.Name != existingSubnet.Name {
+		conflictSubnet, err := s.subnetRepo.GetByName(existingSubnet.VPCID, *req.Name)
+		if err != nil {
+			s.logger.Error("Failed to check name conflict", "error", err, "name", *req.Name)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check subnet name")
+		}
+		if conflictSubnet != nil && conflictSubnet.ID != id {
+			s.logger.Warn("Subnet name already exists", "name", *req.Name)
+			return nil, errors.ErrSubnetAlreadyExists
+		}
+		updates["name"] = *req.Name
+	}
+
+	if req.IsPublic != nil && *req.IsPublic != existingSubnet.IsPublic {
+		updates["is_public"] = *req.IsPublic
+	}
+
+	if len(updates) == 0 {
+		s.logger.Info("No updates provided", "subnet_id", id)
+		return existingSubnet, nil
+	}
+
+	if err := s.subnetRepo.Update(id, userID, updates); err != nil {
+		s.logger.Error("Failed to update subnet", "error", err, "subnet_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to update subnet")
+	}
+
+	s.logger.Info("Subnet updated successfully", "subnet_id", id)
+	return s.GetSubnet(id, userID)
+}
+
+func (s *subnetService) DeleteSubnet(id string, userID string) error {
+	s.logger.Info("Deleting subnet", "subnet_id", id, "user_id", userID)
+
+	if _, err := s.GetSubnet(id, userID); err != nil {
+		return err
+	}
+
+	// Refuse while addresses are still handed out from the subnet
+	allocated, err := s.ipAllocationRepo.CountBySubnet(id)
+	if err != nil {
+		s.logger.Error("Failed to count IP allocations", "error", err, "subnet_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check subnet usage")
+	}
+	if allocated > 0 {
+		s.logger.Warn("Subnet still has allocated addresses", "subnet_id", id, "allocated", allocated)
+		return errors.ErrResourceInUse
+	}
+
+	// The ACL flows match the subnet range, so disassociate first
+	aclID, err := s.networkACLRepo.GetAssociatedACLID(id)
+	if err != nil {
+		s.logger.Error("Failed to get network ACL association", "error", err, "subnet_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check subnet usage")
+	}
+	if aclID != nil {
+		s.logger.Warn("Subnet is still associated with a network ACL", "subnet_id", id, "network_acl_id", *aclID)
+		return errors.ErrResourceInUse
+	}
+
+	// Likewise for the routes of an explicitly associated route table
+	routeTableID, err := s.routeTableRepo.GetAssociatedTableID(id)
+	if err != nil {
+		s.logger.Error("Failed to get route table association", "error", err, "subnet_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check subnet usage")
+	}
+	if routeTableID != nil {
+		s.logger.Warn("Subnet is still associated with a route table", "subnet_id", id, "route_table_id", *routeTableID)
+		return errors.ErrResourceInUse
+	}
+
+	natGateways, err := s.natRepo.CountBySubnet(id)
+	if err != nil {
+		s.logger.Error("Failed to count NAT gateways", "error", err, "subnet_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check subnet usage")
+	}
+	if natGateways > 0 {
+		s.logger.Warn("Subnet still has NAT gateways", "subnet_id", id, "nat_gateways", natGateways)
+		return errors.ErrResourceInUse
+	}
+
+	if err := s.subnetRepo.Delete(id, userID); err != nil {
+		s.logger.Error("Failed to delete subnet", "error", err, "subnet_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete subnet")
+	}
+
+	s.logger.Info("Subnet deleted successfully", "subnet_id", id)
+	return nil
+}
+
+// validateSubnetCIDR checks that the CIDR block sits inside the VPC range and
+// does not overlap any sibling subnet. It returns the canonical network form.
+func (s *subnetService) validateSubnetCIDR(vpc *models.VPC, cidr string) (string, error) {
+	subnetNet, err := network.ParseIPv4CIDR(cidr)
+	if err != nil {
+		return "", errors.ErrInvalidCIDR
+	}
+
+	// Same prefix limits as VPCs (/16 to /28)
+	ones, _ := subnetNet.Mask.Size()
+	if ones < 16 || ones > 28 {
+		return "", errors.ErrInvalidCIDR
+	}
+
+	vpcNet, err := network.ParseIPv4CIDR(vpc.CIDRBlock)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_VPC_CIDR", "VPC has an invalid CIDR block")
+	}
+	if !network.CIDRContains(vpcNet, subnetNet) {
+		return "", errors.ErrSubnetCIDROutOfRange
+	}
+
+	siblings, err := s.subnetRepo.ListByVPC(vpc.ID)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list subnets")
+	}
+	for _, sibling := range siblings {
+		siblingNet, err := network.ParseIPv4CIDR(sibling.CIDRBlock)
+		if err != nil {
+			continue
+		}
+		if network.CIDROverlaps(subnetNet, siblingNet) {
+			return "", errors.ErrSubnetCIDRConflict
+		}
+	}
+
+	return subnetNet.String(), nil
+}
+
+// validateSubnetIPv6CIDR checks that an IPv6 CIDR block is a /64 of the VPC's
+// IPv6 block not taken by a sibling subnet. It returns the canonical network
+// form.
+func (s *subnetService) validateSubnetIPv6CIDR(vpc *models.VPC, cidr string) (string, error) {
+	if vpc.IPv6CIDRBlock == nil {
+		return "", errors.ErrInvalidCIDR
+	}
+	subnetNet, err := network.ParseIPv6CIDR(cidr)
+	if err != nil {
+		return "", errors.ErrInvalidCIDR
+	}
+	if ones, _ := subnetNet.Mask.Size(); ones != network.SubnetIPv6PrefixLength {
+		return "", errors.ErrInvalidCIDR
+	}
+
+	vpcNet, err := network.ParseIPv6CIDR(*vpc.IPv6CIDRBlock)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_VPC_CIDR", "VPC has an invalid IPv6 CIDR block")
+	}
+	if !network.CIDRContains(vpcNet, subnetNet) {
+		return "", errors.ErrSubnetCIDROutOfRange
+	}
+
+	siblings, err := s.subnetRepo.ListByVPC(vpc.ID)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list subnets")
+	}
+	for _, sibling := range siblings {
+		if sibling.IPv6CIDRBlock != nil && *sibling.IPv6CIDRBlock == subnetNet.String() {
+			return "", errors.ErrSubnetCIDRConflict
+		}
+	}
+
+	return subnetNet.String(), nil
+}
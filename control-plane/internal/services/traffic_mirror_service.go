@@ -0,0 +1,548 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// maxTrafficMirrorFilterRules bounds the rules of a session. A rule over a
+// port range becomes a mirror per aligned block of ports.
+const maxTrafficMirrorFilterRules = 10
+
+// TrafficMirrorService copies the traffic of instances for packet captures.
+// A session mirrors the port of its source instance on the VPC bridge of the
+// instance's node to the port of a target instance on the same node, or to
+// a GRE or ERSPAN endpoint through a tunnel port of its own. Every node
+// programs the sessions whose source runs on it.
+type TrafficMirrorService interface {
+	CreateSession(userID string, req *dto.CreateTrafficMirrorSessionRequest) (*models.TrafficMirrorSession, error)
+	GetSession(id string, userID string) (*models.TrafficMirrorSession, error)
+	ListSessions(userID string, vpcID *string, page, pageSize int) (*dto.TrafficMirrorSessionListResponse, error)
+	DeleteSession(id string, userID string) error
+	AddFilterRule(id string, userID string, req *dto.TrafficMirrorFilterRuleRequest) (*models.TrafficMirrorFilterRule, error)
+	DeleteFilterRule(id string, ruleID string, userID string) error
+	DesiredSessions(vpcID string) ([]network.MirrorSession, error)
+}
+
+type trafficMirrorService struct {
+	mirrorRepo   repositories.TrafficMirrorRepository
+	vpcRepo      repositories.VPCRepository
+	subnetRepo   repositories.SubnetRepository
+	instanceRepo repositories.InstanceRepository
+	ovsManager   network.OVSManager
+	logger       *utils.Logger
+}
+
+func NewTrafficMirrorService(
+	mirrorRepo repositories.TrafficMirrorRepository,
+	vpcRepo repositories.VPCRepository,
+	subnetRepo repositories.SubnetRepository,
+	instanceRepo repositories.InstanceRepository,
+	ovsManager network.OVSManager,
+	logger *utils.Logger,
+) TrafficMirrorService {
+	return &trafficMirrorService{
+		mirrorRepo:   mirrorRepo,
+		vpcRepo:      vpcRepo,
+		subnetRepo:   subnetRepo,
+		instanceRepo: instanceRepo,
+		ovsManager:   ovsManager,
+		logger:       logger,
+	}
+}
+
+// CreateSession starts mirroring the traffic of one of the user's instances
+func (s *trafficMirrorService) CreateSession(userID string, req *dto.CreateTrafficMirrorSessionRequest) (*models.TrafficMirrorSession, error) {
+	s.logger.Info("Creating traffic mirror session", "user_id", userID, "source_instance_id", req.SourceInstanceID, "target_type", req.TargetType)
+
+	vpcID, err := s.instanceVPC(req.SourceInstanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.TrafficMirrorSession{
+		ID:               uuid.New().String(),
+		Name:             req.Name,
+		UserID:           userID,
+		VPCID:            vpcID,
+		SourceInstanceID: req.SourceInstanceID,
+		TargetType:       req.TargetType,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	switch req.TargetType {
+	case network.MirrorTargetInstance:
+		if req.TargetInstanceID == "" || req.TargetIP != "" || req.TargetInstanceID == req.SourceInstanceID {
+			return nil, errors.ErrInvalidTrafficMirrorTarget
+		}
+		targetVPCID, err := s.instanceVPC(req.TargetInstanceID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if targetVPCID != vpcID {
+			s.logger.Warn("Traffic mirror target is in another VPC", "source_instance_id", req.SourceInstanceID, "target_instance_id", req.TargetInstanceID)
+			return nil, errors.ErrInvalidTrafficMirrorTarget
+		}
+		session.TargetInstanceID = req.TargetInstanceID
+	default:
+		endpoint := network.MirrorEndpoint{Type: req.TargetType, RemoteIP: req.TargetIP, Key: req.TargetKey}
+		if req.TargetInstanceID != "" {
+			return nil, errors.ErrInvalidTrafficMirrorTarget
+		}
+		if err := endpoint.Validate(); err != nil {
+			s.logger.Warn("Invalid traffic mirror endpoint", "error", err, "target_ip", req.TargetIP)
+			return nil, errors.ErrInvalidTrafficMirrorTarget
+		}
+		session.TargetIP = req.TargetIP
+		session.TargetKey = req.TargetKey
+	}
+
+	if err := s.checkConflicts(session); err != nil {
+		return nil, err
+	}
+
+	if len(req.FilterRules) > maxTrafficMirrorFilterRules {
+		return nil, errors.ErrTrafficMirrorRuleLimit
+	}
+	rules := make([]models.TrafficMirrorFilterRule, len(req.FilterRules))
+	for i := range req.FilterRules {
+		rule, err := s.newRule(session.ID, &req.FilterRules[i])
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = *rule
+	}
+	session.FilterRules = rules
+
+	if err := s.mirrorRepo.Create(session); err != nil {
+		s.logger.Error("Failed to create traffic mirror session in database", "error", err, "traffic_mirror_session_id", session.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create traffic mirror session")
+	}
+	for i := range rules {
+		if err := s.mirrorRepo.CreateRule(&rules[i]); err != nil {
+			s.logger.Error("Failed to create traffic mirror filter rule", "error", err, "traffic_mirror_session_id", session.ID)
+			// Rollback database changes
+			if delErr := s.mirrorRepo.Delete(session.ID); delErr != nil {
+				s.logger.Error("Failed to rollback traffic mirror session creation", "error", delErr, "traffic_mirror_session_id", session.ID)
+			}
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create traffic mirror filter rule")
+		}
+	}
+
+	if err := s.syncSession(session); err != nil {
+		// Rollback database changes
+		if delErr := s.mirrorRepo.Delete(session.ID); delErr != nil {
+			s.logger.Error("Failed to rollback traffic mirror session creation", "error", delErr, "traffic_mirror_session_id", session.ID)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Traffic mirror session created successfully", "traffic_mirror_session_id", session.ID)
+	return session, nil
+}
+
+func (s *trafficMirrorService) GetSession(id string, userID string) (*models.TrafficMirrorSession, error) {
+	session, err := s.mirrorRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get traffic mirror session", "error", err, "traffic_mirror_session_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get traffic mirror session")
+	}
+	if session == nil {
+		return nil, errors.ErrTrafficMirrorSessionNotFound
+	}
+
+	if err := s.loadRules(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *trafficMirrorService) ListSessions(userID string, vpcID *string, page, pageSize int) (*dto.TrafficMirrorSessionListResponse, error) {
+	s.logger.Info("Listing traffic mirror sessions", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	sessions, total, err := s.mirrorRepo.List(userID, vpcID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list traffic mirror sessions", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list traffic mirror sessions")
+	}
+
+	sessionResponses := make([]dto.TrafficMirrorSessionResponse, len(sessions))
+	for i := range sessions {
+		if err := s.loadRules(&sessions[i]); err != nil {
+			return nil, err
+		}
+		sessionResponses[i] = dto.ToTrafficMirrorSessionResponse(&sessions[i])
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.TrafficMirrorSessionListResponse{
+		Sessions:   sessionResponses,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// DeleteSession stops mirroring and deletes a session along with its rules
+func (s *trafficMirrorService) DeleteSession(id string, userID string) error {
+	s.logger.Info("Deleting traffic mirror session", "traffic_mirror_session_id", id, "user_id", userID)
+
+	session, err := s.GetSession(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mirrorRepo.Delete(session.ID); err != nil {
+		s.logger.Error("Failed to delete traffic mirror session", "error", err, "traffic_mirror_session_id", session.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete traffic mirror session")
+	}
+
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, session.VPCID)
+	if err != nil {
+		return err
+	}
+	if err := s.removeSession(bridgeName, session.ID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Traffic mirror session deleted successfully", "traffic_mirror_session_id", session.ID)
+	return nil
+}
+
+// AddFilterRule narrows down the traffic a session mirrors. A session that
+// mirrored everything only mirrors what its rules match from then on.
+func (s *trafficMirrorService) AddFilterRule(id string, userID string, req *dto.TrafficMirrorFilterRuleRequest) (*models.TrafficMirrorFilterRule, error) {
+	s.logger.Info("Adding traffic mirror filter rule", "traffic_mirror_session_id", id, "direction", req.Direction, "protocol", req.Protocol)
+
+	session, err := s.GetSession(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(session.FilterRules) >= maxTrafficMirrorFilterRules {
+		return nil, errors.ErrTrafficMirrorRuleLimit
+	}
+
+	rule, err := s.newRule(session.ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mirrorRepo.CreateRule(rule); err != nil {
+		s.logger.Error("Failed to create traffic mirror filter rule", "error", err, "traffic_mirror_session_id", session.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create traffic mirror filter rule")
+	}
+	session.FilterRules = append(session.FilterRules, *rule)
+
+	if err := s.syncSession(session); err != nil {
+		// Rollback database changes
+		if delErr := s.mirrorRepo.DeleteRule(session.ID, rule.ID); delErr != nil {
+			s.logger.Error("Failed to rollback traffic mirror filter rule", "error", delErr, "rule_id", rule.ID)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Traffic mirror filter rule added successfully", "traffic_mirror_session_id", session.ID, "rule_id", rule.ID)
+	return rule, nil
+}
+
+// DeleteFilterRule removes a rule from a session. A session left without
+// rules mirrors all traffic again.
+func (s *trafficMirrorService) DeleteFilterRule(id string, ruleID string, userID string) error {
+	s.logger.Info("Deleting traffic mirror filter rule", "traffic_mirror_session_id", id, "rule_id", ruleID)
+
+	session, err := s.GetSession(id, userID)
+	if err != nil {
+		return err
+	}
+
+	kept := make([]models.TrafficMirrorFilterRule, 0, len(session.FilterRules))
+	for _, rule := range session.FilterRules {
+		if rule.ID != ruleID {
+			kept = append(kept, rule)
+		}
+	}
+	if len(kept) == len(session.FilterRules) {
+		return errors.ErrTrafficMirrorRuleNotFound
+	}
+
+	if err := s.mirrorRepo.DeleteRule(session.ID, ruleID); err != nil {
+		s.logger.Error("Failed to delete traffic mirror filter rule", "error", err, "rule_id", ruleID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete traffic mirror filter rule")
+	}
+	session.FilterRules = kept
+
+	if err := s.syncSession(session); err != nil {
+		return err
+	}
+
+	s.logger.Info("Traffic mirror filter rule deleted successfully", "traffic_mirror_session_id", session.ID, "rule_id", ruleID)
+	return nil
+}
+
+// DesiredSessions returns the mirror sessions of a VPC as they are
+// programmed on the bridges of its nodes
+func (s *trafficMirrorService) DesiredSessions(vpcID string) ([]network.MirrorSession, error) {
+	sessions, err := s.mirrorRepo.ListByVPC(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list traffic mirror sessions", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list traffic mirror sessions")
+	}
+
+	desired := make([]network.MirrorSession, 0, len(sessions))
+	for i := range sessions {
+		if err := s.loadRules(&sessions[i]); err != nil {
+			return nil, err
+		}
+		desired = append(desired, toMirrorSession(&sessions[i]))
+	}
+	return desired, nil
+}
+
+// syncSession programs the mirrors of a session on this node's bridge of
+// its VPC, or removes them if the source or target instance does not run
+// here. Nodes the instances run on are left to their network controllers.
+func (s *trafficMirrorService) syncSession(session *models.TrafficMirrorSession) error {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, session.VPCID)
+	if err != nil {
+		return err
+	}
+
+	mirrorSession := toMirrorSession(session)
+	mirrors, err := network.CompileMirrorSession(mirrorSession)
+	if err != nil {
+		s.logger.Error("Failed to compile traffic mirror session", "error", err, "traffic_mirror_session_id", session.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile traffic mirror session")
+	}
+
+	ports, err := s.ovsManager.ListPorts(bridgeName)
+	if err != nil {
+		s.logger.Error("Failed to list ports", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to mirror instance traffic")
+	}
+	present := make(map[string]bool, len(ports))
+	for _, port := range ports {
+		present[port.Name] = true
+	}
+	if !present[mirrorSession.SourcePort] || (mirrorSession.Endpoint == nil && !present[mirrorSession.TargetPort]) {
+		return s.removeSession(bridgeName, session.ID)
+	}
+
+	if mirrorSession.Endpoint != nil {
+		if err := s.ovsManager.AddMirrorPort(bridgeName, mirrorSession.TargetPort, *mirrorSession.Endpoint); err != nil {
+			s.logger.Error("Failed to add mirror port", "error", err, "bridge_name", bridgeName)
+			return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to mirror instance traffic")
+		}
+	}
+
+	// Mirrors of rules that were deleted go first
+	wanted := make(map[string]bool, len(mirrors))
+	for _, mirror := range mirrors {
+		wanted[mirror.Name] = true
+	}
+	if err := s.deleteMirrors(bridgeName, session.ID, wanted); err != nil {
+		return err
+	}
+	for _, mirror := range mirrors {
+		if err := s.ovsManager.SetMirror(bridgeName, mirror); err != nil {
+			s.logger.Error("Failed to set mirror", "error", err, "bridge_name", bridgeName, "mirror", mirror.Name)
+			return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to mirror instance traffic")
+		}
+	}
+
+	return nil
+}
+
+// removeSession deletes the mirrors and the tunnel port of a session from a
+// bridge
+func (s *trafficMirrorService) removeSession(bridgeName, sessionID string) error {
+	if err := s.deleteMirrors(bridgeName, sessionID, nil); err != nil {
+		return err
+	}
+	if err := s.ovsManager.DeletePort(bridgeName, network.MirrorTunnelPortName(sessionID)); err != nil {
+		s.logger.Error("Failed to delete mirror port", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to stop mirroring instance traffic")
+	}
+	return nil
+}
+
+// deleteMirrors deletes the mirrors of a session that are not wanted
+func (s *trafficMirrorService) deleteMirrors(bridgeName, sessionID string, wanted map[string]bool) error {
+	mirrors, err := s.ovsManager.ListMirrors(bridgeName)
+	if err != nil {
+		s.logger.Error("Failed to list mirrors", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to list mirrors")
+	}
+
+	prefix := network.MirrorSessionPrefix(sessionID)
+	for _, mirror := range mirrors {
+		if wanted[mirror.Name] || !strings.HasPrefix(mirror.Name, prefix) {
+			continue
+		}
+		if err := s.ovsManager.DeleteMirror(bridgeName, mirror.Name); err != nil {
+			s.logger.Error("Failed to delete mirror", "error", err, "bridge_name", bridgeName, "mirror", mirror.Name)
+			return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to stop mirroring instance traffic")
+		}
+	}
+	return nil
+}
+
+// checkConflicts rejects a session whose source is the target of another
+// session or whose target is the source of one. The port of a target
+// carries nothing but mirrored traffic.
+func (s *trafficMirrorService) checkConflicts(session *models.TrafficMirrorSession) error {
+	sessions, err := s.mirrorRepo.ListByVPC(session.VPCID)
+	if err != nil {
+		s.logger.Error("Failed to list traffic mirror sessions", "error", err, "vpc_id", session.VPCID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list traffic mirror sessions")
+	}
+
+	for _, other := range sessions {
+		if other.TargetInstanceID == session.SourceInstanceID ||
+			(session.TargetInstanceID != "" && other.SourceInstanceID == session.TargetInstanceID) {
+			s.logger.Warn("Traffic mirror session conflicts with another", "traffic_mirror_session_id", other.ID)
+			return errors.ErrTrafficMirrorConflict
+		}
+	}
+	return nil
+}
+
+// newRule validates a filter rule request and normalizes its ports and
+// CIDR block the way security group rules are
+func (s *trafficMirrorService) newRule(sessionID string, req *dto.TrafficMirrorFilterRuleRequest) (*models.TrafficMirrorFilterRule, error) {
+	rule := &models.TrafficMirrorFilterRule{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		Direction: req.Direction,
+		Protocol:  req.Protocol,
+		FromPort:  req.FromPort,
+		ToPort:    req.ToPort,
+		CIDRBlock: req.CIDRBlock,
+	}
+
+	if err := validateMirrorRule(rule); err != nil {
+		s.logger.Warn("Invalid traffic mirror filter rule", "error", err, "traffic_mirror_session_id", sessionID)
+		return nil, errors.ErrInvalidTrafficMirrorRule
+	}
+	return rule, nil
+}
+
+// loadRules fills in the filter rules of a session
+func (s *trafficMirrorService) loadRules(session *models.TrafficMirrorSession) error {
+	rules, err := s.mirrorRepo.ListRules(session.ID)
+	if err != nil {
+		s.logger.Error("Failed to list traffic mirror filter rules", "error", err, "traffic_mirror_session_id", session.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list traffic mirror filter rules")
+	}
+	if rules == nil {
+		rules = []models.TrafficMirrorFilterRule{}
+	}
+	session.FilterRules = rules
+	return nil
+}
+
+// instanceVPC returns the VPC of one of the user's instances
+func (s *trafficMirrorService) instanceVPC(instanceID string, userID string) (string, error) {
+	instance, err := s.instanceRepo.GetByID(instanceID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get instance", "error", err, "instance_id", instanceID)
+		return "", errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get instance")
+	}
+	if instance == nil {
+		return "", errors.ErrInstanceNotFound
+	}
+	if instance.SubnetID == "" {
+		return "", errors.ErrTrafficMirrorNoInterface
+	}
+
+	subnet, err := s.subnetRepo.GetByID(instance.SubnetID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get subnet", "error", err, "subnet_id", instance.SubnetID)
+		return "", errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get subnet")
+	}
+	if subnet == nil {
+		return "", errors.ErrTrafficMirrorNoInterface
+	}
+	return subnet.VPCID, nil
+}
+
+// validateMirrorRule checks ports against the protocol and normalizes the
+// CIDR block
+func validateMirrorRule(rule *models.TrafficMirrorFilterRule) error {
+	switch rule.Protocol {
+	case "tcp", "udp":
+		if rule.FromPort < 0 || rule.ToPort < 0 || rule.FromPort > rule.ToPort {
+			return fmt.Errorf("invalid port range %d-%d", rule.FromPort, rule.ToPort)
+		}
+	case "icmp":
+		if rule.FromPort > 255 || rule.ToPort > 255 {
+			return fmt.Errorf("invalid ICMP type/code %d/%d", rule.FromPort, rule.ToPort)
+		}
+	case "all":
+		rule.FromPort, rule.ToPort = 0, 65535
+	default:
+		return fmt.Errorf("unsupported protocol %s", rule.Protocol)
+	}
+
+	if rule.CIDRBlock == "" {
+		rule.CIDRBlock = "0.0.0.0/0"
+	}
+	ipNet, err := network.ParseIPv4CIDR(rule.CIDRBlock)
+	if err != nil {
+		return err
+	}
+	rule.CIDRBlock = ipNet.String()
+	return nil
+}
+
+// toMirrorSession converts a session to how it is programmed on a bridge
+func toMirrorSession(session *models.TrafficMirrorSession) network.MirrorSession {
+	mirrorSession := network.MirrorSession{
+		ID:         session.ID,
+		SourcePort: network.InstancePortName(session.SourceInstanceID),
+		Rules:      make([]network.MirrorFilterRule, len(session.FilterRules)),
+	}
+	if session.TargetType == network.MirrorTargetInstance {
+		mirrorSession.TargetPort = network.InstancePortName(session.TargetInstanceID)
+	} else {
+		mirrorSession.TargetPort = network.MirrorTunnelPortName(session.ID)
+		mirrorSession.Endpoint = &network.MirrorEndpoint{
+			Type:     session.TargetType,
+			RemoteIP: session.TargetIP,
+			Key:      session.TargetKey,
+		}
+	}
+
+	for i, rule := range session.FilterRules {
+		mirrorSession.Rules[i] = network.MirrorFilterRule{
+			Direction: rule.Direction,
+			Protocol:  rule.Protocol,
+			FromPort:  rule.FromPort,
+			ToPort:    rule.ToPort,
+			CIDRBlock: rule.CIDRBlock,
+		}
+	}
+	return mirrorSession
+}
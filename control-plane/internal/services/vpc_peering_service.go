@@ -0,0 +1,464 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gon-cloud-platform/control-plane/internal/api/handlers/dto"
+	"gon-cloud-platform/control-plane/internal/database/repositories"
+	"gon-cloud-platform/control-plane/internal/models"
+	"gon-cloud-platform/control-plane/internal/network"
+	"gon-cloud-platform/control-plane/internal/utils"
+	"gon-cloud-platform/control-plane/pkg/errors"
+)
+
+// VPC peering lifecycle states
+const (
+	VPCPeeringStatePendingAcceptance = "pending-acceptance"
+	VPCPeeringStateActive            = "active"
+	VPCPeeringStateRejected          = "rejected"
+)
+
+// VPCPeeringService connects pairs of VPCs. The owner of one VPC requests a
+// peering with another VPC, possibly another user's, and the owner of that
+// VPC accepts or rejects it. Either owner may delete it. Only active
+// peerings carry traffic, and only to the ranges of the two VPCs: routes
+// must send traffic into the peering on both sides.
+type VPCPeeringService interface {
+	CreateVPCPeering(userID string, req *dto.CreateVPCPeeringRequest) (*models.VPCPeering, error)
+	GetVPCPeering(id string, userID string) (*models.VPCPeering, error)
+	ListVPCPeerings(userID string, vpcID *string, page, pageSize int) (*dto.VPCPeeringListResponse, error)
+	AcceptVPCPeering(id string, userID string) (*models.VPCPeering, error)
+	RejectVPCPeering(id string, userID string) (*models.VPCPeering, error)
+	DeleteVPCPeering(id string, userID string) error
+	PatchPorts(vpcID string) (map[string]string, error)
+	DesiredFlows(vpc *models.VPC) (*network.FlowSet, error)
+}
+
+type vpcPeeringService struct {
+	peeringRepo       repositories.VPCPeeringRepository
+	vpcRepo           repositories.VPCRepository
+	routeTableService RouteTableService
+	ovsManager        network.OVSManager
+	logger            *utils.Logger
+}
+
+func NewVPCPeeringService(
+	peeringRepo repositories.VPCPeeringRepository,
+	vpcRepo repositories.VPCRepository,
+	routeTableService RouteTableService,
+	ovsManager network.OVSManager,
+	logger *utils.Logger,
+) VPCPeeringService {
+	return &vpcPeeringService{
+		peeringRepo:       peeringRepo,
+		vpcRepo:           vpcRepo,
+		routeTableService: routeTableService,
+		ovsManager:        ovsManager,
+		logger:            logger,
+	}
+}
+
+// CreateVPCPeering requests a peering of one of the caller's VPCs with
+// another VPC. The two VPCs must not overlap, as neither could tell which
+// side an address in both is on.
+func (s *vpcPeeringService) CreateVPCPeering(userID string, req *dto.CreateVPCPeeringRequest) (*models.VPCPeering, error) {
+	s.logger.Info("Requesting VPC peering", "user_id", userID, "vpc_id", req.VPCID, "peer_vpc_id", req.PeerVPCID)
+
+	peerUserID := req.PeerUserID
+	if peerUserID == "" {
+		peerUserID = userID
+	}
+
+	vpc, err := s.getVPC(req.VPCID, userID)
+	if err != nil {
+		return nil, err
+	}
+	peerVPC, err := s.getVPC(req.PeerVPCID, peerUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	vpcNet, err := network.ParseIPv4CIDR(vpc.CIDRBlock)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_CIDR", "Invalid VPC CIDR block")
+	}
+	peerNet, err := network.ParseIPv4CIDR(peerVPC.CIDRBlock)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_CIDR", "Invalid VPC CIDR block")
+	}
+	if network.CIDROverlaps(vpcNet, peerNet) {
+		s.logger.Warn("VPC CIDR blocks overlap", "vpc_id", vpc.ID, "cidr_block", vpc.CIDRBlock, "peer_vpc_id", peerVPC.ID, "peer_cidr_block", peerVPC.CIDRBlock)
+		return nil, errors.ErrVPCPeeringCIDROverlap
+	}
+	if vpc.IPv6CIDRBlock != nil && peerVPC.IPv6CIDRBlock != nil {
+		vpcNet, err := network.ParseIPv6CIDR(*vpc.IPv6CIDRBlock)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_CIDR", "Invalid VPC IPv6 CIDR block")
+		}
+		peerNet, err := network.ParseIPv6CIDR(*peerVPC.IPv6CIDRBlock)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_CIDR", "Invalid VPC IPv6 CIDR block")
+		}
+		if network.CIDROverlaps(vpcNet, peerNet) {
+			s.logger.Warn("VPC IPv6 CIDR blocks overlap", "vpc_id", vpc.ID, "ipv6_cidr_block", *vpc.IPv6CIDRBlock, "peer_vpc_id", peerVPC.ID, "peer_ipv6_cidr_block", *peerVPC.IPv6CIDRBlock)
+			return nil, errors.ErrVPCPeeringCIDROverlap
+		}
+	}
+
+	existing, err := s.peeringRepo.GetBetween(vpc.ID, peerVPC.ID)
+	if err != nil {
+		s.logger.Error("Failed to check existing VPC peering", "error", err, "vpc_id", vpc.ID, "peer_vpc_id", peerVPC.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check existing VPC peering")
+	}
+	if existing != nil {
+		s.logger.Warn("VPCs already have a VPC peering", "vpc_peering_id", existing.ID, "state", existing.State)
+		return nil, errors.ErrVPCPeeringExists
+	}
+
+	now := time.Now()
+	peering := &models.VPCPeering{
+		ID:              uuid.New().String(),
+		Name:            req.Name,
+		RequesterVPCID:  vpc.ID,
+		RequesterUserID: userID,
+		AccepterVPCID:   peerVPC.ID,
+		AccepterUserID:  peerUserID,
+		State:           VPCPeeringStatePendingAcceptance,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if err := s.peeringRepo.Create(peering); err != nil {
+		s.logger.Error("Failed to create VPC peering in database", "error", err, "vpc_peering_id", peering.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create VPC peering")
+	}
+
+	s.logger.Info("VPC peering requested", "vpc_peering_id", peering.ID, "accepter_user_id", peerUserID)
+	return peering, nil
+}
+
+// GetVPCPeering returns a peering the user is a party to
+func (s *vpcPeeringService) GetVPCPeering(id string, userID string) (*models.VPCPeering, error) {
+	peering, err := s.peeringRepo.GetByID(id, userID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC peering", "error", err, "vpc_peering_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC peering")
+	}
+	if peering == nil {
+		return nil, errors.ErrVPCPeeringNotFound
+	}
+	return peering, nil
+}
+
+func (s *vpcPeeringService) ListVPCPeerings(userID string, vpcID *string, page, pageSize int) (*dto.VPCPeeringListResponse, error) {
+	s.logger.Info("Listing VPC peerings", "user_id", userID, "page", page, "page_size", pageSize)
+
+	// Validate pagination parameters
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	peerings, total, err := s.peeringRepo.List(userID, vpcID, page, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list VPC peerings", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list VPC peerings")
+	}
+
+	peeringResponses := make([]dto.VPCPeeringResponse, len(peerings))
+	for i := range peerings {
+		peeringResponses[i] = dto.ToVPCPeeringResponse(&peerings[i])
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return &dto.VPCPeeringListResponse{
+		VPCPeerings: peeringResponses,
+		Total:       total,
+		Page:        page,
+		PageSize:    pageSize,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+// AcceptVPCPeering activates a pending peering and connects the bridges of
+// its VPCs. Only the owner of the accepter VPC may accept.
+func (s *vpcPeeringService) AcceptVPCPeering(id string, userID string) (*models.VPCPeering, error) {
+	s.logger.Info("Accepting VPC peering", "vpc_peering_id", id, "user_id", userID)
+
+	peering, err := s.pendingForAccepter(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.peeringRepo.UpdateState(peering.ID, VPCPeeringStatePendingAcceptance, VPCPeeringStateActive); err != nil {
+		s.logger.Error("Failed to activate VPC peering", "error", err, "vpc_peering_id", peering.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to accept VPC peering")
+	}
+
+	if err := s.connect(peering); err != nil {
+		// Rollback bridge and database changes
+		if stateErr := s.peeringRepo.UpdateState(peering.ID, VPCPeeringStateActive, VPCPeeringStatePendingAcceptance); stateErr != nil {
+			s.logger.Error("Failed to rollback VPC peering acceptance", "error", stateErr, "vpc_peering_id", peering.ID)
+		}
+		if discErr := s.disconnect(peering); discErr != nil {
+			s.logger.Error("Failed to rollback VPC peering connection", "error", discErr, "vpc_peering_id", peering.ID)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("VPC peering accepted", "vpc_peering_id", peering.ID)
+	return s.GetVPCPeering(id, userID)
+}
+
+// RejectVPCPeering turns down a pending peering. Only the owner of the
+// accepter VPC may reject.
+func (s *vpcPeeringService) RejectVPCPeering(id string, userID string) (*models.VPCPeering, error) {
+	s.logger.Info("Rejecting VPC peering", "vpc_peering_id", id, "user_id", userID)
+
+	peering, err := s.pendingForAccepter(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.peeringRepo.UpdateState(peering.ID, VPCPeeringStatePendingAcceptance, VPCPeeringStateRejected); err != nil {
+		s.logger.Error("Failed to reject VPC peering", "error", err, "vpc_peering_id", peering.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to reject VPC peering")
+	}
+
+	s.logger.Info("VPC peering rejected", "vpc_peering_id", peering.ID)
+	return s.GetVPCPeering(id, userID)
+}
+
+// DeleteVPCPeering deletes a peering in any state. The bridges of an active
+// peering are disconnected and routes that targeted it become blackholes.
+func (s *vpcPeeringService) DeleteVPCPeering(id string, userID string) error {
+	s.logger.Info("Deleting VPC peering", "vpc_peering_id", id, "user_id", userID)
+
+	peering, err := s.GetVPCPeering(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.peeringRepo.Delete(peering.ID); err != nil {
+		s.logger.Error("Failed to delete VPC peering", "error", err, "vpc_peering_id", peering.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to delete VPC peering")
+	}
+
+	if peering.State == VPCPeeringStateActive {
+		if err := s.disconnect(peering); err != nil {
+			return err
+		}
+		for _, side := range peeringSides(peering) {
+			if err := s.routeTableService.SyncVPCRoutes(side.vpcID, side.userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.logger.Info("VPC peering deleted successfully", "vpc_peering_id", peering.ID)
+	return nil
+}
+
+// PatchPorts returns the peering patch ports the bridge of a VPC should
+// have, each mapped to its peer on the other bridge
+func (s *vpcPeeringService) PatchPorts(vpcID string) (map[string]string, error) {
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	peers, err := s.peeringRepo.ListPeers(vpcID)
+	if err != nil {
+		s.logger.Error("Failed to list VPC peers", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list VPC peers")
+	}
+
+	ports := make(map[string]string, len(peers))
+	for _, peer := range peers {
+		port, peerPort := network.PeeringPorts(bridgeName, peer.BridgeName)
+		ports[port] = peerPort
+	}
+	return ports, nil
+}
+
+// DesiredFlows returns the flows that admit traffic from the active peers
+// of a VPC into its bridge. Peers whose dataplane is not provisioned yet
+// are left out until it is.
+func (s *vpcPeeringService) DesiredFlows(vpc *models.VPC) (*network.FlowSet, error) {
+	dataplane, err := s.vpcRepo.GetDataplane(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC dataplane", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC dataplane")
+	}
+	if dataplane == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+
+	peers, err := s.peeringRepo.ListPeers(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to list VPC peers", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list VPC peers")
+	}
+	networks := make([]network.PeerNetwork, 0, len(peers))
+	for _, peer := range peers {
+		if peer.ConntrackZone == 0 {
+			continue
+		}
+		port, _ := network.PeeringPorts(dataplane.BridgeName, peer.BridgeName)
+		peerNetwork := network.PeerNetwork{Port: port, CIDRBlock: peer.CIDRBlock, Zone: peer.ConntrackZone}
+		if peer.IPv6CIDRBlock != nil {
+			peerNetwork.IPv6CIDRBlock = *peer.IPv6CIDRBlock
+		}
+		networks = append(networks, peerNetwork)
+	}
+
+	vpcIPv6CIDR := ""
+	if vpc.IPv6CIDRBlock != nil {
+		vpcIPv6CIDR = *vpc.IPv6CIDRBlock
+	}
+	flows, err := network.CompilePeering(vpc.CIDRBlock, vpcIPv6CIDR, dataplane.ConntrackZone, networks)
+	if err != nil {
+		s.logger.Error("Failed to compile peering flows", "error", err, "vpc_id", vpc.ID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile peering flows")
+	}
+
+	cookie := network.FlowCookie(network.CookieKindPeering, vpc.ID)
+	return &network.FlowSet{
+		Cookie: cookie,
+		Mask:   network.CookieMaskResource,
+		Flows:  network.WithCookie(flows, cookie),
+	}, nil
+}
+
+// pendingForAccepter returns a pending peering the user may accept or
+// reject
+func (s *vpcPeeringService) pendingForAccepter(id string, userID string) (*models.VPCPeering, error) {
+	peering, err := s.GetVPCPeering(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if peering.AccepterUserID != userID {
+		s.logger.Warn("User does not own the accepter VPC", "vpc_peering_id", id, "user_id", userID)
+		return nil, errors.ErrVPCPeeringNotAccepter
+	}
+	if peering.State != VPCPeeringStatePendingAcceptance {
+		return nil, errors.ErrVPCPeeringNotPending
+	}
+	return peering, nil
+}
+
+// peeringSide is one of the two VPCs of a peering and its owner
+type peeringSide struct {
+	vpcID  string
+	userID string
+}
+
+func peeringSides(peering *models.VPCPeering) []peeringSide {
+	return []peeringSide{
+		{vpcID: peering.RequesterVPCID, userID: peering.RequesterUserID},
+		{vpcID: peering.AccepterVPCID, userID: peering.AccepterUserID},
+	}
+}
+
+// connect patches the bridges of a peering's VPCs together and lets each
+// admit traffic from the other
+func (s *vpcPeeringService) connect(peering *models.VPCPeering) error {
+	requesterBridge, err := vpcBridge(s.vpcRepo, s.logger, peering.RequesterVPCID)
+	if err != nil {
+		return err
+	}
+	accepterBridge, err := vpcBridge(s.vpcRepo, s.logger, peering.AccepterVPCID)
+	if err != nil {
+		return err
+	}
+	requesterPort, accepterPort := network.PeeringPorts(requesterBridge, accepterBridge)
+
+	if err := s.ovsManager.AddPatchPort(requesterBridge, requesterPort, accepterPort); err != nil {
+		s.logger.Error("Failed to add peering patch port", "error", err, "bridge_name", requesterBridge)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to connect peered VPCs")
+	}
+	if err := s.ovsManager.AddPatchPort(accepterBridge, accepterPort, requesterPort); err != nil {
+		s.logger.Error("Failed to add peering patch port", "error", err, "bridge_name", accepterBridge)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to connect peered VPCs")
+	}
+
+	for _, side := range peeringSides(peering) {
+		if err := s.syncFlows(side.vpcID, side.userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// disconnect reprograms the peering flows of both VPCs from the database
+// and removes both patch ports, so it must run once the peering is no
+// longer active
+func (s *vpcPeeringService) disconnect(peering *models.VPCPeering) error {
+	requesterBridge, err := vpcBridge(s.vpcRepo, s.logger, peering.RequesterVPCID)
+	if err != nil {
+		return err
+	}
+	accepterBridge, err := vpcBridge(s.vpcRepo, s.logger, peering.AccepterVPCID)
+	if err != nil {
+		return err
+	}
+	requesterPort, accepterPort := network.PeeringPorts(requesterBridge, accepterBridge)
+
+	for _, side := range peeringSides(peering) {
+		if err := s.syncFlows(side.vpcID, side.userID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.ovsManager.DeletePort(requesterBridge, requesterPort); err != nil {
+		s.logger.Error("Failed to delete peering patch port", "error", err, "bridge_name", requesterBridge)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to disconnect peered VPCs")
+	}
+	if err := s.ovsManager.DeletePort(accepterBridge, accepterPort); err != nil {
+		s.logger.Error("Failed to delete peering patch port", "error", err, "bridge_name", accepterBridge)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to disconnect peered VPCs")
+	}
+
+	return nil
+}
+
+// syncFlows replaces the peering flows of a VPC bridge in one bundle
+func (s *vpcPeeringService) syncFlows(vpcID string, userID string) error {
+	vpc, err := s.getVPC(vpcID, userID)
+	if err != nil {
+		return err
+	}
+
+	set, err := s.DesiredFlows(vpc)
+	if err != nil {
+		return err
+	}
+
+	bridgeName, err := vpcBridge(s.vpcRepo, s.logger, vpc.ID)
+	if err != nil {
+		return err
+	}
+	if err := set.Apply(s.ovsManager, bridgeName); err != nil {
+		s.logger.Error("Failed to program peering flows", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to program peering flows")
+	}
+	return nil
+}
+
+func (s *vpcPeeringService) getVPC(vpcID string, userID string) (*models.VPC, error) {
+	vpc, err := s.vpcRepo.GetByID(vpcID, userID)
+	if err != nil {
+		s.logger.Error("Failed to get VPC", "error", err, "vpc_id", vpcID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if vpc == nil {
+		return nil, errors.ErrVPCNotFound
+	}
+	return vpc, nil
+}
@@ -22,19 +22,32 @@ type VPCService interface {
 	ListVPCs(userID string, page, pageSize int) (*dto.VPCListResponse, error)
 	UpdateVPC(id string, userID string, req *dto.UpdateVPCRequest) (*models.VPC, error)
 	DeleteVPC(id string, userID string) error
+	DryRunCIDR(userID string, req *dto.VPCCIDRDryRunRequest) (*dto.VPCCIDRDryRunResponse, error)
 }
 
 type vpcService struct {
-	vpcRepo    repositories.VPCRepository
-	ovsManager network.OVSManager
-	logger     *utils.Logger
+	vpcRepo        repositories.VPCRepository
+	routeTableRepo repositories.RouteTableRepository
+	igwRepo        repositories.InternetGatewayRepository
+	natRepo        repositories.NATGatewayRepository
+	overlayService OverlayService
+	ovsManager     network.OVSManager
+	// ipv6Prefixes are the prefixes delegated to the platform that VPC IPv6
+	// blocks may be taken from besides the unique local range
+	ipv6Prefixes []string
+	logger       *utils.Logger
 }
 
-func NewVPCService(vpcRepo repositories.VPCRepository, ovsManager network.OVSManager, logger *utils.Logger) VPCService {
+func NewVPCService(vpcRepo repositories.VPCRepository, routeTableRepo repositories.RouteTableRepository, igwRepo repositories.InternetGatewayRepository, natRepo repositories.NATGatewayRepository, overlayService OverlayService, ovsManager network.OVSManager, ipv6Prefixes []string, logger *utils.Logger) VPCService {
 	return &vpcService{
-		vpcRepo:    vpcRepo,
-		ovsManager: ovsManager,
-		logger:     logger,
+		vpcRepo:        vpcRepo,
+		routeTableRepo: routeTableRepo,
+		igwRepo:        igwRepo,
+		natRepo:        natRepo,
+		overlayService: overlayService,
+		ovsManager:     ovsManager,
+		ipv6Prefixes:   ipv6Prefixes,
+		logger:         logger,
 	}
 }
 
@@ -46,6 +59,25 @@ func (s *vpcService) CreateVPC(userID string, req *dto.CreateVPCRequest) (*model
 		s.logger.Error("Invalid CIDR block", "error", err, "cidr", req.CIDRBlock)
 		return nil, errors.ErrInvalidCIDR
 	}
+	_, ipNet, _ := net.ParseCIDR(req.CIDRBlock)
+	cidrBlock := ipNet.String()
+
+	// Check for overlap with the user's other VPCs
+	conflict, err := s.vpcRepo.CheckCIDRConflict(cidrBlock, userID, nil)
+	if err != nil {
+		s.logger.Error("Failed to check CIDR conflict", "error", err, "cidr", cidrBlock)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check CIDR conflict")
+	}
+	if conflict {
+		s.logger.Warn("CIDR block overlaps existing VPC", "cidr", cidrBlock, "user_id", userID)
+		return nil, errors.ErrCIDRConflict
+	}
+
+	// Take the optional IPv6 block
+	ipv6CIDRBlock, err := s.ipv6CIDRBlock(userID, req)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check for name conflicts
 	existingVPC, err := s.vpcRepo.GetByName(req.Name, userID)
@@ -61,23 +93,39 @@ func (s *vpcService) CreateVPC(userID string, req *dto.CreateVPCRequest) (*model
 	// Create VPC model
 	now := time.Now()
 	vpc := &models.VPC{
-		ID:          uuid.New().String(),
-		Name:        req.Name,
-		CIDRBlock:   req.CIDRBlock,
-		Description: req.Description,
-		UserID:      userID,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		ID:            uuid.New().String(),
+		Name:          req.Name,
+		CIDRBlock:     cidrBlock,
+		IPv6CIDRBlock: ipv6CIDRBlock,
+		Description:   req.Description,
+		UserID:        userID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
 	}
 
-	// Create VPC in database
+	// Create VPC in database. A concurrent request may have taken an
+	// overlapping block since the checks above; the database rejects it.
 	if err := s.vpcRepo.Create(vpc); err != nil {
+		if err == errors.ErrCIDRConflict {
+			s.logger.Warn("CIDR block overlaps a concurrently created VPC", "cidr", cidrBlock, "user_id", userID)
+			return nil, err
+		}
 		s.logger.Error("Failed to create VPC in database", "error", err, "vpc_id", vpc.ID)
 		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create VPC")
 	}
 
+	// Name the bridge and datapath; the mapping goes with the VPC row
+	dataplane, err := s.vpcRepo.AllocateDataplane(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to allocate VPC dataplane", "error", err, "vpc_id", vpc.ID)
+		if delErr := s.vpcRepo.Delete(vpc.ID, userID); delErr != nil {
+			s.logger.Error("Failed to rollback VPC creation", "error", delErr, "vpc_id", vpc.ID)
+		}
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to allocate network bridge")
+	}
+
 	// Create Open vSwitch bridge for VPC
-	bridgeName := fmt.Sprintf("gcp-vpc-%s", vpc.ID[:8])
+	bridgeName := dataplane.BridgeName
 	if err := s.ovsManager.CreateBridge(bridgeName, vpc.CIDRBlock); err != nil {
 		s.logger.Error("Failed to create OVS bridge", "error", err, "bridge_name", bridgeName)
 		// Rollback database changes
@@ -87,7 +135,20 @@ func (s *vpcService) CreateVPC(userID string, req *dto.CreateVPCRequest) (*model
 		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to create network bridge")
 	}
 
-	s.logger.Info("VPC created successfully", "vpc_id", vpc.ID, "name", vpc.Name)
+	// Install the pipeline and the main route table
+	if err := s.provisionDataplane(vpc, dataplane); err != nil {
+		// Rollback bridge and database changes
+		if delErr := s.ovsManager.DeleteBridge(bridgeName); delErr != nil {
+			s.logger.Error("Failed to rollback OVS bridge", "error", delErr, "bridge_name", bridgeName)
+		}
+		if delErr := s.vpcRepo.Delete(vpc.ID, userID); delErr != nil {
+			s.logger.Error("Failed to rollback VPC creation", "error", delErr, "vpc_id", vpc.ID)
+		}
+		return nil, err
+	}
+
+	vpc.Dataplane = dataplane
+	s.logger.Info("VPC created successfully", "vpc_id", vpc.ID, "name", vpc.Name, "bridge_name", bridgeName)
 	return vpc, nil
 }
 
@@ -104,6 +165,12 @@ func (s *vpcService) GetVPC(id string, userID string) (*models.VPC, error) {
 		return nil, errors.ErrVPCNotFound
 	}
 
+	vpc.Dataplane, err = s.vpcRepo.GetDataplane(id)
+	if err != nil {
+		s.logger.Error("Failed to get VPC dataplane", "error", err, "vpc_id", id)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+
 	s.logger.Info("VPC retrieved successfully", "vpc_id", id)
 	return vpc, nil
 }
@@ -214,11 +281,41 @@ func (s *vpcService) DeleteVPC(id string, userID string) error {
 		return errors.ErrVPCNotFound
 	}
 
+	// The gateway's uplink patch port would outlive the bridge
+	igw, err := s.igwRepo.GetByVPC(id)
+	if err != nil {
+		s.logger.Error("Failed to get attached internet gateway", "error", err, "vpc_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check VPC usage")
+	}
+	if igw != nil {
+		s.logger.Warn("VPC still has an internet gateway attached", "vpc_id", id, "internet_gateway_id", igw.ID)
+		return errors.ErrResourceInUse
+	}
+
+	// NAT gateways hold addresses of the VPC's subnets and are not deleted
+	// with it
+	natGateways, err := s.natRepo.ListByVPC(id)
+	if err != nil {
+		s.logger.Error("Failed to list NAT gateways", "error", err, "vpc_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check VPC usage")
+	}
+	if len(natGateways) > 0 {
+		s.logger.Warn("VPC still has NAT gateways", "vpc_id", id, "nat_gateway_id", natGateways[0].ID)
+		return errors.ErrResourceInUse
+	}
+
 	// Delete OVS bridge
-	bridgeName := fmt.Sprintf("gcp-vpc-%s", id[:8])
-	if err := s.ovsManager.DeleteBridge(bridgeName); err != nil {
-		s.logger.Error("Failed to delete OVS bridge", "error", err, "bridge_name", bridgeName)
-		// Continue with deletion even if bridge deletion fails
+	dataplane, err := s.vpcRepo.GetDataplane(id)
+	if err != nil {
+		s.logger.Error("Failed to get VPC dataplane", "error", err, "vpc_id", id)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC")
+	}
+	if dataplane != nil {
+		if err := s.ovsManager.DeleteBridge(dataplane.BridgeName); err != nil {
+			s.logger.Error("Failed to delete OVS bridge", "error", err, "bridge_name", dataplane.BridgeName)
+			// Continue with deletion even if bridge deletion fails: the
+			// network controller collects bridges no VPC owns
+		}
 	}
 
 	// Delete from database
@@ -231,6 +328,155 @@ func (s *vpcService) DeleteVPC(id string, userID string) error {
 	return nil
 }
 
+// DryRunCIDR checks a prospective VPC CIDR block without creating anything and
+// suggests the next free block of the requested size inside RFC 1918 space
+func (s *vpcService) DryRunCIDR(userID string, req *dto.VPCCIDRDryRunRequest) (*dto.VPCCIDRDryRunResponse, error) {
+	s.logger.Info("Dry-running VPC CIDR block", "user_id", userID)
+
+	prefixLength := 16
+	if req.PrefixLength != nil {
+		prefixLength = *req.PrefixLength
+	}
+
+	existing, err := s.vpcRepo.ListCIDRBlocks(userID)
+	if err != nil {
+		s.logger.Error("Failed to list VPC CIDR blocks", "error", err, "user_id", userID)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to list VPC CIDR blocks")
+	}
+
+	usedNets := make([]*net.IPNet, 0, len(existing))
+	for _, cidr := range existing {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			usedNets = append(usedNets, ipNet)
+		}
+	}
+
+	result := &dto.VPCCIDRDryRunResponse{
+		Available:        true,
+		ConflictingCIDRs: []string{},
+	}
+
+	if req.CIDRBlock != nil {
+		if err := s.validateCIDRBlock(*req.CIDRBlock); err != nil {
+			return nil, errors.ErrInvalidCIDR
+		}
+		_, requested, _ := net.ParseCIDR(*req.CIDRBlock)
+		canonical := requested.String()
+		result.CIDRBlock = &canonical
+
+		// The requested block decides the suggestion size unless one was given
+		if req.PrefixLength == nil {
+			prefixLength, _ = requested.Mask.Size()
+		}
+
+		for _, used := range usedNets {
+			if network.CIDROverlaps(requested, used) {
+				result.ConflictingCIDRs = append(result.ConflictingCIDRs, used.String())
+			}
+		}
+		if len(result.ConflictingCIDRs) > 0 {
+			result.Available = false
+			result.Reason = errors.ErrCIDRConflict.Error()
+		}
+	}
+
+	if suggestion, ok := network.NextFreeCIDR(usedNets, prefixLength); ok {
+		suggested := suggestion.String()
+		result.SuggestedCIDRBlock = &suggested
+	}
+
+	return result, nil
+}
+
+// provisionDataplane pins the datapath ID of the VPC's bridge, allocates its
+// conntrack zone and VNI, creates its main route table, installs the base
+// pipeline flows and joins the bridge to the tunnel mesh
+func (s *vpcService) provisionDataplane(vpc *models.VPC, dataplane *models.VPCDataplane) error {
+	bridgeName := dataplane.BridgeName
+	if err := s.ovsManager.SetDatapathID(bridgeName, dataplane.DatapathID); err != nil {
+		s.logger.Error("Failed to set datapath ID", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to create network bridge")
+	}
+
+	ctZone, err := s.vpcRepo.AllocateConntrackZone(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to allocate conntrack zone", "error", err, "vpc_id", vpc.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to allocate conntrack zone")
+	}
+	dataplane.ConntrackZone = ctZone
+
+	vni, err := s.vpcRepo.AllocateVNI(vpc.ID)
+	if err != nil {
+		s.logger.Error("Failed to allocate VNI", "error", err, "vpc_id", vpc.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to allocate VNI")
+	}
+	dataplane.VNI = vni
+
+	mainRouteTable := &models.RouteTable{
+		ID:        uuid.New().String(),
+		VPCID:     vpc.ID,
+		Name:      "main",
+		IsMain:    true,
+		CreatedAt: vpc.CreatedAt,
+		UpdatedAt: vpc.UpdatedAt,
+	}
+	if err := s.routeTableRepo.Create(mainRouteTable); err != nil {
+		s.logger.Error("Failed to create main route table", "error", err, "vpc_id", vpc.ID)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to create main route table")
+	}
+
+	// The main table starts with only the local routes
+	routeFlows, err := network.CompileRoutes(vpcLocalCIDRs(vpc), nil, nil)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrorTypeInternal, "COMPILE_ERROR", "Failed to compile routes")
+	}
+
+	if err := pipelineFlowSet(vpc.ID, ctZone).Apply(s.ovsManager, bridgeName); err != nil {
+		s.logger.Error("Failed to install base pipeline flows", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to install network pipeline")
+	}
+	routeCookie := network.FlowCookie(network.CookieKindRoute, vpc.ID)
+	if err := s.ovsManager.ReplaceFlows(bridgeName, routeCookie, network.CookieMaskKind, routeFlows); err != nil {
+		s.logger.Error("Failed to install route flows", "error", err, "bridge_name", bridgeName)
+		return errors.Wrap(err, errors.ErrorTypeInternal, "OVS_ERROR", "Failed to install network pipeline")
+	}
+
+	return s.overlayService.SyncVPC(vpc.ID)
+}
+
+// vpcLocalCIDRs returns the blocks the local routes of a VPC cover: its IPv4
+// block and its IPv6 block if it has one
+func vpcLocalCIDRs(vpc *models.VPC) []string {
+	cidrs := []string{vpc.CIDRBlock}
+	if vpc.IPv6CIDRBlock != nil {
+		cidrs = append(cidrs, *vpc.IPv6CIDRBlock)
+	}
+	return cidrs
+}
+
+// pipelineFlowSet returns the base pipeline flows of a VPC bridge
+func pipelineFlowSet(vpcID string, ctZone int) *network.FlowSet {
+	return &network.FlowSet{
+		Cookie: network.FlowCookie(network.CookieKindPipeline, vpcID),
+		Mask:   network.CookieMaskResource,
+		Flows:  network.BasePipelineFlows(ctZone),
+	}
+}
+
+// vpcBridge returns the OVS bridge backing a VPC, as allocated when the VPC
+// was created
+func vpcBridge(vpcRepo repositories.VPCRepository, logger *utils.Logger, vpcID string) (string, error) {
+	dataplane, err := vpcRepo.GetDataplane(vpcID)
+	if err == nil && dataplane == nil {
+		err = fmt.Errorf("VPC %s has no bridge", vpcID)
+	}
+	if err != nil {
+		logger.Error("Failed to get VPC bridge", "error", err, "vpc_id", vpcID)
+		return "", errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to get VPC bridge")
+	}
+	return dataplane.BridgeName, nil
+}
+
 // validateCIDRBlock validates that the CIDR block is valid and within allowed ranges
 func (s *vpcService) validateCIDRBlock(cidr string) error {
 	_, ipNet, err := net.ParseCIDR(cidr)
@@ -239,14 +485,8 @@ func (s *vpcService) validateCIDRBlock(cidr string) error {
 	}
 
 	// Check if it's a private IP range (RFC 1918)
-	privateRanges := []string{
-		"10.0.0.0/8",     // 10.0.0.0 - 10.255.255.255
-		"172.16.0.0/12",  // 172.16.0.0 - 172.31.255.255
-		"192.168.0.0/16", // 192.168.0.0 - 192.168.255.255
-	}
-
 	isPrivate := false
-	for _, privateRange := range privateRanges {
+	for _, privateRange := range network.PrivateIPv4Ranges {
 		_, privateNet, _ := net.ParseCIDR(privateRange)
 		if privateNet.Contains(ipNet.IP) {
 			isPrivate = true
@@ -266,3 +506,79 @@ func (s *vpcService) validateCIDRBlock(cidr string) error {
 
 	return nil
 }
+
+// ipv6CIDRBlock returns the IPv6 block of a new VPC: the requested one, a
+// generated unique local one, or nil for an IPv4 only VPC. Unique local
+// blocks only need to be unique among the user's VPCs, which may be peered;
+// blocks of delegated prefixes are globally routable and must be unique
+// across the platform.
+func (s *vpcService) ipv6CIDRBlock(userID string, req *dto.CreateVPCRequest) (*string, error) {
+	var cidrBlock string
+	scope := &userID
+	switch {
+	case req.IPv6CIDRBlock != nil && req.AssignIPv6CIDRBlock:
+		s.logger.Warn("Both an IPv6 CIDR block and its assignment requested", "cidr", *req.IPv6CIDRBlock)
+		return nil, errors.ErrInvalidCIDR
+	case req.IPv6CIDRBlock != nil:
+		delegated, err := s.validateIPv6CIDRBlock(*req.IPv6CIDRBlock)
+		if err != nil {
+			s.logger.Error("Invalid IPv6 CIDR block", "error", err, "cidr", *req.IPv6CIDRBlock)
+			return nil, errors.ErrInvalidCIDR
+		}
+		if delegated {
+			scope = nil
+		}
+		_, ipNet, _ := net.ParseCIDR(*req.IPv6CIDRBlock)
+		cidrBlock = ipNet.String()
+	case req.AssignIPv6CIDRBlock:
+		generated, err := network.NewUniqueLocalIPv6CIDR()
+		if err != nil {
+			s.logger.Error("Failed to generate IPv6 CIDR block", "error", err)
+			return nil, errors.Wrap(err, errors.ErrorTypeInternal, "INVALID_CIDR", "Failed to generate IPv6 CIDR block")
+		}
+		cidrBlock = generated
+	default:
+		return nil, nil
+	}
+
+	conflict, err := s.vpcRepo.CheckIPv6CIDRConflict(cidrBlock, scope)
+	if err != nil {
+		s.logger.Error("Failed to check IPv6 CIDR conflict", "error", err, "cidr", cidrBlock)
+		return nil, errors.Wrap(err, errors.ErrorTypeInternal, "DB_ERROR", "Failed to check CIDR conflict")
+	}
+	if conflict {
+		s.logger.Warn("IPv6 CIDR block overlaps existing VPC", "cidr", cidrBlock, "user_id", userID)
+		return nil, errors.ErrCIDRConflict
+	}
+	return &cidrBlock, nil
+}
+
+// validateIPv6CIDRBlock validates that an IPv6 CIDR block is a /56 of the
+// unique local range or of a delegated prefix, and reports which
+func (s *vpcService) validateIPv6CIDRBlock(cidr string) (bool, error) {
+	ipNet, err := network.ParseIPv6CIDR(cidr)
+	if err != nil {
+		return false, errors.Wrap(err, errors.ErrorTypeValidation, "INVALID_CIDR", "Invalid IPv6 CIDR format")
+	}
+
+	if ones, _ := ipNet.Mask.Size(); ones != network.VPCIPv6PrefixLength {
+		return false, errors.New(errors.ErrorTypeValidation, "INVALID_CIDR", fmt.Sprintf("IPv6 CIDR block prefix must be /%d", network.VPCIPv6PrefixLength))
+	}
+
+	_, uniqueLocal, _ := net.ParseCIDR(network.UniqueLocalIPv6Range)
+	if network.CIDRContains(uniqueLocal, ipNet) {
+		return false, nil
+	}
+	for _, prefix := range s.ipv6Prefixes {
+		delegated, err := network.ParseIPv6CIDR(prefix)
+		if err != nil {
+			s.logger.Warn("Ignoring invalid delegated IPv6 prefix", "error", err, "prefix", prefix)
+			continue
+		}
+		if network.CIDRContains(delegated, ipNet) {
+			return true, nil
+		}
+	}
+
+	return false, errors.New(errors.ErrorTypeValidation, "INVALID_CIDR", "IPv6 CIDR block must be within the unique local range or a delegated prefix")
+}
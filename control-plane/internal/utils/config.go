@@ -4,6 +4,7 @@ package utils
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +15,7 @@ type Config struct {
 	Database    DatabaseConfig
 	RabbitMQ    RabbitMQConfig
 	JWT         JWTConfig
+	Network     NetworkConfig
 	LogLevel    string
 	App         AppConfig
 }
@@ -43,6 +45,18 @@ type RabbitMQConfig struct {
 	VHost    string
 }
 
+type NetworkConfig struct {
+	UplinkBridge      string
+	NodeName          string   // worker node whose OVS this control plane programs
+	TunnelType        string   // vxlan, geneve
+	OVSDBAddress      string   // unix:<path> or tcp:<host>:<port>, empty to use ovs-vsctl
+	ReconcileInterval int      // seconds between two passes of the network controller
+	DHCPDir           string   // where the network controller keeps dnsmasq configuration, pid and lease files
+	DNSUpstream       []string // resolvers queries outside the VPC domains are forwarded to
+	FlowLogCollector  string   // UDP ip:port the network controller collects flow log records on
+	IPv6Prefixes      []string // prefixes delegated to the platform that VPC IPv6 blocks may be taken from
+}
+
 type AppConfig struct {
 	Name string
 	Salt string
@@ -91,6 +105,17 @@ func LoadConfig() (*Config, error) {
 			AccessTokenExpiration:  getEnvAsInt("ACCESS_TOKEN_EXPIRATION", 1800000),    // 30 Minuutes
 			RefreshTokenExpiration: getEnvAsInt("REFRESH_TOKEN_EXPIRATION", 604800000), // 7 Days
 		},
+		Network: NetworkConfig{
+			UplinkBridge:      getEnv("UPLINK_BRIDGE", "br-main"),
+			NodeName:          getEnv("NODE_NAME", hostname()),
+			TunnelType:        getEnv("TUNNEL_TYPE", "vxlan"),
+			OVSDBAddress:      getEnv("OVSDB_ADDRESS", ""),
+			ReconcileInterval: getEnvAsInt("RECONCILE_INTERVAL", 30),
+			DHCPDir:           getEnv("DHCP_DIR", "/var/lib/gcp/dhcp"),
+			DNSUpstream:       getEnvAsList("DNS_UPSTREAM", nil),
+			FlowLogCollector:  getEnv("FLOW_LOG_COLLECTOR", "127.0.0.1:4739"),
+			IPv6Prefixes:      getEnvAsList("IPV6_PREFIXES", nil),
+		},
 	}
 
 	// Build RabbitMQ URL
@@ -116,3 +141,27 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsList reads a comma separated list, skipping empty entries
+func getEnvAsList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// hostname returns the name of the host, or an empty string if it is unknown
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return name
+}
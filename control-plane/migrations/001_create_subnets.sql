@@ -0,0 +1,14 @@
+-- Subnets carve a VPC's CIDR block into smaller networks
+CREATE TABLE IF NOT EXISTS subnets (
+    id UUID PRIMARY KEY,
+    vpc_id UUID NOT NULL REFERENCES vpcs(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    cidr_block CIDR NOT NULL,
+    availability_zone VARCHAR(50) NOT NULL,
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (vpc_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subnets_vpc_id ON subnets(vpc_id);
@@ -0,0 +1,12 @@
+-- Addresses handed out from a subnet's CIDR block by IPAM
+CREATE TABLE IF NOT EXISTS ip_allocations (
+    id UUID PRIMARY KEY,
+    subnet_id UUID NOT NULL REFERENCES subnets(id) ON DELETE CASCADE,
+    ip_address INET NOT NULL,
+    instance_id UUID,
+    description VARCHAR(255),
+    allocated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (subnet_id, ip_address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_allocations_instance_id ON ip_allocations(instance_id);
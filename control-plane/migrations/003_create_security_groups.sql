@@ -0,0 +1,34 @@
+-- Security groups filter traffic to and from their member instances
+CREATE TABLE IF NOT EXISTS security_groups (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    vpc_id UUID NOT NULL REFERENCES vpcs(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (vpc_id, name)
+);
+
+-- Source holds a CIDR block or the ID of another security group
+CREATE TABLE IF NOT EXISTS security_group_rules (
+    id UUID PRIMARY KEY,
+    security_group_id UUID NOT NULL REFERENCES security_groups(id) ON DELETE CASCADE,
+    direction VARCHAR(10) NOT NULL,
+    protocol VARCHAR(10) NOT NULL,
+    from_port INTEGER NOT NULL,
+    to_port INTEGER NOT NULL,
+    source VARCHAR(64) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_group_rules_group_id ON security_group_rules(security_group_id);
+CREATE INDEX IF NOT EXISTS idx_security_group_rules_source ON security_group_rules(source);
+
+CREATE TABLE IF NOT EXISTS instance_security_groups (
+    instance_id UUID NOT NULL,
+    security_group_id UUID NOT NULL REFERENCES security_groups(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (instance_id, security_group_id)
+);
@@ -0,0 +1,7 @@
+-- Conntrack zone per VPC so overlapping tenant address spaces keep separate
+-- connection state. Zone 0 is the OVS default zone and is never handed out.
+CREATE TABLE IF NOT EXISTS vpc_conntrack_zones (
+    vpc_id UUID PRIMARY KEY REFERENCES vpcs(id) ON DELETE CASCADE,
+    zone INTEGER NOT NULL UNIQUE CHECK (zone BETWEEN 1 AND 65535),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
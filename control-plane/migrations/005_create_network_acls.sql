@@ -0,0 +1,36 @@
+-- Network ACLs filter traffic statelessly at the subnet boundary
+CREATE TABLE IF NOT EXISTS network_acls (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    vpc_id UUID NOT NULL REFERENCES vpcs(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (vpc_id, name)
+);
+
+-- Entries are evaluated in rule number order per direction
+CREATE TABLE IF NOT EXISTS network_acl_entries (
+    id UUID PRIMARY KEY,
+    network_acl_id UUID NOT NULL REFERENCES network_acls(id) ON DELETE CASCADE,
+    rule_number INTEGER NOT NULL CHECK (rule_number BETWEEN 1 AND 32766),
+    direction VARCHAR(10) NOT NULL,
+    protocol VARCHAR(10) NOT NULL,
+    from_port INTEGER NOT NULL,
+    to_port INTEGER NOT NULL,
+    cidr_block VARCHAR(43) NOT NULL,
+    action VARCHAR(10) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (network_acl_id, direction, rule_number)
+);
+
+-- A subnet is associated with at most one network ACL
+CREATE TABLE IF NOT EXISTS network_acl_associations (
+    subnet_id UUID PRIMARY KEY REFERENCES subnets(id) ON DELETE CASCADE,
+    network_acl_id UUID NOT NULL REFERENCES network_acls(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_network_acl_associations_acl_id ON network_acl_associations(network_acl_id);
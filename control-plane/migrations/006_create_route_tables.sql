@@ -0,0 +1,35 @@
+-- Route tables decide where traffic leaving a subnet is forwarded
+CREATE TABLE IF NOT EXISTS route_tables (
+    id UUID PRIMARY KEY,
+    vpc_id UUID NOT NULL REFERENCES vpcs(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    is_main BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (vpc_id, name)
+);
+
+-- Every VPC has exactly one main route table
+CREATE UNIQUE INDEX IF NOT EXISTS idx_route_tables_main ON route_tables(vpc_id) WHERE is_main;
+
+CREATE TABLE IF NOT EXISTS routes (
+    id UUID PRIMARY KEY,
+    route_table_id UUID NOT NULL REFERENCES route_tables(id) ON DELETE CASCADE,
+    destination_cidr VARCHAR(43) NOT NULL,
+    target_type VARCHAR(20) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    priority INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (route_table_id, destination_cidr)
+);
+
+CREATE INDEX IF NOT EXISTS idx_routes_target ON routes(target_type, target_id);
+
+-- Subnets without an association use the main route table
+CREATE TABLE IF NOT EXISTS route_table_associations (
+    subnet_id UUID PRIMARY KEY REFERENCES subnets(id) ON DELETE CASCADE,
+    route_table_id UUID NOT NULL REFERENCES route_tables(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_route_table_associations_table_id ON route_table_associations(route_table_id);
@@ -0,0 +1,14 @@
+-- Internet gateways connect a VPC bridge to the uplink bridge
+CREATE TABLE IF NOT EXISTS internet_gateways (
+    id UUID PRIMARY KEY,
+    vpc_id UUID REFERENCES vpcs(id),
+    user_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    state VARCHAR(20) NOT NULL DEFAULT 'available',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+-- A VPC has at most one internet gateway attached
+CREATE UNIQUE INDEX IF NOT EXISTS idx_internet_gateways_vpc_id ON internet_gateways(vpc_id) WHERE vpc_id IS NOT NULL;
@@ -0,0 +1,24 @@
+-- Public address ranges configured by administrators
+CREATE TABLE IF NOT EXISTS public_ip_pools (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    cidr_block CIDR NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Elastic IPs stay allocated to a user until released, whatever happens to
+-- the instance they are associated with
+CREATE TABLE IF NOT EXISTS elastic_ips (
+    id UUID PRIMARY KEY,
+    pool_id UUID NOT NULL REFERENCES public_ip_pools(id),
+    public_ip INET NOT NULL UNIQUE,
+    user_id UUID NOT NULL,
+    instance_id UUID UNIQUE,
+    allocated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    associated_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_elastic_ips_user_id ON elastic_ips(user_id);
+CREATE INDEX IF NOT EXISTS idx_elastic_ips_pool_id ON elastic_ips(pool_id);
@@ -0,0 +1,19 @@
+-- NAT gateways source-NAT private subnets behind a single elastic IP. Each
+-- gateway tracks its translations in a conntrack zone of its own, allocated
+-- from the same range as the VPC zones.
+CREATE TABLE IF NOT EXISTS nat_gateways (
+    id UUID PRIMARY KEY,
+    vpc_id UUID NOT NULL REFERENCES vpcs(id),
+    subnet_id UUID NOT NULL REFERENCES subnets(id),
+    elastic_ip_id UUID NOT NULL UNIQUE REFERENCES elastic_ips(id),
+    user_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    state VARCHAR(20) NOT NULL DEFAULT 'available',
+    conntrack_zone INTEGER NOT NULL UNIQUE CHECK (conntrack_zone BETWEEN 1 AND 65535),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_nat_gateways_vpc_id ON nat_gateways(vpc_id);
+CREATE INDEX IF NOT EXISTS idx_nat_gateways_subnet_id ON nat_gateways(subnet_id);
@@ -0,0 +1,24 @@
+-- Worker nodes running instances. VPC bridges on different nodes are joined
+-- by tunnels between their tunnel endpoint addresses.
+CREATE TABLE IF NOT EXISTS worker_nodes (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    tunnel_ip INET NOT NULL UNIQUE,
+    state VARCHAR(20) NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- VNI per VPC, carried as the tunnel key so traffic of different VPCs stays
+-- apart on the wire. VNI 0 is never handed out.
+CREATE TABLE IF NOT EXISTS vpc_tunnel_keys (
+    vpc_id UUID PRIMARY KEY REFERENCES vpcs(id) ON DELETE CASCADE,
+    vni INTEGER NOT NULL UNIQUE CHECK (vni BETWEEN 1 AND 16777215),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Existing VPCs get their VNI in creation order
+INSERT INTO vpc_tunnel_keys (vpc_id, vni)
+SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id)
+FROM vpcs
+ON CONFLICT (vpc_id) DO NOTHING;
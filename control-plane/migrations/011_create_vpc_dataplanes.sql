@@ -0,0 +1,22 @@
+-- Open vSwitch resources backing each VPC, besides its VNI and conntrack
+-- zone. Interface names are limited to 15 characters, too few for a
+-- collision-free slice of a UUID, so bridges are numbered from a sequence
+-- instead: gcp-vpc- and seven hex digits. Numbers are never reused, so a
+-- bridge a deleted VPC left behind is never taken for a new VPC's. The
+-- datapath ID carries the same number.
+CREATE SEQUENCE IF NOT EXISTS vpc_dataplane_seq MAXVALUE 268435455;
+
+CREATE TABLE IF NOT EXISTS vpc_dataplanes (
+    vpc_id UUID PRIMARY KEY REFERENCES vpcs(id) ON DELETE CASCADE,
+    bridge_name VARCHAR(15) NOT NULL UNIQUE,
+    datapath_id CHAR(16) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Existing VPCs get numbered bridges too. The network controller creates
+-- them once the VPC also has a conntrack zone, which 020 backfills, and
+-- removes the bridges named after the VPC ID.
+INSERT INTO vpc_dataplanes (vpc_id, bridge_name, datapath_id)
+SELECT v.id, 'gcp-vpc-' || lpad(to_hex(v.n), 7, '0'), lpad(to_hex(v.n), 16, '0')
+FROM (SELECT id, nextval('vpc_dataplane_seq') AS n FROM vpcs) v
+ON CONFLICT (vpc_id) DO NOTHING;
@@ -0,0 +1,7 @@
+-- What instances learn about themselves from the metadata service besides
+-- their placement and addresses. metadata_tokens is required when an
+-- instance may only read its metadata within a session token's lifetime.
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS user_data TEXT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS ssh_public_key TEXT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS metadata_tokens VARCHAR(10) NOT NULL DEFAULT 'optional'
+    CHECK (metadata_tokens IN ('optional', 'required'));
@@ -0,0 +1,25 @@
+-- VPC peerings connect two VPCs, possibly of different users. A peering is
+-- requested by the owner of one VPC and takes effect once the owner of the
+-- other accepts it. Rejected peerings are kept so the requester can see the
+-- answer.
+CREATE TABLE IF NOT EXISTS vpc_peerings (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    requester_vpc_id UUID NOT NULL REFERENCES vpcs(id) ON DELETE CASCADE,
+    requester_user_id UUID NOT NULL,
+    accepter_vpc_id UUID NOT NULL REFERENCES vpcs(id) ON DELETE CASCADE,
+    accepter_user_id UUID NOT NULL,
+    state VARCHAR(20) NOT NULL DEFAULT 'pending-acceptance',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (requester_vpc_id <> accepter_vpc_id),
+    CHECK (state IN ('pending-acceptance', 'active', 'rejected'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_vpc_peerings_requester_vpc_id ON vpc_peerings(requester_vpc_id);
+CREATE INDEX IF NOT EXISTS idx_vpc_peerings_accepter_vpc_id ON vpc_peerings(accepter_vpc_id);
+
+-- Two VPCs are peered at most once, whichever of them asked
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vpc_peerings_pair ON vpc_peerings
+    (LEAST(requester_vpc_id, accepter_vpc_id), GREATEST(requester_vpc_id, accepter_vpc_id))
+    WHERE state IN ('pending-acceptance', 'active');
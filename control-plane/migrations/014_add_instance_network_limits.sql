@@ -0,0 +1,9 @@
+-- Per-instance overrides of the bandwidth the instance type allows. Inbound
+-- is what the instance receives and outbound what it sends. NULL leaves the
+-- limit to the instance type.
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS network_inbound_mbps INTEGER
+    CHECK (network_inbound_mbps > 0);
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS network_outbound_mbps INTEGER
+    CHECK (network_outbound_mbps > 0);
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS network_burst_kb INTEGER
+    CHECK (network_burst_kb > 0);
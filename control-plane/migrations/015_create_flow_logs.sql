@@ -0,0 +1,47 @@
+-- Flow logs capture the traffic of a VPC, a subnet or an instance interface.
+-- The number identifies a flow log in the IPFIX samples its traffic is
+-- exported with. Records are kept for the retention of their flow log.
+CREATE TABLE IF NOT EXISTS flow_logs (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL,
+    vpc_id UUID NOT NULL REFERENCES vpcs(id) ON DELETE CASCADE,
+    resource_type VARCHAR(20) NOT NULL,
+    resource_id UUID NOT NULL,
+    traffic_type VARCHAR(10) NOT NULL DEFAULT 'all',
+    number SERIAL UNIQUE,
+    retention_days INTEGER NOT NULL DEFAULT 14,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (resource_type IN ('vpc', 'subnet', 'instance')),
+    CHECK (traffic_type IN ('accept', 'reject', 'all')),
+    CHECK (retention_days BETWEEN 1 AND 365)
+);
+
+CREATE INDEX IF NOT EXISTS idx_flow_logs_vpc_id ON flow_logs(vpc_id);
+CREATE INDEX IF NOT EXISTS idx_flow_logs_resource ON flow_logs(resource_type, resource_id);
+
+-- One record per connection and flow cache export: a long connection is
+-- recorded once per active timeout
+CREATE TABLE IF NOT EXISTS flow_log_records (
+    id BIGSERIAL PRIMARY KEY,
+    flow_log_id UUID NOT NULL REFERENCES flow_logs(id) ON DELETE CASCADE,
+    vpc_id UUID NOT NULL,
+    action VARCHAR(10) NOT NULL,
+    protocol SMALLINT NOT NULL,
+    src_ip INET NOT NULL,
+    src_port INTEGER NOT NULL DEFAULT 0,
+    dst_ip INET NOT NULL,
+    dst_port INTEGER NOT NULL DEFAULT 0,
+    packets BIGINT NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    node_name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (action IN ('accept', 'reject'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_flow_log_records_flow_log_id ON flow_log_records(flow_log_id, end_time);
+CREATE INDEX IF NOT EXISTS idx_flow_log_records_vpc_id ON flow_log_records(vpc_id, end_time);
+CREATE INDEX IF NOT EXISTS idx_flow_log_records_end_time ON flow_log_records(end_time);
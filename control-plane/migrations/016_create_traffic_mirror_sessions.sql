@@ -0,0 +1,42 @@
+-- Traffic mirror sessions copy the traffic of an instance's interface to
+-- another instance of the VPC or to a GRE or ERSPAN endpoint. The key is the
+-- GRE key or ERSPAN session ID of tunnel targets.
+CREATE TABLE IF NOT EXISTS traffic_mirror_sessions (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL,
+    vpc_id UUID NOT NULL REFERENCES vpcs(id) ON DELETE CASCADE,
+    source_instance_id UUID NOT NULL,
+    target_type VARCHAR(10) NOT NULL,
+    target_instance_id UUID,
+    target_ip INET,
+    target_key BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (target_type IN ('instance', 'gre', 'erspan')),
+    CHECK ((target_type = 'instance') = (target_instance_id IS NOT NULL)),
+    CHECK ((target_type = 'instance') = (target_ip IS NULL)),
+    CHECK (source_instance_id <> target_instance_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_traffic_mirror_sessions_vpc_id ON traffic_mirror_sessions(vpc_id);
+CREATE INDEX IF NOT EXISTS idx_traffic_mirror_sessions_source ON traffic_mirror_sessions(source_instance_id);
+CREATE INDEX IF NOT EXISTS idx_traffic_mirror_sessions_target ON traffic_mirror_sessions(target_instance_id);
+
+-- Filter rules narrow down the traffic a session copies. A session without
+-- rules copies everything. The CIDR block is the remote side of the
+-- source instance's traffic.
+CREATE TABLE IF NOT EXISTS traffic_mirror_filter_rules (
+    id UUID PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES traffic_mirror_sessions(id) ON DELETE CASCADE,
+    direction VARCHAR(10) NOT NULL,
+    protocol VARCHAR(10) NOT NULL,
+    from_port INTEGER NOT NULL,
+    to_port INTEGER NOT NULL,
+    cidr_block CIDR NOT NULL DEFAULT '0.0.0.0/0',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (direction IN ('inbound', 'outbound')),
+    CHECK (protocol IN ('tcp', 'udp', 'icmp', 'all'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_traffic_mirror_filter_rules_session_id ON traffic_mirror_filter_rules(session_id);
@@ -0,0 +1,11 @@
+-- Optional IPv6 blocks: a /56 per VPC and a /64 of it per subnet. Instances
+-- in a subnet with a block get the EUI-64 address of their port's MAC in it,
+-- recorded with their IPv4 allocation.
+ALTER TABLE vpcs ADD COLUMN IF NOT EXISTS ipv6_cidr_block CIDR
+    CHECK (family(ipv6_cidr_block) = 6 AND masklen(ipv6_cidr_block) = 56);
+ALTER TABLE subnets ADD COLUMN IF NOT EXISTS ipv6_cidr_block CIDR
+    CHECK (family(ipv6_cidr_block) = 6 AND masklen(ipv6_cidr_block) = 64);
+ALTER TABLE ip_allocations ADD COLUMN IF NOT EXISTS ipv6_address INET
+    CHECK (family(ipv6_address) = 6);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subnets_ipv6_cidr_block ON subnets(ipv6_cidr_block) WHERE ipv6_cidr_block IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Subnets of a VPC never overlap. The service checks before inserting, but
+-- two concurrent requests can both pass the check, so the database has the
+-- final say. btree_gist provides the gist equality operator for vpc_id.
+-- Subnets created before the service checked for overlaps can stand in the
+-- way. The control plane then refuses to start and lists the overlapping
+-- pairs. Delete one subnet of each pair and restart it.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE subnets ADD CONSTRAINT subnets_cidr_block_excl
+    EXCLUDE USING gist (vpc_id WITH =, cidr_block inet_ops WITH &&);
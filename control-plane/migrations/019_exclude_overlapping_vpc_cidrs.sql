@@ -0,0 +1,11 @@
+-- A user's VPCs never overlap, in either family. Like the subnet constraint,
+-- this closes the window between the service's check and the insert. Blocks
+-- delegated to the platform must also be unique across users, which is
+-- still only checked by the service.
+-- VPCs created before the service checked for overlaps can stand in the way.
+-- The control plane then refuses to start and lists the overlapping pairs.
+-- Delete one VPC of each pair, e.g. through the API, and restart it.
+ALTER TABLE vpcs ADD CONSTRAINT vpcs_cidr_block_excl
+    EXCLUDE USING gist (user_id WITH =, cidr_block inet_ops WITH &&);
+ALTER TABLE vpcs ADD CONSTRAINT vpcs_ipv6_cidr_block_excl
+    EXCLUDE USING gist (user_id WITH =, ipv6_cidr_block inet_ops WITH &&);
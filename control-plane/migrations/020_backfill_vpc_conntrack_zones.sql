@@ -0,0 +1,20 @@
+-- VPCs created before conntrack zones existed never got one, and the network
+-- controller leaves a VPC without a zone alone. They get the lowest zones
+-- neither a VPC nor a NAT gateway holds, in creation order.
+LOCK TABLE vpc_conntrack_zones IN SHARE ROW EXCLUSIVE MODE;
+LOCK TABLE nat_gateways IN SHARE ROW EXCLUSIVE MODE;
+
+INSERT INTO vpc_conntrack_zones (vpc_id, zone)
+SELECT v.id, f.zone
+FROM (
+    SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS n
+    FROM vpcs
+    WHERE NOT EXISTS (SELECT 1 FROM vpc_conntrack_zones z WHERE z.vpc_id = vpcs.id)
+) v
+JOIN (
+    SELECT s.zone, ROW_NUMBER() OVER (ORDER BY s.zone) AS n
+    FROM generate_series(1, 65535) AS s(zone)
+    WHERE NOT EXISTS (SELECT 1 FROM vpc_conntrack_zones z WHERE z.zone = s.zone)
+        AND NOT EXISTS (SELECT 1 FROM nat_gateways g WHERE g.conntrack_zone = s.zone)
+) f ON f.n = v.n
+ON CONFLICT (vpc_id) DO NOTHING;
@@ -56,8 +56,103 @@ var (
 	ErrSubnetNotFound        = errors.New("subnet not found")
 	ErrSubnetAlreadyExists   = errors.New("subnet already exists")
 	ErrSubnetCIDROutOfRange  = errors.New("subnet CIDR is out of VPC range")
+	ErrSubnetCIDRConflict    = errors.New("subnet CIDR overlaps with existing subnet")
 	ErrSecurityGroupNotFound = errors.New("security group not found")
+	ErrSecurityGroupExists   = errors.New("security group already exists")
 	ErrInvalidSecurityRule   = errors.New("invalid security group rule")
+	ErrSecurityRuleNotFound  = errors.New("security group rule not found")
+)
+
+// Route table errors
+var (
+	ErrRouteTableNotFound = errors.New("route table not found")
+	ErrRouteTableExists   = errors.New("route table already exists")
+	ErrMainRouteTable     = errors.New("operation not allowed on the main route table")
+	ErrRouteNotFound      = errors.New("route not found")
+	ErrRouteExists        = errors.New("route to destination already exists")
+	ErrInvalidRoute       = errors.New("invalid route")
+)
+
+// Internet gateway errors
+var (
+	ErrInternetGatewayNotFound    = errors.New("internet gateway not found")
+	ErrInternetGatewayExists      = errors.New("internet gateway already exists")
+	ErrInternetGatewayAttached    = errors.New("internet gateway is attached to a VPC")
+	ErrInternetGatewayNotAttached = errors.New("internet gateway is not attached")
+	ErrVPCHasInternetGateway      = errors.New("VPC already has an internet gateway attached")
+)
+
+// Elastic IP errors
+var (
+	ErrPublicIPPoolNotFound   = errors.New("public IP pool not found")
+	ErrPublicIPPoolExists     = errors.New("public IP pool already exists")
+	ErrPublicIPPoolConflict   = errors.New("public IP pool overlaps an existing pool")
+	ErrPublicIPPoolExhausted  = errors.New("no free public IP addresses left")
+	ErrElasticIPNotFound      = errors.New("elastic IP not found")
+	ErrElasticIPAssociated    = errors.New("elastic IP is associated with an instance or NAT gateway")
+	ErrElasticIPNotAssociated = errors.New("elastic IP is not associated")
+	ErrInstanceHasPublicIP    = errors.New("instance already has a public IP")
+)
+
+// NAT gateway errors
+var (
+	ErrNATGatewayNotFound = errors.New("NAT gateway not found")
+	ErrNATGatewayExists   = errors.New("NAT gateway already exists")
+	ErrSubnetNotPublic    = errors.New("subnet has no route to an attached internet gateway")
+)
+
+// VPC peering errors
+var (
+	ErrVPCPeeringNotFound    = errors.New("VPC peering not found")
+	ErrVPCPeeringExists      = errors.New("VPCs are already peered or have a pending peering")
+	ErrVPCPeeringCIDROverlap = errors.New("VPC CIDR blocks overlap")
+	ErrVPCPeeringNotPending  = errors.New("VPC peering is not pending acceptance")
+	ErrVPCPeeringNotAccepter = errors.New("only the owner of the accepter VPC can accept or reject a VPC peering")
+)
+
+// Flow log errors
+var (
+	ErrFlowLogNotFound    = errors.New("flow log not found")
+	ErrFlowLogNoInterface = errors.New("instance has no network interface to log")
+)
+
+// Traffic mirror errors
+var (
+	ErrTrafficMirrorSessionNotFound = errors.New("traffic mirror session not found")
+	ErrTrafficMirrorRuleNotFound    = errors.New("traffic mirror filter rule not found")
+	ErrInvalidTrafficMirrorTarget   = errors.New("invalid traffic mirror target")
+	ErrInvalidTrafficMirrorRule     = errors.New("invalid traffic mirror filter rule")
+	ErrTrafficMirrorRuleLimit       = errors.New("traffic mirror session has too many filter rules")
+	ErrTrafficMirrorConflict        = errors.New("instance cannot be both a traffic mirror source and target")
+	ErrTrafficMirrorNoInterface     = errors.New("instance has no network interface to mirror")
+)
+
+// Worker node errors
+var (
+	ErrWorkerNodeNotFound = errors.New("worker node not found")
+	ErrWorkerNodeExists   = errors.New("worker node already exists")
+	ErrInvalidTunnelIP    = errors.New("invalid tunnel IP address")
+)
+
+// Network ACL errors
+var (
+	ErrNetworkACLNotFound         = errors.New("network ACL not found")
+	ErrNetworkACLExists           = errors.New("network ACL already exists")
+	ErrNetworkACLEntryNotFound    = errors.New("network ACL entry not found")
+	ErrNetworkACLRuleNumberExists = errors.New("network ACL rule number already in use")
+	ErrInvalidNetworkACLEntry     = errors.New("invalid network ACL entry")
+)
+
+// Reachability analyzer errors
+var (
+	ErrInvalidEndpoint = errors.New("endpoint is neither an instance nor an address in the VPC")
+)
+
+// IP address management errors
+var (
+	ErrIPAddressExhausted   = errors.New("no free IP addresses left in subnet")
+	ErrIPAddressUnavailable = errors.New("IP address is reserved, out of range or already allocated")
+	ErrIPAllocationNotFound = errors.New("IP address allocation not found")
 )
 
 // Instance errors